@@ -9,28 +9,36 @@ import (
 	"flag"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	cloudtasks "cloud.google.com/go/cloudtasks/apiv2"
 	"cloud.google.com/go/profiler"
 	"contrib.go.opencensus.io/integrations/ocsql"
 	"github.com/go-redis/redis/v7"
+	"go.opentelemetry.io/otel"
 	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/cache"
 	"golang.org/x/pkgsite/internal/config"
 	"golang.org/x/pkgsite/internal/database"
 	"golang.org/x/pkgsite/internal/dcensus"
+	"golang.org/x/pkgsite/internal/debug"
 	"golang.org/x/pkgsite/internal/derrors"
 	"golang.org/x/pkgsite/internal/experiment"
 	"golang.org/x/pkgsite/internal/frontend"
 	"golang.org/x/pkgsite/internal/log"
 	"golang.org/x/pkgsite/internal/middleware"
 	"golang.org/x/pkgsite/internal/postgres"
+	"golang.org/x/pkgsite/internal/prometheus"
 	"golang.org/x/pkgsite/internal/proxy"
 	"golang.org/x/pkgsite/internal/proxydatasource"
 	"golang.org/x/pkgsite/internal/queue"
+	"golang.org/x/pkgsite/internal/search"
 	"golang.org/x/pkgsite/internal/source"
+	"golang.org/x/pkgsite/internal/tracing"
 )
 
 var (
@@ -42,6 +50,12 @@ var (
 		"for direct proxy mode and frontend fetches")
 	directProxy = flag.Bool("direct_proxy", false, "if set to true, uses the module proxy referred to by this URL "+
 		"as a direct backend, bypassing the database")
+	fuzzySearch        = flag.Bool("fuzzy_search", false, "if set to true, search also returns packages whose paths are similar to the query")
+	maxDependencyDepth = flag.Int("max_dependency_depth", 0, "maximum depth of imports followed to find module dependencies; if 0, a default is used")
+	synonymsFile       = flag.String("synonyms_file", "", "YAML file of search term synonyms, like internal/search/testdata/synonyms.yaml; if empty, search terms are not expanded")
+	rankingConfigFile  = flag.String("ranking_config_file", "", "YAML file of search ranking parameters, optionally for experiment buckets, like \"lowgomod.noGoModPenalty: 0.5\"; if empty, defaults are used")
+	logSearchQueries   = flag.Bool("log_search_queries", false, "if set to true, record hashes of search queries and their result counts in the search_query_log table")
+	shutdownTimeout    = flag.Duration("shutdown_timeout", 30*time.Second, "maximum time to wait for in-flight requests when shutting down")
 )
 
 func main() {
@@ -58,9 +72,10 @@ func main() {
 		}
 	}
 	var (
-		ds         internal.DataSource
-		exp        internal.ExperimentSource
-		fetchQueue queue.Queue
+		ds                internal.DataSource
+		exp               internal.ExperimentSource
+		fetchQueue        queue.Queue
+		experimentBuckets []string
 	)
 	proxyClient, err := proxy.New(*proxyURL)
 	if err != nil {
@@ -80,7 +95,39 @@ func main() {
 			log.Fatal(ctx, err)
 		}
 		db := postgres.New(ddb)
-		defer db.Close()
+		db.FuzzySearchEnabled = *fuzzySearch
+		db.MaxDependencyDepth = *maxDependencyDepth
+		if *synonymsFile != "" {
+			synonyms, err := search.LoadSynonyms(*synonymsFile)
+			if err != nil {
+				log.Fatal(ctx, err)
+			}
+			db.Synonyms = synonyms
+		}
+		if cfg.DBHLLPrecision != 0 {
+			if err := postgres.ValidateHLLPrecision(cfg.DBHLLPrecision); err != nil {
+				log.Fatal(ctx, err)
+			}
+			db.HLLPrecision = cfg.DBHLLPrecision
+		}
+		if *rankingConfigFile != "" {
+			cfg, err := postgres.LoadExperimentConfig(*rankingConfigFile)
+			if err != nil {
+				log.Fatal(ctx, err)
+			}
+			db.ExperimentConfig = cfg
+			experimentBuckets = postgres.ExperimentBuckets(cfg)
+		}
+		if *logSearchQueries {
+			if cfg.SearchQueryLogKey == "" {
+				log.Fatal(ctx, "-log_search_queries requires GO_DISCOVERY_SEARCH_QUERY_LOG_KEY to be set")
+			}
+			db.SearchQueryLogger = postgres.NewPostgresSearchQueryLogger(db)
+			db.SearchQueryLogKey = []byte(cfg.SearchQueryLogKey)
+		}
+		if err := db.PrepareSearchStatements(ctx); err != nil {
+			log.Errorf(ctx, "searching with unprepared queries: %v", err)
+		}
 		ds = db
 		exp = db
 		sourceClient := source.NewClient(config.SourceTimeout)
@@ -92,39 +139,64 @@ func main() {
 			Addr: cfg.RedisHAHost + ":" + cfg.RedisHAPort,
 		})
 	}
+	var (
+		cacheClient *redis.Client
+		dsCache     cache.RedisClient
+	)
+	if cfg.RedisCacheHost != "" {
+		cacheClient = redis.NewClient(&redis.Options{
+			Addr: cfg.RedisCacheHost + ":" + cfg.RedisCachePort,
+		})
+		dsCache = cache.NewRedisClient(cacheClient)
+	}
 	server, err := frontend.NewServer(frontend.ServerConfig{
-		DataSource:           ds,
-		Queue:                fetchQueue,
-		CompletionClient:     haClient,
-		TaskIDChangeInterval: config.TaskIDChangeIntervalFrontend,
-		StaticPath:           *staticPath,
-		ThirdPartyPath:       *thirdPartyPath,
-		DevMode:              *devMode,
-		AppVersionLabel:      cfg.AppVersionLabel(),
+		DataSource:            ds,
+		DataSourceCache:       dsCache,
+		Queue:                 fetchQueue,
+		CompletionClient:      haClient,
+		TaskIDChangeInterval:  config.TaskIDChangeIntervalFrontend,
+		StaticPath:            *staticPath,
+		ThirdPartyPath:        *thirdPartyPath,
+		DevMode:               *devMode,
+		AppVersionLabel:       cfg.AppVersionLabel(),
+		BadgeQuota:            cfg.BadgeQuota,
+		SearchRateLimit:       cfg.SearchRateLimit,
+		AutocompleteRateLimit: cfg.AutocompleteRateLimit,
+		AllowedOrigins:        cfg.AllowedOrigins,
+		ShutdownTimeout:       *shutdownTimeout,
 	})
 	if err != nil {
 		log.Fatalf(ctx, "frontend.NewServer: %v", err)
 	}
 	router := dcensus.NewRouter(frontend.TagRoute)
-	var cacheClient *redis.Client
-	if cfg.RedisCacheHost != "" {
-		cacheClient = redis.NewClient(&redis.Options{
-			Addr: cfg.RedisCacheHost + ":" + cfg.RedisCachePort,
-		})
-	}
 	server.Install(router.Handle, cacheClient)
 	views := append(dcensus.ServerViews,
 		postgres.SearchLatencyDistribution,
 		postgres.SearchResponseCount,
+		postgres.SearchLatencyPopular,
+		postgres.SearchLatencyDeep,
+		postgres.SearchLatencyEstimate,
+		postgres.SearchCoalescedCount,
+		postgres.SearchQueryLogDroppedCount,
+		postgres.SearchSuggestionLatencyDistribution,
 		frontend.FrontendFetchLatencyDistribution,
 		frontend.FrontendFetchResponseCount,
 		middleware.CacheResultCount,
 		middleware.CacheErrorCount,
 		middleware.QuotaResultCount,
 	)
+	views = append(views, database.PoolViews...)
 	if err := dcensus.Init(cfg, views...); err != nil {
 		log.Fatal(ctx, err)
 	}
+	if cfg.ServeMetrics {
+		router.Handle("/debug/metrics", prometheus.Handler(views))
+	}
+	if cfg.AdminToken != "" {
+		debugMux := http.NewServeMux()
+		debug.RegisterDebugHandlers(debugMux, cfg.AdminToken)
+		router.Handle("/debug/pprof/", debugMux)
+	}
 	// We are not currently forwarding any ports on AppEngine, so serving debug
 	// information is broken.
 	if !cfg.OnAppEngine() {
@@ -143,20 +215,44 @@ func main() {
 	if err != nil {
 		log.Fatal(ctx, err)
 	}
+	if cfg.Tracing.Endpoint != "" {
+		tp, err := tracing.Init(ctx, cfg.Tracing)
+		if err != nil {
+			log.Fatal(ctx, err)
+		}
+		defer tp.Shutdown(ctx)
+	}
 	mw := middleware.Chain(
+		tracing.Middleware(otel.GetTracerProvider()), // must come first, so that the span covers the request
 		middleware.RequestLog(requestLogger),
-		middleware.AcceptMethods(http.MethodGet), // accept only GETs
+		frontend.APIErrors(), // must come before any middleware that can serve errors
+		// Accept only GETs, and the OPTIONS of CORS preflight requests to the
+		// API, which are the only routes that serve them.
+		middleware.AcceptPathMethods(map[string][]string{frontend.APIPathPrefix: {http.MethodOptions}}, http.MethodGet),
 		middleware.Quota(cfg.Quota),
 		middleware.GodocURL(),                          // potentially redirects so should be early in chain
+		middleware.ETag(),                              // must come before SecureHeaders so 304s omit the nonce
 		middleware.SecureHeaders(),                     // must come before any caching for nonces to work
 		middleware.LatestVersion(server.LatestVersion), // must come before caching for version badge to work
 		middleware.Panic(panicHandler),
 		middleware.Timeout(54*time.Second),
 		middleware.Experiment(experimenter),
+		middleware.ExperimentBucket(experimentBuckets),
 	)
 	addr := cfg.HostAddr("localhost:8080")
 	log.Infof(ctx, "Listening on addr %s", addr)
-	log.Fatal(ctx, http.ListenAndServe(addr, mw(router)))
+	go func() {
+		if err := server.ListenAndServe(addr, mw(router)); err != http.ErrServerClosed {
+			log.Fatal(ctx, err)
+		}
+	}()
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	<-sig
+	log.Infof(ctx, "Shutting down")
+	if err := server.Shutdown(ctx); err != nil {
+		log.Fatal(ctx, err)
+	}
 }
 
 func newQueue(ctx context.Context, cfg *config.Config, proxyClient *proxy.Client, sourceClient *source.Client, db *postgres.DB) queue.Queue {
@@ -191,7 +287,15 @@ func newQueue(ctx context.Context, cfg *config.Config, proxyClient *proxy.Client
 func openDB(ctx context.Context, cfg *config.Config, driver string) (_ *database.DB, err error) {
 	derrors.Wrap(&err, "openDB(ctx, cfg, %q)", driver)
 	log.Infof(ctx, "opening database on host %s", cfg.DBHost)
-	ddb, err := database.Open(driver, cfg.DBConnInfo(), cfg.InstanceID)
+	dbcfg := database.DBConfig{
+		MaxOpenConns:       cfg.DBMaxOpenConns,
+		MaxIdleConns:       cfg.DBMaxIdleConns,
+		ConnMaxLifetime:    cfg.DBConnMaxLifetime,
+		ConnMaxIdleTime:    cfg.DBConnMaxIdleTime,
+		SlowQueryThreshold: cfg.DBSlowQueryThreshold,
+		ReplicaInfo:        cfg.DBReplicaConnInfo(),
+	}
+	ddb, err := database.Open(driver, cfg.DBConnInfo(), cfg.InstanceID, dbcfg)
 	if err == nil {
 		return ddb, nil
 	}
@@ -202,7 +306,7 @@ func openDB(ctx context.Context, cfg *config.Config, driver string) (_ *database
 	}
 	log.Errorf(ctx, "database.Open for primary host %s failed with %v; trying secondary host %s ",
 		cfg.DBHost, err, cfg.DBSecondaryHost)
-	return database.Open(driver, ci, cfg.InstanceID)
+	return database.Open(driver, ci, cfg.InstanceID, dbcfg)
 }
 func getLogger(ctx context.Context, cfg *config.Config) middleware.Logger {
 	if cfg.OnAppEngine() {
@@ -0,0 +1,270 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// The loadtest command sends search requests to a frontend at a fixed rate,
+// and reports their latency, error rate and throughput. It is meant to be
+// run before and after changes to the search schema or queries, to compare
+// their performance.
+//
+// The queries are read from a file with one query per line; blank lines and
+// lines starting with '#' are ignored. Requests cycle through the queries,
+// and are sent at the given rate whether or not earlier requests have
+// completed, so that a slow server does not reduce the load.
+//
+// Without -url, and with -db, loadtest creates a local test database (see
+// doc/postgres.md), fills it with sample modules, and serves it with an
+// in-process frontend. It must then be run from the repo root, like the
+// frontend.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/frontend"
+	"golang.org/x/pkgsite/internal/log"
+	"golang.org/x/pkgsite/internal/postgres"
+	"golang.org/x/pkgsite/internal/testing/sample"
+)
+
+var (
+	baseURL     = flag.String("url", "", "base URL of the frontend, like http://localhost:8080")
+	queriesFile = flag.String("queries", "cmd/loadtest/queries.txt", "file of search queries, one per line")
+	rate        = flag.Int("rate", 10, "requests per second")
+	duration    = flag.Duration("duration", 30*time.Second, "how long to send requests for")
+	timeout     = flag.Duration("timeout", 10*time.Second, "timeout of each request")
+	useDB       = flag.Bool("db", false, "if -url is not set, serve sample data from a local test database")
+)
+
+// testDBName is the name of the database created by -db.
+const testDBName = "discovery_loadtest"
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	ctx := context.Background()
+
+	if *rate <= 0 || *duration <= 0 {
+		log.Fatal(ctx, "-rate and -duration must be positive")
+	}
+	queries, err := readQueries(*queriesFile)
+	if err != nil {
+		log.Fatal(ctx, err)
+	}
+	target := *baseURL
+	if target == "" {
+		if !*useDB {
+			log.Fatal(ctx, "one of -url and -db must be set")
+		}
+		srv, err := serveSampleData(ctx)
+		if err != nil {
+			log.Fatal(ctx, err)
+		}
+		defer srv.Close()
+		target = srv.URL
+	}
+	log.Infof(ctx, "sending %d requests/s to %s for %s", *rate, target, *duration)
+	results := attack(ctx, target, queries, *rate, *duration, *timeout)
+	report(os.Stdout, results, *duration)
+}
+
+// readQueries reads the search queries from the file at filename.
+func readQueries(filename string) ([]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var queries []string
+	scan := bufio.NewScanner(f)
+	for scan.Scan() {
+		q := strings.TrimSpace(scan.Text())
+		if q == "" || strings.HasPrefix(q, "#") {
+			continue
+		}
+		queries = append(queries, q)
+	}
+	if err := scan.Err(); err != nil {
+		return nil, err
+	}
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("%s has no queries", filename)
+	}
+	return queries, nil
+}
+
+// serveSampleData sets up the local test database with sample modules, and
+// starts a frontend that serves it.
+func serveSampleData(ctx context.Context) (*httptest.Server, error) {
+	db, err := postgres.SetupTestDB(testDBName)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range sampleModules() {
+		if err := db.InsertModule(ctx, m); err != nil {
+			return nil, err
+		}
+	}
+	s, err := frontend.NewServer(frontend.ServerConfig{
+		DataSource:     db,
+		StaticPath:     "content/static",
+		ThirdPartyPath: "third_party",
+	})
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	s.Install(mux.Handle, nil)
+	return httptest.NewServer(mux), nil
+}
+
+// sampleModules returns the modules inserted into the test database.
+func sampleModules() []*internal.Module {
+	var mods []*internal.Module
+	for _, data := range []struct {
+		modulePath, version string
+		suffixes            []string
+	}{
+		{"github.com/hashicorp/vault", "v1.2.3", []string{"api", "builtin/audit/file", "builtin/audit/socket", "vault/replication"}},
+		{"github.com/hashicorp/vault/api", "v1.1.2", []string{""}},
+		{"github.com/gorilla/mux", "v1.7.4", []string{""}},
+		{"github.com/sirupsen/logrus", "v1.6.0", []string{"", "hooks/syslog", "hooks/test"}},
+		{"golang.org/x/tools", "v0.0.1", []string{"go/packages", "go/ast/astutil", "cmd/goimports"}},
+		{"cloud.google.com/go", "v0.56.0", []string{"storage", "pubsub", "firestore", "bigquery"}},
+	} {
+		m := sample.Module(data.modulePath, data.version, data.suffixes...)
+		for _, p := range m.LegacyPackages {
+			p.Imports = nil
+		}
+		mods = append(mods, m)
+	}
+	return mods
+}
+
+// A result is the outcome of a single request.
+type result struct {
+	latency time.Duration
+	err     error
+}
+
+// attack sends requests for the queries to the search endpoint of target, at
+// rate requests per second for d, and returns their results.
+func attack(ctx context.Context, target string, queries []string, rate int, d, timeout time.Duration) []result {
+	client := &http.Client{Timeout: timeout}
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results []result
+	)
+	ticker := time.NewTicker(time.Second / time.Duration(rate))
+	defer ticker.Stop()
+	end := time.After(d)
+	for i := 0; ; i++ {
+		select {
+		case <-end:
+			wg.Wait()
+			return results
+		case <-ticker.C:
+		}
+		u := target + "/search?q=" + url.QueryEscape(queries[i%len(queries)])
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := get(ctx, client, u)
+			mu.Lock()
+			results = append(results, r)
+			mu.Unlock()
+		}()
+	}
+}
+
+// get requests u and reads the response, and returns its result.
+func get(ctx context.Context, client *http.Client, u string) result {
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return result{err: err}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		// Drop the URL from the error, so that errors are counted by cause.
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return result{latency: time.Since(start), err: err}
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(ioutil.Discard, resp.Body)
+	latency := time.Since(start)
+	if err == nil && resp.StatusCode != http.StatusOK {
+		err = errors.New(resp.Status)
+	}
+	return result{latency: latency, err: err}
+}
+
+// report writes the latency percentiles, error rate and throughput of
+// results, which were sent over d, to w.
+func report(w io.Writer, results []result, d time.Duration) {
+	if len(results) == 0 {
+		fmt.Fprintln(w, "no requests were sent")
+		return
+	}
+	var (
+		latencies []time.Duration
+		errs      = map[string]int{}
+		nerrs     int
+	)
+	for _, r := range results {
+		latencies = append(latencies, r.latency)
+		if r.err != nil {
+			nerrs++
+			errs[r.err.Error()]++
+		}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	fmt.Fprintf(w, "requests:   %d\n", len(results))
+	fmt.Fprintf(w, "latency:    p50 %s, p95 %s, p99 %s, max %s\n",
+		percentile(latencies, 50), percentile(latencies, 95), percentile(latencies, 99), latencies[len(latencies)-1])
+	fmt.Fprintf(w, "errors:     %d (%.2f%%)\n", nerrs, 100*float64(nerrs)/float64(len(results)))
+	fmt.Fprintf(w, "throughput: %.2f successful requests/s\n", float64(len(results)-nerrs)/d.Seconds())
+	if nerrs > 0 {
+		var msgs []string
+		for msg := range errs {
+			msgs = append(msgs, msg)
+		}
+		sort.Strings(msgs)
+		fmt.Fprintln(w, "error counts:")
+		for _, msg := range msgs {
+			fmt.Fprintf(w, "  %5d %s\n", errs[msg], msg)
+		}
+	}
+}
+
+// percentile returns the p-th percentile of sorted, which must not be empty,
+// by the nearest-rank method.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	i := (len(sorted)*p+99)/100 - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
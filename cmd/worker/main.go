@@ -9,6 +9,7 @@ package main
 import (
 	"bufio"
 	"context"
+	"database/sql"
 	"flag"
 	"net/http"
 	"os"
@@ -20,18 +21,23 @@ import (
 	"cloud.google.com/go/errorreporting"
 	"cloud.google.com/go/profiler"
 	"github.com/go-redis/redis/v7"
+	"go.opentelemetry.io/otel"
 	"golang.org/x/pkgsite/internal/config"
 	"golang.org/x/pkgsite/internal/database"
 	"golang.org/x/pkgsite/internal/dcensus"
+	"golang.org/x/pkgsite/internal/debug"
 	"golang.org/x/pkgsite/internal/experiment"
 	"golang.org/x/pkgsite/internal/index"
 	"golang.org/x/pkgsite/internal/queue"
 	"golang.org/x/pkgsite/internal/source"
+	"golang.org/x/pkgsite/internal/sumdb"
+	"golang.org/x/pkgsite/internal/tracing"
 	"golang.org/x/pkgsite/internal/worker"
 
 	"golang.org/x/pkgsite/internal/log"
 	"golang.org/x/pkgsite/internal/middleware"
 	"golang.org/x/pkgsite/internal/postgres"
+	"golang.org/x/pkgsite/internal/prometheus"
 	"golang.org/x/pkgsite/internal/proxy"
 
 	"contrib.go.opencensus.io/integrations/ocsql"
@@ -42,6 +48,41 @@ var (
 	queueName  = config.GetEnv("GO_DISCOVERY_WORKER_TASK_QUEUE", "")
 	workers    = flag.Int("workers", 10, "number of concurrent requests to the fetch service, when running locally")
 	staticPath = flag.String("static", "content/static", "path to folder containing static files served")
+
+	runMigrations = flag.Bool("run_migrations", false, "apply the database migrations that have not been applied before starting")
+	migrationsDir = flag.String("migrations", "migrations", "path to folder containing the database migrations")
+
+	// incremental reports whether to update imported-by counts incrementally.
+	incremental = config.GetEnv("GO_DISCOVERY_WORKER_INCREMENTAL_IMPORTED_BY", "") == "true"
+
+	// searchCleanupInterval is how often to clean up search_documents. If it
+	// is empty, search_documents is not cleaned up.
+	searchCleanupInterval = config.GetEnv("GO_DISCOVERY_WORKER_SEARCH_CLEANUP_INTERVAL", "")
+
+	// indexQueuePollInterval is how often to check module_index_queue for
+	// module versions to fetch when it is empty. If it is empty,
+	// module_index_queue is not processed.
+	indexQueuePollInterval = config.GetEnv("GO_DISCOVERY_WORKER_INDEX_QUEUE_POLL_INTERVAL", "")
+	// indexQueueConcurrency is the number of module versions in
+	// module_index_queue that are fetched at once.
+	indexQueueConcurrency = config.GetEnv("GO_DISCOVERY_WORKER_INDEX_QUEUE_CONCURRENCY", "1")
+
+	// listenForModuleEvents reports whether to schedule module versions to be
+	// fetched as soon as they are inserted into module_version_states, rather
+	// than only when /requeue is called.
+	listenForModuleEvents = config.GetEnv("GO_DISCOVERY_WORKER_LISTEN_FOR_MODULE_EVENTS", "") == "true"
+
+	// moduleEventsURL is the URL to which the events of the module_events
+	// outbox are delivered, in POST requests. If it is empty, the events
+	// are not delivered, and are only pruned.
+	moduleEventsURL = config.GetEnv("GO_DISCOVERY_WORKER_MODULE_EVENTS_URL", "")
+	// moduleEventsPublishInterval is how often to publish and prune the
+	// events of the module_events outbox.
+	moduleEventsPublishInterval = config.GetEnv("GO_DISCOVERY_WORKER_MODULE_EVENTS_PUBLISH_INTERVAL", "1m")
+
+	// importedByHalfLife is the half-life of the decay of the imported-by
+	// counts used for search scoring. If it is empty, a default is used.
+	importedByHalfLife = config.GetEnv("GO_DISCOVERY_WORKER_IMPORTED_BY_HALF_LIFE", "")
 )
 
 func main() {
@@ -68,12 +109,38 @@ func main() {
 	if err != nil {
 		log.Fatalf(ctx, "unable to register the ocsql driver: %v\n", err)
 	}
-	ddb, err := database.Open(driverName, cfg.DBConnInfo(), cfg.InstanceID)
+	if *runMigrations {
+		// Migrate closes the database, so it gets its own.
+		mdb, err := sql.Open(driverName, cfg.DBConnInfo())
+		if err != nil {
+			log.Fatalf(ctx, "sql.Open: %v", err)
+		}
+		if err := database.Migrate(mdb, *migrationsDir); err != nil {
+			log.Fatal(ctx, err)
+		}
+		log.Infof(ctx, "applied the migrations in %s", *migrationsDir)
+	}
+	ddb, err := database.Open(driverName, cfg.DBConnInfo(), cfg.InstanceID, database.DBConfig{
+		MaxOpenConns:       cfg.DBMaxOpenConns,
+		MaxIdleConns:       cfg.DBMaxIdleConns,
+		ConnMaxLifetime:    cfg.DBConnMaxLifetime,
+		ConnMaxIdleTime:    cfg.DBConnMaxIdleTime,
+		SlowQueryThreshold: cfg.DBSlowQueryThreshold,
+	})
 	if err != nil {
 		log.Fatalf(ctx, "database.Open: %v", err)
 	}
 	db := postgres.New(ddb)
 	defer db.Close()
+	if !cfg.DisableSumVerification {
+		db.SumDB = sumdb.New(sumdb.DefaultURL, sumdb.DefaultKey)
+	}
+	if importedByHalfLife != "" {
+		db.ImportedByHalfLife, err = time.ParseDuration(importedByHalfLife)
+		if err != nil {
+			log.Fatalf(ctx, "time.ParseDuration(%q): %v", importedByHalfLife, err)
+		}
+	}
 
 	populateExcluded(ctx, db)
 
@@ -101,6 +168,7 @@ func main() {
 		ReportingClient:      reportingClient,
 		TaskIDChangeInterval: config.TaskIDChangeIntervalWorker,
 		StaticPath:           *staticPath,
+		Incremental:          incremental,
 	})
 	if err != nil {
 		log.Fatal(ctx, err)
@@ -109,9 +177,19 @@ func main() {
 	server.Install(router.Handle)
 
 	views := append(dcensus.ClientViews, dcensus.ServerViews...)
+	views = append(views, worker.SearchDocumentCleanupRunCount, worker.SearchDocumentCleanupDeleted)
+	views = append(views, database.PoolViews...)
 	if err := dcensus.Init(cfg, views...); err != nil {
 		log.Fatal(ctx, err)
 	}
+	if cfg.ServeMetrics {
+		router.Handle("/debug/metrics", prometheus.Handler(views))
+	}
+	if cfg.AdminToken != "" {
+		debugMux := http.NewServeMux()
+		debug.RegisterDebugHandlers(debugMux, cfg.AdminToken)
+		router.Handle("/debug/pprof/", debugMux)
+	}
 	// We are not currently forwarding any ports on AppEngine, so serving debug
 	// information is broken.
 	if !cfg.OnAppEngine() {
@@ -122,6 +200,41 @@ func main() {
 		go http.ListenAndServe(cfg.DebugAddr("localhost:8001"), dcensusServer)
 	}
 
+	if searchCleanupInterval != "" {
+		interval, err := time.ParseDuration(searchCleanupInterval)
+		if err != nil {
+			log.Fatalf(ctx, "time.ParseDuration(%q): %v", searchCleanupInterval, err)
+		}
+		go worker.NewSearchDocumentCleanupWorker(db).Run(ctx, interval)
+	}
+	if indexQueuePollInterval != "" {
+		interval, err := time.ParseDuration(indexQueuePollInterval)
+		if err != nil {
+			log.Fatalf(ctx, "time.ParseDuration(%q): %v", indexQueuePollInterval, err)
+		}
+		concurrency, err := strconv.Atoi(indexQueueConcurrency)
+		if err != nil {
+			log.Fatalf(ctx, "strconv.Atoi(%q): %v", indexQueueConcurrency, err)
+		}
+		go worker.NewIndexQueueWorker(db, proxyClient, sourceClient, cfg.AppVersionLabel(), concurrency).Run(ctx, interval)
+	}
+	if listenForModuleEvents {
+		events, err := postgres.ListenForModuleEvents(ctx, db)
+		if err != nil {
+			log.Fatal(ctx, err)
+		}
+		go server.RunModuleEvents(ctx, events)
+	}
+	publishInterval, err := time.ParseDuration(moduleEventsPublishInterval)
+	if err != nil {
+		log.Fatalf(ctx, "time.ParseDuration(%q): %v", moduleEventsPublishInterval, err)
+	}
+	var eventSink postgres.EventSink
+	if moduleEventsURL != "" {
+		eventSink = worker.NewHTTPEventSink(moduleEventsURL)
+	}
+	go worker.NewModuleEventPublisher(db, eventSink).Run(ctx, publishInterval)
+
 	handlerTimeout, err := strconv.Atoi(timeout)
 	if err != nil {
 		log.Fatalf(ctx, "strconv.Atoi(%q): %v", timeout, err)
@@ -132,16 +245,24 @@ func main() {
 	if err != nil {
 		log.Fatal(ctx, err)
 	}
+	if cfg.Tracing.Endpoint != "" {
+		tp, err := tracing.Init(ctx, cfg.Tracing)
+		if err != nil {
+			log.Fatal(ctx, err)
+		}
+		defer tp.Shutdown(ctx)
+	}
 	mw := middleware.Chain(
+		tracing.Middleware(otel.GetTracerProvider()), // must come first, so that the span covers the request
 		middleware.RequestLog(requestLogger),
 		middleware.Timeout(time.Duration(handlerTimeout)*time.Minute),
 		middleware.Experiment(experimenter),
 	)
-	http.Handle("/", mw(router))
-
 	addr := cfg.HostAddr("localhost:8000")
 	log.Infof(ctx, "Listening on addr %s", addr)
-	log.Fatal(ctx, http.ListenAndServe(addr, nil))
+	// Don't serve http.DefaultServeMux: importing the debug package
+	// registers the pprof handlers on it, without authentication.
+	log.Fatal(ctx, http.ListenAndServe(addr, mw(router)))
 }
 
 func newQueue(ctx context.Context, cfg *config.Config, proxyClient *proxy.Client, sourceClient *source.Client, db *postgres.DB) queue.Queue {
@@ -0,0 +1,125 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package api defines the JSON responses of the frontend's API.
+//
+// The API has one endpoint:
+//
+//   GET /api/v1/packages/<import-path>[?version=<version>]
+//
+// It responds with a Package describing the package with the given import
+// path at the given version, or at the latest version if there is no version
+// parameter. Standard library versions may be given as semantic versions or as
+// Go release tags, like "go1.14".
+//
+// Versions
+//
+// The version of the API is the number N in the path prefix /api/vN/ of its
+// endpoints. A request may also ask for a version with the media type
+// application/vnd.pkgsite.vN+json in its Accept header, which takes precedence
+// over the path; a request for an unsupported version fails with status 406.
+// Every response has an API-Version header holding the version that served
+// it.
+//
+// Version 1 is stable: fields may be added to its responses, but existing
+// fields are never removed, renamed or changed in meaning. Version 2 is in
+// development and may change incompatibly until it is declared stable. Its
+// packages endpoint, at /api/v2/packages/, responds with a PackageV2.
+//
+// Responses have the content type application/json. If the request fails,
+// the response has a non-200 status and its body is a problem detail, as
+// described in RFC 7807, with the content type application/problem+json: the
+// status is 400 for a malformed path or version, and 404 for a package that
+// does not exist at the requested version.
+package api
+
+import (
+	"time"
+
+	"golang.org/x/pkgsite/internal"
+)
+
+// Package is the response of the packages endpoint.
+type Package struct {
+	PackagePath       string
+	ModulePath        string
+	Version           string
+	Synopsis          string
+	Licenses          []*License
+	CommitTime        time.Time
+	IsRedistributable bool
+	// ImportedByCount is the number of packages that import this one, in any
+	// module other than its own. It is periodically recomputed, so it may
+	// lag behind newly published importers.
+	ImportedByCount int
+	// RequiresCgo reports whether the package imports "C", so that it cannot
+	// be built without cgo. It is nil if that is not known.
+	RequiresCgo *bool
+}
+
+// License describes a license file of the package's module that applies to
+// the package.
+type License struct {
+	// Types is the set of license types detected in the file, like "MIT".
+	Types []string
+	// FilePath is the path to the license file, relative to the module root.
+	FilePath string
+}
+
+// NewPackage returns the Package for pkg, which is imported by
+// importedByCount packages.
+func NewPackage(pkg *internal.LegacyVersionedPackage, importedByCount int) *Package {
+	licenses := []*License{}
+	for _, l := range pkg.Licenses {
+		licenses = append(licenses, &License{Types: l.Types, FilePath: l.FilePath})
+	}
+	return &Package{
+		PackagePath:       pkg.Path,
+		ModulePath:        pkg.ModulePath,
+		Version:           pkg.Version,
+		Synopsis:          pkg.Synopsis,
+		Licenses:          licenses,
+		CommitTime:        pkg.CommitTime,
+		IsRedistributable: pkg.LegacyPackage.IsRedistributable,
+		ImportedByCount:   importedByCount,
+		RequiresCgo:       pkg.Facts.RequiresCgo,
+	}
+}
+
+// PackageV2 is the response of the packages endpoint in version 2 of the API.
+// Unlike Package, it groups the fields that describe the module of the
+// package.
+type PackageV2 struct {
+	Path            string
+	Synopsis        string
+	Module          *Module
+	Licenses        []*License
+	ImportedByCount int
+	RequiresCgo     *bool
+}
+
+// Module describes the module of a package in version 2 of the API.
+type Module struct {
+	Path              string
+	Version           string
+	CommitTime        time.Time
+	IsRedistributable bool
+}
+
+// NewPackageV2 returns the PackageV2 with the contents of p.
+func NewPackageV2(p *Package) *PackageV2 {
+	return &PackageV2{
+		Path:     p.PackagePath,
+		Synopsis: p.Synopsis,
+		Module: &Module{
+			Path:              p.ModulePath,
+			Version:           p.Version,
+			CommitTime:        p.CommitTime,
+			IsRedistributable: p.IsRedistributable,
+		},
+		Licenses:        p.Licenses,
+		ImportedByCount: p.ImportedByCount,
+		RequiresCgo:     p.RequiresCgo,
+	}
+}
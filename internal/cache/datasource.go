@@ -0,0 +1,173 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package cache provides a DataSource that caches the results of another
+// DataSource in Redis.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/log"
+)
+
+const (
+	// LatestTTL is the time for which the results of lookups of the latest
+	// version are cached.
+	LatestTTL = 5 * time.Minute
+
+	// VersionedTTL is the time for which the results of lookups of specific
+	// versions are cached. They only change when a module is reprocessed or
+	// deleted, but they still expire, so that such changes are eventually
+	// seen and so that versions that are no longer requested leave the cache.
+	VersionedTTL = 24 * time.Hour
+
+	// MaxValueSize is the size of the largest encoded result that is cached.
+	// Larger results, like packages with very long documentation, are not
+	// cached.
+	MaxValueSize = 1 << 20
+)
+
+// A RedisClient is the subset of the operations of a Redis client used by a
+// CachingDataSource.
+type RedisClient interface {
+	// Get returns the value of key. It returns an error that wraps
+	// derrors.NotFound if key is not set.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set sets the value of key, which expires after ttl.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// NewRedisClient returns a RedisClient that uses c.
+func NewRedisClient(c *redis.Client) RedisClient {
+	return redisClient{c}
+}
+
+type redisClient struct {
+	c *redis.Client
+}
+
+func (r redisClient) Get(ctx context.Context, key string) ([]byte, error) {
+	val, err := r.c.WithContext(ctx).Get(key).Bytes()
+	if err == redis.Nil {
+		return nil, derrors.NotFound
+	}
+	return val, err
+}
+
+func (r redisClient) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return r.c.WithContext(ctx).Set(key, value, ttl).Err()
+}
+
+// A CachingDataSource is a DataSource that caches the results of the
+// LegacyGetModuleInfo and LegacyGetPackage methods of another DataSource. It
+// passes all other calls through.
+//
+// Results are cached for LatestTTL or VersionedTTL, and only if their encoding
+// is at most MaxValueSize bytes. Errors are not cached. If the cache cannot be
+// read or written, the underlying DataSource is used as if the cache were
+// empty.
+type CachingDataSource struct {
+	internal.DataSource
+	rdb RedisClient
+}
+
+// NewCachingDataSource returns a CachingDataSource that caches the results of
+// ds in rdb.
+func NewCachingDataSource(ds internal.DataSource, rdb RedisClient) *CachingDataSource {
+	return &CachingDataSource{DataSource: ds, rdb: rdb}
+}
+
+// LegacyGetModuleInfo returns the result of LegacyGetModuleInfo of the
+// underlying DataSource, from the cache if possible.
+func (c *CachingDataSource) LegacyGetModuleInfo(ctx context.Context, modulePath, version string) (*internal.LegacyModuleInfo, error) {
+	key := cacheKey("LegacyGetModuleInfo", modulePath, version)
+	var mi internal.LegacyModuleInfo
+	if c.get(ctx, key, &mi) {
+		return &mi, nil
+	}
+	res, err := c.DataSource.LegacyGetModuleInfo(ctx, modulePath, version)
+	if err != nil {
+		return nil, err
+	}
+	c.put(ctx, key, res, version)
+	return res, nil
+}
+
+// LegacyGetPackage returns the result of LegacyGetPackage of the underlying
+// DataSource, from the cache if possible.
+func (c *CachingDataSource) LegacyGetPackage(ctx context.Context, pkgPath, modulePath, version string) (*internal.LegacyVersionedPackage, error) {
+	key := cacheKey("LegacyGetPackage", pkgPath, modulePath, version)
+	var cp cachedPackage
+	if c.get(ctx, key, &cp) {
+		return &internal.LegacyVersionedPackage{LegacyPackage: cp.Package, LegacyModuleInfo: cp.Module}, nil
+	}
+	res, err := c.DataSource.LegacyGetPackage(ctx, pkgPath, modulePath, version)
+	if err != nil {
+		return nil, err
+	}
+	c.put(ctx, key, cachedPackage{Package: res.LegacyPackage, Module: res.LegacyModuleInfo}, version)
+	return res, nil
+}
+
+// cachedPackage is the cached form of a LegacyVersionedPackage. Its embedded
+// structs have fields with the same names, like IsRedistributable, which
+// would be lost if it were encoded directly.
+type cachedPackage struct {
+	Package internal.LegacyPackage
+	Module  internal.LegacyModuleInfo
+}
+
+// cacheKey returns the cache key for the result of the method with the given
+// name and arguments.
+func cacheKey(method string, args ...string) string {
+	key := "datasource/" + method
+	for _, a := range args {
+		key += fmt.Sprintf("/%q", a)
+	}
+	return key
+}
+
+// get reads the value cached for key into v, and reports whether it was
+// found.
+func (c *CachingDataSource) get(ctx context.Context, key string, v interface{}) bool {
+	val, err := c.rdb.Get(ctx, key)
+	if err != nil {
+		if !errors.Is(err, derrors.NotFound) {
+			log.Errorf(ctx, "cache get %q: %v", key, err)
+		}
+		return false
+	}
+	if err := json.Unmarshal(val, v); err != nil {
+		log.Errorf(ctx, "cache get %q: %v", key, err)
+		return false
+	}
+	return true
+}
+
+// put caches v, the result of a lookup of version, for key.
+func (c *CachingDataSource) put(ctx context.Context, key string, v interface{}, version string) {
+	val, err := json.Marshal(v)
+	if err != nil {
+		log.Errorf(ctx, "cache put %q: %v", key, err)
+		return
+	}
+	if len(val) > MaxValueSize {
+		return
+	}
+	ttl := VersionedTTL
+	if version == internal.LatestVersion {
+		ttl = LatestTTL
+	}
+	if err := c.rdb.Set(ctx, key, val, ttl); err != nil {
+		log.Errorf(ctx, "cache put %q: %v", key, err)
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/source"
+	"golang.org/x/pkgsite/internal/testing/sample"
+)
+
+// fakeRedis is a RedisClient that stores values in memory, recording their
+// TTLs.
+type fakeRedis struct {
+	values map[string][]byte
+	ttls   map[string]time.Duration
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{values: map[string][]byte{}, ttls: map[string]time.Duration{}}
+}
+
+func (r *fakeRedis) Get(ctx context.Context, key string) ([]byte, error) {
+	v, ok := r.values[key]
+	if !ok {
+		return nil, derrors.NotFound
+	}
+	return v, nil
+}
+
+func (r *fakeRedis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	r.values[key] = value
+	r.ttls[key] = ttl
+	return nil
+}
+
+// countingDataSource is a DataSource with a single package, which counts the
+// calls to it.
+type countingDataSource struct {
+	internal.DataSource
+	pkg   *internal.LegacyVersionedPackage
+	calls int
+}
+
+func (ds *countingDataSource) LegacyGetModuleInfo(ctx context.Context, modulePath, version string) (*internal.LegacyModuleInfo, error) {
+	ds.calls++
+	if modulePath != ds.pkg.ModulePath {
+		return nil, derrors.NotFound
+	}
+	return &ds.pkg.LegacyModuleInfo, nil
+}
+
+func (ds *countingDataSource) LegacyGetPackage(ctx context.Context, pkgPath, modulePath, version string) (*internal.LegacyVersionedPackage, error) {
+	ds.calls++
+	if pkgPath != ds.pkg.Path {
+		return nil, derrors.NotFound
+	}
+	return ds.pkg, nil
+}
+
+func samplePackage() *internal.LegacyVersionedPackage {
+	return &internal.LegacyVersionedPackage{
+		LegacyPackage:    *sample.LegacyPackage(sample.ModulePath, sample.Suffix),
+		LegacyModuleInfo: *sample.LegacyModuleInfo(sample.ModulePath, sample.VersionString),
+	}
+}
+
+func TestCachingDataSource(t *testing.T) {
+	ctx := context.Background()
+	pkg := samplePackage()
+	// The package and module differ in a field that both have.
+	pkg.LegacyPackage.IsRedistributable = false
+	pkg.LegacyModuleInfo.IsRedistributable = true
+
+	for _, test := range []struct {
+		version string
+		wantTTL time.Duration
+	}{
+		{sample.VersionString, VersionedTTL},
+		{internal.LatestVersion, LatestTTL},
+	} {
+		t.Run(test.version, func(t *testing.T) {
+			ds := &countingDataSource{pkg: pkg}
+			rdb := newFakeRedis()
+			c := NewCachingDataSource(ds, rdb)
+
+			for i := 0; i < 2; i++ {
+				got, err := c.LegacyGetPackage(ctx, pkg.Path, pkg.ModulePath, test.version)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if diff := cmp.Diff(pkg, got, cmp.AllowUnexported(source.Info{})); diff != "" {
+					t.Errorf("LegacyGetPackage call %d mismatch (-want +got):\n%s", i, diff)
+				}
+				gotMI, err := c.LegacyGetModuleInfo(ctx, pkg.ModulePath, test.version)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if diff := cmp.Diff(&pkg.LegacyModuleInfo, gotMI, cmp.AllowUnexported(source.Info{})); diff != "" {
+					t.Errorf("LegacyGetModuleInfo call %d mismatch (-want +got):\n%s", i, diff)
+				}
+			}
+			// Only the first calls miss the cache.
+			if ds.calls != 2 {
+				t.Errorf("got %d calls to the DataSource, want 2", ds.calls)
+			}
+			if len(rdb.ttls) != 2 {
+				t.Errorf("got %d cached values, want 2", len(rdb.ttls))
+			}
+			for key, ttl := range rdb.ttls {
+				if ttl != test.wantTTL {
+					t.Errorf("TTL of %q = %v, want %v", key, ttl, test.wantTTL)
+				}
+			}
+		})
+	}
+}
+
+func TestCachingDataSourceErrors(t *testing.T) {
+	ctx := context.Background()
+	ds := &countingDataSource{pkg: samplePackage()}
+	rdb := newFakeRedis()
+	c := NewCachingDataSource(ds, rdb)
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.LegacyGetPackage(ctx, "missing.com/p", "missing.com/p", sample.VersionString); !errors.Is(err, derrors.NotFound) {
+			t.Fatalf("got error %v, want NotFound", err)
+		}
+	}
+	// Errors are not cached.
+	if ds.calls != 2 {
+		t.Errorf("got %d calls to the DataSource, want 2", ds.calls)
+	}
+	if len(rdb.values) != 0 {
+		t.Errorf("got %d cached values, want 0", len(rdb.values))
+	}
+}
+
+func TestCachingDataSourceMaxValueSize(t *testing.T) {
+	ctx := context.Background()
+	pkg := samplePackage()
+	pkg.DocumentationHTML = strings.Repeat("x", MaxValueSize)
+	ds := &countingDataSource{pkg: pkg}
+	rdb := newFakeRedis()
+	c := NewCachingDataSource(ds, rdb)
+
+	if _, err := c.LegacyGetPackage(ctx, pkg.Path, pkg.ModulePath, sample.VersionString); err != nil {
+		t.Fatal(err)
+	}
+	if len(rdb.values) != 0 {
+		t.Errorf("got %d cached values, want 0", len(rdb.values))
+	}
+}
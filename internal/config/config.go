@@ -18,6 +18,7 @@ import (
 	"math/rand"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -64,6 +65,24 @@ type Config struct {
 	// Discovery environment variables
 	ProxyURL, IndexURL string
 
+	// VulnDBURL is the URL of the Go vulnerability database.
+	VulnDBURL string
+
+	// GitHubAPIURL and GitLabAPIURL are the base URLs of the APIs used to
+	// count the contributors of the repositories of modules.
+	GitHubAPIURL, GitLabAPIURL string
+
+	// GitHubToken and GitLabToken authenticate the requests to the APIs at
+	// GitHubAPIURL and GitLabAPIURL, which have much higher rate limits for
+	// authenticated requests. They are optional.
+	GitHubToken string `json:"-"`
+	GitLabToken string `json:"-"`
+
+	// PrivateRepos are the glob patterns of the repositories whose
+	// contributors are not counted, like "github.com/corp/*". Like GONOSUMDB,
+	// a pattern matches a repository path if it matches a prefix of it.
+	PrivateRepos []string
+
 	// Ports used for hosting. 'DebugPort' is used for serving HTTP debug pages.
 	Port, DebugPort string
 
@@ -86,8 +105,23 @@ type Config struct {
 
 	DBSecret, DBUser, DBHost, DBPort, DBName string
 	DBSecondaryHost                          string // DB host to use if first one is down
+	DBReplicaHost                            string // DB host of a read replica, if any
 	DBPassword                               string `json:"-"`
 
+	// Connection pool settings of the DB. Zero values keep the defaults of
+	// database/sql.
+	DBMaxOpenConns, DBMaxIdleConns       int
+	DBConnMaxLifetime, DBConnMaxIdleTime time.Duration
+
+	// DBSlowQueryThreshold, if non-zero, is the latency above which the plan
+	// of a query is logged.
+	DBSlowQueryThreshold time.Duration
+
+	// DBHLLPrecision is the precision of the estimated numbers of search
+	// results, from 4 to 18. If it is zero, postgres.DefaultHLLPrecision is
+	// used.
+	DBHLLPrecision int
+
 	// Configuration for redis page cache.
 	RedisCacheHost, RedisCachePort string
 
@@ -98,7 +132,43 @@ type Config struct {
 	// UseProfiler specifies whether to enable Stackdriver Profiler.
 	UseProfiler bool
 
+	// DisableSumVerification specifies whether the worker skips checking
+	// the hashes of fetched modules against the checksum database. It is
+	// meant for development against a private proxy.
+	DisableSumVerification bool
+
 	Quota QuotaSettings
+
+	// BadgeQuota is the quota for badge images, which is separate from Quota
+	// because badges are embedded in READMEs and so are requested far more
+	// often than pages.
+	BadgeQuota QuotaSettings
+
+	// SearchRateLimit and AutocompleteRateLimit limit the requests of each
+	// client to the search and autocomplete endpoints, which are expensive
+	// to serve.
+	SearchRateLimit       RateLimitSettings
+	AutocompleteRateLimit RateLimitSettings
+
+	// AllowedOrigins are the origins from which browsers may call the JSON
+	// API.
+	AllowedOrigins []string
+
+	// Tracing configures distributed tracing.
+	Tracing TracingConfig
+
+	// ServeMetrics specifies whether to serve metrics in the Prometheus
+	// format at /debug/metrics.
+	ServeMetrics bool
+
+	// AdminToken authorizes requests to the profiling handlers at
+	// /debug/pprof/. They are not served if it is empty.
+	AdminToken string
+
+	// SearchQueryLogKey is the secret key of the HMACs of the search queries
+	// recorded in the search_query_log table, so that the queries cannot be
+	// recovered from the log by hashing guesses.
+	SearchQueryLogKey string `json:"-"`
 }
 
 // AppVersionLabel returns the version label for the current instance.  This is
@@ -150,6 +220,16 @@ func (c *Config) DBSecondaryConnInfo() string {
 	return c.dbConnInfo(c.DBSecondaryHost)
 }
 
+// DBReplicaConnInfo returns a PostgreSQL connection string constructed from
+// environment variables, using the read replica host. It returns the empty
+// string if no replica is configured.
+func (c *Config) DBReplicaConnInfo() string {
+	if c.DBReplicaHost == "" {
+		return ""
+	}
+	return c.dbConnInfo(c.DBReplicaHost)
+}
+
 // dbConnInfo returns a PostgresSQL connection string for the given host.
 func (c *Config) dbConnInfo(host string) string {
 	// For the connection string syntax, see
@@ -184,6 +264,24 @@ type configOverride struct {
 	DBSecondaryHost string
 	DBName          string
 	Quota           QuotaSettings
+	BadgeQuota      QuotaSettings
+}
+
+// TracingConfig is config for internal/tracing.
+type TracingConfig struct {
+	// Endpoint is the host and port of the OTLP/HTTP collector that traces
+	// are exported to. Tracing is disabled if it is empty.
+	Endpoint string
+	// ServiceName identifies the service in traces.
+	ServiceName string
+	// SampleRate is the fraction of traces that are sampled, from 0 to 1.
+	SampleRate float64
+}
+
+// RateLimitSettings is config for internal/middleware/ratelimit.go.
+type RateLimitSettings struct {
+	RPS   float64 // allowed requests per second, per IP; zero means no limit
+	Burst int     // the size of the token bucket
 }
 
 // QuotaSettings is config for internal/middleware/quota.go
@@ -197,6 +295,9 @@ type QuotaSettings struct {
 	// AcceptedURLs is the list of URLs that will be ignored by the quota
 	// middleware.
 	AcceptedURLs []string
+	// ExcludedPathPrefixes is the list of URL path prefixes that are not
+	// subject to the quota, usually because they have a quota of their own.
+	ExcludedPathPrefixes []string
 }
 
 const overrideBucket = "go-discovery"
@@ -210,6 +311,7 @@ func Init(ctx context.Context) (_ *Config, err error) {
 	cfg := &Config{
 		IndexURL:  GetEnv("GO_MODULE_INDEX_URL", "https://index.golang.org/index"),
 		ProxyURL:  GetEnv("GO_MODULE_PROXY_URL", "https://proxy.golang.org"),
+		VulnDBURL: GetEnv("GO_DISCOVERY_VULNDB_URL", "https://vuln.go.dev"),
 		Port:      os.Getenv("PORT"),
 		DebugPort: os.Getenv("DEBUG_PORT"),
 		// Resolve AppEngine identifiers
@@ -228,9 +330,16 @@ func Init(ctx context.Context) (_ *Config, err error) {
 		DBUser:               GetEnv("GO_DISCOVERY_DATABASE_USER", "postgres"),
 		DBPassword:           os.Getenv("GO_DISCOVERY_DATABASE_PASSWORD"),
 		DBSecondaryHost:      chooseOne(os.Getenv("GO_DISCOVERY_DATABASE_SECONDARY_HOST")),
+		DBReplicaHost:        os.Getenv("GO_DISCOVERY_DATABASE_REPLICA_HOST"),
 		DBPort:               GetEnv("GO_DISCOVERY_DATABASE_PORT", "5432"),
 		DBName:               GetEnv("GO_DISCOVERY_DATABASE_NAME", "discovery-db"),
 		DBSecret:             os.Getenv("GO_DISCOVERY_DATABASE_SECRET"),
+		DBMaxOpenConns:       getEnvInt("GO_DISCOVERY_DATABASE_MAX_OPEN_CONNS", 0),
+		DBMaxIdleConns:       getEnvInt("GO_DISCOVERY_DATABASE_MAX_IDLE_CONNS", 0),
+		DBConnMaxLifetime:    getEnvDuration("GO_DISCOVERY_DATABASE_CONN_MAX_LIFETIME", 0),
+		DBConnMaxIdleTime:    getEnvDuration("GO_DISCOVERY_DATABASE_CONN_MAX_IDLE_TIME", 0),
+		DBSlowQueryThreshold: getEnvDuration("GO_DISCOVERY_DATABASE_SLOW_QUERY_THRESHOLD", 0),
+		DBHLLPrecision:       getEnvInt("GO_DISCOVERY_DATABASE_HLL_PRECISION", 0),
 		RedisCacheHost:       os.Getenv("GO_DISCOVERY_REDIS_HOST"),
 		RedisCachePort:       GetEnv("GO_DISCOVERY_REDIS_PORT", "6379"),
 		RedisHAHost:          os.Getenv("GO_DISCOVERY_REDIS_HA_HOST"),
@@ -241,8 +350,41 @@ func Init(ctx context.Context) (_ *Config, err error) {
 			MaxEntries:   1000,
 			RecordOnly:   func() *bool { t := true; return &t }(),
 			AcceptedURLs: parseCommaList(GetEnv("GO_DISCOVERY_ACCEPTED_LIST", "")),
+			// Badges are rate-limited by BadgeQuota.
+			ExcludedPathPrefixes: []string{"/badge/"},
+		},
+		BadgeQuota: QuotaSettings{
+			QPS:        100,
+			Burst:      200,
+			MaxEntries: 1000,
+			RecordOnly: func() *bool { t := true; return &t }(),
+		},
+		SearchRateLimit: RateLimitSettings{
+			RPS:   5,
+			Burst: 10,
+		},
+		// Autocomplete is requested as the user types, but a client
+		// that sends more than this is not a person typing.
+		AutocompleteRateLimit: RateLimitSettings{
+			RPS:   2,
+			Burst: 5,
+		},
+		AllowedOrigins:         parseCommaList(os.Getenv("GO_DISCOVERY_ALLOWED_ORIGINS")),
+		GitHubAPIURL:           GetEnv("GO_DISCOVERY_GITHUB_API_URL", "https://api.github.com"),
+		GitLabAPIURL:           GetEnv("GO_DISCOVERY_GITLAB_API_URL", "https://gitlab.com/api/v4"),
+		GitHubToken:            os.Getenv("GO_DISCOVERY_GITHUB_TOKEN"),
+		GitLabToken:            os.Getenv("GO_DISCOVERY_GITLAB_TOKEN"),
+		PrivateRepos:           parseCommaList(os.Getenv("GO_DISCOVERY_PRIVATE_REPOS")),
+		UseProfiler:            os.Getenv("GO_DISCOVERY_USE_PROFILER") == "TRUE",
+		DisableSumVerification: os.Getenv("GO_DISCOVERY_DISABLE_SUM_VERIFICATION") == "TRUE",
+		ServeMetrics:           os.Getenv("GO_DISCOVERY_SERVE_METRICS") == "TRUE",
+		AdminToken:             os.Getenv("GO_DISCOVERY_ADMIN_TOKEN"),
+		SearchQueryLogKey:      os.Getenv("GO_DISCOVERY_SEARCH_QUERY_LOG_KEY"),
+		Tracing: TracingConfig{
+			Endpoint:    os.Getenv("GO_DISCOVERY_OTLP_ENDPOINT"),
+			ServiceName: GetEnv("GO_DISCOVERY_TRACING_SERVICE_NAME", os.Getenv("GAE_SERVICE")),
+			SampleRate:  getEnvFloat("GO_DISCOVERY_TRACING_SAMPLE_RATE", 0.01),
 		},
-		UseProfiler: os.Getenv("GO_DISCOVERY_USE_PROFILER") == "TRUE",
 	}
 	cfg.AppMonitoredResource = &mrpb.MonitoredResource{
 		Type: "gae_app",
@@ -319,6 +461,10 @@ func processOverrides(cfg *Config, bytes []byte) {
 	overrideInt("Quota.Burst", &cfg.Quota.Burst, ov.Quota.Burst)
 	overrideInt("Quota.MaxEntries", &cfg.Quota.MaxEntries, ov.Quota.MaxEntries)
 	overrideBool("Quota.RecordOnly", &cfg.Quota.RecordOnly, ov.Quota.RecordOnly)
+	overrideInt("BadgeQuota.QPS", &cfg.BadgeQuota.QPS, ov.BadgeQuota.QPS)
+	overrideInt("BadgeQuota.Burst", &cfg.BadgeQuota.Burst, ov.BadgeQuota.Burst)
+	overrideInt("BadgeQuota.MaxEntries", &cfg.BadgeQuota.MaxEntries, ov.BadgeQuota.MaxEntries)
+	overrideBool("BadgeQuota.RecordOnly", &cfg.BadgeQuota.RecordOnly, ov.BadgeQuota.RecordOnly)
 }
 
 func overrideString(name string, field *string, val string) {
@@ -389,6 +535,33 @@ func gceMetadata(ctx context.Context, name string) (_ string, err error) {
 	return string(bytes), nil
 }
 
+// getEnvFloat returns the value of the environment variable key as a
+// float64, or fallback if it is unset or not a number.
+func getEnvFloat(key string, fallback float64) float64 {
+	if f, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil {
+		return f
+	}
+	return fallback
+}
+
+// getEnvInt returns the value of the environment variable key as an int, or
+// fallback if it is unset or not an integer.
+func getEnvInt(key string, fallback int) int {
+	if n, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return n
+	}
+	return fallback
+}
+
+// getEnvDuration returns the value of the environment variable key as a
+// time.Duration, like "30m", or fallback if it is unset or not a duration.
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return d
+	}
+	return fallback
+}
+
 func parseCommaList(s string) []string {
 	var a []string
 	for _, p := range strings.Split(s, ",") {
@@ -20,6 +20,8 @@ import (
 	"unicode"
 
 	"github.com/lib/pq"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
 	"golang.org/x/pkgsite/internal/derrors"
 	"golang.org/x/pkgsite/internal/log"
 )
@@ -36,10 +38,39 @@ type DB struct {
 	tx         *sql.Tx
 	mu         sync.Mutex
 	maxRetries int // max times a single transaction was retried
+
+	// ReplicaDB, if non-nil, is a connection pool to a read replica of the
+	// database. The queries of the DB returned by ReadOnly use it. It must be
+	// set before the DB is used.
+	ReplicaDB *sql.DB
+	readOnly  bool // queries use ReplicaDB, if it is set
+
+	stopStats   context.CancelFunc // stops recording pool stats; nil for transactions
+	connInfo    string             // connection string passed to Open; empty otherwise
+	slowQueries *slowQueryLog      // explains slow queries; nil if disabled
+}
+
+// DBConfig configures the connection pool of a DB. Zero fields leave the
+// corresponding setting of the sql.DB unchanged.
+type DBConfig struct {
+	MaxOpenConns    int           // maximum number of open connections
+	MaxIdleConns    int           // maximum number of idle connections
+	ConnMaxLifetime time.Duration // maximum time a connection may be reused
+	ConnMaxIdleTime time.Duration // maximum time a connection may be idle
+
+	// SlowQueryThreshold, if non-zero, is the latency above which the plan
+	// of a query is logged. See DB.Query.
+	SlowQueryThreshold time.Duration
+
+	// ReplicaInfo is the connection string of a read replica of the
+	// database. If it is empty, there is no replica. It is only used by Open.
+	ReplicaInfo string
 }
 
-// Open creates a new DB  for the given connection string.
-func Open(driverName, dbinfo, instanceID string) (_ *DB, err error) {
+// Open creates a new DB  for the given connection string, with the connection
+// pool configured by cfg. If cfg.ReplicaInfo is set, it also opens a
+// connection pool to the replica, configured the same way.
+func Open(driverName, dbinfo, instanceID string, cfg DBConfig) (_ *DB, err error) {
 	defer derrors.Wrap(&err, "database.Open(%q, %q)",
 		driverName, redactPassword(dbinfo))
 
@@ -50,7 +81,33 @@ func Open(driverName, dbinfo, instanceID string) (_ *DB, err error) {
 	if err := db.Ping(); err != nil {
 		return nil, err
 	}
-	return New(db, instanceID), nil
+	var replica *sql.DB
+	if cfg.ReplicaInfo != "" {
+		replica, err = openReplica(driverName, cfg.ReplicaInfo)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		configurePool(replica, cfg)
+	}
+	ddb := NewDB(db, instanceID, cfg)
+	ddb.ReplicaDB = replica
+	ddb.connInfo = dbinfo
+	return ddb, nil
+}
+
+func openReplica(driverName, dbinfo string) (_ *sql.DB, err error) {
+	defer derrors.Wrap(&err, "openReplica(%q, %q)", driverName, redactPassword(dbinfo))
+
+	db, err := sql.Open(driverName, dbinfo)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
 }
 
 // New creates a new DB from a sql.DB.
@@ -58,10 +115,187 @@ func New(db *sql.DB, instanceID string) *DB {
 	return &DB{db: db, instanceID: instanceID}
 }
 
+// NewDB creates a new DB from a sql.DB, configures its connection pool with
+// cfg, and starts recording the pool stats every poolStatsInterval until the
+// DB is closed.
+func NewDB(db *sql.DB, instanceID string, cfg DBConfig) *DB {
+	configurePool(db, cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	ddb := &DB{db: db, instanceID: instanceID, stopStats: cancel}
+	if cfg.SlowQueryThreshold > 0 {
+		ddb.slowQueries = newSlowQueryLog(cfg.SlowQueryThreshold)
+	}
+	go ddb.recordPoolStatsPeriodically(ctx)
+	return ddb
+}
+
+// configurePool applies the non-zero pool settings of cfg to db.
+func configurePool(db *sql.DB, cfg DBConfig) {
+	if cfg.MaxOpenConns != 0 {
+		db.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns != 0 {
+		db.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if cfg.ConnMaxLifetime != 0 {
+		db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	}
+	if cfg.ConnMaxIdleTime != 0 {
+		db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	}
+}
+
+const poolStatsInterval = 15 * time.Second
+
+var (
+	poolOpenConnections = stats.Int64(
+		"go-discovery/database/pool_open_connections",
+		"Number of open connections of the connection pool.",
+		stats.UnitDimensionless,
+	)
+	poolIdleConnections = stats.Int64(
+		"go-discovery/database/pool_idle_connections",
+		"Number of idle connections of the connection pool.",
+		stats.UnitDimensionless,
+	)
+	poolWaitCount = stats.Int64(
+		"go-discovery/database/pool_wait_count",
+		"Total number of connections waited for.",
+		stats.UnitDimensionless,
+	)
+	poolWaitDuration = stats.Float64(
+		"go-discovery/database/pool_wait_duration",
+		"Total time blocked waiting for a new connection.",
+		stats.UnitMilliseconds,
+	)
+
+	// PoolOpenConnections is a gauge of the number of open connections, in
+	// use or idle.
+	PoolOpenConnections = &view.View{
+		Name:        "go-discovery/database/pool_open_connections",
+		Measure:     poolOpenConnections,
+		Aggregation: view.LastValue(),
+		Description: "open DB connections",
+	}
+	// PoolIdleConnections is a gauge of the number of idle connections.
+	PoolIdleConnections = &view.View{
+		Name:        "go-discovery/database/pool_idle_connections",
+		Measure:     poolIdleConnections,
+		Aggregation: view.LastValue(),
+		Description: "idle DB connections",
+	}
+	// PoolWaitCount is a gauge of the total number of times a query waited
+	// for a connection.
+	PoolWaitCount = &view.View{
+		Name:        "go-discovery/database/pool_wait_count",
+		Measure:     poolWaitCount,
+		Aggregation: view.LastValue(),
+		Description: "total DB connections waited for",
+	}
+	// PoolWaitDuration is a gauge of the total time queries waited for a
+	// connection.
+	PoolWaitDuration = &view.View{
+		Name:        "go-discovery/database/pool_wait_duration",
+		Measure:     poolWaitDuration,
+		Aggregation: view.LastValue(),
+		Description: "total time waited for DB connections, in milliseconds",
+	}
+
+	// PoolViews are the views of the connection pool stats.
+	PoolViews = []*view.View{
+		PoolOpenConnections,
+		PoolIdleConnections,
+		PoolWaitCount,
+		PoolWaitDuration,
+	}
+)
+
+// recordPoolStatsPeriodically records the pool stats of db every
+// poolStatsInterval, until ctx is done.
+func (db *DB) recordPoolStatsPeriodically(ctx context.Context) {
+	ticker := time.NewTicker(poolStatsInterval)
+	defer ticker.Stop()
+	for {
+		db.recordPoolStats(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// recordPoolStats records the current stats of the connection pool of db.
+func (db *DB) recordPoolStats(ctx context.Context) {
+	s := db.db.Stats()
+	stats.Record(ctx,
+		poolOpenConnections.M(int64(s.OpenConnections)),
+		poolIdleConnections.M(int64(s.Idle)),
+		poolWaitCount.M(s.WaitCount),
+		poolWaitDuration.M(float64(s.WaitDuration)/float64(time.Millisecond)))
+}
+
 func (db *DB) InTransaction() bool {
 	return db.tx != nil
 }
 
+// Listen returns a pq.Listener that receives the notifications sent on
+// channel, such as by NOTIFY statements. The listener has its own connection
+// to the database, which it reopens if it is lost; notifications sent while
+// it is reconnecting are lost, and a nil notification is delivered when it
+// has reconnected. Listen can only be called on a DB returned by Open. The
+// listener must be closed.
+func (db *DB) Listen(ctx context.Context, channel string) (_ *pq.Listener, err error) {
+	defer derrors.Wrap(&err, "DB.Listen(%q)", channel)
+
+	if db.connInfo == "" {
+		return nil, errors.New("DB.Listen called on a DB not returned by Open")
+	}
+	l := pq.NewListener(db.connInfo, listenerMinReconnect, listenerMaxReconnect,
+		func(ev pq.ListenerEventType, err error) {
+			if err != nil {
+				log.Errorf(ctx, "listener on %q: event %d: %v", channel, ev, err)
+			}
+		})
+	if err := l.Listen(channel); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+const (
+	// listenerMinReconnect and listenerMaxReconnect bound the interval
+	// between the attempts of a listener to reconnect.
+	listenerMinReconnect = time.Second
+	listenerMaxReconnect = time.Minute
+)
+
+// ReadOnly returns a DB for read-only queries. Outside of a transaction, its
+// Query, QueryRow, RunQuery and Prepare methods use db.ReplicaDB, if it is
+// set. Since a replica may lag behind the primary, queries that must see the
+// writes just made should use db itself.
+func (db *DB) ReadOnly() *DB {
+	return &DB{
+		db:          db.db,
+		instanceID:  db.instanceID,
+		tx:          db.tx,
+		ReplicaDB:   db.ReplicaDB,
+		readOnly:    true,
+		connInfo:    db.connInfo,
+		slowQueries: db.slowQueries,
+	}
+}
+
+// queryDB returns the connection pool for queries outside of a transaction:
+// the replica for a read-only DB with one, and the primary otherwise.
+func (db *DB) queryDB() *sql.DB {
+	if db.readOnly && db.ReplicaDB != nil {
+		return db.ReplicaDB
+	}
+	return db.db
+}
+
 var passwordRegexp = regexp.MustCompile(`password=\S+`)
 
 func redactPassword(dbinfo string) string {
@@ -70,6 +304,15 @@ func redactPassword(dbinfo string) string {
 
 // Close closes the database connection.
 func (db *DB) Close() error {
+	if db.stopStats != nil {
+		db.stopStats()
+	}
+	if db.ReplicaDB != nil {
+		if err := db.ReplicaDB.Close(); err != nil {
+			db.db.Close()
+			return err
+		}
+	}
 	return db.db.Close()
 }
 
@@ -83,22 +326,26 @@ func (db *DB) Exec(ctx context.Context, query string, args ...interface{}) (res
 	return db.db.ExecContext(ctx, query, args...)
 }
 
-// Query runs the DB query.
+// Query runs the DB query. If the query takes longer than the
+// SlowQueryThreshold of the DB, its plan is logged.
 func (db *DB) Query(ctx context.Context, query string, args ...interface{}) (_ *sql.Rows, err error) {
 	defer logQuery(ctx, query, args, db.instanceID)(&err)
+	defer db.explainIfSlow(ctx, query, args, time.Now())
 	if db.tx != nil {
 		return db.tx.QueryContext(ctx, query, args...)
 	}
-	return db.db.QueryContext(ctx, query, args...)
+	return db.queryDB().QueryContext(ctx, query, args...)
 }
 
-// QueryRow runs the query and returns a single row.
+// QueryRow runs the query and returns a single row. Like Query, it logs the
+// plan of a slow query.
 func (db *DB) QueryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
 	defer logQuery(ctx, query, args, db.instanceID)(nil)
+	defer db.explainIfSlow(ctx, query, args, time.Now())
 	if db.tx != nil {
 		return db.tx.QueryRowContext(ctx, query, args...)
 	}
-	return db.db.QueryRowContext(ctx, query, args...)
+	return db.queryDB().QueryRowContext(ctx, query, args...)
 }
 
 func (db *DB) Prepare(ctx context.Context, query string) (*sql.Stmt, error) {
@@ -106,7 +353,7 @@ func (db *DB) Prepare(ctx context.Context, query string) (*sql.Stmt, error) {
 	if db.tx != nil {
 		return db.tx.PrepareContext(ctx, query)
 	}
-	return db.db.PrepareContext(ctx, query)
+	return db.queryDB().PrepareContext(ctx, query)
 }
 
 // RunQuery executes query, then calls f on each row.
@@ -118,6 +365,23 @@ func (db *DB) RunQuery(ctx context.Context, query string, f func(*sql.Rows) erro
 	return processRows(rows, f)
 }
 
+// RunStmt is like RunQuery, but executes the prepared statement stmt, whose
+// text is query. In a transaction, stmt is executed in the transaction. Like
+// Query, it logs the plan of a slow query.
+func (db *DB) RunStmt(ctx context.Context, stmt *sql.Stmt, query string, f func(*sql.Rows) error, params ...interface{}) (err error) {
+	defer logQuery(ctx, "executing prepared statement", params, db.instanceID)(&err)
+	if db.tx != nil {
+		stmt = db.tx.StmtContext(ctx, stmt)
+	}
+	start := time.Now()
+	rows, err := stmt.QueryContext(ctx, params...)
+	db.explainIfSlow(ctx, query, params, start)
+	if err != nil {
+		return err
+	}
+	return processRows(rows, f)
+}
+
 func processRows(rows *sql.Rows, f func(*sql.Rows) error) error {
 	defer rows.Close()
 	for rows.Next() {
@@ -152,7 +416,7 @@ func (db *DB) Transact(ctx context.Context, iso sql.IsolationLevel, txFunc func(
 // serializationFailureCode is the Postgres error code returned when a serializable
 // transaction fails because it would violate serializability.
 // See https://www.postgresql.org/docs/current/errcodes-appendix.html.
-const serializationFailureCode = "40001"
+const serializationFailureCode pq.ErrorCode = "40001"
 
 func (db *DB) transactWithRetry(ctx context.Context, opts *sql.TxOptions, txFunc func(*DB) error) (err error) {
 	defer derrors.Wrap(&err, "transactWithRetry(%v)", opts)
@@ -182,7 +446,12 @@ func (db *DB) transact(ctx context.Context, opts *sql.TxOptions, txFunc func(*DB
 	if db.InTransaction() {
 		return errors.New("a DB Transact function was called on a DB already in a transaction")
 	}
-	tx, err := db.db.BeginTx(ctx, opts)
+	// A transaction on a read-only DB is a read-only transaction on the
+	// replica, if there is one.
+	if db.readOnly {
+		opts.ReadOnly = true
+	}
+	tx, err := db.queryDB().BeginTx(ctx, opts)
 	if err != nil {
 		return fmt.Errorf("db.BeginTx(): %w", err)
 	}
@@ -219,7 +488,9 @@ const OnConflictDoNothing = "ON CONFLICT DO NOTHING"
 
 // BulkInsert constructs and executes a multi-value insert statement. The
 // query is constructed using the format:
-//   INSERT INTO <table> (<columns>) VALUES (<placeholders-for-each-item-in-values>)
+//
+//	INSERT INTO <table> (<columns>) VALUES (<placeholders-for-each-item-in-values>)
+//
 // If conflictAction is not empty, it is appended to the statement.
 //
 // The query is executed using a PREPARE statement with the provided values.
@@ -376,7 +647,8 @@ var maxBulkUpdateArrayLen = 10000
 // the values of the first column.
 //
 // Types holds the database type of each column. For example,
-//    []string{"INT", "TEXT"}
+//
+//	[]string{"INT", "TEXT"}
 //
 // Values contains one slice of values per column. (Note that this is unlike BulkInsert, which
 // takes a single slice of interleaved values.)
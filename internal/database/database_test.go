@@ -13,21 +13,24 @@ import (
 	"os"
 	"sort"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"go.opencensus.io/stats/view"
 	"golang.org/x/pkgsite/internal/derrors"
 	"golang.org/x/pkgsite/internal/testing/dbtest"
 )
 
-const testTimeout = 5 * time.Second
+const (
+	testTimeout = 5 * time.Second
+	dbName      = "discovery_postgres_test"
+)
 
 var testDB *DB
 
 func TestMain(m *testing.M) {
-	const dbName = "discovery_postgres_test"
-
 	if err := dbtest.CreateDBIfNotExists(dbName); err != nil {
 		if errors.Is(err, derrors.NotFound) && os.Getenv("GO_DISCOVERY_TESTDB") != "true" {
 			log.Printf("SKIPPING: could not connect to DB (see doc/postgres.md to set up): %v", err)
@@ -36,7 +39,7 @@ func TestMain(m *testing.M) {
 		log.Fatal(err)
 	}
 	var err error
-	testDB, err = Open("postgres", dbtest.DBConnURI(dbName), "test")
+	testDB, err = Open("postgres", dbtest.DBConnURI(dbName), "test", DBConfig{})
 	if err != nil {
 		log.Fatalf("Open: %v %[1]T", err)
 	}
@@ -439,3 +442,111 @@ func TestTransactSerializable(t *testing.T) {
 	}
 
 }
+
+func TestDBPoolMetrics(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	if err := view.Register(PoolViews...); err != nil {
+		t.Fatal(err)
+	}
+	defer view.Unregister(PoolViews...)
+
+	sdb, err := sql.Open("postgres", dbtest.DBConnURI(dbName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// With fewer connections than concurrent queries, some queries wait.
+	db := NewDB(sdb, "test", DBConfig{MaxOpenConns: 2, MaxIdleConns: 2})
+	defer db.Close()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := db.Exec(ctx, `SELECT pg_sleep(0.05)`); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	// Record the stats now, rather than waiting for the next tick.
+	db.recordPoolStats(ctx)
+
+	for _, v := range PoolViews {
+		rows, err := view.RetrieveData(v.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 1 {
+			t.Errorf("%s: got %d rows, want 1", v.Name, len(rows))
+			continue
+		}
+		if got := rows[0].Data.(*view.LastValueData).Value; got <= 0 {
+			t.Errorf("%s = %v, want a positive value", v.Name, got)
+		}
+	}
+}
+
+func TestReadReplica(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	// Both pools connect to the test database. Which one a statement used is
+	// told by their open connections.
+	open := func() *sql.DB {
+		sdb, err := sql.Open("postgres", dbtest.DBConnURI(dbName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sdb
+	}
+	primary, replica := open(), open()
+	db := New(primary, "test")
+	db.ReplicaDB = replica
+	defer db.Close()
+
+	check := func(name string, wantPrimary, wantReplica int) {
+		t.Helper()
+		if got := primary.Stats().OpenConnections; got != wantPrimary {
+			t.Errorf("%s: primary has %d open connections, want %d", name, got, wantPrimary)
+		}
+		if got := replica.Stats().OpenConnections; got != wantReplica {
+			t.Errorf("%s: replica has %d open connections, want %d", name, got, wantReplica)
+		}
+	}
+
+	ro := db.ReadOnly()
+	var n int
+	if err := ro.QueryRow(ctx, `SELECT 1`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if err := ro.RunQuery(ctx, `SELECT 1`, func(rows *sql.Rows) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	check("read-only queries", 0, 1)
+
+	if _, err := ro.Exec(ctx, `SELECT 1`); err != nil {
+		t.Fatal(err)
+	}
+	check("read-only Exec", 1, 1)
+
+	// Queries of the DB itself, and of transactions, use the primary.
+	if err := db.QueryRow(ctx, `SELECT 1`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Transact(ctx, sql.LevelDefault, func(tx *DB) error {
+		return tx.ReadOnly().QueryRow(ctx, `SELECT 1`).Scan(&n)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	check("primary queries", 1, 1)
+
+	// Without a replica, read-only queries use the primary.
+	db.ReplicaDB = nil
+	if err := db.ReadOnly().QueryRow(ctx, `SELECT 1`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	check("no replica", 1, 1)
+	db.ReplicaDB = replica
+}
@@ -0,0 +1,65 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"golang.org/x/pkgsite/internal/derrors"
+
+	// imported to register the file source migration driver
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+)
+
+// Migrate applies the migrations in dir that have not been applied to the
+// Postgres database db. The migrations are numbered pairs of files, like
+// those of the migrations directory at the repo root: NNNNNN_name.up.sql
+// applies a change to the schema, and NNNNNN_name.down.sql undoes it. The
+// version of the last applied migration is recorded in the schema_migrations
+// table.
+//
+// Concurrent calls for the same database are serialized with an advisory
+// lock. Migrate closes db when it returns, so db should be opened for it
+// rather than shared.
+func Migrate(db *sql.DB, dir string) (err error) {
+	defer derrors.Wrap(&err, "Migrate(%q)", dir)
+
+	m, err := newMigrate(db, dir)
+	if err != nil {
+		db.Close()
+		return err
+	}
+	defer func() {
+		if srcErr, dbErr := m.Close(); err == nil {
+			if srcErr != nil {
+				err = srcErr
+			} else {
+				err = dbErr
+			}
+		}
+	}()
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return nil
+}
+
+// newMigrate returns a Migrate that applies the migrations in dir to db, and
+// that closes db when it is closed.
+func newMigrate(db *sql.DB, dir string) (*migrate.Migrate, error) {
+	driver, err := postgres.WithInstance(db, &postgres.Config{})
+	if err != nil {
+		return nil, err
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	return migrate.NewWithDatabaseInstance("file://"+filepath.ToSlash(abs), "postgres", driver)
+}
@@ -0,0 +1,198 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal/testing/dbtest"
+)
+
+// migrationsDir is the directory of the migrations, relative to this package.
+var migrationsDir = filepath.Join("..", "..", "migrations")
+
+// wantTables are the tables of the schema after all migrations.
+var wantTables = []string{
+	"alternative_module_paths",
+	"documentation",
+	"examples",
+	"excluded_prefixes",
+	"experiments",
+	"implements",
+	"imported_by_count_snapshots",
+	"imports",
+	"imports_unique",
+	"imports_unique_changelog",
+	"licenses",
+	"module_events",
+	"module_files",
+	"module_index_queue",
+	"module_links",
+	"module_version_states",
+	"modules",
+	"package_imports",
+	"package_symbols",
+	"package_version_states",
+	"packages",
+	"paths",
+	"readmes",
+	"schema_migrations",
+	"search_documents",
+	"search_query_log",
+	"symbol_definitions",
+	"symbol_search_documents",
+	"trending_stats",
+	"version_map",
+	"vuln_reports",
+	"workspaces",
+}
+
+func TestMigrate(t *testing.T) {
+	const dbName = "discovery_migrate_test"
+	createFreshDB(t, dbName)
+
+	// Migrating twice is the same as migrating once.
+	for i := 0; i < 2; i++ {
+		if err := Migrate(openDB(t, dbName), migrationsDir); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	db := openDB(t, dbName)
+	defer db.Close()
+	if diff := cmp.Diff(wantTables, tableNames(t, db)); diff != "" {
+		t.Errorf("tables mismatch (-want +got):\n%s", diff)
+	}
+	var (
+		version int
+		dirty   bool
+	)
+	if err := db.QueryRow(`SELECT version, dirty FROM schema_migrations`).Scan(&version, &dirty); err != nil {
+		t.Fatal(err)
+	}
+	if want := latestMigration(t); version != want || dirty {
+		t.Errorf("got version %d, dirty %t; want version %d, not dirty", version, dirty, want)
+	}
+}
+
+// TestMigrateDownUp checks that each migration can be rolled back, and
+// applied again after it has been rolled back.
+func TestMigrateDownUp(t *testing.T) {
+	const dbName = "discovery_migrate_down_up_test"
+	createFreshDB(t, dbName)
+
+	m, err := newMigrate(openDB(t, dbName), migrationsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		version, _, err := m.Version()
+		if errors.Is(err, migrate.ErrNilVersion) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Roll back the migration, apply it again, and roll it back to
+		// go on to the previous one.
+		for _, n := range []int{-1, 1, -1} {
+			if err := m.Steps(n); err != nil {
+				t.Fatalf("migration %d: Steps(%d): %v", version, n, err)
+			}
+		}
+	}
+	if err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	db := openDB(t, dbName)
+	defer db.Close()
+	if diff := cmp.Diff(wantTables, tableNames(t, db)); diff != "" {
+		t.Errorf("tables mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// createFreshDB drops the database dbName, if it exists, and creates it.
+func createFreshDB(t *testing.T, dbName string) {
+	t.Helper()
+	if err := dbtest.ConnectAndExecute(dbtest.DBConnURI(""), func(pg *sql.DB) error {
+		if _, err := pg.Exec(fmt.Sprintf("DROP DATABASE IF EXISTS %q;", dbName)); err != nil {
+			return err
+		}
+		_, err := pg.Exec(fmt.Sprintf("CREATE DATABASE %q;", dbName))
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func openDB(t *testing.T, dbName string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("postgres", dbtest.DBConnURI(dbName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// tableNames returns the sorted names of the tables of the public schema.
+func tableNames(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+	rows, err := db.Query(`
+		SELECT table_name
+		FROM information_schema.tables
+		WHERE table_schema = 'public' AND table_type = 'BASE TABLE'
+		ORDER BY table_name`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+// latestMigration returns the number of the last migration in migrationsDir.
+func latestMigration(t *testing.T) int {
+	t.Helper()
+	infos, err := ioutil.ReadDir(migrationsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest := 0
+	for _, info := range infos {
+		prefix := strings.SplitN(info.Name(), "_", 2)[0]
+		n, err := strconv.Atoi(prefix)
+		if err != nil {
+			t.Fatalf("bad migration file name %q", info.Name())
+		}
+		if n > latest {
+			latest = n
+		}
+	}
+	return latest
+}
@@ -0,0 +1,64 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"syscall"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// transientErrorCodes are the Postgres error codes of failures that may not
+// happen again if the operation is retried.
+// See https://www.postgresql.org/docs/current/errcodes-appendix.html.
+var transientErrorCodes = map[pq.ErrorCode]bool{
+	serializationFailureCode: true, // serialization_failure
+	"40P01":                  true, // deadlock_detected
+	"55P03":                  true, // lock_not_available, as for a lock timeout
+	"57P01":                  true, // admin_shutdown
+	"57P02":                  true, // crash_shutdown
+	"57P03":                  true, // cannot_connect_now
+}
+
+// IsTransientError reports whether err is a database error that may not
+// happen again if the operation that caused it is retried, like a
+// serialization failure, a lock timeout, or a lost connection.
+func IsTransientError(err error) bool {
+	var perr *pq.Error
+	if errors.As(err, &perr) {
+		// Class 08 is that of the connection exceptions.
+		return transientErrorCodes[perr.Code] || perr.Code.Class() == "08"
+	}
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// RetryWithBackoff calls f until it succeeds, up to maxAttempts times, as
+// long as it fails with transient errors (see IsTransientError). It waits
+// base before the second attempt, and twice as long before each following
+// one. It returns the error of the last attempt, or ctx.Err() if ctx is done
+// while it is waiting.
+func RetryWithBackoff(ctx context.Context, maxAttempts int, base time.Duration, f func() error) error {
+	wait := base
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil || !IsTransientError(err) {
+			return err
+		}
+		if attempt >= maxAttempts {
+			return fmt.Errorf("after %d attempts: %w", attempt, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
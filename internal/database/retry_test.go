@@ -0,0 +1,101 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+func TestIsTransientError(t *testing.T) {
+	for _, test := range []struct {
+		err  error
+		want bool
+	}{
+		{&pq.Error{Code: "40001"}, true},
+		{&pq.Error{Code: "57P01"}, true},
+		{&pq.Error{Code: "08006"}, true},
+		{fmt.Errorf("tx.Commit(): %w", &pq.Error{Code: "40P01"}), true},
+		{driver.ErrBadConn, true},
+		{&pq.Error{Code: "23505"}, false}, // unique_violation
+		{derrors.NotFound, false},
+		{nil, false},
+	} {
+		if got := IsTransientError(test.err); got != test.want {
+			t.Errorf("IsTransientError(%v) = %t, want %t", test.err, got, test.want)
+		}
+	}
+}
+
+func TestRetryWithBackoff(t *testing.T) {
+	ctx := context.Background()
+	transient := &pq.Error{Code: "40001"}
+	const base = time.Millisecond
+
+	t.Run("succeeds on the third attempt", func(t *testing.T) {
+		calls := 0
+		err := RetryWithBackoff(ctx, 5, base, func() error {
+			calls++
+			if calls < 3 {
+				return transient
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if calls != 3 {
+			t.Errorf("got %d calls, want 3", calls)
+		}
+	})
+	t.Run("does not retry non-transient errors", func(t *testing.T) {
+		calls := 0
+		err := RetryWithBackoff(ctx, 5, base, func() error {
+			calls++
+			return derrors.InvalidArgument
+		})
+		if !errors.Is(err, derrors.InvalidArgument) {
+			t.Errorf("got %v, want %v", err, derrors.InvalidArgument)
+		}
+		if calls != 1 {
+			t.Errorf("got %d calls, want 1", calls)
+		}
+	})
+	t.Run("gives up after maxAttempts", func(t *testing.T) {
+		calls := 0
+		err := RetryWithBackoff(ctx, 3, base, func() error {
+			calls++
+			return transient
+		})
+		if !errors.Is(err, transient) {
+			t.Errorf("got %v, want %v", err, transient)
+		}
+		if calls != 3 {
+			t.Errorf("got %d calls, want 3", calls)
+		}
+	})
+	t.Run("stops when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		calls := 0
+		err := RetryWithBackoff(ctx, 5, time.Hour, func() error {
+			calls++
+			cancel()
+			return transient
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("got %v, want %v", err, context.Canceled)
+		}
+		if calls != 1 {
+			t.Errorf("got %d calls, want 1", calls)
+		}
+	})
+}
@@ -0,0 +1,100 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/log"
+	"golang.org/x/pkgsite/internal/xcontext"
+)
+
+const (
+	// explainInterval is the minimum time between two explanations of the
+	// same query, so that slow queries do not add much load to the database.
+	explainInterval = 60 * time.Second
+
+	// explainTimeout bounds the time to explain a query.
+	explainTimeout = time.Minute
+)
+
+// A slowQueryLog decides which slow queries to explain.
+type slowQueryLog struct {
+	threshold time.Duration
+
+	mu            sync.Mutex
+	lastExplained map[string]time.Time // by query
+}
+
+func newSlowQueryLog(threshold time.Duration) *slowQueryLog {
+	return &slowQueryLog{
+		threshold:     threshold,
+		lastExplained: map[string]time.Time{},
+	}
+}
+
+// allow reports whether query may be explained at now, and if so records it.
+// A query is explained at most once every explainInterval.
+func (l *slowQueryLog) allow(query string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if last, ok := l.lastExplained[query]; ok && now.Sub(last) < explainInterval {
+		return false
+	}
+	// Forget the queries that may be explained again, so that queries
+	// that vary, like those with embedded filters, do not accumulate.
+	for q, last := range l.lastExplained {
+		if now.Sub(last) >= explainInterval {
+			delete(l.lastExplained, q)
+		}
+	}
+	l.lastExplained[query] = now
+	return true
+}
+
+// explainIfSlow logs the plan of query if it started longer than the
+// SlowQueryThreshold of db ago. The query is run again with EXPLAIN ANALYZE
+// in the background, in a read-only transaction that is rolled back, so that
+// it has no effect. Queries in a transaction are not explained.
+func (db *DB) explainIfSlow(ctx context.Context, query string, args []interface{}, start time.Time) {
+	if db.slowQueries == nil || db.tx != nil {
+		return
+	}
+	latency := time.Since(start)
+	if latency <= db.slowQueries.threshold || !db.slowQueries.allow(query, time.Now()) {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(xcontext.Detach(ctx), explainTimeout)
+		defer cancel()
+		plan, err := db.explainAnalyze(ctx, query, args)
+		if err != nil {
+			log.Errorf(ctx, "slow query (%s): %v", latency, err)
+			return
+		}
+		log.Warningf(ctx, "slow query (%s): %s\nplan: %s", latency, query, plan)
+	}()
+}
+
+// explainAnalyze returns the JSON plan of query, as reported by EXPLAIN
+// ANALYZE.
+func (db *DB) explainAnalyze(ctx context.Context, query string, args []interface{}) (_ string, err error) {
+	defer derrors.Wrap(&err, "explainAnalyze(ctx, %q)", query)
+
+	tx, err := db.queryDB().BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+	var plan string
+	if err := tx.QueryRowContext(ctx, "EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) "+query, args...).Scan(&plan); err != nil {
+		return "", err
+	}
+	return plan, nil
+}
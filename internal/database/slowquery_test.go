@@ -0,0 +1,86 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer that can be written and read concurrently.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestSlowQueryLogging(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	// Capture the output of the logger, which writes with the standard
+	// library's log package.
+	var buf syncBuffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	db := &DB{db: testDB.db, instanceID: "test", slowQueries: newSlowQueryLog(time.Nanosecond)}
+	var n int
+	if err := db.QueryRow(ctx, "SELECT $1::int + 1", 1).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	// The query is explained in the background.
+	for !strings.Contains(buf.String(), `"Plan"`) {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("no query plan in the log:\n%s", buf.String())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	got := buf.String()
+	for _, want := range []string{"Warning", "slow query", "SELECT $1::int + 1", `"Execution Time"`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("log does not contain %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestSlowQueryLogAllow(t *testing.T) {
+	l := newSlowQueryLog(time.Second)
+	now := time.Now()
+	for _, test := range []struct {
+		query string
+		at    time.Duration
+		want  bool
+	}{
+		{"q1", 0, true},
+		{"q1", time.Second, false},
+		{"q2", time.Second, true},
+		{"q1", explainInterval - time.Second, false},
+		{"q1", explainInterval, true},
+		{"q2", explainInterval, false},
+	} {
+		if got := l.allow(test.query, now.Add(test.at)); got != test.want {
+			t.Errorf("allow(%q) at %s = %t, want %t", test.query, test.at, got, test.want)
+		}
+	}
+}
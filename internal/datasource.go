@@ -6,6 +6,7 @@ package internal
 
 import (
 	"context"
+	"time"
 
 	"golang.org/x/pkgsite/internal/licenses"
 )
@@ -21,8 +22,76 @@ type DataSource interface {
 	// GetImports returns a slice of import paths imported by the package
 	// specified by path and version.
 	GetImports(ctx context.Context, pkgPath, modulePath, version string) ([]string, error)
+	// GetModuleDependencies returns the modules whose packages are imported,
+	// directly or indirectly, by the packages of the module version specified
+	// by modulePath and version.
+	GetModuleDependencies(ctx context.Context, modulePath, version string) ([]*ModuleDependency, error)
+	// GetPackageSymbols returns the exported symbols of the package with the
+	// given path at the given version, ordered by name.
+	GetPackageSymbols(ctx context.Context, pkgPath, version string) ([]*Symbol, error)
+	// GetExamples returns the examples of the package with the given path at
+	// the given version, ordered by name.
+	GetExamples(ctx context.Context, pkgPath, version string) ([]*Example, error)
+	// GetExportedSymbolCount returns the number of exported symbols of the
+	// package with the given path at the given version, including methods.
+	GetExportedSymbolCount(ctx context.Context, pkgPath, version string) (int, error)
+	// GetBuildContexts returns the major build contexts that the package
+	// with the given path at the given version builds for, or nil if they
+	// are unknown.
+	GetBuildContexts(ctx context.Context, pkgPath, version string) ([]BuildContext, error)
+	// GetEmbeddedPatterns returns the patterns of the //go:embed directives
+	// of the package with the given path at the given version.
+	GetEmbeddedPatterns(ctx context.Context, pkgPath, version string) ([]string, error)
+	// GetHasFuzzTests reports whether the package with the given path at the
+	// given version has a fuzz target.
+	GetHasFuzzTests(ctx context.Context, pkgPath, version string) (bool, error)
+	// GetBenchmarkCount returns the number of benchmarks of the package with
+	// the given path at the given version.
+	GetBenchmarkCount(ctx context.Context, pkgPath, version string) (int, error)
+	// GetContributorCount returns the number of contributors of the
+	// repository of the module with the given path and version.
+	GetContributorCount(ctx context.Context, modulePath, version string) (int, error)
+	// GetSuccessorPackages returns the paths of the known packages that are
+	// mentioned in the deprecation message of the package with the given
+	// path at the given version.
+	GetSuccessorPackages(ctx context.Context, pkgPath, version string) ([]string, error)
+	// GetPackagePathsForSitemap returns up to limit paths of redistributable
+	// packages to list in the sitemap, starting after the first offset paths.
+	GetPackagePathsForSitemap(ctx context.Context, offset, limit int) ([]string, error)
 	// GetPathInfo returns information about a path.
 	GetPathInfo(ctx context.Context, path, inModulePath, inVersion string) (outModulePath, outVersion string, isPackage bool, err error)
+	// GetSymbolDefinition returns the location of the declaration of the
+	// exported symbol symbolName in the latest version of the package with
+	// path pkgPath.
+	GetSymbolDefinition(ctx context.Context, pkgPath, symbolName string) (*SymbolLocation, error)
+	// GetInterfaceImplementations returns the exported types of the latest
+	// versions of modules that implement the interface ifaceName of the
+	// package ifacePkg.
+	GetInterfaceImplementations(ctx context.Context, ifacePkg, ifaceName string) ([]*TypeRef, error)
+	// GetSearchSuggestions returns up to limit packages whose path, a path
+	// element, or name starts with prefix, best suggestion first.
+	GetSearchSuggestions(ctx context.Context, prefix string, limit int) ([]*SearchSuggestion, error)
+	// GetVulnerabilities returns the reports of the known vulnerabilities
+	// that affect the given version of the module.
+	GetVulnerabilities(ctx context.Context, modulePath, version string) ([]*VulnReport, error)
+	// GetRetractedVersions returns the versions of the module that are
+	// retracted by the go.mod file of the version itself or of a newer one.
+	GetRetractedVersions(ctx context.Context, modulePath string) ([]string, error)
+	// GetWorkspace returns the workspace declared by the go.work file at the
+	// root of the repository repoPath.
+	GetWorkspace(ctx context.Context, repoPath string) (*Workspace, error)
+	// GetModuleLinks returns the links to the issue tracker and the CI
+	// service of the repository of the latest version of the module.
+	GetModuleLinks(ctx context.Context, modulePath string) (*ModuleLinks, error)
+	// GetModuleFiles returns the file at subpath in the given module
+	// version, or the files and subdirectories of the directory at subpath.
+	GetModuleFiles(ctx context.Context, modulePath, version, subpath string) ([]*FileInfo, error)
+	// GetRecentlyIndexedModules returns up to limit module versions that
+	// were added to the database after since, most recent first.
+	GetRecentlyIndexedModules(ctx context.Context, limit int, since time.Time) ([]*IndexedModule, error)
+	// GetSitemapPackageCount returns the number of package paths that
+	// GetPackagePathsForSitemap can return.
+	GetSitemapPackageCount(ctx context.Context) (int, error)
 	// GetPseudoVersionsForModule returns LegacyModuleInfo for all known
 	// pseudo-versions for the module corresponding to modulePath.
 	GetPseudoVersionsForModule(ctx context.Context, modulePath string) ([]*ModuleInfo, error)
@@ -51,6 +120,11 @@ type DataSource interface {
 	// pkgPath, modulePath, and version. When multiple package paths satisfy this query, it
 	// should prefer the module with the longest path.
 	LegacyGetPackage(ctx context.Context, pkgPath, modulePath, version string) (*LegacyVersionedPackage, error)
+	// LegacyGetPackages returns the LegacyVersionedPackages with the given
+	// paths in the module version specified by modulePath and version. The
+	// result is in the same order as pkgPaths, with nil for paths that are
+	// not found.
+	LegacyGetPackages(ctx context.Context, pkgPaths []string, modulePath, version string) ([]*LegacyVersionedPackage, error)
 	// LegacyGetPackageLicenses returns all Licenses that apply to pkgPath, within the
 	// module version specified by modulePath and version.
 	LegacyGetPackageLicenses(ctx context.Context, pkgPath, modulePath, version string) ([]*licenses.License, error)
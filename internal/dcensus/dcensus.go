@@ -22,6 +22,7 @@ import (
 	"golang.org/x/pkgsite/internal/config"
 	"golang.org/x/pkgsite/internal/derrors"
 	"golang.org/x/pkgsite/internal/log"
+	"golang.org/x/pkgsite/internal/tracing"
 	mrpb "google.golang.org/genproto/googleapis/api/monitoredres"
 )
 
@@ -58,6 +59,7 @@ func NewRouter(tagger RouteTagger) *Router {
 func (r *Router) Handle(route string, handler http.Handler) {
 	r.mux.HandleFunc(route, func(w http.ResponseWriter, req *http.Request) {
 		tag := r.tagger(route, req)
+		tracing.SetRoute(req.Context(), route)
 		ochttp.WithRouteTag(handler, tag).ServeHTTP(w, req)
 	})
 }
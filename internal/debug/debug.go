@@ -0,0 +1,49 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package debug serves profiling information for administrators.
+//
+// Importing this package imports net/http/pprof, which registers its handlers
+// on http.DefaultServeMux without authentication. Programs that import it must
+// not serve http.DefaultServeMux.
+package debug
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+)
+
+// RegisterDebugHandlers registers the net/http/pprof handlers on mux, under
+// /debug/pprof/. Requests to them must have an Authorization header of the
+// form "Bearer <adminToken>"; other requests are served a 401
+// (Unauthorized). If adminToken is empty, all requests are unauthorized.
+func RegisterDebugHandlers(mux *http.ServeMux, adminToken string) {
+	handle := func(pattern string, h http.HandlerFunc) {
+		mux.Handle(pattern, requireToken(adminToken, h))
+	}
+	// pprof.Index also serves the named profiles, like /debug/pprof/heap.
+	handle("/debug/pprof/", pprof.Index)
+	handle("/debug/pprof/cmdline", pprof.Cmdline)
+	handle("/debug/pprof/profile", pprof.Profile)
+	handle("/debug/pprof/symbol", pprof.Symbol)
+	handle("/debug/pprof/trace", pprof.Trace)
+}
+
+// requireToken returns a handler that serves requests authorized by token
+// with h, and others with a 401.
+func requireToken(token string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const prefix = "Bearer "
+		auth := r.Header.Get("Authorization")
+		if token == "" || !strings.HasPrefix(auth, prefix) ||
+			subtle.ConstantTimeCompare([]byte(auth[len(prefix):]), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
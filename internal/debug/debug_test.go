@@ -0,0 +1,38 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRegisterDebugHandlers(t *testing.T) {
+	for _, test := range []struct {
+		name, adminToken, auth string
+		want                   int
+	}{
+		{"no header", "secret", "", http.StatusUnauthorized},
+		{"wrong token", "secret", "Bearer wrong", http.StatusUnauthorized},
+		{"not bearer", "secret", "Basic secret", http.StatusUnauthorized},
+		{"no admin token", "", "Bearer ", http.StatusUnauthorized},
+		{"authorized", "secret", "Bearer secret", http.StatusOK},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			RegisterDebugHandlers(mux, test.adminToken)
+			r := httptest.NewRequest("GET", "/debug/pprof/heap", nil)
+			if test.auth != "" {
+				r.Header.Set("Authorization", test.auth)
+			}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+			if w.Code != test.want {
+				t.Errorf("got status %d, want %d", w.Code, test.want)
+			}
+		})
+	}
+}
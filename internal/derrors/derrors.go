@@ -33,6 +33,15 @@ var (
 	// from the path specified in the go.mod file.
 	AlternativeModule = errors.New("alternative module")
 
+	// HashMismatch indicates that the hash of a module zip does not match
+	// the one in the checksum database, or that the checksum database could
+	// not prove it.
+	HashMismatch = errors.New("hash mismatch")
+
+	// ProxyUnavailable indicates that the module proxy was not called,
+	// because recent calls to it failed (HTTP 503).
+	ProxyUnavailable = errors.New("proxy unavailable")
+
 	// Unknown indicates that the error has unknown semantics.
 	Unknown = errors.New("unknown")
 
@@ -65,6 +74,10 @@ var (
 	// postgres.InsertModule.
 	DBModuleInsertInvalid = errors.New("db module insert invalid")
 
+	// ModuleLocked indicates that a module version could not be inserted
+	// because another process holds its lock, and is inserting it.
+	ModuleLocked = errors.New("module locked")
+
 	// ReprocessStatusOK indicates that the module to be reprocessed
 	// previously had a status of http.StatusOK.
 	ReprocessStatusOK = errors.New("reprocess status ok")
@@ -86,12 +99,15 @@ var httpCodes = []struct {
 	{NotFound, http.StatusNotFound},
 	{InvalidArgument, http.StatusBadRequest},
 	{Excluded, http.StatusForbidden},
+	{ModuleLocked, http.StatusConflict},
+	{ProxyUnavailable, http.StatusServiceUnavailable},
 
 	// Since the following aren't HTTP statuses, pick unused codes.
 	{HasIncompletePackages, 290},
 	{DBModuleInsertInvalid, 480},
 	{BadModule, 490},
 	{AlternativeModule, 491},
+	{HashMismatch, 492},
 
 	// 52x errors represents modules that need to be reprocessed, and the
 	// previous status code the module had. Note that the status code
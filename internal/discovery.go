@@ -37,7 +37,9 @@ type ModuleInfo struct {
 	CommitTime        time.Time
 	VersionType       version.Type
 	IsRedistributable bool
-	HasGoMod          bool // whether the module zip has a go.mod file
+	HasGoMod          bool   // whether the module zip has a go.mod file
+	IsRetracted       bool   // whether the module's go.mod file retracts this version
+	ToolchainVersion  string // the toolchain directive of the module's go.mod file, like "go1.21.3"
 	SourceInfo        *source.Info
 }
 
@@ -99,10 +101,72 @@ type Module struct {
 	// that may be contained in nested subdirectories.
 	Licenses    []*licenses.License
 	Directories []*DirectoryNew
+	// RetractedVersions are the versions retracted by the module's go.mod
+	// file, as single versions or as intervals of the form "[low, high]".
+	RetractedVersions []string
+	// Workspace is the workspace declared by the go.work file at the root of
+	// the module, if any.
+	Workspace *Workspace
+	// Files are the files of the module zip, sorted by name.
+	Files []*FileInfo
+	// Links are the links to the issue tracker and the CI status of the
+	// repository of the module, if its host is known.
+	Links *ModuleLinks
+	// Implementations records which exported types of the module's packages
+	// implement exported interfaces of those packages or of the standard
+	// library packages they import.
+	Implementations []*Implementation
+	// ZipHash is the "h1:" hash of the module zip, as it appears in go.sum
+	// files. It is not stored in the database.
+	ZipHash string
 
 	LegacyPackages []*LegacyPackage
 }
 
+// ModuleLinks are links to the services used to develop a module.
+type ModuleLinks struct {
+	// IssuesURL is the URL of the issue tracker of the repository.
+	IssuesURL string
+	// CIURL is the URL of the build status of the repository on its
+	// continuous integration service. It is empty if the repository has
+	// none.
+	CIURL string
+}
+
+// A ModuleDependency is a module that provides packages imported, directly or
+// indirectly, by the packages of another module.
+type ModuleDependency struct {
+	ModulePath string
+	Version    string
+	// IsIndirect reports whether the module is only imported through other
+	// dependencies.
+	IsIndirect bool
+	// Depth is the length of the shortest chain of imports from the other
+	// module to this one. It is 1 for direct dependencies.
+	Depth int
+	// ImportedBy are the module versions, as "path@version", whose packages
+	// import packages of this module: the other module or its dependencies.
+	// They are sorted.
+	ImportedBy []string
+}
+
+// A LicenseConflict is a pair of incompatible licenses in the import graph of
+// a package, each with the package that introduced it.
+type LicenseConflict struct {
+	// License and OtherLicense are SPDX identifiers.
+	License      string
+	Package      string
+	OtherLicense string
+	OtherPackage string
+}
+
+// An IndexedModule is a module version along with the time it was added to
+// the database.
+type IndexedModule struct {
+	ModuleInfo
+	IndexedAt time.Time
+}
+
 // VersionedDirectory is a DirectoryNew along with its corresponding module
 // information.
 type VersionedDirectory struct {
@@ -135,6 +199,35 @@ type PackageNew struct {
 	Path          string
 	Documentation *Documentation
 	Imports       []string
+	Facts         PackageFacts
+}
+
+// PackageFacts holds facts about a package that are computed when its module
+// is processed, as read from the database. A nil field means that the fact
+// is not known, because the package was stored before it was recorded.
+type PackageFacts struct {
+	// HasTests reports whether the directory of the package has a Go test
+	// file.
+	HasTests *bool
+
+	// DocCoverage is the percentage of the exported symbols of the package
+	// that have a documentation comment. It is also nil if the package has
+	// no exported symbols.
+	DocCoverage *int
+
+	// RequiresCgo reports whether a file of the package imports "C".
+	RequiresCgo *bool
+
+	// IsDeprecated reports whether the package doc comment has a
+	// "Deprecated: " paragraph, and DeprecationMessage is the rest of that
+	// paragraph.
+	IsDeprecated       *bool
+	DeprecationMessage string
+
+	// LicenseConflicts are the pairs of incompatible licenses of the package
+	// and the packages it imports, as computed when the package was
+	// inserted. It is nil if there are none, or if they are not known.
+	LicenseConflicts []*LicenseConflict
 }
 
 // Documentation is the rendered documentation for a given package
@@ -231,6 +324,27 @@ type SearchResult struct {
 	// NumImportedBy is the number of packages that import PackagePath.
 	NumImportedBy uint64
 
+	// ExportedSymbols is the number of exported symbols of the package,
+	// including methods.
+	ExportedSymbols int
+
+	// HasTests reports whether the directory of the package has a Go test
+	// file.
+	HasTests bool
+
+	// DocCoverage is the percentage of the exported symbols of the package
+	// that have a documentation comment.
+	DocCoverage int
+
+	// RequiresCgo reports whether the package imports "C".
+	RequiresCgo bool
+
+	// HasFuzzTests reports whether the package has a fuzz target.
+	HasFuzzTests bool
+
+	// BenchmarkCount is the number of benchmarks of the package.
+	BenchmarkCount int
+
 	// NumResults is the total number of packages that were returned for this
 	// search.
 	NumResults uint64
@@ -238,6 +352,31 @@ type SearchResult struct {
 	// can be approximate if search scanned only a subset of documents, and
 	// result count is estimated using the hyperloglog algorithm.
 	Approximate bool
+	// NumResultsError is the relative standard error of NumResults, if it is
+	// approximate.
+	NumResultsError float64
+
+	// SymbolName and SymbolKind identify the matching symbol of a symbol
+	// search. They are empty for a package search.
+	SymbolName string
+	SymbolKind SymbolKind
+
+	// FacetCounts maps each license type to the number of packages matching
+	// the search that have it. It is only populated if requested.
+	FacetCounts map[string]uint64
+
+	// NextCursor is an opaque token identifying the position of this result.
+	// Passing it back as the AfterCursor of a search for the same query
+	// returns the results that follow this one.
+	NextCursor string
+}
+
+// SearchSuggestion is a package suggested to complete a partial search query.
+type SearchSuggestion struct {
+	PackagePath string
+	Synopsis    string
+	// Score ranks suggestions: a higher score is a better suggestion.
+	Score float64
 }
 
 // A FieldSet is a bit set of struct fields. It is used to avoid reading large
@@ -298,6 +437,72 @@ type LegacyPackage struct {
 	// V1Path is the package path of a package with major version 1 in a given
 	// series.
 	V1Path string
+
+	// Symbols are the exported symbols of the package.
+	Symbols []*Symbol
+
+	// EmbeddedPatterns are the patterns of the //go:embed directives of the
+	// package, sorted and without duplicates.
+	EmbeddedPatterns []string
+
+	// BuildContexts are the elements of BuildContexts that the package
+	// builds for.
+	BuildContexts []BuildContext
+
+	// RequiresCgo reports whether a file of the package imports "C".
+	RequiresCgo bool
+
+	// IsDeprecated reports whether a paragraph of the package doc comment
+	// begins with "Deprecated: ", and DeprecationMessage is the rest of that
+	// paragraph.
+	IsDeprecated       bool
+	DeprecationMessage string
+
+	// SuccessorPackages are the import paths mentioned in
+	// DeprecationMessage, which may name the packages that replace this one.
+	SuccessorPackages []string
+
+	// Examples are the example functions of the package.
+	Examples []*Example
+
+	// HasFuzzTests reports whether a test file of the package declares a
+	// fuzz target.
+	HasFuzzTests bool
+
+	// BenchmarkCount is the number of benchmarks declared in the test files
+	// of the package.
+	BenchmarkCount int
+
+	// Facts are set when the package is read from the database. They are
+	// not used when it is inserted.
+	Facts PackageFacts
+}
+
+// A BuildContext is a combination of the GOOS and GOARCH environment
+// variables.
+type BuildContext struct {
+	GOOS   string
+	GOARCH string
+}
+
+func (b BuildContext) String() string {
+	return b.GOOS + "/" + b.GOARCH
+}
+
+// BuildContexts are the major build contexts, for which the availability of
+// packages is computed and displayed.
+var BuildContexts = []BuildContext{
+	{"linux", "amd64"},
+	{"linux", "386"},
+	{"linux", "arm"},
+	{"linux", "arm64"},
+	{"darwin", "amd64"},
+	{"darwin", "arm64"},
+	{"windows", "amd64"},
+	{"windows", "386"},
+	{"windows", "arm64"},
+	{"freebsd", "amd64"},
+	{"js", "wasm"},
 }
 
 // LegacyVersionedPackage is a LegacyPackage along with its corresponding module
@@ -0,0 +1,18 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package internal
+
+// An Example is an example function of a package, declared in one of its
+// _test.go files.
+type Example struct {
+	// Name is the name of the example function, like "ExampleBuffer_Write".
+	Name string
+	// Code is the body of the example function, without its enclosing
+	// braces and its output comment.
+	Code string
+	// Output is the expected output of the example, if it has an output
+	// comment.
+	Output string
+}
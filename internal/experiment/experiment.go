@@ -55,3 +55,19 @@ func (s *Set) IsActive(experiment string) bool {
 	}
 	return s.set[experiment]
 }
+
+type bucketKey struct{}
+
+// NewBucketContext stores the experiment bucket of a request in the context.
+// Buckets group requests that should see the same variant of an A/B test,
+// like a set of search ranking parameters.
+func NewBucketContext(ctx context.Context, bucket string) context.Context {
+	return context.WithValue(ctx, bucketKey{}, bucket)
+}
+
+// Bucket returns the experiment bucket stored in the context, or the empty
+// string if there is none.
+func Bucket(ctx context.Context) string {
+	b, _ := ctx.Value(bucketKey{}).(string)
+	return b
+}
@@ -28,3 +28,14 @@ func TestGetAndSetExperiments(t *testing.T) {
 		t.Fatalf("s.IsActive(ctx, %q) = true; want = false", testExperiment2)
 	}
 }
+
+func TestBucket(t *testing.T) {
+	ctx := context.Background()
+	if got := Bucket(ctx); got != "" {
+		t.Errorf("Bucket(ctx) = %q, want empty", got)
+	}
+	ctx = NewBucketContext(ctx, "b")
+	if got := Bucket(ctx); got != "b" {
+		t.Errorf("Bucket(ctx) = %q, want %q", got, "b")
+	}
+}
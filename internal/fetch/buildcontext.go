@@ -0,0 +1,173 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"archive/zip"
+	"go/build"
+	"go/build/constraint"
+	"go/parser"
+	"go/token"
+	"path"
+	"strings"
+
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+// Known operating systems and architectures, as in go/build. A file name
+// suffix that is not one of them is not a constraint.
+var (
+	knownOS = map[string]bool{
+		"aix": true, "android": true, "darwin": true, "dragonfly": true,
+		"freebsd": true, "hurd": true, "illumos": true, "ios": true, "js": true,
+		"linux": true, "nacl": true, "netbsd": true, "openbsd": true,
+		"plan9": true, "solaris": true, "windows": true, "zos": true,
+	}
+	unixOS = map[string]bool{
+		"aix": true, "android": true, "darwin": true, "dragonfly": true,
+		"freebsd": true, "hurd": true, "illumos": true, "ios": true,
+		"linux": true, "netbsd": true, "openbsd": true, "solaris": true,
+	}
+	knownArch = map[string]bool{
+		"386": true, "amd64": true, "amd64p32": true, "arm": true, "armbe": true,
+		"arm64": true, "arm64be": true, "loong64": true, "mips": true,
+		"mipsle": true, "mips64": true, "mips64le": true, "mips64p32": true,
+		"mips64p32le": true, "ppc": true, "ppc64": true, "ppc64le": true,
+		"riscv": true, "riscv64": true, "s390": true, "s390x": true,
+		"sparc": true, "sparc64": true, "wasm": true,
+	}
+)
+
+// buildContexts returns the elements of internal.BuildContexts that the
+// package made of the .go files in zipGoFiles builds for.
+func buildContexts(zipGoFiles []*zip.File) (_ []internal.BuildContext, err error) {
+	defer derrors.Wrap(&err, "buildContexts(zipGoFiles)")
+
+	files := map[string][]byte{}
+	for _, f := range zipGoFiles {
+		_, name := path.Split(f.Name)
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		b, err := readZipFile(f)
+		if err != nil {
+			return nil, err
+		}
+		files[name] = b
+	}
+	return matchingBuildContexts(files), nil
+}
+
+// matchingBuildContexts returns the elements of internal.BuildContexts for
+// which at least one of files, a map from file names to contents, satisfies
+// its build constraints: the GOOS and GOARCH suffixes of its name, and its
+// //go:build line or, if it has none, its // +build lines. Files that cannot
+// be parsed are ignored.
+func matchingBuildContexts(files map[string][]byte) []internal.BuildContext {
+	exprs := map[string]constraint.Expr{}
+	for name, src := range files {
+		expr, ok := fileConstraint(name, src)
+		if ok {
+			exprs[name] = expr
+		}
+	}
+	var bcs []internal.BuildContext
+	for _, bc := range internal.BuildContexts {
+		for name, expr := range exprs {
+			if goodOSArchFile(name, bc) && (expr == nil || expr.Eval(func(tag string) bool { return matchTag(tag, bc) })) {
+				bcs = append(bcs, bc)
+				break
+			}
+		}
+	}
+	return bcs
+}
+
+// fileConstraint returns the build constraint expression of the Go file with
+// the given name and contents, or nil if it has none. It reports false if
+// the file cannot be parsed.
+//
+// Like the go command, it ignores constraints in the package doc comment.
+func fileConstraint(name string, src []byte) (_ constraint.Expr, ok bool) {
+	f, err := parser.ParseFile(token.NewFileSet(), name, src, parser.PackageClauseOnly|parser.ParseComments)
+	if err != nil {
+		return nil, false
+	}
+	var goBuild, plusBuild constraint.Expr
+	for _, cg := range f.Comments {
+		if cg.Pos() >= f.Package {
+			break
+		}
+		if cg == f.Doc {
+			continue
+		}
+		for _, c := range cg.List {
+			switch {
+			case constraint.IsGoBuild(c.Text):
+				if x, err := constraint.Parse(c.Text); err == nil && goBuild == nil {
+					goBuild = x
+				}
+			case constraint.IsPlusBuild(c.Text):
+				if x, err := constraint.Parse(c.Text); err == nil {
+					if plusBuild == nil {
+						plusBuild = x
+					} else {
+						plusBuild = &constraint.AndExpr{X: plusBuild, Y: x}
+					}
+				}
+			}
+		}
+	}
+	if goBuild != nil {
+		return goBuild, true
+	}
+	return plusBuild, true
+}
+
+// goodOSArchFile reports whether the GOOS and GOARCH suffixes of the file
+// name, like those of "x_linux_amd64.go", match bc.
+func goodOSArchFile(name string, bc internal.BuildContext) bool {
+	name = strings.TrimSuffix(name, path.Ext(name))
+	i := strings.Index(name, "_")
+	if i < 0 {
+		return true
+	}
+	l := strings.Split(name[i:], "_")
+	if n := len(l); n > 0 && l[n-1] == "test" {
+		l = l[:n-1]
+	}
+	n := len(l)
+	if n >= 2 && knownOS[l[n-2]] && knownArch[l[n-1]] {
+		return matchTag(l[n-2], bc) && matchTag(l[n-1], bc)
+	}
+	if n >= 1 && (knownOS[l[n-1]] || knownArch[l[n-1]]) {
+		return matchTag(l[n-1], bc)
+	}
+	return true
+}
+
+// matchTag reports whether the build tag is satisfied in bc, with cgo
+// enabled and the gc compiler.
+func matchTag(tag string, bc internal.BuildContext) bool {
+	switch tag {
+	case bc.GOOS, bc.GOARCH, "cgo", "gc":
+		return true
+	case "unix":
+		return unixOS[bc.GOOS]
+	case "linux":
+		return bc.GOOS == "android"
+	case "darwin":
+		return bc.GOOS == "ios"
+	case "solaris":
+		return bc.GOOS == "illumos"
+	}
+	for _, t := range build.Default.ReleaseTags {
+		if tag == t {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal"
+)
+
+func TestMatchingBuildContexts(t *testing.T) {
+	all := internal.BuildContexts
+	only := func(goos ...string) []internal.BuildContext {
+		var bcs []internal.BuildContext
+		for _, bc := range all {
+			for _, g := range goos {
+				if bc.GOOS == g {
+					bcs = append(bcs, bc)
+				}
+			}
+		}
+		return bcs
+	}
+	for _, test := range []struct {
+		name  string
+		files map[string]string
+		want  []internal.BuildContext
+	}{
+		{
+			name:  "unconstrained",
+			files: map[string]string{"p.go": "package p"},
+			want:  all,
+		},
+		{
+			name:  "linux only",
+			files: map[string]string{"p.go": "//go:build linux\n\npackage p"},
+			want:  only("linux"),
+		},
+		{
+			name:  "not windows",
+			files: map[string]string{"p.go": "//go:build !windows\n\npackage p"},
+			want:  only("linux", "darwin", "freebsd", "js"),
+		},
+		{
+			name:  "legacy plus build lines",
+			files: map[string]string{"p.go": "// +build linux darwin\n// +build amd64\n\npackage p"},
+			want: []internal.BuildContext{
+				{GOOS: "linux", GOARCH: "amd64"},
+				{GOOS: "darwin", GOARCH: "amd64"},
+			},
+		},
+		{
+			name:  "go:build takes precedence",
+			files: map[string]string{"p.go": "//go:build windows\n// +build linux\n\npackage p"},
+			want:  only("windows"),
+		},
+		{
+			name:  "unix",
+			files: map[string]string{"p.go": "//go:build unix && !js\n\npackage p"},
+			want:  only("linux", "darwin", "freebsd"),
+		},
+		{
+			name: "file names",
+			files: map[string]string{
+				"p_linux.go":         "package p",
+				"p_windows_arm64.go": "package p",
+				"p_wasm.go":          "package p",
+			},
+			want: []internal.BuildContext{
+				{GOOS: "linux", GOARCH: "amd64"},
+				{GOOS: "linux", GOARCH: "386"},
+				{GOOS: "linux", GOARCH: "arm"},
+				{GOOS: "linux", GOARCH: "arm64"},
+				{GOOS: "windows", GOARCH: "arm64"},
+				{GOOS: "js", GOARCH: "wasm"},
+			},
+		},
+		{
+			name: "any file suffices",
+			files: map[string]string{
+				"p.go":       "//go:build linux\n\npackage p",
+				"p_other.go": "//go:build !windows && !linux\n\npackage p",
+			},
+			want: only("linux", "darwin", "freebsd", "js"),
+		},
+		{
+			name:  "doc comment is not a constraint",
+			files: map[string]string{"p.go": "// +build linux\npackage p"},
+			want:  all,
+		},
+		{
+			name:  "custom tag",
+			files: map[string]string{"p.go": "//go:build ignore\n\npackage p"},
+			want:  nil,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			files := map[string][]byte{}
+			for name, src := range test.files {
+				files[name] = []byte(src)
+			}
+			got := matchingBuildContexts(files)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"go/ast"
+	"go/token"
+	"testing"
+)
+
+func TestRequiresCgo(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		srcs []string
+		want bool
+	}{
+		{"no files", nil, false},
+		{"pure Go", []string{"package p\n\nimport \"fmt\"\n\nvar _ = fmt.Sprint"}, false},
+		{
+			"import C",
+			[]string{"package p\n\n// #include <stdio.h>\nimport \"C\"\n"},
+			true,
+		},
+		{
+			"grouped import",
+			[]string{"package p\n\nimport (\n\t\"unsafe\"\n\t\"C\"\n)\n\nvar _ unsafe.Pointer\n"},
+			true,
+		},
+		{
+			"one of several files",
+			[]string{"package p\n", "package p\n\nimport \"C\"\n"},
+			true,
+		},
+		{"string mentioning C", []string{"package p\n\nconst s = `import \"C\"`\n"}, false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			fset := token.NewFileSet()
+			files := map[string]*ast.File{}
+			for i, src := range test.srcs {
+				name := string(rune('a'+i)) + ".go"
+				files[name] = mustParse(fset, name, src)
+			}
+			if got := requiresCgo(files); got != test.want {
+				t.Errorf("requiresCgo = %t, want %t", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/mod/module"
+)
+
+// deprecation reports whether the package with the given doc comment text,
+// as computed by go/doc, is deprecated, and returns the deprecation message.
+//
+// By convention, a package is deprecated if a paragraph of its doc comment
+// begins with "Deprecated: ". The message is the rest of that paragraph,
+// with its lines joined by spaces.
+func deprecation(doc string) (message string, deprecated bool) {
+	const prefix = "Deprecated:"
+	for _, para := range strings.Split(doc, "\n\n") {
+		para = strings.TrimSpace(para)
+		if !strings.HasPrefix(para, prefix) {
+			continue
+		}
+		return strings.Join(strings.Fields(para[len(prefix):]), " "), true
+	}
+	return "", false
+}
+
+// importPathRegexp matches the words of a deprecation message that look like
+// import paths: at least two elements separated by slashes.
+var importPathRegexp = regexp.MustCompile(`[A-Za-z0-9][-A-Za-z0-9_.~]*(/[-A-Za-z0-9_.~]+)+`)
+
+// successorPackages returns the import paths mentioned in the deprecation
+// message of the package with path pkgPath, in the order they appear and
+// without duplicates. They are candidates for packages that replace it; they
+// may not exist.
+func successorPackages(pkgPath, message string) []string {
+	var paths []string
+	seen := map[string]bool{pkgPath: true}
+	for _, p := range importPathRegexp.FindAllString(message, -1) {
+		// A path at the end of a sentence is followed by a period.
+		p = strings.TrimRight(p, ".")
+		if seen[p] || module.CheckImportPath(p) != nil {
+			continue
+		}
+		seen[p] = true
+		paths = append(paths, p)
+	}
+	return paths
+}
@@ -0,0 +1,62 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDeprecation(t *testing.T) {
+	for _, test := range []struct {
+		doc            string
+		wantMessage    string
+		wantDeprecated bool
+	}{
+		{"", "", false},
+		{"Package p does things.\n", "", false},
+		{
+			"Package p does things.\n\nDeprecated: use q instead.\n",
+			"use q instead.", true,
+		},
+		{
+			"Package p does things.\n\nDeprecated: the functionality\nmoved to q.\n\nIt will be removed.\n",
+			"the functionality moved to q.", true,
+		},
+		{"Deprecated:\n", "", true},
+		// The prefix must begin a paragraph.
+		{"Package p is not Deprecated: at all.\n", "", false},
+		{"Package p does things.\nDeprecated: not a paragraph.\n", "", false},
+		// The prefix is case-sensitive.
+		{"Package p does things.\n\ndeprecated: use q.\n", "", false},
+	} {
+		gotMessage, gotDeprecated := deprecation(test.doc)
+		if gotMessage != test.wantMessage || gotDeprecated != test.wantDeprecated {
+			t.Errorf("deprecation(%q) = %q, %t, want %q, %t", test.doc, gotMessage, gotDeprecated, test.wantMessage, test.wantDeprecated)
+		}
+	}
+}
+
+func TestSuccessorPackages(t *testing.T) {
+	const pkgPath = "example.com/old"
+	for _, test := range []struct {
+		message string
+		want    []string
+	}{
+		{"", nil},
+		{"Use newpkg instead.", nil},
+		{"Use example.com/new instead.", []string{"example.com/new"}},
+		{"Use golang.org/x/net/context or context/ctx.", []string{"golang.org/x/net/context", "context/ctx"}},
+		{"See https://github.com/foo/bar/issues/1.", []string{"github.com/foo/bar/issues/1"}},
+		{"Use example.com/new, not example.com/old; example.com/new is faster.", []string{"example.com/new"}},
+		{"Use a/.hidden instead.", nil},
+	} {
+		got := successorPackages(pkgPath, test.message)
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("successorPackages(%q) mismatch (-want +got):\n%s", test.message, diff)
+		}
+	}
+}
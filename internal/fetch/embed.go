@@ -0,0 +1,85 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"go/ast"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// embeddedPatterns returns the patterns of the //go:embed directives in
+// files, sorted and without duplicates. Malformed patterns are skipped.
+func embeddedPatterns(files map[string]*ast.File) []string {
+	seen := map[string]bool{}
+	for _, f := range files {
+		for _, cg := range f.Comments {
+			for _, c := range cg.List {
+				for _, p := range parseEmbedDirective(c.Text) {
+					seen[p] = true
+				}
+			}
+		}
+	}
+	var patterns []string
+	for p := range seen {
+		patterns = append(patterns, p)
+	}
+	sort.Strings(patterns)
+	return patterns
+}
+
+// parseEmbedDirective returns the patterns of the comment text, if it is a
+// //go:embed directive. Like the go command, it accepts patterns separated by
+// spaces, and Go string literals for patterns that contain spaces.
+func parseEmbedDirective(text string) []string {
+	const prefix = "//go:embed"
+	if !strings.HasPrefix(text, prefix) {
+		return nil
+	}
+	args := text[len(prefix):]
+	if args == "" || !unicode.IsSpace(rune(args[0])) {
+		// Something like //go:embedded.
+		return nil
+	}
+	var patterns []string
+	for {
+		args = strings.TrimLeftFunc(args, unicode.IsSpace)
+		if args == "" {
+			return patterns
+		}
+		var p string
+		switch args[0] {
+		case '"', '`':
+			quote := args[0]
+			i := 1
+			for i < len(args) && args[i] != quote {
+				if quote == '"' && args[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i >= len(args) {
+				return patterns
+			}
+			q, err := strconv.Unquote(args[:i+1])
+			if err != nil {
+				return patterns
+			}
+			p, args = q, args[i+1:]
+		default:
+			i := strings.IndexFunc(args, unicode.IsSpace)
+			if i < 0 {
+				i = len(args)
+			}
+			p, args = args[:i], args[i:]
+		}
+		if p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"go/ast"
+	"go/token"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEmbeddedPatterns(t *testing.T) {
+	const (
+		srcA = `
+package p
+
+import "embed"
+
+//go:embed static/*
+var static embed.FS
+
+// Templates are embedded too.
+//go:embed templates/*.tmpl "with space.txt"
+//go:embed static/*
+var templates embed.FS
+
+//go:embedded is not a directive.
+//go:embed
+var version string
+`
+		srcB = `
+package p
+
+import _ "embed"
+
+//go:embed ` + "`raw quoted.txt`" + ` VERSION
+var version string
+`
+	)
+	fset := token.NewFileSet()
+	files := map[string]*ast.File{
+		"a.go": mustParse(fset, "a.go", srcA),
+		"b.go": mustParse(fset, "b.go", srcB),
+	}
+	got := embeddedPatterns(files)
+	want := []string{"VERSION", "raw quoted.txt", "static/*", "templates/*.tmpl", "with space.txt"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	if got := embeddedPatterns(map[string]*ast.File{"c.go": mustParse(fset, "c.go", "package p\n")}); got != nil {
+		t.Errorf("got %v for a package without directives, want nil", got)
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"bytes"
+	"go/ast"
+	"go/printer"
+	"go/token"
+	"regexp"
+	"strings"
+
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/fetch/dochtml"
+	"golang.org/x/pkgsite/internal/fetch/internal/doc"
+)
+
+// outputCommentRegexp matches the output comment of an example, as in the
+// testing package.
+var outputCommentRegexp = regexp.MustCompile(`(?i)^[[:space:]]*(unordered )?output:`)
+
+// packageExamples returns the examples of d, in the order of
+// dochtml.WalkExamples.
+func packageExamples(fset *token.FileSet, d *doc.Package) []*internal.Example {
+	var exs []*internal.Example
+	dochtml.WalkExamples(d, func(_ string, ex *doc.Example) {
+		exs = append(exs, &internal.Example{
+			Name:   "Example" + ex.Name,
+			Code:   exampleCode(fset, ex),
+			Output: ex.Output,
+		})
+	})
+	return exs
+}
+
+// exampleCode returns the formatted body of ex, without its braces and its
+// output comment. The body is unindented by one level.
+func exampleCode(fset *token.FileSet, ex *doc.Example) string {
+	var comments []*ast.CommentGroup
+	for _, cg := range ex.Comments {
+		if !outputCommentRegexp.MatchString(cg.Text()) {
+			comments = append(comments, cg)
+		}
+	}
+	var node interface{} = ex.Code
+	if len(comments) > 0 {
+		node = &printer.CommentedNode{Node: ex.Code, Comments: comments}
+	}
+	var buf bytes.Buffer
+	if err := (&printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}).Fprint(&buf, fset, node); err != nil {
+		return ""
+	}
+	src := buf.String()
+	if _, ok := ex.Code.(*ast.BlockStmt); !ok {
+		return src
+	}
+	src = strings.TrimSuffix(strings.TrimPrefix(src, "{"), "}")
+	src = strings.Trim(src, "\n")
+	lines := strings.Split(src, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimPrefix(l, "\t")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,82 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"go/ast"
+	"go/token"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/fetch/internal/doc"
+)
+
+func TestPackageExamples(t *testing.T) {
+	const (
+		src = `
+package p
+
+type T int
+
+func (T) M() {}
+`
+		testSrc = `
+package p_test
+
+import (
+	"fmt"
+
+	"example.com/p"
+)
+
+func Example() {
+	// Say hello.
+	fmt.Println("hello")
+	// Output: hello
+}
+
+func ExampleT_M() {
+	var t p.T
+	t.M()
+}
+
+func ExampleT_M_second() {
+	for i := 0; i < 2; i++ {
+		fmt.Println(i)
+	}
+	// Unordered output:
+	// 1
+	// 0
+}
+`
+	)
+	fset := token.NewFileSet()
+	files := []*ast.File{mustParse(fset, "p.go", src), mustParse(fset, "p_test.go", testSrc)}
+	d, err := doc.NewFromFiles(fset, files, "example.com/p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := packageExamples(fset, d)
+	want := []*internal.Example{
+		{
+			Name:   "Example",
+			Code:   "// Say hello.\nfmt.Println(\"hello\")",
+			Output: "hello\n",
+		},
+		{
+			Name: "ExampleT_M",
+			Code: "var t p.T\nt.M()",
+		},
+		{
+			Name:   "ExampleT_M_second",
+			Code:   "for i := 0; i < 2; i++ {\n\tfmt.Println(i)\n}",
+			Output: "1\n0\n",
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("packageExamples mismatch (-want +got):\n%s", diff)
+	}
+}
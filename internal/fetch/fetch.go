@@ -30,6 +30,7 @@ import (
 	"go.opencensus.io/trace"
 	"golang.org/x/mod/modfile"
 	"golang.org/x/mod/module"
+	"golang.org/x/mod/sumdb/dirhash"
 	"golang.org/x/pkgsite/internal"
 	"golang.org/x/pkgsite/internal/derrors"
 	"golang.org/x/pkgsite/internal/experiment"
@@ -83,6 +84,7 @@ func FetchModule(ctx context.Context, modulePath, requestedVersion string, proxy
 	var (
 		commitTime time.Time
 		zipReader  *zip.Reader
+		goModBytes []byte
 		err        error
 	)
 	if modulePath == stdlib.ModulePath {
@@ -101,7 +103,7 @@ func FetchModule(ctx context.Context, modulePath, requestedVersion string, proxy
 		fr.ResolvedVersion = info.Version
 		commitTime = info.Time
 
-		goModBytes, err := proxyClient.GetMod(ctx, modulePath, fr.ResolvedVersion)
+		goModBytes, err = proxyClient.GetMod(ctx, modulePath, fr.ResolvedVersion)
 		if err != nil {
 			fr.Error = err
 			return fr
@@ -139,6 +141,31 @@ func FetchModule(ctx context.Context, modulePath, requestedVersion string, proxy
 	fr.PackageVersionStates = pvs
 	if modulePath == stdlib.ModulePath {
 		fr.Module.HasGoMod = true
+	} else {
+		fr.Module.IsRetracted = isRetracted(goModBytes, fr.ResolvedVersion)
+		fr.Module.RetractedVersions = retractions(goModBytes)
+		fr.Module.ToolchainVersion = toolchainVersion(goModBytes)
+		// A malformed go.work file does not make the module unusable.
+		fr.Module.Workspace, err = zipWorkspace(zipReader, modulePath, fr.ResolvedVersion)
+		if err != nil {
+			log.Infof(ctx, "%s@%s: ignoring go.work file: %v", modulePath, fr.ResolvedVersion, err)
+		}
+		fr.Module.ZipHash, err = zipHash(zipReader)
+		if err != nil {
+			fr.Error = err
+			return fr
+		}
+	}
+	fr.Module.Files, err = moduleFiles(zipReader, modulePath, fr.ResolvedVersion)
+	if err != nil {
+		fr.Error = err
+		return fr
+	}
+	fr.Module.Links = moduleLinks(fr.Module)
+	fr.Module.Implementations, err = moduleImplementations(zipReader, modulePath, fr.ResolvedVersion, fr.Module.LegacyPackages)
+	if err != nil {
+		fr.Error = err
+		return fr
 	}
 	for _, state := range fr.PackageVersionStates {
 		if state.Status != http.StatusOK {
@@ -206,6 +233,25 @@ func processZipFile(ctx context.Context, modulePath string, versionType version.
 	}, packageVersionStates, nil
 }
 
+// zipHash returns the "h1:" hash of the module zip read by r, which is the
+// hash recorded for the module version in go.sum files and the checksum
+// database.
+func zipHash(r *zip.Reader) (string, error) {
+	files := make([]string, 0, len(r.File))
+	zfiles := make(map[string]*zip.File, len(r.File))
+	for _, f := range r.File {
+		files = append(files, f.Name)
+		zfiles[f.Name] = f
+	}
+	hash, err := dirhash.Hash1(files, func(name string) (io.ReadCloser, error) {
+		return zfiles[name].Open()
+	})
+	if err != nil {
+		return "", fmt.Errorf("dirhash.Hash1: %v: %w", err, derrors.BadModule)
+	}
+	return hash, nil
+}
+
 // moduleVersionDir formats the content subdirectory for the given
 // modulePath and version.
 func moduleVersionDir(modulePath, version string) string {
@@ -494,7 +540,8 @@ var goEnvs = []struct{ GOOS, GOARCH string }{
 // loadPackage loads a Go package by calling loadPackageWithBuildContext, trying
 // several build contexts in turn. The first build context in the list to produce
 // a non-empty package is used. If none of them result in a package, then
-// loadPackage returns nil, nil.
+// loadPackage returns nil, nil. The BuildContexts of the package are computed
+// separately, for all of internal.BuildContexts.
 //
 // If the package is fine except that its documentation is too large, loadPackage
 // returns both a package and a non-nil error with dochtml.ErrTooLarge in its chain.
@@ -507,6 +554,11 @@ func loadPackage(ctx context.Context, zipGoFiles []*zip.File, innerPath, moduleP
 			return nil, err
 		}
 		if pkg != nil {
+			bcs, berr := buildContexts(zipGoFiles)
+			if berr != nil {
+				return nil, berr
+			}
+			pkg.BuildContexts = bcs
 			return pkg, err
 		}
 	}
@@ -547,6 +599,7 @@ func loadPackageWithBuildContext(ctx context.Context, goos, goarch string, zipGo
 		fset            = token.NewFileSet()
 		goFiles         = make(map[string]*ast.File)
 		allGoFiles      []*ast.File
+		testFiles       []*ast.File
 		packageName     string
 		packageNameFile string // Name of file where packageName came from.
 	)
@@ -560,6 +613,7 @@ func loadPackageWithBuildContext(ctx context.Context, goos, goarch string, zipGo
 		}
 		allGoFiles = append(allGoFiles, pf)
 		if strings.HasSuffix(name, "_test.go") {
+			testFiles = append(testFiles, pf)
 			continue
 		}
 		goFiles[name] = pf
@@ -670,15 +724,25 @@ func loadPackageWithBuildContext(ctx context.Context, goos, goarch string, zipGo
 	if modulePath == stdlib.ModulePath {
 		importPath = innerPath
 	}
+	deprecationMessage, isDeprecated := deprecation(d.Doc)
 	return &internal.LegacyPackage{
-		Path:              importPath,
-		Name:              packageName,
-		Synopsis:          doc.Synopsis(d.Doc),
-		V1Path:            v1path,
-		Imports:           d.Imports,
-		DocumentationHTML: docHTML,
-		GOOS:              goos,
-		GOARCH:            goarch,
+		Path:               importPath,
+		Name:               packageName,
+		Synopsis:           doc.Synopsis(d.Doc),
+		V1Path:             v1path,
+		Imports:            d.Imports,
+		DocumentationHTML:  docHTML,
+		GOOS:               goos,
+		GOARCH:             goarch,
+		Symbols:            exportedSymbols(fset, d, innerPath),
+		EmbeddedPatterns:   embeddedPatterns(goFiles),
+		RequiresCgo:        requiresCgo(goFiles),
+		IsDeprecated:       isDeprecated,
+		DeprecationMessage: deprecationMessage,
+		SuccessorPackages:  successorPackages(importPath, deprecationMessage),
+		Examples:           packageExamples(fset, d),
+		HasFuzzTests:       hasFuzzTests(testFiles),
+		BenchmarkCount:     benchmarkCount(testFiles),
 	}, err
 }
 
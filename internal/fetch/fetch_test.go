@@ -13,14 +13,17 @@ import (
 	"go/parser"
 	"go/token"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"sort"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	"golang.org/x/mod/sumdb/dirhash"
 	"golang.org/x/pkgsite/internal"
 	"golang.org/x/pkgsite/internal/derrors"
 	"golang.org/x/pkgsite/internal/experiment"
@@ -99,7 +102,8 @@ func TestFetchModule(t *testing.T) {
 			sortFetchResult(fr)
 			sortFetchResult(got)
 			opts := []cmp.Option{
-				cmpopts.IgnoreFields(internal.LegacyPackage{}, "DocumentationHTML"),
+				cmpopts.IgnoreFields(internal.Module{}, "ZipHash", "Files", "Implementations", "Links"),
+				cmpopts.IgnoreFields(internal.LegacyPackage{}, "DocumentationHTML", "Symbols", "BuildContexts", "Examples", "BenchmarkCount"),
 				cmpopts.IgnoreFields(internal.Documentation{}, "HTML"),
 				cmpopts.IgnoreFields(internal.PackageVersionState{}, "Error"),
 				cmp.AllowUnexported(source.Info{}),
@@ -436,3 +440,40 @@ package p
 		}
 	}
 }
+
+func TestZipHash(t *testing.T) {
+	data, err := testhelper.ZipContents(map[string]string{
+		"m.com@v1.0.0/go.mod": "module m.com\n",
+		"m.com@v1.0.0/p/p.go": "package p\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := ioutil.TempFile("", "ziphash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want, err := dirhash.HashZip(f.Name(), dirhash.Hash1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := zipHash(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("zipHash = %q, want %q", got, want)
+	}
+}
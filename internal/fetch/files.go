@@ -0,0 +1,96 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"archive/zip"
+	"mime"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/pkgsite/internal"
+)
+
+// maxFileContentsSize is the size of the largest file whose contents are
+// stored by moduleFiles.
+const maxFileContentsSize = 256 * 1024
+
+// sourceContentTypes maps the extensions of files commonly found in modules
+// that are unknown to the mime package to their content type.
+var sourceContentTypes = map[string]string{
+	".go":    "text/x-go; charset=utf-8",
+	".mod":   "text/plain; charset=utf-8",
+	".sum":   "text/plain; charset=utf-8",
+	".work":  "text/plain; charset=utf-8",
+	".s":     "text/x-asm; charset=utf-8",
+	".c":     "text/x-c; charset=utf-8",
+	".h":     "text/x-c; charset=utf-8",
+	".md":    "text/markdown; charset=utf-8",
+	".yaml":  "text/yaml; charset=utf-8",
+	".yml":   "text/yaml; charset=utf-8",
+	".proto": "text/plain; charset=utf-8",
+}
+
+// moduleFiles returns the files of the zip of modulePath@version read by r,
+// sorted by name, with the contents of the text files that are no larger
+// than maxFileContentsSize.
+func moduleFiles(r *zip.Reader, modulePath, version string) ([]*internal.FileInfo, error) {
+	prefix := moduleVersionDir(modulePath, version) + "/"
+	var files []*internal.FileInfo
+	for _, f := range r.File {
+		if !strings.HasPrefix(f.Name, prefix) || strings.HasSuffix(f.Name, "/") {
+			continue
+		}
+		fi := &internal.FileInfo{
+			Name:        strings.TrimPrefix(f.Name, prefix),
+			Size:        int64(f.UncompressedSize64),
+			ContentType: fileContentType(f.Name),
+		}
+		if fi.Size <= maxFileContentsSize {
+			contents, err := readZipFile(f)
+			if err != nil {
+				return nil, err
+			}
+			if fi.ContentType == "" {
+				fi.ContentType = http.DetectContentType(contents)
+			}
+			if isTextContentType(fi.ContentType) {
+				fi.Contents = contents
+			}
+		}
+		if fi.ContentType == "" {
+			fi.ContentType = "application/octet-stream"
+		}
+		files = append(files, fi)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files, nil
+}
+
+// fileContentType returns the content type of the file with the given name
+// based on its extension, or the empty string if the extension is unknown.
+func fileContentType(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	if ct, ok := sourceContentTypes[ext]; ok {
+		return ct
+	}
+	return mime.TypeByExtension(ext)
+}
+
+// isTextContentType reports whether files of the given content type can be
+// displayed as text.
+func isTextContentType(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch mt {
+	case "application/json", "application/javascript", "application/xml":
+		return true
+	}
+	return strings.HasPrefix(mt, "text/")
+}
@@ -0,0 +1,51 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/testing/testhelper"
+)
+
+func TestModuleFiles(t *testing.T) {
+	big := strings.Repeat("x", maxFileContentsSize+1)
+	data, err := testhelper.ZipContents(map[string]string{
+		"m.com@v1.0.0/go.mod":       "module m.com\n",
+		"m.com@v1.0.0/LICENSE":      "Permission is granted.\n",
+		"m.com@v1.0.0/p/p.go":       "package p\n",
+		"m.com@v1.0.0/p/logo.png":   "\x89PNG\r\n\x1a\n",
+		"m.com@v1.0.0/p/data.bin":   "\x00\x01\x02",
+		"m.com@v1.0.0/p/big.go":     big,
+		"other.com@v1.0.0/other.go": "package other\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := moduleFiles(r, "m.com", "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*internal.FileInfo{
+		{Name: "LICENSE", Size: 23, ContentType: "text/plain; charset=utf-8", Contents: []byte("Permission is granted.\n")},
+		{Name: "go.mod", Size: 13, ContentType: "text/plain; charset=utf-8", Contents: []byte("module m.com\n")},
+		{Name: "p/big.go", Size: int64(len(big)), ContentType: "text/x-go; charset=utf-8"},
+		{Name: "p/data.bin", Size: 3, ContentType: "application/octet-stream"},
+		{Name: "p/logo.png", Size: 8, ContentType: "image/png"},
+		{Name: "p/p.go", Size: 10, ContentType: "text/x-go; charset=utf-8", Contents: []byte("package p\n")},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
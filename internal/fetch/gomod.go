@@ -0,0 +1,48 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"regexp"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/pkgsite/internal/version"
+)
+
+// goDirectiveRE matches the go directive of a go.mod file with a version
+// that has a patch number or a prerelease, like "go 1.21.0" or "go 1.21rc1".
+var goDirectiveRE = regexp.MustCompile(`(?m)^(\s*go\s+[1-9][0-9]*\.(?:0|[1-9][0-9]*))(?:\.[0-9]+|(?:rc|beta)[0-9]+)\s*(//.*)?$`)
+
+// parseGoModLax parses the go.mod file contents goMod, ignoring the
+// directives that are unknown to the version of golang.org/x/mod we use, like
+// retract and toolchain. They are kept in the syntax tree of the result.
+func parseGoModLax(goMod []byte) (*modfile.File, error) {
+	// Since Go 1.21, the go directive may have a patch number or a
+	// prerelease, which golang.org/x/mod rejects. They do not matter to us,
+	// so drop them.
+	goMod = goDirectiveRE.ReplaceAll(goMod, []byte("$1 $2"))
+	return modfile.ParseLax("go.mod", goMod, nil)
+}
+
+// toolchainVersion returns the argument of the toolchain directive of the
+// go.mod file contents goMod, like "go1.21.3". It returns the empty string
+// if there is no such directive, or if it or the go.mod file is malformed.
+func toolchainVersion(goMod []byte) string {
+	f, err := parseGoModLax(goMod)
+	if err != nil {
+		return ""
+	}
+	for _, stmt := range f.Syntax.Stmt {
+		l, ok := stmt.(*modfile.Line)
+		if !ok || l.Token[0] != "toolchain" {
+			continue
+		}
+		if len(l.Token) != 2 || !version.IsToolchain(l.Token[1]) {
+			return ""
+		}
+		return l.Token[1]
+	}
+	return ""
+}
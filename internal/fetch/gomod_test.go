@@ -0,0 +1,62 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import "testing"
+
+func TestToolchainVersion(t *testing.T) {
+	for _, test := range []struct {
+		name, goMod, want string
+	}{
+		{
+			name:  "no directive",
+			goMod: "module example.com/m\n\ngo 1.14\n",
+			want:  "",
+		},
+		{
+			name:  "directive",
+			goMod: "module example.com/m\n\ngo 1.21\n\ntoolchain go1.21.3\n",
+			want:  "go1.21.3",
+		},
+		{
+			name:  "go version with patch number",
+			goMod: "module example.com/m\n\ngo 1.21.0 // minimum\n\ntoolchain go1.22rc1\n",
+			want:  "go1.22rc1",
+		},
+		{
+			name:  "go version with prerelease",
+			goMod: "module example.com/m\n\ngo 1.22rc1\ntoolchain go1.22rc2\n",
+			want:  "go1.22rc2",
+		},
+		{
+			name:  "invalid toolchain",
+			goMod: "module example.com/m\n\ngo 1.21.0\n\ntoolchain 1.21.3\n",
+			want:  "",
+		},
+		{
+			name:  "too many arguments",
+			goMod: "module example.com/m\n\ntoolchain go1.21.3 go1.21.4\n",
+			want:  "",
+		},
+		{
+			name:  "malformed go.mod",
+			goMod: "module (\n\ntoolchain go1.21.3\n",
+			want:  "",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := toolchainVersion([]byte(test.goMod)); got != test.want {
+				t.Errorf("toolchainVersion(%q) = %q, want %q", test.goMod, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseGoModLaxNewGoVersions(t *testing.T) {
+	const goMod = "module example.com/m\n\ngo 1.21.0\n\nretract v1.0.0\n"
+	if !isRetracted([]byte(goMod), "v1.0.0") {
+		t.Error("isRetracted with go 1.21.0 directive = false, want true")
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"archive/zip"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/stdlib"
+)
+
+// stdPackages caches the standard library packages imported by importStd, so
+// that each is type-checked from the source in GOROOT at most once per
+// process. Once a package is cached, importing it takes only a read lock.
+var stdPackages = struct {
+	mu      sync.RWMutex
+	results map[string]stdImportResult
+
+	// importMu serializes calls to imp, which is not safe for concurrent use.
+	importMu sync.Mutex
+	imp      types.Importer
+}{
+	results: map[string]stdImportResult{},
+	imp:     importer.ForCompiler(token.NewFileSet(), "source", nil),
+}
+
+type stdImportResult struct {
+	pkg *types.Package
+	err error
+}
+
+// importStd imports the standard library package with the given path. Errors
+// are cached too, so a package that cannot be type-checked is not retried.
+func importStd(importPath string) (*types.Package, error) {
+	stdPackages.mu.RLock()
+	r, ok := stdPackages.results[importPath]
+	stdPackages.mu.RUnlock()
+	if ok {
+		return r.pkg, r.err
+	}
+
+	stdPackages.importMu.Lock()
+	defer stdPackages.importMu.Unlock()
+	// Another goroutine may have imported the package while we waited.
+	stdPackages.mu.RLock()
+	r, ok = stdPackages.results[importPath]
+	stdPackages.mu.RUnlock()
+	if ok {
+		return r.pkg, r.err
+	}
+	r.pkg, r.err = stdPackages.imp.Import(importPath)
+	stdPackages.mu.Lock()
+	stdPackages.results[importPath] = r
+	stdPackages.mu.Unlock()
+	return r.pkg, r.err
+}
+
+// moduleImplementations returns the implementations computed by
+// computeImplementations for the packages pkgs of the zip of
+// modulePath@version read by r. Commands are not analyzed, and the files of
+// each package are those that match the linux/amd64 build context. Files that
+// cannot be parsed are skipped.
+func moduleImplementations(r *zip.Reader, modulePath, version string, pkgs []*internal.LegacyPackage) ([]*internal.Implementation, error) {
+	prefix := moduleVersionDir(modulePath, version) + "/"
+	dirs := map[string][]*zip.File{}
+	for _, f := range r.File {
+		if !strings.HasPrefix(f.Name, prefix) || !strings.HasSuffix(f.Name, ".go") || strings.HasSuffix(f.Name, "_test.go") {
+			continue
+		}
+		dir := path.Dir(strings.TrimPrefix(f.Name, prefix))
+		dirs[dir] = append(dirs[dir], f)
+	}
+
+	fset := token.NewFileSet()
+	files := map[string][]*ast.File{}
+	for _, pkg := range pkgs {
+		if pkg.Name == "main" {
+			continue
+		}
+		innerPath := "."
+		if modulePath == stdlib.ModulePath {
+			innerPath = pkg.Path
+		} else if pkg.Path != modulePath {
+			innerPath = strings.TrimPrefix(pkg.Path, modulePath+"/")
+		}
+		contents, err := matchingFiles("linux", "amd64", dirs[innerPath])
+		if err != nil {
+			return nil, err
+		}
+		var names []string
+		for name := range contents {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			f, err := parser.ParseFile(fset, name, contents[name], 0)
+			if err != nil {
+				continue
+			}
+			files[pkg.Path] = append(files[pkg.Path], f)
+		}
+	}
+	return computeImplementations(fset, modulePath, files), nil
+}
+
+// computeImplementations type-checks the packages of the module modulePath,
+// whose files by import path are files, and returns which of their exported
+// types implement the exported interfaces declared by them or by the standard
+// library packages they import. Interfaces without methods and types that
+// are themselves interfaces are not considered. A type implements an
+// interface if either it or a pointer to it does.
+//
+// Type errors, like those from imports of other modules, which cannot be
+// resolved, are ignored.
+func computeImplementations(fset *token.FileSet, modulePath string, files map[string][]*ast.File) []*internal.Implementation {
+	checked := map[string]*types.Package{}
+	var check func(importPath string) (*types.Package, error)
+	imp := importerFunc(func(importPath string) (*types.Package, error) {
+		if importPath == "unsafe" {
+			return types.Unsafe, nil
+		}
+		if _, ok := files[importPath]; ok {
+			return check(importPath)
+		}
+		if stdlib.Contains(importPath) {
+			return importStd(importPath)
+		}
+		return nil, fmt.Errorf("cannot import %q", importPath)
+	})
+	check = func(importPath string) (*types.Package, error) {
+		if pkg, ok := checked[importPath]; ok {
+			if pkg == nil {
+				return nil, fmt.Errorf("import cycle through %q", importPath)
+			}
+			return pkg, nil
+		}
+		checked[importPath] = nil
+		conf := &types.Config{
+			Importer: imp,
+			Error:    func(error) {},
+		}
+		pkg, _ := conf.Check(importPath, fset, files[importPath], nil)
+		checked[importPath] = pkg
+		return pkg, nil
+	}
+
+	var paths []string
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	// Collect the candidate interfaces and types.
+	var (
+		ifaces []*types.TypeName
+		named  []*types.TypeName
+		seen   = map[*types.Package]bool{}
+	)
+	addInterfaces := func(pkg *types.Package) {
+		if seen[pkg] {
+			return
+		}
+		seen[pkg] = true
+		for _, obj := range exportedTypeNames(pkg) {
+			if it, ok := obj.Type().Underlying().(*types.Interface); ok && it.NumMethods() > 0 && it.IsMethodSet() {
+				ifaces = append(ifaces, obj)
+			}
+		}
+	}
+	for _, p := range paths {
+		pkg, _ := check(p)
+		if pkg == nil {
+			continue
+		}
+		addInterfaces(pkg)
+		for _, obj := range exportedTypeNames(pkg) {
+			if !types.IsInterface(obj.Type()) {
+				named = append(named, obj)
+			}
+		}
+	}
+	for _, p := range paths {
+		if pkg := checked[p]; pkg != nil {
+			for _, ip := range pkg.Imports() {
+				if stdlib.Contains(ip.Path()) {
+					addInterfaces(ip)
+				}
+			}
+		}
+	}
+
+	var impls []*internal.Implementation
+	for _, obj := range named {
+		for _, iface := range ifaces {
+			it := iface.Type().Underlying().(*types.Interface)
+			if !types.Implements(obj.Type(), it) && !types.Implements(types.NewPointer(obj.Type()), it) {
+				continue
+			}
+			impls = append(impls, &internal.Implementation{
+				InterfacePackagePath: iface.Pkg().Path(),
+				InterfaceName:        iface.Name(),
+				Type: internal.TypeRef{
+					PackagePath: obj.Pkg().Path(),
+					TypeName:    obj.Name(),
+					ModulePath:  modulePath,
+				},
+			})
+		}
+	}
+	return impls
+}
+
+// exportedTypeNames returns the exported named types declared at the top
+// level of pkg, sorted by name. Generic types are omitted.
+func exportedTypeNames(pkg *types.Package) []*types.TypeName {
+	var tns []*types.TypeName
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		if tn, ok := scope.Lookup(name).(*types.TypeName); ok && tn.Exported() && !tn.IsAlias() {
+			if n, ok := tn.Type().(*types.Named); ok && n.TypeParams().Len() > 0 {
+				continue
+			}
+			tns = append(tns, tn)
+		}
+	}
+	return tns
+}
+
+// importerFunc adapts a function to the types.Importer interface.
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
@@ -0,0 +1,118 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal"
+)
+
+func TestComputeImplementations(t *testing.T) {
+	const (
+		modulePath = "example.com/m"
+		srcA       = `
+package a
+
+// Shape is implemented by types of package b.
+type Shape interface {
+	Area() float64
+	Perimeter() float64
+}
+
+type Any interface{}
+
+type hidden interface {
+	Area() float64
+}
+
+// Square implements Shape.
+type Square struct{ Side float64 }
+
+func (s Square) Area() float64      { return s.Side * s.Side }
+func (s Square) Perimeter() float64 { return 4 * s.Side }
+`
+		srcB = `
+package b
+
+import (
+	"example.com/m/a"
+	"fmt"
+	"example.com/other/dep"
+)
+
+var _ a.Shape = (*Circle)(nil)
+
+// Circle implements a.Shape through a pointer, and fmt.Stringer.
+type Circle struct{ R float64 }
+
+func (c *Circle) Area() float64      { return 3 * c.R * c.R }
+func (c *Circle) Perimeter() float64 { return 6 * c.R }
+func (c *Circle) String() string     { return fmt.Sprint(c.R) }
+
+// Line implements neither.
+type Line struct{}
+
+func (Line) Area() float64 { return 0 }
+
+// Wrapper depends on another module, which cannot be resolved.
+type Wrapper struct{ D dep.T }
+
+type circle struct{ Circle }
+
+// Solid is an interface, so it is not an implementation.
+type Solid interface {
+	a.Shape
+	Volume() float64
+}
+`
+	)
+	fset := token.NewFileSet()
+	files := map[string][]*ast.File{
+		"example.com/m/a": {mustParse(fset, "a.go", srcA)},
+		"example.com/m/b": {mustParse(fset, "b.go", srcB)},
+	}
+	got := computeImplementations(fset, modulePath, files)
+	typ := func(pkgPath, name string) internal.TypeRef {
+		return internal.TypeRef{PackagePath: pkgPath, TypeName: name, ModulePath: modulePath}
+	}
+	want := []*internal.Implementation{
+		{InterfacePackagePath: "example.com/m/a", InterfaceName: "Shape", Type: typ("example.com/m/a", "Square")},
+		{InterfacePackagePath: "example.com/m/a", InterfaceName: "Shape", Type: typ("example.com/m/b", "Circle")},
+		{InterfacePackagePath: "fmt", InterfaceName: "Stringer", Type: typ("example.com/m/b", "Circle")},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("computeImplementations mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestImportStdConcurrent(t *testing.T) {
+	const n = 4
+	pkgs := make([]*types.Package, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			pkg, err := importStd("io")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			pkgs[i] = pkg
+		}(i)
+	}
+	wg.Wait()
+	for _, pkg := range pkgs[1:] {
+		if pkg != pkgs[0] {
+			t.Fatal("importStd returned different packages for the same path")
+		}
+	}
+}
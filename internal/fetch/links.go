@@ -0,0 +1,28 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/modulelinks"
+)
+
+// moduleLinks returns the links to the issue tracker and the CI service of
+// the repository of m, or nil if its host is unknown. The CI service is
+// detected from the configuration files of m if it is at the root of its
+// repository, because the files of the rest of the repository are not known.
+func moduleLinks(m *internal.Module) *internal.ModuleLinks {
+	repoURL := m.SourceInfo.RepoURL()
+	links := modulelinks.DetectModuleLinks(repoURL)
+	if links == nil || m.SourceInfo.ModuleDir() != "" {
+		return links
+	}
+	var files []string
+	for _, f := range m.Files {
+		files = append(files, f.Name)
+	}
+	links.CIURL = modulelinks.DetectCIURL(repoURL, files)
+	return links
+}
@@ -0,0 +1,83 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+	"golang.org/x/pkgsite/internal/version"
+)
+
+// retractions returns the versions retracted by the retract directives in the
+// go.mod file contents goMod. A directive retracts either a single version,
+// like
+//   retract v1.0.1
+// or a closed interval of versions, like
+//   retract [v1.0.0, v1.0.5]
+// Directives may also appear in a block. Intervals are returned in the form
+// "[v1.0.0, v1.0.5]"; see version.RetractionCovers. Malformed go.mod files
+// and directives retract nothing.
+func retractions(goMod []byte) []string {
+	f, err := parseGoModLax(goMod)
+	if err != nil {
+		return nil
+	}
+	var rs []string
+	add := func(args []string) {
+		if r := parseRetraction(args); r != "" {
+			rs = append(rs, r)
+		}
+	}
+	for _, stmt := range f.Syntax.Stmt {
+		switch x := stmt.(type) {
+		case *modfile.Line:
+			if x.Token[0] == "retract" {
+				add(x.Token[1:])
+			}
+		case *modfile.LineBlock:
+			if len(x.Token) != 1 || x.Token[0] != "retract" {
+				continue
+			}
+			for _, l := range x.Line {
+				add(l.Token)
+			}
+		}
+	}
+	return rs
+}
+
+// parseRetraction returns the version or interval of versions retracted by a
+// retract directive with the given arguments, or the empty string if the
+// arguments are malformed.
+func parseRetraction(args []string) string {
+	// The go.mod lexer does not split on brackets or commas, so join the
+	// arguments and split them ourselves.
+	s := strings.Join(args, "")
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		bounds := strings.Split(s[1:len(s)-1], ",")
+		if len(bounds) != 2 || !semver.IsValid(bounds[0]) || !semver.IsValid(bounds[1]) {
+			return ""
+		}
+		return fmt.Sprintf("[%s, %s]", bounds[0], bounds[1])
+	}
+	if !semver.IsValid(s) {
+		return ""
+	}
+	return s
+}
+
+// isRetracted reports whether the go.mod file contents goMod contain a
+// retract directive covering v.
+func isRetracted(goMod []byte, v string) bool {
+	for _, r := range retractions(goMod) {
+		if version.RetractionCovers(r, v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const retractGoMod = `
+module example.com/m
+
+go 1.14
+
+retract v1.0.1 // published accidentally
+
+retract (
+	[v1.1.0, v1.1.3]
+	v1.2.0-pre
+)
+
+retract [ v1.3.0,v1.3.0 ]
+retract [v1.4.0]
+`
+
+func TestRetractions(t *testing.T) {
+	got := retractions([]byte(retractGoMod))
+	want := []string{"v1.0.1", "[v1.1.0, v1.1.3]", "v1.2.0-pre", "[v1.3.0, v1.3.0]"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("retractions mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestIsRetracted(t *testing.T) {
+	for _, test := range []struct {
+		version string
+		want    bool
+	}{
+		{"v1.0.0", false},
+		{"v1.0.1", true},
+		{"v1.1.0", true},
+		{"v1.1.2", true},
+		{"v1.1.3", true},
+		{"v1.1.4", false},
+		{"v1.2.0-pre", true},
+		{"v1.2.0", false},
+		{"v1.3.0", true},
+		{"v1.4.0", false}, // malformed interval
+	} {
+		if got := isRetracted([]byte(retractGoMod), test.version); got != test.want {
+			t.Errorf("isRetracted(%q) = %t, want %t", test.version, got, test.want)
+		}
+	}
+
+	if isRetracted([]byte("module example.com/m"), "v1.0.0") {
+		t.Error("isRetracted with no retract directives = true, want false")
+	}
+	if isRetracted([]byte("module ("), "v1.0.0") {
+		t.Error("isRetracted with malformed go.mod = true, want false")
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"bytes"
+	"go/ast"
+	"go/printer"
+	"go/token"
+	"path"
+	"strings"
+
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/fetch/internal/doc"
+)
+
+// exportedSymbols returns the exported symbols declared in d, in the order in
+// which they appear in the documentation. Methods are included only if their
+// receiver type is exported. The positions of the declarations in d are
+// relative to fset, and the names of the files in fset are relative to
+// innerPath, the directory of the package in its module.
+func exportedSymbols(fset *token.FileSet, d *doc.Package, innerPath string) []*internal.Symbol {
+	var syms []*internal.Symbol
+	add := func(name string, kind internal.SymbolKind, docText string, decl ast.Decl) {
+		if !token.IsExported(name) {
+			return
+		}
+		sym := &internal.Symbol{
+			Name:      name,
+			Kind:      kind,
+			Synopsis:  doc.Synopsis(docText),
+			Signature: declSignature(fset, decl, name),
+		}
+		// The name of a method is qualified by its receiver type.
+		if p := fset.Position(declNamePos(decl, name[strings.LastIndex(name, ".")+1:])); p.IsValid() {
+			sym.FilePath = path.Join(innerPath, p.Filename)
+			sym.Line = p.Line
+		}
+		syms = append(syms, sym)
+	}
+	addValues := func(vals []*doc.Value, kind internal.SymbolKind) {
+		for _, v := range vals {
+			for _, n := range v.Names {
+				add(n, kind, v.Doc, v.Decl)
+			}
+		}
+	}
+
+	addValues(d.Consts, internal.SymbolKindConstant)
+	addValues(d.Vars, internal.SymbolKindVariable)
+	for _, f := range d.Funcs {
+		add(f.Name, internal.SymbolKindFunction, f.Doc, f.Decl)
+	}
+	for _, t := range d.Types {
+		if !token.IsExported(t.Name) {
+			continue
+		}
+		add(t.Name, internal.SymbolKindType, t.Doc, t.Decl)
+		addValues(t.Consts, internal.SymbolKindConstant)
+		addValues(t.Vars, internal.SymbolKindVariable)
+		for _, f := range t.Funcs {
+			add(f.Name, internal.SymbolKindFunction, f.Doc, f.Decl)
+		}
+		for _, m := range t.Methods {
+			if token.IsExported(m.Name) {
+				add(t.Name+"."+m.Name, internal.SymbolKindMethod, m.Doc, m.Decl)
+			}
+		}
+	}
+	return syms
+}
+
+// declNamePos returns the position of the identifier that declares name in
+// decl, or token.NoPos if decl does not declare name.
+func declNamePos(decl ast.Decl, name string) token.Pos {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Name.Name == name {
+			return d.Name.Pos()
+		}
+	case *ast.GenDecl:
+		for _, spec := range d.Specs {
+			switch s := spec.(type) {
+			case *ast.TypeSpec:
+				if s.Name.Name == name {
+					return s.Name.Pos()
+				}
+			case *ast.ValueSpec:
+				for _, n := range s.Names {
+					if n.Name == name {
+						return n.Pos()
+					}
+				}
+			}
+		}
+	}
+	return token.NoPos
+}
+
+// declSignature returns the source of the declaration of name in decl,
+// formatted as by gofmt, without comments or a function body. It returns the
+// empty string if decl does not declare name.
+func declSignature(fset *token.FileSet, decl ast.Decl, name string) string {
+	var node ast.Node
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		fd := *d
+		fd.Doc, fd.Body = nil, nil
+		node = &fd
+	case *ast.GenDecl:
+		// Print only the spec that declares name, not the whole group.
+		for _, spec := range d.Specs {
+			switch s := spec.(type) {
+			case *ast.TypeSpec:
+				if s.Name.Name == name {
+					ts := *s
+					ts.Doc, ts.Comment = nil, nil
+					node = &ast.GenDecl{Tok: d.Tok, Specs: []ast.Spec{&ts}}
+				}
+			case *ast.ValueSpec:
+				for _, n := range s.Names {
+					if n.Name == name {
+						vs := *s
+						vs.Doc, vs.Comment = nil, nil
+						node = &ast.GenDecl{Tok: d.Tok, Specs: []ast.Spec{&vs}}
+					}
+				}
+			}
+		}
+	}
+	if node == nil {
+		return ""
+	}
+
+	// The printer prints the comments of struct fields and interface methods,
+	// so remove them while printing.
+	var restore []func()
+	ast.Inspect(node, func(n ast.Node) bool {
+		if f, ok := n.(*ast.Field); ok && (f.Doc != nil || f.Comment != nil) {
+			doc, comment := f.Doc, f.Comment
+			f.Doc, f.Comment = nil, nil
+			restore = append(restore, func() { f.Doc, f.Comment = doc, comment })
+		}
+		return true
+	})
+	defer func() {
+		for _, r := range restore {
+			r()
+		}
+	}()
+	var buf bytes.Buffer
+	if err := (&printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}).Fprint(&buf, fset, node); err != nil {
+		return ""
+	}
+	return buf.String()
+}
@@ -0,0 +1,126 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"go/ast"
+	"go/token"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/fetch/internal/doc"
+)
+
+func TestExportedSymbols(t *testing.T) {
+	const src = `
+// Package p is a package.
+package p
+
+// Max is the maximum.
+const Max = 10
+
+const min = 0
+
+// Default is the default T.
+var Default = New()
+
+// F does something.
+func F() {}
+
+func g() {}
+
+// T is a type.
+type T struct {
+	// X is a field.
+	X int // trailing comment
+	y int
+}
+
+// Zero is the zero T.
+var Zero T
+
+// New returns a T.
+func New() *T { return nil }
+
+// M is a method.
+func (*T) M() {}
+
+func (*T) m() {}
+
+type u struct{}
+
+// U is a method of an unexported type.
+func (u) U() {}
+`
+	fset := token.NewFileSet()
+	f := mustParse(fset, "p.go", src)
+	d, err := doc.NewFromFiles(fset, []*ast.File{f}, "example.com/p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := exportedSymbols(fset, d, "p")
+	want := []*internal.Symbol{
+		{Name: "Max", Kind: internal.SymbolKindConstant, Synopsis: "Max is the maximum.", Signature: "const Max = 10", FilePath: "p/p.go", Line: 6},
+		{Name: "Default", Kind: internal.SymbolKindVariable, Synopsis: "Default is the default T.", Signature: "var Default = New()", FilePath: "p/p.go", Line: 11},
+		{Name: "F", Kind: internal.SymbolKindFunction, Synopsis: "F does something.", Signature: "func F()", FilePath: "p/p.go", Line: 14},
+		{
+			Name:      "T",
+			Kind:      internal.SymbolKindType,
+			Synopsis:  "T is a type.",
+			Signature: "type T struct {\n\tX int\n\t// contains filtered or unexported fields\n}",
+			FilePath:  "p/p.go",
+			Line:      19,
+		},
+		{Name: "Zero", Kind: internal.SymbolKindVariable, Synopsis: "Zero is the zero T.", Signature: "var Zero T", FilePath: "p/p.go", Line: 26},
+		{Name: "New", Kind: internal.SymbolKindFunction, Synopsis: "New returns a T.", Signature: "func New() *T", FilePath: "p/p.go", Line: 29},
+		{Name: "T.M", Kind: internal.SymbolKindMethod, Synopsis: "M is a method.", Signature: "func (*T) M()", FilePath: "p/p.go", Line: 32},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("exportedSymbols mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDeclSignature(t *testing.T) {
+	const src = `
+package p
+
+const (
+	// A is a.
+	A, B = 1, "b"
+	C    = 3.0 // C is c.
+)
+
+type (
+	I interface {
+		// M is a method.
+		M(int) error
+	}
+	S []string
+)
+`
+	fset := token.NewFileSet()
+	f := mustParse(fset, "p.go", src)
+	for _, test := range []struct {
+		decl ast.Decl
+		name string
+		want string
+	}{
+		{f.Decls[0], "B", `const A, B = 1, "b"`},
+		{f.Decls[0], "C", "const C = 3.0"},
+		{f.Decls[0], "D", ""},
+		{f.Decls[1], "I", "type I interface {\n\tM(int) error\n}"},
+		{f.Decls[1], "S", "type S []string"},
+	} {
+		if got := declSignature(fset, test.decl, test.name); got != test.want {
+			t.Errorf("declSignature(%q) = %q, want %q", test.name, got, test.want)
+		}
+	}
+	// The comments must be left in place.
+	field := f.Decls[1].(*ast.GenDecl).Specs[0].(*ast.TypeSpec).Type.(*ast.InterfaceType).Methods.List[0]
+	if field.Doc == nil {
+		t.Error("declSignature removed the doc comment of an interface method")
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"go/ast"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// hasFuzzTests reports whether any of the test files declares a fuzz
+// target: a function named Fuzz followed by an upper-case letter, with a
+// single parameter of type *testing.F.
+func hasFuzzTests(testFiles []*ast.File) bool {
+	return countTestFuncs(testFiles, "Fuzz", "F") > 0
+}
+
+// benchmarkCount returns the number of benchmarks declared in the test
+// files: functions named Benchmark followed by an upper-case letter, with a
+// single parameter of type *testing.B.
+func benchmarkCount(testFiles []*ast.File) int {
+	return countTestFuncs(testFiles, "Benchmark", "B")
+}
+
+// countTestFuncs returns the number of functions in files whose name is
+// prefix followed by an upper-case letter, and which have a single parameter
+// of type *testing.<typeName>.
+func countTestFuncs(files []*ast.File, prefix, typeName string) int {
+	n := 0
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && isTestFunc(fn, prefix, typeName) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func isTestFunc(fn *ast.FuncDecl, prefix, typeName string) bool {
+	if fn.Recv != nil || !strings.HasPrefix(fn.Name.Name, prefix) {
+		return false
+	}
+	if r, _ := utf8.DecodeRuneInString(fn.Name.Name[len(prefix):]); !unicode.IsUpper(r) {
+		return false
+	}
+	params := fn.Type.Params.List
+	if len(params) != 1 || len(params[0].Names) > 1 {
+		return false
+	}
+	// The testing package may be imported under another name, so only the
+	// name of the type is checked.
+	star, ok := params[0].Type.(*ast.StarExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := star.X.(*ast.SelectorExpr)
+	return ok && sel.Sel.Name == typeName
+}
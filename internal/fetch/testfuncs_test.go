@@ -0,0 +1,64 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"go/ast"
+	"go/token"
+	"testing"
+)
+
+func TestHasFuzzTests(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		src  string
+		want bool
+	}{
+		{"fuzz target", "package p\n\nimport \"testing\"\n\nfunc FuzzParse(f *testing.F) {}\n", true},
+		{"renamed import", "package p\n\nimport tt \"testing\"\n\nfunc FuzzParse(f *tt.F) {}\n", true},
+		{"test only", "package p\n\nimport \"testing\"\n\nfunc TestParse(t *testing.T) {}\n", false},
+		{"lower-case suffix", "package p\n\nimport \"testing\"\n\nfunc Fuzzy(f *testing.F) {}\n", false},
+		{"no suffix", "package p\n\nimport \"testing\"\n\nfunc Fuzz(f *testing.F) {}\n", false},
+		{"wrong parameter", "package p\n\nfunc FuzzParse(data []byte) int { return 0 }\n", false},
+		{"method", "package p\n\nimport \"testing\"\n\ntype s struct{}\n\nfunc (s) FuzzParse(f *testing.F) {}\n", false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			f := mustParse(token.NewFileSet(), "p_test.go", test.src)
+			if got := hasFuzzTests([]*ast.File{f}); got != test.want {
+				t.Errorf("hasFuzzTests = %t, want %t", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBenchmarkCount(t *testing.T) {
+	const src = `
+package p
+
+import "testing"
+
+func BenchmarkParse(b *testing.B) {}
+
+func BenchmarkFormat(b *testing.B) {}
+
+// Not benchmarks.
+func Benchmarking(b *testing.B)      {}
+func BenchmarkWrong(t *testing.T)    {}
+func TestParse(t *testing.T)         {}
+func benchmarkHelper(b *testing.B)   {}
+func BenchmarkTwo(a, b *testing.B)   {}
+`
+	fset := token.NewFileSet()
+	files := []*ast.File{
+		mustParse(fset, "p_test.go", src),
+		mustParse(fset, "q_test.go", "package p\n\nimport \"testing\"\n\nfunc BenchmarkOther(b *testing.B) {}\n"),
+	}
+	if got, want := benchmarkCount(files), 3; got != want {
+		t.Errorf("benchmarkCount = %d, want %d", got, want)
+	}
+	if got := benchmarkCount(nil); got != 0 {
+		t.Errorf("benchmarkCount(nil) = %d, want 0", got)
+	}
+}
@@ -0,0 +1,35 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"archive/zip"
+	"path"
+
+	"golang.org/x/pkgsite/internal"
+)
+
+// zipWorkspace returns the workspace declared by the go.work file at the root
+// of the zip of modulePath@version read by r. It returns nil if there is no
+// such file.
+func zipWorkspace(r *zip.Reader, modulePath, version string) (*internal.Workspace, error) {
+	name := path.Join(moduleVersionDir(modulePath, version), "go.work")
+	for _, f := range r.File {
+		if f.Name != name {
+			continue
+		}
+		content, err := readZipFile(f)
+		if err != nil {
+			return nil, err
+		}
+		w, err := internal.ParseWorkspaceFile(content)
+		if err != nil {
+			return nil, err
+		}
+		w.SetRepoPath(modulePath)
+		return w, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,54 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/testing/testhelper"
+)
+
+func TestZipWorkspace(t *testing.T) {
+	zipReader := func(contents map[string]string) *zip.Reader {
+		data, err := testhelper.ZipContents(contents)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	r := zipReader(map[string]string{
+		"m.com@v1.0.0/go.mod":      "module m.com\n",
+		"m.com@v1.0.0/go.work":     "go 1.18\n\nuse (\n\t.\n\t./tools\n)\n",
+		"m.com@v1.0.0/sub/go.work": "use ./x\n",
+	})
+	got, err := zipWorkspace(r, "m.com", "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &internal.Workspace{
+		RepoPath:    "m.com",
+		GoVersion:   "1.18",
+		UseDirs:     []string{".", "tools"},
+		ModulePaths: []string{"m.com", "m.com/tools"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	r = zipReader(map[string]string{"m.com@v1.0.0/go.mod": "module m.com\n"})
+	got, err = zipWorkspace(r, "m.com", "v1.0.0")
+	if err != nil || got != nil {
+		t.Errorf("zipWorkspace without go.work = %v, %v; want nil, nil", got, err)
+	}
+}
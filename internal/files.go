@@ -0,0 +1,23 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package internal
+
+// FileInfo describes a file or directory of a module version.
+type FileInfo struct {
+	// Name is the slash-separated path of the file relative to the module
+	// root, like "internal/foo/foo.go".
+	Name string
+	// Size is the uncompressed size of the file in bytes. For directories, it
+	// is the total size of the files in the directory and its subdirectories.
+	Size  int64
+	IsDir bool
+	// ContentType is the MIME type of the file, like
+	// "text/x-go; charset=utf-8". It is empty for directories.
+	ContentType string
+	// Contents holds the contents of small text files of redistributable
+	// modules. It is nil for other files and for directories, and when the
+	// file is part of a directory listing.
+	Contents []byte
+}
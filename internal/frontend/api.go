@@ -0,0 +1,138 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/api"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/log"
+	"golang.org/x/pkgsite/internal/postgres"
+	"golang.org/x/pkgsite/internal/stdlib"
+)
+
+const (
+	// packageAPIPrefix is the path prefix of the packages API endpoint.
+	packageAPIPrefix = "/api/v1/packages/"
+	// packageAPIPrefixV2 is the path prefix of the packages API endpoint in
+	// version 2 of the API.
+	packageAPIPrefixV2 = "/api/v2/packages/"
+)
+
+// handlePackageAPI handles requests for
+// /api/v1/packages/<import-path>[?version=<version>] by responding with the
+// JSON description of the package. See the api package for details.
+func (s *Server) handlePackageAPI(w http.ResponseWriter, r *http.Request) {
+	s.servePackageAPI(w, r, packageAPIPrefix, func(pkg *api.Package) interface{} { return pkg })
+}
+
+// handlePackageAPIV2 handles requests for
+// /api/v2/packages/<import-path>[?version=<version>] by responding with the
+// api.PackageV2 of the package.
+func (s *Server) handlePackageAPIV2(w http.ResponseWriter, r *http.Request) {
+	s.servePackageAPI(w, r, packageAPIPrefixV2, func(pkg *api.Package) interface{} { return api.NewPackageV2(pkg) })
+}
+
+// servePackageAPI responds to a request for the package whose import path
+// follows prefix in the path of r, with the JSON encoding of the result of
+// calling convert on its api.Package.
+func (s *Server) servePackageAPI(w http.ResponseWriter, r *http.Request, prefix string, convert func(*api.Package) interface{}) {
+	ctx := r.Context()
+	pkg, err := s.packageAPIResponse(ctx, r, prefix)
+	if err != nil {
+		status := http.StatusInternalServerError
+		var serr *serverError
+		if errors.As(err, &serr) {
+			status = serr.status
+		}
+		if status == http.StatusInternalServerError {
+			log.Errorf(ctx, "servePackageAPI: %v", err)
+		}
+		WriteJSONError(w, newProblemDetail(r, status, ""))
+		return
+	}
+	writeJSON(ctx, w, http.StatusOK, convert(pkg))
+}
+
+// packageAPIResponse returns the api.Package for the package requested by r,
+// whose import path follows prefix in the path of r.
+func (s *Server) packageAPIResponse(ctx context.Context, r *http.Request, prefix string) (_ *api.Package, err error) {
+	pkgPath := strings.Trim(strings.TrimPrefix(r.URL.Path, prefix), "/")
+	defer derrors.Wrap(&err, "packageAPIResponse(ctx, %q)", pkgPath)
+
+	if err := module.CheckImportPath(pkgPath); err != nil {
+		return nil, &serverError{status: http.StatusBadRequest, err: err}
+	}
+	modulePath := internal.UnknownModulePath
+	version := r.FormValue("version")
+	if version == "" {
+		version = internal.LatestVersion
+	}
+	if stdlib.Contains(pkgPath) {
+		modulePath = stdlib.ModulePath
+		if v := stdlib.VersionForTag(version); v != "" {
+			version = v
+		}
+	}
+	if err := checkPathAndVersion(ctx, s.ds, pkgPath, version); err != nil {
+		return nil, err
+	}
+	pkg, err := s.ds.LegacyGetPackage(ctx, pkgPath, modulePath, version)
+	if err != nil {
+		if errors.Is(err, derrors.NotFound) {
+			return nil, &serverError{status: http.StatusNotFound, err: err}
+		}
+		return nil, err
+	}
+	return s.apiPackage(ctx, pkg)
+}
+
+// apiPackage returns the api.Package for pkg.
+func (s *Server) apiPackage(ctx context.Context, pkg *internal.LegacyVersionedPackage) (_ *api.Package, err error) {
+	var importedByCount int
+	if db, ok := s.ds.(*postgres.DB); ok {
+		importedByCount, err = db.GetImportedByCount(ctx, pkg.Path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return api.NewPackage(pkg, importedByCount), nil
+}
+
+// writePackageJSON writes the api.Package for pkg to w, in response to a
+// request for the details page of pkg that asks for JSON.
+func (s *Server) writePackageJSON(ctx context.Context, w http.ResponseWriter, pkg *internal.LegacyVersionedPackage) error {
+	res, err := s.apiPackage(ctx, pkg)
+	if err != nil {
+		return err
+	}
+	writeJSON(ctx, w, http.StatusOK, res)
+	return nil
+}
+
+// writeJSON writes v to w as JSON, with the given status.
+func writeJSON(ctx context.Context, w http.ResponseWriter, status int, v interface{}) {
+	response, err := json.Marshal(v)
+	if err != nil {
+		log.Errorf(ctx, "writeJSON: json.Marshal: %v", err)
+		status := http.StatusInternalServerError
+		WriteJSONError(w, ProblemDetail{Type: "about:blank", Title: http.StatusText(status), Status: status})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if _, err := io.Copy(w, bytes.NewReader(response)); err != nil {
+		log.Errorf(ctx, "Error copying json buffer to ResponseWriter: %v", err)
+	}
+}
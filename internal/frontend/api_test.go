@@ -0,0 +1,179 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal/api"
+	"golang.org/x/pkgsite/internal/postgres"
+	"golang.org/x/pkgsite/internal/testing/sample"
+)
+
+func TestHandlePackageAPI(t *testing.T) {
+	skipIfNoDB(t)
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	defer postgres.ResetTestDB(testDB, t)
+	for _, m := range []struct {
+		modulePath, version string
+	}{
+		{sample.ModulePath, "v1.0.0"},
+		{sample.ModulePath, "v1.1.0"},
+		{"excluded.com/m", "v1.0.0"},
+	} {
+		if err := testDB.InsertModule(ctx, sample.Module(m.modulePath, m.version, sample.Suffix)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := testDB.InsertExcludedPrefix(ctx, "excluded.com", "user", "for testing"); err != nil {
+		t.Fatal(err)
+	}
+	_, handler, _ := newTestServer(t, nil)
+
+	pkgPath := sample.ModulePath + "/" + sample.Suffix
+	problem := func(status int, path string) *ProblemDetail {
+		return &ProblemDetail{Type: "about:blank", Title: http.StatusText(status), Status: status, Instance: path}
+	}
+	wantPackage := func(version string) *api.Package {
+		return &api.Package{
+			PackagePath: pkgPath,
+			ModulePath:  sample.ModulePath,
+			Version:     version,
+			Synopsis:    sample.Synopsis,
+			Licenses: []*api.License{
+				{Types: sample.LicenseMetadata[0].Types, FilePath: sample.LicenseMetadata[0].FilePath},
+			},
+			CommitTime:        sample.CommitTime,
+			IsRedistributable: true,
+		}
+	}
+	for _, test := range []struct {
+		name, path string
+		wantStatus int
+		want       interface{}
+	}{
+		{
+			name:       "latest",
+			path:       "/api/v1/packages/" + pkgPath,
+			wantStatus: http.StatusOK,
+			want:       wantPackage("v1.1.0"),
+		},
+		{
+			name:       "version",
+			path:       "/api/v1/packages/" + pkgPath + "?version=v1.0.0",
+			wantStatus: http.StatusOK,
+			want:       wantPackage("v1.0.0"),
+		},
+		{
+			name:       "not found",
+			path:       "/api/v1/packages/github.com/not/found",
+			wantStatus: http.StatusNotFound,
+			want:       problem(http.StatusNotFound, "/api/v1/packages/github.com/not/found"),
+		},
+		{
+			name:       "version not found",
+			path:       "/api/v1/packages/" + pkgPath + "?version=v1.2.0",
+			wantStatus: http.StatusNotFound,
+			want:       problem(http.StatusNotFound, "/api/v1/packages/"+pkgPath),
+		},
+		{
+			name:       "excluded",
+			path:       "/api/v1/packages/excluded.com/m/" + sample.Suffix,
+			wantStatus: http.StatusNotFound,
+			want:       problem(http.StatusNotFound, "/api/v1/packages/excluded.com/m/"+sample.Suffix),
+		},
+		{
+			name:       "bad version",
+			path:       "/api/v1/packages/" + pkgPath + "?version=bad",
+			wantStatus: http.StatusBadRequest,
+			want:       problem(http.StatusBadRequest, "/api/v1/packages/"+pkgPath),
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+			if w.Code != test.wantStatus {
+				t.Fatalf("GET %q = %d, want %d", test.path, w.Code, test.wantStatus)
+			}
+			wantContentType := "application/json"
+			if test.wantStatus != http.StatusOK {
+				wantContentType = problemContentType
+			}
+			if got, want := w.Header().Get("Content-Type"), wantContentType; got != want {
+				t.Errorf("GET %q: Content-Type = %q, want %q", test.path, got, want)
+			}
+			var got interface{}
+			switch test.want.(type) {
+			case *api.Package:
+				got = &api.Package{}
+			default:
+				got = &ProblemDetail{}
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), got); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("GET %q mismatch (-want +got):\n%s", test.path, diff)
+			}
+		})
+	}
+}
+
+func TestPackagePageContentNegotiation(t *testing.T) {
+	skipIfNoDB(t)
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	defer postgres.ResetTestDB(testDB, t)
+	if err := testDB.InsertModule(ctx, sample.Module(sample.ModulePath, sample.VersionString, sample.Suffix)); err != nil {
+		t.Fatal(err)
+	}
+	_, handler, _ := newTestServer(t, nil)
+
+	pkgPath := sample.ModulePath + "/" + sample.Suffix
+	path := "/" + pkgPath + "?tab=doc"
+	for _, test := range []struct {
+		accept          string
+		wantContentType string
+	}{
+		{"text/html", "text/html; charset=utf-8"},
+		{"text/html,application/xhtml+xml,*/*;q=0.8", "text/html; charset=utf-8"},
+		{"application/json", "application/json"},
+		{"text/html;q=0.5, application/json", "application/json"},
+	} {
+		t.Run(test.accept, func(t *testing.T) {
+			r := httptest.NewRequest("GET", path, nil)
+			r.Header.Set("Accept", test.accept)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != http.StatusOK {
+				t.Fatalf("GET %q = %d, want %d", path, w.Code, http.StatusOK)
+			}
+			if got := w.Header().Get("Content-Type"); got != test.wantContentType {
+				t.Fatalf("Content-Type = %q, want %q", got, test.wantContentType)
+			}
+			if test.wantContentType != "application/json" {
+				return
+			}
+			// The JSON response is the same as that of the API.
+			var got api.Package
+			dec := json.NewDecoder(w.Body)
+			dec.DisallowUnknownFields()
+			if err := dec.Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got.PackagePath != pkgPath || got.Version != sample.VersionString {
+				t.Errorf("got package %s@%s, want %s@%s", got.PackagePath, got.Version, pkgPath, sample.VersionString)
+			}
+		})
+	}
+}
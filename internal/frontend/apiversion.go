@@ -0,0 +1,130 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/pkgsite/internal/middleware"
+)
+
+// apiVersions are the supported versions of the API. See the api package for
+// their stability guarantees.
+var apiVersions = []int{1, 2}
+
+// APIPathPrefix is the path prefix of the endpoints of all versions of the
+// API.
+const APIPathPrefix = "/api/"
+
+// apiVersionPrefix returns the path prefix of the endpoints of the given
+// version of the API, like "/api/v1/".
+func apiVersionPrefix(version int) string {
+	return fmt.Sprintf("%sv%d/", APIPathPrefix, version)
+}
+
+// VersionedMux returns a mux that serves the endpoints of the given version
+// of the API, at paths beginning with its prefix, like "/api/v1/". Every
+// response of the mux, including those to requests for unknown endpoints, has
+// an API-Version header holding the version. The mux has no endpoints for
+// an unsupported version.
+func (s *Server) VersionedMux(version int) *http.ServeMux {
+	mux := http.NewServeMux()
+	handle := func(pattern string, h http.HandlerFunc) {
+		mux.Handle(pattern, apiVersionHeader(version)(middleware.CORS(s.allowedOrigins)(h)))
+	}
+	switch version {
+	case 1:
+		handle(packageAPIPrefix, s.handlePackageAPI)
+	case 2:
+		handle(packageAPIPrefixV2, s.handlePackageAPIV2)
+	default:
+		return mux
+	}
+	handle(apiVersionPrefix(version), http.NotFound)
+	return mux
+}
+
+// apiVersionHeader returns a middleware that sets the API-Version header of
+// responses to version.
+func apiVersionHeader(version int) middleware.Middleware {
+	v := strconv.Itoa(version)
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("API-Version", v)
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// VersionNegotiationMiddleware returns a middleware that serves the API
+// requests whose Accept header asks for a media type of the form
+// application/vnd.pkgsite.vN+json with muxes[N], as if the version in their
+// path was N. So a request for /api/v1/packages/<import-path> that accepts
+// application/vnd.pkgsite.v2+json is served as a request for
+// /api/v2/packages/<import-path>. If there is no mux for the version, it
+// responds with 406 Not Acceptable. Other requests are served by the wrapped
+// handler.
+func VersionNegotiationMiddleware(muxes map[int]*http.ServeMux) middleware.Middleware {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// The response depends on the Accept header, so caches must
+			// not serve it for other values.
+			w.Header().Add("Vary", "Accept")
+			version, ok := acceptedAPIVersion(r.Header.Get("Accept"))
+			m := apiVersionPathRegexp.FindStringIndex(r.URL.Path)
+			if !ok || m == nil {
+				h.ServeHTTP(w, r)
+				return
+			}
+			mux, ok := muxes[version]
+			if !ok {
+				WriteJSONError(w, newProblemDetail(r, http.StatusNotAcceptable,
+					fmt.Sprintf("API version %d is not supported", version)))
+				return
+			}
+			r2 := r.Clone(r.Context())
+			r2.URL.Path = apiVersionPrefix(version) + r.URL.Path[m[1]:]
+			r2.URL.RawPath = ""
+			mux.ServeHTTP(w, r2)
+		})
+	}
+}
+
+var (
+	// apiVersionPathRegexp matches the version prefix of the path of an API
+	// request.
+	apiVersionPathRegexp = regexp.MustCompile(`^/api/v[0-9]+/`)
+
+	// apiMediaTypeRegexp matches the media types that ask for a version of
+	// the API.
+	apiMediaTypeRegexp = regexp.MustCompile(`^application/vnd\.pkgsite\.v([0-9]+)\+json$`)
+)
+
+// acceptedAPIVersion returns the version of the API asked for by the first
+// media type of the form application/vnd.pkgsite.vN+json in the Accept header
+// value accept. It reports false if there is no such media type.
+func acceptedAPIVersion(accept string) (int, bool) {
+	for _, mt := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(mt)
+		if err != nil {
+			continue
+		}
+		m := apiMediaTypeRegexp.FindStringSubmatch(mediaType)
+		if m == nil {
+			continue
+		}
+		version, err := strconv.Atoi(m[1])
+		if err != nil {
+			continue
+		}
+		return version, true
+	}
+	return 0, false
+}
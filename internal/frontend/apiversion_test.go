@@ -0,0 +1,149 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/api"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/testing/sample"
+)
+
+// apiDataSource is a DataSource with a single package.
+type apiDataSource struct {
+	internal.DataSource
+	pkg *internal.LegacyVersionedPackage
+}
+
+func (ds apiDataSource) LegacyGetPackage(ctx context.Context, pkgPath, modulePath, version string) (*internal.LegacyVersionedPackage, error) {
+	if pkgPath != ds.pkg.Path {
+		return nil, derrors.NotFound
+	}
+	return ds.pkg, nil
+}
+
+func TestVersionedAPI(t *testing.T) {
+	pkg := &internal.LegacyVersionedPackage{
+		LegacyPackage:    *sample.LegacyPackage(sample.ModulePath, sample.Suffix),
+		LegacyModuleInfo: *sample.LegacyModuleInfo(sample.ModulePath, sample.VersionString),
+	}
+	requiresCgo := true
+	pkg.Facts.RequiresCgo = &requiresCgo
+	s := &Server{ds: apiDataSource{pkg: pkg}}
+	mux := http.NewServeMux()
+	s.Install(mux.Handle, nil)
+
+	v1 := api.NewPackage(pkg, 0)
+	v2 := api.NewPackageV2(v1)
+
+	for _, test := range []struct {
+		name, path, accept string
+		wantStatus         int
+		wantVersion        string
+		want               interface{}
+	}{
+		{
+			name:        "v1",
+			path:        "/api/v1/packages/" + pkg.Path,
+			wantStatus:  http.StatusOK,
+			wantVersion: "1",
+			want:        v1,
+		},
+		{
+			name:        "v2",
+			path:        "/api/v2/packages/" + pkg.Path,
+			wantStatus:  http.StatusOK,
+			wantVersion: "2",
+			want:        v2,
+		},
+		{
+			name:        "v2 by Accept header",
+			path:        "/api/v1/packages/" + pkg.Path,
+			accept:      "application/json, application/vnd.pkgsite.v2+json; q=0.9",
+			wantStatus:  http.StatusOK,
+			wantVersion: "2",
+			want:        v2,
+		},
+		{
+			name:        "v1 by Accept header",
+			path:        "/api/v2/packages/" + pkg.Path,
+			accept:      "application/vnd.pkgsite.v1+json",
+			wantStatus:  http.StatusOK,
+			wantVersion: "1",
+			want:        v1,
+		},
+		{
+			name:       "unsupported version",
+			path:       "/api/v1/packages/" + pkg.Path,
+			accept:     "application/vnd.pkgsite.v3+json",
+			wantStatus: http.StatusNotAcceptable,
+		},
+		{
+			name:        "unknown endpoint",
+			path:        "/api/v2/modules/" + pkg.ModulePath,
+			wantStatus:  http.StatusNotFound,
+			wantVersion: "2",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", test.path, nil)
+			if test.accept != "" {
+				r.Header.Set("Accept", test.accept)
+			}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+			if w.Code != test.wantStatus {
+				t.Fatalf("GET %q = %d, want %d", test.path, w.Code, test.wantStatus)
+			}
+			if got := w.Header().Get("API-Version"); got != test.wantVersion {
+				t.Errorf("API-Version = %q, want %q", got, test.wantVersion)
+			}
+			if test.want == nil {
+				return
+			}
+			var got interface{}
+			switch test.want.(type) {
+			case *api.Package:
+				got = &api.Package{}
+			case *api.PackageV2:
+				got = &api.PackageV2{}
+			}
+			dec := json.NewDecoder(w.Body)
+			dec.DisallowUnknownFields()
+			if err := dec.Decode(got); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAcceptedAPIVersion(t *testing.T) {
+	for _, test := range []struct {
+		accept string
+		want   int
+		wantOK bool
+	}{
+		{"", 0, false},
+		{"application/json", 0, false},
+		{"application/vnd.pkgsite.v2+json", 2, true},
+		{"text/html, application/vnd.pkgsite.v1+json;q=0.8, application/vnd.pkgsite.v2+json", 1, true},
+		{"application/vnd.pkgsite.vX+json", 0, false},
+	} {
+		got, ok := acceptedAPIVersion(test.accept)
+		if got != test.want || ok != test.wantOK {
+			t.Errorf("acceptedAPIVersion(%q) = %d, %t, want %d, %t", test.accept, got, ok, test.want, test.wantOK)
+		}
+	}
+}
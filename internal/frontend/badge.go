@@ -0,0 +1,105 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"golang.org/x/mod/semver"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/log"
+	"golang.org/x/pkgsite/internal/version"
+)
+
+const (
+	badgePathPrefix = "/badge/"
+	badgeLabel      = "pkg.go.dev"
+
+	badgeColorRelease = "#4c1"
+	badgeColorPseudo  = "#dfb317"
+	badgeColorUnknown = "#9f9f9f"
+)
+
+// badgeTemplate is a minimal flat badge, with badgeLabel on the left and a
+// version on the right.
+var badgeTemplate = template.Must(template.New("badge").Parse(`<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="20" role="img" aria-label="{{.Label | html}}: {{.Message | html}}">
+<title>{{.Label | html}}: {{.Message | html}}</title>
+<rect width="{{.LabelWidth}}" height="20" fill="#555"/>
+<rect x="{{.LabelWidth}}" width="{{.MessageWidth}}" height="20" fill="{{.Color}}"/>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="{{.LabelX}}" y="14">{{.Label | html}}</text>
+<text x="{{.MessageX}}" y="14">{{.Message | html}}</text>
+</g>
+</svg>
+`))
+
+// handleBadge handles requests for /badge/<import-path>.svg, by serving an
+// SVG image that shows the latest version of the package.
+func (s *Server) handleBadge(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	pkgPath := strings.TrimPrefix(r.URL.Path, badgePathPrefix)
+	if !strings.HasSuffix(pkgPath, ".svg") {
+		http.NotFound(w, r)
+		return
+	}
+	pkgPath = strings.TrimSuffix(pkgPath, ".svg")
+	if pkgPath == "" {
+		http.NotFound(w, r)
+		return
+	}
+	body, err := badgeSVG(s.LatestVersion(ctx, pkgPath, internal.UnknownModulePath, "pkg"))
+	if err != nil {
+		log.Errorf(ctx, "handleBadge: %v", err)
+		code := http.StatusInternalServerError
+		http.Error(w, http.StatusText(code), code)
+		return
+	}
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Cache-Control", "max-age=3600")
+	if _, err := w.Write(body); err != nil {
+		log.Errorf(ctx, "handleBadge, writing: %v", err)
+	}
+}
+
+// badgeSVG returns a badge image for the given latest version. Release and
+// prerelease versions are shown in green and pseudo-versions in yellow. If
+// latestVersion is empty or not a valid version, the badge says "unknown".
+func badgeSVG(latestVersion string) ([]byte, error) {
+	message, color := latestVersion, badgeColorRelease
+	switch {
+	case !semver.IsValid(latestVersion):
+		message, color = "unknown", badgeColorUnknown
+	case version.IsPseudo(latestVersion):
+		color = badgeColorPseudo
+	}
+	labelWidth, messageWidth := badgeTextWidth(badgeLabel), badgeTextWidth(message)
+	var buf bytes.Buffer
+	if err := badgeTemplate.Execute(&buf, map[string]interface{}{
+		"Label":        badgeLabel,
+		"Message":      message,
+		"Color":        color,
+		"Width":        labelWidth + messageWidth,
+		"LabelWidth":   labelWidth,
+		"MessageWidth": messageWidth,
+		"LabelX":       labelWidth / 2,
+		"MessageX":     labelWidth + messageWidth/2,
+	}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// badgeTextWidth returns an estimate of the width in pixels of a badge
+// section containing s, including padding.
+func badgeTextWidth(s string) int {
+	const (
+		charWidth = 7
+		padding   = 10
+	)
+	return len(s)*charWidth + 2*padding
+}
@@ -0,0 +1,94 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+// badgeDataSource is a DataSource whose packages have the latest versions in
+// the map.
+type badgeDataSource struct {
+	internal.DataSource
+	latest map[string]string
+}
+
+func (ds badgeDataSource) LegacyGetPackage(ctx context.Context, pkgPath, modulePath, version string) (*internal.LegacyVersionedPackage, error) {
+	v, ok := ds.latest[pkgPath]
+	if !ok {
+		return nil, derrors.NotFound
+	}
+	return &internal.LegacyVersionedPackage{
+		LegacyModuleInfo: internal.LegacyModuleInfo{ModuleInfo: internal.ModuleInfo{Version: v}},
+	}, nil
+}
+
+func TestHandleBadge(t *testing.T) {
+	s := &Server{ds: badgeDataSource{latest: map[string]string{
+		"github.com/release/pkg": "v1.2.3",
+		"github.com/pseudo/pkg":  "v0.0.0-20200101120000-abcdef123456",
+	}}}
+	for _, test := range []struct {
+		name, path             string
+		wantStatus             int
+		wantMessage, wantColor string
+	}{
+		{"release", "/badge/github.com/release/pkg.svg", http.StatusOK, "v1.2.3", badgeColorRelease},
+		{"pseudo", "/badge/github.com/pseudo/pkg.svg", http.StatusOK, "v0.0.0-20200101120000-abcdef123456", badgeColorPseudo},
+		{"unknown", "/badge/github.com/not/found.svg", http.StatusOK, "unknown", badgeColorUnknown},
+		{"no suffix", "/badge/github.com/release/pkg", http.StatusNotFound, "", ""},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.handleBadge(w, httptest.NewRequest("GET", test.path, nil))
+			if w.Code != test.wantStatus {
+				t.Fatalf("GET %q = %d, want %d", test.path, w.Code, test.wantStatus)
+			}
+			if test.wantStatus != http.StatusOK {
+				return
+			}
+			for header, want := range map[string]string{
+				"Content-Type":  "image/svg+xml",
+				"Cache-Control": "max-age=3600",
+			} {
+				if got := w.Header().Get(header); got != want {
+					t.Errorf("%s = %q, want %q", header, got, want)
+				}
+			}
+			body := w.Body.String()
+			checkWellFormedXML(t, w.Body.Bytes())
+			if !strings.Contains(body, ">"+test.wantMessage+"</text>") {
+				t.Errorf("badge does not contain %q:\n%s", test.wantMessage, body)
+			}
+			if !strings.Contains(body, `fill="`+test.wantColor+`"`) {
+				t.Errorf("badge does not have color %q:\n%s", test.wantColor, body)
+			}
+		})
+	}
+}
+
+func checkWellFormedXML(t *testing.T, body []byte) {
+	t.Helper()
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		_, err := dec.Token()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			t.Fatalf("malformed XML: %v\n%s", err, body)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/stdlib"
+)
+
+// comparePathPrefix is the path prefix of the package comparison page.
+const comparePathPrefix = "/compare/"
+
+// ComparePage contains data for the page that compares the exported API of
+// two versions of a package.
+type ComparePage struct {
+	basePage
+	PackagePath string
+	// FromVersion and ToVersion are the display versions being compared.
+	FromVersion, ToVersion string
+	Diff                   *SymbolDiff
+}
+
+// SymbolDiff is the difference between the exported symbols of two versions
+// of a package. Each of its lists is sorted by symbol name.
+//
+// A symbol is identified by its name, so a renamed symbol is reported as
+// removed under its old name and added under its new one.
+type SymbolDiff struct {
+	Added   []*internal.Symbol
+	Removed []*internal.Symbol
+	Changed []*SymbolChange
+}
+
+// SymbolChange is a symbol whose kind or signature differs between two
+// versions of a package.
+type SymbolChange struct {
+	From, To *internal.Symbol
+}
+
+// handleCompare handles requests for
+// /compare/<import-path>?from=<version>&to=<version>, by serving a page that
+// lists the symbols that were added, removed or changed between the two
+// versions of the package.
+func (s *Server) handleCompare(w http.ResponseWriter, r *http.Request) (err error) {
+	pkgPath := strings.Trim(strings.TrimPrefix(r.URL.Path, comparePathPrefix), "/")
+	defer derrors.Wrap(&err, "handleCompare(w, r[%q])", pkgPath)
+
+	if err := module.CheckImportPath(pkgPath); err != nil {
+		return &serverError{
+			status: http.StatusBadRequest,
+			err:    err,
+			epage:  &errorPage{Message: fmt.Sprintf("%q is not a valid import path.", pkgPath)},
+		}
+	}
+	ctx := r.Context()
+	// The module path is only needed to display the versions.
+	modulePath := internal.UnknownModulePath
+	if stdlib.Contains(pkgPath) {
+		modulePath = stdlib.ModulePath
+	}
+	var versions [2]string
+	for i, param := range []string{"from", "to"} {
+		v := r.FormValue(param)
+		if modulePath == stdlib.ModulePath {
+			if sv := stdlib.VersionForTag(v); sv != "" {
+				v = sv
+			}
+		}
+		if !semver.IsValid(v) {
+			return &serverError{
+				status: http.StatusBadRequest,
+				err:    fmt.Errorf("invalid %s version %q", param, v),
+				epage: &errorPage{
+					Message:          fmt.Sprintf("%q is not a valid semantic version.", r.FormValue(param)),
+					SecondaryMessage: template.HTML(fmt.Sprintf("Use <code>%s%s?from=&lt;version&gt;&amp;to=&lt;version&gt;</code>.", comparePathPrefix, template.HTMLEscapeString(pkgPath))),
+				},
+			}
+		}
+		versions[i] = v
+	}
+	if err := checkPathAndVersion(ctx, s.ds, pkgPath, versions[0]); err != nil {
+		return err
+	}
+
+	var symbols [2][]*internal.Symbol
+	for i, v := range versions {
+		symbols[i], err = s.ds.GetPackageSymbols(ctx, pkgPath, v)
+		if err != nil {
+			if errors.Is(err, derrors.NotFound) {
+				return &serverError{
+					status: http.StatusNotFound,
+					err:    err,
+					epage:  &errorPage{Message: fmt.Sprintf("Package %s@%s was not found.", pkgPath, v)},
+				}
+			}
+			return err
+		}
+	}
+	page := &ComparePage{
+		basePage:    s.newBasePage(r, fmt.Sprintf("Compare %s", pkgPath)),
+		PackagePath: pkgPath,
+		FromVersion: displayVersion(versions[0], modulePath),
+		ToVersion:   displayVersion(versions[1], modulePath),
+		Diff:        diffSymbols(symbols[0], symbols[1]),
+	}
+	s.servePage(ctx, w, "compare.tmpl", page)
+	return nil
+}
+
+// diffSymbols returns the difference between the symbols from and to of two
+// versions of a package.
+func diffSymbols(from, to []*internal.Symbol) *SymbolDiff {
+	fromByName := map[string]*internal.Symbol{}
+	for _, s := range from {
+		fromByName[s.Name] = s
+	}
+	toByName := map[string]*internal.Symbol{}
+	for _, s := range to {
+		toByName[s.Name] = s
+	}
+
+	diff := &SymbolDiff{}
+	for _, s := range to {
+		old, ok := fromByName[s.Name]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, s)
+		case old.Kind != s.Kind || old.Signature != s.Signature:
+			diff.Changed = append(diff.Changed, &SymbolChange{From: old, To: s})
+		}
+	}
+	for _, s := range from {
+		if _, ok := toByName[s.Name]; !ok {
+			diff.Removed = append(diff.Removed, s)
+		}
+	}
+	sortSymbols(diff.Added)
+	sortSymbols(diff.Removed)
+	sort.Slice(diff.Changed, func(i, j int) bool { return diff.Changed[i].To.Name < diff.Changed[j].To.Name })
+	return diff
+}
+
+func sortSymbols(syms []*internal.Symbol) {
+	sort.Slice(syms, func(i, j int) bool { return syms[i].Name < syms[j].Name })
+}
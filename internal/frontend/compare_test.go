@@ -0,0 +1,169 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+func TestDiffSymbols(t *testing.T) {
+	var (
+		fn = func(name, sig string) *internal.Symbol {
+			return &internal.Symbol{Name: name, Kind: internal.SymbolKindFunction, Signature: sig}
+		}
+		typ = func(name, sig string) *internal.Symbol {
+			return &internal.Symbol{Name: name, Kind: internal.SymbolKindType, Signature: sig}
+		}
+		vr = func(name, sig string) *internal.Symbol {
+			return &internal.Symbol{Name: name, Kind: internal.SymbolKindVariable, Signature: sig}
+		}
+	)
+	for _, test := range []struct {
+		name     string
+		from, to []*internal.Symbol
+		want     *SymbolDiff
+	}{
+		{
+			name: "no change",
+			from: []*internal.Symbol{fn("F", "func F()"), typ("T", "type T int")},
+			to:   []*internal.Symbol{typ("T", "type T int"), fn("F", "func F()")},
+			want: &SymbolDiff{},
+		},
+		{
+			name: "added and removed",
+			from: []*internal.Symbol{fn("F", "func F()"), fn("Old", "func Old()")},
+			to:   []*internal.Symbol{fn("New", "func New()"), fn("F", "func F()"), fn("A", "func A()")},
+			want: &SymbolDiff{
+				Added:   []*internal.Symbol{fn("A", "func A()"), fn("New", "func New()")},
+				Removed: []*internal.Symbol{fn("Old", "func Old()")},
+			},
+		},
+		{
+			name: "rename",
+			from: []*internal.Symbol{fn("Parse", "func Parse(s string) error")},
+			to:   []*internal.Symbol{fn("ParseString", "func ParseString(s string) error")},
+			want: &SymbolDiff{
+				Added:   []*internal.Symbol{fn("ParseString", "func ParseString(s string) error")},
+				Removed: []*internal.Symbol{fn("Parse", "func Parse(s string) error")},
+			},
+		},
+		{
+			name: "signature change",
+			from: []*internal.Symbol{fn("F", "func F(int)"), typ("T", "type T int")},
+			to:   []*internal.Symbol{fn("F", "func F(int64)"), typ("T", "type T int64")},
+			want: &SymbolDiff{
+				Changed: []*SymbolChange{
+					{From: fn("F", "func F(int)"), To: fn("F", "func F(int64)")},
+					{From: typ("T", "type T int"), To: typ("T", "type T int64")},
+				},
+			},
+		},
+		{
+			name: "kind change",
+			from: []*internal.Symbol{vr("Default", "var Default = New()")},
+			to:   []*internal.Symbol{fn("Default", "func Default() *T")},
+			want: &SymbolDiff{
+				Changed: []*SymbolChange{
+					{From: vr("Default", "var Default = New()"), To: fn("Default", "func Default() *T")},
+				},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got := diffSymbols(test.from, test.to)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("diffSymbols mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// compareDataSource is a DataSource whose packages have the symbols in the
+// map, keyed by "path@version".
+type compareDataSource struct {
+	internal.DataSource
+	symbols map[string][]*internal.Symbol
+}
+
+func (ds compareDataSource) GetPackageSymbols(ctx context.Context, pkgPath, version string) ([]*internal.Symbol, error) {
+	syms, ok := ds.symbols[pkgPath+"@"+version]
+	if !ok {
+		return nil, derrors.NotFound
+	}
+	return syms, nil
+}
+
+func TestHandleCompare(t *testing.T) {
+	ds := compareDataSource{symbols: map[string][]*internal.Symbol{
+		"a.com/m/p@v1.0.0": {
+			{Name: "Old", Kind: internal.SymbolKindFunction, Signature: "func Old()"},
+			{Name: "T", Kind: internal.SymbolKindType, Signature: "type T int"},
+		},
+		"a.com/m/p@v1.1.0": {
+			{Name: "New", Kind: internal.SymbolKindFunction, Signature: "func New()"},
+			{Name: "T", Kind: internal.SymbolKindType, Signature: "type T string"},
+		},
+	}}
+	s, err := NewServer(ServerConfig{DataSource: ds, StaticPath: "../../content/static"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := s.errorHandler(s.handleCompare)
+	for _, test := range []struct {
+		name, path string
+		wantStatus int
+		want       []string
+	}{
+		{
+			name:       "ok",
+			path:       "/compare/a.com/m/p?from=v1.0.0&to=v1.1.0",
+			wantStatus: http.StatusOK,
+			want: []string{
+				`<pre class="Compare-symbol Compare-symbol--added" title="Function New">func New()</pre>`,
+				`<pre class="Compare-symbol Compare-symbol--removed" title="Function Old">func Old()</pre>`,
+				`<pre class="Compare-symbol Compare-symbol--removed" title="Type T">type T int</pre>`,
+				`<pre class="Compare-symbol Compare-symbol--added" title="Type T">type T string</pre>`,
+			},
+		},
+		{
+			name:       "same version",
+			path:       "/compare/a.com/m/p?from=v1.0.0&to=v1.0.0",
+			wantStatus: http.StatusOK,
+			want:       []string{"The exported API did not change."},
+		},
+		{
+			name:       "missing version",
+			path:       "/compare/a.com/m/p?from=v1.0.0",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "version not found",
+			path:       "/compare/a.com/m/p?from=v1.0.0&to=v1.2.0",
+			wantStatus: http.StatusNotFound,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+			if w.Code != test.wantStatus {
+				t.Fatalf("GET %q = %d, want %d", test.path, w.Code, test.wantStatus)
+			}
+			body := w.Body.String()
+			for _, want := range test.want {
+				if !strings.Contains(body, want) {
+					t.Errorf("GET %q: body does not contain %q", test.path, want)
+				}
+			}
+		})
+	}
+}
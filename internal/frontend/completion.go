@@ -8,12 +8,15 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/go-redis/redis/v7"
+	"golang.org/x/pkgsite/internal"
 	"golang.org/x/pkgsite/internal/complete"
 	"golang.org/x/pkgsite/internal/derrors"
 	"golang.org/x/pkgsite/internal/log"
@@ -50,6 +53,65 @@ func (s *Server) handleAutoCompletion(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+const (
+	// defaultSuggestionLimit is the number of suggestions returned by
+	// handleAutocomplete when the request does not specify a limit.
+	defaultSuggestionLimit = 5
+	// maxSuggestionLimit is the largest number of suggestions that
+	// handleAutocomplete will return.
+	maxSuggestionLimit = 20
+)
+
+// handleAutocomplete handles requests for /autocomplete?q=<prefix>&limit=<n>
+// by querying the data source for packages whose path or name starts with
+// prefix. It is used when no redis completion index is configured.
+func (s *Server) handleAutocomplete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	limit, err := parseSuggestionLimit(r.FormValue("limit"))
+	if err != nil {
+		code := http.StatusBadRequest
+		http.Error(w, http.StatusText(code), code)
+		return
+	}
+	suggestions, err := s.ds.GetSearchSuggestions(ctx, strings.TrimSpace(r.FormValue("q")), limit)
+	if err != nil {
+		log.Errorf(ctx, "handleAutocomplete: %v", err)
+		code := http.StatusInternalServerError
+		http.Error(w, http.StatusText(code), code)
+		return
+	}
+	if suggestions == nil {
+		// As in handleAutoCompletion, serialize an empty JSON array rather
+		// than null.
+		suggestions = []*internal.SearchSuggestion{}
+	}
+	response, err := json.Marshal(suggestions)
+	if err != nil {
+		log.Errorf(ctx, "error marshalling suggestions: json.Marshal: %v", err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := io.Copy(w, bytes.NewReader(response)); err != nil {
+		log.Errorf(ctx, "Error copying json buffer to ResponseWriter: %v", err)
+	}
+}
+
+// parseSuggestionLimit parses the limit parameter of an autocomplete request.
+// An empty limit means defaultSuggestionLimit, and larger limits are reduced
+// to maxSuggestionLimit.
+func parseSuggestionLimit(s string) (int, error) {
+	if s == "" {
+		return defaultSuggestionLimit, nil
+	}
+	limit, err := strconv.Atoi(s)
+	if err != nil || limit <= 0 {
+		return 0, fmt.Errorf("invalid limit %q", s)
+	}
+	if limit > maxSuggestionLimit {
+		limit = maxSuggestionLimit
+	}
+	return limit, nil
+}
+
 // scoredCompletion wraps Completions with a relevancy score, so that they can
 // be sorted.
 type scoredCompletion struct {
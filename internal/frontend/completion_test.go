@@ -93,3 +93,27 @@ func TestNextPrefix(t *testing.T) {
 		}
 	}
 }
+
+func TestParseSuggestionLimit(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want int
+	}{
+		{"", defaultSuggestionLimit},
+		{"3", 3},
+		{"1000", maxSuggestionLimit},
+	} {
+		got, err := parseSuggestionLimit(test.in)
+		if err != nil {
+			t.Fatalf("parseSuggestionLimit(%q): %v", test.in, err)
+		}
+		if got != test.want {
+			t.Errorf("parseSuggestionLimit(%q) = %d, want %d", test.in, got, test.want)
+		}
+	}
+	for _, in := range []string{"-1", "0", "ten"} {
+		if _, err := parseSuggestionLimit(in); err == nil {
+			t.Errorf("parseSuggestionLimit(%q): got nil error, want error", in)
+		}
+	}
+}
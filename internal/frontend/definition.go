@@ -0,0 +1,92 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/log"
+	"golang.org/x/pkgsite/internal/stdlib"
+)
+
+// definitionPath is the path of the go-to-definition endpoint.
+const definitionPath = "/definition"
+
+// definitionTarget is the JSON response of the go-to-definition endpoint.
+type definitionTarget struct {
+	*internal.SymbolLocation
+	// URL is the URL of the line of the declaration on the file page.
+	URL string
+}
+
+// handleDefinition handles requests for
+// /definition?pkg=<import-path>&sym=<symbol-name>, by redirecting to the line
+// that declares the symbol in the latest version of the package, on the file
+// page of the package's module. The name of a method is qualified by its
+// receiver type, as in "Buffer.Write".
+//
+// If the request accepts application/json, the response is the JSON of a
+// definitionTarget instead of a redirect, or of a ProblemDetail if the request
+// fails.
+func (s *Server) handleDefinition(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	wantJSON := strings.Contains(r.Header.Get("Accept"), "application/json")
+	loc, err := s.symbolDefinition(ctx, r.FormValue("pkg"), r.FormValue("sym"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		var serr *serverError
+		if errors.As(err, &serr) {
+			status = serr.status
+		}
+		if status == http.StatusInternalServerError {
+			log.Errorf(ctx, "handleDefinition: %v", err)
+		}
+		if wantJSON {
+			WriteJSONError(w, newProblemDetail(r, status, ""))
+		} else {
+			http.Error(w, http.StatusText(status), status)
+		}
+		return
+	}
+	target := &definitionTarget{
+		SymbolLocation: loc,
+		URL:            fmt.Sprintf("%s#L%d", fileTreeURL(loc.ModulePath, loc.Version, loc.FilePath), loc.Line),
+	}
+	if wantJSON {
+		writeJSON(ctx, w, http.StatusOK, target)
+		return
+	}
+	http.Redirect(w, r, target.URL, http.StatusFound)
+}
+
+// symbolDefinition returns the location of the declaration of the symbol sym
+// in the package pkgPath.
+func (s *Server) symbolDefinition(ctx context.Context, pkgPath, sym string) (_ *internal.SymbolLocation, err error) {
+	defer derrors.Wrap(&err, "symbolDefinition(ctx, %q, %q)", pkgPath, sym)
+
+	if pkgPath == "" || sym == "" {
+		return nil, &serverError{status: http.StatusBadRequest, err: errors.New("missing pkg or sym parameter")}
+	}
+	if !stdlib.Contains(pkgPath) {
+		if err := module.CheckImportPath(pkgPath); err != nil {
+			return nil, &serverError{status: http.StatusBadRequest, err: err}
+		}
+	}
+	loc, err := s.ds.GetSymbolDefinition(ctx, pkgPath, sym)
+	if err != nil && !errors.Is(err, derrors.NotFound) {
+		return nil, err
+	}
+	if loc == nil {
+		return nil, &serverError{status: http.StatusNotFound, err: err}
+	}
+	return loc, nil
+}
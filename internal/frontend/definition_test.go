@@ -0,0 +1,123 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+// definitionDataSource is a DataSource with the given symbol locations, keyed
+// by package path and symbol name.
+type definitionDataSource struct {
+	internal.DataSource
+	locations map[[2]string]*internal.SymbolLocation
+}
+
+func (ds definitionDataSource) GetSymbolDefinition(ctx context.Context, pkgPath, symbolName string) (*internal.SymbolLocation, error) {
+	loc, ok := ds.locations[[2]string{pkgPath, symbolName}]
+	if !ok {
+		return nil, derrors.NotFound
+	}
+	return loc, nil
+}
+
+func TestHandleDefinition(t *testing.T) {
+	ds := definitionDataSource{
+		locations: map[[2]string]*internal.SymbolLocation{
+			{"encoding/json", "Marshal"}: {
+				PackagePath: "encoding/json",
+				ModulePath:  "std",
+				Version:     "v1.15.0",
+				FilePath:    "encoding/json/encode.go",
+				Line:        158,
+			},
+			{"github.com/a/b/p", "T.M"}: {
+				PackagePath: "github.com/a/b/p",
+				ModulePath:  "github.com/a/b",
+				Version:     "v1.2.0",
+				FilePath:    "p/t.go",
+				Line:        30,
+			},
+		},
+	}
+	s, err := NewServer(ServerConfig{DataSource: ds, StaticPath: "../../content/static"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		name, url, accept string
+		wantStatus        int
+		wantLocation      string
+		wantBody          string
+	}{
+		{
+			name:         "redirect",
+			url:          "/definition?pkg=encoding/json&sym=Marshal",
+			wantStatus:   http.StatusFound,
+			wantLocation: "/files/std@v1.15.0/encoding/json/encode.go#L158",
+		},
+		{
+			name:         "method",
+			url:          "/definition?pkg=github.com/a/b/p&sym=T.M",
+			wantStatus:   http.StatusFound,
+			wantLocation: "/files/github.com/a/b@v1.2.0/p/t.go#L30",
+		},
+		{
+			name:       "json",
+			url:        "/definition?pkg=github.com/a/b/p&sym=T.M",
+			accept:     "application/json",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"PackagePath":"github.com/a/b/p","ModulePath":"github.com/a/b","Version":"v1.2.0","FilePath":"p/t.go","Line":30,"URL":"/files/github.com/a/b@v1.2.0/p/t.go#L30"}`,
+		},
+		{
+			name:       "unknown symbol",
+			url:        "/definition?pkg=encoding/json&sym=Unmarshal",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "unknown symbol json",
+			url:        "/definition?pkg=encoding/json&sym=Unmarshal",
+			accept:     "application/json",
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"type":"about:blank","title":"Not Found","status":404,"instance":"/definition"}`,
+		},
+		{
+			name:       "missing symbol",
+			url:        "/definition?pkg=encoding/json",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid package path",
+			url:        "/definition?pkg=github.com/a/b/../c&sym=T",
+			wantStatus: http.StatusBadRequest,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", test.url, nil)
+			if test.accept != "" {
+				req.Header.Set("Accept", test.accept)
+			}
+			w := httptest.NewRecorder()
+			s.handleDefinition(w, req)
+			if w.Code != test.wantStatus {
+				t.Fatalf("GET %q = %d, want %d", test.url, w.Code, test.wantStatus)
+			}
+			if got := w.Header().Get("Location"); got != test.wantLocation {
+				t.Errorf("GET %q: Location = %q, want %q", test.url, got, test.wantLocation)
+			}
+			if test.wantBody != "" {
+				if got := w.Body.String(); got != test.wantBody {
+					t.Errorf("GET %q: body = %s, want %s", test.url, got, test.wantBody)
+				}
+			}
+		})
+	}
+}
@@ -6,6 +6,8 @@ package frontend
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"html/template"
 	"net/http"
@@ -17,6 +19,7 @@ import (
 	"golang.org/x/pkgsite/internal/derrors"
 	"golang.org/x/pkgsite/internal/experiment"
 	"golang.org/x/pkgsite/internal/log"
+	"golang.org/x/pkgsite/internal/middleware"
 	"golang.org/x/pkgsite/internal/postgres"
 	"golang.org/x/pkgsite/internal/stdlib"
 )
@@ -35,6 +38,27 @@ type DetailsPage struct {
 	// PageType is either "mod", "dir", or "pkg" depending on the details
 	// handler.
 	PageType string
+
+	// Vulns are the known vulnerabilities of the module version. They are
+	// only populated for package and module pages.
+	Vulns []*internal.VulnReport
+
+	// IsRetracted reports whether the module version is retracted. It is
+	// only populated for package and module pages.
+	IsRetracted bool
+
+	// Deprecation describes why the package is deprecated. It is nil if the
+	// package is not deprecated, and is only populated for package pages.
+	Deprecation *Deprecation
+}
+
+// A Deprecation describes why a package is deprecated by its doc comment.
+type Deprecation struct {
+	// Message is the text of the "Deprecated: " paragraph of the doc
+	// comment, without that prefix.
+	Message string
+	// Successors are the paths of the known packages mentioned in Message.
+	Successors []string
 }
 
 // serveDetails handles requests for package/directory/module details pages. It
@@ -46,6 +70,10 @@ func (s *Server) serveDetails(w http.ResponseWriter, r *http.Request) (err error
 		s.staticPageHandler("index.tmpl", "")(w, r)
 		return nil
 	}
+	if isSitemapPath(r.URL.Path) {
+		s.handleSitemap(w, r)
+		return nil
+	}
 	if r.URL.Path == "/C" {
 		// Package "C" is a special case: redirect to the Go Blog article on cgo.
 		// (This is what godoc.org does.)
@@ -54,7 +82,8 @@ func (s *Server) serveDetails(w http.ResponseWriter, r *http.Request) (err error
 	}
 	if r.URL.Path == "/mod/std" {
 		// The stdlib module page is hosted at "/std".
-		http.Redirect(w, r, "/std", http.StatusMovedPermanently)
+		middleware.DeprecationMiddleware("/mod/std", "/std", deprecatedURLSunset)(
+			http.RedirectHandler("/std", http.StatusMovedPermanently)).ServeHTTP(w, r)
 		return nil
 	}
 
@@ -124,6 +153,9 @@ func (s *Server) serveDetails(w http.ResponseWriter, r *http.Request) (err error
 // 1. The path has no '@', like github.com/hashicorp/vault/api.
 //    This is the full path. The module path is unknown. So is the version, so we
 //    treat it as the latest version for whatever the path denotes.
+//    If the path has a major version element, like github.com/foo/bar/v2/pkg,
+//    the module path is taken to be the path through that element
+//    (github.com/foo/bar/v2).
 //
 // 2. The path has "@version" at the end, like github.com/hashicorp/vault/api@v1.2.3.
 //    We split this at the '@' into a full path (github.com/hashicorp/vault/api)
@@ -148,19 +180,25 @@ func parseDetailsURLPath(urlPath string) (fullPath, modulePath, version string,
 	// or
 	//  /<module-path>/<suffix>, @<version>
 	parts := strings.SplitN(urlPath, "@", 2)
+	if len(parts) == 2 && strings.HasSuffix(parts[0], "/") {
+		return "", "", "", fmt.Errorf("slash before version in %q", urlPath)
+	}
 	basePath := strings.TrimSuffix(strings.TrimPrefix(parts[0], "/"), "/")
 	if len(parts) == 1 { // no '@'
 		modulePath = internal.UnknownModulePath
 		version = internal.LatestVersion
 		fullPath = basePath
+		if mp := majorVersionModulePath(basePath); mp != "" && !stdlib.Contains(basePath) {
+			modulePath = mp
+		}
 	} else {
 		// Parse the version and suffix from parts[1], the string after the '@'.
 		endParts := strings.Split(parts[1], "/")
 		suffix := strings.Join(endParts[1:], "/")
 		// The first path component after the '@' is the version.
 		version = endParts[0]
-		// You cannot explicitly write "latest" for the version.
-		if version == internal.LatestVersion {
+		// You cannot explicitly write "latest" for the version, or omit it.
+		if version == internal.LatestVersion || version == "" {
 			return "", "", "", fmt.Errorf("invalid version: %q", version)
 		}
 		if suffix == "" {
@@ -192,6 +230,101 @@ func parseDetailsURLPath(urlPath string) (fullPath, modulePath, version string,
 	return fullPath, modulePath, version, nil
 }
 
+// majorVersionModulePath returns the prefix of fullPath through its last
+// major version element "/vN", where N >= 2, or the empty string if fullPath
+// has no such element. For example, the result for github.com/foo/bar/v2/pkg
+// is github.com/foo/bar/v2.
+func majorVersionModulePath(fullPath string) string {
+	elems := strings.Split(fullPath, "/")
+	// The first element is never a major version: it is the module's host.
+	for i := len(elems) - 1; i > 0; i-- {
+		if isMajorVersionElem(elems[i]) {
+			return strings.Join(elems[:i+1], "/")
+		}
+	}
+	return ""
+}
+
+// isMajorVersionElem reports whether elem has the form "vN", where N is an
+// integer greater than 1 without leading zeroes.
+func isMajorVersionElem(elem string) bool {
+	if len(elem) < 2 || elem[0] != 'v' || elem[1] == '0' || elem == "v1" {
+		return false
+	}
+	for _, c := range elem[1:] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// canonicalURL returns the URL of the latest version of the page for the
+// package or directory pkgPath in the module modulePath, or of the module page
+// if pkgPath is empty. Since the page for every version of a path links to
+// it, search engines should index it instead of the versioned URLs.
+func canonicalURL(pkgPath, modulePath string) string {
+	if pkgPath == "" {
+		return constructModuleURL(modulePath, internal.LatestVersion)
+	}
+	return constructPackageURL(pkgPath, modulePath, internal.LatestVersion)
+}
+
+// setCanonicalURL sets a Link header on w marking url as the canonical URL of
+// the page being served.
+func setCanonicalURL(w http.ResponseWriter, url string) {
+	w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="canonical"`, url))
+}
+
+// checkETag sets the ETag header of the tab of the details page for the
+// module version specified by modulePath and version, unless the tab is
+// mutable. If the ETag matches the request, checkETag writes a 304 Not
+// Modified response and reports true; the page should not be served.
+// retracted and vulns are the retraction status and the vulnerabilities
+// shown on the page.
+func checkETag(w http.ResponseWriter, r *http.Request, modulePath, version, tab string, retracted bool, vulns []*internal.VulnReport) bool {
+	if isMutableTab(tab) {
+		return false
+	}
+	etag := detailsETag(modulePath, version, tab, retracted, vulns)
+	w.Header().Set("ETag", etag)
+	if !middleware.ETagMatches(r, etag) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// detailsETag returns the ETag of the tab of the details page for
+// modulePath@version. A module version can be retracted by a newer one and
+// vulnerabilities can be reported for it at any time, so the ETag depends on
+// retracted and the IDs of vulns.
+func detailsETag(modulePath, version, tab string, retracted bool, vulns []*internal.VulnReport) string {
+	key := modulePath + "@" + version + ":" + tab
+	if retracted {
+		key += ":retracted"
+	}
+	for _, v := range vulns {
+		key += "\n" + v.ID
+	}
+	sum := sha256.Sum256([]byte(key))
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// isRetracted reports whether the given version of the module is retracted.
+func isRetracted(ctx context.Context, ds internal.DataSource, modulePath, version string) (bool, error) {
+	retracted, err := ds.GetRetractedVersions(ctx, modulePath)
+	if err != nil {
+		return false, err
+	}
+	for _, v := range retracted {
+		if v == version {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // checkPathAndVersion verifies that the requested path and version are
 // acceptable. The given path may be a module or package path.
 func checkPathAndVersion(ctx context.Context, ds internal.DataSource, fullPath, requestedVersion string) error {
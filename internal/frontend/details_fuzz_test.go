@@ -0,0 +1,64 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package frontend
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/stdlib"
+)
+
+func FuzzParseDetailsURLPath(f *testing.F) {
+	for _, tc := range parseDetailsURLPathTests {
+		f.Add(tc.url)
+	}
+	f.Fuzz(func(t *testing.T, urlPath string) {
+		fullPath, modulePath, version, err := parseDetailsURLPath(urlPath)
+		// A version must follow the path immediately, and must not be
+		// empty.
+		if i := strings.Index(urlPath, "@"); i >= 0 && (strings.HasPrefix(urlPath[i:], "@/") || urlPath[i:] == "@" || (i > 0 && urlPath[i-1] == '/')) {
+			if err == nil {
+				t.Fatalf("parseDetailsURLPath(%q) succeeded, want error", urlPath)
+			}
+			return
+		}
+		if err != nil {
+			return
+		}
+		if fullPath == "" {
+			t.Fatalf("parseDetailsURLPath(%q): empty full path", urlPath)
+		}
+		p := strings.TrimPrefix(urlPath, "/")
+		switch {
+		case version == internal.LatestVersion:
+			// The path has no version.
+			if strings.Contains(urlPath, "@") {
+				t.Fatalf("parseDetailsURLPath(%q) = latest version, but the path has one", urlPath)
+			}
+			if !strings.HasPrefix(p, fullPath) {
+				t.Fatalf("parseDetailsURLPath(%q): full path %q is not a prefix", urlPath, fullPath)
+			}
+		case modulePath == internal.UnknownModulePath || modulePath == stdlib.ModulePath:
+			// The version is at the end of the path.
+			if !strings.HasPrefix(p, fullPath+"@"+version) {
+				t.Fatalf("parseDetailsURLPath(%q): %q is not a prefix", urlPath, fullPath+"@"+version)
+			}
+		default:
+			// The version follows the module path, which the rest of the
+			// full path follows.
+			if !strings.HasPrefix(p, modulePath+"@"+version+"/") {
+				t.Fatalf("parseDetailsURLPath(%q): %q is not a prefix", urlPath, modulePath+"@"+version+"/")
+			}
+			if !strings.HasPrefix(fullPath, modulePath+"/") {
+				t.Fatalf("parseDetailsURLPath(%q): full path %q is not in module %q", urlPath, fullPath, modulePath)
+			}
+		}
+	})
+}
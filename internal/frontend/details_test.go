@@ -6,83 +6,157 @@ package frontend
 
 import (
 	"context"
+	"fmt"
+	"math/rand"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/quick"
 
+	"golang.org/x/mod/semver"
 	"golang.org/x/pkgsite/internal"
 	"golang.org/x/pkgsite/internal/stdlib"
 )
 
+// parseDetailsURLPathTests are the test cases of TestParseDetailsURLPath,
+// which also seed the corpus of FuzzParseDetailsURLPath.
+var parseDetailsURLPathTests = []struct {
+	name, url, wantModulePath, wantFullPath, wantVersion string
+	wantErr                                              bool
+}{
+	{
+		name:           "latest",
+		url:            "/github.com/hashicorp/vault/api",
+		wantModulePath: internal.UnknownModulePath,
+		wantFullPath:   "github.com/hashicorp/vault/api",
+		wantVersion:    internal.LatestVersion,
+	},
+	{
+		name:           "package at version in nested module",
+		url:            "/github.com/hashicorp/vault/api@v1.0.3",
+		wantModulePath: internal.UnknownModulePath,
+		wantFullPath:   "github.com/hashicorp/vault/api",
+		wantVersion:    "v1.0.3",
+	},
+	{
+		name:           "package at version in parent module",
+		url:            "/github.com/hashicorp/vault@v1.0.3/api",
+		wantModulePath: "github.com/hashicorp/vault",
+		wantFullPath:   "github.com/hashicorp/vault/api",
+		wantVersion:    "v1.0.3",
+	},
+	{
+		name:           "package at version trailing slash",
+		url:            "/github.com/hashicorp/vault/api@v1.0.3/",
+		wantModulePath: internal.UnknownModulePath,
+		wantFullPath:   "github.com/hashicorp/vault/api",
+		wantVersion:    "v1.0.3",
+	},
+	{
+		name:           "latest major version 2",
+		url:            "/github.com/foo/bar/v2",
+		wantModulePath: "github.com/foo/bar/v2",
+		wantFullPath:   "github.com/foo/bar/v2",
+		wantVersion:    internal.LatestVersion,
+	},
+	{
+		name:           "latest major version 3",
+		url:            "/github.com/foo/bar/v3/",
+		wantModulePath: "github.com/foo/bar/v3",
+		wantFullPath:   "github.com/foo/bar/v3",
+		wantVersion:    internal.LatestVersion,
+	},
+	{
+		name:           "latest major version 4",
+		url:            "/github.com/foo/bar/v4",
+		wantModulePath: "github.com/foo/bar/v4",
+		wantFullPath:   "github.com/foo/bar/v4",
+		wantVersion:    internal.LatestVersion,
+	},
+	{
+		name:           "latest package in major version 2",
+		url:            "/github.com/foo/bar/v2/pkg",
+		wantModulePath: "github.com/foo/bar/v2",
+		wantFullPath:   "github.com/foo/bar/v2/pkg",
+		wantVersion:    internal.LatestVersion,
+	},
+	{
+		name:           "latest nested package in major version 3",
+		url:            "/github.com/foo/bar/v3/pkg/sub",
+		wantModulePath: "github.com/foo/bar/v3",
+		wantFullPath:   "github.com/foo/bar/v3/pkg/sub",
+		wantVersion:    internal.LatestVersion,
+	},
+	{
+		name:           "not a major version",
+		url:            "/github.com/foo/bar/v1/v02/vx",
+		wantModulePath: internal.UnknownModulePath,
+		wantFullPath:   "github.com/foo/bar/v1/v02/vx",
+		wantVersion:    internal.LatestVersion,
+	},
+	{
+		name:           "major version with explicit version",
+		url:            "/github.com/foo/bar/v2/pkg@v2.1.0",
+		wantModulePath: internal.UnknownModulePath,
+		wantFullPath:   "github.com/foo/bar/v2/pkg",
+		wantVersion:    "v2.1.0",
+	},
+	{
+		name:           "stdlib",
+		url:            "net/http",
+		wantModulePath: stdlib.ModulePath,
+		wantFullPath:   "net/http",
+		wantVersion:    internal.LatestVersion,
+	},
+	{
+		name:           "stdlib at version",
+		url:            "net/http@go1.14",
+		wantModulePath: stdlib.ModulePath,
+		wantFullPath:   "net/http",
+		wantVersion:    "go1.14",
+	},
+	{
+		name:    "invalid url",
+		url:     "/",
+		wantErr: true,
+	},
+	{
+		name:    "invalid url missing module",
+		url:     "@v1.0.0",
+		wantErr: true,
+	},
+	{
+		name:    "explicit latest",
+		url:     "/github.com/hashicorp/vault/api@latest",
+		wantErr: true,
+	},
+	{
+		name:    "split stdlib",
+		url:     "/net@go1.14/http",
+		wantErr: true,
+	},
+	{
+		name:    "missing version",
+		url:     "/github.com/hashicorp/vault/api@",
+		wantErr: true,
+	},
+	{
+		name:    "slash before version",
+		url:     "/github.com/hashicorp/vault/@v1.0.3/api",
+		wantErr: true,
+	},
+	{
+		name:    "slash before missing version",
+		url:     "00000000000/@",
+		wantErr: true,
+	},
+}
+
 func TestParseDetailsURLPath(t *testing.T) {
-	testCases := []struct {
-		name, url, wantModulePath, wantFullPath, wantVersion string
-		wantErr                                              bool
-	}{
-		{
-			name:           "latest",
-			url:            "/github.com/hashicorp/vault/api",
-			wantModulePath: internal.UnknownModulePath,
-			wantFullPath:   "github.com/hashicorp/vault/api",
-			wantVersion:    internal.LatestVersion,
-		},
-		{
-			name:           "package at version in nested module",
-			url:            "/github.com/hashicorp/vault/api@v1.0.3",
-			wantModulePath: internal.UnknownModulePath,
-			wantFullPath:   "github.com/hashicorp/vault/api",
-			wantVersion:    "v1.0.3",
-		},
-		{
-			name:           "package at version in parent module",
-			url:            "/github.com/hashicorp/vault@v1.0.3/api",
-			wantModulePath: "github.com/hashicorp/vault",
-			wantFullPath:   "github.com/hashicorp/vault/api",
-			wantVersion:    "v1.0.3",
-		},
-		{
-			name:           "package at version trailing slash",
-			url:            "/github.com/hashicorp/vault/api@v1.0.3/",
-			wantModulePath: internal.UnknownModulePath,
-			wantFullPath:   "github.com/hashicorp/vault/api",
-			wantVersion:    "v1.0.3",
-		},
-		{
-			name:           "stdlib",
-			url:            "net/http",
-			wantModulePath: stdlib.ModulePath,
-			wantFullPath:   "net/http",
-			wantVersion:    internal.LatestVersion,
-		},
-		{
-			name:           "stdlib at version",
-			url:            "net/http@go1.14",
-			wantModulePath: stdlib.ModulePath,
-			wantFullPath:   "net/http",
-			wantVersion:    "go1.14",
-		},
-		{
-			name:    "invalid url",
-			url:     "/",
-			wantErr: true,
-		},
-		{
-			name:    "invalid url missing module",
-			url:     "@v1.0.0",
-			wantErr: true,
-		},
-		{
-			name:    "explicit latest",
-			url:     "/github.com/hashicorp/vault/api@latest",
-			wantErr: true,
-		},
-		{
-			name:    "split stdlib",
-			url:     "/net@go1.14/http",
-			wantErr: true,
-		},
-	}
-	for _, tc := range testCases {
+	for _, tc := range parseDetailsURLPathTests {
 		t.Run(tc.name, func(t *testing.T) {
 			u, parseErr := url.Parse(tc.url)
 			if parseErr != nil {
@@ -101,6 +175,92 @@ func TestParseDetailsURLPath(t *testing.T) {
 	}
 }
 
+// A detailsURL is a details page URL path for a package at a valid semantic
+// version, in one of the forms that have a version. It implements
+// quick.Generator.
+type detailsURL struct {
+	modulePath, suffix, version string
+	// canonical reports whether the version follows the module path, rather
+	// than the full path.
+	canonical bool
+}
+
+func (detailsURL) Generate(r *rand.Rand, size int) reflect.Value {
+	// Elements of four or more letters are valid, and cannot be reserved
+	// names like "con" or major versions like "v2".
+	elem := func() string {
+		b := make([]byte, 4+r.Intn(5))
+		for i := range b {
+			b[i] = byte('a' + r.Intn(26))
+		}
+		return string(b)
+	}
+	path := func(n int) string {
+		var elems []string
+		for i := 0; i < n; i++ {
+			elems = append(elems, elem())
+		}
+		return strings.Join(elems, "/")
+	}
+	u := detailsURL{
+		modulePath: elem() + ".com/" + path(1+r.Intn(3)),
+		suffix:     path(1 + r.Intn(3)),
+		version:    fmt.Sprintf("v%d.%d.%d", r.Intn(size+1), r.Intn(size+1), r.Intn(size+1)),
+		canonical:  r.Intn(2) == 0,
+	}
+	if r.Intn(2) == 0 {
+		u.version += fmt.Sprintf("-%s.%d", elem(), r.Intn(size+1))
+	}
+	return reflect.ValueOf(u)
+}
+
+func (u detailsURL) String() string {
+	if u.canonical {
+		return "/" + u.modulePath + "@" + u.version + "/" + u.suffix
+	}
+	return "/" + u.modulePath + "/" + u.suffix + "@" + u.version
+}
+
+func TestParseDetailsURLPathVersions(t *testing.T) {
+	parses := func(u detailsURL) bool {
+		if !semver.IsValid(u.version) {
+			t.Fatalf("generated invalid version %q", u.version)
+		}
+		fullPath, modulePath, version, err := parseDetailsURLPath(u.String())
+		if err != nil {
+			t.Logf("parseDetailsURLPath(%q): %v", u, err)
+			return false
+		}
+		wantModulePath := internal.UnknownModulePath
+		if u.canonical {
+			wantModulePath = u.modulePath
+		}
+		return version == u.version && modulePath == wantModulePath && fullPath == u.modulePath+"/"+u.suffix
+	}
+	if err := quick.Check(parses, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCanonicalURL(t *testing.T) {
+	for _, test := range []struct {
+		pkgPath, modulePath, want string
+	}{
+		{"github.com/foo/bar/pkg", "github.com/foo/bar", "/github.com/foo/bar/pkg"},
+		{"github.com/foo/bar", "github.com/foo/bar", "/github.com/foo/bar"},
+		{"net/http", stdlib.ModulePath, "/net/http"},
+		{"", "github.com/foo/bar", "/mod/github.com/foo/bar"},
+		{"", stdlib.ModulePath, "/std"},
+	} {
+		w := httptest.NewRecorder()
+		setCanonicalURL(w, canonicalURL(test.pkgPath, test.modulePath))
+		want := fmt.Sprintf(`<%s>; rel="canonical"`, test.want)
+		if got := w.Header().Get("Link"); got != want {
+			t.Errorf("canonicalURL(%q, %q): Link header = %q, want %q", test.pkgPath, test.modulePath, got, want)
+		}
+	}
+}
+
 func TestCheckPathAndVersion(t *testing.T) {
 	tests := []struct {
 		path, version string
@@ -69,6 +69,7 @@ func (s *Server) legacyServeDirectoryPage(ctx context.Context, w http.ResponseWr
 		Tabs:           directoryTabSettings,
 		PageType:       "dir",
 	}
+	setCanonicalURL(w, canonicalURL(dbDir.Path, dbDir.ModulePath))
 	s.servePage(ctx, w, settings.TemplateName, page)
 	return nil
 }
@@ -20,6 +20,7 @@ import (
 )
 
 func TestFetchDirectoryDetails(t *testing.T) {
+	skipIfNoDB(t)
 	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
 	defer cancel()
 
@@ -154,6 +155,7 @@ func TestFetchDirectoryDetails(t *testing.T) {
 }
 
 func TestFetchDirectoryDetailsInvalidArguments(t *testing.T) {
+	skipIfNoDB(t)
 	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
 	defer cancel()
 
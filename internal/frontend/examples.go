@@ -0,0 +1,104 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"context"
+	"go/scanner"
+	"go/token"
+	"html/template"
+	"strings"
+
+	"golang.org/x/pkgsite/internal"
+)
+
+// ExamplesDetails contains the examples of a package.
+type ExamplesDetails struct {
+	Examples []*Example
+}
+
+// Example is an example function of a package, for display.
+type Example struct {
+	// Name is the name of the example function, like "ExampleBuffer_Write".
+	Name string
+	// ID is the ID of the HTML element of the example, like
+	// "example-Buffer_Write", or "example-package" for the package example.
+	ID string
+	// CodeHTML is the syntax-highlighted code of the example.
+	CodeHTML template.HTML
+	// Output is the expected output of the example, if any.
+	Output string
+}
+
+// fetchExamplesDetails returns the examples of the package with path pkgPath
+// at the given version.
+func fetchExamplesDetails(ctx context.Context, ds internal.DataSource, pkgPath, version string) (*ExamplesDetails, error) {
+	exs, err := ds.GetExamples(ctx, pkgPath, version)
+	if err != nil {
+		return nil, err
+	}
+	details := &ExamplesDetails{}
+	for _, ex := range exs {
+		details.Examples = append(details.Examples, &Example{
+			Name:     ex.Name,
+			ID:       exampleID(ex.Name),
+			CodeHTML: highlightGo(ex.Code),
+			Output:   ex.Output,
+		})
+	}
+	return details, nil
+}
+
+// exampleID returns the ID of the HTML element of the example function with
+// the given name.
+func exampleID(name string) string {
+	id := strings.TrimPrefix(name, "Example")
+	if id == "" || id[0] == '_' {
+		// An example of the package, possibly with a suffix.
+		id = "package" + id
+	}
+	return "example-" + id
+}
+
+// highlightGo returns the Go source code src as HTML, with its comments,
+// keywords and literals in spans with the classes "comment", "keyword" and
+// "literal".
+func highlightGo(src string) template.HTML {
+	var (
+		b    strings.Builder
+		s    scanner.Scanner
+		last int // offset of src up to which b has been written
+	)
+	file := token.NewFileSet().AddFile("", -1, len(src))
+	s.Init(file, []byte(src), nil, scanner.ScanComments)
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		var class string
+		switch {
+		case tok == token.COMMENT:
+			class = "comment"
+		case tok.IsKeyword():
+			class = "keyword"
+		case tok.IsLiteral() && tok != token.IDENT:
+			class = "literal"
+		default:
+			continue
+		}
+		offset := file.Offset(pos)
+		if tok.IsKeyword() {
+			lit = tok.String()
+		}
+		b.WriteString(template.HTMLEscapeString(src[last:offset]))
+		b.WriteString(`<span class="` + class + `">`)
+		b.WriteString(template.HTMLEscapeString(lit))
+		b.WriteString(`</span>`)
+		last = offset + len(lit)
+	}
+	b.WriteString(template.HTMLEscapeString(src[last:]))
+	return template.HTML(b.String())
+}
@@ -0,0 +1,67 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"context"
+	"html/template"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal"
+)
+
+func TestHighlightGo(t *testing.T) {
+	for _, test := range []struct {
+		src  string
+		want template.HTML
+	}{
+		{"", ""},
+		{"x := y", "x := y"},
+		{
+			"// Print a <b>.\nfmt.Println(\"<b>\", 1)",
+			`<span class="comment">// Print a &lt;b&gt;.</span>` + "\n" +
+				`fmt.Println(<span class="literal">&#34;&lt;b&gt;&#34;</span>, <span class="literal">1</span>)`,
+		},
+		{
+			"for i := range s {\n\treturn\n}",
+			`<span class="keyword">for</span> i := <span class="keyword">range</span> s {` + "\n\t" +
+				`<span class="keyword">return</span>` + "\n}",
+		},
+	} {
+		if got := highlightGo(test.src); got != test.want {
+			t.Errorf("highlightGo(%q) =\n%s\nwant\n%s", test.src, got, test.want)
+		}
+	}
+}
+
+type examplesDataSource struct {
+	internal.DataSource
+	examples []*internal.Example
+}
+
+func (ds examplesDataSource) GetExamples(ctx context.Context, pkgPath, version string) ([]*internal.Example, error) {
+	return ds.examples, nil
+}
+
+func TestFetchExamplesDetails(t *testing.T) {
+	ds := examplesDataSource{examples: []*internal.Example{
+		{Name: "Example", Code: "f()"},
+		{Name: "Example_second", Code: "g()"},
+		{Name: "ExampleT_M", Code: "t.M()", Output: "ok\n"},
+	}}
+	got, err := fetchExamplesDetails(context.Background(), ds, "example.com/p", "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &ExamplesDetails{Examples: []*Example{
+		{Name: "Example", ID: "example-package", CodeHTML: "f()"},
+		{Name: "Example_second", ID: "example-package_second", CodeHTML: "g()"},
+		{Name: "ExampleT_M", ID: "example-T_M", CodeHTML: "t.M()", Output: "ok\n"},
+	}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("fetchExamplesDetails mismatch (-want +got):\n%s", diff)
+	}
+}
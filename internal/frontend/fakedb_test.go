@@ -0,0 +1,139 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/middleware"
+	"golang.org/x/pkgsite/internal/testing/fakedb"
+	"golang.org/x/pkgsite/internal/testing/sample"
+)
+
+// newFakeTestServer returns a handler that serves the module versions ms from
+// a fakedb.FakeDataSource.
+func newFakeTestServer(t *testing.T, ms ...*internal.Module) http.Handler {
+	t.Helper()
+	ds := fakedb.New()
+	for _, m := range ms {
+		ds.AddModule(m)
+	}
+	s, err := NewServer(ServerConfig{
+		DataSource:     ds,
+		StaticPath:     "../../content/static",
+		ThirdPartyPath: "../../third_party",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	s.Install(mux.Handle, nil)
+	return middleware.LatestVersion(s.LatestVersion)(mux)
+}
+
+// fakeTestModule returns a module with a package "pkg", whose documentation is
+// doc.
+func fakeTestModule(version, doc string) *internal.Module {
+	m := sample.Module("example.com/mod", version, "pkg")
+	m.LegacyPackages[0].Synopsis = doc
+	m.LegacyPackages[0].DocumentationHTML = "<p>" + doc + "</p>"
+	for _, d := range m.Directories {
+		if d.Package != nil {
+			d.Package.Documentation.Synopsis = doc
+			d.Package.Documentation.HTML = "<p>" + doc + "</p>"
+		}
+	}
+	return m
+}
+
+func TestServePackagePage(t *testing.T) {
+	handler := newFakeTestServer(t,
+		fakeTestModule("v1.0.0", "Package pkg is old."),
+		fakeTestModule("v1.1.0", "Package pkg is new."))
+	for _, test := range []struct {
+		path, wantBody string
+	}{
+		{"/example.com/mod/pkg?tab=doc", "Package pkg is new."},
+		{"/example.com/mod/pkg@v1.0.0?tab=doc", "Package pkg is old."},
+	} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: got status %d, want %d", test.path, w.Code, http.StatusOK)
+			continue
+		}
+		if !strings.Contains(w.Body.String(), test.wantBody) {
+			t.Errorf("%s: body does not contain %q", test.path, test.wantBody)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/example.com/mod/missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("missing package: got status %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestServeModulePage(t *testing.T) {
+	handler := newFakeTestServer(t,
+		fakeTestModule("v1.0.0", "Package pkg is old."),
+		fakeTestModule("v1.1.0", "Package pkg is new."))
+	for _, test := range []struct {
+		path, wantVersion string
+	}{
+		{"/mod/example.com/mod", "v1.1.0"},
+		{"/mod/example.com/mod@v1.0.0", "v1.0.0"},
+	} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: got status %d, want %d", test.path, w.Code, http.StatusOK)
+			continue
+		}
+		body := w.Body.String()
+		for _, want := range []string{"example.com/mod", test.wantVersion} {
+			if !strings.Contains(body, want) {
+				t.Errorf("%s: body does not contain %q", test.path, want)
+			}
+		}
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/mod/example.com/mod@v9.9.9", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("missing version: got status %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestHandleSearch(t *testing.T) {
+	handler := newFakeTestServer(t, fakeTestModule(sample.VersionString, "Package pkg is a package."))
+	for _, test := range []struct {
+		name, query  string
+		wantStatus   int
+		wantLocation string
+	}{
+		{"empty query", "", http.StatusFound, "/"},
+		{"package path", "example.com/mod/pkg", http.StatusFound, "/example.com/mod/pkg"},
+		{"module path", "example.com/mod", http.StatusFound, "/mod/example.com/mod"},
+		// Only postgres.DB supports searching.
+		{"text", "package", http.StatusFailedDependency, ""},
+		{"unknown path", "example.com/other", http.StatusFailedDependency, ""},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", "/search?q="+test.query, nil))
+			if w.Code != test.wantStatus {
+				t.Fatalf("got status %d, want %d", w.Code, test.wantStatus)
+			}
+			if got := w.Header().Get("Location"); got != test.wantLocation {
+				t.Errorf("got location %q, want %q", got, test.wantLocation)
+			}
+		})
+	}
+}
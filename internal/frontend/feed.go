@@ -0,0 +1,198 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"time"
+
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/log"
+)
+
+const (
+	// feedLimit is the maximum number of module versions in a feed.
+	feedLimit = 100
+	// feedMaxAge is how long a module version stays in a feed after it is
+	// indexed.
+	feedMaxAge = 7 * 24 * time.Hour
+
+	feedTitle       = "New modules on pkg.go.dev"
+	feedDescription = "Module versions recently added to pkg.go.dev."
+)
+
+// handleFeed handles requests for /feed/new-packages?format=<rss|atom>, by
+// serving an RSS 2.0 or Atom feed of recently indexed module versions. The
+// default format is RSS.
+func (s *Server) handleFeed(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	format := r.FormValue("format")
+	if format != "" && format != "rss" && format != "atom" {
+		code := http.StatusBadRequest
+		http.Error(w, http.StatusText(code), code)
+		return
+	}
+	mods, err := s.ds.GetRecentlyIndexedModules(ctx, feedLimit, time.Now().Add(-feedMaxAge))
+	if err != nil {
+		log.Errorf(ctx, "handleFeed: %v", err)
+		code := http.StatusInternalServerError
+		http.Error(w, http.StatusText(code), code)
+		return
+	}
+
+	base := baseURL(r)
+	var (
+		feed        interface{}
+		contentType string
+	)
+	if format == "atom" {
+		feed = newAtomFeed(base, r.URL.String(), mods)
+		contentType = "application/atom+xml; charset=utf-8"
+	} else {
+		feed = newRSSFeed(base, mods)
+		contentType = "application/rss+xml; charset=utf-8"
+	}
+	body, err := marshalFeed(feed)
+	if err != nil {
+		log.Errorf(ctx, "handleFeed: %v", err)
+		code := http.StatusInternalServerError
+		http.Error(w, http.StatusText(code), code)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	if _, err := w.Write(body); err != nil {
+		log.Errorf(ctx, "handleFeed, writing: %v", err)
+	}
+}
+
+// marshalFeed returns the XML document for feed.
+func marshalFeed(feed interface{}) ([]byte, error) {
+	body, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("xml.MarshalIndent: %v", err)
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+// rssFeed is an RSS 2.0 document; see https://www.rssboard.org/rss-specification.
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string     `xml:"title"`
+	Link          string     `xml:"link"`
+	Description   string     `xml:"description"`
+	LastBuildDate string     `xml:"lastBuildDate,omitempty"`
+	Items         []*rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+// newRSSFeed returns an RSS feed of mods, whose links are relative to baseURL.
+func newRSSFeed(baseURL string, mods []*internal.IndexedModule) *rssFeed {
+	ch := rssChannel{
+		Title:       feedTitle,
+		Link:        baseURL,
+		Description: feedDescription,
+	}
+	for _, m := range mods {
+		url := baseURL + constructModuleURL(m.ModulePath, linkVersion(m.Version, m.ModulePath))
+		ch.Items = append(ch.Items, &rssItem{
+			Title:       feedEntryTitle(m),
+			Link:        url,
+			Description: feedEntryDescription(m),
+			GUID:        rssGUID{Value: url, IsPermaLink: true},
+			PubDate:     m.IndexedAt.UTC().Format(time.RFC1123Z),
+		})
+	}
+	if len(mods) > 0 {
+		ch.LastBuildDate = mods[0].IndexedAt.UTC().Format(time.RFC1123Z)
+	}
+	return &rssFeed{Version: "2.0", Channel: ch}
+}
+
+// atomFeed is an Atom document; see https://tools.ietf.org/html/rfc4287.
+type atomFeed struct {
+	XMLName xml.Name     `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string       `xml:"title"`
+	ID      string       `xml:"id"`
+	Links   []*atomLink  `xml:"link"`
+	Updated string       `xml:"updated"`
+	Author  atomAuthor   `xml:"author"`
+	Entries []*atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	Title   string    `xml:"title"`
+	ID      string    `xml:"id"`
+	Link    *atomLink `xml:"link"`
+	Updated string    `xml:"updated"`
+	Summary string    `xml:"summary"`
+}
+
+// newAtomFeed returns an Atom feed of mods, whose links are relative to
+// baseURL. selfPath is the path of the feed itself.
+func newAtomFeed(baseURL, selfPath string, mods []*internal.IndexedModule) *atomFeed {
+	feed := &atomFeed{
+		Title: feedTitle,
+		ID:    baseURL + selfPath,
+		Links: []*atomLink{
+			{Href: baseURL + selfPath, Rel: "self"},
+			{Href: baseURL},
+		},
+		Author: atomAuthor{Name: "pkg.go.dev"},
+	}
+	// An Atom feed must have an updated time even if it is empty.
+	updated := time.Now()
+	if len(mods) > 0 {
+		updated = mods[0].IndexedAt
+	}
+	feed.Updated = updated.UTC().Format(time.RFC3339)
+	for _, m := range mods {
+		url := baseURL + constructModuleURL(m.ModulePath, linkVersion(m.Version, m.ModulePath))
+		feed.Entries = append(feed.Entries, &atomEntry{
+			Title:   feedEntryTitle(m),
+			ID:      url,
+			Link:    &atomLink{Href: url},
+			Updated: m.IndexedAt.UTC().Format(time.RFC3339),
+			Summary: feedEntryDescription(m),
+		})
+	}
+	return feed
+}
+
+func feedEntryTitle(m *internal.IndexedModule) string {
+	return fmt.Sprintf("%s %s", m.ModulePath, displayVersion(m.Version, m.ModulePath))
+}
+
+func feedEntryDescription(m *internal.IndexedModule) string {
+	return fmt.Sprintf("Version %s of module %s, committed %s.",
+		displayVersion(m.Version, m.ModulePath), m.ModulePath, m.CommitTime.UTC().Format("Jan 2, 2006"))
+}
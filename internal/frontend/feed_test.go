@@ -0,0 +1,129 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal"
+)
+
+// feedDataSource is a DataSource that returns mods as the recently indexed
+// modules.
+type feedDataSource struct {
+	internal.DataSource
+	mods []*internal.IndexedModule
+}
+
+func (ds feedDataSource) GetRecentlyIndexedModules(ctx context.Context, limit int, since time.Time) ([]*internal.IndexedModule, error) {
+	return ds.mods, nil
+}
+
+func TestHandleFeed(t *testing.T) {
+	indexed := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	var mods []*internal.IndexedModule
+	for i, mv := range []struct{ modulePath, version string }{
+		{"c.com/m", "v1.1.0"},
+		{"b.com/m", "v0.3.0"},
+		{"a.com/m", "v1.0.0"},
+	} {
+		mods = append(mods, &internal.IndexedModule{
+			ModuleInfo: internal.ModuleInfo{
+				ModulePath: mv.modulePath,
+				Version:    mv.version,
+				CommitTime: indexed.Add(-24 * time.Hour),
+			},
+			IndexedAt: indexed.Add(-time.Duration(i) * time.Hour),
+		})
+	}
+	s := &Server{ds: feedDataSource{mods: mods}}
+	wantLinks := []string{
+		"https://pkg.go.dev/mod/c.com/m@v1.1.0",
+		"https://pkg.go.dev/mod/b.com/m@v0.3.0",
+		"https://pkg.go.dev/mod/a.com/m@v1.0.0",
+	}
+
+	serve := func(query string) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequest("GET", "https://pkg.go.dev/feed/new-packages"+query, nil)
+		w := httptest.NewRecorder()
+		s.handleFeed(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %q = %d, want %d", query, w.Code, http.StatusOK)
+		}
+		if !strings.HasPrefix(w.Body.String(), xml.Header) {
+			t.Errorf("GET %q: body does not start with the XML header", query)
+		}
+		return w
+	}
+
+	for _, query := range []string{"", "?format=rss"} {
+		t.Run("rss"+query, func(t *testing.T) {
+			w := serve(query)
+			if got, want := w.Header().Get("Content-Type"), "application/rss+xml; charset=utf-8"; got != want {
+				t.Errorf("Content-Type = %q, want %q", got, want)
+			}
+			var feed rssFeed
+			if err := xml.Unmarshal(w.Body.Bytes(), &feed); err != nil {
+				t.Fatal(err)
+			}
+			if feed.Version != "2.0" {
+				t.Errorf("version = %q, want 2.0", feed.Version)
+			}
+			var gotLinks []string
+			for _, item := range feed.Channel.Items {
+				gotLinks = append(gotLinks, item.Link)
+			}
+			if diff := cmp.Diff(wantLinks, gotLinks); diff != "" {
+				t.Errorf("item links mismatch (-want +got):\n%s", diff)
+			}
+			if got, want := feed.Channel.Items[0].Title, "c.com/m v1.1.0"; got != want {
+				t.Errorf("first item title = %q, want %q", got, want)
+			}
+			if got, want := feed.Channel.Items[0].PubDate, "Mon, 01 Jun 2020 12:00:00 +0000"; got != want {
+				t.Errorf("first item pubDate = %q, want %q", got, want)
+			}
+		})
+	}
+
+	t.Run("atom", func(t *testing.T) {
+		w := serve("?format=atom")
+		if got, want := w.Header().Get("Content-Type"), "application/atom+xml; charset=utf-8"; got != want {
+			t.Errorf("Content-Type = %q, want %q", got, want)
+		}
+		var feed atomFeed
+		if err := xml.Unmarshal(w.Body.Bytes(), &feed); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := feed.XMLName.Space, "http://www.w3.org/2005/Atom"; got != want {
+			t.Errorf("namespace = %q, want %q", got, want)
+		}
+		if got, want := feed.Updated, "2020-06-01T12:00:00Z"; got != want {
+			t.Errorf("updated = %q, want %q", got, want)
+		}
+		var gotLinks []string
+		for _, e := range feed.Entries {
+			gotLinks = append(gotLinks, e.Link.Href)
+		}
+		if diff := cmp.Diff(wantLinks, gotLinks); diff != "" {
+			t.Errorf("entry links mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("bad format", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		s.handleFeed(w, httptest.NewRequest("GET", "/feed/new-packages?format=json", nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("got status %d, want %d", w.Code, http.StatusBadRequest)
+		}
+	})
+}
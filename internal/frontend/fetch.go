@@ -27,6 +27,7 @@ import (
 	"golang.org/x/pkgsite/internal/postgres"
 	"golang.org/x/pkgsite/internal/proxy"
 	"golang.org/x/pkgsite/internal/source"
+	"golang.org/x/time/rate"
 )
 
 var (
@@ -199,6 +200,10 @@ func (s *Server) fetchAndPoll(parentCtx context.Context, modulePath, fullPath, r
 			fmt.Sprintf("%q could not be found. Other versions of module %q may have it! Check them out at https://pkg.go.dev/mod/%s?tab=versions",
 				fullPath, moduleMatchingPathPrefix, moduleMatchingPathPrefix)
 	}
+	// None of the candidate module paths could be found. Move them to the
+	// front of the index queue, so that they are fetched again soon in case
+	// they have been published since.
+	enqueueNotFound(parentCtx, db, results, requestedVersion)
 	p := fullPath
 	if requestedVersion != internal.LatestVersion {
 		p = fullPath + "@" + requestedVersion
@@ -206,6 +211,40 @@ func (s *Server) fetchAndPoll(parentCtx context.Context, modulePath, fullPath, r
 	return http.StatusNotFound, fmt.Sprintf("%q could not be found.", p)
 }
 
+const (
+	// notFoundRetryInterval is the time after a module version was not
+	// found during which requests for it do not enqueue it again.
+	notFoundRetryInterval = time.Hour
+	// maxNotFoundQueued is the length of the index queue above which
+	// requested module versions that were not found are not enqueued.
+	maxNotFoundQueued = 10000
+)
+
+// enqueueNotFoundLimiter limits the rate at which requests for paths that
+// were not found enqueue module versions, so that a client requesting many
+// paths that do not exist cannot keep the worker busy.
+var enqueueNotFoundLimiter = rate.NewLimiter(rate.Every(100*time.Millisecond), 20)
+
+// enqueueNotFound adds the module versions of results that were not found to
+// the index queue, with postgres.RequestedModulePriority, unless they were
+// not found less than notFoundRetryInterval ago, the queue is full, or
+// enqueueNotFoundLimiter does not allow it. Errors are logged.
+func enqueueNotFound(ctx context.Context, db *postgres.DB, results []*fetchResult, requestedVersion string) {
+	for _, fr := range results {
+		if fr.status != http.StatusNotFound {
+			continue
+		}
+		if !enqueueNotFoundLimiter.Allow() {
+			log.Infof(ctx, "enqueueNotFound: rate limited; not enqueuing %s@%s", fr.modulePath, requestedVersion)
+			return
+		}
+		if _, err := db.EnqueueRequestedModule(ctx, fr.modulePath, requestedVersion,
+			time.Now().Add(-notFoundRetryInterval), maxNotFoundQueued); err != nil {
+			log.Errorf(ctx, "enqueueNotFound: %v", err)
+		}
+	}
+}
+
 func (s *Server) fetchModule(ctx context.Context, fullPath, modulePath, requestedVersion string) (fr *fetchResult) {
 	// Before enqueuing the module version to be fetched, check if we have
 	// already attempted to fetch it in the past. If so, just return the result
@@ -38,6 +38,7 @@ var (
 )
 
 func TestFetch(t *testing.T) {
+	skipIfNoDB(t)
 	for _, test := range []struct {
 		name, fullPath, version, want string
 	}{
@@ -87,6 +88,7 @@ func TestFetch(t *testing.T) {
 }
 
 func TestFetchErrors(t *testing.T) {
+	skipIfNoDB(t)
 	for _, test := range []struct {
 		name, modulePath, fullPath, version string
 		fetchTimeout                        time.Duration
@@ -145,6 +147,7 @@ func TestFetchErrors(t *testing.T) {
 }
 
 func TestFetchPathAlreadyExists(t *testing.T) {
+	skipIfNoDB(t)
 	for _, status := range []int{
 		http.StatusOK,
 		http.StatusNotFound,
@@ -0,0 +1,250 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/stdlib"
+)
+
+// filesPathPrefix is the path prefix of the module file tree browser.
+const filesPathPrefix = "/files/"
+
+// maxFileTreeSubdirs is the maximum number of subdirectories of a directory
+// whose entries are listed on the directory's page. Subdirectories beyond
+// that are only linked to.
+const maxFileTreeSubdirs = 50
+
+// FileTreePage contains data for the page that shows a directory or file of
+// a module version.
+type FileTreePage struct {
+	basePage
+	ModulePath string
+	Version    string
+	// Path is the path of the directory or file relative to the module root.
+	// It is empty for the module root.
+	Path string
+	// Name is the last element of Path.
+	Name string
+	// Breadcrumbs link to the module root and to each parent directory of
+	// Path.
+	Breadcrumbs []*FileTreeEntry
+	// Entries are the contents of the directory at Path, or nil if Path is a
+	// file.
+	Entries []*FileTreeEntry
+	// File is the file at Path, or nil if Path is a directory.
+	File *FileView
+}
+
+// FileTreeEntry is a file or directory listed on a FileTreePage.
+type FileTreeEntry struct {
+	Name  string
+	URL   string
+	IsDir bool
+	Size  string
+	// Entries are the contents of the directory, if it is a directory
+	// whose contents were fetched.
+	Entries []*FileTreeEntry
+}
+
+// FileView is a file shown on a FileTreePage.
+type FileView struct {
+	Size        string
+	ContentType string
+	// Contents is the HTML of the file contents, or empty if they are not
+	// available.
+	Contents template.HTML
+}
+
+// handleFileTree handles requests for
+// /files/<module-path>@<version>/<subpath>, by serving a page that shows the
+// files and subdirectories of the directory at subpath, or the file at
+// subpath.
+func (s *Server) handleFileTree(w http.ResponseWriter, r *http.Request) (err error) {
+	urlPath := strings.TrimPrefix(r.URL.Path, filesPathPrefix)
+	defer derrors.Wrap(&err, "handleFileTree(w, r[%q])", urlPath)
+
+	modulePath, version, subpath, err := parseFilesPath(urlPath)
+	if err != nil {
+		return &serverError{
+			status: http.StatusBadRequest,
+			err:    err,
+			epage: &errorPage{
+				Message:          fmt.Sprintf("%q is not a valid module file path.", urlPath),
+				SecondaryMessage: template.HTML(fmt.Sprintf("Use <code>%s&lt;module&gt;@&lt;version&gt;/&lt;path&gt;</code>.", filesPathPrefix)),
+			},
+		}
+	}
+	ctx := r.Context()
+	files, err := s.ds.GetModuleFiles(ctx, modulePath, version, subpath)
+	if err != nil && !errors.Is(err, derrors.NotFound) {
+		return err
+	}
+	if len(files) == 0 {
+		return &serverError{
+			status: http.StatusNotFound,
+			err:    err,
+			epage:  &errorPage{Message: fmt.Sprintf("%s@%s/%s could not be found.", modulePath, version, subpath)},
+		}
+	}
+	page := &FileTreePage{
+		basePage:    s.newBasePage(r, fmt.Sprintf("%s@%s/%s", modulePath, version, subpath)),
+		ModulePath:  modulePath,
+		Version:     version,
+		Path:        subpath,
+		Name:        path.Base(subpath),
+		Breadcrumbs: fileTreeBreadcrumbs(modulePath, version, subpath),
+	}
+	if len(files) == 1 && files[0].Name == subpath && !files[0].IsDir {
+		page.File = newFileView(files[0], modulePath, version)
+	} else {
+		page.Entries, err = fileTreeEntries(ctx, s.ds, modulePath, version, files, true)
+		if err != nil {
+			return err
+		}
+	}
+	s.servePage(ctx, w, "files.tmpl", page)
+	return nil
+}
+
+// parseFilesPath parses urlPath, which has the form
+// <module-path>@<version>[/<subpath>].
+func parseFilesPath(urlPath string) (modulePath, version, subpath string, err error) {
+	i := strings.IndexByte(urlPath, '@')
+	if i < 0 {
+		return "", "", "", fmt.Errorf("missing version: %w", derrors.InvalidArgument)
+	}
+	modulePath = urlPath[:i]
+	version = urlPath[i+1:]
+	if j := strings.IndexByte(version, '/'); j >= 0 {
+		version, subpath = version[:j], strings.Trim(version[j+1:], "/")
+	}
+	if modulePath != stdlib.ModulePath {
+		if err := module.CheckPath(modulePath); err != nil {
+			return "", "", "", fmt.Errorf("%v: %w", err, derrors.InvalidArgument)
+		}
+	}
+	if !semver.IsValid(version) {
+		return "", "", "", fmt.Errorf("%q is not a valid semantic version: %w", version, derrors.InvalidArgument)
+	}
+	if subpath != "" && path.Clean("/"+subpath) != "/"+subpath {
+		return "", "", "", fmt.Errorf("%q is not a clean path: %w", subpath, derrors.InvalidArgument)
+	}
+	return modulePath, version, subpath, nil
+}
+
+// fileTreeURL returns the URL of the file tree page for the given path in
+// the module version.
+func fileTreeURL(modulePath, version, subpath string) string {
+	u := fmt.Sprintf("%s%s@%s", filesPathPrefix, modulePath, version)
+	if subpath != "" {
+		u += "/" + subpath
+	}
+	return u
+}
+
+// fileTreeBreadcrumbs returns links to the module root and to each parent
+// directory of subpath.
+func fileTreeBreadcrumbs(modulePath, version, subpath string) []*FileTreeEntry {
+	crumbs := []*FileTreeEntry{{
+		Name:  modulePath + "@" + version,
+		URL:   fileTreeURL(modulePath, version, ""),
+		IsDir: true,
+	}}
+	if subpath == "" {
+		return crumbs
+	}
+	elems := strings.Split(subpath, "/")
+	for i, e := range elems[:len(elems)-1] {
+		crumbs = append(crumbs, &FileTreeEntry{
+			Name:  e,
+			URL:   fileTreeURL(modulePath, version, strings.Join(elems[:i+1], "/")),
+			IsDir: true,
+		})
+	}
+	return crumbs
+}
+
+// fileTreeEntries returns the entries for files. If expand is true, the
+// contents of up to maxFileTreeSubdirs of the directories in files are
+// fetched as well, so that they can be shown as a collapsible tree.
+func fileTreeEntries(ctx context.Context, ds internal.DataSource, modulePath, version string, files []*internal.FileInfo, expand bool) (_ []*FileTreeEntry, err error) {
+	var (
+		entries []*FileTreeEntry
+		ndirs   int
+	)
+	for _, f := range files {
+		e := &FileTreeEntry{
+			Name:  path.Base(f.Name),
+			URL:   fileTreeURL(modulePath, version, f.Name),
+			IsDir: f.IsDir,
+			Size:  formatFileSize(f.Size),
+		}
+		if f.IsDir && expand && ndirs < maxFileTreeSubdirs {
+			ndirs++
+			sub, err := ds.GetModuleFiles(ctx, modulePath, version, f.Name)
+			if err != nil && !errors.Is(err, derrors.NotFound) {
+				return nil, err
+			}
+			e.Entries, err = fileTreeEntries(ctx, ds, modulePath, version, sub, false)
+			if err != nil {
+				return nil, err
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// formatFileSize formats a size in bytes for display, like "12.3 kB".
+func formatFileSize(n int64) string {
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "kMGTPE"[exp])
+}
+
+// newFileView returns the FileView for f, a file of the given module version.
+// Go files are rendered by renderSourceFile, with links to the documentation
+// of the package, except for tests.
+func newFileView(f *internal.FileInfo, modulePath, version string) *FileView {
+	fv := &FileView{
+		Size:        formatFileSize(f.Size),
+		ContentType: f.ContentType,
+	}
+	if f.Contents == nil {
+		return fv
+	}
+	if mt, _, _ := mime.ParseMediaType(f.ContentType); mt == "text/x-go" {
+		var pkgPath string
+		if !strings.HasSuffix(f.Name, "_test.go") {
+			pkgPath = path.Join(modulePath, path.Dir(f.Name))
+		}
+		if h, err := renderSourceFile(f.Contents, pkgPath, version); err == nil {
+			fv.Contents = h
+			return fv
+		}
+	}
+	fv.Contents = renderTextFile(f.Contents)
+	return fv
+}
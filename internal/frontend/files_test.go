@@ -0,0 +1,190 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+// filesDataSource is a DataSource with a single module version, whose files
+// are files.
+type filesDataSource struct {
+	internal.DataSource
+	modulePath, version string
+	files               []*internal.FileInfo
+}
+
+// GetModuleFiles is a simplified version of postgres.DB.GetModuleFiles, which
+// doesn't compute directory sizes.
+func (ds filesDataSource) GetModuleFiles(ctx context.Context, modulePath, version, subpath string) ([]*internal.FileInfo, error) {
+	if modulePath != ds.modulePath || version != ds.version {
+		return nil, derrors.NotFound
+	}
+	var (
+		entries []*internal.FileInfo
+		seen    = map[string]bool{}
+	)
+	for _, f := range ds.files {
+		if f.Name == subpath {
+			return []*internal.FileInfo{f}, nil
+		}
+		dir := path.Dir(f.Name)
+		if dir == "." {
+			dir = ""
+		}
+		switch {
+		case dir == subpath:
+			entries = append(entries, &internal.FileInfo{Name: f.Name, Size: f.Size, ContentType: f.ContentType})
+		case subpath == "" || strings.HasPrefix(dir, subpath+"/"):
+			name := strings.SplitN(strings.TrimPrefix(dir, subpath+"/"), "/", 2)[0]
+			if subpath != "" {
+				name = subpath + "/" + name
+			}
+			if !seen[name] {
+				seen[name] = true
+				entries = append([]*internal.FileInfo{{Name: name, IsDir: true}}, entries...)
+			}
+		}
+	}
+	if len(entries) == 0 {
+		return nil, derrors.NotFound
+	}
+	return entries, nil
+}
+
+func TestHandleFileTree(t *testing.T) {
+	ds := filesDataSource{
+		modulePath: "github.com/a/b",
+		version:    "v1.0.0",
+		files: []*internal.FileInfo{
+			{Name: "LICENSE", Size: 1500, ContentType: "text/plain; charset=utf-8", Contents: []byte("Use <freely>.")},
+			{Name: "go.mod", Size: 20, ContentType: "text/plain; charset=utf-8", Contents: []byte("module github.com/a/b\n")},
+			{Name: "internal/deep/d.go", Size: 10, ContentType: "text/x-go; charset=utf-8", Contents: []byte("package d\n")},
+			{Name: "p/logo.png", Size: 300, ContentType: "image/png"},
+			{Name: "p/p.go", Size: 40, ContentType: "text/x-go; charset=utf-8", Contents: []byte("// Package p.\npackage p\n\nconst S = \"<s>\"\n")},
+		},
+	}
+	s, err := NewServer(ServerConfig{DataSource: ds, StaticPath: "../../content/static"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := s.errorHandler(s.handleFileTree)
+	for _, test := range []struct {
+		name, path string
+		wantStatus int
+		want       []string
+	}{
+		{
+			name:       "module root",
+			path:       "/files/github.com/a/b@v1.0.0/",
+			wantStatus: http.StatusOK,
+			want: []string{
+				`<a href="/files/github.com/a/b@v1.0.0/LICENSE">LICENSE</a> <span class="FileTree-size">1.5 kB</span>`,
+				`<a href="/files/github.com/a/b@v1.0.0/go.mod">go.mod</a>`,
+				`<summary><a href="/files/github.com/a/b@v1.0.0/p">p/</a>`,
+				`<a href="/files/github.com/a/b@v1.0.0/p/p.go">p.go</a>`,
+				`<a href="/files/github.com/a/b@v1.0.0/p/logo.png">logo.png</a>`,
+				`<a class="FileTree-dir" href="/files/github.com/a/b@v1.0.0/internal/deep">deep/</a>`,
+			},
+		},
+		{
+			name:       "directory",
+			path:       "/files/github.com/a/b@v1.0.0/p",
+			wantStatus: http.StatusOK,
+			want: []string{
+				`<a href="/files/github.com/a/b@v1.0.0">github.com/a/b@v1.0.0</a>/p`,
+				`<a href="/files/github.com/a/b@v1.0.0/p/p.go">p.go</a>`,
+			},
+		},
+		{
+			name:       "go file",
+			path:       "/files/github.com/a/b@v1.0.0/p/p.go",
+			wantStatus: http.StatusOK,
+			want: []string{
+				`<a href="/files/github.com/a/b@v1.0.0/p">p</a>/p.go`,
+				`<span class="FileView-comment">// Package p.</span>`,
+				`<span class="FileView-keyword">package</span> p`,
+				`<span class="FileView-string">&#34;&lt;s&gt;&#34;</span>`,
+				`<a href="/github.com/a/b/p@v1.0.0#S">S</a>`,
+			},
+		},
+		{
+			name:       "text file",
+			path:       "/files/github.com/a/b@v1.0.0/LICENSE",
+			wantStatus: http.StatusOK,
+			want:       []string{`<pre class="FileView-contents"><span id="L1"></span>Use &lt;freely&gt;.</pre>`},
+		},
+		{
+			name:       "binary file",
+			path:       "/files/github.com/a/b@v1.0.0/p/logo.png",
+			wantStatus: http.StatusOK,
+			want:       []string{"300 B | image/png", "The contents of this file are not available."},
+		},
+		{
+			name:       "missing file",
+			path:       "/files/github.com/a/b@v1.0.0/p/missing.go",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "missing version",
+			path:       "/files/github.com/a/b@v1.2.0/",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "no version",
+			path:       "/files/github.com/a/b",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid version",
+			path:       "/files/github.com/a/b@master/",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unclean path",
+			path:       "/files/github.com/a/b@v1.0.0/p/../go.mod",
+			wantStatus: http.StatusBadRequest,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+			if w.Code != test.wantStatus {
+				t.Fatalf("GET %q = %d, want %d", test.path, w.Code, test.wantStatus)
+			}
+			body := w.Body.String()
+			for _, want := range test.want {
+				if !strings.Contains(body, want) {
+					t.Errorf("GET %q: body does not contain %q", test.path, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatFileSize(t *testing.T) {
+	for _, test := range []struct {
+		in   int64
+		want string
+	}{
+		{0, "0 B"},
+		{999, "999 B"},
+		{1000, "1.0 kB"},
+		{12345, "12.3 kB"},
+		{4500000, "4.5 MB"},
+	} {
+		if got := formatFileSize(test.in); got != test.want {
+			t.Errorf("formatFileSize(%d) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
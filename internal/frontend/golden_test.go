@@ -0,0 +1,79 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/pkgsite/internal/proxy"
+	"golang.org/x/pkgsite/internal/proxydatasource"
+	"golang.org/x/pkgsite/internal/testing/goldentest"
+	"golang.org/x/pkgsite/internal/testing/testhelper"
+)
+
+// goldenModule is the module whose pages are compared with golden files. It is
+// hosted on GitHub, so that its source information is known without network
+// access.
+var goldenModule = &proxy.TestModule{
+	ModulePath: "github.com/golden/module",
+	Files: map[string]string{
+		"LICENSE":   testhelper.MITLicense,
+		"README.md": "# Golden\n\nThis module tests the rendering of pages.\n",
+		"pkg/pkg.go": `// Package pkg prints greetings.
+package pkg
+
+import "fmt"
+
+// Greeting is the default greeting.
+const Greeting = "Hello"
+
+// Hello prints a greeting to name.
+func Hello(name string) {
+	fmt.Println(Greeting + ", " + name)
+}
+`,
+	},
+}
+
+// TestGoldenPages compares the rendered details pages of goldenModule with
+// golden files in testdata. Run it with -update to update them after an
+// intended change to the templates.
+func TestGoldenPages(t *testing.T) {
+	proxyClient, teardown := proxy.SetupTestProxy(t, []*proxy.TestModule{goldenModule})
+	defer teardown()
+	s, err := NewServer(ServerConfig{
+		DataSource:           proxydatasource.New(proxyClient),
+		TaskIDChangeInterval: 10 * time.Minute,
+		StaticPath:           "../../content/static",
+		ThirdPartyPath:       "../../third_party",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	s.Install(mux.Handle, nil)
+
+	pkgPath := goldenModule.ModulePath + "/pkg"
+	for _, test := range []struct {
+		name, path string
+	}{
+		{"package-overview", "/" + pkgPath + "?tab=overview"},
+		{"package-doc", "/" + pkgPath + "?tab=doc"},
+		{"package-imports", "/" + pkgPath + "?tab=imports"},
+		{"package-versions", "/" + pkgPath + "?tab=versions"},
+		{"module-overview", "/mod/" + goldenModule.ModulePath + "?tab=overview"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			goldentest.MakeGoldenHandler(t, test.name, mux).ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+			if w.Code != http.StatusOK {
+				t.Errorf("GET %q = %d, want %d", test.path, w.Code, http.StatusOK)
+			}
+		})
+	}
+}
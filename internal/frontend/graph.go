@@ -0,0 +1,164 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/graph"
+	"golang.org/x/pkgsite/internal/log"
+)
+
+const (
+	// graphPathPrefix is the path prefix of the dependency graph endpoint.
+	graphPathPrefix = "/graph/"
+
+	// maxGraphDepth is the number of levels of dependencies in a dependency
+	// graph, and maxGraphNodes is the maximum number of modules in it.
+	maxGraphDepth = 2
+	maxGraphNodes = 200
+)
+
+// handleGraph handles requests for /graph/<module-path>@<version>[?format=dot]
+// by serving the graph of the direct dependencies of the module version, and
+// of their direct dependencies, in the DOT language of Graphviz. Retracted and
+// vulnerable module versions are drawn in red. DOT is the only format.
+func (s *Server) handleGraph(w http.ResponseWriter, r *http.Request) (err error) {
+	urlPath := strings.TrimPrefix(r.URL.Path, graphPathPrefix)
+	defer derrors.Wrap(&err, "handleGraph(w, r[%q])", urlPath)
+
+	modulePath, version, subpath, err := parseFilesPath(urlPath)
+	if err == nil && subpath != "" {
+		err = fmt.Errorf("unexpected path after version: %w", derrors.InvalidArgument)
+	}
+	if err != nil {
+		return &serverError{
+			status: http.StatusBadRequest,
+			err:    err,
+			epage:  &errorPage{Message: "The path must have the form /graph/<module-path>@<version>."},
+		}
+	}
+	if format := r.FormValue("format"); format != "" && format != "dot" {
+		return &serverError{
+			status: http.StatusBadRequest,
+			err:    fmt.Errorf("unsupported format %q: %w", format, derrors.InvalidArgument),
+			epage:  &errorPage{Message: fmt.Sprintf("Unsupported format %q.", format)},
+		}
+	}
+	ctx := r.Context()
+	if _, err := s.ds.LegacyGetModuleInfo(ctx, modulePath, version); err != nil {
+		if errors.Is(err, derrors.NotFound) {
+			return &serverError{status: http.StatusNotFound, err: err}
+		}
+		return err
+	}
+	nodes, edges, err := dependencyGraph(ctx, s.ds, modulePath, version)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+	if _, err := io.WriteString(w, graph.ToDOT(nodes, edges)); err != nil {
+		log.Errorf(ctx, "handleGraph: %v", err)
+	}
+	return nil
+}
+
+// dependencyGraph returns the nodes and edges of the graph of the direct
+// dependencies of the module version, followed to a depth of maxGraphDepth.
+// The graph is truncated at maxGraphNodes nodes, dropping the farthest
+// dependencies.
+func dependencyGraph(ctx context.Context, ds internal.DataSource, modulePath, version string) (_ []graph.Node, _ []graph.Edge, err error) {
+	defer derrors.Wrap(&err, "dependencyGraph(ctx, ds, %q, %q)", modulePath, version)
+
+	deps, err := nearestDependencies(ctx, ds, modulePath, version, maxGraphDepth, maxGraphNodes-1)
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		nodes  []graph.Node
+		depths = map[string]int{} // by node ID
+	)
+	for _, d := range append([]*internal.ModuleDependency{{ModulePath: modulePath, Version: version}}, deps...) {
+		flagged, err := isRetractedOrVulnerable(ctx, ds, d.ModulePath, d.Version)
+		if err != nil {
+			return nil, nil, err
+		}
+		n := graph.Node{
+			ID:  d.ModulePath + "@" + d.Version,
+			URL: constructModuleURL(d.ModulePath, linkVersion(d.Version, d.ModulePath)),
+		}
+		if flagged {
+			n.Color = "red"
+		}
+		depths[n.ID] = d.Depth
+		nodes = append(nodes, n)
+	}
+	// Only the imports of the modules within maxGraphDepth-1 of the module
+	// version are edges, so that the graph has the same shape as if the
+	// direct dependencies of each module were followed in turn.
+	var edges []graph.Edge
+	for _, d := range deps {
+		for _, from := range d.ImportedBy {
+			if depth, ok := depths[from]; ok && depth < maxGraphDepth {
+				edges = append(edges, graph.Edge{From: from, To: d.ModulePath + "@" + d.Version})
+			}
+		}
+	}
+	return nodes, edges, nil
+}
+
+// nearestDependencies returns the dependencies of the module version within
+// maxDepth of it, with a single call to GetModuleDependencies, sorted by
+// depth and then by module path. At most max dependencies are returned; the
+// farthest are dropped.
+func nearestDependencies(ctx context.Context, ds internal.DataSource, modulePath, version string, maxDepth, max int) ([]*internal.ModuleDependency, error) {
+	all, err := ds.GetModuleDependencies(ctx, modulePath, version)
+	if err != nil {
+		return nil, err
+	}
+	var deps []*internal.ModuleDependency
+	for _, d := range all {
+		if d.Depth <= maxDepth {
+			deps = append(deps, d)
+		}
+	}
+	sort.SliceStable(deps, func(i, j int) bool {
+		if deps[i].Depth != deps[j].Depth {
+			return deps[i].Depth < deps[j].Depth
+		}
+		return deps[i].ModulePath < deps[j].ModulePath
+	})
+	if len(deps) > max {
+		deps = deps[:max]
+	}
+	return deps, nil
+}
+
+// isRetractedOrVulnerable reports whether the module version is retracted or
+// affected by a known vulnerability.
+func isRetractedOrVulnerable(ctx context.Context, ds internal.DataSource, modulePath, version string) (bool, error) {
+	retracted, err := ds.GetRetractedVersions(ctx, modulePath)
+	if err != nil {
+		return false, err
+	}
+	for _, v := range retracted {
+		if v == version {
+			return true, nil
+		}
+	}
+	vulns, err := ds.GetVulnerabilities(ctx, modulePath, version)
+	if err != nil {
+		return false, err
+	}
+	return len(vulns) > 0, nil
+}
@@ -0,0 +1,153 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+// graphDataSource is a DataSource with the dependencies of some module
+// versions.
+type graphDataSource struct {
+	internal.DataSource
+	deps       map[string][]*internal.ModuleDependency // by module@version
+	retracted  map[string][]string                     // by module path
+	vulnerable map[string]bool                         // by module@version
+	depCalls   int                                     // calls to GetModuleDependencies
+}
+
+func (ds *graphDataSource) LegacyGetModuleInfo(ctx context.Context, modulePath, version string) (*internal.LegacyModuleInfo, error) {
+	if _, ok := ds.deps[modulePath+"@"+version]; !ok {
+		return nil, derrors.NotFound
+	}
+	return &internal.LegacyModuleInfo{}, nil
+}
+
+func (ds *graphDataSource) GetModuleDependencies(ctx context.Context, modulePath, version string) ([]*internal.ModuleDependency, error) {
+	ds.depCalls++
+	return ds.deps[modulePath+"@"+version], nil
+}
+
+func (ds *graphDataSource) GetRetractedVersions(ctx context.Context, modulePath string) ([]string, error) {
+	return ds.retracted[modulePath], nil
+}
+
+func (ds *graphDataSource) GetVulnerabilities(ctx context.Context, modulePath, version string) ([]*internal.VulnReport, error) {
+	if ds.vulnerable[modulePath+"@"+version] {
+		return []*internal.VulnReport{{ID: "GO-2020-0001"}}, nil
+	}
+	return nil, nil
+}
+
+func TestHandleGraph(t *testing.T) {
+	dep := func(path, version string, depth int, importedBy ...string) *internal.ModuleDependency {
+		return &internal.ModuleDependency{ModulePath: path, Version: version, IsIndirect: depth > 1, Depth: depth, ImportedBy: importedBy}
+	}
+	ds := &graphDataSource{
+		deps: map[string][]*internal.ModuleDependency{
+			"example.com/a@v1.0.0": {
+				dep("example.com/b", "v1.1.0", 1, "example.com/a@v1.0.0"),
+				dep("example.com/c", "v0.2.0", 1, "example.com/a@v1.0.0", "example.com/b@v1.1.0"),
+				dep("example.com/d", "v1.0.0", 2, "example.com/b@v1.1.0"),
+				// Beyond the maximum depth.
+				dep("example.com/e", "v1.0.0", 3, "example.com/d@v1.0.0"),
+			},
+			"example.com/d@v1.0.0": {
+				dep("example.com/e", "v1.0.0", 1, "example.com/d@v1.0.0"),
+			},
+		},
+		retracted:  map[string][]string{"example.com/c": {"v0.2.0"}},
+		vulnerable: map[string]bool{"example.com/d@v1.0.0": true},
+	}
+	s, err := NewServer(ServerConfig{DataSource: ds, StaticPath: "../../content/static"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	s.Install(mux.Handle, nil)
+
+	for _, test := range []struct {
+		name, path string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "graph",
+			path:       "/graph/example.com/a@v1.0.0?format=dot",
+			wantStatus: http.StatusOK,
+			wantBody: `digraph {
+	"example.com/a@v1.0.0" [label="example.com/a@v1.0.0", URL="/mod/example.com/a@v1.0.0"];
+	"example.com/b@v1.1.0" [label="example.com/b@v1.1.0", URL="/mod/example.com/b@v1.1.0"];
+	"example.com/c@v0.2.0" [label="example.com/c@v0.2.0", URL="/mod/example.com/c@v0.2.0", color="red"];
+	"example.com/d@v1.0.0" [label="example.com/d@v1.0.0", URL="/mod/example.com/d@v1.0.0", color="red"];
+	"example.com/a@v1.0.0" -> "example.com/b@v1.1.0";
+	"example.com/a@v1.0.0" -> "example.com/c@v0.2.0";
+	"example.com/b@v1.1.0" -> "example.com/c@v0.2.0";
+	"example.com/b@v1.1.0" -> "example.com/d@v1.0.0";
+}
+`,
+		},
+		{
+			name:       "no dependencies",
+			path:       "/graph/example.com/d@v1.0.0",
+			wantStatus: http.StatusOK,
+			wantBody: `digraph {
+	"example.com/d@v1.0.0" [label="example.com/d@v1.0.0", URL="/mod/example.com/d@v1.0.0", color="red"];
+	"example.com/e@v1.0.0" [label="example.com/e@v1.0.0", URL="/mod/example.com/e@v1.0.0"];
+	"example.com/d@v1.0.0" -> "example.com/e@v1.0.0";
+}
+`,
+		},
+		{name: "not found", path: "/graph/example.com/x@v1.0.0", wantStatus: http.StatusNotFound},
+		{name: "no version", path: "/graph/example.com/a", wantStatus: http.StatusBadRequest},
+		{name: "subpath", path: "/graph/example.com/a@v1.0.0/p", wantStatus: http.StatusBadRequest},
+		{name: "bad format", path: "/graph/example.com/a@v1.0.0?format=svg", wantStatus: http.StatusBadRequest},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+			if w.Code != test.wantStatus {
+				t.Fatalf("GET %s: got status %d, want %d", test.path, w.Code, test.wantStatus)
+			}
+			if test.wantBody == "" {
+				return
+			}
+			if diff := cmp.Diff(test.wantBody, w.Body.String()); diff != "" {
+				t.Errorf("GET %s mismatch (-want +got):\n%s", test.path, diff)
+			}
+		})
+	}
+}
+
+func TestDependencyGraphMaxNodes(t *testing.T) {
+	var deps []*internal.ModuleDependency
+	for i := 0; i < 2*maxGraphNodes; i++ {
+		deps = append(deps, &internal.ModuleDependency{
+			ModulePath: fmt.Sprintf("example.com/m%d", i),
+			Version:    "v1.0.0",
+			Depth:      1,
+			ImportedBy: []string{"example.com/a@v1.0.0"},
+		})
+	}
+	ds := &graphDataSource{deps: map[string][]*internal.ModuleDependency{"example.com/a@v1.0.0": deps}}
+	nodes, edges, err := dependencyGraph(context.Background(), ds, "example.com/a", "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != maxGraphNodes || len(edges) != maxGraphNodes-1 {
+		t.Errorf("got %d nodes and %d edges, want %d and %d", len(nodes), len(edges), maxGraphNodes, maxGraphNodes-1)
+	}
+	if ds.depCalls != 1 {
+		t.Errorf("got %d calls to GetModuleDependencies, want 1", ds.depCalls)
+	}
+}
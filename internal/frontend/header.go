@@ -29,6 +29,17 @@ type Package struct {
 	URL                string // relative to this site
 	LatestURL          string // link with latest-version placeholder, relative to this site
 	Licenses           []LicenseMetadata
+	// HasTests reports whether the directory of the package has a Go test
+	// file, and HasTestsKnown whether that is known. They are only set for
+	// package pages.
+	HasTests      bool
+	HasTestsKnown bool
+	// RequiresCgo reports whether the package is known to import "C". It is
+	// only set for package pages.
+	RequiresCgo bool
+	// HasFuzzTests reports whether the package has a fuzz target. It is only
+	// set for package pages.
+	HasFuzzTests bool
 }
 
 // Module contains information for an individual module.
@@ -41,6 +52,13 @@ type Module struct {
 	URL               string // relative to this site
 	LatestURL         string // link with latest-version placeholder, relative to this site
 	Licenses          []LicenseMetadata
+	// ToolchainVersion is the Go toolchain required by the module, like
+	// "go1.21.3". It is only set for module pages.
+	ToolchainVersion string
+	// Links are the links to the issue tracker and the CI status of the
+	// repository of the module, if known. They are only set for module
+	// pages.
+	Links *internal.ModuleLinks
 }
 
 // legacyCreatePackage returns a *Package based on the fields of the specified
@@ -0,0 +1,53 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"golang.org/x/pkgsite/internal/log"
+)
+
+// readinessTimeout limits the time that handleReadiness waits for the
+// database.
+var readinessTimeout = 2 * time.Second
+
+// A pinger is a DataSource with a database whose connectivity can be
+// checked, like *postgres.DB.
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// healthStatus is the response of the health checks.
+type healthStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// handleReadiness handles requests for /healthz/ready, which report whether
+// the server is ready to serve traffic: it serves a 200 if the database of
+// the server's DataSource can be queried within readinessTimeout, and a 503
+// otherwise. A DataSource without a database is always ready.
+func (s *Server) handleReadiness(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if p, ok := s.ds.(pinger); ok {
+		ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+		defer cancel()
+		if err := p.Ping(ctx); err != nil {
+			log.Errorf(ctx, "handleReadiness: %v", err)
+			writeJSON(ctx, w, http.StatusServiceUnavailable, &healthStatus{Status: "db_unavailable", Error: err.Error()})
+			return
+		}
+	}
+	writeJSON(ctx, w, http.StatusOK, &healthStatus{Status: "ok"})
+}
+
+// handleLiveness handles requests for /healthz/live, which report whether the
+// server is running. It always serves a 200.
+func (s *Server) handleLiveness(w http.ResponseWriter, r *http.Request) {
+	writeJSON(r.Context(), w, http.StatusOK, &healthStatus{Status: "ok"})
+}
@@ -0,0 +1,90 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/pkgsite/internal"
+)
+
+// pingDataSource is a DataSource whose Ping takes delay.
+type pingDataSource struct {
+	internal.DataSource
+	delay time.Duration
+}
+
+func (ds *pingDataSource) Ping(ctx context.Context) error {
+	select {
+	case <-time.After(ds.delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestHealthz(t *testing.T) {
+	defer func(t time.Duration) { readinessTimeout = t }(readinessTimeout)
+	readinessTimeout = 50 * time.Millisecond
+
+	for _, test := range []struct {
+		name, path string
+		ds         internal.DataSource
+		wantStatus int
+		want       healthStatus
+	}{
+		{
+			name:       "ready",
+			path:       "/healthz/ready",
+			ds:         &pingDataSource{},
+			wantStatus: http.StatusOK,
+			want:       healthStatus{Status: "ok"},
+		},
+		{
+			name:       "slow database",
+			path:       "/healthz/ready",
+			ds:         &pingDataSource{delay: time.Minute},
+			wantStatus: http.StatusServiceUnavailable,
+			want:       healthStatus{Status: "db_unavailable", Error: context.DeadlineExceeded.Error()},
+		},
+		{
+			name:       "no database",
+			path:       "/healthz/ready",
+			ds:         &closingDataSource{},
+			wantStatus: http.StatusOK,
+			want:       healthStatus{Status: "ok"},
+		},
+		{
+			name:       "live with slow database",
+			path:       "/healthz/live",
+			ds:         &pingDataSource{delay: time.Minute},
+			wantStatus: http.StatusOK,
+			want:       healthStatus{Status: "ok"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s := &Server{ds: test.ds}
+			mux := http.NewServeMux()
+			s.Install(mux.Handle, nil)
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+			if w.Code != test.wantStatus {
+				t.Errorf("got status %d, want %d", w.Code, test.wantStatus)
+			}
+			var got healthStatus
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("%v; body: %s", err, w.Body)
+			}
+			if got != test.want {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
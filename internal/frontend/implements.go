@@ -0,0 +1,86 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/token"
+	"net/http"
+	"strings"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/log"
+	"golang.org/x/pkgsite/internal/stdlib"
+)
+
+// implementsPath is the path of the interface implementations endpoint.
+const implementsPath = "/implements"
+
+// implementsResponse is the JSON response of the interface implementations
+// endpoint.
+type implementsResponse struct {
+	// Interface is the interface, as in "io.Reader".
+	Interface       string
+	Implementations []*internal.TypeRef
+}
+
+// handleImplements handles requests for /implements?iface=<import-path>.<name>,
+// by serving the JSON of an implementsResponse that lists the exported types
+// of the latest versions of modules that implement the interface, or of a
+// ProblemDetail if the request fails.
+func (s *Server) handleImplements(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	iface := r.FormValue("iface")
+	refs, err := s.interfaceImplementations(ctx, iface)
+	if err != nil {
+		status := http.StatusInternalServerError
+		var serr *serverError
+		if errors.As(err, &serr) {
+			status = serr.status
+		}
+		if status == http.StatusInternalServerError {
+			log.Errorf(ctx, "handleImplements: %v", err)
+		}
+		WriteJSONError(w, newProblemDetail(r, status, ""))
+		return
+	}
+	writeJSON(ctx, w, http.StatusOK, &implementsResponse{Interface: iface, Implementations: refs})
+}
+
+// interfaceImplementations returns the types that implement iface, which has
+// the form <import-path>.<name>.
+func (s *Server) interfaceImplementations(ctx context.Context, iface string) (_ []*internal.TypeRef, err error) {
+	defer derrors.Wrap(&err, "interfaceImplementations(ctx, %q)", iface)
+
+	pkgPath, name, err := parseQualifiedName(iface)
+	if err != nil {
+		return nil, &serverError{status: http.StatusBadRequest, err: err}
+	}
+	return s.ds.GetInterfaceImplementations(ctx, pkgPath, name)
+}
+
+// parseQualifiedName splits s, of the form <import-path>.<name>, into the
+// import path and the exported name. The name is the part of s after the
+// last dot, since import paths may contain dots but names cannot.
+func parseQualifiedName(s string) (pkgPath, name string, err error) {
+	i := strings.LastIndexByte(s, '.')
+	if i <= 0 {
+		return "", "", fmt.Errorf("%q is not of the form <import-path>.<name>: %w", s, derrors.InvalidArgument)
+	}
+	pkgPath, name = s[:i], s[i+1:]
+	if !token.IsIdentifier(name) || !token.IsExported(name) {
+		return "", "", fmt.Errorf("%q is not an exported identifier: %w", name, derrors.InvalidArgument)
+	}
+	if !stdlib.Contains(pkgPath) {
+		if err := module.CheckImportPath(pkgPath); err != nil {
+			return "", "", fmt.Errorf("%v: %w", err, derrors.InvalidArgument)
+		}
+	}
+	return pkgPath, name, nil
+}
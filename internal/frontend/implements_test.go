@@ -0,0 +1,86 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/pkgsite/internal"
+)
+
+// implementsDataSource is a DataSource with the given implementations, keyed
+// by interface package path and name.
+type implementsDataSource struct {
+	internal.DataSource
+	impls map[[2]string][]*internal.TypeRef
+}
+
+func (ds implementsDataSource) GetInterfaceImplementations(ctx context.Context, ifacePkg, ifaceName string) ([]*internal.TypeRef, error) {
+	return ds.impls[[2]string{ifacePkg, ifaceName}], nil
+}
+
+func TestHandleImplements(t *testing.T) {
+	ds := implementsDataSource{
+		impls: map[[2]string][]*internal.TypeRef{
+			{"gopkg.in/shapes.v1", "Shape"}: {
+				{PackagePath: "github.com/a/b/circle", TypeName: "Circle", ModulePath: "github.com/a/b"},
+			},
+		},
+	}
+	s, err := NewServer(ServerConfig{DataSource: ds, StaticPath: "../../content/static"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		name, url  string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "implementations",
+			url:        "/implements?iface=gopkg.in/shapes.v1.Shape",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"Interface":"gopkg.in/shapes.v1.Shape","Implementations":[{"PackagePath":"github.com/a/b/circle","TypeName":"Circle","ModulePath":"github.com/a/b"}]}`,
+		},
+		{
+			name:       "no implementations",
+			url:        "/implements?iface=io.Reader",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"Interface":"io.Reader","Implementations":null}`,
+		},
+		{
+			name:       "missing parameter",
+			url:        "/implements",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"type":"about:blank","title":"Bad Request","status":400,"instance":"/implements"}`,
+		},
+		{
+			name:       "unexported name",
+			url:        "/implements?iface=io.reader",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"type":"about:blank","title":"Bad Request","status":400,"instance":"/implements"}`,
+		},
+		{
+			name:       "invalid path",
+			url:        "/implements?iface=example.com//b.Reader",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"type":"about:blank","title":"Bad Request","status":400,"instance":"/implements"}`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.handleImplements(w, httptest.NewRequest("GET", test.url, nil))
+			if w.Code != test.wantStatus {
+				t.Fatalf("GET %q = %d, want %d", test.url, w.Code, test.wantStatus)
+			}
+			if got := w.Body.String(); got != test.wantBody {
+				t.Errorf("GET %q: body = %s, want %s", test.url, got, test.wantBody)
+			}
+		})
+	}
+}
@@ -97,3 +97,42 @@ func fetchImportedByDetails(ctx context.Context, db *postgres.DB, pkgPath, modul
 		TotalIsExact: totalIsExact,
 	}, nil
 }
+
+// DependenciesDetails contains information for the modules that a module
+// depends on.
+type DependenciesDetails struct {
+	ModulePath string
+
+	// Dependencies is the flat list of direct and indirect dependencies,
+	// sorted by module path.
+	Dependencies []*Dependency
+}
+
+// Dependency is a module that another module depends on.
+type Dependency struct {
+	ModulePath string
+	Version    string
+	// URL is the URL of the module page of the dependency.
+	URL        string
+	IsIndirect bool
+}
+
+// fetchDependenciesDetails fetches the dependencies of the module version
+// specified by modulePath and version from the database and returns a
+// DependenciesDetails.
+func fetchDependenciesDetails(ctx context.Context, db *postgres.DB, modulePath, version string) (*DependenciesDetails, error) {
+	deps, err := db.GetModuleDependencies(ctx, modulePath, version)
+	if err != nil {
+		return nil, err
+	}
+	details := &DependenciesDetails{ModulePath: modulePath}
+	for _, d := range deps {
+		details.Dependencies = append(details.Dependencies, &Dependency{
+			ModulePath: d.ModulePath,
+			Version:    d.Version,
+			URL:        constructModuleURL(d.ModulePath, linkVersion(d.Version, d.ModulePath)),
+			IsIndirect: d.IsIndirect,
+		})
+	}
+	return details, nil
+}
@@ -16,6 +16,7 @@ import (
 )
 
 func TestFetchImportsDetails(t *testing.T) {
+	skipIfNoDB(t)
 	for _, tc := range []struct {
 		name        string
 		imports     []string
@@ -82,6 +83,7 @@ func firstVersionedPackage(m *internal.Module) *internal.LegacyVersionedPackage
 }
 
 func TestFetchImportedByDetails(t *testing.T) {
+	skipIfNoDB(t)
 	defer postgres.ResetTestDB(testDB, t)
 
 	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
@@ -13,6 +13,7 @@ import (
 	"golang.org/x/pkgsite/internal"
 	"golang.org/x/pkgsite/internal/derrors"
 	"golang.org/x/pkgsite/internal/log"
+	"golang.org/x/pkgsite/internal/version"
 )
 
 // legacyServeModulePage serves details pages for the module specified by modulePath
@@ -29,7 +30,7 @@ func (s *Server) legacyServeModulePage(w http.ResponseWriter, r *http.Request, m
 	//     b. We have valid versions for this module path, but `version` isn't
 	//        one of them. Serve a 404 but recommend the other versions.
 	ctx := r.Context()
-	mi, err := s.ds.LegacyGetModuleInfo(ctx, modulePath, requestedVersion)
+	mi, err := s.cachedDS.LegacyGetModuleInfo(ctx, modulePath, requestedVersion)
 	if err == nil {
 		return s.legacyServeModulePageWithModule(ctx, w, r, mi, requestedVersion)
 	}
@@ -37,7 +38,7 @@ func (s *Server) legacyServeModulePage(w http.ResponseWriter, r *http.Request, m
 		return err
 	}
 	if requestedVersion != internal.LatestVersion {
-		_, err = s.ds.LegacyGetModuleInfo(ctx, modulePath, internal.LatestVersion)
+		_, err = s.cachedDS.LegacyGetModuleInfo(ctx, modulePath, internal.LatestVersion)
 		if err == nil {
 			return pathFoundAtLatestError(ctx, "module", modulePath, displayVersion(requestedVersion, modulePath))
 		}
@@ -55,12 +56,32 @@ func (s *Server) legacyServeModulePageWithModule(ctx context.Context, w http.Res
 	}
 
 	modHeader := createModule(&mi.ModuleInfo, licensesToMetadatas(licenses), requestedVersion == internal.LatestVersion)
+	// Only show the toolchain badge for a well-formed toolchain name, since
+	// it is taken from the module's go.mod file.
+	if version.IsToolchain(mi.ToolchainVersion) {
+		modHeader.ToolchainVersion = mi.ToolchainVersion
+	}
+	modHeader.Links, err = moduleLinks(ctx, s.ds, mi.ModulePath)
+	if err != nil {
+		return err
+	}
 	tab := r.FormValue("tab")
 	settings, ok := moduleTabLookup[tab]
 	if !ok {
 		tab = "overview"
 		settings = moduleTabLookup["overview"]
 	}
+	retracted, err := isRetracted(ctx, s.ds, mi.ModulePath, mi.Version)
+	if err != nil {
+		return err
+	}
+	vulns, err := s.ds.GetVulnerabilities(ctx, mi.ModulePath, mi.Version)
+	if err != nil {
+		return err
+	}
+	if checkETag(w, r, mi.ModulePath, mi.Version, tab, retracted, vulns) {
+		return nil
+	}
 	canShowDetails := modHeader.IsRedistributable || settings.AlwaysShowDetails
 	var details interface{}
 	if canShowDetails {
@@ -80,7 +101,22 @@ func (s *Server) legacyServeModulePageWithModule(ctx context.Context, w http.Res
 		CanShowDetails: canShowDetails,
 		Tabs:           moduleTabSettings,
 		PageType:       "mod",
+		Vulns:          vulns,
+		IsRetracted:    retracted,
 	}
+	setCanonicalURL(w, canonicalURL("", mi.ModulePath))
 	s.servePage(ctx, w, settings.TemplateName, page)
 	return nil
 }
+
+// moduleLinks returns the links to the issue tracker and the CI status of the
+// repository of the module with the given path, or nil if there are none.
+func moduleLinks(ctx context.Context, ds internal.DataSource, modulePath string) (_ *internal.ModuleLinks, err error) {
+	defer derrors.Wrap(&err, "moduleLinks(ctx, ds, %q)", modulePath)
+
+	links, err := ds.GetModuleLinks(ctx, modulePath)
+	if errors.Is(err, derrors.NotFound) {
+		return nil, nil
+	}
+	return links, err
+}
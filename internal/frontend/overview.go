@@ -7,6 +7,7 @@ package frontend
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"html/template"
 	"net/url"
@@ -19,9 +20,11 @@ import (
 	"golang.org/x/net/html"
 	"golang.org/x/net/html/atom"
 	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
 	"golang.org/x/pkgsite/internal/experiment"
 	"golang.org/x/pkgsite/internal/log"
 	"golang.org/x/pkgsite/internal/stdlib"
+	"golang.org/x/pkgsite/internal/version"
 )
 
 // OverviewDetails contains all of the data that the readme template
@@ -34,6 +37,36 @@ type OverviewDetails struct {
 	ReadMeSource     string
 	Redistributable  bool
 	RepositoryURL    string
+	// ToolchainVersion is the Go toolchain required by the module, like
+	// "go1.21.3", if any.
+	ToolchainVersion string
+	// ContributorCount is the number of contributors of the repository of
+	// the module. It is zero if that is unknown, and for packages and
+	// directories.
+	ContributorCount int
+	// ExportedSymbols is the number of exported symbols of the package,
+	// including methods. It is zero for modules and directories.
+	ExportedSymbols int
+	// DocCoverage is the percentage of the exported symbols of the package
+	// that are documented, and DocCoverageLevel is "low", "medium" or "high"
+	// depending on it. DocCoverageLevel is empty if the coverage is unknown.
+	DocCoverage      int
+	DocCoverageLevel string
+	// EmbeddedPatterns are the patterns of the //go:embed directives of the
+	// package. It is empty for modules and directories.
+	EmbeddedPatterns []string
+	// BenchmarkCount is the number of benchmarks of the package. It is zero
+	// for modules and directories.
+	BenchmarkCount int
+	// BuildContexts says, for each of internal.BuildContexts, whether the
+	// package builds for it. It is nil if that is unknown, and for modules
+	// and directories.
+	BuildContexts []*BuildContextAvailability
+	// LicenseConflicts are the pairs of incompatible licenses of the package
+	// and the packages it imports, as computed when the package was
+	// inserted. It is empty if they are not known, and for modules and
+	// directories.
+	LicenseConflicts []*internal.LicenseConflict
 }
 
 // versionedLinks says whether the constructed URLs should have versions.
@@ -51,6 +84,9 @@ func constructOverviewDetails(ctx context.Context, mi *internal.ModuleInfo, read
 		RepositoryURL:   mi.SourceInfo.RepoURL(),
 		Redistributable: isRedistributable,
 	}
+	if version.IsToolchain(mi.ToolchainVersion) {
+		overview.ToolchainVersion = mi.ToolchainVersion
+	}
 	if overview.Redistributable && readme != nil {
 		overview.ReadMeSource = fileSource(mi.ModulePath, mi.Version, readme.Filepath)
 		overview.ReadMe = readmeHTML(ctx, mi, readme)
@@ -91,6 +127,111 @@ func fetchPackageOverviewDetailsNew(ctx context.Context, vdir *internal.Versione
 	return overview
 }
 
+// addSymbolDetails sets od.ExportedSymbols to the number of exported symbols
+// of the package with path pkgPath at version, and the documentation coverage
+// fields of od to the coverage in facts, if it is known and the package has
+// exported symbols.
+func addSymbolDetails(ctx context.Context, ds internal.DataSource, od *OverviewDetails, pkgPath, version string, facts internal.PackageFacts) (err error) {
+	defer derrors.Wrap(&err, "addSymbolDetails(ctx, ds, od, %q, %q)", pkgPath, version)
+
+	n, err := ds.GetExportedSymbolCount(ctx, pkgPath, version)
+	if err != nil && !errors.Is(err, derrors.NotFound) {
+		return err
+	}
+	od.ExportedSymbols = n
+	if n == 0 {
+		return nil
+	}
+	if facts.DocCoverage == nil {
+		return nil
+	}
+	od.DocCoverage = *facts.DocCoverage
+	od.DocCoverageLevel = docCoverageLevel(od.DocCoverage)
+	return nil
+}
+
+// BuildContextAvailability says whether a package builds for a build context.
+type BuildContextAvailability struct {
+	Name      string // like "linux/amd64"
+	Supported bool
+}
+
+// addBuildContexts sets the BuildContexts of od from the build contexts that
+// the package with the given path at the given version builds for.
+func addBuildContexts(ctx context.Context, ds internal.DataSource, od *OverviewDetails, pkgPath, version string) (err error) {
+	defer derrors.Wrap(&err, "addBuildContexts(ctx, ds, od, %q, %q)", pkgPath, version)
+
+	bcs, err := ds.GetBuildContexts(ctx, pkgPath, version)
+	if err != nil && !errors.Is(err, derrors.NotFound) {
+		return err
+	}
+	if bcs == nil {
+		return nil
+	}
+	supported := map[internal.BuildContext]bool{}
+	for _, bc := range bcs {
+		supported[bc] = true
+	}
+	for _, bc := range internal.BuildContexts {
+		od.BuildContexts = append(od.BuildContexts, &BuildContextAvailability{Name: bc.String(), Supported: supported[bc]})
+	}
+	return nil
+}
+
+// addEmbeddedPatterns sets the EmbeddedPatterns of od to the patterns of the
+// //go:embed directives of the package with the given path at the given
+// version.
+func addEmbeddedPatterns(ctx context.Context, ds internal.DataSource, od *OverviewDetails, pkgPath, version string) (err error) {
+	defer derrors.Wrap(&err, "addEmbeddedPatterns(ctx, ds, od, %q, %q)", pkgPath, version)
+
+	patterns, err := ds.GetEmbeddedPatterns(ctx, pkgPath, version)
+	if err != nil && !errors.Is(err, derrors.NotFound) {
+		return err
+	}
+	od.EmbeddedPatterns = patterns
+	return nil
+}
+
+// addBenchmarkCount sets the BenchmarkCount of od to the number of benchmarks
+// of the package with the given path at the given version.
+func addBenchmarkCount(ctx context.Context, ds internal.DataSource, od *OverviewDetails, pkgPath, version string) (err error) {
+	defer derrors.Wrap(&err, "addBenchmarkCount(ctx, ds, od, %q, %q)", pkgPath, version)
+
+	count, err := ds.GetBenchmarkCount(ctx, pkgPath, version)
+	if err != nil && !errors.Is(err, derrors.NotFound) {
+		return err
+	}
+	od.BenchmarkCount = count
+	return nil
+}
+
+// addContributorCount sets the ContributorCount of od to the number of
+// contributors of the repository of the module with the given path and
+// version.
+func addContributorCount(ctx context.Context, ds internal.DataSource, od *OverviewDetails, modulePath, version string) (err error) {
+	defer derrors.Wrap(&err, "addContributorCount(ctx, ds, od, %q, %q)", modulePath, version)
+
+	count, err := ds.GetContributorCount(ctx, modulePath, version)
+	if err != nil && !errors.Is(err, derrors.NotFound) {
+		return err
+	}
+	od.ContributorCount = count
+	return nil
+}
+
+// docCoverageLevel returns "low" for a documentation coverage percentage pct
+// below 50, "high" for one above 80, and "medium" otherwise.
+func docCoverageLevel(pct int) string {
+	switch {
+	case pct < 50:
+		return "low"
+	case pct > 80:
+		return "high"
+	default:
+		return "medium"
+	}
+}
+
 // packageSubdir returns the subdirectory of the package relative to its module.
 func packageSubdir(pkgPath, modulePath string) string {
 	switch {
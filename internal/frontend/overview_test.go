@@ -14,11 +14,13 @@ import (
 	"golang.org/x/pkgsite/internal/postgres"
 	"golang.org/x/pkgsite/internal/source"
 	"golang.org/x/pkgsite/internal/stdlib"
+	"golang.org/x/pkgsite/internal/testing/fakedb"
 	"golang.org/x/pkgsite/internal/testing/sample"
 	"golang.org/x/pkgsite/internal/version"
 )
 
 func TestFetchOverviewDetails(t *testing.T) {
+	skipIfNoDB(t)
 	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
 	defer cancel()
 
@@ -335,3 +337,90 @@ func TestPackageSubdir(t *testing.T) {
 		}
 	}
 }
+
+func TestDocCoverageLevel(t *testing.T) {
+	for _, test := range []struct {
+		pct  int
+		want string
+	}{
+		{0, "low"},
+		{49, "low"},
+		{50, "medium"},
+		{80, "medium"},
+		{81, "high"},
+		{100, "high"},
+	} {
+		if got := docCoverageLevel(test.pct); got != test.want {
+			t.Errorf("docCoverageLevel(%d) = %q, want %q", test.pct, got, test.want)
+		}
+	}
+}
+
+// buildContextsDataSource is a DataSource that returns fixed build contexts.
+type buildContextsDataSource struct {
+	internal.DataSource
+	bcs []internal.BuildContext
+}
+
+func (ds *buildContextsDataSource) GetBuildContexts(ctx context.Context, pkgPath, version string) ([]internal.BuildContext, error) {
+	return ds.bcs, nil
+}
+
+func TestAddBuildContexts(t *testing.T) {
+	ctx := context.Background()
+
+	// Unknown build contexts are not displayed.
+	var od OverviewDetails
+	if err := addBuildContexts(ctx, &buildContextsDataSource{}, &od, "p", "v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if od.BuildContexts != nil {
+		t.Errorf("got %v for unknown build contexts, want nil", od.BuildContexts)
+	}
+
+	ds := &buildContextsDataSource{bcs: []internal.BuildContext{{GOOS: "linux", GOARCH: "amd64"}}}
+	if err := addBuildContexts(ctx, ds, &od, "p", "v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(od.BuildContexts), len(internal.BuildContexts); got != want {
+		t.Fatalf("got %d build contexts, want %d", got, want)
+	}
+	for _, a := range od.BuildContexts {
+		if want := a.Name == "linux/amd64"; a.Supported != want {
+			t.Errorf("%s: got Supported %t, want %t", a.Name, a.Supported, want)
+		}
+	}
+}
+
+func TestAddSymbolDetails(t *testing.T) {
+	ctx := context.Background()
+	m := sample.Module(sample.ModulePath, sample.VersionString, "pkg")
+	pkg := m.LegacyPackages[0]
+	pkg.Symbols = []*internal.Symbol{{Name: "A"}, {Name: "B"}}
+	ds := fakedb.New()
+	ds.AddModule(m)
+
+	pct := 50
+	for _, test := range []struct {
+		name      string
+		facts     internal.PackageFacts
+		wantPct   int
+		wantLevel string
+	}{
+		{"unknown", internal.PackageFacts{}, 0, ""},
+		{"known", internal.PackageFacts{DocCoverage: &pct}, 50, "medium"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var od OverviewDetails
+			if err := addSymbolDetails(ctx, ds, &od, pkg.Path, m.Version, test.facts); err != nil {
+				t.Fatal(err)
+			}
+			if od.ExportedSymbols != 2 {
+				t.Errorf("ExportedSymbols = %d, want 2", od.ExportedSymbols)
+			}
+			if od.DocCoverage != test.wantPct || od.DocCoverageLevel != test.wantLevel {
+				t.Errorf("got coverage (%d, %q), want (%d, %q)", od.DocCoverage, od.DocCoverageLevel, test.wantPct, test.wantLevel)
+			}
+		})
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"golang.org/x/pkgsite/internal"
 	"golang.org/x/pkgsite/internal/derrors"
 	"golang.org/x/pkgsite/internal/log"
+	"golang.org/x/pkgsite/internal/middleware"
 	"golang.org/x/pkgsite/internal/postgres"
 	"golang.org/x/pkgsite/internal/stdlib"
 )
@@ -36,7 +37,7 @@ func (s *Server) legacyServePackagePage(w http.ResponseWriter, r *http.Request,
 	//   3. If there is another version that contains this package path: serve a
 	//      404 and suggest these versions.
 	//   4. Just serve a 404
-	pkg, err := s.ds.LegacyGetPackage(ctx, pkgPath, modulePath, version)
+	pkg, err := s.cachedDS.LegacyGetPackage(ctx, pkgPath, modulePath, version)
 	if err == nil {
 		return s.legacyServePackagePageWithPackage(ctx, w, r, pkg, version)
 	}
@@ -65,7 +66,7 @@ func (s *Server) legacyServePackagePage(w http.ResponseWriter, r *http.Request,
 		// whatever response we resolve below might be inconsistent or misleading.
 		return fmt.Errorf("checking for directory: %v", err)
 	}
-	_, err = s.ds.LegacyGetPackage(ctx, pkgPath, modulePath, internal.LatestVersion)
+	_, err = s.cachedDS.LegacyGetPackage(ctx, pkgPath, modulePath, internal.LatestVersion)
 	if err == nil {
 		return pathFoundAtLatestError(ctx, "package", pkgPath, version)
 	}
@@ -88,10 +89,19 @@ func (s *Server) legacyServePackagePageWithPackage(ctx context.Context, w http.R
 			derrors.Wrap(&err, "legacyServePackagePageWithPackage(w, r, %q, %q, %q)", pkg.Path, pkg.ModulePath, requestedVersion)
 		}
 	}()
+	if middleware.FormatFromContext(ctx) == middleware.FormatJSON {
+		setCanonicalURL(w, canonicalURL(pkg.Path, pkg.ModulePath))
+		return s.writePackageJSON(ctx, w, pkg)
+	}
 	pkgHeader, err := legacyCreatePackage(&pkg.LegacyPackage, &pkg.ModuleInfo, requestedVersion == internal.LatestVersion)
 	if err != nil {
 		return fmt.Errorf("creating package header for %s@%s: %v", pkg.Path, pkg.Version, err)
 	}
+	setPackageFacts(pkgHeader, pkg.Facts)
+	pkgHeader.HasFuzzTests, err = packageHasFuzzTests(ctx, s.ds, pkg.Path, pkg.Version)
+	if err != nil {
+		return err
+	}
 
 	tab := r.FormValue("tab")
 	settings, ok := packageTabLookup[tab]
@@ -105,6 +115,21 @@ func (s *Server) legacyServePackagePageWithPackage(ctx context.Context, w http.R
 		http.Redirect(w, r, fmt.Sprintf(r.URL.Path+"?tab=%s", tab), http.StatusFound)
 		return nil
 	}
+	retracted, err := isRetracted(ctx, s.ds, pkg.ModulePath, pkg.Version)
+	if err != nil {
+		return err
+	}
+	vulns, err := s.ds.GetVulnerabilities(ctx, pkg.ModulePath, pkg.Version)
+	if err != nil {
+		return err
+	}
+	deprecation, err := packageDeprecation(ctx, s.ds, pkg.Path, pkg.Version, pkg.Facts)
+	if err != nil {
+		return err
+	}
+	if checkETag(w, r, pkg.ModulePath, pkg.Version, tab, retracted, vulns) {
+		return nil
+	}
 	canShowDetails := pkg.LegacyPackage.IsRedistributable || settings.AlwaysShowDetails
 
 	var details interface{}
@@ -126,7 +151,11 @@ func (s *Server) legacyServePackagePageWithPackage(ctx context.Context, w http.R
 		CanShowDetails: canShowDetails,
 		Tabs:           packageTabSettings,
 		PageType:       "pkg",
+		Vulns:          vulns,
+		IsRetracted:    retracted,
+		Deprecation:    deprecation,
 	}
+	setCanonicalURL(w, canonicalURL(pkg.Path, pkg.ModulePath))
 	s.servePage(ctx, w, settings.TemplateName, page)
 	return nil
 }
@@ -208,10 +237,23 @@ func (s *Server) stdlibPathForShortcut(ctx context.Context, shortcut string) (pa
 
 func (s *Server) servePackagePageWithVersionedDirectory(ctx context.Context,
 	w http.ResponseWriter, r *http.Request, vdir *internal.VersionedDirectory, requestedVersion string) error {
+	if middleware.FormatFromContext(ctx) == middleware.FormatJSON {
+		pkg, err := s.cachedDS.LegacyGetPackage(ctx, vdir.Path, vdir.ModulePath, vdir.Version)
+		if err != nil {
+			return err
+		}
+		setCanonicalURL(w, canonicalURL(vdir.Path, vdir.ModulePath))
+		return s.writePackageJSON(ctx, w, pkg)
+	}
 	pkgHeader, err := createPackageNew(vdir, requestedVersion == internal.LatestVersion)
 	if err != nil {
 		return fmt.Errorf("creating package header for %s@%s: %v", vdir.Path, vdir.Version, err)
 	}
+	setPackageFacts(pkgHeader, vdir.Package.Facts)
+	pkgHeader.HasFuzzTests, err = packageHasFuzzTests(ctx, s.ds, vdir.Path, vdir.Version)
+	if err != nil {
+		return err
+	}
 
 	tab := r.FormValue("tab")
 	settings, ok := packageTabLookup[tab]
@@ -225,6 +267,21 @@ func (s *Server) servePackagePageWithVersionedDirectory(ctx context.Context,
 		http.Redirect(w, r, fmt.Sprintf(r.URL.Path+"?tab=%s", tab), http.StatusFound)
 		return nil
 	}
+	retracted, err := isRetracted(ctx, s.ds, vdir.ModulePath, vdir.Version)
+	if err != nil {
+		return err
+	}
+	vulns, err := s.ds.GetVulnerabilities(ctx, vdir.ModulePath, vdir.Version)
+	if err != nil {
+		return err
+	}
+	deprecation, err := packageDeprecation(ctx, s.ds, vdir.Path, vdir.Version, vdir.Package.Facts)
+	if err != nil {
+		return err
+	}
+	if checkETag(w, r, vdir.ModulePath, vdir.Version, tab, retracted, vulns) {
+		return nil
+	}
 	canShowDetails := vdir.DirectoryNew.IsRedistributable || settings.AlwaysShowDetails
 
 	var details interface{}
@@ -246,7 +303,47 @@ func (s *Server) servePackagePageWithVersionedDirectory(ctx context.Context,
 		CanShowDetails: canShowDetails,
 		Tabs:           packageTabSettings,
 		PageType:       "pkg",
+		Vulns:          vulns,
+		IsRetracted:    retracted,
+		Deprecation:    deprecation,
 	}
+	setCanonicalURL(w, canonicalURL(vdir.Path, vdir.ModulePath))
 	s.servePage(ctx, w, settings.TemplateName, page)
 	return nil
 }
+
+// setPackageFacts sets the fields of the package header h that are read from
+// the stored facts about the package. Unknown facts are shown as unknown.
+func setPackageFacts(h *Package, facts internal.PackageFacts) {
+	if facts.HasTests != nil {
+		h.HasTests = *facts.HasTests
+		h.HasTestsKnown = true
+	}
+	h.RequiresCgo = facts.RequiresCgo != nil && *facts.RequiresCgo
+}
+
+// packageHasFuzzTests reports whether the package with path pkgPath at the
+// given version has a fuzz target. It reports false if that is not known.
+func packageHasFuzzTests(ctx context.Context, ds internal.DataSource, pkgPath, version string) (bool, error) {
+	hasFuzzTests, err := ds.GetHasFuzzTests(ctx, pkgPath, version)
+	if err != nil && !errors.Is(err, derrors.NotFound) {
+		return false, err
+	}
+	return hasFuzzTests, nil
+}
+
+// packageDeprecation returns the Deprecation of the package with path
+// pkgPath at the given version, whose stored facts are facts. It returns nil
+// if the package is not deprecated, or if that is not known.
+func packageDeprecation(ctx context.Context, ds internal.DataSource, pkgPath, version string, facts internal.PackageFacts) (_ *Deprecation, err error) {
+	defer derrors.Wrap(&err, "packageDeprecation(ctx, ds, %q, %q)", pkgPath, version)
+
+	if facts.IsDeprecated == nil || !*facts.IsDeprecated {
+		return nil, nil
+	}
+	successors, err := ds.GetSuccessorPackages(ctx, pkgPath, version)
+	if err != nil {
+		return nil, err
+	}
+	return &Deprecation{Message: facts.DeprecationMessage, Successors: successors}, nil
+}
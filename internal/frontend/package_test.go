@@ -8,13 +8,16 @@ import (
 	"context"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"golang.org/x/pkgsite/internal"
 	"golang.org/x/pkgsite/internal/postgres"
 	"golang.org/x/pkgsite/internal/stdlib"
+	"golang.org/x/pkgsite/internal/testing/fakedb"
 	"golang.org/x/pkgsite/internal/testing/sample"
 )
 
 func TestStdlibPathForShortcut(t *testing.T) {
+	skipIfNoDB(t)
 	defer postgres.ResetTestDB(testDB, t)
 
 	m := sample.Module(stdlib.ModulePath, "v1.2.3",
@@ -44,3 +47,60 @@ func TestStdlibPathForShortcut(t *testing.T) {
 		}
 	}
 }
+
+func TestSetPackageFacts(t *testing.T) {
+	yes, no := true, false
+	for _, test := range []struct {
+		name  string
+		facts internal.PackageFacts
+		want  Package
+	}{
+		{"unknown", internal.PackageFacts{}, Package{}},
+		{"tests", internal.PackageFacts{HasTests: &yes}, Package{HasTests: true, HasTestsKnown: true}},
+		{"no tests", internal.PackageFacts{HasTests: &no}, Package{HasTestsKnown: true}},
+		{"cgo", internal.PackageFacts{RequiresCgo: &yes}, Package{RequiresCgo: true}},
+		{"no cgo", internal.PackageFacts{RequiresCgo: &no}, Package{}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var got Package
+			setPackageFacts(&got, test.facts)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPackageDeprecation(t *testing.T) {
+	ctx := context.Background()
+	m := sample.Module(sample.ModulePath, sample.VersionString, "p")
+	pkg := m.LegacyPackages[0]
+	pkg.SuccessorPackages = []string{"example.com/q"}
+	ds := fakedb.New()
+	ds.AddModule(m)
+
+	yes, no := true, false
+	for _, test := range []struct {
+		name  string
+		facts internal.PackageFacts
+		want  *Deprecation
+	}{
+		{"unknown", internal.PackageFacts{}, nil},
+		{"not deprecated", internal.PackageFacts{IsDeprecated: &no}, nil},
+		{
+			"deprecated",
+			internal.PackageFacts{IsDeprecated: &yes, DeprecationMessage: "use example.com/q."},
+			&Deprecation{Message: "use example.com/q.", Successors: []string{"example.com/q"}},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := packageDeprecation(ctx, ds, pkg.Path, sample.VersionString, test.facts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -29,13 +29,30 @@ type pagination struct {
 	NextPage    int      //   "    "   "  next page, usually Page+1, but zero on the last page
 	Offset      int      // offset of the first item on the current page
 	Pages       []int    // consecutive page numbers to be displayed for navigation
+	nextCursor  string   // cursor for the results after this page, if supported
 }
 
 // PageURL constructs a URL that displays the given page.
-// It adds a "page" query parameter to the base URL.
+// It adds a "page" query parameter to the base URL, and removes any "after"
+// cursor, since a cursor is only valid for the page that follows it.
 func (p pagination) PageURL(page int) string {
 	newQuery := p.baseURL.Query()
 	newQuery.Set("page", strconv.Itoa(page))
+	newQuery.Del("after")
+	p.baseURL.RawQuery = newQuery.Encode()
+	return p.baseURL.String()
+}
+
+// NextPageURL constructs a URL that displays the next page. If a cursor for
+// the next page is known, it is added as the "after" query parameter, so that
+// the next page can be computed without skipping over the earlier results.
+func (p pagination) NextPageURL() string {
+	if p.nextCursor == "" {
+		return p.PageURL(p.NextPage)
+	}
+	newQuery := p.baseURL.Query()
+	newQuery.Set("page", strconv.Itoa(p.NextPage))
+	newQuery.Set("after", p.nextCursor)
 	p.baseURL.RawQuery = newQuery.Encode()
 	return p.baseURL.String()
 }
@@ -61,8 +78,9 @@ func newPagination(params paginationParams, resultCount, totalCount int) paginat
 // paginationParams holds pagination parameters extracted from the request.
 type paginationParams struct {
 	baseURL *url.URL
-	page    int // the number of the page to display
-	limit   int // the maximum number of results to display on the page
+	page    int    // the number of the page to display
+	limit   int    // the maximum number of results to display on the page
+	after   string // if non-empty, a cursor for the last result of the previous page
 }
 
 // offset returns the offset of the first result on the page.
@@ -89,6 +107,7 @@ func newPaginationParams(r *http.Request, defaultLimit int) paginationParams {
 		baseURL: r.URL,
 		page:    positiveParam("page", 1),
 		limit:   positiveParam("limit", defaultLimit),
+		after:   r.FormValue("after"),
 	}
 }
 
@@ -5,6 +5,7 @@
 package frontend
 
 import (
+	"net/url"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -197,3 +198,21 @@ func TestPagesToDisplay(t *testing.T) {
 		})
 	}
 }
+
+func TestPageURLs(t *testing.T) {
+	baseURL, err := url.Parse("/search?q=foo&page=2&after=abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := newPagination(paginationParams{baseURL: baseURL, page: 2, limit: 10}, 10, 47)
+	if got, want := p.PageURL(1), "/search?page=1&q=foo"; got != want {
+		t.Errorf("PageURL(1) = %q; want = %q", got, want)
+	}
+	if got, want := p.NextPageURL(), "/search?page=3&q=foo"; got != want {
+		t.Errorf("NextPageURL() without cursor = %q; want = %q", got, want)
+	}
+	p.nextCursor = "xyz"
+	if got, want := p.NextPageURL(), "/search?after=xyz&page=3&q=foo"; got != want {
+		t.Errorf("NextPageURL() with cursor = %q; want = %q", got, want)
+	}
+}
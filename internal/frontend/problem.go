@@ -0,0 +1,113 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"golang.org/x/pkgsite/internal/middleware"
+)
+
+// problemContentType is the content type of a ProblemDetail.
+const problemContentType = "application/problem+json"
+
+// A ProblemDetail describes an error in the response to a request of the
+// JSON API, in the format of RFC 7807.
+type ProblemDetail struct {
+	// Type is a URI that identifies the kind of problem. It is
+	// "about:blank" when the problem is described by the status alone.
+	Type string `json:"type"`
+	// Title is a short summary of the kind of problem.
+	Title string `json:"title"`
+	// Status is the HTTP status code of the response.
+	Status int `json:"status"`
+	// Detail explains this occurrence of the problem.
+	Detail string `json:"detail,omitempty"`
+	// Instance is the path of the request that failed.
+	Instance string `json:"instance,omitempty"`
+}
+
+// newProblemDetail returns the ProblemDetail for a failed request r with the
+// given status.
+func newProblemDetail(r *http.Request, status int, detail string) ProblemDetail {
+	return ProblemDetail{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+	}
+}
+
+// WriteJSONError writes pd to w, with the status of pd.
+func WriteJSONError(w http.ResponseWriter, pd ProblemDetail) {
+	// A ProblemDetail consists of strings and an int, so it can always be
+	// marshaled.
+	body, _ := json.Marshal(pd)
+	w.Header().Set("Content-Type", problemContentType)
+	w.Header().Del("Content-Length")
+	w.WriteHeader(pd.Status)
+	w.Write(body)
+}
+
+// isAPIRequest reports whether r is a request of the JSON API, or accepts
+// JSON. Errors in response to such requests are served as ProblemDetails
+// instead of HTML pages.
+func isAPIRequest(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, "/api/") ||
+		strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+// APIErrors returns a middleware that replaces the error responses to API
+// requests that are not JSON, like the HTML error pages and plain-text
+// errors written by other middleware, with ProblemDetails.
+func APIErrors() middleware.Middleware {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !isAPIRequest(r) {
+				h.ServeHTTP(w, r)
+				return
+			}
+			h.ServeHTTP(&problemWriter{ResponseWriter: w, r: r}, r)
+		})
+	}
+}
+
+// problemWriter is an http.ResponseWriter that writes a ProblemDetail instead
+// of an error response whose content type is not JSON.
+type problemWriter struct {
+	http.ResponseWriter
+	r           *http.Request
+	wroteHeader bool
+	// replaced is set when the response was replaced, so that the rest of
+	// the original body is discarded.
+	replaced bool
+}
+
+func (w *problemWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	ct := w.Header().Get("Content-Type")
+	if status < http.StatusBadRequest || strings.Contains(ct, "json") {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.replaced = true
+	WriteJSONError(w.ResponseWriter, newProblemDetail(w.r, status, ""))
+}
+
+func (w *problemWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.replaced {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,138 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestServeErrorJSON(t *testing.T) {
+	s := &Server{}
+	for _, test := range []struct {
+		name, path, accept string
+		err                error
+		want               ProblemDetail
+	}{
+		{
+			name: "bad request",
+			path: "/api/v1/packages/bad",
+			err: &serverError{
+				status: http.StatusBadRequest,
+				epage:  &errorPage{Message: "Malformed path."},
+			},
+			want: ProblemDetail{
+				Type:     "about:blank",
+				Title:    "Bad Request",
+				Status:   http.StatusBadRequest,
+				Detail:   "Malformed path.",
+				Instance: "/api/v1/packages/bad",
+			},
+		},
+		{
+			name: "not found",
+			path: "/api/v1/packages/example.com/missing",
+			err:  &serverError{status: http.StatusNotFound},
+			want: ProblemDetail{
+				Type:     "about:blank",
+				Title:    "Not Found",
+				Status:   http.StatusNotFound,
+				Instance: "/api/v1/packages/example.com/missing",
+			},
+		},
+		{
+			name:   "internal error, accepts JSON",
+			path:   "/example.com/p",
+			accept: "application/json",
+			err:    errors.New("bad"),
+			want: ProblemDetail{
+				Type:     "about:blank",
+				Title:    "Internal Server Error",
+				Status:   http.StatusInternalServerError,
+				Instance: "/example.com/p",
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			h := s.errorHandler(func(http.ResponseWriter, *http.Request) error { return test.err })
+			r := httptest.NewRequest("GET", test.path, nil)
+			if test.accept != "" {
+				r.Header.Set("Accept", test.accept)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			checkProblem(t, w, test.want)
+		})
+	}
+}
+
+func TestAPIErrors(t *testing.T) {
+	handler := APIErrors()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("result") {
+		case "text":
+			http.Error(w, "too many", http.StatusTooManyRequests)
+		case "html":
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("<html>error</html>"))
+		case "json":
+			WriteJSONError(w, ProblemDetail{Type: "about:blank", Status: http.StatusNotFound})
+		default:
+			w.Write([]byte("<html>ok</html>"))
+		}
+	}))
+	serve := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		return w
+	}
+
+	// Errors that aren't JSON are replaced.
+	checkProblem(t, serve("/api/v1/x?result=text"), ProblemDetail{
+		Type:     "about:blank",
+		Title:    "Too Many Requests",
+		Status:   http.StatusTooManyRequests,
+		Instance: "/api/v1/x",
+	})
+	checkProblem(t, serve("/api/v1/x?result=html"), ProblemDetail{
+		Type:     "about:blank",
+		Title:    "Internal Server Error",
+		Status:   http.StatusInternalServerError,
+		Instance: "/api/v1/x",
+	})
+	// JSON errors are not.
+	checkProblem(t, serve("/api/v1/x?result=json"), ProblemDetail{Type: "about:blank", Status: http.StatusNotFound})
+
+	// Successful responses, and responses to requests that are not API
+	// requests, are unchanged.
+	for _, url := range []string{"/api/v1/x", "/x?result=html"} {
+		w := serve(url)
+		if got, want := w.Body.String(), "<html>"; len(got) < len(want) || got[:len(want)] != want {
+			t.Errorf("%s: got body %q, want HTML", url, got)
+		}
+	}
+}
+
+// checkProblem checks that w holds the response for want.
+func checkProblem(t *testing.T, w *httptest.ResponseRecorder, want ProblemDetail) {
+	t.Helper()
+	if w.Code != want.Status {
+		t.Errorf("got status %d, want %d", w.Code, want.Status)
+	}
+	if got := w.Header().Get("Content-Type"); got != problemContentType {
+		t.Errorf("got Content-Type %q, want %q", got, problemContentType)
+	}
+	var got ProblemDetail
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("%v; body: %s", err, w.Body)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,247 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/log"
+	"golang.org/x/pkgsite/internal/middleware"
+)
+
+// proxyPathPrefix is the path prefix of the GOPROXY protocol endpoints.
+const proxyPathPrefix = "/proxy/"
+
+const (
+	// proxyInfoMaxAge is the cache lifetime of a successful .info response
+	// for a specific version, which never changes.
+	proxyInfoMaxAge = 24 * time.Hour
+	// proxyListMaxAge is the cache lifetime of successful list and @latest
+	// responses, which change as new versions are indexed.
+	proxyListMaxAge = 5 * time.Minute
+)
+
+// proxyHandler serves a read-only subset of the GOPROXY protocol
+// (https://golang.org/ref/mod#goproxy-protocol) from the modules in a
+// DataSource:
+//
+//   /proxy/<module>/@v/list
+//   /proxy/<module>/@v/<version>.info
+//   /proxy/<module>/@latest
+//
+// Module paths and versions in the URL are case-encoded as in the protocol.
+type proxyHandler struct {
+	ds internal.DataSource
+}
+
+// proxyInfo is the JSON response of the .info and @latest endpoints.
+type proxyInfo struct {
+	Version string
+	Time    time.Time
+}
+
+// proxyRequest is a parsed request to a proxy endpoint.
+type proxyRequest struct {
+	modulePath string
+	// version is the requested version for .info requests, and empty
+	// otherwise.
+	version string
+	// latest reports whether this is an @latest request. If both latest is
+	// false and version is empty, this is a list request.
+	latest bool
+}
+
+// parseProxyPath parses the path of a request to a proxy endpoint. It returns
+// a derrors.NotFound error if the path doesn't name an endpoint, and a
+// derrors.InvalidArgument error if the module path or version is invalid.
+func parseProxyPath(urlPath string) (_ *proxyRequest, err error) {
+	defer derrors.Wrap(&err, "parseProxyPath(%q)", urlPath)
+
+	p := strings.TrimPrefix(urlPath, proxyPathPrefix)
+	var (
+		escapedPath, escapedVersion string
+		pr                          = &proxyRequest{}
+	)
+	switch {
+	case strings.HasSuffix(p, "/@latest"):
+		escapedPath = strings.TrimSuffix(p, "/@latest")
+		pr.latest = true
+	case strings.HasSuffix(p, "/@v/list"):
+		escapedPath = strings.TrimSuffix(p, "/@v/list")
+	case strings.HasSuffix(p, ".info") && strings.Contains(p, "/@v/"):
+		i := strings.LastIndex(p, "/@v/")
+		escapedPath = p[:i]
+		escapedVersion = strings.TrimSuffix(p[i+len("/@v/"):], ".info")
+		if escapedVersion == "" {
+			return nil, derrors.NotFound
+		}
+	default:
+		return nil, derrors.NotFound
+	}
+	if pr.modulePath, err = module.UnescapePath(escapedPath); err != nil {
+		return nil, fmt.Errorf("%v: %w", err, derrors.InvalidArgument)
+	}
+	if escapedVersion == "" {
+		return pr, nil
+	}
+	if pr.version, err = module.UnescapeVersion(escapedVersion); err != nil {
+		return nil, fmt.Errorf("%v: %w", err, derrors.InvalidArgument)
+	}
+	if !semver.IsValid(pr.version) {
+		return nil, fmt.Errorf("%q is not a valid semantic version: %w", pr.version, derrors.InvalidArgument)
+	}
+	return pr, nil
+}
+
+// proxyMiddleware returns a middleware for the proxy endpoints. It responds
+// with 400 to requests with an invalid module path or version, and with 404
+// to requests for unknown endpoints or excluded modules. Otherwise it calls
+// the wrapped handler, and sets a Cache-Control header on successful
+// responses.
+func proxyMiddleware(ds internal.DataSource) middleware.Middleware {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			pr, err := parseProxyPath(r.URL.Path)
+			if err != nil {
+				writeProxyError(ctx, w, err)
+				return
+			}
+			excluded, err := isExcluded(ctx, ds, pr.modulePath)
+			if err != nil {
+				writeProxyError(ctx, w, err)
+				return
+			}
+			if excluded {
+				// Don't let the user know that the module was excluded.
+				writeProxyError(ctx, w, derrors.NotFound)
+				return
+			}
+			maxAge := proxyListMaxAge
+			if pr.version != "" {
+				maxAge = proxyInfoMaxAge
+			}
+			h.ServeHTTP(&proxyResponseWriter{ResponseWriter: w, maxAge: maxAge}, r)
+		})
+	}
+}
+
+// proxyResponseWriter is an http.ResponseWriter that sets a Cache-Control
+// header if the response status is 200.
+type proxyResponseWriter struct {
+	http.ResponseWriter
+	maxAge      time.Duration
+	wroteHeader bool
+}
+
+func (w *proxyResponseWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if statusCode == http.StatusOK {
+			w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(w.maxAge.Seconds())))
+		}
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *proxyResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// isExcluded reports whether path is excluded from ds. Data sources that
+// don't support exclusion exclude nothing.
+func isExcluded(ctx context.Context, ds internal.DataSource, path string) (bool, error) {
+	db, ok := ds.(interface {
+		IsExcluded(ctx context.Context, path string) (bool, error)
+	})
+	if !ok {
+		return false, nil
+	}
+	return db.IsExcluded(ctx, path)
+}
+
+func (h *proxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	pr, err := parseProxyPath(r.URL.Path)
+	if err != nil {
+		writeProxyError(ctx, w, err)
+		return
+	}
+	if pr.version == "" && !pr.latest {
+		versions, err := h.listVersions(ctx, pr.modulePath)
+		if err != nil {
+			writeProxyError(ctx, w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		var b strings.Builder
+		for _, v := range versions {
+			fmt.Fprintln(&b, v)
+		}
+		if _, err := w.Write([]byte(b.String())); err != nil {
+			log.Errorf(ctx, "proxyHandler, writing: %v", err)
+		}
+		return
+	}
+	v := pr.version
+	if pr.latest {
+		v = internal.LatestVersion
+	}
+	mi, err := h.ds.LegacyGetModuleInfo(ctx, pr.modulePath, v)
+	if err != nil {
+		writeProxyError(ctx, w, err)
+		return
+	}
+	writeJSON(ctx, w, http.StatusOK, &proxyInfo{Version: mi.Version, Time: mi.CommitTime})
+}
+
+// listVersions returns the tagged versions of the module in ascending semver
+// order. Like the GOPROXY list endpoint, it omits pseudo-versions.
+func (h *proxyHandler) listVersions(ctx context.Context, modulePath string) (_ []string, err error) {
+	defer derrors.Wrap(&err, "listVersions(ctx, %q)", modulePath)
+
+	mis, err := h.ds.GetTaggedVersionsForModule(ctx, modulePath)
+	if err != nil {
+		return nil, err
+	}
+	var versions []string
+	for _, mi := range mis {
+		// GetTaggedVersionsForModule returns the versions of all the modules
+		// in the series.
+		if mi.ModulePath == modulePath {
+			versions = append(versions, mi.Version)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return semver.Compare(versions[i], versions[j]) < 0 })
+	return versions, nil
+}
+
+// writeProxyError writes the plain-text response for err, which is 404 for
+// derrors.NotFound, 400 for derrors.InvalidArgument and 500 otherwise.
+func writeProxyError(ctx context.Context, w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, derrors.NotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, derrors.InvalidArgument):
+		code = http.StatusBadRequest
+	default:
+		log.Errorf(ctx, "proxy endpoint: %v", err)
+	}
+	http.Error(w, http.StatusText(code), code)
+}
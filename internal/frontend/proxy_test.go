@@ -0,0 +1,178 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/version"
+)
+
+// proxyDataSource is a DataSource with the given module versions, in
+// descending order within each module, and excluded module paths.
+type proxyDataSource struct {
+	internal.DataSource
+	versions map[string][]*internal.ModuleInfo
+	excluded map[string]bool
+}
+
+func (ds proxyDataSource) IsExcluded(ctx context.Context, path string) (bool, error) {
+	return ds.excluded[path], nil
+}
+
+func (ds proxyDataSource) GetTaggedVersionsForModule(ctx context.Context, modulePath string) ([]*internal.ModuleInfo, error) {
+	var mis []*internal.ModuleInfo
+	for _, mi := range ds.versions[internal.SeriesPathForModule(modulePath)] {
+		if mi.VersionType != version.TypePseudo {
+			mis = append(mis, mi)
+		}
+	}
+	return mis, nil
+}
+
+func (ds proxyDataSource) LegacyGetModuleInfo(ctx context.Context, modulePath, version string) (*internal.LegacyModuleInfo, error) {
+	for _, mi := range ds.versions[internal.SeriesPathForModule(modulePath)] {
+		if mi.ModulePath == modulePath && (version == internal.LatestVersion || mi.Version == version) {
+			return &internal.LegacyModuleInfo{ModuleInfo: *mi}, nil
+		}
+	}
+	return nil, derrors.NotFound
+}
+
+func TestProxyHandler(t *testing.T) {
+	commitTime := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	mi := func(modulePath, v string, vt version.Type) *internal.ModuleInfo {
+		return &internal.ModuleInfo{ModulePath: modulePath, Version: v, VersionType: vt, CommitTime: commitTime}
+	}
+	ds := proxyDataSource{
+		versions: map[string][]*internal.ModuleInfo{
+			"github.com/Foo/bar": {
+				mi("github.com/Foo/bar/v2", "v2.0.0", version.TypeRelease),
+				mi("github.com/Foo/bar", "v1.10.0", version.TypeRelease),
+				mi("github.com/Foo/bar", "v1.2.0", version.TypeRelease),
+				mi("github.com/Foo/bar", "v1.2.0-pre", version.TypePrerelease),
+			},
+			"example.com/pseudo": {
+				mi("example.com/pseudo", "v0.0.0-20200601120000-0123456789ab", version.TypePseudo),
+			},
+			"example.com/excluded": {
+				mi("example.com/excluded", "v1.0.0", version.TypeRelease),
+			},
+		},
+		excluded: map[string]bool{"example.com/excluded": true},
+	}
+	handler := proxyMiddleware(ds)(&proxyHandler{ds: ds})
+	const (
+		infoCache = "public, max-age=86400"
+		listCache = "public, max-age=300"
+	)
+	for _, test := range []struct {
+		name, path       string
+		wantStatus       int
+		wantContentType  string
+		wantBody         string
+		wantCacheControl string
+	}{
+		{
+			name:             "list",
+			path:             "/proxy/github.com/!foo/bar/@v/list",
+			wantStatus:       http.StatusOK,
+			wantContentType:  "text/plain; charset=utf-8",
+			wantBody:         "v1.2.0-pre\nv1.2.0\nv1.10.0\n",
+			wantCacheControl: listCache,
+		},
+		{
+			name:             "list of pseudo-versions only",
+			path:             "/proxy/example.com/pseudo/@v/list",
+			wantStatus:       http.StatusOK,
+			wantContentType:  "text/plain; charset=utf-8",
+			wantBody:         "",
+			wantCacheControl: listCache,
+		},
+		{
+			name:             "info",
+			path:             "/proxy/github.com/!foo/bar/@v/v1.2.0.info",
+			wantStatus:       http.StatusOK,
+			wantContentType:  "application/json",
+			wantBody:         `{"Version":"v1.2.0","Time":"2020-06-01T12:00:00Z"}`,
+			wantCacheControl: infoCache,
+		},
+		{
+			name:             "info pseudo-version",
+			path:             "/proxy/example.com/pseudo/@v/v0.0.0-20200601120000-0123456789ab.info",
+			wantStatus:       http.StatusOK,
+			wantContentType:  "application/json",
+			wantBody:         `{"Version":"v0.0.0-20200601120000-0123456789ab","Time":"2020-06-01T12:00:00Z"}`,
+			wantCacheControl: infoCache,
+		},
+		{
+			name:       "info unknown version",
+			path:       "/proxy/github.com/!foo/bar/@v/v1.3.0.info",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "info invalid version",
+			path:       "/proxy/github.com/!foo/bar/@v/master.info",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:             "latest",
+			path:             "/proxy/github.com/!foo/bar/@latest",
+			wantStatus:       http.StatusOK,
+			wantContentType:  "application/json",
+			wantBody:         `{"Version":"v1.10.0","Time":"2020-06-01T12:00:00Z"}`,
+			wantCacheControl: listCache,
+		},
+		{
+			name:       "latest unknown module",
+			path:       "/proxy/example.com/unknown/@latest",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "unescaped upper-case module path",
+			path:       "/proxy/github.com/Foo/bar/@latest",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "excluded module",
+			path:       "/proxy/example.com/excluded/@v/v1.0.0.info",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "unknown endpoint",
+			path:       "/proxy/github.com/!foo/bar/@v/v1.2.0.zip",
+			wantStatus: http.StatusNotFound,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+			if w.Code != test.wantStatus {
+				t.Fatalf("GET %q = %d, want %d", test.path, w.Code, test.wantStatus)
+			}
+			if test.wantStatus != http.StatusOK {
+				if got := w.Header().Get("Cache-Control"); got != "" {
+					t.Errorf("GET %q: Cache-Control = %q, want none", test.path, got)
+				}
+				return
+			}
+			if got := w.Header().Get("Content-Type"); got != test.wantContentType {
+				t.Errorf("GET %q: Content-Type = %q, want %q", test.path, got, test.wantContentType)
+			}
+			if got := w.Header().Get("Cache-Control"); got != test.wantCacheControl {
+				t.Errorf("GET %q: Cache-Control = %q, want %q", test.path, got, test.wantCacheControl)
+			}
+			if got := w.Body.String(); got != test.wantBody {
+				t.Errorf("GET %q: body = %q, want %q", test.path, got, test.wantBody)
+			}
+		})
+	}
+}
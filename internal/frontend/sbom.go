@@ -0,0 +1,123 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/log"
+	"golang.org/x/pkgsite/internal/sbom"
+)
+
+const (
+	// sbomPathPrefix is the path prefix of the SBOM endpoint.
+	sbomPathPrefix = "/sbom/"
+
+	// maxSBOMDepth is the number of levels of dependencies in an SBOM, and
+	// maxSBOMComponents is the maximum number of dependencies in it.
+	maxSBOMDepth      = 5
+	maxSBOMComponents = 500
+)
+
+// handleSBOM handles requests for
+// /sbom/<module-path>@<version>[?format=cyclonedx] by serving a software bill
+// of materials for the module version in the CycloneDX JSON format. It lists
+// the dependencies of the module version, and their dependencies, to a depth
+// of maxSBOMDepth, and at most maxSBOMComponents of them.
+func (s *Server) handleSBOM(w http.ResponseWriter, r *http.Request) (err error) {
+	urlPath := strings.TrimPrefix(r.URL.Path, sbomPathPrefix)
+	defer derrors.Wrap(&err, "handleSBOM(w, r[%q])", urlPath)
+
+	modulePath, version, subpath, err := parseFilesPath(urlPath)
+	if err == nil && subpath != "" {
+		err = fmt.Errorf("unexpected path after version: %w", derrors.InvalidArgument)
+	}
+	if err != nil {
+		return &serverError{
+			status: http.StatusBadRequest,
+			err:    err,
+			epage:  &errorPage{Message: "The path must have the form /sbom/<module-path>@<version>."},
+		}
+	}
+	if format := r.FormValue("format"); format != "" && format != "cyclonedx" {
+		return &serverError{
+			status: http.StatusBadRequest,
+			err:    fmt.Errorf("unsupported format %q: %w", format, derrors.InvalidArgument),
+			epage:  &errorPage{Message: fmt.Sprintf("Unsupported format %q.", format)},
+		}
+	}
+	ctx := r.Context()
+	if _, err := s.ds.LegacyGetModuleInfo(ctx, modulePath, version); err != nil {
+		if errors.Is(err, derrors.NotFound) {
+			return &serverError{status: http.StatusNotFound, err: err}
+		}
+		return err
+	}
+	doc, err := moduleSBOM(ctx, s.ds, modulePath, version)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", sbom.ContentType)
+	if _, err := w.Write(data); err != nil {
+		log.Errorf(ctx, "handleSBOM: %v", err)
+	}
+	return nil
+}
+
+// moduleSBOM returns the CycloneDX document for the module version, with a
+// component for each module it depends on, to a depth of maxSBOMDepth. If
+// there are more than maxSBOMComponents such modules, the farthest are
+// dropped. Components are sorted by module path and version.
+func moduleSBOM(ctx context.Context, ds internal.DataSource, modulePath, version string) (_ *sbom.CycloneDXDocument, err error) {
+	defer derrors.Wrap(&err, "moduleSBOM(ctx, ds, %q, %q)", modulePath, version)
+
+	newComponent := func(modulePath, version string) (sbom.Component, error) {
+		lics, err := ds.LegacyGetModuleLicenses(ctx, modulePath, version)
+		if err != nil && !errors.Is(err, derrors.NotFound) {
+			return sbom.Component{}, err
+		}
+		var types []string
+		for _, l := range lics {
+			types = append(types, l.Types...)
+		}
+		return sbom.NewComponent(modulePath, version, types), nil
+	}
+
+	root, err := newComponent(modulePath, version)
+	if err != nil {
+		return nil, err
+	}
+	deps, err := nearestDependencies(ctx, ds, modulePath, version, maxSBOMDepth, maxSBOMComponents)
+	if err != nil {
+		return nil, err
+	}
+	var components []sbom.Component
+	for _, d := range deps {
+		c, err := newComponent(d.ModulePath, d.Version)
+		if err != nil {
+			return nil, err
+		}
+		components = append(components, c)
+	}
+	sort.Slice(components, func(i, j int) bool {
+		if components[i].Name != components[j].Name {
+			return components[i].Name < components[j].Name
+		}
+		return components[i].Version < components[j].Version
+	})
+	return sbom.New(root, components), nil
+}
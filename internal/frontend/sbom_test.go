@@ -0,0 +1,158 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/licenses"
+	"golang.org/x/pkgsite/internal/sbom"
+)
+
+// sbomDataSource is a graphDataSource with the license types of some module
+// versions.
+type sbomDataSource struct {
+	graphDataSource
+	licenses map[string][]string // by module@version
+}
+
+func (ds *sbomDataSource) LegacyGetModuleLicenses(ctx context.Context, modulePath, version string) ([]*licenses.License, error) {
+	types := ds.licenses[modulePath+"@"+version]
+	if types == nil {
+		return nil, nil
+	}
+	return []*licenses.License{{Metadata: &licenses.Metadata{Types: types, FilePath: "LICENSE"}}}, nil
+}
+
+func TestHandleSBOM(t *testing.T) {
+	dep := func(path, version string, depth int, importedBy ...string) *internal.ModuleDependency {
+		return &internal.ModuleDependency{ModulePath: path, Version: version, IsIndirect: depth > 1, Depth: depth, ImportedBy: importedBy}
+	}
+	ds := &sbomDataSource{
+		graphDataSource: graphDataSource{
+			deps: map[string][]*internal.ModuleDependency{
+				"example.com/a@v1.0.0": {
+					dep("example.com/b", "v1.1.0", 1, "example.com/a@v1.0.0"),
+					dep("example.com/c", "v0.2.0+incompatible", 1, "example.com/a@v1.0.0", "example.com/b@v1.1.0"),
+					dep("example.com/d", "v1.0.0", 2, "example.com/b@v1.1.0"),
+					// Beyond the maximum depth.
+					dep("example.com/e", "v1.0.0", maxSBOMDepth+1, "example.com/d@v1.0.0"),
+				},
+			},
+		},
+		licenses: map[string][]string{
+			"example.com/a@v1.0.0": {"MIT"},
+			"example.com/b@v1.1.0": {"Apache-2.0", "BSD-3-Clause"},
+		},
+	}
+	s, err := NewServer(ServerConfig{DataSource: ds, StaticPath: "../../content/static"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	s.Install(mux.Handle, nil)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/sbom/example.com/a@v1.0.0?format=cyclonedx", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusOK)
+	}
+	if got := w.Header().Get("Content-Type"); got != sbom.ContentType {
+		t.Errorf("got Content-Type %q, want %q", got, sbom.ContentType)
+	}
+	var got sbom.CycloneDXDocument
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := sbom.CycloneDXDocument{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.4",
+		Version:     1,
+		Metadata: &sbom.Metadata{Component: &sbom.Component{
+			Type:     "library",
+			BOMRef:   "pkg:golang/example.com/a@v1.0.0",
+			Name:     "example.com/a",
+			Version:  "v1.0.0",
+			PURL:     "pkg:golang/example.com/a@v1.0.0",
+			Licenses: []sbom.LicenseChoice{{License: sbom.License{ID: "MIT"}}},
+		}},
+		Components: []sbom.Component{
+			{
+				Type:    "library",
+				BOMRef:  "pkg:golang/example.com/b@v1.1.0",
+				Name:    "example.com/b",
+				Version: "v1.1.0",
+				PURL:    "pkg:golang/example.com/b@v1.1.0",
+				Licenses: []sbom.LicenseChoice{
+					{License: sbom.License{ID: "Apache-2.0"}},
+					{License: sbom.License{ID: "BSD-3-Clause"}},
+				},
+			},
+			{
+				Type:    "library",
+				BOMRef:  "pkg:golang/example.com/c@v0.2.0%2Bincompatible",
+				Name:    "example.com/c",
+				Version: "v0.2.0+incompatible",
+				PURL:    "pkg:golang/example.com/c@v0.2.0%2Bincompatible",
+			},
+			{
+				Type:    "library",
+				BOMRef:  "pkg:golang/example.com/d@v1.0.0",
+				Name:    "example.com/d",
+				Version: "v1.0.0",
+				PURL:    "pkg:golang/example.com/d@v1.0.0",
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, test := range []struct {
+		path       string
+		wantStatus int
+	}{
+		{"/sbom/example.com/x@v1.0.0", http.StatusNotFound},
+		{"/sbom/example.com/a", http.StatusBadRequest},
+		{"/sbom/example.com/a@v1.0.0/p", http.StatusBadRequest},
+		{"/sbom/example.com/a@v1.0.0?format=spdx", http.StatusBadRequest},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+		if w.Code != test.wantStatus {
+			t.Errorf("GET %s: got status %d, want %d", test.path, w.Code, test.wantStatus)
+		}
+	}
+}
+
+func TestModuleSBOMMaxComponents(t *testing.T) {
+	var deps []*internal.ModuleDependency
+	for i := 0; i < 2*maxSBOMComponents; i++ {
+		deps = append(deps, &internal.ModuleDependency{
+			ModulePath: fmt.Sprintf("example.com/m%d", i),
+			Version:    "v1.0.0",
+			Depth:      1,
+			ImportedBy: []string{"example.com/a@v1.0.0"},
+		})
+	}
+	ds := &sbomDataSource{graphDataSource: graphDataSource{deps: map[string][]*internal.ModuleDependency{"example.com/a@v1.0.0": deps}}}
+	doc, err := moduleSBOM(context.Background(), ds, "example.com/a", "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Components) != maxSBOMComponents {
+		t.Errorf("got %d components, want %d", len(doc.Components), maxSBOMComponents)
+	}
+	if ds.depCalls != 1 {
+		t.Errorf("got %d calls to GetModuleDependencies, want 1", ds.depCalls)
+	}
+}
@@ -8,15 +8,21 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"html/template"
 	"math"
 	"net/http"
+	"net/url"
 	"path"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"golang.org/x/pkgsite/internal"
 	"golang.org/x/pkgsite/internal/derrors"
 	"golang.org/x/pkgsite/internal/log"
 	"golang.org/x/pkgsite/internal/postgres"
+	"golang.org/x/pkgsite/internal/search"
 )
 
 const defaultSearchLimit = 10
@@ -27,6 +33,21 @@ type SearchPage struct {
 	basePage
 	Pagination pagination
 	Results    []*SearchResult
+	// AlternativeQuery is a query that is suggested to the user when there
+	// are no results. It is empty if there are results, or no suggestion.
+	AlternativeQuery string
+	// LicenseFacets are the license types of the matching packages, in
+	// decreasing order of count. They are only populated on request.
+	LicenseFacets []*LicenseFacet
+}
+
+// LicenseFacet is the number of packages matching a search that have a
+// license type.
+type LicenseFacet struct {
+	License string
+	Count   uint64
+	// URL is the URL of the search restricted to packages with License.
+	URL string
 }
 
 // SearchResult contains data needed to display a single search result.
@@ -40,12 +61,20 @@ type SearchResult struct {
 	CommitTime     string
 	NumImportedBy  uint64
 	Approximate    bool
+	// SymbolName and SymbolKind identify the matching symbol of a symbol
+	// search. They are empty for a package search.
+	SymbolName string
+	SymbolKind internal.SymbolKind
 }
 
 // fetchSearchPage fetches data matching the search query from the database and
-// returns a SearchPage.
-func fetchSearchPage(ctx context.Context, db *postgres.DB, query string, pageParams paginationParams) (*SearchPage, error) {
-	dbresults, err := db.Search(ctx, query, pageParams.limit, pageParams.offset())
+// returns a SearchPage. The page to fetch is determined by pageParams, which
+// override the corresponding fields of opts.
+func fetchSearchPage(ctx context.Context, db *postgres.DB, query string, opts postgres.SearchOptions, pageParams paginationParams) (*SearchPage, error) {
+	opts.Limit = pageParams.limit
+	opts.Offset = pageParams.offset()
+	opts.AfterCursor = pageParams.after
+	dbresults, err := db.Search(ctx, query, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -71,21 +100,90 @@ func fetchSearchPage(ctx context.Context, db *postgres.DB, query string, pagePar
 	if len(dbresults) > 0 {
 		numResults = int(dbresults[0].NumResults)
 		if dbresults[0].Approximate {
-			// 128 buckets corresponds to a standard error of 10%.
-			// http://algo.inria.fr/flajolet/Publications/FlFuGaMe07.pdf
-			numResults = approximateNumber(numResults, 0.1)
+			// Round the estimate to about its standard error, which depends
+			// on the precision of the estimate.
+			numResults = approximateNumber(numResults, dbresults[0].NumResultsError)
 			approximate = true
 		}
 	}
 
 	pgs := newPagination(pageParams, len(results), numResults)
 	pgs.Approximate = approximate
-	return &SearchPage{
+	if len(dbresults) > 0 {
+		pgs.nextCursor = dbresults[len(dbresults)-1].NextCursor
+	}
+	page := &SearchPage{
 		Results:    results,
 		Pagination: pgs,
+	}
+	if len(dbresults) > 0 {
+		page.LicenseFacets = licenseFacets(query, dbresults[0].FacetCounts)
+	}
+	if len(results) == 0 {
+		alt, err := db.SuggestAlternativeQuery(ctx, query)
+		if err != nil {
+			// A missing suggestion is not worth failing the search.
+			log.Errorf(ctx, "fetchSearchPage: %v", err)
+		}
+		page.AlternativeQuery = alt
+	}
+	return page, nil
+}
+
+// fetchSymbolSearchPage fetches the packages that declare a symbol matching
+// name from the database and returns a SearchPage. If kind is non-empty, only
+// symbols of that kind are matched.
+func fetchSymbolSearchPage(ctx context.Context, db *postgres.DB, name string, kind internal.SymbolKind, pageParams paginationParams) (*SearchPage, error) {
+	dbresults, err := db.SearchSymbols(ctx, name, kind, pageParams.limit, pageParams.offset())
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*SearchResult
+	for _, r := range dbresults {
+		results = append(results, &SearchResult{
+			Name:           r.Name,
+			PackagePath:    r.PackagePath,
+			ModulePath:     r.ModulePath,
+			Synopsis:       r.Synopsis,
+			DisplayVersion: displayVersion(r.Version, r.ModulePath),
+			Licenses:       r.Licenses,
+			CommitTime:     elapsedTime(r.CommitTime),
+			NumImportedBy:  r.NumImportedBy,
+			SymbolName:     r.SymbolName,
+			SymbolKind:     r.SymbolKind,
+		})
+	}
+	var numResults int
+	if len(dbresults) > 0 {
+		numResults = int(dbresults[0].NumResults)
+	}
+	return &SearchPage{
+		Results:    results,
+		Pagination: newPagination(pageParams, len(results), numResults),
 	}, nil
 }
 
+// licenseFacets returns the facets for counts, the facet counts of a search
+// for query, sorted by decreasing count and then by license.
+func licenseFacets(query string, counts map[string]uint64) []*LicenseFacet {
+	var facets []*LicenseFacet
+	for lic, n := range counts {
+		facets = append(facets, &LicenseFacet{
+			License: lic,
+			Count:   n,
+			URL:     "/search?q=" + url.QueryEscape(query+" "+search.FieldLicense+":"+lic),
+		})
+	}
+	sort.Slice(facets, func(i, j int) bool {
+		if facets[i].Count != facets[j].Count {
+			return facets[i].Count > facets[j].Count
+		}
+		return facets[i].License < facets[j].License
+	})
+	return facets
+}
+
 // approximateNumber returns an approximation of the estimate, calibrated by
 // the statistical estimate of standard error.
 // i.e., a number that isn't misleading when we say '1-10 of approximately N
@@ -103,12 +201,6 @@ func approximateNumber(estimate int, sigma float64) int {
 // /search?q=<query>. If <query> is an exact match for a package path, the user
 // will be redirected to the details page.
 func (s *Server) serveSearch(w http.ResponseWriter, r *http.Request) error {
-	db, ok := s.ds.(*postgres.DB)
-	if !ok {
-		// The proxydatasource does not support the imported by page.
-		return proxydatasourceNotSupportedErr()
-	}
-
 	ctx := r.Context()
 	query := searchQuery(r)
 	if query == "" {
@@ -116,12 +208,62 @@ func (s *Server) serveSearch(w http.ResponseWriter, r *http.Request) error {
 		return nil
 	}
 
-	if path := searchRequestRedirectPath(ctx, s.ds, query); path != "" {
-		http.Redirect(w, r, path, http.StatusFound)
-		return nil
+	symbolSearch := r.FormValue("symbol") == "1"
+	if !symbolSearch {
+		// Redirecting to a path only needs a DataSource, so it works with
+		// every data source.
+		if path := searchRequestRedirectPath(ctx, s.ds, query); path != "" {
+			http.Redirect(w, r, path, http.StatusFound)
+			return nil
+		}
+	}
+	db, ok := s.ds.(*postgres.DB)
+	if !ok {
+		// The proxydatasource does not support searching.
+		return proxydatasourceNotSupportedErr()
+	}
+	if symbolSearch {
+		return s.serveSymbolSearch(w, r, db, query)
 	}
-	page, err := fetchSearchPage(ctx, db, query, newPaginationParams(r, defaultSearchLimit))
+	minImportedBy, err := minImportsParam(r)
 	if err != nil {
+		return &serverError{
+			status: http.StatusBadRequest,
+			err:    err,
+			epage: &errorPage{
+				Message: "The min_imports parameter must be a non-negative integer.",
+			},
+		}
+	}
+	updatedAfter, updatedBefore, err := updatedRangeParams(r)
+	if err != nil {
+		return &serverError{
+			status: http.StatusBadRequest,
+			err:    err,
+			epage: &errorPage{
+				Message:          "Invalid updated date range.",
+				SecondaryMessage: rangeErrorMessage(err),
+			},
+		}
+	}
+	opts := postgres.SearchOptions{
+		MinImportedBy: minImportedBy,
+		UpdatedAfter:  updatedAfter,
+		UpdatedBefore: updatedBefore,
+		IncludeFacets: r.FormValue("facets") == "1",
+	}
+	page, err := fetchSearchPage(ctx, db, query, opts, newPaginationParams(r, defaultSearchLimit))
+	if err != nil {
+		if errors.Is(err, derrors.InvalidArgument) {
+			return &serverError{
+				status: http.StatusBadRequest,
+				err:    err,
+				epage: &errorPage{
+					Message:          "Invalid search query.",
+					SecondaryMessage: template.HTML(`See <a href="/search-help">search help</a>.`),
+				},
+			}
+		}
 		return fmt.Errorf("fetchSearchPage(ctx, db, %q): %v", query, err)
 	}
 	page.basePage = s.newBasePage(r, query)
@@ -129,6 +271,99 @@ func (s *Server) serveSearch(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
+// serveSymbolSearch serves the packages that declare a symbol matching query.
+// Handles endpoint /search?q=<query>&symbol=1, optionally with a kind
+// parameter that restricts the kind of symbol.
+func (s *Server) serveSymbolSearch(w http.ResponseWriter, r *http.Request, db *postgres.DB, query string) error {
+	ctx := r.Context()
+	var kind internal.SymbolKind
+	if k := r.FormValue("kind"); k != "" {
+		var ok bool
+		kind, ok = internal.ParseSymbolKind(k)
+		if !ok {
+			var kinds []string
+			for _, k := range internal.SymbolKinds() {
+				kinds = append(kinds, string(k))
+			}
+			return &serverError{
+				status: http.StatusBadRequest,
+				err:    fmt.Errorf("unknown symbol kind %q", k),
+				epage: &errorPage{
+					Message:          fmt.Sprintf("%q is not a symbol kind.", k),
+					SecondaryMessage: template.HTML(fmt.Sprintf("Supported kinds are %s.", strings.Join(kinds, ", "))),
+				},
+			}
+		}
+	}
+	page, err := fetchSymbolSearchPage(ctx, db, query, kind, newPaginationParams(r, defaultSearchLimit))
+	if err != nil {
+		return fmt.Errorf("fetchSymbolSearchPage(ctx, db, %q, %q): %v", query, kind, err)
+	}
+	page.basePage = s.newBasePage(r, query)
+	s.servePage(ctx, w, "search.tmpl", page)
+	return nil
+}
+
+// minImportsParam returns the value of the min_imports parameter of r, or 0
+// if it is not set.
+func minImportsParam(r *http.Request) (_ int, err error) {
+	defer derrors.Wrap(&err, "minImportsParam(r)")
+	v := r.FormValue("min_imports")
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("negative value %d", n)
+	}
+	return n, nil
+}
+
+// errInvertedRange is returned by updatedRangeParams when the start of the
+// range is after its end.
+var errInvertedRange = errors.New("updated_after is later than updated_before")
+
+// updatedRangeParams returns the values of the updated_after and
+// updated_before parameters of r, which are RFC 3339 times. A parameter that
+// is not set is returned as the zero time. If both are set and updated_after
+// is later than updated_before, the error wraps errInvertedRange.
+func updatedRangeParams(r *http.Request) (after, before time.Time, err error) {
+	defer derrors.Wrap(&err, "updatedRangeParams(r)")
+	parse := func(name string) (time.Time, error) {
+		v := r.FormValue(name)
+		if v == "" {
+			return time.Time{}, nil
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%s: %v", name, err)
+		}
+		return t, nil
+	}
+	if after, err = parse("updated_after"); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if before, err = parse("updated_before"); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if !after.IsZero() && !before.IsZero() && after.After(before) {
+		return time.Time{}, time.Time{}, errInvertedRange
+	}
+	return after, before, nil
+}
+
+// rangeErrorMessage returns the message shown to the user for an error from
+// updatedRangeParams.
+func rangeErrorMessage(err error) template.HTML {
+	if errors.Is(err, errInvertedRange) {
+		return "The updated_after date must not be later than the updated_before date."
+	}
+	return "The updated_after and updated_before dates must be in RFC 3339 format, like 2020-06-01T00:00:00Z."
+}
+
 // searchRequestRedirectPath returns the path that a search request should be
 // redirected to, or the empty string if there is no such path. If the user
 // types an existing package path into the search bar, we will redirect the
@@ -6,7 +6,10 @@ package frontend
 
 import (
 	"context"
+	"errors"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -18,6 +21,7 @@ import (
 )
 
 func TestFetchSearchPage(t *testing.T) {
+	skipIfNoDB(t)
 	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
 	defer cancel()
 
@@ -138,7 +142,7 @@ func TestFetchSearchPage(t *testing.T) {
 				}
 			}
 
-			got, err := fetchSearchPage(ctx, testDB, tc.query, paginationParams{limit: 20, page: 1})
+			got, err := fetchSearchPage(ctx, testDB, tc.query, postgres.SearchOptions{}, paginationParams{limit: 20, page: 1})
 			if err != nil {
 				t.Fatalf("fetchSearchPage(db, %q): %v", tc.query, err)
 			}
@@ -146,7 +150,7 @@ func TestFetchSearchPage(t *testing.T) {
 			opts := cmp.Options{
 				cmp.AllowUnexported(SearchPage{}, pagination{}),
 				cmpopts.IgnoreFields(licenses.Metadata{}, "FilePath"),
-				cmpopts.IgnoreFields(pagination{}, "Approximate"),
+				cmpopts.IgnoreFields(pagination{}, "Approximate", "nextCursor"),
 			}
 			if diff := cmp.Diff(tc.wantSearchPage, got, opts...); diff != "" {
 				t.Errorf("fetchSearchPage(db, %q) mismatch (-want +got):\n%s", tc.query, diff)
@@ -155,6 +159,51 @@ func TestFetchSearchPage(t *testing.T) {
 	}
 }
 
+func TestFetchSymbolSearchPage(t *testing.T) {
+	skipIfNoDB(t)
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer postgres.ResetTestDB(testDB, t)
+
+	m := sample.Module("github.com/mod/foo", sample.VersionString, "")
+	m.LegacyPackages[0].Symbols = []*internal.Symbol{
+		{Name: "Reader", Kind: internal.SymbolKindType, Synopsis: "Reader reads."},
+		{Name: "ReadAll", Kind: internal.SymbolKindFunction, Synopsis: "ReadAll reads everything."},
+	}
+	if err := testDB.InsertModule(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		name string
+		kind internal.SymbolKind
+		want []string
+	}{
+		{"Reader", "", []string{"Reader"}},
+		{"Read", "", []string{"ReadAll", "Reader"}},
+		{"Read", internal.SymbolKindType, []string{"Reader"}},
+		{"Writer", "", nil},
+	} {
+		page, err := fetchSymbolSearchPage(ctx, testDB, test.name, test.kind, paginationParams{limit: 20, page: 1})
+		if err != nil {
+			t.Fatalf("fetchSymbolSearchPage(db, %q, %q): %v", test.name, test.kind, err)
+		}
+		var got []string
+		for _, r := range page.Results {
+			if r.PackagePath != m.LegacyPackages[0].Path {
+				t.Errorf("%s: got package %q, want %q", r.SymbolName, r.PackagePath, m.LegacyPackages[0].Path)
+			}
+			got = append(got, r.SymbolName)
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("fetchSymbolSearchPage(db, %q, %q) mismatch (-want +got):\n%s", test.name, test.kind, diff)
+		}
+		if page.Pagination.TotalCount != len(test.want) {
+			t.Errorf("fetchSymbolSearchPage(db, %q, %q): TotalCount = %d, want %d", test.name, test.kind, page.Pagination.TotalCount, len(test.want))
+		}
+	}
+}
+
 func TestApproximateNumber(t *testing.T) {
 	tests := []struct {
 		estimate int
@@ -178,7 +227,76 @@ func TestApproximateNumber(t *testing.T) {
 	}
 }
 
+func TestLicenseFacets(t *testing.T) {
+	got := licenseFacets("yaml", map[string]uint64{"MIT": 142, "BSD-3-Clause": 34, "Apache-2.0": 89, "ISC": 34})
+	want := []*LicenseFacet{
+		{License: "MIT", Count: 142, URL: "/search?q=yaml+license%3AMIT"},
+		{License: "Apache-2.0", Count: 89, URL: "/search?q=yaml+license%3AApache-2.0"},
+		{License: "BSD-3-Clause", Count: 34, URL: "/search?q=yaml+license%3ABSD-3-Clause"},
+		{License: "ISC", Count: 34, URL: "/search?q=yaml+license%3AISC"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("licenseFacets mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMinImportsParam(t *testing.T) {
+	for _, test := range []struct {
+		url  string
+		want int
+	}{
+		{"/search?q=foo", 0},
+		{"/search?q=foo&min_imports=0", 0},
+		{"/search?q=foo&min_imports=10", 10},
+	} {
+		got, err := minImportsParam(httptest.NewRequest("GET", test.url, nil))
+		if err != nil {
+			t.Fatalf("minImportsParam(%q): %v", test.url, err)
+		}
+		if got != test.want {
+			t.Errorf("minImportsParam(%q) = %d, want %d", test.url, got, test.want)
+		}
+	}
+	for _, url := range []string{"/search?q=foo&min_imports=-1", "/search?q=foo&min_imports=many"} {
+		if _, err := minImportsParam(httptest.NewRequest("GET", url, nil)); err == nil {
+			t.Errorf("minImportsParam(%q): got nil error, want error", url)
+		}
+	}
+}
+
+func TestUpdatedRangeParams(t *testing.T) {
+	jan := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	jun := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	for _, test := range []struct {
+		url                   string
+		wantAfter, wantBefore time.Time
+	}{
+		{"/search?q=foo", time.Time{}, time.Time{}},
+		{"/search?q=foo&updated_after=2020-01-01T00:00:00Z", jan, time.Time{}},
+		{"/search?q=foo&updated_before=2020-06-01T12:00:00Z", time.Time{}, jun},
+		{"/search?q=foo&updated_after=2020-01-01T00:00:00Z&updated_before=2020-06-01T12:00:00Z", jan, jun},
+	} {
+		after, before, err := updatedRangeParams(httptest.NewRequest("GET", test.url, nil))
+		if err != nil {
+			t.Fatalf("updatedRangeParams(%q): %v", test.url, err)
+		}
+		if !after.Equal(test.wantAfter) || !before.Equal(test.wantBefore) {
+			t.Errorf("updatedRangeParams(%q) = %v, %v; want %v, %v", test.url, after, before, test.wantAfter, test.wantBefore)
+		}
+	}
+
+	_, _, err := updatedRangeParams(httptest.NewRequest("GET", "/search?q=foo&updated_after=2020-06-01T12:00:00Z&updated_before=2020-01-01T00:00:00Z", nil))
+	if !errors.Is(err, errInvertedRange) {
+		t.Errorf("got error %v, want errInvertedRange", err)
+	}
+	_, _, err = updatedRangeParams(httptest.NewRequest("GET", "/search?q=foo&updated_after=yesterday", nil))
+	if err == nil || errors.Is(err, errInvertedRange) {
+		t.Errorf("got error %v, want parse error", err)
+	}
+}
+
 func TestSearchRequestRedirectPath(t *testing.T) {
+	skipIfNoDB(t)
 	t.Run("no experiments ", func(t *testing.T) {
 		testSearchRequestRedirectPath(t)
 	})
@@ -19,6 +19,8 @@ import (
 
 	"github.com/go-redis/redis/v7"
 	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/cache"
+	"golang.org/x/pkgsite/internal/config"
 	"golang.org/x/pkgsite/internal/derrors"
 	"golang.org/x/pkgsite/internal/experiment"
 	"golang.org/x/pkgsite/internal/licenses"
@@ -29,8 +31,12 @@ import (
 
 // Server can be installed to serve the go discovery frontend.
 type Server struct {
-	ds    internal.DataSource
-	queue queue.Queue
+	ds internal.DataSource
+	// cachedDS is ds, wrapped in a cache.CachingDataSource if
+	// ServerConfig.DataSourceCache is set. It is used for the lookups of the
+	// package and module pages, which are repeated on every request.
+	cachedDS internal.DataSource
+	queue    queue.Queue
 	// cmplClient is a redis client that has access to the "completions" sorted
 	// set.
 	cmplClient           *redis.Client
@@ -41,6 +47,18 @@ type Server struct {
 	devMode              bool
 	errorPage            []byte
 	appVersionLabel      string
+	badgeQuota           config.QuotaSettings
+	searchRateLimit      config.RateLimitSettings
+	autocompleteLimit    config.RateLimitSettings
+	allowedOrigins       []string
+	shutdownTimeout      time.Duration
+
+	// httpServer is the server started by Serve, and inflight counts the
+	// requests that it is handling. See Server.Shutdown.
+	httpServer   *http.Server
+	inflight     sync.WaitGroup
+	shutdownMu   sync.Mutex // protects shuttingDown
+	shuttingDown bool
 
 	mu        sync.Mutex // Protects all fields below
 	templates map[string]*template.Template
@@ -48,7 +66,10 @@ type Server struct {
 
 // ServerConfig contains everything needed by a Server.
 type ServerConfig struct {
-	DataSource           internal.DataSource
+	DataSource internal.DataSource
+	// DataSourceCache, if set, caches the packages and modules of the package
+	// and module pages read from DataSource.
+	DataSourceCache      cache.RedisClient
 	Queue                queue.Queue
 	CompletionClient     *redis.Client
 	TaskIDChangeInterval time.Duration
@@ -56,6 +77,19 @@ type ServerConfig struct {
 	ThirdPartyPath       string
 	DevMode              bool
 	AppVersionLabel      string
+	// BadgeQuota is the quota for requests to the badge endpoint.
+	BadgeQuota config.QuotaSettings
+	// SearchRateLimit and AutocompleteRateLimit limit the requests of each
+	// client to the search and autocomplete endpoints. They are not applied
+	// if their RPS is zero.
+	SearchRateLimit       config.RateLimitSettings
+	AutocompleteRateLimit config.RateLimitSettings
+	// AllowedOrigins are the origins from which browsers may call the JSON
+	// API.
+	AllowedOrigins []string
+	// ShutdownTimeout, if positive, limits the time that Server.Shutdown
+	// waits for in-flight requests to finish.
+	ShutdownTimeout time.Duration
 }
 
 // NewServer creates a new Server for the given database and template directory.
@@ -77,12 +111,21 @@ func NewServer(scfg ServerConfig) (_ *Server, err error) {
 		templates:            ts,
 		taskIDChangeInterval: scfg.TaskIDChangeInterval,
 		appVersionLabel:      scfg.AppVersionLabel,
+		badgeQuota:           scfg.BadgeQuota,
+		searchRateLimit:      scfg.SearchRateLimit,
+		autocompleteLimit:    scfg.AutocompleteRateLimit,
+		allowedOrigins:       scfg.AllowedOrigins,
+		shutdownTimeout:      scfg.ShutdownTimeout,
 	}
 	errorPageBytes, err := s.renderErrorPage(context.Background(), http.StatusInternalServerError, "error.tmpl", nil)
 	if err != nil {
 		return nil, fmt.Errorf("s.renderErrorPage(http.StatusInternalServerError, nil): %v", err)
 	}
 	s.errorPage = errorPageBytes
+	s.cachedDS = s.ds
+	if scfg.DataSourceCache != nil {
+		s.cachedDS = cache.NewCachingDataSource(s.ds, scfg.DataSourceCache)
+	}
 	return s, nil
 }
 
@@ -96,19 +139,47 @@ func (s *Server) Install(handle func(string, http.Handler), redisClient *redis.C
 		detailHandler = middleware.Cache("details", redisClient, detailsTTL)(detailHandler)
 		searchHandler = middleware.Cache("search", redisClient, middleware.TTL(defaultTTL))(searchHandler)
 	}
+	// Package pages are also served as JSON, to requests that ask for it.
+	detailHandler = middleware.NegotiateContent()(detailHandler)
+	searchHandler = rateLimit(s.searchRateLimit)(searchHandler)
 	handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir(s.staticPath))))
 	handle("/third_party/", http.StripPrefix("/third_party", http.FileServer(http.Dir(s.thirdPartyPath))))
 	handle("/favicon.ico", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		http.ServeFile(w, r, fmt.Sprintf("%s/img/favicon.ico", http.Dir(s.staticPath)))
 	}))
 	handle("/fetch/", http.HandlerFunc(s.fetchHandler))
-	handle("/pkg/", http.HandlerFunc(s.handlePackageDetailsRedirect))
+	handle("/pkg/", middleware.DeprecationMiddleware("/pkg/", "/", deprecatedURLSunset)(http.HandlerFunc(s.handlePackageDetailsRedirect)))
 	handle("/search", searchHandler)
+	apiMuxes := map[int]*http.ServeMux{}
+	for _, v := range apiVersions {
+		apiMuxes[v] = s.VersionedMux(v)
+	}
+	negotiateAPIVersion := VersionNegotiationMiddleware(apiMuxes)
+	for v, mux := range apiMuxes {
+		handle(apiVersionPrefix(v), negotiateAPIVersion(mux))
+	}
+	handle("/feed/new-packages", http.HandlerFunc(s.handleFeed))
+	handle("/trending", s.errorHandler(s.handleTrending))
+	handle(comparePathPrefix, s.errorHandler(s.handleCompare))
+	handle(workspacePathPrefix, s.errorHandler(s.handleWorkspace))
+	handle(filesPathPrefix, s.errorHandler(s.handleFileTree))
+	handle(graphPathPrefix, s.errorHandler(s.handleGraph))
+	handle(sbomPathPrefix, s.errorHandler(s.handleSBOM))
+	handle(definitionPath, http.HandlerFunc(s.handleDefinition))
+	handle(implementsPath, http.HandlerFunc(s.handleImplements))
+	handle(proxyPathPrefix, proxyMiddleware(s.ds)(&proxyHandler{ds: s.ds}))
+	handle(badgePathPrefix, middleware.Quota(s.badgeQuota)(http.HandlerFunc(s.handleBadge)))
+	handle("/healthz/ready", http.HandlerFunc(s.handleReadiness))
+	handle("/healthz/live", http.HandlerFunc(s.handleLiveness))
 	handle("/search-help", s.staticPageHandler("search_help.tmpl", "Search Help - go.dev"))
 	handle("/license-policy", s.licensePolicyHandler())
 	handle("/about", http.RedirectHandler("https://go.dev/about", http.StatusFound))
 	handle("/", detailHandler)
-	handle("/autocomplete", http.HandlerFunc(s.handleAutoCompletion))
+	autocompleteHandler := http.HandlerFunc(s.handleAutocomplete)
+	if s.cmplClient != nil {
+		autocompleteHandler = s.handleAutoCompletion
+	}
+	handle("/autocomplete", rateLimit(s.autocompleteLimit)(autocompleteHandler))
 	handle("/robots.txt", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(`User-agent: *
@@ -117,6 +188,15 @@ Disallow: /search?*
 	}))
 }
 
+// rateLimit returns a middleware that limits requests according to settings,
+// or that does nothing if settings.RPS is zero.
+func rateLimit(settings config.RateLimitSettings) middleware.Middleware {
+	if settings.RPS == 0 {
+		return middleware.Identity()
+	}
+	return middleware.RateLimit(settings.RPS, settings.Burst)
+}
+
 const (
 	// defaultTTL is used when details tab contents are subject to change, or when
 	// there is a problem confirming that the details can be permanently cached.
@@ -128,6 +208,10 @@ const (
 	longTTL = 24 * time.Hour
 )
 
+// deprecatedURLSunset is the date after which the deprecated URL patterns,
+// which redirect to their canonical forms, may stop being served.
+var deprecatedURLSunset = time.Date(2021, time.July, 1, 0, 0, 0, 0, time.UTC)
+
 // detailsTTL assigns the cache TTL for package detail requests.
 func detailsTTL(r *http.Request) time.Duration {
 	return detailsTTLForPath(r.Context(), r.URL.Path, r.FormValue("tab"))
@@ -148,12 +232,25 @@ func detailsTTLForPath(ctx context.Context, urlPath, tab string) time.Duration {
 	if version == internal.LatestVersion {
 		return shortTTL
 	}
-	if tab == "importedby" || tab == "versions" {
+	if isMutableTab(tab) {
 		return defaultTTL
 	}
 	return longTTL
 }
 
+// isMutableTab reports whether the contents of the tab with the given name
+// can change for a fixed module version, because they depend on other
+// modules.
+func isMutableTab(tab string) bool {
+	return tab == "importedby" || tab == "versions" || tab == "dependencies"
+}
+
+// baseURL returns the scheme and host of the site serving r, for constructing
+// absolute URLs.
+func baseURL(r *http.Request) string {
+	return "https://" + r.Host
+}
+
 // TagRoute categorizes incoming requests to the frontend for use in
 // monitoring.
 func TagRoute(route string, r *http.Request) string {
@@ -293,6 +390,14 @@ func (s *Server) serveError(w http.ResponseWriter, r *http.Request, err error) {
 	} else {
 		log.Infof(ctx, "returning %d (%s) for error %v", serr.status, http.StatusText(serr.status), err)
 	}
+	if isAPIRequest(r) {
+		var detail string
+		if serr.epage != nil {
+			detail = serr.epage.Message
+		}
+		WriteJSONError(w, newProblemDetail(r, serr.status, detail))
+		return
+	}
 	s.serveErrorPage(w, r, serr.status, serr.epage)
 }
 
@@ -396,12 +501,18 @@ func parsePageTemplates(base string) (map[string]*template.Template, error) {
 		{"notfound.tmpl"},
 		{"search.tmpl"},
 		{"search_help.tmpl"},
+		{"trending.tmpl"},
+		{"compare.tmpl"},
+		{"workspace.tmpl"},
+		{"files.tmpl"},
 		{"license_policy.tmpl"},
 		{"overview.tmpl", "details.tmpl"},
 		{"subdirectories.tmpl", "details.tmpl"},
 		{"pkg_doc.tmpl", "details.tmpl"},
+		{"pkg_examples.tmpl", "details.tmpl"},
 		{"pkg_importedby.tmpl", "details.tmpl"},
 		{"pkg_imports.tmpl", "details.tmpl"},
+		{"mod_dependencies.tmpl", "details.tmpl"},
 		{"licenses.tmpl", "details.tmpl"},
 		{"versions.tmpl", "details.tmpl"},
 		{"not_implemented.tmpl", "details.tmpl"},
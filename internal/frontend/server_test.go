@@ -35,9 +35,22 @@ var testDB *postgres.DB
 
 func TestMain(m *testing.M) {
 	postgres.RunDBTests("discovery_frontend_test", m, &testDB)
+	// RunDBTests returns only if no database is available. The tests that
+	// need one skip themselves, with skipIfNoDB.
+	os.Exit(m.Run())
+}
+
+// skipIfNoDB skips t if no database is available, in which case testDB is
+// nil.
+func skipIfNoDB(t *testing.T) {
+	t.Helper()
+	if testDB == nil {
+		t.Skip("no test database")
+	}
 }
 
 func TestHTMLInjection(t *testing.T) {
+	skipIfNoDB(t)
 	_, handler, _ := newTestServer(t, nil)
 	w := httptest.NewRecorder()
 	handler.ServeHTTP(w, httptest.NewRequest("GET", "/<em>UHOH</em>", nil))
@@ -174,6 +187,7 @@ func insertTestModules(ctx context.Context, t *testing.T, mods []testModule) {
 //
 // We aim to test all combinations of these.
 func TestServer(t *testing.T) {
+	skipIfNoDB(t)
 	t.Run("no experiments", func(t *testing.T) {
 		testServer(t)
 	})
@@ -361,6 +375,19 @@ func testServer(t *testing.T, experimentNames ...string) {
 			wantStatusCode: http.StatusOK,
 			want:           in("", text("css"), text("html"), text("img"), text("js")),
 		},
+		{
+			name:           "trending",
+			urlPath:        "/trending",
+			wantStatusCode: http.StatusOK,
+			want: in("",
+				in(".SearchResults-header", text("Trending packages")),
+				in(".SearchResults-emptyContentMessage", text("No trending packages yet."))),
+		},
+		{
+			name:           "trending bad period",
+			urlPath:        "/trending?period=3",
+			wantStatusCode: http.StatusBadRequest,
+		},
 		{
 			name:           "license policy",
 			urlPath:        "/license-policy",
@@ -762,6 +789,15 @@ func testServer(t *testing.T, experimentNames ...string) {
 						attr("title", "v1.0.0"),
 						text("v1.0.0")))),
 		},
+		{
+			name:           "module at version dependencies tab",
+			urlPath:        fmt.Sprintf("/mod/%s@%s?tab=dependencies", sample.ModulePath, sample.VersionString),
+			wantStatusCode: http.StatusOK,
+			want: in("",
+				pagecheck.ModuleHeader(mod, versioned),
+				in("li.selected", text(`Dependencies`)),
+				in(".EmptyContent-message", text(`This module does not have any dependencies!`))),
+		},
 		{
 			name:           "module at version licenses tab",
 			urlPath:        fmt.Sprintf("/mod/%s@%s?tab=licenses", sample.ModulePath, sample.VersionString),
@@ -828,6 +864,7 @@ func testServer(t *testing.T, experimentNames ...string) {
 }
 
 func TestServerErrors(t *testing.T) {
+	skipIfNoDB(t)
 	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
 	defer cancel()
 
@@ -855,6 +892,99 @@ func TestServerErrors(t *testing.T) {
 	}
 }
 
+func TestServerCanonicalURL(t *testing.T) {
+	skipIfNoDB(t)
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	defer postgres.ResetTestDB(testDB, t)
+	if err := testDB.InsertModule(ctx, sample.DefaultModule()); err != nil {
+		t.Fatal(err)
+	}
+	_, handler, _ := newTestServer(t, nil)
+
+	var (
+		pkgURL = "/" + sample.ModulePath + "/" + sample.Suffix
+		modURL = "/mod/" + sample.ModulePath
+	)
+	for _, test := range []struct {
+		path, want string
+	}{
+		{pkgURL + "?tab=doc", pkgURL},
+		{"/" + sample.ModulePath + "@" + sample.VersionString + "/" + sample.Suffix + "?tab=doc", pkgURL},
+		{modURL, modURL},
+		{modURL + "@" + sample.VersionString, modURL},
+	} {
+		t.Run(test.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("GET %q = %d, want %d", test.path, w.Code, http.StatusOK)
+			}
+			want := fmt.Sprintf(`<%s>; rel="canonical"`, test.want)
+			if got := w.Header().Get("Link"); got != want {
+				t.Errorf("GET %q: Link header = %q, want %q", test.path, got, want)
+			}
+		})
+	}
+}
+
+func TestServerETag(t *testing.T) {
+	skipIfNoDB(t)
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	defer postgres.ResetTestDB(testDB, t)
+	if err := testDB.InsertModule(ctx, sample.DefaultModule()); err != nil {
+		t.Fatal(err)
+	}
+	_, handler, _ := newTestServer(t, nil)
+
+	get := func(path, ifNoneMatch string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		if ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+	for _, path := range []string{
+		"/" + sample.ModulePath + "/" + sample.Suffix + "?tab=doc",
+		"/" + sample.ModulePath + "@" + sample.VersionString + "/" + sample.Suffix + "?tab=doc",
+		"/mod/" + sample.ModulePath + "@" + sample.VersionString + "?tab=overview",
+	} {
+		t.Run(path, func(t *testing.T) {
+			w := get(path, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("GET %q = %d, want %d", path, w.Code, http.StatusOK)
+			}
+			etag := w.Header().Get("ETag")
+			if etag == "" {
+				t.Fatalf("GET %q: no ETag header", path)
+			}
+
+			w = get(path, etag)
+			if w.Code != http.StatusNotModified {
+				t.Errorf("GET %q with matching If-None-Match = %d, want %d", path, w.Code, http.StatusNotModified)
+			}
+			if w.Body.Len() != 0 {
+				t.Errorf("GET %q with matching If-None-Match: got body of length %d, want empty", path, w.Body.Len())
+			}
+
+			if w := get(path, `"other"`); w.Code != http.StatusOK {
+				t.Errorf("GET %q with other If-None-Match = %d, want %d", path, w.Code, http.StatusOK)
+			}
+		})
+	}
+
+	// The imported-by tab changes as other modules are added, so it has no ETag.
+	path := "/" + sample.ModulePath + "/" + sample.Suffix + "?tab=importedby"
+	if w := get(path, ""); w.Header().Get("ETag") != "" {
+		t.Errorf("GET %q: got ETag %q, want none", path, w.Header().Get("ETag"))
+	}
+}
+
 func mustRequest(urlPath string, t *testing.T) *http.Request {
 	t.Helper()
 	r, err := http.NewRequest(http.MethodGet, "http://localhost"+urlPath, nil)
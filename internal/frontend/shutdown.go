@@ -0,0 +1,103 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+// Serve serves HTTP requests on l with h, until s.Shutdown is called. The
+// requests are tracked so that Shutdown can wait for them to finish.
+//
+// Like http.Server.Serve, Serve always returns a non-nil error. After
+// Shutdown, the error is http.ErrServerClosed.
+func (s *Server) Serve(l net.Listener, h http.Handler) error {
+	s.shutdownMu.Lock()
+	if s.shuttingDown {
+		s.shutdownMu.Unlock()
+		return http.ErrServerClosed
+	}
+	s.httpServer = &http.Server{Handler: s.trackRequests(h)}
+	hs := s.httpServer
+	s.shutdownMu.Unlock()
+	return hs.Serve(l)
+}
+
+// ListenAndServe listens on the TCP network address addr and calls s.Serve.
+func (s *Server) ListenAndServe(addr string, h http.Handler) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l, h)
+}
+
+// trackRequests returns a handler that counts the requests to h that are in
+// flight. Once s is shutting down, it rejects new requests with a 503, so
+// that the count can't grow while Shutdown waits for it to reach zero.
+func (s *Server) trackRequests(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.shutdownMu.Lock()
+		if s.shuttingDown {
+			s.shutdownMu.Unlock()
+			w.Header().Set("Connection", "close")
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+		s.inflight.Add(1)
+		s.shutdownMu.Unlock()
+		defer s.inflight.Done()
+		h.ServeHTTP(w, r)
+	})
+}
+
+// Shutdown gracefully shuts down s: it stops the server started by Serve
+// from accepting connections, waits for the requests in flight to finish,
+// and then closes the DataSource of s, if it has a Close method.
+//
+// If ctx is done, or the ShutdownTimeout of the ServerConfig of s elapses,
+// before all requests finish, Shutdown stops waiting and returns the
+// context's error, after closing the DataSource.
+func (s *Server) Shutdown(ctx context.Context) (err error) {
+	defer derrors.Wrap(&err, "Shutdown")
+
+	if s.shutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.shutdownTimeout)
+		defer cancel()
+	}
+	s.shutdownMu.Lock()
+	s.shuttingDown = true
+	hs := s.httpServer
+	s.shutdownMu.Unlock()
+
+	if hs != nil {
+		err = hs.Shutdown(ctx)
+	}
+	// http.Server.Shutdown does not wait for the handlers of hijacked
+	// connections.
+	done := make(chan struct{})
+	go func() {
+		s.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		if err == nil {
+			err = ctx.Err()
+		}
+	}
+	if c, ok := s.ds.(interface{ Close() error }); ok {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,119 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/pkgsite/internal"
+)
+
+// closingDataSource is a DataSource that counts the calls to its Close
+// method.
+type closingDataSource struct {
+	internal.DataSource
+	closed int32
+}
+
+func (ds *closingDataSource) Close() error {
+	atomic.AddInt32(&ds.closed, 1)
+	return nil
+}
+
+func TestShutdown(t *testing.T) {
+	ds := &closingDataSource{}
+	s, err := NewServer(ServerConfig{
+		DataSource:      ds,
+		StaticPath:      "../../content/static",
+		ShutdownTimeout: 2 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const slowDelay = 500 * time.Millisecond
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(slowDelay)
+		if atomic.LoadInt32(&ds.closed) != 0 {
+			t.Error("DataSource closed during request")
+		}
+		w.Write([]byte("done"))
+	})
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- s.Serve(l, handler) }()
+
+	type result struct {
+		status int
+		body   string
+		err    error
+	}
+	results := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + l.Addr().String())
+		if err != nil {
+			results <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		results <- result{resp.StatusCode, string(body), err}
+	}()
+	<-started
+
+	start := time.Now()
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if d := time.Since(start); d < slowDelay/2 {
+		t.Errorf("Shutdown returned after %v, before the request finished", d)
+	}
+	res := <-results
+	if res.err != nil {
+		t.Fatalf("slow request: %v", res.err)
+	}
+	if res.status != http.StatusOK || res.body != "done" {
+		t.Errorf("slow request: got %d %q, want %d %q", res.status, res.body, http.StatusOK, "done")
+	}
+	if got := atomic.LoadInt32(&ds.closed); got != 1 {
+		t.Errorf("DataSource closed %d times, want 1", got)
+	}
+	if err := <-serveErr; err != http.ErrServerClosed {
+		t.Errorf("Serve returned %v, want %v", err, http.ErrServerClosed)
+	}
+	// Serving after shutdown fails immediately.
+	if err := s.Serve(l, handler); err != http.ErrServerClosed {
+		t.Errorf("Serve after Shutdown returned %v, want %v", err, http.ErrServerClosed)
+	}
+}
+
+func TestTrackRequestsAfterShutdown(t *testing.T) {
+	s := &Server{}
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	called := false
+	h := s.trackRequests(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { called = true }))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if called {
+		t.Error("handler called after Shutdown")
+	}
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("got status %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+}
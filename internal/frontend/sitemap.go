@@ -0,0 +1,124 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/log"
+)
+
+const (
+	// sitemapURLLimit is the maximum number of URLs in a sitemap, as set by
+	// the sitemap protocol.
+	sitemapURLLimit = 50000
+	sitemapXMLNS    = "http://www.sitemaps.org/schemas/sitemap/0.9"
+)
+
+// sitemapPathRegexp matches the paths served by handleSitemap. The submatch
+// is the number of the sitemap, if any.
+var sitemapPathRegexp = regexp.MustCompile(`^/sitemap(?:-(0|[1-9][0-9]*))?\.xml$`)
+
+// isSitemapPath reports whether urlPath is served by handleSitemap.
+func isSitemapPath(urlPath string) bool {
+	return sitemapPathRegexp.MatchString(urlPath)
+}
+
+// handleSitemap handles requests for the sitemap index at /sitemap.xml, and
+// for the sitemaps at /sitemap-<n>.xml that it lists. The nth sitemap lists
+// the pages of the redistributable packages from n*sitemapURLLimit on, at
+// their latest version.
+func (s *Server) handleSitemap(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	m := sitemapPathRegexp.FindStringSubmatch(r.URL.Path)
+	if m == nil {
+		http.NotFound(w, r)
+		return
+	}
+	count, err := s.ds.GetSitemapPackageCount(ctx)
+	if err != nil {
+		log.Errorf(ctx, "handleSitemap: %v", err)
+		code := http.StatusInternalServerError
+		http.Error(w, http.StatusText(code), code)
+		return
+	}
+	numSitemaps := (count + sitemapURLLimit - 1) / sitemapURLLimit
+	if m[1] == "" {
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		if err := writeSitemapIndex(w, baseURL(r), numSitemaps); err != nil {
+			log.Errorf(ctx, "handleSitemap: %v", err)
+		}
+		return
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil || n >= numSitemaps {
+		http.NotFound(w, r)
+		return
+	}
+	paths, err := s.ds.GetPackagePathsForSitemap(ctx, n*sitemapURLLimit, sitemapURLLimit)
+	if err != nil {
+		log.Errorf(ctx, "handleSitemap: %v", err)
+		code := http.StatusInternalServerError
+		http.Error(w, http.StatusText(code), code)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	if err := writeSitemap(w, baseURL(r), paths); err != nil {
+		log.Errorf(ctx, "handleSitemap: %v", err)
+	}
+}
+
+// sitemapLoc is an element of a sitemap or sitemap index.
+type sitemapLoc struct {
+	Loc string `xml:"loc"`
+}
+
+// writeSitemapIndex writes to w a sitemap index listing the first
+// numSitemaps sitemaps.
+func writeSitemapIndex(w io.Writer, baseURL string, numSitemaps int) error {
+	return writeSitemapXML(w, "sitemapindex", "sitemap", numSitemaps, func(i int) string {
+		return fmt.Sprintf("%s/sitemap-%d.xml", baseURL, i)
+	})
+}
+
+// writeSitemap writes to w a sitemap listing the pages of the latest
+// versions of the packages with the given paths.
+func writeSitemap(w io.Writer, baseURL string, pkgPaths []string) error {
+	return writeSitemapXML(w, "urlset", "url", len(pkgPaths), func(i int) string {
+		return baseURL + constructPackageURL(pkgPaths[i], internal.UnknownModulePath, internal.LatestVersion)
+	})
+}
+
+// writeSitemapXML streams to w an XML document whose root element, named
+// root, has n child elements named elem, the ith of which has the location
+// loc(i).
+func writeSitemapXML(w io.Writer, root, elem string, n int, loc func(int) string) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	start := xml.StartElement{
+		Name: xml.Name{Local: root},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: sitemapXMLNS}},
+	}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		if err := enc.EncodeElement(sitemapLoc{Loc: loc(i)}, xml.StartElement{Name: xml.Name{Local: elem}}); err != nil {
+			return err
+		}
+	}
+	if err := enc.EncodeToken(start.End()); err != nil {
+		return err
+	}
+	return enc.Flush()
+}
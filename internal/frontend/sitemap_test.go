@@ -0,0 +1,141 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal/postgres"
+)
+
+// parseSitemap checks that body is a well-formed XML document with the given
+// root element, and returns the contents of its <loc> elements.
+func parseSitemap(t *testing.T, body []byte, root string) []string {
+	t.Helper()
+	if !bytes.HasPrefix(body, []byte(xml.Header)) {
+		t.Errorf("body does not start with the XML header")
+	}
+	var (
+		locs     []string
+		gotRoot  string
+		inLoc    bool
+		dec      = xml.NewDecoder(bytes.NewReader(body))
+		elements = 0
+	)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("malformed XML: %v\n%s", err, body)
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			if elements == 0 {
+				gotRoot = tok.Name.Local
+				if tok.Name.Space != sitemapXMLNS {
+					t.Errorf("namespace = %q, want %q", tok.Name.Space, sitemapXMLNS)
+				}
+			}
+			elements++
+			inLoc = tok.Name.Local == "loc"
+		case xml.CharData:
+			if inLoc {
+				locs = append(locs, string(tok))
+			}
+		case xml.EndElement:
+			inLoc = false
+		}
+	}
+	if gotRoot != root {
+		t.Errorf("root element = %q, want %q", gotRoot, root)
+	}
+	return locs
+}
+
+func TestWriteSitemap(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeSitemap(&buf, "https://pkg.go.dev", []string{"a.com/m", "b.com/m/p&q", "net/http"}); err != nil {
+		t.Fatal(err)
+	}
+	got := parseSitemap(t, buf.Bytes(), "urlset")
+	want := []string{
+		"https://pkg.go.dev/a.com/m",
+		"https://pkg.go.dev/b.com/m/p&q",
+		"https://pkg.go.dev/net/http",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("sitemap mismatch (-want +got):\n%s", diff)
+	}
+
+	buf.Reset()
+	if err := writeSitemapIndex(&buf, "https://pkg.go.dev", 2); err != nil {
+		t.Fatal(err)
+	}
+	got = parseSitemap(t, buf.Bytes(), "sitemapindex")
+	want = []string{
+		"https://pkg.go.dev/sitemap-0.xml",
+		"https://pkg.go.dev/sitemap-1.xml",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("sitemap index mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestServerSitemap(t *testing.T) {
+	skipIfNoDB(t)
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer postgres.ResetTestDB(testDB, t)
+
+	var (
+		redist    = testModule{path: "github.com/redist", redistributable: true, versions: []string{"v1.0.0"}}
+		nonRedist = testModule{path: "github.com/nonredist", redistributable: false, versions: []string{"v1.0.0"}}
+		want      []string
+	)
+	for i := 0; i < 8; i++ {
+		suffix := fmt.Sprintf("pkg%d", i)
+		redist.packages = append(redist.packages, testPackage{suffix: suffix})
+		want = append(want, "https://example.com/github.com/redist/"+suffix)
+	}
+	for i := 0; i < 2; i++ {
+		nonRedist.packages = append(nonRedist.packages, testPackage{suffix: fmt.Sprintf("pkg%d", i)})
+	}
+	insertTestModules(ctx, t, []testModule{redist, nonRedist})
+	_, handler, _ := newTestServer(t, nil)
+
+	get := func(path string) []byte {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "https://example.com"+path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %q = %d, want %d", path, w.Code, http.StatusOK)
+		}
+		return w.Body.Bytes()
+	}
+	got := parseSitemap(t, get("/sitemap.xml"), "sitemapindex")
+	if diff := cmp.Diff([]string{"https://example.com/sitemap-0.xml"}, got); diff != "" {
+		t.Errorf("sitemap index mismatch (-want +got):\n%s", diff)
+	}
+	got = parseSitemap(t, get("/sitemap-0.xml"), "urlset")
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("sitemap mismatch (-want +got):\n%s", diff)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/sitemap-1.xml", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("GET /sitemap-1.xml = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,310 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"errors"
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"html/template"
+	"strings"
+)
+
+// sourceToken is a token of a Go source file.
+type sourceToken struct {
+	tok token.Token
+	// start and end are the offsets of the token in the file. They are equal
+	// for automatically inserted semicolons.
+	start, end int
+}
+
+// renderSourceFile returns the HTML of the Go source file src of the package
+// pkgPath at version. Keywords, comments and literals are wrapped in spans
+// with the classes FileView-keyword, FileView-comment, FileView-string and
+// FileView-number, and each line starts with an anchor whose id is "L"
+// followed by the line number. The exported identifiers declared at the top level of the
+// file, at their declarations and wherever the file refers to them without a
+// qualifier, link to their documentation on the package page.
+//
+// Files that are not part of the documented package, like commands, external
+// tests and files with an "ignore" build constraint, are highlighted but not
+// linked. Neither are any files if pkgPath is empty.
+// Other build constraints are ignored. Syntax errors are tolerated: the text
+// around them is escaped and left unhighlighted. renderSourceFile returns an
+// error only if src has no package clause.
+func renderSourceFile(src []byte, pkgPath, version string) (template.HTML, error) {
+	toks := scanSource(src)
+	pkgName, ok := sourcePackageName(toks, src)
+	if !ok {
+		return "", errors.New("renderSourceFile: missing package clause")
+	}
+	var (
+		names   map[string]bool
+		anchors map[int]string
+	)
+	if pkgPath != "" && pkgName != "main" && !strings.HasSuffix(pkgName, "_test") && !hasIgnoreConstraint(src) {
+		names, anchors = exportedDeclarations(toks, src)
+	}
+
+	w := newSourceWriter()
+	last := 0
+	for i, t := range toks {
+		if t.start == t.end || t.start < last {
+			continue
+		}
+		text := string(src[t.start:t.end])
+		var open, close string
+		switch {
+		case t.tok.IsKeyword():
+			open, close = `<span class="FileView-keyword">`, `</span>`
+		case t.tok == token.COMMENT:
+			open, close = `<span class="FileView-comment">`, `</span>`
+		case t.tok == token.STRING || t.tok == token.CHAR:
+			open, close = `<span class="FileView-string">`, `</span>`
+		case t.tok == token.INT || t.tok == token.FLOAT || t.tok == token.IMAG:
+			open, close = `<span class="FileView-number">`, `</span>`
+		case t.tok == token.IDENT:
+			anchor, ok := anchors[i]
+			if !ok && names[text] && (i == 0 || toks[i-1].tok != token.PERIOD) {
+				anchor, ok = text, true
+			}
+			if !ok {
+				continue
+			}
+			href := fmt.Sprintf("/%s@%s#%s", pkgPath, version, anchor)
+			open, close = fmt.Sprintf(`<a href="%s">`, template.HTMLEscapeString(href)), `</a>`
+		default:
+			continue
+		}
+		w.text(string(src[last:t.start]))
+		w.b.WriteString(open)
+		w.text(text)
+		w.b.WriteString(close)
+		last = t.end
+	}
+	w.text(string(src[last:]))
+	return template.HTML(w.b.String()), nil
+}
+
+// renderTextFile returns the HTML of the text file src, with line anchors as
+// in renderSourceFile.
+func renderTextFile(src []byte) template.HTML {
+	w := newSourceWriter()
+	w.text(string(src))
+	return template.HTML(w.b.String())
+}
+
+// A sourceWriter builds the HTML of a source file. It starts each line with
+// an empty span whose id is "L" followed by the line number, so that
+// "#L12" in a URL links to line 12.
+type sourceWriter struct {
+	b    strings.Builder
+	line int
+}
+
+func newSourceWriter() *sourceWriter {
+	w := &sourceWriter{}
+	w.newLine()
+	return w
+}
+
+func (w *sourceWriter) newLine() {
+	w.line++
+	fmt.Fprintf(&w.b, `<span id="L%d"></span>`, w.line)
+}
+
+// text writes s, HTML-escaped.
+func (w *sourceWriter) text(s string) {
+	for {
+		i := strings.IndexByte(s, '\n')
+		if i < 0 {
+			w.b.WriteString(template.HTMLEscapeString(s))
+			return
+		}
+		w.b.WriteString(template.HTMLEscapeString(s[:i+1]))
+		w.newLine()
+		s = s[i+1:]
+	}
+}
+
+// scanSource returns the tokens of src, including comments. Scanning errors
+// are ignored. Since the scanner removes carriage returns from comments and
+// raw strings, the end of those tokens may be before their actual end.
+func scanSource(src []byte) []sourceToken {
+	var s scanner.Scanner
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	s.Init(file, src, func(token.Position, string) {}, scanner.ScanComments)
+	var toks []sourceToken
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			return toks
+		}
+		start := file.Offset(pos)
+		end := start
+		switch {
+		case tok == token.SEMICOLON && lit == "\n":
+		case lit != "":
+			end = start + len(lit)
+		default:
+			end = start + len(tok.String())
+		}
+		if end > len(src) {
+			end = len(src)
+		}
+		toks = append(toks, sourceToken{tok: tok, start: start, end: end})
+	}
+}
+
+// sourcePackageName returns the name in the package clause of the file with
+// tokens toks.
+func sourcePackageName(toks []sourceToken, src []byte) (string, bool) {
+	for i, t := range toks {
+		if t.tok == token.COMMENT {
+			continue
+		}
+		if t.tok != token.PACKAGE || i+1 >= len(toks) || toks[i+1].tok != token.IDENT {
+			return "", false
+		}
+		return string(src[toks[i+1].start:toks[i+1].end]), true
+	}
+	return "", false
+}
+
+// hasIgnoreConstraint reports whether src has an "ignore" build constraint
+// before its package clause, which excludes it from its package.
+func hasIgnoreConstraint(src []byte) bool {
+	for _, line := range strings.Split(string(src), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "package ") {
+			return false
+		}
+		for _, prefix := range []string{"//go:build ", "// +build "} {
+			if strings.HasPrefix(line, prefix) {
+				for _, f := range strings.Fields(strings.TrimPrefix(line, prefix)) {
+					if f == "ignore" {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// exportedDeclarations returns the exported functions, types, variables and
+// constants declared at the top level of the file with tokens toks. It also
+// returns the documentation anchors of the names of exported methods of
+// exported types, like "T.M", by token index.
+func exportedDeclarations(toks []sourceToken, src []byte) (names map[string]bool, anchors map[int]string) {
+	names = map[string]bool{}
+	anchors = map[int]string{}
+	text := func(i int) string { return string(src[toks[i].start:toks[i].end]) }
+	// next returns the index of the first token after i that isn't a comment.
+	next := func(i int) int {
+		for i++; i < len(toks) && toks[i].tok == token.COMMENT; i++ {
+		}
+		return i
+	}
+	// addSpec adds the names declared by the value or type spec starting at
+	// token i.
+	addSpec := func(i int) {
+		for i < len(toks) && toks[i].tok == token.IDENT {
+			if token.IsExported(text(i)) {
+				names[text(i)] = true
+			}
+			if i = next(i); i >= len(toks) || toks[i].tok != token.COMMA {
+				return
+			}
+			i = next(i)
+		}
+	}
+
+	depth := 0
+	for i := 0; i < len(toks); i++ {
+		switch toks[i].tok {
+		case token.LPAREN, token.LBRACK, token.LBRACE:
+			depth++
+			continue
+		case token.RPAREN, token.RBRACK, token.RBRACE:
+			depth--
+			continue
+		}
+		if depth != 0 {
+			continue
+		}
+		switch toks[i].tok {
+		case token.FUNC:
+			j := next(i)
+			if j >= len(toks) {
+				continue
+			}
+			if toks[j].tok == token.IDENT {
+				addSpec(j)
+				continue
+			}
+			if toks[j].tok != token.LPAREN {
+				continue
+			}
+			// A method: find the receiver type name, which is the last
+			// identifier in the receiver before any type parameters.
+			var recv string
+			d, inTypeParams := 0, false
+			for j < len(toks) {
+				switch toks[j].tok {
+				case token.LPAREN:
+					d++
+				case token.RPAREN:
+					d--
+				case token.LBRACK:
+					inTypeParams = true
+				case token.IDENT:
+					if !inTypeParams {
+						recv = text(j)
+					}
+				}
+				if d == 0 {
+					break
+				}
+				j++
+			}
+			if j = next(j); j < len(toks) && toks[j].tok == token.IDENT &&
+				token.IsExported(recv) && token.IsExported(text(j)) {
+				anchors[j] = recv + "." + text(j)
+			}
+		case token.TYPE, token.VAR, token.CONST:
+			j := next(i)
+			if j >= len(toks) || toks[j].tok != token.LPAREN {
+				addSpec(j)
+				continue
+			}
+			// A group: add the specs that start after the opening
+			// parenthesis or a semicolon at the group's top level.
+			d := 0
+			for ; j < len(toks); j++ {
+				switch toks[j].tok {
+				case token.LPAREN, token.LBRACK, token.LBRACE:
+					d++
+					if d == 1 {
+						addSpec(next(j))
+					}
+				case token.RPAREN, token.RBRACK, token.RBRACE:
+					d--
+				case token.SEMICOLON:
+					if d == 1 {
+						addSpec(next(j))
+					}
+				}
+				if d == 0 {
+					break
+				}
+			}
+			i = j
+		}
+	}
+	return names, anchors
+}
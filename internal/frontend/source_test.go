@@ -0,0 +1,158 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderSourceFile(t *testing.T) {
+	const src = `// Package p is a package.
+package p
+
+import "fmt"
+
+// Max is the maximum.
+const Max = 10
+
+const (
+	A, B = 1, 2
+	c    = 3
+)
+
+var (
+	Default = New(Max)
+	hidden  T
+)
+
+type T struct {
+	Name string
+}
+
+type unexported int
+
+// New returns a T.
+func New(n int) *T {
+	fmt.Println("<new>", A)
+	return &T{Name: fmt.Sprint(n)}
+}
+
+func (t *T) String() string { return t.Name }
+
+func (u unexported) Method() {}
+
+func helper() {}
+`
+	got, err := renderSourceFile([]byte(src), "example.com/m/p", "v1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	link := func(anchor, text string) string {
+		return `<a href="/example.com/m/p@v1.2.3#` + anchor + `">` + text + `</a>`
+	}
+	for _, want := range []string{
+		`<span id="L1"></span><span class="FileView-comment">// Package p is a package.</span>`,
+		`<span id="L2"></span><span class="FileView-keyword">package</span> p`,
+		`<span id="L7"></span><span class="FileView-keyword">const</span> ` + link("Max", "Max") + ` = <span class="FileView-number">10</span>`,
+		link("A", "A") + `, ` + link("B", "B") + ` = `,
+		`	c    = <span class="FileView-number">3</span>`,
+		link("Default", "Default") + ` = ` + link("New", "New") + `(` + link("Max", "Max") + `)`,
+		`hidden  ` + link("T", "T"),
+		`<span class="FileView-keyword">type</span> ` + link("T", "T") + ` <span class="FileView-keyword">struct</span>`,
+		`<span class="FileView-keyword">type</span> unexported int`,
+		`fmt.Println(<span class="FileView-string">&#34;&lt;new&gt;&#34;</span>, ` + link("A", "A") + `)`,
+		`fmt.Sprint(n)`,
+		`(t *` + link("T", "T") + `) ` + link("T.String", "String") + `() string`,
+		`t.Name }`,
+		`(u unexported) Method()`,
+		`<span class="FileView-keyword">func</span> helper()`,
+	} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("output does not contain %q", want)
+		}
+	}
+	if t.Failed() {
+		t.Logf("output:\n%s", got)
+	}
+}
+
+func TestRenderTextFile(t *testing.T) {
+	got := renderTextFile([]byte("a <b>\n\nc\n"))
+	const want = `<span id="L1"></span>a &lt;b&gt;
+<span id="L2"></span>
+<span id="L3"></span>c
+<span id="L4"></span>`
+	if string(got) != want {
+		t.Errorf("renderTextFile = %q, want %q", got, want)
+	}
+}
+
+func TestRenderSourceFileNotLinked(t *testing.T) {
+	for _, test := range []struct {
+		name, src, pkgPath string
+	}{
+		{"no package path", "package p\n\nfunc Exported() {}\n", ""},
+		{"command", "package main\n\nfunc Exported() {}\n", "example.com/p"},
+		{"external test", "package p_test\n\nfunc TestX(t *testing.T) {}\n", "example.com/p"},
+		{"ignored", "// +build ignore\n\npackage p\n\nfunc Exported() {}\n", "example.com/p"},
+		{"go:build ignored", "//go:build ignore\n\npackage p\n\nfunc Exported() {}\n", "example.com/p"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := renderSourceFile([]byte(test.src), test.pkgPath, "v1.0.0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(string(got), "<a ") {
+				t.Errorf("got links in %s", got)
+			}
+		})
+	}
+}
+
+func TestRenderSourceFileBuildConstraints(t *testing.T) {
+	const src = "// +build windows,!race\n\npackage p\n\nfunc Exported() {}\n"
+	got, err := renderSourceFile([]byte(src), "example.com/p", "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<span class="FileView-comment">// +build windows,!race</span>`,
+		`<a href="/example.com/p@v1.0.0#Exported">Exported</a>`,
+	} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("output does not contain %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestRenderSourceFileErrors(t *testing.T) {
+	// Syntax errors don't prevent rendering, and all the text is kept.
+	for _, src := range []string{
+		"package p\n\nfunc F( {\n\t\"unterminated\n}\n",
+		"package p\n\nvar X = `unterminated",
+		"package p\n\nfunc (",
+		"package p\n\ntype (",
+		"package p\n\n\x00\xff #$ <b>",
+	} {
+		got, err := renderSourceFile([]byte(src), "example.com/p", "v1.0.0")
+		if err != nil {
+			t.Errorf("renderSourceFile(%q): %v", src, err)
+			continue
+		}
+		if !strings.Contains(string(got), "package") {
+			t.Errorf("renderSourceFile(%q) = %q, want package clause", src, got)
+		}
+		if strings.Contains(string(got), "<b>") {
+			t.Errorf("renderSourceFile(%q) = %q, want escaped text", src, got)
+		}
+	}
+
+	for _, src := range []string{"", "not Go", "// just a comment\n"} {
+		if _, err := renderSourceFile([]byte(src), "example.com/p", "v1.0.0"); err == nil {
+			t.Errorf("renderSourceFile(%q): got nil error, want error", src)
+		}
+	}
+}
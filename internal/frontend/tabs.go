@@ -14,6 +14,7 @@ import (
 	"golang.org/x/pkgsite/internal"
 	"golang.org/x/pkgsite/internal/licenses"
 	"golang.org/x/pkgsite/internal/postgres"
+	"golang.org/x/pkgsite/internal/tracing"
 )
 
 // TabSettings defines tab-specific metadata.
@@ -43,6 +44,11 @@ var (
 			DisplayName:  "Doc",
 			TemplateName: "pkg_doc.tmpl",
 		},
+		{
+			Name:         "examples",
+			DisplayName:  "Examples",
+			TemplateName: "pkg_examples.tmpl",
+		},
 		{
 			Name:              "overview",
 			AlwaysShowDetails: true,
@@ -103,6 +109,12 @@ var (
 			DisplayName:       "Versions",
 			TemplateName:      "versions.tmpl",
 		},
+		{
+			Name:              "dependencies",
+			DisplayName:       "Dependencies",
+			AlwaysShowDetails: true,
+			TemplateName:      "mod_dependencies.tmpl",
+		},
 		{
 			Name:         "licenses",
 			DisplayName:  "Licenses",
@@ -143,9 +155,13 @@ func init() {
 // fetchDetailsForPackage returns tab details by delegating to the correct detail
 // handler.
 func fetchDetailsForPackage(ctx context.Context, r *http.Request, tab string, ds internal.DataSource, pkg *internal.LegacyVersionedPackage) (interface{}, error) {
+	ctx, span := tracing.StartSpan(ctx, "fetchDetailsForPackage")
+	defer span.End()
 	switch tab {
 	case "doc":
 		return fetchDocumentationDetails(pkg), nil
+	case "examples":
+		return fetchExamplesDetails(ctx, ds, pkg.Path, pkg.Version)
 	case "versions":
 		return fetchPackageVersionsDetails(ctx, ds, pkg.Path, pkg.V1Path, pkg.ModulePath)
 	case "subdirectories":
@@ -162,7 +178,21 @@ func fetchDetailsForPackage(ctx context.Context, r *http.Request, tab string, ds
 	case "licenses":
 		return fetchPackageLicensesDetails(ctx, ds, pkg.Path, pkg.ModulePath, pkg.Version)
 	case "overview":
-		return fetchPackageOverviewDetails(ctx, pkg, urlIsVersioned(r.URL)), nil
+		od := fetchPackageOverviewDetails(ctx, pkg, urlIsVersioned(r.URL))
+		if err := addSymbolDetails(ctx, ds, od, pkg.Path, pkg.Version, pkg.Facts); err != nil {
+			return nil, err
+		}
+		if err := addBuildContexts(ctx, ds, od, pkg.Path, pkg.Version); err != nil {
+			return nil, err
+		}
+		if err := addEmbeddedPatterns(ctx, ds, od, pkg.Path, pkg.Version); err != nil {
+			return nil, err
+		}
+		if err := addBenchmarkCount(ctx, ds, od, pkg.Path, pkg.Version); err != nil {
+			return nil, err
+		}
+		od.LicenseConflicts = pkg.Facts.LicenseConflicts
+		return od, nil
 	}
 	return nil, fmt.Errorf("BUG: unable to fetch details: unknown tab %q", tab)
 }
@@ -174,6 +204,8 @@ func fetchDetailsForVersionedDirectory(ctx context.Context, r *http.Request, tab
 	switch tab {
 	case "doc":
 		return fetchDocumentationDetailsNew(vdir.Package.Documentation), nil
+	case "examples":
+		return fetchExamplesDetails(ctx, ds, vdir.Path, vdir.Version)
 	case "versions":
 		return fetchPackageVersionsDetails(ctx, ds, vdir.Path, vdir.V1Path, vdir.ModulePath)
 	case "subdirectories":
@@ -190,7 +222,21 @@ func fetchDetailsForVersionedDirectory(ctx context.Context, r *http.Request, tab
 	case "licenses":
 		return fetchPackageLicensesDetails(ctx, ds, vdir.Path, vdir.ModulePath, vdir.Version)
 	case "overview":
-		return fetchPackageOverviewDetailsNew(ctx, vdir, urlIsVersioned(r.URL)), nil
+		od := fetchPackageOverviewDetailsNew(ctx, vdir, urlIsVersioned(r.URL))
+		if err := addSymbolDetails(ctx, ds, od, vdir.Path, vdir.Version, vdir.Package.Facts); err != nil {
+			return nil, err
+		}
+		if err := addBuildContexts(ctx, ds, od, vdir.Path, vdir.Version); err != nil {
+			return nil, err
+		}
+		if err := addEmbeddedPatterns(ctx, ds, od, vdir.Path, vdir.Version); err != nil {
+			return nil, err
+		}
+		if err := addBenchmarkCount(ctx, ds, od, vdir.Path, vdir.Version); err != nil {
+			return nil, err
+		}
+		od.LicenseConflicts = vdir.Package.Facts.LicenseConflicts
+		return od, nil
 	}
 	return nil, fmt.Errorf("BUG: unable to fetch details: unknown tab %q", tab)
 }
@@ -209,9 +255,20 @@ func fetchDetailsForModule(ctx context.Context, r *http.Request, tab string, ds
 		return &LicensesDetails{Licenses: transformLicenses(mi.ModulePath, mi.Version, licenses)}, nil
 	case "versions":
 		return fetchModuleVersionsDetails(ctx, ds, &mi.ModuleInfo)
+	case "dependencies":
+		db, ok := ds.(*postgres.DB)
+		if !ok {
+			// The proxydatasource does not support the dependencies page.
+			return nil, proxydatasourceNotSupportedErr()
+		}
+		return fetchDependenciesDetails(ctx, db, mi.ModulePath, mi.Version)
 	case "overview":
 		readme := &internal.Readme{Filepath: mi.LegacyReadmeFilePath, Contents: mi.LegacyReadmeContents}
-		return constructOverviewDetails(ctx, &mi.ModuleInfo, readme, mi.IsRedistributable, urlIsVersioned(r.URL)), nil
+		od := constructOverviewDetails(ctx, &mi.ModuleInfo, readme, mi.IsRedistributable, urlIsVersioned(r.URL))
+		if err := addContributorCount(ctx, ds, od, mi.ModulePath, mi.Version); err != nil {
+			return nil, err
+		}
+		return od, nil
 	}
 	return nil, fmt.Errorf("BUG: unable to fetch details: unknown tab %q", tab)
 }
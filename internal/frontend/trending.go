@@ -0,0 +1,101 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+
+	"golang.org/x/pkgsite/internal/postgres"
+)
+
+const (
+	// defaultTrendingPeriod is the period, in days, of the trending page when
+	// the request does not specify one.
+	defaultTrendingPeriod = 7
+	// trendingLimit is the number of packages on the trending page.
+	trendingLimit = 50
+)
+
+// TrendingPage contains data for the trending packages page.
+type TrendingPage struct {
+	basePage
+	// PeriodDays is the period over which growth is measured.
+	PeriodDays int
+	// Periods are the periods the page can be shown for.
+	Periods []int
+	Results []*TrendingResult
+}
+
+// TrendingResult is a package on the trending page.
+type TrendingResult struct {
+	*SearchResult
+	// NewImportedBy is how much NumImportedBy grew over the period.
+	NewImportedBy int
+}
+
+// handleTrending serves the packages whose imported-by counts grew the most
+// over a period. Handles endpoint /trending?period=<days>.
+func (s *Server) handleTrending(w http.ResponseWriter, r *http.Request) error {
+	db, ok := s.ds.(*postgres.DB)
+	if !ok {
+		// The proxydatasource does not support the trending page.
+		return proxydatasourceNotSupportedErr()
+	}
+	period := defaultTrendingPeriod
+	if p := r.FormValue("period"); p != "" {
+		var err error
+		period, err = strconv.Atoi(p)
+		if err != nil || !postgres.IsTrendingPeriod(period) {
+			return &serverError{
+				status: http.StatusBadRequest,
+				err:    fmt.Errorf("invalid period %q", p),
+				epage: &errorPage{
+					Message:          fmt.Sprintf("%q is not a supported period.", p),
+					SecondaryMessage: template.HTML(fmt.Sprintf("Supported periods are %v days.", postgres.TrendingPeriods)),
+				},
+			}
+		}
+	}
+	ctx := r.Context()
+	page, err := fetchTrendingPage(ctx, db, period)
+	if err != nil {
+		return err
+	}
+	page.basePage = s.newBasePage(r, "Trending Packages")
+	s.servePage(ctx, w, "trending.tmpl", page)
+	return nil
+}
+
+// fetchTrendingPage returns the TrendingPage for period.
+func fetchTrendingPage(ctx context.Context, db *postgres.DB, period int) (*TrendingPage, error) {
+	dbresults, err := db.GetTrendingPackages(ctx, period, trendingLimit)
+	if err != nil {
+		return nil, err
+	}
+	page := &TrendingPage{
+		PeriodDays: period,
+		Periods:    postgres.TrendingPeriods,
+	}
+	for _, r := range dbresults {
+		page.Results = append(page.Results, &TrendingResult{
+			SearchResult: &SearchResult{
+				Name:           r.Name,
+				PackagePath:    r.PackagePath,
+				ModulePath:     r.ModulePath,
+				Synopsis:       r.Synopsis,
+				DisplayVersion: displayVersion(r.Version, r.ModulePath),
+				Licenses:       r.Licenses,
+				CommitTime:     elapsedTime(r.CommitTime),
+				NumImportedBy:  r.NumImportedBy,
+			},
+			NewImportedBy: int(r.Score),
+		})
+	}
+	return page, nil
+}
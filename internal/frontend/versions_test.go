@@ -55,6 +55,7 @@ func versionSummaries(path string, versions []string, linkify func(path, version
 }
 
 func TestFetchModuleVersionDetails(t *testing.T) {
+	skipIfNoDB(t)
 	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
 	defer cancel()
 
@@ -153,6 +154,7 @@ func TestFetchModuleVersionDetails(t *testing.T) {
 }
 
 func TestFetchPackageVersionsDetails(t *testing.T) {
+	skipIfNoDB(t)
 	ctx, cancel := context.WithTimeout(context.Background(), testTimeout*2)
 	defer cancel()
 
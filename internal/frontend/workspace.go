@@ -0,0 +1,112 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+// workspacePathPrefix is the path prefix of the workspace page.
+const workspacePathPrefix = "/workspace/"
+
+// WorkspacePage contains data for the page that shows the packages of all the
+// modules of a workspace.
+type WorkspacePage struct {
+	basePage
+	RepoPath  string
+	GoVersion string
+	Toolchain string
+	Modules   []*WorkspaceModule
+}
+
+// WorkspaceModule is a module of a workspace, along with its packages.
+type WorkspaceModule struct {
+	ModulePath string
+	// Module is the latest version of the module, or nil if the module has
+	// not been indexed.
+	Module   *Module
+	Packages []*Package
+}
+
+// handleWorkspace handles requests for /workspace/<repo-path>, by serving a
+// page that lists the packages of the latest versions of the modules in the
+// workspace declared by the go.work file at the root of the repository.
+func (s *Server) handleWorkspace(w http.ResponseWriter, r *http.Request) (err error) {
+	repoPath := strings.Trim(strings.TrimPrefix(r.URL.Path, workspacePathPrefix), "/")
+	defer derrors.Wrap(&err, "handleWorkspace(w, r[%q])", repoPath)
+
+	if err := module.CheckPath(repoPath); err != nil {
+		return &serverError{
+			status: http.StatusBadRequest,
+			err:    err,
+			epage:  &errorPage{Message: fmt.Sprintf("%q is not a valid repository path.", repoPath)},
+		}
+	}
+	ctx := r.Context()
+	ws, err := s.ds.GetWorkspace(ctx, repoPath)
+	if err != nil && !errors.Is(err, derrors.NotFound) {
+		return err
+	}
+	if ws == nil {
+		return &serverError{
+			status: http.StatusNotFound,
+			err:    err,
+			epage:  &errorPage{Message: fmt.Sprintf("No go.work file was found for %s.", repoPath)},
+		}
+	}
+	page := &WorkspacePage{
+		basePage:  s.newBasePage(r, fmt.Sprintf("Workspace %s", repoPath)),
+		RepoPath:  repoPath,
+		GoVersion: ws.GoVersion,
+		Toolchain: ws.Toolchain,
+	}
+	for _, modulePath := range ws.ModulePaths {
+		wm, err := fetchWorkspaceModule(ctx, s.ds, modulePath)
+		if err != nil {
+			return err
+		}
+		page.Modules = append(page.Modules, wm)
+	}
+	s.servePage(ctx, w, "workspace.tmpl", page)
+	return nil
+}
+
+// fetchWorkspaceModule returns the latest version of the module with the
+// given path and its packages, sorted by path.
+func fetchWorkspaceModule(ctx context.Context, ds internal.DataSource, modulePath string) (_ *WorkspaceModule, err error) {
+	defer derrors.Wrap(&err, "fetchWorkspaceModule(ctx, ds, %q)", modulePath)
+
+	wm := &WorkspaceModule{ModulePath: modulePath}
+	mi, err := ds.LegacyGetModuleInfo(ctx, modulePath, internal.LatestVersion)
+	if errors.Is(err, derrors.NotFound) {
+		return wm, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	wm.Module = createModule(&mi.ModuleInfo, nil, true)
+	pkgs, err := ds.LegacyGetPackagesInModule(ctx, modulePath, mi.Version)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range pkgs {
+		pkg, err := legacyCreatePackage(p, &mi.ModuleInfo, true)
+		if err != nil {
+			return nil, err
+		}
+		wm.Packages = append(wm.Packages, pkg)
+	}
+	sort.Slice(wm.Packages, func(i, j int) bool { return wm.Packages[i].Path < wm.Packages[j].Path })
+	return wm, nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package frontend
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/testing/sample"
+)
+
+// workspaceDataSource is a DataSource with a single workspace, whose modules
+// are the ones in modules.
+type workspaceDataSource struct {
+	internal.DataSource
+	workspace *internal.Workspace
+	modules   map[string]*internal.Module
+}
+
+func (ds workspaceDataSource) GetWorkspace(ctx context.Context, repoPath string) (*internal.Workspace, error) {
+	if repoPath != ds.workspace.RepoPath {
+		return nil, derrors.NotFound
+	}
+	return ds.workspace, nil
+}
+
+func (ds workspaceDataSource) LegacyGetModuleInfo(ctx context.Context, modulePath, version string) (*internal.LegacyModuleInfo, error) {
+	m, ok := ds.modules[modulePath]
+	if !ok {
+		return nil, derrors.NotFound
+	}
+	return &m.LegacyModuleInfo, nil
+}
+
+func (ds workspaceDataSource) LegacyGetPackagesInModule(ctx context.Context, modulePath, version string) ([]*internal.LegacyPackage, error) {
+	return ds.modules[modulePath].LegacyPackages, nil
+}
+
+func TestHandleWorkspace(t *testing.T) {
+	ds := workspaceDataSource{
+		workspace: &internal.Workspace{
+			RepoPath:    "github.com/work/space",
+			GoVersion:   "1.21.0",
+			Toolchain:   "go1.21.3",
+			ModulePaths: []string{"github.com/work/space", "github.com/work/space/tools", "github.com/work/space/new"},
+		},
+		modules: map[string]*internal.Module{
+			"github.com/work/space":       sample.Module("github.com/work/space", "v1.2.0", "a", "b"),
+			"github.com/work/space/tools": sample.Module("github.com/work/space/tools", "v0.1.0", "cmd"),
+		},
+	}
+	s, err := NewServer(ServerConfig{DataSource: ds, StaticPath: "../../content/static"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := s.errorHandler(s.handleWorkspace)
+	for _, test := range []struct {
+		name, path string
+		wantStatus int
+		want       []string
+	}{
+		{
+			name:       "ok",
+			path:       "/workspace/github.com/work/space",
+			wantStatus: http.StatusOK,
+			want: []string{
+				"3 modules | go 1.21.0 | toolchain go1.21.3",
+				`<a href="/mod/github.com/work/space">github.com/work/space</a>`,
+				`<a href="/github.com/work/space/a">github.com/work/space/a</a>`,
+				`<a href="/github.com/work/space/b">github.com/work/space/b</a>`,
+				`<a href="/github.com/work/space/tools/cmd">github.com/work/space/tools/cmd</a>`,
+				"<h2>github.com/work/space/new</h2>",
+				"This module has not been indexed.",
+			},
+		},
+		{
+			name:       "no workspace",
+			path:       "/workspace/github.com/other/repo",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "invalid path",
+			path:       "/workspace/nodot/path",
+			wantStatus: http.StatusBadRequest,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+			if w.Code != test.wantStatus {
+				t.Fatalf("GET %q = %d, want %d", test.path, w.Code, test.wantStatus)
+			}
+			body := w.Body.String()
+			for _, want := range test.want {
+				if !strings.Contains(body, want) {
+					t.Errorf("GET %q: body does not contain %q", test.path, want)
+				}
+			}
+		})
+	}
+}
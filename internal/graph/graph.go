@@ -0,0 +1,62 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package graph renders graphs in the DOT language of Graphviz.
+package graph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A Node is a node of a graph.
+type Node struct {
+	// ID identifies the node in edges. It must be unique in the graph.
+	ID string
+	// Label is the text of the node. If it is empty, ID is used.
+	Label string
+	// URL, if not empty, is the link of the node.
+	URL string
+	// Color, if not empty, is the color of the node, like "red".
+	Color string
+}
+
+// An Edge is a directed edge between the nodes with the IDs From and To.
+type Edge struct {
+	From, To string
+}
+
+// ToDOT returns the DOT source of the directed graph with the given nodes and
+// edges, in that order.
+func ToDOT(nodes []Node, edges []Edge) string {
+	var b strings.Builder
+	b.WriteString("digraph {\n")
+	for _, n := range nodes {
+		label := n.Label
+		if label == "" {
+			label = n.ID
+		}
+		attrs := []string{"label=" + quote(label)}
+		if n.URL != "" {
+			attrs = append(attrs, "URL="+quote(n.URL))
+		}
+		if n.Color != "" {
+			attrs = append(attrs, "color="+quote(n.Color))
+		}
+		fmt.Fprintf(&b, "\t%s [%s];\n", quote(n.ID), strings.Join(attrs, ", "))
+	}
+	for _, e := range edges {
+		fmt.Fprintf(&b, "\t%s -> %s;\n", quote(e.From), quote(e.To))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// quoteEscaper escapes the characters that are special in DOT strings.
+var quoteEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// quote returns s as a DOT double-quoted string.
+func quote(s string) string {
+	return `"` + quoteEscaper.Replace(s) + `"`
+}
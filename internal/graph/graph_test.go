@@ -0,0 +1,37 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package graph
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestToDOT(t *testing.T) {
+	nodes := []Node{
+		{ID: "example.com/a@v1.0.0", URL: "/mod/example.com/a@v1.0.0"},
+		{ID: "example.com/b@v1.2.0", Label: "b", URL: "/mod/example.com/b@v1.2.0", Color: "red"},
+		{ID: `weird"name\`},
+	}
+	edges := []Edge{
+		{From: "example.com/a@v1.0.0", To: "example.com/b@v1.2.0"},
+		{From: "example.com/b@v1.2.0", To: `weird"name\`},
+	}
+	want := `digraph {
+	"example.com/a@v1.0.0" [label="example.com/a@v1.0.0", URL="/mod/example.com/a@v1.0.0"];
+	"example.com/b@v1.2.0" [label="b", URL="/mod/example.com/b@v1.2.0", color="red"];
+	"weird\"name\\" [label="weird\"name\\"];
+	"example.com/a@v1.0.0" -> "example.com/b@v1.2.0";
+	"example.com/b@v1.2.0" -> "weird\"name\\";
+}
+`
+	if diff := cmp.Diff(want, ToDOT(nodes, edges)); diff != "" {
+		t.Errorf("ToDOT mismatch (-want +got):\n%s", diff)
+	}
+	if got, want := ToDOT(nil, nil), "digraph {\n}\n"; got != want {
+		t.Errorf("ToDOT(nil, nil) = %q, want %q", got, want)
+	}
+}
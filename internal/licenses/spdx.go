@@ -0,0 +1,258 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package licenses
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+// spdxIDs are the SPDX 2.3 license identifiers that NormalizeSPDX returns.
+// See https://spdx.org/licenses/.
+var spdxIDs = []string{
+	"0BSD",
+	"AGPL-3.0-only",
+	"AGPL-3.0-or-later",
+	"Apache-2.0",
+	"Artistic-2.0",
+	"BlueOak-1.0.0",
+	"BSD-2-Clause",
+	"BSD-2-Clause-Views",
+	"BSD-3-Clause",
+	"BSL-1.0",
+	"CC-BY-3.0",
+	"CC-BY-4.0",
+	"CC-BY-SA-3.0",
+	"CC-BY-SA-4.0",
+	"CC0-1.0",
+	"EPL-1.0",
+	"EPL-2.0",
+	"GPL-2.0-only",
+	"GPL-2.0-or-later",
+	"GPL-3.0-only",
+	"GPL-3.0-or-later",
+	"ISC",
+	"JSON",
+	"LGPL-2.1-only",
+	"LGPL-2.1-or-later",
+	"LGPL-3.0-only",
+	"LGPL-3.0-or-later",
+	"MIT",
+	"MIT-0",
+	"MPL-2.0",
+	"NCSA",
+	"OpenSSL",
+	"OSL-3.0",
+	"Unlicense",
+	"Zlib",
+}
+
+// spdxAliases maps the spdxKeys of common names of licenses, including the
+// license types reported by licensecheck, to SPDX identifiers. The keys of
+// the identifiers themselves need not be listed.
+var spdxAliases = map[string]string{
+	"apl2":                  "Apache-2.0",
+	"asl2":                  "Apache-2.0",
+	"apachesoftware2":       "Apache-2.0",
+	"boostsoftware1":        "BSL-1.0",
+	"cc0":                   "CC0-1.0",
+	"bsd0clause":            "0BSD",
+	"zeroclausebsd":         "0BSD",
+	"bsd2":                  "BSD-2-Clause",
+	"simplifiedbsd":         "BSD-2-Clause",
+	"bsd2clausefreebsd":     "BSD-2-Clause-Views",
+	"bsd3":                  "BSD-3-Clause",
+	"newbsd":                "BSD-3-Clause",
+	"modifiedbsd":           "BSD-3-Clause",
+	"revisedbsd":            "BSD-3-Clause",
+	"eclipsepublic1":        "EPL-1.0",
+	"eclipsepublic2":        "EPL-2.0",
+	"gpl2":                  "GPL-2.0-only",
+	"gpl2orlater":           "GPL-2.0-or-later",
+	"generalpublic2":        "GPL-2.0-only",
+	"gpl3":                  "GPL-3.0-only",
+	"gpl3orlater":           "GPL-3.0-or-later",
+	"generalpublic3":        "GPL-3.0-only",
+	"agpl3":                 "AGPL-3.0-only",
+	"agpl3orlater":          "AGPL-3.0-or-later",
+	"afferogeneralpublic3":  "AGPL-3.0-only",
+	"lgpl21":                "LGPL-2.1-only",
+	"lgpl21orlater":         "LGPL-2.1-or-later",
+	"lessergeneralpublic21": "LGPL-2.1-only",
+	"lgpl3":                 "LGPL-3.0-only",
+	"lgpl3orlater":          "LGPL-3.0-or-later",
+	"lessergeneralpublic3":  "LGPL-3.0-only",
+	"expat":                 "MIT",
+	"mit0":                  "MIT-0",
+	"mozillapublic2":        "MPL-2.0",
+}
+
+var (
+	// spdxByKey maps the spdxKeys of the identifiers in spdxIDs and
+	// spdxAliases to their identifiers.
+	spdxByKey = map[string]string{}
+
+	spdxNoise        = regexp.MustCompile(`\b(the|gnu|licen[cs]e|version)\b|v(\d)`)
+	spdxNonAlnum     = regexp.MustCompile(`[^a-z0-9]+`)
+	spdxZeroDecimals = regexp.MustCompile(`(\d)(\.0)+\b`)
+)
+
+func init() {
+	for _, id := range spdxIDs {
+		spdxByKey[spdxKey(id)] = id
+	}
+	for k, id := range spdxAliases {
+		spdxByKey[k] = id
+	}
+}
+
+// spdxKey returns the key of the license name s, ignoring case, punctuation,
+// noise words like "License" and "GNU", a "v" before a version number and
+// trailing zero components of version numbers. So "Apache-2.0", "Apache 2"
+// and "the Apache License, Version 2.0" have the same key.
+func spdxKey(s string) string {
+	s = strings.ToLower(s)
+	s = strings.ReplaceAll(s, "+", " or later")
+	s = spdxZeroDecimals.ReplaceAllString(s, "$1")
+	s = spdxNoise.ReplaceAllString(s, "$2")
+	return spdxNonAlnum.ReplaceAllString(s, "")
+}
+
+// NormalizeSPDX returns the SPDX identifier of the license with the given
+// name, which may be a license type reported by licensecheck, an SPDX
+// identifier in any case, or one of the common variants of the name of a
+// license, like "Apache 2" or "APL2". It returns an error wrapping
+// derrors.NotFound if the license is not recognized.
+//
+// GPL licenses without an "or later" qualifier, like licensecheck's "GPL2",
+// are assumed to be the "-only" variants.
+func NormalizeSPDX(raw string) (_ string, err error) {
+	defer derrors.Wrap(&err, "NormalizeSPDX(%q)", raw)
+
+	if id, ok := spdxByKey[spdxKey(raw)]; ok {
+		return id, nil
+	}
+	return "", derrors.NotFound
+}
+
+// Compatibility describes whether code under two licenses can be combined
+// in one program.
+type Compatibility int
+
+const (
+	// Unknown means the compatibility of the licenses is not known.
+	Unknown Compatibility = iota
+	// Compatible means the licenses are compatible.
+	Compatible
+	// Incompatible means the licenses are incompatible.
+	Incompatible
+)
+
+func (c Compatibility) String() string {
+	switch c {
+	case Compatible:
+		return "Compatible"
+	case Incompatible:
+		return "Incompatible"
+	default:
+		return "Unknown"
+	}
+}
+
+// permissiveSPDX and copyleftSPDX are the licenses that the compatibility
+// matrix knows about. Permissive licenses are compatible with each other and
+// with copyleft licenses, except as listed in spdxCompatibility.
+var (
+	permissiveSPDX = map[string]bool{
+		"0BSD":          true,
+		"Apache-2.0":    true,
+		"BlueOak-1.0.0": true,
+		"BSD-2-Clause":  true,
+		"BSD-3-Clause":  true,
+		"BSL-1.0":       true,
+		"CC0-1.0":       true,
+		"ISC":           true,
+		"MIT":           true,
+		"MIT-0":         true,
+		"NCSA":          true,
+		"Unlicense":     true,
+		"Zlib":          true,
+	}
+	copyleftSPDX = map[string]bool{
+		"AGPL-3.0-only":    true,
+		"EPL-1.0":          true,
+		"EPL-2.0":          true,
+		"GPL-2.0-only":     true,
+		"GPL-2.0-or-later": true,
+		"GPL-3.0-only":     true,
+		"LGPL-2.1-only":    true,
+		"LGPL-3.0-only":    true,
+		"MPL-2.0":          true,
+	}
+)
+
+// spdxCompatibility holds the exceptions to the rule for permissive licenses,
+// and the compatibility of pairs of copyleft licenses. Each pair is listed
+// once, with its licenses in either order.
+var spdxCompatibility = map[[2]string]Compatibility{
+	{"Apache-2.0", "GPL-2.0-only"}:  Incompatible,
+	{"Apache-2.0", "LGPL-2.1-only"}: Incompatible,
+
+	{"AGPL-3.0-only", "GPL-2.0-only"}:     Incompatible,
+	{"AGPL-3.0-only", "GPL-2.0-or-later"}: Compatible,
+	{"AGPL-3.0-only", "GPL-3.0-only"}:     Compatible,
+	{"AGPL-3.0-only", "LGPL-2.1-only"}:    Compatible,
+	{"AGPL-3.0-only", "LGPL-3.0-only"}:    Compatible,
+	{"AGPL-3.0-only", "MPL-2.0"}:          Compatible,
+	{"EPL-1.0", "GPL-2.0-only"}:           Incompatible,
+	{"EPL-1.0", "GPL-2.0-or-later"}:       Incompatible,
+	{"EPL-1.0", "GPL-3.0-only"}:           Incompatible,
+	{"EPL-2.0", "GPL-3.0-only"}:           Incompatible,
+	{"GPL-2.0-only", "GPL-2.0-or-later"}:  Compatible,
+	{"GPL-2.0-only", "GPL-3.0-only"}:      Incompatible,
+	{"GPL-2.0-only", "LGPL-2.1-only"}:     Compatible,
+	{"GPL-2.0-only", "LGPL-3.0-only"}:     Incompatible,
+	{"GPL-2.0-only", "MPL-2.0"}:           Compatible,
+	{"GPL-2.0-or-later", "GPL-3.0-only"}:  Compatible,
+	{"GPL-2.0-or-later", "LGPL-2.1-only"}: Compatible,
+	{"GPL-2.0-or-later", "LGPL-3.0-only"}: Compatible,
+	{"GPL-2.0-or-later", "MPL-2.0"}:       Compatible,
+	{"GPL-3.0-only", "LGPL-2.1-only"}:     Compatible,
+	{"GPL-3.0-only", "LGPL-3.0-only"}:     Compatible,
+	{"GPL-3.0-only", "MPL-2.0"}:           Compatible,
+	{"LGPL-2.1-only", "LGPL-3.0-only"}:    Compatible,
+	{"LGPL-2.1-only", "MPL-2.0"}:          Compatible,
+	{"LGPL-3.0-only", "MPL-2.0"}:          Compatible,
+}
+
+// GetLicenseCompatibility reports whether code under the licenses a and b,
+// which are normalized with NormalizeSPDX, can be combined in one program,
+// according to a hard-coded compatibility matrix. The result is Unknown if
+// either license is not recognized or not in the matrix.
+//
+// The matrix is a guide for readers, not legal advice.
+func GetLicenseCompatibility(a, b string) Compatibility {
+	a, errA := NormalizeSPDX(a)
+	b, errB := NormalizeSPDX(b)
+	if errA != nil || errB != nil {
+		return Unknown
+	}
+	if a == b {
+		return Compatible
+	}
+	if c, ok := spdxCompatibility[[2]string{a, b}]; ok {
+		return c
+	}
+	if c, ok := spdxCompatibility[[2]string{b, a}]; ok {
+		return c
+	}
+	if permissiveSPDX[a] && (permissiveSPDX[b] || copyleftSPDX[b]) ||
+		permissiveSPDX[b] && copyleftSPDX[a] {
+		return Compatible
+	}
+	return Unknown
+}
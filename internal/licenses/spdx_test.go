@@ -0,0 +1,114 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package licenses
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+func TestNormalizeSPDX(t *testing.T) {
+	for _, test := range []struct {
+		raw, want string
+	}{
+		{"MIT", "MIT"},
+		{"mit", "MIT"},
+		{"MIT License", "MIT"},
+		{"Expat", "MIT"},
+		{"MIT-0", "MIT-0"},
+		{"Apache-2.0", "Apache-2.0"},
+		{"Apache 2", "Apache-2.0"},
+		{"apache2", "Apache-2.0"},
+		{"APL2", "Apache-2.0"},
+		{"ASL 2.0", "Apache-2.0"},
+		{"Apache License, Version 2.0", "Apache-2.0"},
+		{"BSD-2-Clause", "BSD-2-Clause"},
+		{"Simplified BSD", "BSD-2-Clause"},
+		{"BSD-2-Clause-FreeBSD", "BSD-2-Clause-Views"},
+		{"bsd-3-clause", "BSD-3-Clause"},
+		{"BSD 3-Clause License", "BSD-3-Clause"},
+		{"New BSD License", "BSD-3-Clause"},
+		{"BSD-0-Clause", "0BSD"},
+		{"0BSD", "0BSD"},
+		{"GPL2", "GPL-2.0-only"},
+		{"GPLv2", "GPL-2.0-only"},
+		{"GPL-2.0+", "GPL-2.0-or-later"},
+		{"GPL-2.0-or-later", "GPL-2.0-or-later"},
+		{"GPL3", "GPL-3.0-only"},
+		{"GNU General Public License v3.0", "GPL-3.0-only"},
+		{"AGPL-3.0", "AGPL-3.0-only"},
+		{"AGPLv3", "AGPL-3.0-only"},
+		{"LGPL-2.1", "LGPL-2.1-only"},
+		{"GNU Lesser General Public License v2.1", "LGPL-2.1-only"},
+		{"LGPL-3.0", "LGPL-3.0-only"},
+		{"MPL 2.0", "MPL-2.0"},
+		{"Mozilla Public License 2.0", "MPL-2.0"},
+		{"EPL-1.0", "EPL-1.0"},
+		{"Eclipse Public License 2.0", "EPL-2.0"},
+		{"ISC", "ISC"},
+		{"isc license", "ISC"},
+		{"BSL-1.0", "BSL-1.0"},
+		{"Boost Software License 1.0", "BSL-1.0"},
+		{"BlueOak-1.0", "BlueOak-1.0.0"},
+		{"CC0", "CC0-1.0"},
+		{"CC0-1.0", "CC0-1.0"},
+		{"CC-BY-SA-4.0", "CC-BY-SA-4.0"},
+		{"The Unlicense", "Unlicense"},
+		{"zlib", "Zlib"},
+		{"OpenSSL", "OpenSSL"},
+	} {
+		got, err := NormalizeSPDX(test.raw)
+		if err != nil {
+			t.Errorf("NormalizeSPDX(%q): %v", test.raw, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("NormalizeSPDX(%q) = %q, want %q", test.raw, got, test.want)
+		}
+	}
+
+	for _, raw := range []string{"", "BSD", "Apache", "GPL", "proprietary", "GooglePatentClause"} {
+		if got, err := NormalizeSPDX(raw); !errors.Is(err, derrors.NotFound) {
+			t.Errorf("NormalizeSPDX(%q) = %q, %v, want NotFound", raw, got, err)
+		}
+	}
+
+	// Every identifier normalizes to itself.
+	for _, id := range spdxIDs {
+		if got, err := NormalizeSPDX(id); err != nil || got != id {
+			t.Errorf("NormalizeSPDX(%q) = %q, %v", id, got, err)
+		}
+	}
+}
+
+func TestGetLicenseCompatibility(t *testing.T) {
+	for _, test := range []struct {
+		a, b string
+		want Compatibility
+	}{
+		{"MIT", "MIT", Compatible},
+		{"MIT", "BSD-3-Clause", Compatible},
+		{"Apache 2", "MIT", Compatible},
+		{"MIT", "GPL3", Compatible},
+		{"GPL3", "MIT", Compatible},
+		{"Apache-2.0", "GPL-3.0", Compatible},
+		{"Apache-2.0", "GPL2", Incompatible},
+		{"GPL2", "Apache-2.0", Incompatible},
+		{"GPL2", "GPL3", Incompatible},
+		{"GPL-2.0+", "GPL3", Compatible},
+		{"AGPL-3.0", "GPL3", Compatible},
+		{"EPL-1.0", "GPL3", Incompatible},
+		{"MPL-2.0", "GPL2", Compatible},
+		{"EPL-2.0", "MPL-2.0", Unknown},
+		{"MIT", "JSON", Unknown},
+		{"MIT", "proprietary", Unknown},
+	} {
+		if got := GetLicenseCompatibility(test.a, test.b); got != test.want {
+			t.Errorf("GetLicenseCompatibility(%q, %q) = %v, want %v", test.a, test.b, got, test.want)
+		}
+	}
+}
@@ -146,6 +146,11 @@ func Infof(ctx context.Context, format string, args ...interface{}) {
 	logf(ctx, logging.Info, format, args)
 }
 
+// Warningf logs a formatted string at the Warning level.
+func Warningf(ctx context.Context, format string, args ...interface{}) {
+	logf(ctx, logging.Warning, format, args)
+}
+
 // Errorf logs a formatted string at the Error level.
 func Errorf(ctx context.Context, format string, args ...interface{}) {
 	logf(ctx, logging.Error, format, args)
@@ -169,6 +174,9 @@ func logf(ctx context.Context, s logging.Severity, format string, args []interfa
 // Info logs arg, which can be a string or a struct, at the Info level.
 func Info(ctx context.Context, arg interface{}) { doLog(ctx, logging.Info, arg) }
 
+// Warning logs arg, which can be a string or a struct, at the Warning level.
+func Warning(ctx context.Context, arg interface{}) { doLog(ctx, logging.Warning, arg) }
+
 // Error logs arg, which can be a string or a struct, at the Error level.
 func Error(ctx context.Context, arg interface{}) { doLog(ctx, logging.Error, arg) }
 
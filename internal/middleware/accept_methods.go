@@ -4,14 +4,27 @@
 
 package middleware
 
-import "net/http"
+import (
+	"net/http"
+	"strings"
+)
 
 // AcceptMethods serves 405 (Method Not Allowed) for any method not on the given list.
 func AcceptMethods(methods ...string) Middleware {
+	return AcceptPathMethods(nil, methods...)
+}
+
+// AcceptPathMethods is like AcceptMethods, but also accepts the methods of
+// pathMethods[prefix] for requests whose URL paths begin with prefix.
+func AcceptPathMethods(pathMethods map[string][]string, methods ...string) Middleware {
 	return func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			for _, m := range methods {
-				if r.Method == m {
+			if containsMethod(methods, r.Method) {
+				h.ServeHTTP(w, r)
+				return
+			}
+			for prefix, ms := range pathMethods {
+				if strings.HasPrefix(r.URL.Path, prefix) && containsMethod(ms, r.Method) {
 					h.ServeHTTP(w, r)
 					return
 				}
@@ -20,3 +33,12 @@ func AcceptMethods(methods ...string) Middleware {
 		})
 	}
 }
+
+func containsMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
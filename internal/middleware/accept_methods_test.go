@@ -52,3 +52,26 @@ func TestAcceptMethods(t *testing.T) {
 		}
 	}
 }
+
+func TestAcceptPathMethods(t *testing.T) {
+	mw := AcceptPathMethods(map[string][]string{"/api/": {"OPTIONS"}}, "GET")
+	h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, test := range []struct {
+		method, path string
+		wantCode     int
+	}{
+		{"GET", "/example.com/p", http.StatusOK},
+		{"GET", "/api/v1/packages/example.com/p", http.StatusOK},
+		{"OPTIONS", "/api/v1/packages/example.com/p", http.StatusOK},
+		{"OPTIONS", "/example.com/p", http.StatusMethodNotAllowed},
+		{"HEAD", "/api/v1/packages/example.com/p", http.StatusMethodNotAllowed},
+		{"POST", "/api/v1/packages/example.com/p", http.StatusMethodNotAllowed},
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(test.method, test.path, nil))
+		if w.Code != test.wantCode {
+			t.Errorf("%s %s: got code %d, want %d", test.method, test.path, w.Code, test.wantCode)
+		}
+	}
+}
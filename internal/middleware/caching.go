@@ -18,6 +18,7 @@ import (
 	"go.opencensus.io/stats"
 	"go.opencensus.io/stats/view"
 	"go.opencensus.io/tag"
+	"golang.org/x/pkgsite/internal/experiment"
 	"golang.org/x/pkgsite/internal/log"
 )
 
@@ -114,7 +115,13 @@ func (c *cache) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	ctx := r.Context()
-	key := r.URL.String()
+	// The cache does not store response headers, so only responses in the
+	// default format, whose content type can be sniffed, are cached.
+	if FormatFromContext(ctx) != FormatHTML {
+		c.delegate.ServeHTTP(w, r)
+		return
+	}
+	key := cacheKey(r)
 	if reader, ok := c.get(ctx, key); ok {
 		recordCacheResult(ctx, c.name, true)
 		if _, err := io.Copy(w, reader); err != nil {
@@ -135,6 +142,17 @@ func (c *cache) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// cacheKey returns the key of the cached response to r. Requests in
+// different experiment buckets may get different responses, so the bucket is
+// part of the key.
+func cacheKey(r *http.Request) string {
+	key := r.URL.String()
+	if b := experiment.Bucket(r.Context()); b != "" {
+		key += " bucket=" + b
+	}
+	return key
+}
+
 func (c *cache) get(ctx context.Context, key string) (io.Reader, bool) {
 	// Set a short timeout for redis requests, so that we can quickly
 	// fall back to un-cached serving if redis is unavailable.
@@ -17,6 +17,7 @@ import (
 	"github.com/go-redis/redis/v7"
 	"github.com/google/go-cmp/cmp"
 	"go.opencensus.io/stats/view"
+	"golang.org/x/pkgsite/internal/experiment"
 )
 
 func TestCache(t *testing.T) {
@@ -177,3 +178,13 @@ func TestCache(t *testing.T) {
 		}
 	}
 }
+
+func TestCacheKey(t *testing.T) {
+	r := httptest.NewRequest("GET", "/search?q=json", nil)
+	plain := cacheKey(r)
+	a := cacheKey(r.WithContext(experiment.NewBucketContext(r.Context(), "a")))
+	b := cacheKey(r.WithContext(experiment.NewBucketContext(r.Context(), "b")))
+	if plain == a || plain == b || a == b {
+		t.Errorf("got keys %q, %q and %q, want them to differ", plain, a, b)
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import "net/http"
+
+// corsAllowedMethods are the methods that cross-origin requests may use. They
+// must be accepted for the routes that CORS is applied to; see
+// AcceptPathMethods.
+const corsAllowedMethods = "GET, OPTIONS"
+
+// CORS allows browsers to make cross-origin requests from the given origins.
+// If the Origin header of a request is one of allowedOrigins, CORS sets
+// Access-Control-Allow-Origin to it. It serves preflight (OPTIONS) requests
+// itself, with a 204 (No Content).
+func CORS(allowedOrigins []string) Middleware {
+	allowed := map[string]bool{}
+	for _, o := range allowedOrigins {
+		allowed[o] = true
+	}
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// The response depends on the Origin header, so it mustn't
+			// be cached for other origins.
+			w.Header().Add("Vary", "Origin")
+			origin := r.Header.Get("Origin")
+			if allowed[origin] {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Set("Access-Control-Allow-Methods", corsAllowedMethods)
+			}
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORS(t *testing.T) {
+	mw := CORS([]string{"https://example.com", "https://example.org"})
+	var called bool
+	h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.Write([]byte("ok"))
+	}))
+
+	for _, test := range []struct {
+		name, method, origin string
+		wantStatus           int
+		wantOrigin           string
+		wantCalled           bool
+	}{
+		{"allowed", http.MethodGet, "https://example.com", http.StatusOK, "https://example.com", true},
+		{"other allowed", http.MethodGet, "https://example.org", http.StatusOK, "https://example.org", true},
+		{"not allowed", http.MethodGet, "https://evil.com", http.StatusOK, "", true},
+		{"no origin", http.MethodGet, "", http.StatusOK, "", true},
+		{"preflight", http.MethodOptions, "https://example.com", http.StatusNoContent, "https://example.com", false},
+		{"preflight not allowed", http.MethodOptions, "https://evil.com", http.StatusNoContent, "", false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			called = false
+			r := httptest.NewRequest(test.method, "/api/v1/packages/example.com/p", nil)
+			if test.origin != "" {
+				r.Header.Set("Origin", test.origin)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != test.wantStatus {
+				t.Errorf("got status %d, want %d", w.Code, test.wantStatus)
+			}
+			if called != test.wantCalled {
+				t.Errorf("handler called: got %t, want %t", called, test.wantCalled)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != test.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin: got %q, want %q", got, test.wantOrigin)
+			}
+			wantMethods := ""
+			if test.wantOrigin != "" {
+				wantMethods = corsAllowedMethods
+			}
+			if got := w.Header().Get("Access-Control-Allow-Methods"); got != wantMethods {
+				t.Errorf("Access-Control-Allow-Methods: got %q, want %q", got, wantMethods)
+			}
+			if got := w.Header().Get("Vary"); got != "Origin" {
+				t.Errorf("Vary: got %q, want %q", got, "Origin")
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DeprecationMiddleware marks the responses to requests for URL paths
+// beginning with deprecatedPattern as deprecated, for clients that follow
+// the Deprecation HTTP header field
+// (https://tools.ietf.org/html/draft-ietf-httpapi-deprecation-header) and the
+// Sunset HTTP header field (https://tools.ietf.org/html/rfc8594).
+//
+// It sets the Deprecation header to "true", and a Link header with relation
+// "successor-version" to the canonical URL of the request, whose path is that
+// of the request with the prefix deprecatedPattern replaced by replacement.
+// If sunsetDate is not zero, the Sunset header is set to it, as the date after
+// which the deprecated URLs may stop working.
+func DeprecationMiddleware(deprecatedPattern, replacement string, sunsetDate time.Time) Middleware {
+	var sunset string
+	if !sunsetDate.IsZero() {
+		sunset = sunsetDate.UTC().Format(http.TimeFormat)
+	}
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasPrefix(r.URL.Path, deprecatedPattern) {
+				w.Header().Set("Deprecation", "true")
+				u := *r.URL
+				u.Path = replacement + strings.TrimPrefix(r.URL.Path, deprecatedPattern)
+				u.RawPath = ""
+				w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, u.RequestURI()))
+				if sunset != "" {
+					w.Header().Set("Sunset", sunset)
+				}
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
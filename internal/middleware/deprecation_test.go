@@ -0,0 +1,65 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeprecationMiddleware(t *testing.T) {
+	sunset := time.Date(2021, time.July, 1, 0, 0, 0, 0, time.UTC)
+	redirect := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, strings.TrimPrefix(r.URL.Path, "/pkg"), http.StatusMovedPermanently)
+	})
+
+	for _, test := range []struct {
+		name, path           string
+		sunset               time.Time
+		wantDeprecation      string
+		wantLink, wantSunset string
+	}{
+		{
+			name:            "deprecated",
+			path:            "/pkg/github.com/a/b?tab=doc",
+			sunset:          sunset,
+			wantDeprecation: "true",
+			wantLink:        `</github.com/a/b?tab=doc>; rel="successor-version"`,
+			wantSunset:      "Thu, 01 Jul 2021 00:00:00 GMT",
+		},
+		{
+			name:            "no sunset",
+			path:            "/pkg/fmt",
+			wantDeprecation: "true",
+			wantLink:        `</fmt>; rel="successor-version"`,
+		},
+		{
+			name:   "not deprecated",
+			path:   "/fmt",
+			sunset: sunset,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			h := DeprecationMiddleware("/pkg/", "/", test.sunset)(redirect)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+			if w.Code != http.StatusMovedPermanently {
+				t.Errorf("got status %d, want %d", w.Code, http.StatusMovedPermanently)
+			}
+			for header, want := range map[string]string{
+				"Deprecation": test.wantDeprecation,
+				"Link":        test.wantLink,
+				"Sunset":      test.wantSunset,
+			} {
+				if got := w.Header().Get(header); got != want {
+					t.Errorf("%s: got %q, want %q", header, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// notModifiedHeaders are the only headers sent with a 304 Not Modified
+// response; see https://tools.ietf.org/html/rfc7232#section-4.1.
+//
+// In particular, the Content-Security-Policy header must not be sent: its
+// nonce would not match the one in the body that the client has cached.
+var notModifiedHeaders = map[string]bool{
+	"Cache-Control":    true,
+	"Content-Location": true,
+	"Date":             true,
+	"Etag":             true,
+	"Expires":          true,
+	"Vary":             true,
+}
+
+// ETag returns a Middleware that supports conditional GETs for handlers that
+// set an ETag header. If a handler responds with 200 OK and an ETag matching
+// the request's If-None-Match header, the response is replaced by a 304 Not
+// Modified response with no body.
+//
+// ETag must come before any middleware that sets headers which should not be
+// sent with a 304 response, like SecureHeaders.
+func ETag() Middleware {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h.ServeHTTP(&etagResponseWriter{ResponseWriter: w, r: r}, r)
+		})
+	}
+}
+
+// ETagMatches reports whether etag matches the If-None-Match header of r.
+// It always reports false for an empty etag.
+func ETagMatches(r *http.Request, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	for _, tag := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		// If-None-Match uses the weak comparison function.
+		if strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// etagResponseWriter is an http.ResponseWriter that turns a 200 OK response
+// into a 304 Not Modified response if its ETag matches the request.
+type etagResponseWriter struct {
+	http.ResponseWriter
+	r           *http.Request
+	wroteHeader bool
+	notModified bool
+}
+
+func (w *etagResponseWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if statusCode == http.StatusOK && ETagMatches(w.r, w.Header().Get("ETag")) {
+		statusCode = http.StatusNotModified
+	}
+	if statusCode == http.StatusNotModified {
+		w.notModified = true
+		for k := range w.Header() {
+			if !notModifiedHeaders[k] {
+				w.Header().Del(k)
+			}
+		}
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *etagResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.notModified {
+		// A 304 response has no body.
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
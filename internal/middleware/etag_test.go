@@ -0,0 +1,66 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestETag(t *testing.T) {
+	const etag = `"abc"`
+	handler := func(status int, etag string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if etag != "" {
+				w.Header().Set("ETag", etag)
+			}
+			w.Header().Set("Content-Security-Policy", "script-src 'nonce-x'")
+			w.WriteHeader(status)
+			w.Write([]byte("body"))
+		})
+	}
+	for _, test := range []struct {
+		name        string
+		status      int
+		etag        string
+		ifNoneMatch string
+		wantStatus  int
+	}{
+		{"no If-None-Match", http.StatusOK, etag, "", http.StatusOK},
+		{"match", http.StatusOK, etag, etag, http.StatusNotModified},
+		{"match in list", http.StatusOK, etag, `"xyz", ` + etag, http.StatusNotModified},
+		{"weak match", http.StatusOK, etag, "W/" + etag, http.StatusNotModified},
+		{"wildcard", http.StatusOK, etag, "*", http.StatusNotModified},
+		{"mismatch", http.StatusOK, etag, `"xyz"`, http.StatusOK},
+		{"no ETag", http.StatusOK, "", etag, http.StatusOK},
+		{"error", http.StatusNotFound, etag, etag, http.StatusNotFound},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			if test.ifNoneMatch != "" {
+				r.Header.Set("If-None-Match", test.ifNoneMatch)
+			}
+			w := httptest.NewRecorder()
+			ETag()(handler(test.status, test.etag)).ServeHTTP(w, r)
+			if w.Code != test.wantStatus {
+				t.Fatalf("got status %d, want %d", w.Code, test.wantStatus)
+			}
+			if got := w.Header().Get("ETag"); got != test.etag {
+				t.Errorf("got ETag %q, want %q", got, test.etag)
+			}
+			wantBody, wantCSP := "body", true
+			if test.wantStatus == http.StatusNotModified {
+				wantBody, wantCSP = "", false
+			}
+			if got := w.Body.String(); got != wantBody {
+				t.Errorf("got body %q, want %q", got, wantBody)
+			}
+			if got := w.Header().Get("Content-Security-Policy") != ""; got != wantCSP {
+				t.Errorf("Content-Security-Policy header present: got %t, want %t", got, wantCSP)
+			}
+		})
+	}
+}
@@ -57,6 +57,62 @@ func Experiment(e *Experimenter) Middleware {
 	}
 }
 
+const (
+	// experimentBucketCookie is the name of the cookie that assigns a
+	// request to an experiment bucket, and of the query parameter that sets
+	// it.
+	experimentBucketCookie = "experiment-bucket"
+
+	// experimentBucketMaxAge is the lifetime of the experiment-bucket
+	// cookie, in seconds.
+	experimentBucketMaxAge = 30 * 24 * 60 * 60
+)
+
+// ExperimentBucket returns a Middleware that stores the experiment bucket of
+// each incoming request in the request context, where it can be retrieved
+// with experiment.Bucket. Only the names in buckets are accepted, so that
+// arbitrary values do not end up in cache keys.
+//
+// The bucket of a request is the value of its experiment-bucket cookie. The
+// cookie is set by visiting a URL with an experiment-bucket query parameter,
+// like /search?q=http&experiment-bucket=lowgomod, which also applies to that
+// request; an empty or unknown bucket in the query parameter removes the
+// cookie.
+func ExperimentBucket(buckets []string) Middleware {
+	allowed := map[string]bool{}
+	for _, b := range buckets {
+		allowed[b] = true
+	}
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var bucket string
+			if c, err := r.Cookie(experimentBucketCookie); err == nil {
+				bucket = c.Value
+			}
+			if vs, ok := r.URL.Query()[experimentBucketCookie]; ok {
+				bucket = vs[0]
+				c := &http.Cookie{
+					Name:     experimentBucketCookie,
+					Value:    bucket,
+					Path:     "/",
+					MaxAge:   experimentBucketMaxAge,
+					HttpOnly: true,
+					SameSite: http.SameSiteLaxMode,
+				}
+				if !allowed[bucket] {
+					c.Value = ""
+					c.MaxAge = -1
+				}
+				http.SetCookie(w, c)
+			}
+			if allowed[bucket] {
+				r = r.WithContext(experiment.NewBucketContext(r.Context(), bucket))
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
 // setExperimentsForRequest sets the experiments for a given request.
 // Experiments should be stable for a given IP address.
 func (e *Experimenter) setExperimentsForRequest(r *http.Request) *http.Request {
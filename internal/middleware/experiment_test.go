@@ -91,6 +91,53 @@ func TestSetAndLoadExperiments(t *testing.T) {
 	}
 }
 
+func TestExperimentBucket(t *testing.T) {
+	var got string
+	handler := ExperimentBucket([]string{"a", "b"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = experiment.Bucket(r.Context())
+	}))
+	for _, test := range []struct {
+		url        string
+		cookie     *http.Cookie
+		want       string
+		wantCookie string // the Set-Cookie header of the response
+	}{
+		{"/", nil, "", ""},
+		{"/", &http.Cookie{Name: experimentBucketCookie, Value: "b"}, "b", ""},
+		{"/", &http.Cookie{Name: experimentBucketCookie, Value: "unknown"}, "", ""},
+		{"/", &http.Cookie{Name: "other", Value: "b"}, "", ""},
+		{
+			"/?experiment-bucket=a", nil, "a",
+			"experiment-bucket=a; Path=/; Max-Age=2592000; HttpOnly; SameSite=Lax",
+		},
+		{
+			"/?experiment-bucket=a", &http.Cookie{Name: experimentBucketCookie, Value: "b"}, "a",
+			"experiment-bucket=a; Path=/; Max-Age=2592000; HttpOnly; SameSite=Lax",
+		},
+		{
+			"/?experiment-bucket=", &http.Cookie{Name: experimentBucketCookie, Value: "b"}, "",
+			"experiment-bucket=; Path=/; Max-Age=0; HttpOnly; SameSite=Lax",
+		},
+		{
+			"/?experiment-bucket=unknown", nil, "",
+			"experiment-bucket=; Path=/; Max-Age=0; HttpOnly; SameSite=Lax",
+		},
+	} {
+		req := httptest.NewRequest("GET", test.url, nil)
+		if test.cookie != nil {
+			req.AddCookie(test.cookie)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if got != test.want {
+			t.Errorf("%s with cookie %v: got bucket %q, want %q", test.url, test.cookie, got, test.want)
+		}
+		if got := w.Header().Get("Set-Cookie"); got != test.wantCookie {
+			t.Errorf("%s with cookie %v: got Set-Cookie %q, want %q", test.url, test.cookie, got, test.wantCookie)
+		}
+	}
+}
+
 func TestShouldSetExperiment(t *testing.T) {
 	ipv4Addr := func() string {
 		a := make([]string, 4)
@@ -0,0 +1,82 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// A Format is a representation of a resource that can be requested with the
+// Accept header.
+type Format int
+
+const (
+	// FormatHTML is the format of web pages, and the default.
+	FormatHTML Format = iota
+	// FormatJSON is the format of the JSON API.
+	FormatJSON
+)
+
+type formatKey struct{}
+
+// NegotiateContent returns a middleware that chooses the Format of the
+// response from the Accept header of the request, and stores it in the
+// request context, from which handlers can retrieve it with
+// FormatFromContext. The format is JSON if the header prefers
+// application/json to text/html, and HTML otherwise.
+func NegotiateContent() Middleware {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept")
+			f := negotiateFormat(r.Header.Get("Accept"))
+			h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), formatKey{}, f)))
+		})
+	}
+}
+
+// FormatFromContext returns the Format stored in ctx by NegotiateContent, or
+// FormatHTML if there is none.
+func FormatFromContext(ctx context.Context) Format {
+	f, _ := ctx.Value(formatKey{}).(Format)
+	return f
+}
+
+// negotiateFormat returns the Format preferred by the Accept header value
+// accept. application/json must be listed explicitly to be chosen, while
+// text/html also matches the wildcards "text/*" and "*/*".
+func negotiateFormat(accept string) Format {
+	var jsonQ, htmlQ float64
+	for _, mr := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(mr)
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			q, err = strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+		}
+		switch mediaType {
+		case "application/json":
+			if q > jsonQ {
+				jsonQ = q
+			}
+		case "text/html", "text/*", "*/*":
+			if q > htmlQ {
+				htmlQ = q
+			}
+		}
+	}
+	if jsonQ > htmlQ {
+		return FormatJSON
+	}
+	return FormatHTML
+}
@@ -0,0 +1,43 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiateContent(t *testing.T) {
+	var got Format
+	h := NegotiateContent()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = FormatFromContext(r.Context())
+	}))
+	for _, test := range []struct {
+		accept string
+		want   Format
+	}{
+		{"", FormatHTML},
+		{"*/*", FormatHTML},
+		{"text/html", FormatHTML},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", FormatHTML},
+		{"application/json", FormatJSON},
+		{"application/json, */*;q=0.1", FormatJSON},
+		{"text/*;q=0.5, application/json;q=0.8", FormatJSON},
+		{"application/json;q=0.5, text/html", FormatHTML},
+		{"application/json;q=bad", FormatHTML},
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept", test.accept)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if got != test.want {
+			t.Errorf("Accept %q: got format %d, want %d", test.accept, got, test.want)
+		}
+		if v := w.Header().Get("Vary"); v != "Accept" {
+			t.Errorf("Accept %q: Vary = %q, want %q", test.accept, v, "Accept")
+		}
+	}
+}
@@ -44,12 +44,20 @@ var (
 // Information is kept in an LRU cache of size maxEntries.
 //
 // If a request is disallowed, a 429 (TooManyRequests) will be served.
+// Requests whose paths begin with one of settings.ExcludedPathPrefixes are
+// always served.
 func Quota(settings config.QuotaSettings) Middleware {
 	var mu sync.Mutex
 	cache := lru.New(settings.MaxEntries)
 
 	return func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, prefix := range settings.ExcludedPathPrefixes {
+				if strings.HasPrefix(r.URL.Path, prefix) {
+					h.ServeHTTP(w, r)
+					return
+				}
+			}
 			for _, url := range settings.AcceptedURLs {
 				if r.Referer() == url {
 					recordQuotaMetric("accepted")
@@ -142,6 +142,46 @@ func TestQuotaBadKey(t *testing.T) {
 	}
 }
 
+func TestQuotaExcludedPathPrefixes(t *testing.T) {
+	// Verify that requests for excluded paths are neither blocked nor counted.
+	mw := Quota(config.QuotaSettings{
+		QPS:                  1,
+		Burst:                2,
+		MaxEntries:           1,
+		RecordOnly:           boolptr(false),
+		ExcludedPathPrefixes: []string{"/badge/"},
+	})
+	npass := 0
+	h := func(w http.ResponseWriter, r *http.Request) {
+		npass++
+	}
+	ts := httptest.NewServer(mw(http.HandlerFunc(h)))
+	defer ts.Close()
+	c := ts.Client()
+	view.Register(QuotaResultCount)
+	defer view.Unregister(QuotaResultCount)
+
+	const nreq = 10
+	for i := 0; i < nreq; i++ {
+		req, err := http.NewRequest("GET", ts.URL+"/badge/github.com/a/b.svg", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Add("X-Forwarded-For", "1.2.3.4, and more")
+		res, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+	if npass != nreq {
+		t.Errorf("%d passed, want %d", npass, nreq)
+	}
+	if got := collectViewData(t); len(got) != 0 {
+		t.Errorf("got quota results %v, want none", got)
+	}
+}
+
 func collectViewData(t *testing.T) map[bool]int {
 	m := map[bool]int{}
 	rows, err := view.RetrieveData(QuotaResultCount.Name)
@@ -0,0 +1,118 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// rateLimitIdleTime is how long a client's limiter is kept after its
+	// last request. A limiter that has been idle for longer than
+	// burst/rps has a full bucket, so it can be recreated without loss.
+	rateLimitIdleTime = 10 * time.Minute
+
+	// rateLimitCleanupInterval is how often idle limiters are removed.
+	rateLimitCleanupInterval = time.Minute
+)
+
+// rateLimitEntry is the limiter of one client, with the time of its last
+// request in Unix nanoseconds.
+type rateLimitEntry struct {
+	limiter  *rate.Limiter
+	lastSeen int64 // accessed atomically
+}
+
+// RateLimit returns a middleware that allows each client IP address rps
+// requests per second, with the given burst. Requests over the limit are
+// served a 429 (TooManyRequests) with a Retry-After header saying how many
+// seconds to wait.
+//
+// Unlike Quota, RateLimit limits individual addresses rather than blocks of
+// them, and always blocks. Limiters of clients that have been idle for a while
+// are discarded periodically.
+func RateLimit(rps float64, burst int) Middleware {
+	var (
+		limiters    sync.Map // client IP => *rateLimitEntry
+		lastCleanup = time.Now().UnixNano()
+	)
+	cleanup := func(now time.Time) {
+		last := atomic.LoadInt64(&lastCleanup)
+		if now.UnixNano()-last < int64(rateLimitCleanupInterval) ||
+			!atomic.CompareAndSwapInt64(&lastCleanup, last, now.UnixNano()) {
+			return
+		}
+		limiters.Range(func(key, value interface{}) bool {
+			e := value.(*rateLimitEntry)
+			if now.UnixNano()-atomic.LoadInt64(&e.lastSeen) > int64(rateLimitIdleTime) {
+				limiters.Delete(key)
+			}
+			return true
+		})
+	}
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := clientIP(r)
+			// Fail open if there is no client address.
+			if key == "" {
+				h.ServeHTTP(w, r)
+				return
+			}
+			now := time.Now()
+			v, ok := limiters.Load(key)
+			if !ok {
+				v, _ = limiters.LoadOrStore(key, &rateLimitEntry{limiter: rate.NewLimiter(rate.Limit(rps), burst)})
+			}
+			e := v.(*rateLimitEntry)
+			atomic.StoreInt64(&e.lastSeen, now.UnixNano())
+			cleanup(now)
+
+			res := e.limiter.ReserveN(now, 1)
+			if delay := res.DelayFrom(now); !res.OK() || delay > 0 {
+				res.CancelAt(now)
+				retry := 1
+				if res.OK() {
+					retry = int(math.Ceil(delay.Seconds()))
+				}
+				w.Header().Set("Retry-After", strconv.Itoa(retry))
+				const tmr = http.StatusTooManyRequests
+				http.Error(w, http.StatusText(tmr), tmr)
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientIP returns the IP address of the client that made r: the originating
+// address in the X-Forwarded-For header if there is one, and otherwise the
+// remote address of the connection. It returns the empty string if neither
+// can be parsed.
+func clientIP(r *http.Request) string {
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		origin := strings.TrimSpace(strings.SplitN(xff, ",", 2)[0])
+		if ip := net.ParseIP(origin); ip != nil {
+			return ip.String()
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String()
+	}
+	return ""
+}
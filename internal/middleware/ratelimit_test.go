@@ -0,0 +1,80 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestRateLimit(t *testing.T) {
+	const burst = 3
+	mw := RateLimit(0.1, burst)
+	var npass int
+	h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		npass++
+	}))
+
+	request := func(xff string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/search?q=foo", nil)
+		if xff != "" {
+			r.Header.Set("X-Forwarded-For", xff)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	for i := 0; i < 2*burst; i++ {
+		w := request("1.2.3.4, and more")
+		want := http.StatusOK
+		if i >= burst {
+			want = http.StatusTooManyRequests
+		}
+		if w.Code != want {
+			t.Errorf("#%d: got %d, want %d", i, w.Code, want)
+		}
+		if w.Code == http.StatusTooManyRequests {
+			secs, err := strconv.Atoi(w.Header().Get("Retry-After"))
+			if err != nil || secs < 1 {
+				t.Errorf("#%d: got Retry-After %q, want a positive number of seconds", i, w.Header().Get("Retry-After"))
+			}
+		}
+	}
+	if npass != burst {
+		t.Errorf("got %d requests to pass, want %d", npass, burst)
+	}
+
+	// Addresses are limited separately, including addresses in the same
+	// block, and the remote address is used without X-Forwarded-For.
+	for _, xff := range []string{"1.2.3.5", ""} {
+		if w := request(xff); w.Code != http.StatusOK {
+			t.Errorf("X-Forwarded-For %q: got %d, want %d", xff, w.Code, http.StatusOK)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	for _, test := range []struct {
+		xff, remoteAddr, want string
+	}{
+		{"1.2.3.4", "5.6.7.8:80", "1.2.3.4"},
+		{"1.2.3.4, 9.9.9.9", "5.6.7.8:80", "1.2.3.4"},
+		{"", "5.6.7.8:80", "5.6.7.8"},
+		{"junk", "[::1]:80", "::1"},
+		{"", "junk", ""},
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = test.remoteAddr
+		if test.xff != "" {
+			r.Header.Set("X-Forwarded-For", test.xff)
+		}
+		if got := clientIP(r); got != test.want {
+			t.Errorf("clientIP(%q, %q) = %q, want %q", test.xff, test.remoteAddr, got, test.want)
+		}
+	}
+}
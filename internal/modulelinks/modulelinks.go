@@ -0,0 +1,108 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package modulelinks derives links to the issue trackers and continuous
+// integration services of modules from the URLs of their repositories.
+package modulelinks
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/pkgsite/internal"
+)
+
+// A host is a code hosting service.
+type host struct {
+	// issues and ci are the paths of the issue tracker and of the built-in
+	// CI service of a repository, relative to its URL.
+	issues, ci string
+	// ciConfig is the path, relative to the repository root, of the file or
+	// directory that configures the built-in CI service.
+	ciConfig string
+	// vcs is the name of the host on Travis CI and CircleCI, if they support
+	// it.
+	vcs string
+}
+
+var hosts = map[string]host{
+	"github.com":    {issues: "/issues", ci: "/actions", ciConfig: ".github/workflows", vcs: "github"},
+	"gitlab.com":    {issues: "/-/issues", ci: "/-/pipelines", ciConfig: ".gitlab-ci.yml"},
+	"bitbucket.org": {issues: "/issues", ci: "/addon/pipelines/home", ciConfig: "bitbucket-pipelines.yml", vcs: "bitbucket"},
+}
+
+// DetectModuleLinks returns the links to the issue tracker and the CI service
+// of the repository at repoURL, if it is hosted on GitHub, GitLab or
+// Bitbucket. The CI link is to the service built into the host, like GitHub
+// Actions. It returns nil for other hosts.
+func DetectModuleLinks(repoURL string) *internal.ModuleLinks {
+	r, ok := parseRepoURL(repoURL)
+	if !ok {
+		return nil
+	}
+	return &internal.ModuleLinks{
+		IssuesURL: r.url() + r.host.issues,
+		CIURL:     r.url() + r.host.ci,
+	}
+}
+
+// DetectCIURL returns the URL of the build status of the repository at
+// repoURL on the CI service configured by one of files, which are
+// slash-separated paths relative to the root of the repository. The service
+// built into the host is preferred, then Travis CI and CircleCI. It returns
+// the empty string if no supported service is configured.
+func DetectCIURL(repoURL string, files []string) string {
+	r, ok := parseRepoURL(repoURL)
+	if !ok {
+		return ""
+	}
+	has := func(name string) bool {
+		for _, f := range files {
+			if f == name || strings.HasPrefix(f, name+"/") {
+				return true
+			}
+		}
+		return false
+	}
+	switch vcs := r.host.vcs; {
+	case has(r.host.ciConfig):
+		return r.url() + r.host.ci
+	case vcs != "" && has(".travis.yml"):
+		return "https://app.travis-ci.com/" + vcs + "/" + r.path
+	case vcs != "" && has(".circleci/config.yml"):
+		return "https://app.circleci.com/pipelines/" + vcs + "/" + r.path
+	}
+	return ""
+}
+
+// A repo is a repository on a known host.
+type repo struct {
+	hostname string // like "github.com"
+	path     string // like "owner/name"
+	host     host
+}
+
+// url returns the URL of the home page of r.
+func (r repo) url() string {
+	return "https://" + r.hostname + "/" + r.path
+}
+
+// parseRepoURL parses repoURL, and reports whether it is the URL of a
+// repository on a known host. A trailing slash or ".git" suffix is ignored.
+func parseRepoURL(repoURL string) (repo, bool) {
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return repo{}, false
+	}
+	h, ok := hosts[u.Host]
+	if !ok {
+		return repo{}, false
+	}
+	p := strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+	if !strings.Contains(p, "/") {
+		// Not a repository, like "https://github.com/owner".
+		return repo{}, false
+	}
+	return repo{hostname: u.Host, path: p, host: h}, true
+}
@@ -0,0 +1,117 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modulelinks
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal"
+)
+
+func TestDetectModuleLinks(t *testing.T) {
+	for _, test := range []struct {
+		repoURL string
+		want    *internal.ModuleLinks
+	}{
+		{
+			"https://github.com/owner/repo",
+			&internal.ModuleLinks{
+				IssuesURL: "https://github.com/owner/repo/issues",
+				CIURL:     "https://github.com/owner/repo/actions",
+			},
+		},
+		{
+			"https://github.com/owner/repo.git/",
+			&internal.ModuleLinks{
+				IssuesURL: "https://github.com/owner/repo/issues",
+				CIURL:     "https://github.com/owner/repo/actions",
+			},
+		},
+		{
+			"https://gitlab.com/group/subgroup/project",
+			&internal.ModuleLinks{
+				IssuesURL: "https://gitlab.com/group/subgroup/project/-/issues",
+				CIURL:     "https://gitlab.com/group/subgroup/project/-/pipelines",
+			},
+		},
+		{
+			"https://bitbucket.org/team/repo",
+			&internal.ModuleLinks{
+				IssuesURL: "https://bitbucket.org/team/repo/issues",
+				CIURL:     "https://bitbucket.org/team/repo/addon/pipelines/home",
+			},
+		},
+		{"https://github.com/owner", nil},
+		{"https://go.googlesource.com/tools", nil},
+		{"https://git.example.com/owner/repo", nil},
+		{"", nil},
+	} {
+		t.Run(test.repoURL, func(t *testing.T) {
+			got := DetectModuleLinks(test.repoURL)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDetectCIURL(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		repoURL string
+		files   []string
+		want    string
+	}{
+		{
+			"GitHub Actions",
+			"https://github.com/owner/repo",
+			[]string{".github/workflows/test.yml", ".travis.yml", "go.mod"},
+			"https://github.com/owner/repo/actions",
+		},
+		{
+			"Travis CI",
+			"https://github.com/owner/repo",
+			[]string{".travis.yml", ".circleci/config.yml", "go.mod"},
+			"https://app.travis-ci.com/github/owner/repo",
+		},
+		{
+			"CircleCI",
+			"https://bitbucket.org/team/repo",
+			[]string{".circleci/config.yml", "go.mod"},
+			"https://app.circleci.com/pipelines/bitbucket/team/repo",
+		},
+		{
+			"GitLab CI",
+			"https://gitlab.com/group/project",
+			[]string{".gitlab-ci.yml", "go.mod"},
+			"https://gitlab.com/group/project/-/pipelines",
+		},
+		{
+			"Travis CI is not supported on GitLab",
+			"https://gitlab.com/group/project",
+			[]string{".travis.yml", "go.mod"},
+			"",
+		},
+		{
+			"no CI",
+			"https://github.com/owner/repo",
+			[]string{"go.mod", "workflows/x.yml"},
+			"",
+		},
+		{
+			"unknown host",
+			"https://git.example.com/owner/repo",
+			[]string{".travis.yml"},
+			"",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := DetectCIURL(test.repoURL, test.files); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -38,19 +38,19 @@ func BenchmarkSearch(b *testing.B) {
 	if err != nil {
 		b.Fatal(err)
 	}
-	ddb, err := database.Open("pgx", cfg.DBConnInfo(), "bench")
+	ddb, err := database.Open("pgx", cfg.DBConnInfo(), "bench", database.DBConfig{})
 	if err != nil {
 		b.Fatal(err)
 	}
 	db := New(ddb)
-	searchers := map[string]func(context.Context, string, int, int) ([]*internal.SearchResult, error){
+	searchers := map[string]func(context.Context, string, SearchOptions) ([]*internal.SearchResult, error){
 		"db.Search": db.Search,
 	}
 	for name, search := range searchers {
 		for _, query := range testQueries {
 			b.Run(name+":"+query, func(b *testing.B) {
 				for i := 0; i < b.N; i++ {
-					if _, err := search(ctx, query, 10, 0); err != nil {
+					if _, err := search(ctx, query, SearchOptions{Limit: 10}); err != nil {
 						b.Fatal(err)
 					}
 				}
@@ -0,0 +1,28 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"golang.org/x/pkgsite/internal/proxy"
+	"golang.org/x/pkgsite/internal/testing/datasourcetest"
+)
+
+func TestContract(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout*3)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	proxyClient, teardownProxy := proxy.SetupTestProxy(t, datasourcetest.TestModules())
+	defer teardownProxy()
+	for _, m := range datasourcetest.FetchModules(ctx, t, proxyClient) {
+		if err := testDB.InsertModule(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	datasourcetest.DataSourceContractTest(t, testDB)
+}
@@ -0,0 +1,126 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+// GetModulesForContributorCount returns up to limit module versions with
+// source information whose contributor count was never fetched, or was last
+// fetched before the given time. Those never fetched come first, followed by
+// the least recently fetched.
+func (db *DB) GetModulesForContributorCount(ctx context.Context, before time.Time, limit int) (_ []*internal.ModuleInfo, err error) {
+	defer derrors.Wrap(&err, "GetModulesForContributorCount(ctx, %v, %d)", before, limit)
+
+	if limit <= 0 {
+		return nil, fmt.Errorf("limit must be positive: %w", derrors.InvalidArgument)
+	}
+	query := `
+		SELECT module_path, version, source_info
+		FROM modules
+		WHERE source_info IS NOT NULL
+		AND (contributor_count_fetched_at IS NULL OR contributor_count_fetched_at < $1)
+		ORDER BY contributor_count_fetched_at NULLS FIRST, module_path, version
+		LIMIT $2`
+
+	var mods []*internal.ModuleInfo
+	collect := func(rows *sql.Rows) error {
+		var mi internal.ModuleInfo
+		if err := rows.Scan(&mi.ModulePath, &mi.Version, jsonbScanner{&mi.SourceInfo}); err != nil {
+			return fmt.Errorf("rows.Scan(): %v", err)
+		}
+		mods = append(mods, &mi)
+		return nil
+	}
+	if err := db.db.RunQuery(ctx, query, collect, before, limit); err != nil {
+		return nil, err
+	}
+	return mods, nil
+}
+
+// UpdateContributorCount stores count as the contributor count of the module
+// with the given path and version, and records that it was fetched now. A
+// negative count records that the count could not be determined, so that
+// the module is not retried until its count is stale.
+func (db *DB) UpdateContributorCount(ctx context.Context, modulePath, version string, count int) (err error) {
+	defer derrors.Wrap(&err, "UpdateContributorCount(ctx, %q, %q, %d)", modulePath, version, count)
+
+	var c sql.NullInt64
+	if count >= 0 {
+		c = sql.NullInt64{Int64: int64(count), Valid: true}
+	}
+	res, err := db.db.Exec(ctx, `
+		UPDATE modules
+		SET contributor_count = $3, contributor_count_fetched_at = CURRENT_TIMESTAMP
+		WHERE module_path = $1 AND version = $2`,
+		modulePath, version, c)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("RowsAffected(): %v", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("module %s@%s: %w", modulePath, version, derrors.NotFound)
+	}
+	return nil
+}
+
+// MarkContributorCountFetched records that the contributor count of the
+// module with the given path and version was fetched now, without changing
+// the count. It is used when fetching the count failed, so that the module is
+// retried after the others.
+func (db *DB) MarkContributorCountFetched(ctx context.Context, modulePath, version string) (err error) {
+	defer derrors.Wrap(&err, "MarkContributorCountFetched(ctx, %q, %q)", modulePath, version)
+
+	res, err := db.db.Exec(ctx, `
+		UPDATE modules
+		SET contributor_count_fetched_at = CURRENT_TIMESTAMP
+		WHERE module_path = $1 AND version = $2`,
+		modulePath, version)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("RowsAffected(): %v", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("module %s@%s: %w", modulePath, version, derrors.NotFound)
+	}
+	return nil
+}
+
+// GetContributorCount returns the number of contributors of the repository of
+// the module with the given path and version. It returns an error wrapping
+// derrors.NotFound if the module does not exist or its count is unknown.
+func (db *DB) GetContributorCount(ctx context.Context, modulePath, version string) (_ int, err error) {
+	defer derrors.Wrap(&err, "GetContributorCount(ctx, %q, %q)", modulePath, version)
+
+	var count sql.NullInt64
+	err = db.db.QueryRow(ctx, `
+		SELECT contributor_count
+		FROM modules
+		WHERE module_path = $1 AND version = $2`,
+		modulePath, version).Scan(&count)
+	switch {
+	case err == sql.ErrNoRows:
+		return 0, fmt.Errorf("module %s@%s: %w", modulePath, version, derrors.NotFound)
+	case err != nil:
+		return 0, err
+	case !count.Valid:
+		return 0, fmt.Errorf("contributor count of %s@%s: %w", modulePath, version, derrors.NotFound)
+	default:
+		return int(count.Int64), nil
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/stdlib"
+)
+
+// defaultMaxDependencyDepth is the depth at which GetModuleDependencies stops
+// following imports, if DB.MaxDependencyDepth is not set.
+const defaultMaxDependencyDepth = 10
+
+// GetModuleDependencies returns the modules whose packages are imported by
+// the packages of the module version specified by modulePath and version,
+// or, transitively, by the packages of those modules. The standard library
+// is not included. The result is sorted by module path.
+//
+// The imports table does not record which module version provides an
+// imported package, so each imported package is attributed to the module
+// version that LegacyGetPackage would return for it at the latest version:
+// the latest release version if there is one, and the longest module path if
+// several modules provide the package.
+//
+// Imports are followed to a depth of at most db.MaxDependencyDepth. A module
+// is indirect if it is not imported by modulePath itself. The ImportedBy
+// field of each dependency lists the module versions that import it, so that
+// the graph of dependencies can be drawn from the result.
+func (db *DB) GetModuleDependencies(ctx context.Context, modulePath, version string) (_ []*internal.ModuleDependency, err error) {
+	defer derrors.Wrap(&err, "GetModuleDependencies(ctx, %q, %q)", modulePath, version)
+
+	if modulePath == "" || version == "" {
+		return nil, fmt.Errorf("neither modulePath nor version can be empty: %w", derrors.InvalidArgument)
+	}
+	maxDepth := db.MaxDependencyDepth
+	if maxDepth <= 0 {
+		maxDepth = defaultMaxDependencyDepth
+	}
+	query := `
+		WITH RECURSIVE deps (module_path, version, depth, from_module) AS (
+			SELECT $1::text, $2::text, 0, NULL::text
+			WHERE ` + db.versionNotDeletedSQL("$1", "$2") + `
+			UNION
+			SELECT r.module_path, r.version, d.depth + 1, d.module_path || '@' || d.version
+			FROM deps d
+			CROSS JOIN LATERAL (
+				SELECT DISTINCT i.to_path
+				FROM packages p
+				INNER JOIN imports i
+				ON
+					i.from_path = p.path
+					AND i.from_module_path = p.module_path
+					AND i.from_version = p.version
+				WHERE p.module_path = d.module_path AND p.version = d.version
+			) imp
+			CROSS JOIN LATERAL (
+				SELECT p.module_path, p.version
+				FROM packages p
+				INNER JOIN modules m
+				USING (module_path, version)
+				WHERE p.path = imp.to_path AND ` + db.notDeletedSQL("m") + `
+				ORDER BY
+					-- Imports of packages in the same module refer to
+					-- that module.
+					(p.module_path, p.version) = (d.module_path, d.version) DESC,
+					m.version_type = 'release' DESC,
+					m.sort_version DESC,
+					m.module_path DESC
+				LIMIT 1
+			) r
+			WHERE
+				d.depth < $3
+				AND r.module_path <> d.module_path
+				AND r.module_path <> $4
+		)
+		SELECT module_path, version, MIN(depth), ARRAY_AGG(DISTINCT from_module ORDER BY from_module)
+		FROM deps
+		WHERE depth > 0 AND module_path <> $1
+		GROUP BY module_path, version
+		ORDER BY module_path, version`
+
+	var deps []*internal.ModuleDependency
+	collect := func(rows *sql.Rows) error {
+		var d internal.ModuleDependency
+		if err := rows.Scan(&d.ModulePath, &d.Version, &d.Depth, pq.Array(&d.ImportedBy)); err != nil {
+			return fmt.Errorf("rows.Scan(): %v", err)
+		}
+		d.IsIndirect = d.Depth > 1
+		deps = append(deps, &d)
+		return nil
+	}
+	if err := db.db.RunQuery(ctx, query, collect, modulePath, version, maxDepth, stdlib.ModulePath); err != nil {
+		return nil, err
+	}
+	return deps, nil
+}
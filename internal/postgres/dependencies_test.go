@@ -0,0 +1,115 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/stdlib"
+	"golang.org/x/pkgsite/internal/testing/sample"
+)
+
+func TestGetModuleDependencies(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	// module returns a module whose packages have the given imports, keyed
+	// by package path suffix.
+	module := func(modulePath, version string, imports map[string][]string) *internal.Module {
+		var suffixes []string
+		for s := range imports {
+			suffixes = append(suffixes, s)
+		}
+		m := sample.Module(modulePath, version, suffixes...)
+		for _, p := range m.LegacyPackages {
+			for s, imps := range imports {
+				if p.Path == sample.LegacyPackage(modulePath, s).Path {
+					p.Imports = imps
+				}
+			}
+		}
+		return m
+	}
+	for _, m := range []*internal.Module{
+		module("a.com/a", "v1.0.0", map[string][]string{
+			"":     {"b.com/b/pkg", "fmt", "a.com/a/util"},
+			"util": nil,
+		}),
+		module("b.com/b", "v1.0.0", map[string][]string{"pkg": {"c.com/c"}}),
+		module("c.com/c", "v1.0.0", map[string][]string{"": nil}),
+		// The latest version of c.com/c imports a.com/a, making a cycle.
+		module("c.com/c", "v1.1.0", map[string][]string{"": {"a.com/a"}}),
+		module("d.com/d", "v1.0.0", map[string][]string{"": {"a.com/a"}}),
+		module(stdlib.ModulePath, "v1.14.0", map[string][]string{"fmt": nil}),
+	} {
+		if err := testDB.InsertModule(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, test := range []struct {
+		name                string
+		modulePath, version string
+		maxDepth            int
+		want                []*internal.ModuleDependency
+	}{
+		{
+			name:       "direct and transitive",
+			modulePath: "a.com/a",
+			version:    "v1.0.0",
+			want: []*internal.ModuleDependency{
+				{ModulePath: "b.com/b", Version: "v1.0.0", Depth: 1, ImportedBy: []string{"a.com/a@v1.0.0"}},
+				{ModulePath: "c.com/c", Version: "v1.1.0", IsIndirect: true, Depth: 2, ImportedBy: []string{"b.com/b@v1.0.0"}},
+			},
+		},
+		{
+			name:       "cycle",
+			modulePath: "b.com/b",
+			version:    "v1.0.0",
+			want: []*internal.ModuleDependency{
+				{ModulePath: "a.com/a", Version: "v1.0.0", IsIndirect: true, Depth: 2, ImportedBy: []string{"c.com/c@v1.1.0"}},
+				{ModulePath: "c.com/c", Version: "v1.1.0", Depth: 1, ImportedBy: []string{"b.com/b@v1.0.0"}},
+			},
+		},
+		{
+			name:       "earlier version",
+			modulePath: "c.com/c",
+			version:    "v1.0.0",
+			want:       nil,
+		},
+		{
+			name:       "depth limit",
+			modulePath: "d.com/d",
+			version:    "v1.0.0",
+			maxDepth:   2,
+			want: []*internal.ModuleDependency{
+				{ModulePath: "a.com/a", Version: "v1.0.0", Depth: 1, ImportedBy: []string{"d.com/d@v1.0.0"}},
+				{ModulePath: "b.com/b", Version: "v1.0.0", IsIndirect: true, Depth: 2, ImportedBy: []string{"a.com/a@v1.0.0"}},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			db := *testDB
+			db.MaxDependencyDepth = test.maxDepth
+			got, err := db.GetModuleDependencies(ctx, test.modulePath, test.version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("GetModuleDependencies(%q, %q) mismatch (-want +got):\n%s", test.modulePath, test.version, diff)
+			}
+		})
+	}
+
+	if _, err := testDB.GetModuleDependencies(ctx, "", "v1.0.0"); !errors.Is(err, derrors.InvalidArgument) {
+		t.Errorf("empty module path: got error %v, want InvalidArgument", err)
+	}
+}
@@ -40,6 +40,7 @@ func (db *DB) LegacyGetPackagesInModule(ctx context.Context, modulePath, version
 	WHERE
 		module_path = $1
 		AND version = $2
+		AND ` + db.versionNotDeletedSQL("module_path", "version") + `
 	ORDER BY path;`
 
 	var packages []*internal.LegacyPackage
@@ -62,7 +63,7 @@ func (db *DB) LegacyGetPackagesInModule(ctx context.Context, modulePath, version
 		return nil
 	}
 
-	if err := db.db.RunQuery(ctx, query, collect, modulePath, version); err != nil {
+	if err := db.readDB().RunQuery(ctx, query, collect, modulePath, version); err != nil {
 		return nil, fmt.Errorf("DB.LegacyGetPackagesInModule(ctx, %q, %q): %w", modulePath, version, err)
 	}
 	return packages, nil
@@ -108,6 +109,7 @@ func getPackageVersions(ctx context.Context, db *DB, pkgPath string, versionType
 				LIMIT 1
 			)
 			AND version_type in (%s)
+			AND ` + db.notDeletedSQL("m") + `
 		ORDER BY
 			m.sort_version DESC %s`
 	queryEnd := `;`
@@ -118,7 +120,7 @@ func getPackageVersions(ctx context.Context, db *DB, pkgPath string, versionType
 	}
 	query := fmt.Sprintf(baseQuery, versionTypeExpr(versionTypes), queryEnd)
 
-	rows, err := db.db.Query(ctx, query, pkgPath)
+	rows, err := db.readDB().Query(ctx, query, pkgPath)
 	if err != nil {
 		return nil, err
 	}
@@ -176,6 +178,7 @@ func getModuleVersions(ctx context.Context, db *DB, modulePath string, versionTy
 	WHERE
 		series_path = $1
 	    AND version_type in (%s)
+	    AND ` + db.notDeletedSQL("modules") + `
 	ORDER BY
 		sort_version DESC %s`
 
@@ -195,7 +198,7 @@ func getModuleVersions(ctx context.Context, db *DB, modulePath string, versionTy
 		vinfos = append(vinfos, &mi)
 		return nil
 	}
-	if err := db.db.RunQuery(ctx, query, collect, internal.SeriesPathForModule(modulePath)); err != nil {
+	if err := db.readDB().RunQuery(ctx, query, collect, internal.SeriesPathForModule(modulePath)); err != nil {
 		return nil, err
 	}
 	return vinfos, nil
@@ -221,6 +224,7 @@ func (db *DB) GetImports(ctx context.Context, pkgPath, modulePath, version strin
 			from_path = $1
 			AND from_version = $2
 			AND from_module_path = $3
+			AND ` + db.versionNotDeletedSQL("from_module_path", "from_version") + `
 		ORDER BY
 			to_path;`
 
@@ -232,7 +236,7 @@ func (db *DB) GetImports(ctx context.Context, pkgPath, modulePath, version strin
 		imports = append(imports, toPath)
 		return nil
 	}
-	if err := db.db.RunQuery(ctx, query, collect, pkgPath, version, modulePath); err != nil {
+	if err := db.readDB().RunQuery(ctx, query, collect, pkgPath, version, modulePath); err != nil {
 		return nil, err
 	}
 	return imports, nil
@@ -258,6 +262,11 @@ func (db *DB) GetImportedBy(ctx context.Context, pkgPath, modulePath string, lim
 			to_path = $1
 		AND
 			from_module_path <> $2
+		AND
+			EXISTS (
+				SELECT 1 FROM modules im
+				WHERE im.module_path = from_module_path
+				AND ` + db.notDeletedSQL("im") + `)
 		ORDER BY
 			from_path
 		LIMIT $3`
@@ -271,12 +280,35 @@ func (db *DB) GetImportedBy(ctx context.Context, pkgPath, modulePath string, lim
 		importedby = append(importedby, fromPath)
 		return nil
 	}
-	if err := db.db.RunQuery(ctx, query, collect, pkgPath, modulePath, limit); err != nil {
+	if err := db.readDB().RunQuery(ctx, query, collect, pkgPath, modulePath, limit); err != nil {
 		return nil, err
 	}
 	return importedby, nil
 }
 
+// GetImportedByCount returns the number of packages that import the package
+// with path pkgPath, as last computed for its search document. It returns 0
+// if the package has no search document.
+func (db *DB) GetImportedByCount(ctx context.Context, pkgPath string) (_ int, err error) {
+	defer derrors.Wrap(&err, "GetImportedByCount(ctx, %q)", pkgPath)
+	if pkgPath == "" {
+		return 0, fmt.Errorf("pkgPath cannot be empty: %w", derrors.InvalidArgument)
+	}
+	query := `
+		SELECT imported_by_count
+		FROM search_documents
+		WHERE package_path = $1`
+	var count int
+	err = db.readDB().QueryRow(ctx, query, pkgPath).Scan(&count)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
 // LegacyGetModuleInfo fetches a Version from the database with the primary key
 // (module_path, version).
 func (db *DB) LegacyGetModuleInfo(ctx context.Context, modulePath string, version string) (_ *internal.LegacyModuleInfo, err error) {
@@ -292,14 +324,16 @@ func (db *DB) LegacyGetModuleInfo(ctx context.Context, modulePath string, versio
 			version_type,
 			source_info,
 			redistributable,
-			has_go_mod
+			has_go_mod,
+			toolchain_version
 		FROM
-			modules`
+			modules
+		WHERE ` + db.notDeletedSQL("modules")
 
 	args := []interface{}{modulePath}
 	if version == internal.LatestVersion {
 		query += `
-			WHERE module_path = $1
+			AND module_path = $1
 			ORDER BY
 				-- Order the versions by release then prerelease.
 				-- The default version should be the first release
@@ -309,7 +343,7 @@ func (db *DB) LegacyGetModuleInfo(ctx context.Context, modulePath string, versio
 			LIMIT 1;`
 	} else {
 		query += `
-			WHERE module_path = $1 AND version = $2;`
+			AND module_path = $1 AND version = $2;`
 		args = append(args, version)
 	}
 
@@ -317,10 +351,11 @@ func (db *DB) LegacyGetModuleInfo(ctx context.Context, modulePath string, versio
 		mi       internal.LegacyModuleInfo
 		hasGoMod sql.NullBool
 	)
-	row := db.db.QueryRow(ctx, query, args...)
+	row := db.readDB().QueryRow(ctx, query, args...)
 	if err := row.Scan(&mi.ModulePath, &mi.Version, &mi.CommitTime,
 		database.NullIsEmpty(&mi.LegacyReadmeFilePath), database.NullIsEmpty(&mi.LegacyReadmeContents), &mi.VersionType,
-		jsonbScanner{&mi.SourceInfo}, &mi.IsRedistributable, &hasGoMod); err != nil {
+		jsonbScanner{&mi.SourceInfo}, &mi.IsRedistributable, &hasGoMod,
+		database.NullIsEmpty(&mi.ToolchainVersion)); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("module version %s@%s: %w", modulePath, version, derrors.NotFound)
 		}
@@ -339,6 +374,23 @@ func setHasGoMod(mi *internal.ModuleInfo, nb sql.NullBool) {
 	}
 }
 
+// nullBoolPtr returns a pointer to the value of nb, or nil if nb is NULL.
+func nullBoolPtr(nb sql.NullBool) *bool {
+	if !nb.Valid {
+		return nil
+	}
+	return &nb.Bool
+}
+
+// nullIntPtr returns a pointer to the value of ni, or nil if ni is NULL.
+func nullIntPtr(ni sql.NullInt64) *int {
+	if !ni.Valid {
+		return nil
+	}
+	n := int(ni.Int64)
+	return &n
+}
+
 // jsonbScanner scans a jsonb value into a Go value.
 type jsonbScanner struct {
 	ptr interface{} // a pointer to a Go struct or other JSON-serializable value
@@ -172,6 +172,50 @@ func TestPostgres_GetImportsAndImportedBy(t *testing.T) {
 	}
 }
 
+func TestGetImportedByCount(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	var (
+		m1   = sample.Module("path.to/foo", "v1.1.0", "bar")
+		m2   = sample.Module("path2.to/foo", "v1.2.0", "bar2")
+		m3   = sample.Module("path3.to/foo", "v1.3.0", "bar3")
+		pkg1 = m1.LegacyPackages[0]
+		pkg2 = m2.LegacyPackages[0]
+		pkg3 = m3.LegacyPackages[0]
+	)
+	pkg1.Imports = nil
+	pkg2.Imports = []string{pkg1.Path}
+	pkg3.Imports = []string{pkg2.Path, pkg1.Path}
+	for _, m := range []*internal.Module{m1, m2, m3} {
+		if err := testDB.InsertModule(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := testDB.UpdateSearchDocumentsImportedByCount(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		path string
+		want int
+	}{
+		{pkg1.Path, 2},
+		{pkg2.Path, 1},
+		{pkg3.Path, 0},
+		{"unknown.com/pkg", 0},
+	} {
+		got, err := testDB.GetImportedByCount(ctx, test.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("GetImportedByCount(ctx, %q) = %d, want %d", test.path, got, test.want)
+		}
+	}
+}
+
 func TestPostgres_GetTaggedAndPseudoVersions(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
 	defer cancel()
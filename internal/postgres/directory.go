@@ -40,16 +40,27 @@ func (db *DB) GetDirectoryNew(ctx context.Context, path, modulePath, version str
 			d.goos,
 			d.goarch,
 			d.synopsis,
-			d.html
+			d.html,
+			pkg.has_tests,
+			pkg.doc_coverage_pct,
+			pkg.requires_cgo,
+			pkg.is_deprecated,
+			pkg.deprecation_message,
+			pkg.license_conflicts
 		FROM modules m
 		INNER JOIN paths p
 		ON p.module_id = m.id
 		LEFT JOIN documentation d
 		ON d.path_id = p.id
+		LEFT JOIN packages pkg
+		ON pkg.path = p.path
+		AND pkg.module_path = m.module_path
+		AND pkg.version = m.version
 		WHERE
 			p.path = $1
 			AND m.module_path = $2
-			AND m.version = $3;`
+			AND m.version = $3
+			AND ` + db.notDeletedSQL("m")
 	var (
 		mi                         internal.ModuleInfo
 		dir                        internal.DirectoryNew
@@ -57,8 +68,12 @@ func (db *DB) GetDirectoryNew(ctx context.Context, path, modulePath, version str
 		pkg                        internal.PackageNew
 		licenseTypes, licensePaths []string
 		pathID                     int
+		hasTests, requiresCgo      sql.NullBool
+		isDeprecated               sql.NullBool
+		deprecationMessage         sql.NullString
+		docCoverage                sql.NullInt64
 	)
-	row := db.db.QueryRow(ctx, query, path, modulePath, version)
+	row := db.readDB().QueryRow(ctx, query, path, modulePath, version)
 	if err := row.Scan(
 		&mi.ModulePath,
 		&mi.Version,
@@ -78,6 +93,12 @@ func (db *DB) GetDirectoryNew(ctx context.Context, path, modulePath, version str
 		database.NullIsEmpty(&doc.GOARCH),
 		database.NullIsEmpty(&doc.Synopsis),
 		database.NullIsEmpty(&doc.HTML),
+		&hasTests,
+		&docCoverage,
+		&requiresCgo,
+		&isDeprecated,
+		&deprecationMessage,
+		jsonbScanner{&pkg.Facts.LicenseConflicts},
 	); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("directory %s@%s: %w", path, version, derrors.NotFound)
@@ -94,6 +115,11 @@ func (db *DB) GetDirectoryNew(ctx context.Context, path, modulePath, version str
 		dir.Package = &pkg
 		pkg.Path = dir.Path
 		pkg.Documentation = &doc
+		pkg.Facts.HasTests = nullBoolPtr(hasTests)
+		pkg.Facts.DocCoverage = nullIntPtr(docCoverage)
+		pkg.Facts.RequiresCgo = nullBoolPtr(requiresCgo)
+		pkg.Facts.IsDeprecated = nullBoolPtr(isDeprecated)
+		pkg.Facts.DeprecationMessage = deprecationMessage.String
 		collect := func(rows *sql.Rows) error {
 			var path string
 			if err := rows.Scan(&path); err != nil {
@@ -102,7 +128,7 @@ func (db *DB) GetDirectoryNew(ctx context.Context, path, modulePath, version str
 			pkg.Imports = append(pkg.Imports, path)
 			return nil
 		}
-		if err := db.db.RunQuery(ctx, `
+		if err := db.readDB().RunQuery(ctx, `
 		SELECT to_path
 		FROM package_imports
 		WHERE path_id = $1`, collect, pathID); err != nil {
@@ -114,7 +140,7 @@ func (db *DB) GetDirectoryNew(ctx context.Context, path, modulePath, version str
 	// we start displaying READMEs for directories instead of the top-level
 	// module.
 	var readme internal.Readme
-	row = db.db.QueryRow(ctx, `
+	row = db.readDB().QueryRow(ctx, `
 		SELECT file_path, contents
 		FROM modules m
 		INNER JOIN paths p
@@ -124,7 +150,8 @@ func (db *DB) GetDirectoryNew(ctx context.Context, path, modulePath, version str
 		WHERE
 		    module_path=$1
 			AND m.version=$2
-			AND m.module_path=p.path`, modulePath, version)
+			AND m.module_path=p.path
+			AND `+db.notDeletedSQL("m"), modulePath, version)
 	if err := row.Scan(&readme.Filepath, &readme.Contents); err != nil && err != sql.ErrNoRows {
 		return nil, err
 	}
@@ -185,9 +212,9 @@ func (db *DB) LegacyGetDirectory(ctx context.Context, dirPath, modulePath, versi
 		args  []interface{}
 	)
 	if modulePath == internal.UnknownModulePath || modulePath == stdlib.ModulePath {
-		query, args = directoryQueryWithoutModulePath(dirPath, version, fields)
+		query, args = directoryQueryWithoutModulePath(dirPath, version, fields, db.notDeletedSQL("m"))
 	} else {
-		query, args = directoryQueryWithModulePath(dirPath, modulePath, version, fields)
+		query, args = directoryQueryWithModulePath(dirPath, modulePath, version, fields, db.notDeletedSQL("m"))
 	}
 
 	var (
@@ -240,7 +267,7 @@ func (db *DB) LegacyGetDirectory(ctx context.Context, dirPath, modulePath, versi
 		packages = append(packages, &pkg)
 		return nil
 	}
-	if err := db.db.RunQuery(ctx, query, collect, args...); err != nil {
+	if err := db.readDB().RunQuery(ctx, query, collect, args...); err != nil {
 		return nil, err
 	}
 	if len(packages) == 0 {
@@ -296,8 +323,10 @@ const orderByLatest = `
 				module_path DESC`
 
 // directoryQueryWithoutModulePath returns the query and args needed to fetch a
-// directory when no module path is provided.
-func directoryQueryWithoutModulePath(dirPath, version string, fields internal.FieldSet) (string, []interface{}) {
+// directory when no module path is provided. The module versions are
+// restricted to those for which notDeleted, a predicate on the modules table
+// under the name m, holds.
+func directoryQueryWithoutModulePath(dirPath, version string, fields internal.FieldSet, notDeleted string) (string, []interface{}) {
 	if version == internal.LatestVersion {
 		// internal packages are filtered out from the search_documents table.
 		// However, for other packages, fetching from search_documents is
@@ -324,7 +353,7 @@ func directoryQueryWithoutModulePath(dirPath, version string, fields internal.Fi
 			INNER JOIN (
 				SELECT *
 				FROM
-					modules
+					modules m
 				WHERE
 					(module_path, version) IN (
 						SELECT module_path, version
@@ -332,6 +361,7 @@ func directoryQueryWithoutModulePath(dirPath, version string, fields internal.Fi
 						WHERE tsv_parent_directories @@ $1::tsquery
 						GROUP BY 1, 2
 					)
+					AND %s
 				%s
 				LIMIT 1
 			) m
@@ -339,7 +369,7 @@ func directoryQueryWithoutModulePath(dirPath, version string, fields internal.Fi
 				p.module_path = m.module_path
 				AND p.version = m.version
 			WHERE tsv_parent_directories @@ $1::tsquery;`,
-			directoryColumns(fields), table, orderByLatest), []interface{}{dirPath}
+			directoryColumns(fields), table, notDeleted, orderByLatest), []interface{}{dirPath}
 	}
 
 	// dirPath and version are specified, so get that directory version
@@ -360,6 +390,7 @@ func directoryQueryWithoutModulePath(dirPath, version string, fields internal.Fi
 				AND p.version = m.version
 			WHERE
 				p.version = $2
+				AND %s
 			ORDER BY
 				module_path DESC
 			LIMIT 1
@@ -369,12 +400,14 @@ func directoryQueryWithoutModulePath(dirPath, version string, fields internal.Fi
 		INNER JOIN module_version m
 		ON
 			p.module_path = m.module_path
-			AND p.version = m.version;`, directoryColumns(fields)), []interface{}{dirPath, version}
+			AND p.version = m.version;`, notDeleted, directoryColumns(fields)), []interface{}{dirPath, version}
 }
 
-// directoryQueryWithoutModulePath returns the query and args needed to fetch a
-// directory when a module path is provided.
-func directoryQueryWithModulePath(dirPath, modulePath, version string, fields internal.FieldSet) (string, []interface{}) {
+// directoryQueryWithModulePath returns the query and args needed to fetch a
+// directory when a module path is provided. The module versions are
+// restricted to those for which notDeleted, a predicate on the modules table
+// under the name m, holds.
+func directoryQueryWithModulePath(dirPath, modulePath, version string, fields internal.FieldSet, notDeleted string) (string, []interface{}) {
 	if version == internal.LatestVersion {
 		// dirPath and modulePath are specified, so get the latest version of
 		// the package in the specified module.
@@ -383,7 +416,7 @@ func directoryQueryWithModulePath(dirPath, modulePath, version string, fields in
 			FROM packages p
 			INNER JOIN (
 				SELECT *
-				FROM modules
+				FROM modules m
 				WHERE
 					module_path = $2
 					AND version IN (
@@ -393,6 +426,7 @@ func directoryQueryWithModulePath(dirPath, modulePath, version string, fields in
 							tsv_parent_directories @@ $1::tsquery
 							AND module_path=$2
 					)
+					AND %s
 				%s
 				LIMIT 1
 			) m
@@ -402,7 +436,7 @@ func directoryQueryWithModulePath(dirPath, modulePath, version string, fields in
 			WHERE
 				p.module_path = $2
 				AND tsv_parent_directories @@ $1::tsquery;`,
-			directoryColumns(fields), orderByLatest), []interface{}{dirPath, modulePath}
+			directoryColumns(fields), notDeleted, orderByLatest), []interface{}{dirPath, modulePath}
 	}
 
 	// dirPath, modulePath and version were all specified. Only one
@@ -419,5 +453,6 @@ func directoryQueryWithModulePath(dirPath, modulePath, version string, fields in
 			WHERE
 				tsv_parent_directories @@ $1::tsquery
 				AND p.module_path = $2
-				AND p.version = $3;`, directoryColumns(fields)), []interface{}{dirPath, modulePath, version}
+				AND p.version = $3
+				AND %s;`, directoryColumns(fields), notDeleted), []interface{}{dirPath, modulePath, version}
 }
@@ -273,6 +273,7 @@ func TestLegacyGetDirectory(t *testing.T) {
 				cmp.AllowUnexported(source.Info{}),
 				// The packages table only includes partial license information; it omits the Coverage field.
 				cmpopts.IgnoreFields(licenses.Metadata{}, "Coverage"),
+				cmpopts.IgnoreFields(internal.PackageNew{}, "Facts"),
 			}
 			if diff := cmp.Diff(wantDirectory, got, opts...); diff != "" {
 				t.Errorf("testDB.LegacyGetDirectory(ctx, %q, %q, %q) mismatch (-want +got):\n%s", tc.dirPath, tc.modulePath, tc.version, diff)
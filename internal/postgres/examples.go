@@ -0,0 +1,62 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+// GetExamples returns the examples of the package with path pkgPath at the
+// given version, ordered by name. If the package exists at that version in
+// more than one module, the examples of the package in the module with the
+// longest path are returned, as in GetPackageSymbols.
+//
+// If the package does not exist at the version, GetExamples returns an error
+// that wraps derrors.NotFound.
+func (db *DB) GetExamples(ctx context.Context, pkgPath, version string) (_ []*internal.Example, err error) {
+	defer derrors.Wrap(&err, "GetExamples(ctx, %q, %q)", pkgPath, version)
+
+	if pkgPath == "" || version == "" {
+		return nil, fmt.Errorf("pkgPath and version must both be non-empty: %w", derrors.InvalidArgument)
+	}
+	var modulePath string
+	err = db.db.QueryRow(ctx, `
+		SELECT module_path
+		FROM packages
+		WHERE path = $1 AND version = $2
+		ORDER BY module_path DESC
+		LIMIT 1`, pkgPath, version).Scan(&modulePath)
+	switch err {
+	case sql.ErrNoRows:
+		return nil, fmt.Errorf("package %s@%s: %w", pkgPath, version, derrors.NotFound)
+	case nil:
+	default:
+		return nil, err
+	}
+
+	query := `
+		SELECT name, code, output
+		FROM examples
+		WHERE package_path = $1 AND module_path = $2 AND version = $3
+		ORDER BY name`
+	var exs []*internal.Example
+	collect := func(rows *sql.Rows) error {
+		var e internal.Example
+		if err := rows.Scan(&e.Name, &e.Code, &e.Output); err != nil {
+			return fmt.Errorf("rows.Scan(): %v", err)
+		}
+		exs = append(exs, &e)
+		return nil
+	}
+	if err := db.db.RunQuery(ctx, query, collect, pkgPath, modulePath, version); err != nil {
+		return nil, err
+	}
+	return exs, nil
+}
@@ -0,0 +1,59 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/testing/sample"
+)
+
+func TestGetExamples(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	m := sample.Module(sample.ModulePath, sample.VersionString, sample.Suffix)
+	pkg := m.LegacyPackages[0]
+	pkg.Examples = []*internal.Example{
+		{Name: "ExampleT_M", Code: "var t p.T\nt.M()"},
+		{Name: "Example", Code: "fmt.Println(\"hello\")", Output: "hello\n"},
+		{Name: "ExampleF", Code: "p.F()", Output: "1\n2\n"},
+	}
+	if err := testDB.InsertModule(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := testDB.GetExamples(ctx, pkg.Path, sample.VersionString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*internal.Example{pkg.Examples[1], pkg.Examples[2], pkg.Examples[0]}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("GetExamples mismatch (-want +got):\n%s", diff)
+	}
+
+	// Reinserting the module replaces its examples.
+	pkg.Examples = pkg.Examples[:1]
+	if err := testDB.InsertModule(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+	got, err = testDB.GetExamples(ctx, pkg.Path, sample.VersionString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(pkg.Examples, got); diff != "" {
+		t.Errorf("GetExamples after reinsert mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := testDB.GetExamples(ctx, pkg.Path, "v9.0.0"); !errors.Is(err, derrors.NotFound) {
+		t.Errorf("got error %v, want NotFound", err)
+	}
+}
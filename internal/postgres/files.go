@@ -0,0 +1,120 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/database"
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+// insertModuleFiles inserts the files of m into the module_files table.
+func insertModuleFiles(ctx context.Context, db *database.DB, m *internal.Module, moduleID int) (err error) {
+	defer derrors.Wrap(&err, "insertModuleFiles(ctx, %q, %q)", m.ModulePath, m.Version)
+
+	var values []interface{}
+	for _, f := range m.Files {
+		values = append(values, moduleID, f.Name, f.Size, f.ContentType, f.Contents)
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	cols := []string{"module_id", "path", "size", "content_type", "contents"}
+	return db.BulkUpsert(ctx, "module_files", cols, values, []string{"module_id", "path"})
+}
+
+// GetModuleFiles returns information about the files of the given module
+// version at subpath, a slash-separated path relative to the module root.
+//
+// If subpath names a file, GetModuleFiles returns just that file, with its
+// contents if they are stored. Otherwise it returns the files and
+// subdirectories of the directory subpath, directories first, each sorted by
+// name. It returns a derrors.NotFound error if the module version or subpath
+// doesn't exist.
+func (db *DB) GetModuleFiles(ctx context.Context, modulePath, version, subpath string) (_ []*internal.FileInfo, err error) {
+	defer derrors.Wrap(&err, "GetModuleFiles(ctx, %q, %q, %q)", modulePath, version, subpath)
+
+	var moduleID int
+	err = db.db.QueryRow(ctx, `
+		SELECT id FROM modules WHERE module_path = $1 AND version = $2`,
+		modulePath, version).Scan(&moduleID)
+	switch err {
+	case sql.ErrNoRows:
+		return nil, derrors.NotFound
+	case nil:
+	default:
+		return nil, err
+	}
+
+	subpath = strings.Trim(subpath, "/")
+	if subpath != "" {
+		f := &internal.FileInfo{Name: subpath}
+		err = db.db.QueryRow(ctx, `
+			SELECT size, content_type, contents
+			FROM module_files
+			WHERE module_id = $1 AND path = $2`,
+			moduleID, subpath).Scan(&f.Size, &f.ContentType, &f.Contents)
+		switch err {
+		case sql.ErrNoRows:
+		case nil:
+			return []*internal.FileInfo{f}, nil
+		default:
+			return nil, err
+		}
+	}
+
+	prefix := ""
+	if subpath != "" {
+		prefix = subpath + "/"
+	}
+	var (
+		files []*internal.FileInfo
+		dirs  = map[string]*internal.FileInfo{}
+	)
+	collect := func(rows *sql.Rows) error {
+		var (
+			name string
+			f    internal.FileInfo
+		)
+		if err := rows.Scan(&name, &f.Size, &f.ContentType); err != nil {
+			return err
+		}
+		if i := strings.IndexByte(strings.TrimPrefix(name, prefix), '/'); i >= 0 {
+			dirName := name[:len(prefix)+i]
+			d := dirs[dirName]
+			if d == nil {
+				d = &internal.FileInfo{Name: dirName, IsDir: true}
+				dirs[dirName] = d
+			}
+			d.Size += f.Size
+			return nil
+		}
+		f.Name = name
+		files = append(files, &f)
+		return nil
+	}
+	if err := db.db.RunQuery(ctx, `
+		SELECT path, size, content_type
+		FROM module_files
+		WHERE module_id = $1 AND left(path, length($2)) = $2`,
+		collect, moduleID, prefix); err != nil {
+		return nil, err
+	}
+	if len(files) == 0 && len(dirs) == 0 {
+		return nil, derrors.NotFound
+	}
+	var entries []*internal.FileInfo
+	for _, d := range dirs {
+		entries = append(entries, d)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return append(entries, files...), nil
+}
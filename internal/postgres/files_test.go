@@ -0,0 +1,132 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/testing/sample"
+)
+
+func TestGetModuleFiles(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	m := sample.Module("m.com", "v1.0.0", "p")
+	m.Files = []*internal.FileInfo{
+		{Name: "LICENSE", Size: 100, ContentType: "text/plain; charset=utf-8", Contents: []byte("license")},
+		{Name: "go.mod", Size: 13, ContentType: "text/plain; charset=utf-8", Contents: []byte("module m.com\n")},
+		{Name: "p/a/a.go", Size: 20, ContentType: "text/x-go; charset=utf-8", Contents: []byte("package a\n")},
+		{Name: "p/logo.png", Size: 300, ContentType: "image/png"},
+		{Name: "p/p.go", Size: 10, ContentType: "text/x-go; charset=utf-8", Contents: []byte("package p\n")},
+	}
+	if err := testDB.InsertModule(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		name, modulePath, version, subpath string
+		want                               []*internal.FileInfo
+		wantErr                            error
+	}{
+		{
+			name:       "module root",
+			modulePath: "m.com",
+			version:    "v1.0.0",
+			want: []*internal.FileInfo{
+				{Name: "p", Size: 330, IsDir: true},
+				{Name: "LICENSE", Size: 100, ContentType: "text/plain; charset=utf-8"},
+				{Name: "go.mod", Size: 13, ContentType: "text/plain; charset=utf-8"},
+			},
+		},
+		{
+			name:       "directory",
+			modulePath: "m.com",
+			version:    "v1.0.0",
+			subpath:    "p/",
+			want: []*internal.FileInfo{
+				{Name: "p/a", Size: 20, IsDir: true},
+				{Name: "p/logo.png", Size: 300, ContentType: "image/png"},
+				{Name: "p/p.go", Size: 10, ContentType: "text/x-go; charset=utf-8"},
+			},
+		},
+		{
+			name:       "file",
+			modulePath: "m.com",
+			version:    "v1.0.0",
+			subpath:    "p/a/a.go",
+			want: []*internal.FileInfo{
+				{Name: "p/a/a.go", Size: 20, ContentType: "text/x-go; charset=utf-8", Contents: []byte("package a\n")},
+			},
+		},
+		{
+			name:       "file without contents",
+			modulePath: "m.com",
+			version:    "v1.0.0",
+			subpath:    "p/logo.png",
+			want: []*internal.FileInfo{
+				{Name: "p/logo.png", Size: 300, ContentType: "image/png"},
+			},
+		},
+		{
+			name:       "missing path",
+			modulePath: "m.com",
+			version:    "v1.0.0",
+			subpath:    "p/b",
+			wantErr:    derrors.NotFound,
+		},
+		{
+			name:       "missing version",
+			modulePath: "m.com",
+			version:    "v1.1.0",
+			wantErr:    derrors.NotFound,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := testDB.GetModuleFiles(ctx, test.modulePath, test.version, test.subpath)
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Fatalf("got error %v, want %v", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGetModuleFilesNonRedistributable(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	m := sample.Module("m.com", "v1.0.0", "p")
+	m.IsRedistributable = false
+	m.Files = []*internal.FileInfo{
+		{Name: "p/p.go", Size: 10, ContentType: "text/x-go; charset=utf-8", Contents: []byte("package p\n")},
+	}
+	if err := testDB.InsertModule(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+	got, err := testDB.GetModuleFiles(ctx, "m.com", "v1.0.0", "p/p.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*internal.FileInfo{{Name: "p/p.go", Size: 10, ContentType: "text/x-go; charset=utf-8"}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
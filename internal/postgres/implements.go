@@ -0,0 +1,79 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/database"
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+// maxImplementations is the maximum number of types returned by
+// GetInterfaceImplementations.
+const maxImplementations = 1000
+
+// upsertImplementations replaces the rows of implements for the module of m,
+// which must be the latest version of the module, with its implementations.
+func upsertImplementations(ctx context.Context, db *database.DB, m *internal.Module) (err error) {
+	defer derrors.Wrap(&err, "upsertImplementations(ctx, %q, %q)", m.ModulePath, m.Version)
+
+	var (
+		values                                 []interface{}
+		ifacePkgs, ifaceNames, pkgs, typeNames []string
+	)
+	for _, impl := range m.Implementations {
+		values = append(values, impl.InterfacePackagePath, impl.InterfaceName,
+			impl.Type.PackagePath, m.ModulePath, m.Version, impl.Type.TypeName)
+		ifacePkgs = append(ifacePkgs, impl.InterfacePackagePath)
+		ifaceNames = append(ifaceNames, impl.InterfaceName)
+		pkgs = append(pkgs, impl.Type.PackagePath)
+		typeNames = append(typeNames, impl.Type.TypeName)
+	}
+	if err := deleteRowsNotIn(ctx, db, "implements", "module_path = $1", []interface{}{m.ModulePath},
+		[]string{"interface_package_path", "interface_name", "package_path", "type_name"},
+		ifacePkgs, ifaceNames, pkgs, typeNames); err != nil {
+		return err
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	cols := []string{"interface_package_path", "interface_name", "package_path", "module_path", "version", "type_name"}
+	return db.BulkUpsert(ctx, "implements", cols, values,
+		[]string{"interface_package_path", "interface_name", "package_path", "module_path", "type_name"})
+}
+
+// GetInterfaceImplementations returns up to maxImplementations exported
+// types of the latest versions of modules that implement the interface
+// ifaceName of the package ifacePkg, ordered by package path and type name.
+func (db *DB) GetInterfaceImplementations(ctx context.Context, ifacePkg, ifaceName string) (_ []*internal.TypeRef, err error) {
+	defer derrors.Wrap(&err, "GetInterfaceImplementations(ctx, %q, %q)", ifacePkg, ifaceName)
+
+	if ifacePkg == "" || ifaceName == "" {
+		return nil, fmt.Errorf("ifacePkg and ifaceName must both be non-empty: %w", derrors.InvalidArgument)
+	}
+	query := `
+		SELECT package_path, type_name, module_path
+		FROM implements
+		WHERE interface_package_path = $1 AND interface_name = $2
+		ORDER BY package_path, type_name, module_path
+		LIMIT $3`
+	var refs []*internal.TypeRef
+	collect := func(rows *sql.Rows) error {
+		var r internal.TypeRef
+		if err := rows.Scan(&r.PackagePath, &r.TypeName, &r.ModulePath); err != nil {
+			return fmt.Errorf("rows.Scan(): %v", err)
+		}
+		refs = append(refs, &r)
+		return nil
+	}
+	if err := db.db.RunQuery(ctx, query, collect, ifacePkg, ifaceName, maxImplementations); err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/testing/sample"
+)
+
+func TestGetInterfaceImplementations(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	ifacePkg := sample.ModulePath + "/a"
+	implPkg := sample.ModulePath + "/b"
+	impl := func(ifaceName, typeName string) *internal.Implementation {
+		return &internal.Implementation{
+			InterfacePackagePath: ifacePkg,
+			InterfaceName:        ifaceName,
+			Type:                 internal.TypeRef{PackagePath: implPkg, TypeName: typeName, ModulePath: sample.ModulePath},
+		}
+	}
+	insert := func(version string, impls ...*internal.Implementation) {
+		t.Helper()
+		m := sample.Module(sample.ModulePath, version, "a", "b")
+		m.Implementations = impls
+		if err := testDB.InsertModule(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	insert("v1.1.0", impl("Shape", "Square"), impl("Shape", "Circle"), impl("Stringer", "Circle"))
+	// Older versions don't replace the implementations of the latest version.
+	insert("v1.0.0", impl("Shape", "Triangle"))
+
+	typ := func(name string) *internal.TypeRef {
+		return &internal.TypeRef{PackagePath: implPkg, TypeName: name, ModulePath: sample.ModulePath}
+	}
+	for _, test := range []struct {
+		iface string
+		want  []*internal.TypeRef
+	}{
+		{"Shape", []*internal.TypeRef{typ("Circle"), typ("Square")}},
+		{"Stringer", []*internal.TypeRef{typ("Circle")}},
+		{"Missing", nil},
+	} {
+		got, err := testDB.GetInterfaceImplementations(ctx, ifacePkg, test.iface)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("GetInterfaceImplementations(%q, %q) mismatch (-want +got):\n%s", ifacePkg, test.iface, diff)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+// RequestedModulePriority is the priority of module versions that are
+// enqueued because a user requested them before they were indexed. It is
+// higher than the default priority of 0, so that they are fetched first.
+const RequestedModulePriority = 100
+
+// A QueueItem is a module version in module_index_queue.
+type QueueItem struct {
+	ModulePath string
+	Version    string
+	Priority   int
+	EnqueuedAt time.Time
+}
+
+// EnqueueModule adds modulePath@version to module_index_queue with the given
+// priority. If the module version is already enqueued, it keeps its place in
+// the queue, and its priority is raised to priority if that is higher.
+func (db *DB) EnqueueModule(ctx context.Context, modulePath, version string, priority int) (err error) {
+	defer derrors.Wrap(&err, "EnqueueModule(ctx, %q, %q, %d)", modulePath, version, priority)
+
+	_, err = db.db.Exec(ctx, `
+		INSERT INTO module_index_queue (module_path, version, priority)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (module_path, version) DO UPDATE
+		SET priority = GREATEST(module_index_queue.priority, excluded.priority)`,
+		modulePath, version, priority)
+	return err
+}
+
+// EnqueueRequestedModule is like EnqueueModule with RequestedModulePriority,
+// for module versions that a user requested but that could not be found. It
+// does nothing, and returns false, if fetching the module version returned a
+// 404 after notFoundSince, according to module_version_states or version_map,
+// or if maxQueued other module versions are already enqueued, so that requests
+// for modules that do not exist cannot fill the queue.
+func (db *DB) EnqueueRequestedModule(ctx context.Context, modulePath, version string, notFoundSince time.Time, maxQueued int) (enqueued bool, err error) {
+	defer derrors.Wrap(&err, "EnqueueRequestedModule(ctx, %q, %q, %v, %d)", modulePath, version, notFoundSince, maxQueued)
+
+	res, err := db.db.Exec(ctx, `
+		INSERT INTO module_index_queue (module_path, version, priority)
+		SELECT $1, $2, $3
+		WHERE NOT EXISTS (
+			SELECT 1 FROM module_version_states
+			WHERE module_path = $1 AND version = $2
+			AND status = 404 AND last_processed_at > $4
+		)
+		AND NOT EXISTS (
+			SELECT 1 FROM version_map
+			WHERE module_path = $1 AND requested_version = $2
+			AND status = 404 AND updated_at > $4
+		)
+		AND (
+			(SELECT COUNT(*) FROM (SELECT 1 FROM module_index_queue LIMIT $5) q) < $5
+			OR EXISTS (
+				SELECT 1 FROM module_index_queue
+				WHERE module_path = $1 AND version = $2
+			)
+		)
+		ON CONFLICT (module_path, version) DO UPDATE
+		SET priority = GREATEST(module_index_queue.priority, excluded.priority)`,
+		modulePath, version, RequestedModulePriority, notFoundSince, maxQueued)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("RowsAffected(): %v", err)
+	}
+	return n > 0, nil
+}
+
+// DequeueModule removes the module version with the highest priority from
+// module_index_queue and returns it. Module versions with the same priority
+// are dequeued in the order in which they were enqueued. Rows locked by
+// concurrent calls are skipped, so that concurrent workers never dequeue the
+// same module version. If the queue is empty, DequeueModule returns an error
+// that wraps derrors.NotFound.
+//
+// The module version is removed from the queue whether or not it is fetched
+// successfully; failed fetches are retried through module_version_states.
+func (db *DB) DequeueModule(ctx context.Context) (_ *QueueItem, err error) {
+	defer derrors.Wrap(&err, "DequeueModule(ctx)")
+
+	var item QueueItem
+	err = db.db.QueryRow(ctx, `
+		DELETE FROM module_index_queue
+		WHERE (module_path, version) = (
+			SELECT module_path, version
+			FROM module_index_queue
+			ORDER BY priority DESC, enqueued_at, module_path, version
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING module_path, version, priority, enqueued_at`).
+		Scan(&item.ModulePath, &item.Version, &item.Priority, &item.EnqueuedAt)
+	switch err {
+	case sql.ErrNoRows:
+		return nil, derrors.NotFound
+	case nil:
+		return &item, nil
+	default:
+		return nil, err
+	}
+}
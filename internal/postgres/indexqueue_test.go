@@ -0,0 +1,98 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+func TestEnqueueDequeueModule(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	for _, e := range []struct {
+		modulePath, version string
+		priority            int
+	}{
+		{"example.com/low", "v1.0.0", 0},
+		{"example.com/first", "v1.0.0", 5},
+		{"example.com/second", "v1.0.0", 5},
+		{"example.com/raised", "v1.0.0", 0},
+		// Enqueueing again raises the priority...
+		{"example.com/raised", "v1.0.0", RequestedModulePriority},
+		// ...but never lowers it.
+		{"example.com/first", "v1.0.0", 0},
+	} {
+		if err := testDB.EnqueueModule(ctx, e.modulePath, e.version, e.priority); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, want := range []struct {
+		modulePath string
+		priority   int
+	}{
+		{"example.com/raised", RequestedModulePriority},
+		{"example.com/first", 5},
+		{"example.com/second", 5},
+		{"example.com/low", 0},
+	} {
+		got, err := testDB.DequeueModule(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ModulePath != want.modulePath || got.Version != "v1.0.0" || got.Priority != want.priority {
+			t.Errorf("got %s@%s with priority %d, want %s@v1.0.0 with priority %d",
+				got.ModulePath, got.Version, got.Priority, want.modulePath, want.priority)
+		}
+	}
+	if _, err := testDB.DequeueModule(ctx); !errors.Is(err, derrors.NotFound) {
+		t.Errorf("DequeueModule on empty queue: got %v, want NotFound", err)
+	}
+}
+
+func TestEnqueueRequestedModule(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	// example.com/gone was not found a minute ago.
+	if err := testDB.UpsertVersionMap(ctx, &internal.VersionMap{
+		ModulePath:       "example.com/gone",
+		RequestedVersion: "latest",
+		Status:           404,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	const maxQueued = 2
+	for _, test := range []struct {
+		modulePath    string
+		notFoundSince time.Time
+		want          bool
+	}{
+		{"example.com/gone", time.Now().Add(-time.Hour), false},
+		{"example.com/gone", time.Now().Add(time.Minute), true},
+		{"example.com/new", time.Now(), true},
+		// Enqueueing again is allowed...
+		{"example.com/new", time.Now(), true},
+		// ...but the queue is full.
+		{"example.com/other", time.Now(), false},
+	} {
+		got, err := testDB.EnqueueRequestedModule(ctx, test.modulePath, "latest", test.notFoundSince, maxQueued)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("EnqueueRequestedModule(%q, %v) = %t, want %t", test.modulePath, test.notFoundSince, got, test.want)
+		}
+	}
+}
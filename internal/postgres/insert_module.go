@@ -25,14 +25,26 @@ import (
 	"golang.org/x/pkgsite/internal/database"
 	"golang.org/x/pkgsite/internal/derrors"
 	"golang.org/x/pkgsite/internal/experiment"
+	"golang.org/x/pkgsite/internal/licenses"
 	"golang.org/x/pkgsite/internal/log"
+	"golang.org/x/pkgsite/internal/scoring"
 	"golang.org/x/pkgsite/internal/stdlib"
+	"golang.org/x/pkgsite/internal/tracing"
 	"golang.org/x/pkgsite/internal/version"
 )
 
 // InsertModule inserts a version into the database using
 // db.saveVersion, along with a search document corresponding to each of its
 // packages.
+//
+// InsertModule is idempotent: every row is written with an upsert, inside a
+// single transaction, so inserting the same module twice, or retrying after
+// a failure, leaves the same rows as inserting it once. If the module version
+// is being inserted by another process, InsertModule returns a
+// derrors.ModuleLocked error; see tryLockModuleVersion. Rows for the module
+// version that the new data no longer has, like those of removed symbols,
+// are deleted. Rows of other versions of the module are left alone, except
+// for the tables that only hold the latest version, like search_documents.
 func (db *DB) InsertModule(ctx context.Context, m *internal.Module) (err error) {
 	defer func() {
 		if m == nil {
@@ -41,10 +53,17 @@ func (db *DB) InsertModule(ctx context.Context, m *internal.Module) (err error)
 		}
 		derrors.Wrap(&err, "DB.InsertModule(ctx, Module(%q, %q))", m.ModulePath, m.Version)
 	}()
+	ctx, span := tracing.StartSpan(ctx, "DB.InsertModule")
+	defer span.End()
 
 	if err := validateModule(m); err != nil {
 		return err
 	}
+	if db.SumDB != nil && m.ModulePath != stdlib.ModulePath {
+		if err := db.SumDB.VerifyModuleChecksum(ctx, m.ModulePath, m.Version, m.ZipHash); err != nil {
+			return err
+		}
+	}
 	// Compare existing data from the database, and the module to be
 	// inserted. Rows that currently exist should not be missing from the
 	// new module. We want to be sure that we will overwrite every row that
@@ -63,8 +82,8 @@ func (db *DB) InsertModule(ctx context.Context, m *internal.Module) (err error)
 }
 
 // saveModule inserts a Module into the database along with its packages,
-// imports, and licenses.  If any of these rows already exist, the module and
-// corresponding will be deleted and reinserted.
+// imports, and licenses.  If any of these rows already exist, they are
+// updated in place.
 // If the module is malformed then insertion will fail.
 //
 // A derrors.InvalidArgument error will be returned if the given module and
@@ -76,21 +95,40 @@ func (db *DB) saveModule(ctx context.Context, m *internal.Module) (err error) {
 
 	logMemory(ctx, "at start of saveModule")
 	return db.db.Transact(ctx, sql.LevelDefault, func(tx *database.DB) error {
+		// Lock the module version for the whole transaction, so that it is
+		// not inserted by two workers at once.
+		if err := tryLockModuleVersion(ctx, tx, m.ModulePath, m.Version); err != nil {
+			return err
+		}
 		moduleID, err := insertModule(ctx, tx, m)
 		if err != nil {
 			return err
 		}
 		logMemory(ctx, "after insertModule")
 
+		if err := insertModuleEvent(ctx, tx, ModuleEventIndexed, m.ModulePath, m.Version); err != nil {
+			return err
+		}
+
 		if err := insertLicenses(ctx, tx, m, moduleID); err != nil {
 			return err
 		}
 
 		logMemory(ctx, "after insertLicenses")
+		if err := insertModuleFiles(ctx, tx, m, moduleID); err != nil {
+			return err
+		}
 		if err := insertPackages(ctx, tx, m); err != nil {
 			return err
 		}
 		logMemory(ctx, "after insertPackages")
+		maxDepth := db.MaxDependencyDepth
+		if maxDepth <= 0 {
+			maxDepth = defaultMaxDependencyDepth
+		}
+		if err := updateLicenseConflicts(ctx, tx, m, maxDepth); err != nil {
+			return err
+		}
 
 		if experiment.IsActive(ctx, internal.ExperimentInsertDirectories) {
 			if err := insertDirectories(ctx, tx, m, moduleID); err != nil {
@@ -109,6 +147,10 @@ func (db *DB) saveModule(ctx context.Context, m *internal.Module) (err error) {
 			return err
 		}
 
+		if err := updateRetractions(ctx, tx, m); err != nil {
+			return err
+		}
+
 		// We only insert into imports_unique and search_documents if this is
 		// the latest version of the module.
 		isLatest, err := isLatestVersion(ctx, tx, m.ModulePath, m.Version)
@@ -119,6 +161,18 @@ func (db *DB) saveModule(ctx context.Context, m *internal.Module) (err error) {
 			return nil
 		}
 
+		if err := upsertWorkspace(ctx, tx, m); err != nil {
+			return err
+		}
+		if err := upsertModuleLinks(ctx, tx, m); err != nil {
+			return err
+		}
+		if err := upsertSymbolDefinitions(ctx, tx, m); err != nil {
+			return err
+		}
+		if err := upsertImplementations(ctx, tx, m); err != nil {
+			return err
+		}
 		if err := insertImportsUnique(ctx, tx, m); err != nil {
 			return err
 		}
@@ -152,11 +206,24 @@ func (db *DB) saveModule(ctx context.Context, m *internal.Module) (err error) {
 			log.Infof(ctx, "%s@%s: not inserting into search documents", m.ModulePath, m.Version)
 			return err
 		}
-		// Insert the module's packages into search_documents.
-		return UpsertSearchDocuments(ctx, tx, m)
+		// Insert the module's packages into search_documents, and their
+		// symbols into symbol_search_documents.
+		if err := UpsertSearchDocuments(ctx, tx, m); err != nil {
+			return err
+		}
+		return upsertSymbolSearchDocuments(ctx, tx, m)
 	})
 }
 
+// insertModule upserts the modules row of m and returns its id.
+//
+// If the row exists, the columns that come from the module's zip and go.mod
+// file, which a reprocessed module may change, are updated: the README,
+// source info, redistributability, retractions and toolchain version. If the
+// module version was deleted, it is restored. The other columns are
+// preserved, so the row keeps its id: the module path and version, the
+// columns derived from them (sort_version, version_type and series_path), the
+// commit time, and has_go_mod.
 func insertModule(ctx context.Context, db *database.DB, m *internal.Module) (_ int, err error) {
 	ctx, span := trace.StartSpan(ctx, "insertModule")
 	defer span.End()
@@ -178,15 +245,22 @@ func insertModule(ctx context.Context, db *database.DB, m *internal.Module) (_ i
 			series_path,
 			source_info,
 			redistributable,
-			has_go_mod)
-		VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10, $11)
+			has_go_mod,
+			retracted,
+			retracted_versions,
+			toolchain_version)
+		VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10, $11, $12, $13, $14)
 		ON CONFLICT
 			(module_path, version)
 		DO UPDATE SET
 			readme_file_path=excluded.readme_file_path,
 			readme_contents=excluded.readme_contents,
 			source_info=excluded.source_info,
-			redistributable=excluded.redistributable
+			redistributable=excluded.redistributable,
+			retracted=excluded.retracted,
+			retracted_versions=excluded.retracted_versions,
+			toolchain_version=excluded.toolchain_version,
+			deleted_at=NULL
 		RETURNING id`,
 		m.ModulePath,
 		m.Version,
@@ -199,6 +273,9 @@ func insertModule(ctx context.Context, db *database.DB, m *internal.Module) (_ i
 		sourceInfoJSON,
 		m.IsRedistributable,
 		m.HasGoMod,
+		m.IsRetracted,
+		pq.Array(m.RetractedVersions),
+		m.ToolchainVersion,
 	).Scan(&moduleID)
 	if err != nil {
 		return 0, err
@@ -217,7 +294,8 @@ func insertLicenses(ctx context.Context, db *database.DB, m *internal.Module, mo
 			return fmt.Errorf("marshalling %+v: %v", l.Coverage, err)
 		}
 		licenseValues = append(licenseValues, m.ModulePath, m.Version,
-			l.FilePath, makeValidUnicode(string(l.Contents)), pq.Array(l.Types), covJSON, moduleID)
+			l.FilePath, makeValidUnicode(string(l.Contents)), pq.Array(l.Types), pq.Array(spdxTypes(l.Types)),
+			covJSON, moduleID)
 	}
 	if len(licenseValues) > 0 {
 		licenseCols := []string{
@@ -226,6 +304,7 @@ func insertLicenses(ctx context.Context, db *database.DB, m *internal.Module, mo
 			"file_path",
 			"contents",
 			"types",
+			"spdx_types",
 			"coverage",
 			"module_id",
 		}
@@ -235,6 +314,22 @@ func insertLicenses(ctx context.Context, db *database.DB, m *internal.Module, mo
 	return nil
 }
 
+// spdxTypes returns the SPDX identifiers of the license types that have one,
+// without duplicates. The others are only stored in their raw form.
+func spdxTypes(types []string) []string {
+	ids := []string{}
+	seen := map[string]bool{}
+	for _, typ := range types {
+		id, err := licenses.NormalizeSPDX(typ)
+		if err != nil || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids
+}
+
 func insertPackages(ctx context.Context, db *database.DB, m *internal.Module) (err error) {
 	ctx, span := trace.StartSpan(ctx, "insertPackages")
 	defer span.End()
@@ -255,7 +350,10 @@ func insertPackages(ctx context.Context, db *database.DB, m *internal.Module) (e
 	for _, p := range m.LegacyPackages {
 		sort.Strings(p.Imports)
 	}
-	var pkgValues, importValues []interface{}
+	var (
+		pkgValues, importValues, symbolValues, exampleValues []interface{}
+		symbolPkgs, symbolNames, examplePkgs, exampleNames   []string
+	)
 	for _, p := range m.LegacyPackages {
 		if p.DocumentationHTML == internal.StringFieldMissing {
 			return errors.New("saveModule: package missing DocumentationHTML")
@@ -290,10 +388,25 @@ func insertPackages(ctx context.Context, db *database.DB, m *internal.Module) (e
 			p.GOOS,
 			p.GOARCH,
 			m.CommitTime,
+			packageHasTests(m, p.Path),
+			docCoverage(p),
+			p.RequiresCgo,
+			p.IsDeprecated,
+			sql.NullString{String: p.DeprecationMessage, Valid: p.IsDeprecated},
 		)
 		for _, i := range p.Imports {
 			importValues = append(importValues, p.Path, m.ModulePath, m.Version, i)
 		}
+		for _, s := range p.Symbols {
+			symbolValues = append(symbolValues, p.Path, m.ModulePath, m.Version, s.Name, string(s.Kind), makeValidUnicode(s.Signature))
+			symbolPkgs = append(symbolPkgs, p.Path)
+			symbolNames = append(symbolNames, s.Name)
+		}
+		for _, e := range p.Examples {
+			exampleValues = append(exampleValues, p.Path, m.ModulePath, m.Version, e.Name, makeValidUnicode(e.Code), makeValidUnicode(e.Output))
+			examplePkgs = append(examplePkgs, p.Path)
+			exampleNames = append(exampleNames, e.Name)
+		}
 	}
 	if len(pkgValues) > 0 {
 		uniqueCols := []string{"path", "module_path", "version"}
@@ -311,6 +424,11 @@ func insertPackages(ctx context.Context, db *database.DB, m *internal.Module) (e
 			"goos",
 			"goarch",
 			"commit_time",
+			"has_tests",
+			"doc_coverage_pct",
+			"requires_cgo",
+			"is_deprecated",
+			"deprecation_message",
 		}
 		if err := db.BulkUpsert(ctx, "packages", pkgCols, pkgValues, uniqueCols); err != nil {
 			return err
@@ -328,9 +446,74 @@ func insertPackages(ctx context.Context, db *database.DB, m *internal.Module) (e
 			return err
 		}
 	}
+
+	// Delete the symbols that are gone, in case this module version is being
+	// reprocessed, and upsert the others.
+	if err := deleteRowsNotIn(ctx, db, "package_symbols", "module_path = $1 AND version = $2",
+		[]interface{}{m.ModulePath, m.Version},
+		[]string{"package_path", "symbol_name"}, symbolPkgs, symbolNames); err != nil {
+		return err
+	}
+	if len(symbolValues) > 0 {
+		symbolCols := []string{
+			"package_path",
+			"module_path",
+			"version",
+			"symbol_name",
+			"symbol_kind",
+			"signature",
+		}
+		if err := db.BulkUpsert(ctx, "package_symbols", symbolCols, symbolValues,
+			[]string{"package_path", "module_path", "version", "symbol_name"}); err != nil {
+			return err
+		}
+	}
+
+	// Likewise for the examples.
+	if err := deleteRowsNotIn(ctx, db, "examples", "module_path = $1 AND version = $2",
+		[]interface{}{m.ModulePath, m.Version},
+		[]string{"package_path", "name"}, examplePkgs, exampleNames); err != nil {
+		return err
+	}
+	if len(exampleValues) > 0 {
+		exampleCols := []string{
+			"package_path",
+			"module_path",
+			"version",
+			"name",
+			"code",
+			"output",
+		}
+		if err := db.BulkUpsert(ctx, "examples", exampleCols, exampleValues,
+			[]string{"package_path", "module_path", "version", "name"}); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// deleteRowsNotIn deletes the rows of table that satisfy the condition where,
+// whose arguments are whereArgs, except those whose keyCols are among keys.
+// keys holds one slice of values for each of keyCols, all of the same length.
+//
+// It is used with BulkUpsert to replace the rows of a module: unlike deleting
+// all of them and inserting the new ones, it leaves the rows that have not
+// changed alone.
+func deleteRowsNotIn(ctx context.Context, db *database.DB, table, where string, whereArgs []interface{}, keyCols []string, keys ...[]string) error {
+	args := append([]interface{}{}, whereArgs...)
+	var arrays []string
+	for _, k := range keys {
+		args = append(args, pq.Array(k))
+		arrays = append(arrays, fmt.Sprintf("$%d::text[]", len(args)))
+	}
+	query := fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE %s AND (%s) NOT IN (SELECT * FROM UNNEST(%s))`,
+		table, where, strings.Join(keyCols, ", "), strings.Join(arrays, ", "))
+	_, err := db.Exec(ctx, query, args...)
+	return err
+}
+
 // insertImportsUnique inserts and removes rows from the imports_unique table. It should only
 // be called if the given module's version is the latest.
 func insertImportsUnique(ctx context.Context, tx *database.DB, m *internal.Module) (err error) {
@@ -545,12 +728,54 @@ func lock(ctx context.Context, tx *database.DB, modulePath string) (err error) {
 	return nil
 }
 
-// isLatestVersion reports whether version is the latest version of the module.
+// tryLockModuleVersion obtains an exclusive, transaction-scoped advisory
+// lock on the given module version, so that concurrent workers do not insert
+// the same module version at once. It does not wait: if another transaction
+// holds the lock, it returns a derrors.ModuleLocked error. The lock is
+// released automatically at the end of the transaction.
+func tryLockModuleVersion(ctx context.Context, tx *database.DB, modulePath, version string) (err error) {
+	defer derrors.Wrap(&err, "tryLockModuleVersion(ctx, tx, %q, %q)", modulePath, version)
+	if !tx.InTransaction() {
+		return errors.New("not in a transaction")
+	}
+	// See https://www.postgresql.org/docs/11/functions-admin.html#FUNCTIONS-ADVISORY-LOCKS.
+	var locked bool
+	if err := tx.QueryRow(ctx, `SELECT pg_try_advisory_xact_lock(hashtext($1::text || '@' || $2::text))`,
+		modulePath, version).Scan(&locked); err != nil {
+		return err
+	}
+	if !locked {
+		return derrors.ModuleLocked
+	}
+	log.Debugf(ctx, "locked %s@%s", modulePath, version)
+	return nil
+}
+
+// packageHasTests returns the value of the has_tests column of the package of
+// m with path pkgPath. It is NULL if the files of m are not known.
+func packageHasTests(m *internal.Module, pkgPath string) sql.NullBool {
+	if len(m.Files) == 0 {
+		return sql.NullBool{}
+	}
+	return sql.NullBool{Bool: scoring.PackageHasTests(m, pkgPath), Valid: true}
+}
+
+// docCoverage returns the value of the doc_coverage_pct column of p. It is
+// NULL if p has no exported symbols.
+func docCoverage(p *internal.LegacyPackage) sql.NullInt64 {
+	if len(p.Symbols) == 0 {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(internal.DocCoverage(p.Symbols)), Valid: true}
+}
+
+// isLatestVersion reports whether version is the latest version of the
+// module that has not been deleted.
 func isLatestVersion(ctx context.Context, db *database.DB, modulePath, version string) (_ bool, err error) {
 	defer derrors.Wrap(&err, "isLatestVersion(ctx, tx, %q)", modulePath)
 
 	row := db.QueryRow(ctx, `
-		SELECT version FROM modules WHERE module_path = $1
+		SELECT version FROM modules WHERE module_path = $1 AND deleted_at IS NULL
 		ORDER BY version_type = 'release' DESC, sort_version DESC
 		LIMIT 1`,
 		modulePath)
@@ -684,30 +909,68 @@ func removeNonDistributableData(m *internal.Module) {
 	if !m.IsRedistributable {
 		m.LegacyReadmeFilePath = ""
 		m.LegacyReadmeContents = ""
+		for _, f := range m.Files {
+			f.Contents = nil
+		}
 	}
 }
 
-// DeleteModule deletes a Version from the database.
+// DeleteModule marks a module version as deleted. Its rows are kept, so
+// that they can be inspected with db.WithDeleted and restored with
+// UndeleteModule, but it is no longer served. Its search documents are
+// removed, so that search does not have to filter them out. If no other
+// versions of the module are left, it is also removed from imports_unique.
+// Both are rebuilt when the module is next inserted.
 func (db *DB) DeleteModule(ctx context.Context, modulePath, version string) (err error) {
 	defer derrors.Wrap(&err, "DeleteModule(ctx, db, %q, %q)", modulePath, version)
 	return db.db.Transact(ctx, sql.LevelDefault, func(tx *database.DB) error {
-		// We only need to delete from the modules table. Thanks to ON DELETE
-		// CASCADE constraints, that will trigger deletions from all other tables.
-		const stmt = `DELETE FROM modules WHERE module_path=$1 AND version=$2`
-		if _, err := db.db.Exec(ctx, stmt, modulePath, version); err != nil {
+		// Only the modules row is marked. The rows of other tables that
+		// refer to it are filtered out by joining with it.
+		const stmt = `
+			UPDATE modules SET deleted_at = CURRENT_TIMESTAMP
+			WHERE module_path=$1 AND version=$2 AND deleted_at IS NULL`
+		if _, err := tx.Exec(ctx, stmt, modulePath, version); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM search_documents WHERE module_path=$1 AND version=$2`, modulePath, version); err != nil {
 			return err
 		}
 		var x int
-		err = db.db.QueryRow(ctx, `SELECT 1 FROM modules WHERE module_path=$1 LIMIT 1`, modulePath).Scan(&x)
+		err = tx.QueryRow(ctx, `SELECT 1 FROM modules WHERE module_path=$1 AND deleted_at IS NULL LIMIT 1`, modulePath).Scan(&x)
 		if err != sql.ErrNoRows || err == nil {
 			return err
 		}
-		// No versions of this module exist; remove it from imports_unique.
-		_, err = db.db.Exec(ctx, `DELETE FROM imports_unique WHERE from_module_path = $1`, modulePath)
+		// No versions of this module are left; remove it from imports_unique.
+		_, err = tx.Exec(ctx, `DELETE FROM imports_unique WHERE from_module_path = $1`, modulePath)
 		return err
 	})
 }
 
+// UndeleteModule restores a module version deleted by DeleteModule. The
+// module's search documents, and its rows in imports_unique if DeleteModule
+// removed them, are only restored when the module is next inserted. UndeleteModule returns a
+// derrors.NotFound error if the module version does not exist or is not
+// deleted.
+func (db *DB) UndeleteModule(ctx context.Context, modulePath, version string) (err error) {
+	defer derrors.Wrap(&err, "UndeleteModule(ctx, db, %q, %q)", modulePath, version)
+
+	res, err := db.db.Exec(ctx, `
+		UPDATE modules SET deleted_at = NULL
+		WHERE module_path=$1 AND version=$2 AND deleted_at IS NOT NULL`,
+		modulePath, version)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("deleted module version %s@%s: %w", modulePath, version, derrors.NotFound)
+	}
+	return nil
+}
+
 // makeValidUnicode removes null runes from a string that will be saved in a
 // column of type TEXT, because pq doesn't like them. It also replaces non-unicode
 // characters with the Unicode replacement character, which is the behavior of
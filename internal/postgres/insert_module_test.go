@@ -22,6 +22,7 @@ import (
 	"golang.org/x/pkgsite/internal/experiment"
 	"golang.org/x/pkgsite/internal/licenses"
 	"golang.org/x/pkgsite/internal/source"
+	"golang.org/x/pkgsite/internal/sumdb"
 	"golang.org/x/pkgsite/internal/testing/sample"
 )
 
@@ -101,7 +102,7 @@ func checkModule(ctx context.Context, t *testing.T, want *internal.Module) {
 		opts := cmp.Options{
 			// The packages table only includes partial license information; it
 			// omits the Coverage field.
-			cmpopts.IgnoreFields(internal.LegacyPackage{}, "Imports"),
+			cmpopts.IgnoreFields(internal.LegacyPackage{}, "Imports", "Facts"),
 			cmpopts.IgnoreFields(licenses.Metadata{}, "Coverage"),
 			cmpopts.EquateEmpty(),
 		}
@@ -129,6 +130,7 @@ func checkModule(ctx context.Context, t *testing.T, want *internal.Module) {
 			cmpopts.IgnoreFields(internal.LegacyModuleInfo{}, "LegacyReadmeFilePath"),
 			cmpopts.IgnoreFields(internal.LegacyModuleInfo{}, "LegacyReadmeContents"),
 			cmpopts.IgnoreFields(licenses.Metadata{}, "Coverage"),
+			cmpopts.IgnoreFields(internal.PackageNew{}, "Facts"),
 			cmp.AllowUnexported(source.Info{}),
 		}
 		if diff := cmp.Diff(wantd, *got, opts); diff != "" {
@@ -173,6 +175,102 @@ func TestUpsertModule(t *testing.T) {
 	checkModule(ctx, t, m)
 }
 
+func TestInsertModuleIdempotent(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	ctx = experiment.NewContext(ctx,
+		experiment.NewSet(map[string]bool{
+			internal.ExperimentInsertDirectories: true,
+		}))
+	defer ResetTestDB(testDB, t)
+
+	const modulePath = "idempotent.org/mod"
+	newModule := func(version string) *internal.Module {
+		m := sample.Module(modulePath, version, "a", "b")
+		for _, p := range m.LegacyPackages {
+			p.Symbols = []*internal.Symbol{{Name: "F", Kind: internal.SymbolKindFunction, Signature: "func F()", FilePath: "f.go", Line: 1}}
+			p.Examples = []*internal.Example{{Name: "ExampleF", Code: "F()"}}
+		}
+		return m
+	}
+	// Each query counts the rows of a table for the module.
+	queries := map[string]string{
+		"modules":            `SELECT COUNT(*) FROM modules WHERE module_path = $1`,
+		"packages":           `SELECT COUNT(*) FROM packages WHERE module_path = $1`,
+		"licenses":           `SELECT COUNT(*) FROM licenses WHERE module_path = $1`,
+		"imports":            `SELECT COUNT(*) FROM imports WHERE from_module_path = $1`,
+		"package_symbols":    `SELECT COUNT(*) FROM package_symbols WHERE module_path = $1`,
+		"examples":           `SELECT COUNT(*) FROM examples WHERE module_path = $1`,
+		"symbol_definitions": `SELECT COUNT(*) FROM symbol_definitions WHERE module_path = $1`,
+		"search_documents":   `SELECT COUNT(*) FROM search_documents WHERE module_path = $1`,
+		"paths":              `SELECT COUNT(*) FROM paths p INNER JOIN modules m ON p.module_id = m.id WHERE m.module_path = $1`,
+	}
+	counts := func() map[string]int {
+		t.Helper()
+		c := map[string]int{}
+		for table, q := range queries {
+			var n int
+			if err := testDB.db.QueryRow(ctx, q, modulePath).Scan(&n); err != nil {
+				t.Fatalf("%s: %v", table, err)
+			}
+			c[table] = n
+		}
+		return c
+	}
+
+	m := newModule("v1.0.0")
+	if err := testDB.InsertModule(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+	want := counts()
+	if want["modules"] != 1 {
+		t.Fatalf("got %d modules rows, want 1", want["modules"])
+	}
+	if err := testDB.InsertModule(ctx, newModule("v1.0.0")); err != nil {
+		t.Fatalf("inserting again: %v", err)
+	}
+	if diff := cmp.Diff(want, counts()); diff != "" {
+		t.Errorf("row counts after inserting again mismatch (-want +got):\n%s", diff)
+	}
+
+	// A newer version replaces the search documents of the older one, but
+	// the older version keeps its row.
+	if err := testDB.InsertModule(ctx, newModule("v1.1.0")); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range m.LegacyPackages {
+		_, gotVersion, found := GetFromSearchDocuments(ctx, t, testDB, p.Path)
+		if !found || gotVersion != "v1.1.0" {
+			t.Errorf("%s: got search document at version %q (found=%t), want v1.1.0", p.Path, gotVersion, found)
+		}
+	}
+	if _, err := testDB.LegacyGetModuleInfo(ctx, modulePath, "v1.0.0"); err != nil {
+		t.Errorf("LegacyGetModuleInfo(v1.0.0) after inserting v1.1.0: %v", err)
+	}
+	if got := counts()["modules"]; got != 2 {
+		t.Errorf("got %d modules rows, want 2", got)
+	}
+
+	// Reprocessing a version deletes the rows of the symbols and examples it
+	// no longer has, and keeps the others.
+	m = newModule("v1.1.0")
+	m.LegacyPackages[0].Symbols = nil
+	m.LegacyPackages[0].Examples = nil
+	if err := testDB.InsertModule(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range []string{"package_symbols", "examples"} {
+		var n int
+		q := `SELECT COUNT(*) FROM ` + table + ` WHERE module_path = $1 AND version = $2`
+		if err := testDB.db.QueryRow(ctx, q, modulePath, "v1.1.0").Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		if want := len(m.LegacyPackages) - 1; n != want {
+			t.Errorf("%s: got %d rows for v1.1.0, want %d", table, n, want)
+		}
+	}
+}
+
 func TestInsertModuleErrors(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), testTimeout*2)
 	defer cancel()
@@ -249,6 +347,44 @@ func TestInsertModuleErrors(t *testing.T) {
 	}
 }
 
+func TestInsertModuleSumDB(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	client, teardown := sumdb.SetupTestSumDB(t, map[string]string{
+		sample.ModulePath + "@" + sample.VersionString: "h1:good=",
+	})
+	defer teardown()
+	testDB.SumDB = client
+	defer func() { testDB.SumDB = nil }()
+
+	for _, test := range []struct {
+		hash string
+		want error
+	}{
+		{"h1:bad=", derrors.HashMismatch},
+		{"", derrors.HashMismatch},
+		{"h1:good=", nil},
+	} {
+		t.Run(test.hash, func(t *testing.T) {
+			defer ResetTestDB(testDB, t)
+
+			m := sample.DefaultModule()
+			m.ZipHash = test.hash
+			if err := testDB.InsertModule(ctx, m); !errors.Is(err, test.want) {
+				t.Fatalf("InsertModule with hash %q: got error %v, want %v", test.hash, err, test.want)
+			}
+			_, err := testDB.LegacyGetModuleInfo(ctx, m.ModulePath, m.Version)
+			if test.want != nil && !errors.Is(err, derrors.NotFound) {
+				t.Errorf("LegacyGetModuleInfo after failed insert: got error %v, want NotFound", err)
+			}
+			if test.want == nil && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
 func TestPostgres_ReadAndWriteModuleOtherColumns(t *testing.T) {
 	// Verify that InsertModule correctly populates the columns in the versions
 	// table that are not in the LegacyModuleInfo struct.
@@ -256,13 +392,15 @@ func TestPostgres_ReadAndWriteModuleOtherColumns(t *testing.T) {
 	ctx := context.Background()
 
 	type other struct {
-		sortVersion, seriesPath string
+		sortVersion, seriesPath, toolchainVersion string
 	}
 
 	v := sample.Module("github.com/user/repo/path/v2", "v1.2.3-beta.4.a", sample.Suffix)
+	v.ToolchainVersion = "go1.21.3"
 	want := other{
-		sortVersion: "1,2,3,~beta,4,~a",
-		seriesPath:  "github.com/user/repo/path",
+		sortVersion:      "1,2,3,~beta,4,~a",
+		seriesPath:       "github.com/user/repo/path",
+		toolchainVersion: "go1.21.3",
 	}
 
 	if err := testDB.InsertModule(ctx, v); err != nil {
@@ -270,14 +408,14 @@ func TestPostgres_ReadAndWriteModuleOtherColumns(t *testing.T) {
 	}
 	query := `
 	SELECT
-		sort_version, series_path
+		sort_version, series_path, toolchain_version
 	FROM
 		modules
 	WHERE
 		module_path = $1 AND version = $2`
 	row := testDB.db.QueryRow(ctx, query, v.ModulePath, v.Version)
 	var got other
-	if err := row.Scan(&got.sortVersion, &got.seriesPath); err != nil {
+	if err := row.Scan(&got.sortVersion, &got.seriesPath, &got.toolchainVersion); err != nil {
 		t.Fatal(err)
 	}
 	if got != want {
@@ -312,6 +450,104 @@ func TestDeleteModule(t *testing.T) {
 	// TODO(golang/go#39633): check removal from version_map
 }
 
+func TestDeleteAndUndeleteModule(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	m := sample.DefaultModule()
+	if err := testDB.InsertModule(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+	pkgPath := m.LegacyPackages[0].Path
+	withDeleted := New(testDB.db)
+	withDeleted.WithDeleted = true
+
+	// check checks whether the module is served by db, and whether it is
+	// found by search.
+	check := func(name string, db *DB, wantFound, wantSearch bool) {
+		t.Helper()
+		_, err := db.LegacyGetModuleInfo(ctx, m.ModulePath, m.Version)
+		checkFound(t, name+": LegacyGetModuleInfo", err, wantFound)
+		_, err = db.LegacyGetPackage(ctx, pkgPath, m.ModulePath, m.Version)
+		checkFound(t, name+": LegacyGetPackage", err, wantFound)
+		_, _, _, err = db.GetPathInfo(ctx, pkgPath, m.ModulePath, m.Version)
+		checkFound(t, name+": GetPathInfo", err, wantFound)
+		_, err = db.LegacyGetDirectory(ctx, m.ModulePath, m.ModulePath, m.Version, internal.AllFields)
+		checkFound(t, name+": LegacyGetDirectory", err, wantFound)
+
+		// checkLen checks that a list is empty if and only if the module
+		// is not served.
+		checkLen := func(method string, n int, err error) {
+			t.Helper()
+			if err != nil {
+				t.Fatalf("%s: %s: %v", name, method, err)
+			}
+			if got := n > 0; got != wantFound {
+				t.Errorf("%s: %s returned %d items, want found=%t", name, method, n, wantFound)
+			}
+		}
+		imports, err := db.GetImports(ctx, pkgPath, m.ModulePath, m.Version)
+		checkLen("GetImports", len(imports), err)
+		lics, err := db.LegacyGetModuleLicenses(ctx, m.ModulePath, m.Version)
+		checkLen("LegacyGetModuleLicenses", len(lics), err)
+		versions, err := db.GetTaggedVersionsForModule(ctx, m.ModulePath)
+		checkLen("GetTaggedVersionsForModule", len(versions), err)
+		recent, err := db.GetRecentlyIndexedModules(ctx, 10, time.Time{})
+		checkLen("GetRecentlyIndexedModules", len(recent), err)
+
+		results, err := db.Search(ctx, m.LegacyPackages[0].Name, SearchOptions{Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := len(results) > 0; got != wantSearch {
+			t.Errorf("%s: Search found %d results, want found=%t", name, len(results), wantSearch)
+		}
+	}
+
+	check("inserted", testDB, true, true)
+	if err := testDB.DeleteModule(ctx, m.ModulePath, m.Version); err != nil {
+		t.Fatal(err)
+	}
+	check("deleted", testDB, false, false)
+	// The search documents of deleted modules are removed.
+	check("deleted, WithDeleted", withDeleted, true, false)
+	// Deleting again has no effect.
+	if err := testDB.DeleteModule(ctx, m.ModulePath, m.Version); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := testDB.UndeleteModule(ctx, m.ModulePath, m.Version); err != nil {
+		t.Fatal(err)
+	}
+	// The search documents are only restored by inserting the module again.
+	check("undeleted", testDB, true, false)
+	if err := testDB.UndeleteModule(ctx, m.ModulePath, m.Version); !errors.Is(err, derrors.NotFound) {
+		t.Errorf("undeleting again: got %v, want NotFound", err)
+	}
+
+	// Inserting a deleted module restores it.
+	if err := testDB.DeleteModule(ctx, m.ModulePath, m.Version); err != nil {
+		t.Fatal(err)
+	}
+	if err := testDB.InsertModule(ctx, sample.DefaultModule()); err != nil {
+		t.Fatal(err)
+	}
+	check("reinserted", testDB, true, true)
+}
+
+// checkFound reports an error if err is not nil and found is true, or if
+// err is not a derrors.NotFound error and found is false.
+func checkFound(t *testing.T, name string, err error, found bool) {
+	t.Helper()
+	switch {
+	case found && err != nil:
+		t.Errorf("%s: got %v, want no error", name, err)
+	case !found && !errors.Is(err, derrors.NotFound):
+		t.Errorf("%s: got %v, want NotFound", name, err)
+	}
+}
+
 func TestPostgres_NewerAlternative(t *testing.T) {
 	// Verify that packages are not added to search_documents if the module has a newer
 	// alternative version.
@@ -426,3 +662,76 @@ func TestLock(t *testing.T) {
 		t.Errorf("got %d, want %d", count, n)
 	}
 }
+
+func TestTryLockModuleVersion(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	// Two transactions try to take the same lock. Each one that gets it
+	// holds it until both have tried.
+	const n = 2
+	var (
+		tried    sync.WaitGroup
+		mu       sync.Mutex
+		acquired int
+		errs     []error
+	)
+	tried.Add(n)
+	done := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			done <- testDB.db.Transact(ctx, sql.LevelDefault, func(tx *database.DB) error {
+				err := tryLockModuleVersion(ctx, tx, sample.ModulePath, sample.VersionString)
+				mu.Lock()
+				if err == nil {
+					acquired++
+				} else {
+					errs = append(errs, err)
+				}
+				mu.Unlock()
+				tried.Done()
+				tried.Wait()
+				return nil
+			})
+		}()
+	}
+	for i := 0; i < n; i++ {
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if acquired != 1 {
+		t.Errorf("lock acquired %d times, want 1", acquired)
+	}
+	for _, err := range errs {
+		if !errors.Is(err, derrors.ModuleLocked) {
+			t.Errorf("got error %v, want ModuleLocked", err)
+		}
+	}
+
+	// Once the transaction that held the lock has ended, the lock can be
+	// acquired again, and while it is held the module cannot be inserted.
+	if err := testDB.db.Transact(ctx, sql.LevelDefault, func(tx *database.DB) error {
+		if err := tryLockModuleVersion(ctx, tx, sample.ModulePath, sample.VersionString); err != nil {
+			return err
+		}
+		if err := testDB.InsertModule(ctx, sample.DefaultModule()); !errors.Is(err, derrors.ModuleLocked) {
+			t.Errorf("InsertModule with the lock held: got %v, want ModuleLocked", err)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := testDB.InsertModule(ctx, sample.DefaultModule()); err != nil {
+		t.Errorf("InsertModule after releasing the lock: %v", err)
+	}
+}
+
+func TestSPDXTypes(t *testing.T) {
+	got := spdxTypes([]string{"GPL2", "Unknown-License", "MIT", "Expat", ""})
+	want := []string{"GPL-2.0-only", "MIT"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -7,14 +7,18 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"sort"
 	"strings"
 
 	"github.com/lib/pq"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/database"
 	"golang.org/x/pkgsite/internal/derrors"
 	"golang.org/x/pkgsite/internal/licenses"
+	"golang.org/x/pkgsite/internal/stdlib"
 )
 
 // LegacyGetModuleLicenses returns all licenses associated with the given module path and
@@ -33,8 +37,8 @@ func (db *DB) LegacyGetModuleLicenses(ctx context.Context, modulePath, version s
 		licenses
 	WHERE
 		module_path = $1 AND version = $2 AND position('/' in file_path) = 0
-    `
-	rows, err := db.db.Query(ctx, query, modulePath, version)
+		AND ` + db.versionNotDeletedSQL("module_path", "version")
+	rows, err := db.readDB().Query(ctx, query, modulePath, version)
 	if err != nil {
 		return nil, err
 	}
@@ -70,13 +74,14 @@ func (db *DB) LegacyGetPackageLicenses(ctx context.Context, pkgPath, modulePath,
 				path = $1
 				AND module_path = $2
 				AND version = $3
+				AND ` + db.versionNotDeletedSQL("module_path", "version") + `
 		) p
 		ON
 			p.module_path = l.module_path
 			AND p.version = l.version
 			AND p.license_file_path = l.file_path;`
 
-	rows, err := db.db.Query(ctx, query, pkgPath, modulePath, version)
+	rows, err := db.readDB().Query(ctx, query, pkgPath, modulePath, version)
 	if err != nil {
 		return nil, err
 	}
@@ -157,3 +162,155 @@ func compareLicenses(i, j *licenses.Metadata) bool {
 	}
 	return i.FilePath < j.FilePath
 }
+
+// updateLicenseConflicts stores in the license_conflicts column of each
+// package of m the pairs of incompatible licenses of the package and the
+// packages it imports, directly or indirectly, according to
+// licenses.GetLicenseCompatibility. Each license is attributed to the package
+// closest to the package in the import graph that has it. The licenses of
+// imported packages that are inserted later are not taken into account.
+//
+// Imports are resolved as in GetModuleDependencies, and followed to a depth of
+// at most maxDepth. Packages of the standard library are ignored.
+//
+// It must be called after the packages and licenses of m are inserted.
+func updateLicenseConflicts(ctx context.Context, db *database.DB, m *internal.Module, maxDepth int) (err error) {
+	defer derrors.Wrap(&err, "updateLicenseConflicts(ctx, db, %q, %q)", m.ModulePath, m.Version)
+
+	query := `
+		WITH RECURSIVE pkgs (root, path, module_path, version, depth) AS (
+			SELECT path, path, module_path, version, 0
+			FROM packages
+			WHERE module_path = $1 AND version = $2
+			UNION
+			SELECT p.root, r.path, r.module_path, r.version, p.depth + 1
+			FROM pkgs p
+			INNER JOIN imports i
+			ON
+				i.from_path = p.path
+				AND i.from_module_path = p.module_path
+				AND i.from_version = p.version
+			CROSS JOIN LATERAL (
+				SELECT q.path, q.module_path, q.version
+				FROM packages q
+				INNER JOIN modules m
+				USING (module_path, version)
+				WHERE q.path = i.to_path
+				ORDER BY
+					(q.module_path, q.version) = (p.module_path, p.version) DESC,
+					m.version_type = 'release' DESC,
+					m.sort_version DESC,
+					m.module_path DESC
+				LIMIT 1
+			) r
+			WHERE p.depth < $3 AND r.module_path <> $4
+		), nearest AS (
+			SELECT root, path, module_path, version, MIN(depth) AS depth
+			FROM pkgs
+			GROUP BY root, path, module_path, version
+		)
+		SELECT n.root, n.path, l.types, l.spdx_types IS NOT NULL, l.spdx_types
+		FROM nearest n
+		INNER JOIN packages q
+		ON q.path = n.path AND q.module_path = n.module_path AND q.version = n.version
+		INNER JOIN licenses l
+		ON
+			l.module_path = n.module_path
+			AND l.version = n.version
+			AND l.file_path = ANY(q.license_paths)
+		ORDER BY n.root, n.depth, n.path, l.file_path`
+
+	// pkgsByRoot holds, for each package of m, the license types of the
+	// packages in its import graph, nearest first.
+	pkgsByRoot := map[string][]packageLicenseTypes{}
+	collect := func(rows *sql.Rows) error {
+		var (
+			root, path   string
+			types, ids   []string
+			hasSPDXTypes bool
+		)
+		if err := rows.Scan(&root, &path, pq.Array(&types), &hasSPDXTypes, pq.Array(&ids)); err != nil {
+			return fmt.Errorf("rows.Scan(): %v", err)
+		}
+		if !hasSPDXTypes {
+			// The license was inserted before its SPDX identifiers were
+			// stored.
+			ids = spdxTypes(types)
+		}
+		pkgs := pkgsByRoot[root]
+		if len(pkgs) == 0 || pkgs[len(pkgs)-1].path != path {
+			pkgs = append(pkgs, packageLicenseTypes{path: path})
+		}
+		last := &pkgs[len(pkgs)-1]
+		last.ids = append(last.ids, ids...)
+		pkgsByRoot[root] = pkgs
+		return nil
+	}
+	if err := db.RunQuery(ctx, query, collect, m.ModulePath, m.Version, maxDepth, stdlib.ModulePath); err != nil {
+		return err
+	}
+
+	var paths, conflicts []string
+	for _, p := range m.LegacyPackages {
+		cs := licenseConflicts(pkgsByRoot[p.Path])
+		if cs == nil {
+			cs = []*internal.LicenseConflict{}
+		}
+		data, err := json.Marshal(cs)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, p.Path)
+		conflicts = append(conflicts, string(data))
+	}
+	_, err = db.Exec(ctx, `
+		UPDATE packages p
+		SET license_conflicts = c.conflicts::jsonb
+		FROM UNNEST($3::text[], $4::text[]) AS c(path, conflicts)
+		WHERE p.path = c.path AND p.module_path = $1 AND p.version = $2`,
+		m.ModulePath, m.Version, pq.Array(paths), pq.Array(conflicts))
+	return err
+}
+
+// packageLicenseTypes holds the SPDX identifiers of the licenses of a
+// package.
+type packageLicenseTypes struct {
+	path string
+	ids  []string
+}
+
+// licenseConflicts returns the pairs of incompatible licenses of pkgs. Each
+// license is attributed to the first package in pkgs that has it. Pairs of
+// licenses of the same package are not conflicts, since they are most often
+// alternatives that the package is dual-licensed under.
+func licenseConflicts(pkgs []packageLicenseTypes) []*internal.LicenseConflict {
+	var (
+		ids     []string
+		pkgByID = map[string]string{}
+	)
+	for _, p := range pkgs {
+		for _, id := range p.ids {
+			if _, ok := pkgByID[id]; !ok {
+				pkgByID[id] = p.path
+				ids = append(ids, id)
+			}
+		}
+	}
+	var conflicts []*internal.LicenseConflict
+	for i, a := range ids {
+		for _, b := range ids[i+1:] {
+			if pkgByID[a] == pkgByID[b] {
+				continue
+			}
+			if licenses.GetLicenseCompatibility(a, b) == licenses.Incompatible {
+				conflicts = append(conflicts, &internal.LicenseConflict{
+					License:      a,
+					Package:      pkgByID[a],
+					OtherLicense: b,
+					OtherPackage: pkgByID[b],
+				})
+			}
+		}
+	}
+	return conflicts
+}
@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal"
 	"golang.org/x/pkgsite/internal/licenses"
 	"golang.org/x/pkgsite/internal/testing/sample"
 )
@@ -88,3 +89,93 @@ func TestLegacyGetPackageLicenses(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdateLicenseConflicts(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	// a.com/a (MIT) imports b.com/b (Apache-2.0), which imports c.com/c
+	// (GPL2). Only Apache-2.0 and GPL2 are incompatible. d.com/d is under
+	// both of them. The modules are inserted after their dependencies, since
+	// the conflicts are computed on insertion.
+	for _, m := range []struct {
+		path         string
+		licenseTypes []string
+		imports      []string
+	}{
+		{"c.com/c", []string{"GPL2"}, nil},
+		{"b.com/b", []string{"Apache-2.0"}, []string{"c.com/c"}},
+		{"a.com/a", []string{"MIT"}, []string{"b.com/b"}},
+		{"d.com/d", []string{"Apache-2.0", "GPL2"}, nil},
+	} {
+		mod := sample.Module(m.path, sample.VersionString, "")
+		mod.LegacyPackages[0].Imports = m.imports
+		mod.LegacyPackages[0].Licenses = []*licenses.Metadata{{Types: m.licenseTypes, FilePath: "LICENSE"}}
+		mod.Licenses = []*licenses.License{{Metadata: mod.LegacyPackages[0].Licenses[0], Contents: []byte("license")}}
+		if err := testDB.InsertModule(ctx, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	conflict := []*internal.LicenseConflict{{
+		License:      "Apache-2.0",
+		Package:      "b.com/b",
+		OtherLicense: "GPL-2.0-only",
+		OtherPackage: "c.com/c",
+	}}
+	for _, test := range []struct {
+		pkgPath string
+		want    []*internal.LicenseConflict
+	}{
+		{"a.com/a", conflict},
+		{"b.com/b", conflict},
+		{"c.com/c", []*internal.LicenseConflict{}},
+		{"d.com/d", []*internal.LicenseConflict{}},
+	} {
+		pkg, err := testDB.LegacyGetPackage(ctx, test.pkgPath, internal.UnknownModulePath, sample.VersionString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(test.want, pkg.Facts.LicenseConflicts); diff != "" {
+			t.Errorf("LegacyGetPackage(%q).Facts.LicenseConflicts mismatch (-want +got):\n%s", test.pkgPath, diff)
+		}
+	}
+}
+
+func TestLicenseConflicts(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		pkgs []packageLicenseTypes
+		want []*internal.LicenseConflict
+	}{
+		{
+			name: "nearest package",
+			pkgs: []packageLicenseTypes{
+				{"a.com/a", []string{"MIT"}},
+				{"b.com/b", []string{"Apache-2.0"}},
+				{"c.com/c", []string{"GPL-2.0-only"}},
+				{"d.com/d", []string{"Apache-2.0", "GPL-3.0-only"}},
+			},
+			want: []*internal.LicenseConflict{
+				{License: "Apache-2.0", Package: "b.com/b", OtherLicense: "GPL-2.0-only", OtherPackage: "c.com/c"},
+				{License: "GPL-2.0-only", Package: "c.com/c", OtherLicense: "GPL-3.0-only", OtherPackage: "d.com/d"},
+			},
+		},
+		{
+			name: "same package",
+			pkgs: []packageLicenseTypes{
+				{"a.com/a", []string{"Apache-2.0", "GPL-2.0-only"}},
+				{"b.com/b", []string{"MIT"}},
+			},
+			want: nil,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got := licenseConflicts(test.pkgs)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
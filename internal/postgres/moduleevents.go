@@ -0,0 +1,118 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/database"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/log"
+)
+
+// moduleInsertedChannel is the channel on which InsertIndexVersions notifies
+// listeners of the module versions that it inserts.
+const moduleInsertedChannel = "pkgsite_module_inserted"
+
+// listenerPingInterval is how often ListenForModuleEvents checks that the
+// connection of its listener is alive, since a dead connection is otherwise
+// only detected when the next notification is sent.
+const listenerPingInterval = 90 * time.Second
+
+// A ModuleEvent reports something that happened to a module version. It is
+// the payload of the notifications on moduleInsertedChannel, and of the events
+// in the module_events outbox.
+type ModuleEvent struct {
+	Type       string `json:"type"` // one of the ModuleEvent constants
+	ModulePath string `json:"module_path"`
+	Version    string `json:"version"`
+}
+
+// The types of ModuleEvents.
+const (
+	// ModuleEventQueued is the type of the events of module versions that
+	// were inserted into module_version_states, so that they need to be
+	// fetched.
+	ModuleEventQueued = "version_queued"
+	// ModuleEventIndexed is the type of the events of module versions that
+	// were inserted by InsertModule.
+	ModuleEventIndexed = "version_indexed"
+)
+
+// notifyModuleEvents sends a notification on moduleInsertedChannel for each
+// of versions. If db is a transaction, the notifications are delivered when
+// it commits, and not at all if it is rolled back.
+func notifyModuleEvents(ctx context.Context, db *database.DB, versions []*internal.IndexVersion) (err error) {
+	defer derrors.Wrap(&err, "notifyModuleEvents(ctx, %d versions)", len(versions))
+
+	var payloads []string
+	for _, v := range versions {
+		p, err := json.Marshal(ModuleEvent{Type: ModuleEventQueued, ModulePath: v.Path, Version: v.Version})
+		if err != nil {
+			return err
+		}
+		payloads = append(payloads, string(p))
+	}
+	_, err = db.Exec(ctx, `SELECT pg_notify($1, p) FROM UNNEST($2::text[]) AS p`,
+		moduleInsertedChannel, pq.Array(payloads))
+	return err
+}
+
+// ListenForModuleEvents returns a channel of the module versions that are
+// inserted into module_version_states by InsertIndexVersions, from now until
+// ctx is done, when the channel is closed. db must have been created from a
+// database.DB returned by database.Open.
+//
+// Events are not delivered reliably: those sent while the listener is
+// reconnecting to the database are lost, so module_version_states should still
+// be polled from time to time.
+func ListenForModuleEvents(ctx context.Context, db *DB) (_ <-chan ModuleEvent, err error) {
+	defer derrors.Wrap(&err, "ListenForModuleEvents(ctx)")
+
+	l, err := db.db.Listen(ctx, moduleInsertedChannel)
+	if err != nil {
+		return nil, err
+	}
+	events := make(chan ModuleEvent)
+	go func() {
+		defer close(events)
+		defer l.Close()
+		ticker := time.NewTicker(listenerPingInterval)
+		defer ticker.Stop()
+		for {
+			var n *pq.Notification
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := l.Ping(); err != nil {
+					log.Errorf(ctx, "ListenForModuleEvents: %v", err)
+				}
+				continue
+			case n = <-l.Notify:
+			}
+			if n == nil {
+				// The listener reconnected, and events may have been lost.
+				log.Infof(ctx, "ListenForModuleEvents: listener reconnected")
+				continue
+			}
+			var e ModuleEvent
+			if err := json.Unmarshal([]byte(n.Extra), &e); err != nil {
+				log.Errorf(ctx, "ListenForModuleEvents: bad payload %q: %v", n.Extra, err)
+				continue
+			}
+			select {
+			case events <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/testing/sample"
+)
+
+func TestListenForModuleEvents(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	listenCtx, stop := context.WithCancel(ctx)
+	events, err := ListenForModuleEvents(listenCtx, testDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := &internal.IndexVersion{
+		Path:      "example.com/events",
+		Version:   "v1.2.3",
+		Timestamp: sample.NowTruncated(),
+	}
+	if err := testDB.InsertIndexVersions(ctx, []*internal.IndexVersion{v}); err != nil {
+		t.Fatal(err)
+	}
+	want := ModuleEvent{Type: ModuleEventQueued, ModulePath: v.Path, Version: v.Version}
+	select {
+	case got := <-events:
+		if got != want {
+			t.Errorf("got event %+v, want %+v", got, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no event received within 2s")
+	}
+
+	// The channel is closed when the context is done.
+	stop()
+	select {
+	case e, ok := <-events:
+		if ok {
+			t.Errorf("got event %+v after the context was done, want closed channel", e)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("channel not closed within 2s of the context being done")
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/database"
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+// upsertModuleLinks stores the links of m, which must be the latest version
+// of its module, in the module_links table. If m has no links, the links of
+// an earlier version of the module are deleted.
+func upsertModuleLinks(ctx context.Context, db *database.DB, m *internal.Module) (err error) {
+	defer derrors.Wrap(&err, "upsertModuleLinks(ctx, db, %q, %q)", m.ModulePath, m.Version)
+
+	if m.Links == nil {
+		_, err := db.Exec(ctx, `DELETE FROM module_links WHERE module_path = $1`, m.ModulePath)
+		return err
+	}
+	_, err = db.Exec(ctx, `
+		INSERT INTO module_links (module_path, version, issues_url, ci_url)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (module_path)
+		DO UPDATE SET
+			version=excluded.version,
+			issues_url=excluded.issues_url,
+			ci_url=excluded.ci_url`,
+		m.ModulePath, m.Version, m.Links.IssuesURL, m.Links.CIURL)
+	return err
+}
+
+// GetModuleLinks returns the links to the issue tracker and the CI service of
+// the repository of the latest version of the module with the given path. It
+// returns a derrors.NotFound error if there are none.
+func (db *DB) GetModuleLinks(ctx context.Context, modulePath string) (_ *internal.ModuleLinks, err error) {
+	defer derrors.Wrap(&err, "GetModuleLinks(ctx, %q)", modulePath)
+
+	var links internal.ModuleLinks
+	err = db.db.QueryRow(ctx, `
+		SELECT issues_url, ci_url
+		FROM module_links
+		WHERE module_path = $1`, modulePath).
+		Scan(&links.IssuesURL, &links.CIURL)
+	switch err {
+	case sql.ErrNoRows:
+		return nil, derrors.NotFound
+	case nil:
+		return &links, nil
+	default:
+		return nil, err
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/testing/sample"
+)
+
+func TestGetModuleLinks(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	const modulePath = "github.com/links/mod"
+	links := &internal.ModuleLinks{
+		IssuesURL: "https://github.com/links/mod/issues",
+		CIURL:     "https://github.com/links/mod/actions",
+	}
+	insert := func(version string, l *internal.ModuleLinks) {
+		t.Helper()
+		m := sample.Module(modulePath, version, "p")
+		m.Links = l
+		if err := testDB.InsertModule(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check := func(want *internal.ModuleLinks) {
+		t.Helper()
+		got, err := testDB.GetModuleLinks(ctx, modulePath)
+		if want == nil {
+			if !errors.Is(err, derrors.NotFound) {
+				t.Errorf("got error %v, want NotFound", err)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("GetModuleLinks mismatch (-want +got):\n%s", diff)
+		}
+	}
+
+	insert("v1.0.0", links)
+	check(links)
+
+	// The links of an older version do not replace those of the latest.
+	insert("v0.9.0", &internal.ModuleLinks{IssuesURL: "https://github.com/links/mod/issues"})
+	check(links)
+
+	// A latest version on an unknown host removes the links.
+	insert("v1.1.0", nil)
+	check(nil)
+}
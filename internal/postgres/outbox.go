@@ -0,0 +1,112 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"golang.org/x/pkgsite/internal/database"
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+// An EventSink delivers module events to downstream consumers.
+type EventSink interface {
+	// Publish delivers event. If it returns an error, the event is
+	// delivered again later.
+	Publish(ctx context.Context, event ModuleEvent) error
+}
+
+// publishBatchSize is the maximum number of events that PublishPendingEvents
+// delivers in one call.
+const publishBatchSize = 1000
+
+// insertModuleEvent adds an event of type eventType for modulePath@version to
+// the module_events outbox. It should be called in the transaction that makes
+// the change the event describes, so that the event is recorded if and only
+// if the change is.
+func insertModuleEvent(ctx context.Context, db *database.DB, eventType, modulePath, version string) (err error) {
+	defer derrors.Wrap(&err, "insertModuleEvent(ctx, %q, %q, %q)", eventType, modulePath, version)
+
+	payload, err := json.Marshal(ModuleEvent{Type: eventType, ModulePath: modulePath, Version: version})
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(ctx, `
+		INSERT INTO module_events (event_type, payload)
+		VALUES ($1, $2)`,
+		eventType, string(payload))
+	return err
+}
+
+// PublishPendingEvents delivers the unpublished events of the module_events
+// outbox to sink, oldest first, and marks each one published once sink has
+// accepted it. It stops at the first event that sink fails to publish, so
+// that the event is retried by the next call before any later ones, and
+// returns the number of events published along with the error.
+//
+// Delivery is at least once: an event is delivered again if it could not be
+// marked published, or if concurrent calls both deliver it.
+func PublishPendingEvents(ctx context.Context, db *DB, sink EventSink) (n int, err error) {
+	defer derrors.Wrap(&err, "PublishPendingEvents(ctx)")
+
+	type pending struct {
+		id    int64
+		event ModuleEvent
+	}
+	var events []pending
+	err = db.db.RunQuery(ctx, `
+		SELECT id, payload
+		FROM module_events
+		WHERE NOT published
+		ORDER BY id
+		LIMIT $1`, func(rows *sql.Rows) error {
+		var (
+			p       pending
+			payload []byte
+		)
+		if err := rows.Scan(&p.id, &payload); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(payload, &p.event); err != nil {
+			return err
+		}
+		events = append(events, p)
+		return nil
+	}, publishBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	for _, p := range events {
+		if err := sink.Publish(ctx, p.event); err != nil {
+			return n, err
+		}
+		if _, err := db.db.Exec(ctx, `UPDATE module_events SET published = TRUE WHERE id = $1`, p.id); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// PruneModuleEvents deletes the events of the module_events outbox that were
+// published and created before publishedBefore, and all those created before
+// before, whether they were published or not, so that the outbox does not grow
+// without bound when no sink accepts its events. It returns the number of
+// events deleted.
+func PruneModuleEvents(ctx context.Context, db *DB, publishedBefore, before time.Time) (n int64, err error) {
+	defer derrors.Wrap(&err, "PruneModuleEvents(ctx, %v, %v)", publishedBefore, before)
+
+	res, err := db.db.Exec(ctx, `
+		DELETE FROM module_events
+		WHERE (published AND created_at < $1) OR created_at < $2`,
+		publishedBefore, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,115 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal/testing/sample"
+)
+
+// flakySink is an EventSink that fails the first failures calls to Publish.
+type flakySink struct {
+	failures  int
+	calls     int
+	published []ModuleEvent
+}
+
+func (s *flakySink) Publish(ctx context.Context, event ModuleEvent) error {
+	s.calls++
+	if s.calls <= s.failures {
+		return errors.New("sink unavailable")
+	}
+	s.published = append(s.published, event)
+	return nil
+}
+
+func TestPublishPendingEvents(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	for _, v := range []string{"v1.0.0", "v1.1.0"} {
+		if err := testDB.InsertModule(ctx, sample.Module("example.com/outbox", v, "p")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []ModuleEvent{
+		{Type: ModuleEventIndexed, ModulePath: "example.com/outbox", Version: "v1.0.0"},
+		{Type: ModuleEventIndexed, ModulePath: "example.com/outbox", Version: "v1.1.0"},
+	}
+
+	sink := &flakySink{failures: 1}
+	publish := func(wantN int, wantErr bool) {
+		t.Helper()
+		n, err := PublishPendingEvents(ctx, testDB, sink)
+		if (err != nil) != wantErr {
+			t.Fatalf("PublishPendingEvents: got error %v, want error: %t", err, wantErr)
+		}
+		if n != wantN {
+			t.Errorf("PublishPendingEvents: got %d published, want %d", n, wantN)
+		}
+	}
+
+	// The sink fails on the first call, so nothing is published, and the
+	// events stay pending.
+	publish(0, true)
+	if len(sink.published) != 0 {
+		t.Fatalf("got %d events published, want 0", len(sink.published))
+	}
+	// The next call delivers all the events, in order, including the one
+	// that failed.
+	publish(2, false)
+	if diff := cmp.Diff(want, sink.published); diff != "" {
+		t.Errorf("published mismatch (-want +got):\n%s", diff)
+	}
+	// Published events are not delivered again.
+	publish(0, false)
+	if got, wantCalls := sink.calls, 3; got != wantCalls {
+		t.Errorf("got %d calls to Publish, want %d", got, wantCalls)
+	}
+}
+
+func TestPruneModuleEvents(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	for _, v := range []string{"v1.0.0", "v1.1.0"} {
+		if err := testDB.InsertModule(ctx, sample.Module("example.com/outbox", v, "p")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Publish the events, then mark the second one pending again.
+	if _, err := PublishPendingEvents(ctx, testDB, &flakySink{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := testDB.db.Exec(ctx, `UPDATE module_events SET published = FALSE WHERE payload->>'version' = 'v1.1.0'`); err != nil {
+		t.Fatal(err)
+	}
+
+	future := time.Now().Add(time.Hour)
+	for _, test := range []struct {
+		name                    string
+		publishedBefore, before time.Time
+		want                    int64
+	}{
+		{"recent", time.Time{}, time.Time{}, 0},
+		{"published", future, time.Time{}, 1},
+		{"all", future, future, 1},
+	} {
+		n, err := PruneModuleEvents(ctx, testDB, test.publishedBefore, test.before)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != test.want {
+			t.Errorf("%s: pruned %d events, want %d", test.name, n, test.want)
+		}
+	}
+}
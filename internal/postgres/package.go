@@ -48,34 +48,9 @@ func (db *DB) LegacyGetPackage(ctx context.Context, pkgPath, modulePath, version
 	}
 
 	args := []interface{}{pkgPath}
-	query := `
-		SELECT
-			p.path,
-			p.name,
-			p.synopsis,
-			p.v1_path,
-			p.license_types,
-			p.license_paths,
-			p.redistributable,
-			p.documentation,
-			p.goos,
-			p.goarch,
-			m.version,
-			m.commit_time,
-			m.readme_file_path,
-			m.readme_contents,
-			m.module_path,
-			m.version_type,
-		    m.source_info,
-			m.redistributable,
-			m.has_go_mod
-		FROM
-			modules m
-		INNER JOIN
-			packages p
-		ON
-			p.module_path = m.module_path
-			AND m.version = p.version`
+	// Deleted module versions are left out by the join condition.
+	query := legacyVersionedPackageQuery + `
+			AND ` + db.notDeletedSQL("m")
 
 	if modulePath == internal.UnknownModulePath || modulePath == stdlib.ModulePath {
 		if version == internal.LatestVersion {
@@ -131,25 +106,129 @@ func (db *DB) LegacyGetPackage(ctx context.Context, pkgPath, modulePath, version
 		args = append(args, version, modulePath)
 	}
 
+	pkg, err := scanLegacyVersionedPackage(db.readDB().QueryRow(ctx, query, args...).Scan)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("package %s@%s: %w", pkgPath, version, derrors.NotFound)
+		}
+		return nil, err
+	}
+	return pkg, nil
+}
+
+// LegacyGetPackages returns the packages with the given paths in the module
+// version specified by modulePath and version, using a single query. The
+// result has the same length and order as pkgPaths; an element is nil if
+// there is no package with that path in the module version.
+//
+// Unlike LegacyGetPackage, LegacyGetPackages requires a known module path and
+// version: neither can be internal.UnknownModulePath or
+// internal.LatestVersion.
+func (db *DB) LegacyGetPackages(ctx context.Context, pkgPaths []string, modulePath, version string) (_ []*internal.LegacyVersionedPackage, err error) {
+	defer derrors.Wrap(&err, "DB.LegacyGetPackages(ctx, [%d paths], %q, %q)", len(pkgPaths), modulePath, version)
+	if modulePath == "" || version == "" {
+		return nil, fmt.Errorf("neither modulePath nor version can be empty: %w", derrors.InvalidArgument)
+	}
+	if modulePath == internal.UnknownModulePath || version == internal.LatestVersion {
+		return nil, fmt.Errorf("modulePath and version must be known: %w", derrors.InvalidArgument)
+	}
+	if len(pkgPaths) == 0 {
+		return nil, nil
+	}
+
+	query := legacyVersionedPackageQuery + `
+		WHERE
+			p.path = ANY($1)
+			AND p.version = $2
+			AND p.module_path = $3`
+	byPath := map[string]*internal.LegacyVersionedPackage{}
+	collect := func(rows *sql.Rows) error {
+		pkg, err := scanLegacyVersionedPackage(rows.Scan)
+		if err != nil {
+			return err
+		}
+		byPath[pkg.Path] = pkg
+		return nil
+	}
+	if err := db.readDB().RunQuery(ctx, query, collect, pq.Array(pkgPaths), version, modulePath); err != nil {
+		return nil, err
+	}
+	pkgs := make([]*internal.LegacyVersionedPackage, len(pkgPaths))
+	for i, p := range pkgPaths {
+		pkgs[i] = byPath[p]
+	}
+	return pkgs, nil
+}
+
+// legacyVersionedPackageQuery selects the columns read by
+// scanLegacyVersionedPackage. Callers append a WHERE clause.
+const legacyVersionedPackageQuery = `
+		SELECT
+			p.path,
+			p.name,
+			p.synopsis,
+			p.v1_path,
+			p.license_types,
+			p.license_paths,
+			p.redistributable,
+			p.documentation,
+			p.goos,
+			p.goarch,
+			m.version,
+			m.commit_time,
+			m.readme_file_path,
+			m.readme_contents,
+			m.module_path,
+			m.version_type,
+		    m.source_info,
+			m.redistributable,
+			m.has_go_mod,
+			m.toolchain_version,
+			p.has_tests,
+			p.doc_coverage_pct,
+			p.requires_cgo,
+			p.is_deprecated,
+			p.deprecation_message,
+			p.license_conflicts
+		FROM
+			modules m
+		INNER JOIN
+			packages p
+		ON
+			p.module_path = m.module_path
+			AND m.version = p.version`
+
+// scanLegacyVersionedPackage reads a package selected by
+// legacyVersionedPackageQuery using scan. It returns sql.ErrNoRows unwrapped,
+// so that callers can check for it.
+func scanLegacyVersionedPackage(scan func(dest ...interface{}) error) (*internal.LegacyVersionedPackage, error) {
 	var (
 		pkg                        internal.LegacyVersionedPackage
 		licenseTypes, licensePaths []string
-		hasGoMod                   sql.NullBool
+		hasGoMod, hasTests         sql.NullBool
+		requiresCgo, isDeprecated  sql.NullBool
+		deprecationMessage         sql.NullString
+		docCoverage                sql.NullInt64
 	)
-	row := db.db.QueryRow(ctx, query, args...)
-	err = row.Scan(&pkg.Path, &pkg.Name, &pkg.Synopsis,
+	err := scan(&pkg.Path, &pkg.Name, &pkg.Synopsis,
 		&pkg.V1Path, pq.Array(&licenseTypes), pq.Array(&licensePaths), &pkg.LegacyPackage.IsRedistributable,
 		database.NullIsEmpty(&pkg.DocumentationHTML), &pkg.GOOS, &pkg.GOARCH, &pkg.Version,
 		&pkg.CommitTime, database.NullIsEmpty(&pkg.LegacyReadmeFilePath), database.NullIsEmpty(&pkg.LegacyReadmeContents),
 		&pkg.ModulePath, &pkg.VersionType, jsonbScanner{&pkg.SourceInfo}, &pkg.LegacyModuleInfo.IsRedistributable,
-		&hasGoMod)
+		&hasGoMod, database.NullIsEmpty(&pkg.ToolchainVersion), &hasTests, &docCoverage, &requiresCgo,
+		&isDeprecated, &deprecationMessage, jsonbScanner{&pkg.Facts.LicenseConflicts})
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("package %s@%s: %w", pkgPath, version, derrors.NotFound)
+			return nil, err
 		}
 		return nil, fmt.Errorf("row.Scan(): %v", err)
 	}
 	setHasGoMod(&pkg.ModuleInfo, hasGoMod)
+	pkg.Facts.HasTests = nullBoolPtr(hasTests)
+	pkg.Facts.DocCoverage = nullIntPtr(docCoverage)
+	pkg.Facts.RequiresCgo = nullBoolPtr(requiresCgo)
+	pkg.Facts.IsDeprecated = nullBoolPtr(isDeprecated)
+	pkg.Facts.DeprecationMessage = deprecationMessage.String
 	lics, err := zipLicenseMetadata(licenseTypes, licensePaths)
 	if err != nil {
 		return nil, err
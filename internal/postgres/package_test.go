@@ -54,6 +54,8 @@ func TestLegacyGetPackage(t *testing.T) {
 			cmp.AllowUnexported(source.Info{}),
 			// The packages table only includes partial license information; it omits the Coverage field.
 			cmpopts.IgnoreFields(licenses.Metadata{}, "Coverage"),
+			// The stored facts are checked by other tests.
+			cmpopts.IgnoreFields(internal.LegacyPackage{}, "Facts"),
 		}
 		if diff := cmp.Diff(want, got, opts...); diff != "" {
 			t.Errorf("testDB.LegacyGetPackage(ctx, %q, %q, %q) mismatch (-want +got):\n%s", pkgPath, modulePath, version, diff)
@@ -234,3 +236,59 @@ func TestLegacyGetPackageInvalidArguments(t *testing.T) {
 		})
 	}
 }
+
+func TestLegacyGetPackages(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	defer ResetTestDB(testDB, t)
+
+	for _, m := range []*internal.Module{
+		sample.Module("example.com/mod", "v1.0.0", "a", "b", "c"),
+		sample.Module("example.com/mod", "v1.1.0", "a", "d"),
+	} {
+		if err := testDB.InsertModule(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	paths := []string{"example.com/mod/c", "example.com/mod/d", "example.com/mod/a", "example.com/other"}
+	got, err := testDB.LegacyGetPackages(ctx, paths, "example.com/mod", "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(paths) {
+		t.Fatalf("got %d packages, want %d", len(got), len(paths))
+	}
+	for i, p := range paths {
+		wantFound := p == "example.com/mod/c" || p == "example.com/mod/a"
+		if !wantFound {
+			if got[i] != nil {
+				t.Errorf("got[%d] = %q, want nil", i, got[i].Path)
+			}
+			continue
+		}
+		if got[i] == nil {
+			t.Errorf("got[%d] = nil, want %q", i, p)
+			continue
+		}
+		if got[i].Path != p || got[i].ModulePath != "example.com/mod" || got[i].Version != "v1.0.0" {
+			t.Errorf("got[%d] = %s in %s@%s, want %s in example.com/mod@v1.0.0", i, got[i].Path, got[i].ModulePath, got[i].Version, p)
+		}
+	}
+
+	got, err = testDB.LegacyGetPackages(ctx, nil, "example.com/mod", "v1.0.0")
+	if err != nil || got != nil {
+		t.Errorf("LegacyGetPackages with no paths = %v, %v; want nil, nil", got, err)
+	}
+	for _, tc := range []struct{ modulePath, version string }{
+		{"", "v1.0.0"},
+		{"example.com/mod", ""},
+		{internal.UnknownModulePath, "v1.0.0"},
+		{"example.com/mod", internal.LatestVersion},
+	} {
+		if _, err := testDB.LegacyGetPackages(ctx, paths, tc.modulePath, tc.version); !errors.Is(err, derrors.InvalidArgument) {
+			t.Errorf("LegacyGetPackages(%q, %q): got %v, want InvalidArgument", tc.modulePath, tc.version, err)
+		}
+	}
+}
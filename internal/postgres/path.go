@@ -38,6 +38,7 @@ func (db *DB) GetPathInfo(ctx context.Context, path, inModulePath, inVersion str
 		constraints = append(constraints, fmt.Sprintf("AND m.version = $%d", len(args)+1))
 		args = append(args, inVersion)
 	}
+	constraints = append(constraints, "AND "+db.notDeletedSQL("m"))
 	query := fmt.Sprintf(`
 		SELECT m.module_path, m.version, p.name != ''
 		FROM paths p
@@ -50,7 +51,7 @@ func (db *DB) GetPathInfo(ctx context.Context, path, inModulePath, inVersion str
 			m.module_path DESC
 		LIMIT 1
 	`, strings.Join(constraints, " "))
-	err = db.db.QueryRow(ctx, query, args...).Scan(&outModulePath, &outVersion, &isPackage)
+	err = db.readDB().QueryRow(ctx, query, args...).Scan(&outModulePath, &outVersion, &isPackage)
 	switch err {
 	case sql.ErrNoRows:
 		return "", "", false, derrors.NotFound
@@ -137,7 +138,7 @@ func (db *DB) GetStdlibPathsWithSuffix(ctx context.Context, suffix string) (path
 			AND path LIKE '%/' || $2
 		ORDER BY path
 	`
-	err = db.db.RunQuery(ctx, q, func(rows *sql.Rows) error {
+	err = db.readDB().RunQuery(ctx, q, func(rows *sql.Rows) error {
 		var p string
 		if err := rows.Scan(&p); err != nil {
 			return err
@@ -5,24 +5,151 @@
 package postgres
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
 	"golang.org/x/pkgsite/internal/database"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/search"
+	"golang.org/x/pkgsite/internal/sumdb"
+	"golang.org/x/sync/singleflight"
 )
 
 type DB struct {
 	db *database.DB
+
+	// FuzzySearchEnabled reports whether search also matches packages whose
+	// paths are similar to the query, so that queries with typos find
+	// results. See DB.Search.
+	FuzzySearchEnabled bool
+
+	// WithDeleted reports whether the methods that look up modules and
+	// packages also return module versions that have been deleted by
+	// DeleteModule. It is meant for admin tools that inspect deleted
+	// records. Search never returns deleted module versions, because
+	// DeleteModule removes their search documents.
+	WithDeleted bool
+
+	// MaxDependencyDepth is the maximum length of a chain of imports followed
+	// by GetModuleDependencies, and by InsertModule to find the license
+	// conflicts of packages. If it is zero, defaultMaxDependencyDepth is used.
+	MaxDependencyDepth int
+
+	// ImportedByHalfLife is the half-life of the decay that
+	// ComputeDecayedImportedByCount applies to imported-by counts. If it is
+	// zero, defaultImportedByHalfLife is used.
+	ImportedByHalfLife time.Duration
+
+	// Synonyms, if non-nil, expands the terms of search queries with their
+	// synonyms. See search.SynonymMap.Expand.
+	Synonyms search.SynonymMap
+
+	// ExperimentConfig overrides the defaults of the search ranking
+	// parameters, like "noGoModPenalty". A key of the form "bucket.name"
+	// overrides the parameter name only for searches in that experiment
+	// bucket; see experiment.Bucket. It should be checked with
+	// ValidateExperimentConfig.
+	ExperimentConfig map[string]float64
+
+	// SearchQueryLogger, if non-nil, records each successful search, without
+	// its query text.
+	SearchQueryLogger SearchQueryLogger
+	// SearchQueryLogKey is the secret key of the HMACs of the queries
+	// recorded by SearchQueryLogger. See HashSearchQuery.
+	SearchQueryLogKey []byte
+
+	// HLLPrecision is the precision of the hyperloglog estimates of the
+	// number of search results, between MinHLLPrecision and MaxHLLPrecision.
+	// Each increment halves the variance of the estimates, but doubles the
+	// number of registers that the estimate looks up. If it is zero,
+	// DefaultHLLPrecision is used.
+	HLLPrecision int
+
+	// SearchUsesPreparedStatements reports whether searches run the
+	// statements prepared by PrepareSearchStatements, which sets it. If it is
+	// false, searches send their queries to the database unprepared.
+	SearchUsesPreparedStatements bool
+
+	// SumDB, if non-nil, is used by InsertModule to verify the zip hash of
+	// each non-standard-library module against the checksum database.
+	SumDB *sumdb.Client
+
+	// searchGroup coalesces concurrent identical searches. See
+	// DB.sharedSearch.
+	searchGroup *singleflight.Group
+
+	// searchLatency tracks the latency of searches, to derive their timeout.
+	// See DB.hedgedSearch.
+	searchLatency *latencyTracker
+
+	// searchStmts holds the statements prepared by PrepareSearchStatements,
+	// by query.
+	searchStmts map[string]*sql.Stmt
+
+	// searchLog queues the searches to be recorded by SearchQueryLogger. See
+	// DB.logSearchQuery.
+	searchLog *searchLogQueue
 }
 
 // New returns a new postgres DB.
 func New(db *database.DB) *DB {
-	return &DB{db}
+	return &DB{
+		db:            db,
+		searchGroup:   &singleflight.Group{},
+		searchLatency: &latencyTracker{},
+		searchLog:     newSearchLogQueue(searchQueryLogQueueSize),
+	}
 }
 
 // Close closes a DB.
 func (db *DB) Close() error {
+	db.closeSearchStatements()
 	return db.db.Close()
 }
 
+// readDB returns the database.DB for the queries of methods that only read,
+// like those that serve frontend pages. It uses the read replica of db, if
+// there is one. See database.DB.ReadOnly.
+func (db *DB) readDB() *database.DB {
+	return db.db.ReadOnly()
+}
+
+// notDeletedSQL returns a SQL predicate that holds for the rows of the modules
+// table, under the name table, whose module versions have not been deleted.
+// If db.WithDeleted is set, it returns TRUE.
+func (db *DB) notDeletedSQL(table string) string {
+	if db.WithDeleted {
+		return "TRUE"
+	}
+	return table + ".deleted_at IS NULL"
+}
+
+// versionNotDeletedSQL returns a SQL predicate that holds for the rows of a
+// table that refer to a module version by the columns modulePathColumn and
+// versionColumn, whose module version has not been deleted. It is used for
+// queries on tables that are not joined with the modules table. If
+// db.WithDeleted is set, it returns TRUE.
+func (db *DB) versionNotDeletedSQL(modulePathColumn, versionColumn string) string {
+	if db.WithDeleted {
+		return "TRUE"
+	}
+	return fmt.Sprintf(`NOT EXISTS (
+		SELECT 1 FROM modules dm
+		WHERE dm.module_path = %s
+		AND dm.version = %s
+		AND dm.deleted_at IS NOT NULL)`, modulePathColumn, versionColumn)
+}
+
 // Underlying returns the *database.DB inside db.
 func (db *DB) Underlying() *database.DB {
 	return db.db
 }
+
+// Ping checks that the database can be queried.
+func (db *DB) Ping(ctx context.Context) (err error) {
+	defer derrors.Wrap(&err, "DB.Ping(ctx)")
+	var one int
+	return db.db.QueryRow(ctx, "SELECT 1").Scan(&one)
+}
@@ -5,6 +5,7 @@
 package postgres
 
 import (
+	"context"
 	"testing"
 	"time"
 )
@@ -16,3 +17,11 @@ var testDB *DB
 func TestMain(m *testing.M) {
 	RunDBTests("discovery_postgres_test", m, &testDB)
 }
+
+func TestPing(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	if err := testDB.Ping(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+// GetRecentlyIndexedModules returns up to limit module versions that were
+// inserted into the modules table after since, ordered by insertion time,
+// most recent first.
+func (db *DB) GetRecentlyIndexedModules(ctx context.Context, limit int, since time.Time) (_ []*internal.IndexedModule, err error) {
+	defer derrors.Wrap(&err, "GetRecentlyIndexedModules(ctx, %d, %v)", limit, since)
+
+	if limit <= 0 {
+		return nil, fmt.Errorf("limit must be positive: %w", derrors.InvalidArgument)
+	}
+	query := `
+		SELECT
+			module_path,
+			version,
+			commit_time,
+			version_type,
+			source_info,
+			redistributable,
+			has_go_mod,
+			created_at
+		FROM modules
+		WHERE created_at > $2 AND ` + db.notDeletedSQL("modules") + `
+		ORDER BY created_at DESC
+		LIMIT $1`
+
+	var mods []*internal.IndexedModule
+	collect := func(rows *sql.Rows) error {
+		var (
+			m        internal.IndexedModule
+			hasGoMod sql.NullBool
+		)
+		if err := rows.Scan(&m.ModulePath, &m.Version, &m.CommitTime, &m.VersionType,
+			jsonbScanner{&m.SourceInfo}, &m.IsRedistributable, &hasGoMod, &m.IndexedAt); err != nil {
+			return fmt.Errorf("rows.Scan(): %v", err)
+		}
+		setHasGoMod(&m.ModuleInfo, hasGoMod)
+		mods = append(mods, &m)
+		return nil
+	}
+	if err := db.db.RunQuery(ctx, query, collect, limit, since); err != nil {
+		return nil, err
+	}
+	return mods, nil
+}
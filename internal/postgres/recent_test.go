@@ -0,0 +1,63 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal/testing/sample"
+)
+
+func TestGetRecentlyIndexedModules(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	base := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	for i, m := range []struct {
+		modulePath, version string
+	}{
+		{"a.com/m", "v1.0.0"},
+		{"b.com/m", "v1.0.0"},
+		{"a.com/m", "v1.1.0"},
+	} {
+		if err := testDB.InsertModule(ctx, sample.Module(m.modulePath, m.version, "")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := testDB.db.Exec(ctx, `UPDATE modules SET created_at = $1 WHERE module_path = $2 AND version = $3`,
+			base.Add(time.Duration(i)*time.Hour), m.modulePath, m.version); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, test := range []struct {
+		name  string
+		limit int
+		since time.Time
+		want  []string
+	}{
+		{"all", 10, time.Time{}, []string{"a.com/m@v1.1.0", "b.com/m@v1.0.0", "a.com/m@v1.0.0"}},
+		{"limit", 2, time.Time{}, []string{"a.com/m@v1.1.0", "b.com/m@v1.0.0"}},
+		{"since", 10, base, []string{"a.com/m@v1.1.0", "b.com/m@v1.0.0"}},
+		{"none", 10, base.Add(2 * time.Hour), nil},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			mods, err := testDB.GetRecentlyIndexedModules(ctx, test.limit, test.since)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, m := range mods {
+				got = append(got, m.ModulePath+"@"+m.Version)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("GetRecentlyIndexedModules(ctx, %d, %v) mismatch (-want +got):\n%s", test.limit, test.since, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"golang.org/x/mod/semver"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/database"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/version"
+)
+
+// updateRetractions marks the versions of m's module that are retracted by
+// a newer version as retracted. That includes m itself, if it is retracted
+// by a version already in the database, and the older versions that m's
+// go.mod file retracts. If one of those is the version of a package in
+// search_documents, the search document is marked as well.
+//
+// It must be called with the lock on m's module path held.
+func updateRetractions(ctx context.Context, db *database.DB, m *internal.Module) (err error) {
+	defer derrors.Wrap(&err, "updateRetractions(ctx, db, %q, %q)", m.ModulePath, m.Version)
+
+	var (
+		isRetracted bool
+		covered     []string
+	)
+	collect := func(rows *sql.Rows) error {
+		var (
+			v           string
+			retractions []string
+		)
+		if err := rows.Scan(&v, pq.Array(&retractions)); err != nil {
+			return fmt.Errorf("rows.Scan(): %v", err)
+		}
+		switch c := semver.Compare(v, m.Version); {
+		case c > 0 && retracts(retractions, m.Version):
+			isRetracted = true
+		case c < 0 && retracts(m.RetractedVersions, v):
+			covered = append(covered, v)
+		}
+		return nil
+	}
+	query := `SELECT version, retracted_versions FROM modules WHERE module_path = $1`
+	if err := db.RunQuery(ctx, query, collect, m.ModulePath); err != nil {
+		return err
+	}
+	if isRetracted {
+		covered = append(covered, m.Version)
+	}
+	if len(covered) == 0 {
+		return nil
+	}
+	if _, err := db.Exec(ctx, `
+		UPDATE modules SET retracted = TRUE
+		WHERE module_path = $1 AND version = ANY($2)`,
+		m.ModulePath, pq.Array(covered)); err != nil {
+		return err
+	}
+	_, err = db.Exec(ctx, `
+		UPDATE search_documents SET latest_version_is_retracted = TRUE
+		WHERE module_path = $1 AND version = ANY($2)`,
+		m.ModulePath, pq.Array(covered))
+	return err
+}
+
+// retracts reports whether any of retractions covers v.
+func retracts(retractions []string, v string) bool {
+	for _, r := range retractions {
+		if version.RetractionCovers(r, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetRetractedVersions returns the versions of the module that are retracted,
+// either by their own go.mod file or by that of a newer version, sorted from
+// newest to oldest.
+func (db *DB) GetRetractedVersions(ctx context.Context, modulePath string) (_ []string, err error) {
+	defer derrors.Wrap(&err, "GetRetractedVersions(ctx, %q)", modulePath)
+
+	query := `
+		SELECT version
+		FROM modules
+		WHERE module_path = $1 AND retracted
+		ORDER BY sort_version DESC`
+	var versions []string
+	collect := func(rows *sql.Rows) error {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return fmt.Errorf("rows.Scan(): %v", err)
+		}
+		versions = append(versions, v)
+		return nil
+	}
+	if err := db.db.RunQuery(ctx, query, collect, modulePath); err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
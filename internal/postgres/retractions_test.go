@@ -0,0 +1,117 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/testing/sample"
+)
+
+func TestGetRetractedVersions(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	const modulePath = "retract.com/m"
+	module := func(version string, retractions ...string) *internal.Module {
+		m := sample.Module(modulePath, version, "p")
+		m.RetractedVersions = retractions
+		return m
+	}
+	for _, test := range []struct {
+		name    string
+		modules []*internal.Module
+		want    []string
+	}{
+		{
+			name: "newer version retracts older",
+			modules: []*internal.Module{
+				module("v1.0.0"),
+				module("v1.0.1"),
+				module("v1.1.0", "v1.0.0"),
+			},
+			want: []string{"v1.0.0"},
+		},
+		{
+			name: "older version inserted after newer",
+			modules: []*internal.Module{
+				module("v1.1.0", "[v1.0.0, v1.0.5]"),
+				module("v1.0.1"),
+				module("v1.0.6"),
+			},
+			want: []string{"v1.0.1"},
+		},
+		{
+			name: "version retracts itself",
+			modules: []*internal.Module{
+				module("v1.0.0"),
+				func() *internal.Module {
+					m := module("v1.1.0", "v1.1.0")
+					m.IsRetracted = true
+					return m
+				}(),
+			},
+			want: []string{"v1.1.0"},
+		},
+		{
+			name: "older version does not retract newer",
+			modules: []*internal.Module{
+				module("v1.1.0"),
+				module("v1.0.0", "v1.1.0"),
+			},
+			want: nil,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			defer ResetTestDB(testDB, t)
+
+			for _, m := range test.modules {
+				if err := testDB.InsertModule(ctx, m); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := testDB.GetRetractedVersions(ctx, modulePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("GetRetractedVersions mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRetractionUpdatesSearchDocuments(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	// The release v1.0.0 stays the latest version of the package in
+	// search_documents, but it is retracted by the newer prerelease.
+	pre := sample.Module("retract.com/m", "v1.1.0-pre", "p")
+	pre.RetractedVersions = []string{"v1.0.0"}
+	for _, m := range []*internal.Module{sample.Module("retract.com/m", "v1.0.0", "p"), pre} {
+		if err := testDB.InsertModule(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var (
+		version   string
+		retracted bool
+	)
+	row := testDB.db.QueryRow(ctx, `
+		SELECT version, latest_version_is_retracted
+		FROM search_documents
+		WHERE package_path = $1`, "retract.com/m/p")
+	if err := row.Scan(&version, &retracted); err != nil {
+		t.Fatal(err)
+	}
+	if version != "v1.0.0" || !retracted {
+		t.Errorf("got version %q, latest_version_is_retracted %t; want v1.0.0, true", version, retracted)
+	}
+}
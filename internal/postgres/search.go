@@ -7,11 +7,15 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"math"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/lib/pq"
 	"go.opencensus.io/plugin/ochttp"
@@ -23,8 +27,15 @@ import (
 	"golang.org/x/pkgsite/internal"
 	"golang.org/x/pkgsite/internal/database"
 	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/experiment"
 	"golang.org/x/pkgsite/internal/log"
+	"golang.org/x/pkgsite/internal/scoring"
+	"golang.org/x/pkgsite/internal/search"
 	"golang.org/x/pkgsite/internal/stdlib"
+	"golang.org/x/pkgsite/internal/tracing"
+	"golang.org/x/pkgsite/internal/xcontext"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/singleflight"
 )
 
 var (
@@ -53,8 +64,84 @@ var (
 		Description: "Search count, by result source query type.",
 		TagKeys:     []tag.Key{keySearchSource},
 	}
+
+	// keySearchLatencyPopular, keySearchLatencyDeep and
+	// keySearchLatencyEstimate hold the observed latency of each of the
+	// queries run by hedgedSearch, whether or not its result is used.
+	keySearchLatencyPopular = stats.Float64(
+		"go-discovery/search/latency-popular",
+		"Latency of a popular search query.",
+		stats.UnitMilliseconds,
+	)
+	keySearchLatencyDeep = stats.Float64(
+		"go-discovery/search/latency-deep",
+		"Latency of a deep search query.",
+		stats.UnitMilliseconds,
+	)
+	keySearchLatencyEstimate = stats.Float64(
+		"go-discovery/search/latency-estimate",
+		"Latency of a search result count estimate query.",
+		stats.UnitMilliseconds,
+	)
+	// SearchLatencyPopular aggregates the latency of popular search queries.
+	SearchLatencyPopular = &view.View{
+		Name:        "go-discovery/search/latency-popular",
+		Measure:     keySearchLatencyPopular,
+		Aggregation: ochttp.DefaultLatencyDistribution,
+		Description: "Popular search query latency.",
+	}
+	// SearchLatencyDeep aggregates the latency of deep search queries.
+	SearchLatencyDeep = &view.View{
+		Name:        "go-discovery/search/latency-deep",
+		Measure:     keySearchLatencyDeep,
+		Aggregation: ochttp.DefaultLatencyDistribution,
+		Description: "Deep search query latency.",
+	}
+	// SearchLatencyEstimate aggregates the latency of search result count
+	// estimate queries.
+	SearchLatencyEstimate = &view.View{
+		Name:        "go-discovery/search/latency-estimate",
+		Measure:     keySearchLatencyEstimate,
+		Aggregation: ochttp.DefaultLatencyDistribution,
+		Description: "Search result count estimate query latency.",
+	}
+)
+
+// keySearchCoalesced counts searches that waited for the result of an
+// identical search instead of querying the database.
+var keySearchCoalesced = stats.Int64(
+	"go-discovery/search/coalesced",
+	"Count of searches that shared the result of a concurrent identical search.",
+	stats.UnitDimensionless,
 )
 
+// SearchCoalescedCount counts searches that shared the result of a
+// concurrent identical search.
+var SearchCoalescedCount = &view.View{
+	Name:        "go-discovery/search/coalesced-count",
+	Measure:     keySearchCoalesced,
+	Aggregation: view.Count(),
+	Description: "Count of coalesced searches.",
+}
+
+// searchSourceLatency holds the latency measure of each search source.
+var searchSourceLatency = map[string]*stats.Float64Measure{
+	"popular":  keySearchLatencyPopular,
+	"deep":     keySearchLatencyDeep,
+	"estimate": keySearchLatencyEstimate,
+}
+
+// recordSearchSourceLatency records the latency of a successful query of the
+// given search source, if it has a latency measure. Failed queries are not
+// recorded, since most of them are canceled after another query returned.
+func recordSearchSourceLatency(ctx context.Context, source string, latency time.Duration, err error) {
+	m, ok := searchSourceLatency[source]
+	if !ok || err != nil {
+		return
+	}
+	stats.Record(ctx, m.M(float64(latency)/float64(time.Millisecond)))
+}
+
 // searchResponse is used for internal bookkeeping when fanning-out search
 // request to multiple different search queries.
 type searchResponse struct {
@@ -86,7 +173,105 @@ type searchEvent struct {
 }
 
 // A searcher is used to execute a single search request.
-type searcher func(db *DB, ctx context.Context, q string, limit, offset int) searchResponse
+type searcher func(db *DB, ctx context.Context, sp searchParams) searchResponse
+
+// searchParams holds the parameters of a single search request, as passed to
+// each searcher.
+type searchParams struct {
+	q      string
+	limit  int
+	offset int
+	// after, if non-nil, restricts results to those ordered after the cursor.
+	after *searchCursor
+	// filter is a SQL predicate on the columns of search_documents that
+	// results must satisfy. The empty string means no restriction.
+	filter string
+	// bucket is the experiment bucket of the search, which selects the
+	// ranking parameters in DB.ExperimentConfig.
+	bucket string
+}
+
+// SearchOptions holds the options for a call to Search.
+type SearchOptions struct {
+	// Limit is the maximum number of results to return.
+	Limit int
+	// Offset is the number of results to skip. It is ignored if AfterCursor is
+	// set.
+	Offset int
+	// AfterCursor, if non-empty, is the value of SearchResult.NextCursor from a
+	// previous search for the same query. Only results following that result
+	// are returned. The zero value means the first page.
+	AfterCursor string
+	// MinImportedBy, if positive, restricts results to packages imported by
+	// at least that many packages. It is equivalent to adding the filter
+	// "imported:>=MinImportedBy" to the query, so the estimated result count
+	// also counts only those packages.
+	MinImportedBy int
+	// UpdatedAfter and UpdatedBefore, if non-zero, restrict results to
+	// packages whose latest version was committed in that range, inclusive.
+	// UpdatedAfter must not be after UpdatedBefore.
+	UpdatedAfter  time.Time
+	UpdatedBefore time.Time
+	// IncludeFacets reports whether to compute SearchResult.FacetCounts.
+	// It requires an extra query over all matching packages.
+	IncludeFacets bool
+}
+
+// searchCursor identifies a position in the ordered list of search results.
+// Results are ordered by score descending, then commit time descending, then
+// package path ascending, so these three values are enough to resume a
+// search without scanning past the results that precede it.
+type searchCursor struct {
+	Score       float64   `json:"s"`
+	CommitTime  time.Time `json:"t"`
+	PackagePath string    `json:"p"`
+}
+
+// encode returns the opaque string form of c.
+func (c *searchCursor) encode() string {
+	b, err := json.Marshal(c)
+	if err != nil {
+		// Marshaling a struct of plain values cannot fail.
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeSearchCursor parses a cursor produced by searchCursor.encode. It
+// returns an error wrapping derrors.InvalidArgument if s is malformed.
+func decodeSearchCursor(s string) (_ *searchCursor, err error) {
+	defer derrors.Wrap(&err, "decodeSearchCursor(%q)", s)
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", err, derrors.InvalidArgument)
+	}
+	var c searchCursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("%v: %w", err, derrors.InvalidArgument)
+	}
+	if c.PackagePath == "" {
+		return nil, fmt.Errorf("missing package path: %w", derrors.InvalidArgument)
+	}
+	return &c, nil
+}
+
+// filterSQL returns sp.filter, or TRUE if sp.filter is empty.
+func (sp searchParams) filterSQL() string {
+	if sp.filter == "" {
+		return "TRUE"
+	}
+	return sp.filter
+}
+
+// cursorArgs returns the arguments used to pass sp.after to a search query:
+// the score, commit time and package path of the cursor, or three nils if
+// there is no cursor.
+func (sp searchParams) cursorArgs() []interface{} {
+	if sp.after == nil {
+		return []interface{}{nil, nil, nil}
+	}
+	return []interface{}{sp.after.Score, sp.after.CommitTime, sp.after.PackagePath}
+}
 
 // The searchers used by Search.
 var searchers = map[string]searcher{
@@ -117,12 +302,79 @@ var searchers = map[string]searcher{
 // The gap in this optimization is search terms that are very frequent, but
 // rarely relevant: "int" or "package", for example. In these cases we'll pay
 // the penalty of a deep search that scans nearly every package.
-func (db *DB) Search(ctx context.Context, q string, limit, offset int) (_ []*internal.SearchResult, err error) {
-	defer derrors.Wrap(&err, "DB.Search(ctx, %q, %d, %d)", q, limit, offset)
-	resp, err := db.hedgedSearch(ctx, q, limit, offset, searchers, nil)
+//
+// If opts.AfterCursor is set, the search resumes after the result whose
+// NextCursor it is, rather than skipping opts.Offset results.
+//
+// If db.FuzzySearchEnabled is set, only deep search is used, and it also
+// matches packages whose paths are similar to the query; see deepSearch.
+//
+// The query is first normalized with search.NormalizeSearchQuery and parsed
+// with search.ParseSearchQuery, which lower-cases its terms, so that queries
+// that differ only in the case of their terms, white space or
+// percent-encoding are the same search. It may contain field filters, like "license:MIT"; see
+// search.ParseSearchQuery. They are applied by each search method as SQL
+// predicates. A query of only filters matches every package that satisfies
+// them, ranked by popularity.
+func (db *DB) Search(ctx context.Context, q string, opts SearchOptions) (_ []*internal.SearchResult, err error) {
+	defer derrors.Wrap(&err, "DB.Search(ctx, %q, %+v)", q, opts)
+	q, err = search.NormalizeSearchQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	sq, err := search.ParseSearchQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	if len(sq.Terms) == 0 && len(sq.Filters) == 0 {
+		return nil, fmt.Errorf("query has no search terms or filters: %w", derrors.InvalidArgument)
+	}
+	if opts.MinImportedBy < 0 {
+		return nil, fmt.Errorf("negative MinImportedBy %d: %w", opts.MinImportedBy, derrors.InvalidArgument)
+	}
+	if !opts.UpdatedAfter.IsZero() && !opts.UpdatedBefore.IsZero() && opts.UpdatedAfter.After(opts.UpdatedBefore) {
+		return nil, fmt.Errorf("UpdatedAfter %s is after UpdatedBefore %s: %w",
+			opts.UpdatedAfter.Format(time.RFC3339), opts.UpdatedBefore.Format(time.RFC3339), derrors.InvalidArgument)
+	}
+	// The query text is expanded with db.Synonyms once, so that the search
+	// and the facet counts match the same packages.
+	sp := searchParams{
+		q:      db.Synonyms.Expand(sq.Text()),
+		limit:  opts.Limit,
+		offset: opts.Offset,
+		filter: searchFilter(sq.Filters, opts),
+		bucket: experiment.Bucket(ctx),
+	}
+	if opts.AfterCursor != "" {
+		sp.after, err = decodeSearchCursor(opts.AfterCursor)
+		if err != nil {
+			return nil, err
+		}
+		sp.offset = 0
+	}
+	ss := searchers
+	if db.FuzzySearchEnabled {
+		// Popular search only finds exact matches, so it could return a
+		// complete page that omits better fuzzy matches.
+		ss = map[string]searcher{"deep": (*DB).deepSearch}
+	}
+	searchStart := time.Now()
+	resp, err := db.sharedSearch(ctx, sp, ss)
 	if err != nil {
 		return nil, err
 	}
+	// Each caller logs its own search, even if it shared the results of
+	// another one.
+	db.logSearchQuery(ctx, sq.String(), resp, time.Since(searchStart))
+	if opts.IncludeFacets && len(resp.results) > 0 {
+		facets, err := db.licenseFacetCounts(ctx, sp)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range resp.results {
+			r.FacetCounts = facets
+		}
+	}
 	// Filter out excluded paths.
 	var results []*internal.SearchResult
 	for _, r := range resp.results {
@@ -137,7 +389,53 @@ func (db *DB) Search(ctx context.Context, q string, limit, offset int) (_ []*int
 	return results, nil
 }
 
-// Penalties to search scores, applied as multipliers to the score.
+const (
+	// parallelSearchTimeoutPerQuery is the time that ParallelSearch allows
+	// for each of its queries, up to maxParallelSearchTimeout in total.
+	parallelSearchTimeoutPerQuery = time.Second
+	maxParallelSearchTimeout      = 5 * time.Second
+)
+
+// ParallelSearch runs Search for each of queries concurrently, with a limit of
+// limit results, and returns their results in the order of queries. Identical
+// queries, including those of other concurrent searches, are run only once;
+// see sharedSearch.
+//
+// The searches are given a timeout of parallelSearchTimeoutPerQuery for each
+// query, up to maxParallelSearchTimeout. If any search fails, the others are
+// canceled and the first error is returned.
+func (db *DB) ParallelSearch(ctx context.Context, queries []string, limit int) (_ [][]*internal.SearchResult, err error) {
+	defer derrors.Wrap(&err, "DB.ParallelSearch(ctx, %q, %d)", queries, limit)
+
+	timeout := time.Duration(len(queries)) * parallelSearchTimeoutPerQuery
+	if timeout > maxParallelSearchTimeout {
+		timeout = maxParallelSearchTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	results := make([][]*internal.SearchResult, len(queries))
+	g, ctx := errgroup.WithContext(ctx)
+	for i, q := range queries {
+		i, q := i, q
+		g.Go(func() error {
+			rs, err := db.Search(ctx, q, SearchOptions{Limit: limit})
+			if err != nil {
+				return err
+			}
+			results[i] = rs
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// Penalties to search scores, applied as multipliers to the score. They are
+// the defaults of the ranking parameters of the same names, which can be
+// changed with DB.ExperimentConfig.
 const (
 	// Module license is non-redistributable.
 	nonRedistributablePenalty = 0.5
@@ -145,49 +443,208 @@ const (
 	// Start this off gently (close to 1), but consider lowering
 	// it as time goes by and more of the ecosystem converts to modules.
 	noGoModPenalty = 0.8
+	// The latest version of the module is retracted by its go.mod file.
+	retractedPenalty = 0.5
+	// The package doc comment has a "Deprecated: " paragraph.
+	deprecatedPenalty = 0.5
+	// The module's health score is 0. The penalty for a health score between
+	// 0 and 100 is proportional to its difference from 100.
+	healthScorePenalty = 0.5
 )
 
-// scoreExpr is the expression that computes the search score.
-// It is the product of:
-// - The Postgres ts_rank score, based the relevance of the document to the query.
+// rankingDefaults maps the names of the ranking parameters to their
+// defaults.
+var rankingDefaults = map[string]float64{
+	"nonRedistributablePenalty": nonRedistributablePenalty,
+	"noGoModPenalty":            noGoModPenalty,
+	"retractedPenalty":          retractedPenalty,
+	"deprecatedPenalty":         deprecatedPenalty,
+	"healthScorePenalty":        healthScorePenalty,
+}
+
+// newScoreExpr returns the expression that computes the search score, given
+// the expression for the relevance of a document to the query and the value
+// of each ranking parameter.
+//
+// The score is the product of:
+// - The relevance.
 // - The log of the module's popularity, estimated by the number of importing packages.
 //   The log factor contains exp(1) so that it is always >= 1. Taking the log
 //   of imported_by_count instead of using it directly makes the effect less
 //   dramatic: being 2x as popular only has an additive effect.
+//   The count is decayed by the age of the package's latest commit, so that
+//   abandoned packages gradually lose popularity; see
+//   ComputeDecayedImportedByCount. Since decayed_imported_by_count never
+//   exceeds imported_by_count, the early exit of popular search stays valid.
 // - A penalty factor for non-redistributable modules, since a lot of
 //   details cannot be displayed.
-// The first argument to ts_rank is an array of weights for the four tsvector sections,
-// in the order D, C, B, A.
-// The weights below match the defaults except for B.
-var scoreExpr = fmt.Sprintf(`
-		ts_rank('{0.1, 0.2, 1.0, 1.0}', tsv_search_tokens, websearch_to_tsquery($1)) *
-		ln(exp(1)+imported_by_count) *
+// - Penalty factors for modules without a go.mod file, and for modules whose
+//   latest version is retracted.
+// - A penalty factor for deprecated packages.
+// - A penalty factor for packages with a low health score; see
+//   scoring.ComputeHealthScore.
+//
+// The same expression is used by popularSearch and deepSearch, so
+// that every search method computes identical scores. That keeps cursors
+// valid when consecutive pages are served by different methods.
+func newScoreExpr(relevance string, param func(name string) float64) string {
+	return fmt.Sprintf(`
+		%s *
+		ln(exp(1)+decayed_imported_by_count) *
 		CASE WHEN redistributable THEN 1 ELSE %f END *
-		CASE WHEN COALESCE(has_go_mod, true) THEN 1 ELSE %f END
-	`, nonRedistributablePenalty, noGoModPenalty)
+		CASE WHEN COALESCE(has_go_mod, true) THEN 1 ELSE %f END *
+		CASE WHEN COALESCE(latest_version_is_retracted, false) THEN %f ELSE 1 END *
+		CASE WHEN is_deprecated THEN %f ELSE 1 END *
+		(1 - %f * (1 - health_score / %d))
+	`, relevance, param("nonRedistributablePenalty"), param("noGoModPenalty"), param("retractedPenalty"),
+		param("deprecatedPenalty"), param("healthScorePenalty"), scoring.MaxHealthScore)
+}
+
+func defaultRankingParam(name string) float64 { return rankingDefaults[name] }
+
+// scoreExpr is the expression that computes the search score with the default
+// ranking parameters. The relevance is computed by searchRank.
+var scoreExpr = newScoreExpr(searchRank, defaultRankingParam)
+
+// searchRank is the Postgres ts_rank score of a search document for the
+// query. It ranks the path tokens, which are the A section of
+// tsv_search_tokens, together with synopsis_vector and readme_vector.
+// The synopsis has weight A and the README weight C, so a word of the
+// synopsis counts five times as much as a word of the README.
+// The first argument to ts_rank is an array of weights for the four tsvector
+// sections, in the order D, C, B, A. These are the defaults.
+const searchRank = `ts_rank('{0.1, 0.2, 0.4, 1.0}',
+			ts_filter(tsv_search_tokens, '{a}') || synopsis_vector || readme_vector,
+			websearch_to_tsquery($1))`
+
+// fuzzySearchRank replaces the ts_rank relevance with the trigram word
+// similarity of the query to the package path, when that is greater. Since
+// word_similarity is also at most 1, scores keep the same bounds as those
+// computed with searchRank.
+var fuzzySearchRank = fmt.Sprintf(`GREATEST(
+			%s,
+			word_similarity($1, package_path)
+		)`, searchRank)
+
+// fuzzyMatch is the predicate that matches search documents in fuzzy search.
+// The <% operator holds when the word similarity of the query to the package
+// path is at least pg_trgm.word_similarity_threshold (0.6 by default), and
+// can use the trigram index on package_path.
+const fuzzyMatch = `tsv_search_tokens @@ websearch_to_tsquery($1) OR $1 <% package_path`
+
+// sharedSearchTimeout is the time allowed for a search run by sharedSearch.
+// The search does not end when the context of the caller that started it is
+// done, since other callers may be waiting for it.
+const sharedSearchTimeout = 10 * time.Second
+
+// sharedSearch calls hedgedSearch, unless an identical search is already in
+// progress, in which case it waits for that search and returns a copy of its
+// results. Searches are identical if their searchParams are; the searchers
+// ss are not compared, since Search chooses them from the configuration of
+// db.
+//
+// The search is run with the values of ctx, but not its deadline or
+// cancellation, and with a timeout of sharedSearchTimeout, so that the
+// callers waiting for it are not affected by the first one going away. Each
+// caller stops waiting when its own ctx is done.
+func (db *DB) sharedSearch(ctx context.Context, sp searchParams, ss map[string]searcher) (*searchResponse, error) {
+	if db.searchGroup == nil {
+		return db.hedgedSearch(ctx, sp, ss, nil)
+	}
+	var executed bool
+	ch := db.searchGroup.DoChan(sp.key(), func() (interface{}, error) {
+		executed = true
+		sctx, cancel := context.WithTimeout(xcontext.Detach(ctx), sharedSearchTimeout)
+		defer cancel()
+		return db.hedgedSearch(sctx, sp, ss, nil)
+	})
+	var res singleflight.Result
+	select {
+	case res = <-ch:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if !executed {
+		stats.Record(ctx, keySearchCoalesced.M(1))
+	}
+	if res.Err != nil {
+		return nil, res.Err
+	}
+	resp := res.Val.(*searchResponse)
+	if !res.Shared {
+		return resp, nil
+	}
+	// The response is shared with other callers, who may modify its results.
+	c := *resp
+	c.results = copySearchResults(resp.results)
+	return &c, nil
+}
+
+// key returns a string that identifies the search of sp.
+func (sp searchParams) key() string {
+	var cursor string
+	if sp.after != nil {
+		cursor = sp.after.encode()
+	}
+	return fmt.Sprintf("%d\x00%d\x00%s\x00%s\x00%s\x00%s", sp.limit, sp.offset, cursor, sp.filter, sp.bucket, sp.q)
+}
+
+// copySearchResults returns a deep copy of rs.
+func copySearchResults(rs []*internal.SearchResult) []*internal.SearchResult {
+	if rs == nil {
+		return nil
+	}
+	c := make([]*internal.SearchResult, len(rs))
+	for i, r := range rs {
+		r2 := *r
+		r2.Licenses = append([]string(nil), r.Licenses...)
+		if r.FacetCounts != nil {
+			r2.FacetCounts = make(map[string]uint64, len(r.FacetCounts))
+			for k, n := range r.FacetCounts {
+				r2.FacetCounts[k] = n
+			}
+		}
+		c[i] = &r2
+	}
+	return c
+}
 
 // hedgedSearch executes multiple search methods and returns the first
 // available result.
 // The optional guardTestResult func may be used to allow tests to control the
 // order in which search results are returned.
-func (db *DB) hedgedSearch(ctx context.Context, q string, limit, offset int, searchers map[string]searcher, guardTestResult func(string) func()) (*searchResponse, error) {
+func (db *DB) hedgedSearch(ctx context.Context, sp searchParams, searchers map[string]searcher, guardTestResult func(string) func()) (*searchResponse, error) {
+	ctx, span := tracing.StartSpan(ctx, "DB.hedgedSearch")
+	defer span.End()
 	searchStart := time.Now()
 	responses := make(chan searchResponse, len(searchers))
 	// cancel all unfinished searches when a result (or error) is returned. The
 	// effectiveness of this depends on the database driver.
-	searchCtx, cancel := context.WithCancel(ctx)
+	// Searches that take much longer than the recent P95 latency (within
+	// bounds) time out.
+	searchCtx, cancel := context.WithTimeout(ctx, db.searchLatency.timeout())
 	defer cancel()
+	succeeded := false
+	defer func() {
+		// Record the latency of searches that timed out too, so that the
+		// timeout can grow when searches get slower.
+		if !succeeded && searchCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+			db.searchLatency.record(time.Since(searchStart))
+		}
+	}()
 
 	// Asynchronously query for the estimated result count.
 	estimateChan := make(chan estimateResponse, 1)
 	go func() {
 		start := time.Now()
-		estimateResp := db.estimateResultsCount(searchCtx, q)
+		estimateResp := db.estimateResultsCount(searchCtx, sp)
+		latency := time.Since(start)
 		log.Debug(ctx, searchEvent{
 			Type:    "estimate",
-			Latency: time.Since(start),
+			Latency: latency,
 			Err:     estimateResp.err,
 		})
+		recordSearchSourceLatency(ctx, "estimate", latency, estimateResp.err)
 		if guardTestResult != nil {
 			defer guardTestResult("estimate")()
 		}
@@ -199,12 +656,14 @@ func (db *DB) hedgedSearch(ctx context.Context, q string, limit, offset int, sea
 		s := s
 		go func() {
 			start := time.Now()
-			resp := s(db, searchCtx, q, limit, offset)
+			resp := s(db, searchCtx, sp)
+			latency := time.Since(start)
 			log.Debug(ctx, searchEvent{
 				Type:    resp.source,
-				Latency: time.Since(start),
+				Latency: latency,
 				Err:     resp.err,
 			})
+			recordSearchSourceLatency(ctx, resp.source, latency, resp.err)
 			if guardTestResult != nil {
 				defer guardTestResult(resp.source)()
 			}
@@ -247,15 +706,18 @@ func (db *DB) hedgedSearch(ctx context.Context, q string, limit, offset int, sea
 					// result-level data from this query-level metadata.
 					r.NumResults = estr.estimate
 					r.Approximate = true
+					r.NumResultsError = hllStandardError(db.hllPrecision())
 				}
 				break loop
-			case <-ctx.Done():
+			case <-searchCtx.Done():
 				return nil, fmt.Errorf("context deadline exceeded while waiting for estimated result count")
 			}
 		}
 	}
 	// cancel proactively here: we've got the search result we need.
 	cancel()
+	succeeded = true
+	db.searchLatency.record(time.Since(searchStart))
 	// latency is only recorded for valid search results, as fast failures could
 	// skew the latency distribution.
 	// Note that this latency measurement might differ meaningfully from the
@@ -271,23 +733,115 @@ func (db *DB) hedgedSearch(ctx context.Context, q string, limit, offset int, sea
 	if err := db.addPackageDataToSearchResults(ctx, resp.results); err != nil {
 		return nil, err
 	}
+	for _, r := range resp.results {
+		c := searchCursor{Score: r.Score, CommitTime: r.CommitTime, PackagePath: r.PackagePath}
+		r.NextCursor = c.encode()
+	}
 	return &resp, nil
 }
 
-const hllRegisterCount = 128
+// logSearchQuery records a successful search of q, the String of the parsed
+// query, with db.SearchQueryLogger, if it is set. It is queued to be recorded
+// in the background, so that the search does not wait for it. If the queue is
+// full, the search is not recorded, and is counted by
+// SearchQueryLogDroppedCount. Failures are logged, and do not fail the
+// search.
+func (db *DB) logSearchQuery(ctx context.Context, q string, resp *searchResponse, d time.Duration) {
+	if db.SearchQueryLogger == nil || db.searchLog == nil {
+		return
+	}
+	entry := SearchLogEntry{
+		QueryHash:   HashSearchQuery(db.SearchQueryLogKey, q),
+		Source:      resp.source,
+		DurationMs:  d.Milliseconds(),
+		ZeroResults: len(resp.results) == 0,
+	}
+	if len(resp.results) > 0 {
+		entry.NumResults = int(resp.results[0].NumResults)
+	}
+	if !db.searchLog.add(ctx, db.SearchQueryLogger, entry) {
+		stats.Record(ctx, keySearchQueryLogDropped.M(1))
+	}
+}
+
+const (
+	// MinHLLPrecision and MaxHLLPrecision bound DB.HLLPrecision.
+	MinHLLPrecision = 4
+	MaxHLLPrecision = 18
+
+	// DefaultHLLPrecision is the precision of the result count estimates if
+	// DB.HLLPrecision is not set.
+	DefaultHLLPrecision = 14
+)
+
+// hllStoredRegisterCount is the number of values of the hll_register column
+// of search_documents. Registers are stored at the maximum precision, so
+// that estimates at any precision can be computed from them.
+const hllStoredRegisterCount = 1 << MaxHLLPrecision
+
+// ValidateHLLPrecision returns an error if p is not a valid precision for the
+// result count estimates of search, that is if it is not between
+// MinHLLPrecision and MaxHLLPrecision.
+func ValidateHLLPrecision(p int) error {
+	if p < MinHLLPrecision || p > MaxHLLPrecision {
+		return fmt.Errorf("HLL precision %d is out of range: it must be between %d and %d: %w",
+			p, MinHLLPrecision, MaxHLLPrecision, derrors.InvalidArgument)
+	}
+	return nil
+}
+
+// hllPrecision returns the precision of the result count estimates of db.
+func (db *DB) hllPrecision() int {
+	if db.HLLPrecision == 0 {
+		return DefaultHLLPrecision
+	}
+	return db.HLLPrecision
+}
+
+// hllStandardError returns the relative standard error of the result count
+// estimates at the given precision, 1.04/sqrt(m) for m registers, per
+// http://algo.inria.fr/flajolet/Publications/FlFuGaMe07.pdf.
+func hllStandardError(precision int) float64 {
+	return 1.04 / math.Sqrt(float64(int(1)<<precision))
+}
+
+// hllAlpha returns the bias correction constant a_m of the hyperloglog
+// algorithm for m registers, per
+// https://en.wikipedia.org/wiki/HyperLogLog#Practical_considerations.
+func hllAlpha(m int) float64 {
+	switch m {
+	case 16:
+		return 0.673
+	case 32:
+		return 0.697
+	case 64:
+		return 0.709
+	default:
+		return 0.7213 / (1 + 1.079/float64(m))
+	}
+}
 
 // hllQuery estimates search result counts using the hyperloglog algorithm.
 // https://en.wikipedia.org/wiki/HyperLogLog
 //
+// Its parameters are the search query ($1), the number of registers m ($2),
+// which is 2 to the power of the precision, the number of bits by which the
+// stored registers are shifted to get the registers at that precision ($3),
+// and the bias correction constant a_m ($4). A higher precision makes the
+// estimate more accurate, with a standard error of about 1.04/sqrt(m), but
+// the query slower, since it looks up each register.
+//
 // Here's how this works:
-//   1) Search documents have been partitioned ~evenly into hllRegisterCount
-//   registers, using the hll_register column. For each hll_register, compute
-//   the maximum number of leading zeros of any element in the register
-//   matching our search query. This is the slowest part of the query, but
-//   since we have an index on (hll_register, hll_leading_zeros desc), we can
-//   parallelize this and it should be very quick if the density of search
-//   results is high.  To achieve this parallelization, we use a trick of
-//   selecting a subselected value from generate_series(0, hllRegisterCount-1).
+//   1) Search documents have been partitioned ~evenly into
+//   hllStoredRegisterCount registers, using the hll_register column. A
+//   register at precision p is the range of stored registers that have the
+//   same p high bits. For each register, compute the maximum number of
+//   leading zeros of any element in the register matching our search query.
+//   This is the slowest part of the query, but since we have an index on
+//   (hll_register, hll_leading_zeros desc), we can parallelize this and it
+//   should be very quick if the density of search results is high. To
+//   achieve this parallelization, we use a trick of selecting a subselected
+//   value from generate_series(0, m-1).
 //
 //   If there are NO search results in a register, the 'zeros' column will be
 //   NULL.
@@ -298,44 +852,54 @@ const hllRegisterCount = 128
 //   Specifically, use linear counting when E < (5/2)m and there are empty
 //   registers.
 //
-//   This should work for any register count >= 128. If we are to decrease this
-//   register count, we should adjust the estimate for a_m below according to
-//   the formulas in the wikipedia article above.
-var hllQuery = fmt.Sprintf(`
+// Only search documents matching the SQL predicate match and satisfying the
+// predicate filter, and with a score above the threshold of the searches,
+// are counted.
+func hllQuery(score, match, filter string) string {
+	return fmt.Sprintf(`
 	WITH hll_data AS (
 		SELECT (
 			SELECT * FROM (
 				SELECT hll_leading_zeros
 				FROM search_documents
 				WHERE (
-					%[2]s *
-					CASE WHEN tsv_search_tokens @@ websearch_to_tsquery($1) THEN 1 ELSE 0 END
+					%[1]s *
+					CASE WHEN %[2]s THEN 1 ELSE 0 END
 				) > 0.1
-				AND hll_register=generate_series
+				AND (%[3]s)
+				AND hll_register BETWEEN generate_series << $3::int
+					AND ((generate_series + 1) << $3::int) - 1
 				ORDER BY hll_leading_zeros DESC
 			) t
 			LIMIT 1
 		) zeros
-		FROM generate_series(0,%[1]d-1)
+		FROM generate_series(0, $2::int - 1)
 	),
 	nonempty_registers as (SELECT zeros FROM hll_data WHERE zeros IS NOT NULL)
 	SELECT
 		-- use linear counting when there are not enough results, and there is at
 		-- least one empty register, per 'Practical Considerations'.
-		CASE WHEN result_count < 2.5 * %[1]d AND empty_register_count > 0
-		THEN ((0.7213 / (1 + 1.079 / %[1]d)) * (%[1]d *
-				log(2, (%[1]d::numeric) / empty_register_count)))::int
+		CASE WHEN result_count < 2.5 * $2::int AND empty_register_count > 0
+		THEN ($2::int * ln($2::numeric / empty_register_count))::int
 		ELSE result_count END AS approx_count
 	FROM (
 		SELECT
 			(
-				(0.7213 / (1 + 1.079 / %[1]d)) *  -- estimate for a_m
-				pow(%[1]d, 2) *                   -- m^2
-				(1/((%[1]d - count(1)) + SUM(POW(2, -1 * (zeros+1)))))  -- Z
+				$4::float8 *         -- estimate for a_m
+				pow($2::int, 2) *    -- m^2
+				(1/(($2::int - count(1)) + SUM(POW(2, -1 * (zeros+1)))))  -- Z
 			)::int AS result_count,
-			%[1]d - count(1) AS empty_register_count
+			$2::int - count(1) AS empty_register_count
 		FROM nonempty_registers
-	) d`, hllRegisterCount, scoreExpr)
+	) d`, score, match, filter)
+}
+
+// hllParams returns the parameters of hllQuery after the search query, for
+// the given precision.
+func hllParams(precision int) []interface{} {
+	m := 1 << precision
+	return []interface{}{m, MaxHLLPrecision - precision, hllAlpha(m)}
+}
 
 type estimateResponse struct {
 	estimate uint64
@@ -343,9 +907,15 @@ type estimateResponse struct {
 }
 
 // EstimateResultsCount uses the hyperloglog algorithm to estimate the number
-// of results for the given search term.
-func (db *DB) estimateResultsCount(ctx context.Context, q string) estimateResponse {
-	row := db.db.QueryRow(ctx, hllQuery, q)
+// of results for the given search.
+func (db *DB) estimateResultsCount(ctx context.Context, sp searchParams) estimateResponse {
+	precision := db.hllPrecision()
+	if err := ValidateHLLPrecision(precision); err != nil {
+		return estimateResponse{err: err}
+	}
+	score, match := db.textSearchExprs(sp)
+	args := append([]interface{}{sp.q}, hllParams(precision)...)
+	row := db.readDB().QueryRow(ctx, hllQuery(score, match, sp.filterSQL()), args...)
 	var estimate sql.NullInt64
 	if err := row.Scan(&estimate); err != nil {
 		return estimateResponse{err: fmt.Errorf("row.Scan(): %v", err)}
@@ -357,28 +927,15 @@ func (db *DB) estimateResultsCount(ctx context.Context, q string) estimateRespon
 
 // deepSearch searches all packages for the query. It is slower, but results
 // are always valid.
-func (db *DB) deepSearch(ctx context.Context, q string, limit, offset int) searchResponse {
-	query := fmt.Sprintf(`
-		SELECT *, COUNT(*) OVER() AS total
-		FROM (
-			SELECT
-				package_path,
-				version,
-				module_path,
-				commit_time,
-				imported_by_count,
-				(%s) AS score
-				FROM
-					search_documents
-				WHERE tsv_search_tokens @@ websearch_to_tsquery($1)
-				ORDER BY
-					score DESC,
-					commit_time DESC,
-					package_path
-		) r
-		WHERE r.score > 0.1
-		LIMIT $2
-		OFFSET $3`, scoreExpr)
+//
+// The total result count is computed before the cursor is applied, so that it
+// is the same on every page.
+//
+// If db.FuzzySearchEnabled is set, packages whose paths are similar to the
+// query also match, and are scored by their similarity.
+func (db *DB) deepSearch(ctx context.Context, sp searchParams) searchResponse {
+	score, match := db.deepSearchExprs(sp)
+	query := deepSearchQuery(score, match, sp.filterSQL())
 	var results []*internal.SearchResult
 	collect := func(rows *sql.Rows) error {
 		var r internal.SearchResult
@@ -389,7 +946,8 @@ func (db *DB) deepSearch(ctx context.Context, q string, limit, offset int) searc
 		results = append(results, &r)
 		return nil
 	}
-	err := db.db.RunQuery(ctx, query, collect, q, limit, offset)
+	args := append([]interface{}{sp.q, sp.limit, sp.offset}, sp.cursorArgs()...)
+	err := db.runSearchQuery(ctx, query, collect, args...)
 	if err != nil {
 		results = nil
 	}
@@ -400,27 +958,174 @@ func (db *DB) deepSearch(ctx context.Context, q string, limit, offset int) searc
 	}
 }
 
-func (db *DB) popularSearch(ctx context.Context, searchQuery string, limit, offset int) searchResponse {
-	query := `
+// deepSearchQuery returns the query of deepSearch, given the SQL expressions
+// for the score of a search document, the predicate matching it, and the
+// predicate of the search filters.
+func deepSearchQuery(score, match, filter string) string {
+	return fmt.Sprintf(`
+		SELECT package_path, version, module_path, commit_time, imported_by_count, score, total
+		FROM (
+			SELECT *, COUNT(*) OVER() AS total
+			FROM (
+				SELECT
+					package_path,
+					version,
+					module_path,
+					commit_time,
+					imported_by_count,
+					(%s) AS score
+					FROM
+						search_documents
+					WHERE (%s)
+					AND (%s)
+			) r
+			WHERE r.score > 0.1
+		) s
+		WHERE
+			$4::float8 IS NULL
+			OR s.score < $4
+			OR (s.score = $4 AND s.commit_time < $5)
+			OR (s.score = $4 AND s.commit_time = $5 AND s.package_path > $6)
+		ORDER BY
+			score DESC,
+			commit_time DESC,
+			package_path
+		LIMIT $2
+		OFFSET $3`, score, match, filter)
+}
+
+// deepSearchExprs returns the SQL expressions for the score of a search
+// document and the predicate matching it, as used by deepSearch.
+func (db *DB) deepSearchExprs(sp searchParams) (score, match string) {
+	if db.FuzzySearchEnabled && sp.q != "" {
+		return db.scoreExpr(sp, fuzzySearchRank), fuzzyMatch
+	}
+	return db.textSearchExprs(sp)
+}
+
+// textSearchExprs returns the SQL expressions for the score of a search
+// document and the predicate matching it, without fuzzy matching. If sp has
+// no query text, because the query has only filters, every search document
+// matches with the same relevance, so that results are ranked by popularity.
+func (db *DB) textSearchExprs(sp searchParams) (score, match string) {
+	if sp.q == "" {
+		return db.scoreExpr(sp, filterOnlyRank), filterOnlyMatch
+	}
+	return db.scoreExpr(sp, searchRank), textMatch
+}
+
+// textMatch is the predicate matching search documents for the query text,
+// unless fuzzy search is enabled.
+const textMatch = "tsv_search_tokens @@ websearch_to_tsquery($1)"
+
+// filterOnlyRank and filterOnlyMatch replace searchRank and textMatch for
+// queries without text. The match still refers to $1, which is then empty,
+// so that the search queries take the same parameters in both cases.
+const (
+	filterOnlyRank  = "1"
+	filterOnlyMatch = "$1::text = ''"
+)
+
+// licenseFacetCounts returns the number of packages matching the search
+// that have each license type. It matches packages in the same way as
+// deepSearch.
+func (db *DB) licenseFacetCounts(ctx context.Context, sp searchParams) (_ map[string]uint64, err error) {
+	defer derrors.Wrap(&err, "licenseFacetCounts(ctx, %q)", sp.q)
+
+	score, match := db.deepSearchExprs(sp)
+	query := fmt.Sprintf(`
+		WITH matches AS (
+			SELECT package_path, license_types
+			FROM search_documents
+			WHERE (%s)
+			AND (%s)
+			AND (%s) > 0.1
+		)
+		SELECT t, COUNT(DISTINCT package_path)
+		FROM matches, UNNEST(license_types) t
+		WHERE t != ''
+		GROUP BY t`, match, sp.filterSQL(), score)
+	counts := make(map[string]uint64)
+	collect := func(rows *sql.Rows) error {
+		var (
+			typ string
+			n   uint64
+		)
+		if err := rows.Scan(&typ, &n); err != nil {
+			return fmt.Errorf("rows.Scan(): %v", err)
+		}
+		counts[typ] = n
+		return nil
+	}
+	if err := db.readDB().RunQuery(ctx, query, collect, sp.q); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// popularSearchQuery returns the query scanned by popularSearch: the search
+// documents satisfying filter, with their score for the query $1, or zero if
+// they do not satisfy match, in descending order of popularity. score must
+// not exceed ln(exp(1)+imported_by_count) for a matching document, or the
+// early exit of popularSearch would not be valid.
+func popularSearchQuery(score, match, filter string) string {
+	return fmt.Sprintf(`
 		SELECT
 			package_path,
 			version,
 			module_path,
 			commit_time,
 			imported_by_count,
-			score
-		FROM popular_search($1, $2, $3, $4, $5)`
+			(%s) * CASE WHEN %s THEN 1 ELSE 0 END AS score
+		FROM search_documents
+		WHERE %s
+		ORDER BY imported_by_count DESC`, score, match, filter)
+}
+
+// popularSearchFetchSize is the number of rows popularSearch fetches from its
+// cursor at a time.
+const popularSearchFetchSize = 500
+
+// popularSearch scans search documents procedurally, in descending order of
+// popularity, and stops scanning early, whenever the search results are
+// provably correct. The scan is a cursor in a read-only transaction, so the
+// query is built here from fixed templates rather than by a stored function.
+func (db *DB) popularSearch(ctx context.Context, sp searchParams) searchResponse {
 	var results []*internal.SearchResult
-	collect := func(rows *sql.Rows) error {
-		var r internal.SearchResult
-		if err := rows.Scan(&r.PackagePath, &r.Version, &r.ModulePath, &r.CommitTime,
-			&r.NumImportedBy, &r.Score); err != nil {
-			return fmt.Errorf("rows.Scan(): %v", err)
+	score, match := db.textSearchExprs(sp)
+	query := popularSearchQuery(score, match, sp.filterSQL())
+	err := db.readDB().Transact(ctx, sql.LevelDefault, func(tx *database.DB) error {
+		if _, err := tx.Exec(ctx, "DECLARE popular_search_cursor NO SCROLL CURSOR FOR "+query, sp.q); err != nil {
+			return err
 		}
-		results = append(results, &r)
+		top := newTopResults(sp)
+		fetch := fmt.Sprintf("FETCH %d FROM popular_search_cursor", popularSearchFetchSize)
+		for {
+			var n int
+			done := false
+			err := tx.RunQuery(ctx, fetch, func(rows *sql.Rows) error {
+				n++
+				if done {
+					return nil
+				}
+				var r internal.SearchResult
+				if err := rows.Scan(&r.PackagePath, &r.Version, &r.ModulePath, &r.CommitTime,
+					&r.NumImportedBy, &r.Score); err != nil {
+					return fmt.Errorf("rows.Scan(): %v", err)
+				}
+				done = !top.add(&r)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if done || n < popularSearchFetchSize {
+				break
+			}
+		}
+		results = top.results()
 		return nil
-	}
-	err := db.db.RunQuery(ctx, query, collect, searchQuery, limit, offset, nonRedistributablePenalty, noGoModPenalty)
+	})
 	if err != nil {
 		results = nil
 	}
@@ -432,6 +1137,160 @@ func (db *DB) popularSearch(ctx context.Context, searchQuery string, limit, offs
 	}
 }
 
+// topResults holds the best limit+offset search results seen so far, in
+// search result order, for popularSearch.
+type topResults struct {
+	after *searchCursor
+	off   int
+	top   []*internal.SearchResult
+}
+
+func newTopResults(sp searchParams) *topResults {
+	return &topResults{
+		after: sp.after,
+		off:   sp.offset,
+		top:   make([]*internal.SearchResult, sp.limit+sp.offset),
+	}
+}
+
+// resultBefore reports whether a search result with score, commitTime and
+// path is ordered before r: by score descending, then commit time
+// descending, then package path ascending.
+func resultBefore(score float64, commitTime time.Time, path string, r *internal.SearchResult) bool {
+	if score != r.Score {
+		return score > r.Score
+	}
+	if !commitTime.Equal(r.CommitTime) {
+		return commitTime.After(r.CommitTime)
+	}
+	return path < r.PackagePath
+}
+
+// add inserts r in t, if it belongs there. Results must be added in
+// descending order of imported-by count. add reports whether later results
+// may still belong in t: scores never exceed ln(exp(1)+imported_by_count), so
+// once the last result of t scores higher than that, the scan can stop.
+func (t *topResults) add(r *internal.SearchResult) bool {
+	last := len(t.top) - 1
+	if last < 0 {
+		return false
+	}
+	// Skip results at or before the cursor position.
+	if t.after == nil || resultBefore(t.after.Score, t.after.CommitTime, t.after.PackagePath, r) {
+		if t.top[last] == nil || r.Score >= t.top[last].Score {
+			for i, x := range t.top {
+				if x == nil || resultBefore(r.Score, r.CommitTime, r.PackagePath, x) {
+					copy(t.top[i+1:], t.top[i:last])
+					t.top[i] = r
+					break
+				}
+			}
+		}
+	}
+	return t.top[last] == nil || t.top[last].Score <= math.Log(math.E+float64(r.NumImportedBy))
+}
+
+// results returns the results of t after the offset, omitting those with
+// negligible scores.
+func (t *topResults) results() []*internal.SearchResult {
+	var rs []*internal.SearchResult
+	for _, r := range t.top[t.off:] {
+		if r != nil && r.Score > 0.1 {
+			rs = append(rs, r)
+		}
+	}
+	return rs
+}
+
+// searchFilterSQL returns a SQL predicate on the columns of search_documents
+// that holds for documents matching all of the given filters, or the empty
+// string if there are no filters. Values are quoted with pq.QuoteLiteral, so
+// the predicate can be embedded directly in a query.
+func searchFilterSQL(filters []search.FieldFilter) string {
+	var preds []string
+	for _, f := range filters {
+		v := pq.QuoteLiteral(f.Value)
+		switch f.Field {
+		case search.FieldLicense:
+			preds = append(preds, fmt.Sprintf(
+				"EXISTS (SELECT 1 FROM UNNEST(license_types) t WHERE LOWER(t) = LOWER(%s))", v))
+		case search.FieldImported:
+			// The operator has been validated by search.ParseSearchQuery.
+			preds = append(preds, fmt.Sprintf("imported_by_count %s %s::integer", f.Op, v))
+		case search.FieldPath:
+			preds = append(preds, fmt.Sprintf(
+				"(package_path = %[1]s OR starts_with(package_path, %[1]s || '/'))", v))
+		case search.FieldCgo:
+			preds = append(preds, fmt.Sprintf("requires_cgo = %s::boolean", v))
+		case search.FieldFuzz:
+			preds = append(preds, fmt.Sprintf("has_fuzz_tests = %s::boolean", v))
+		case search.FieldBenchmarks:
+			preds = append(preds, fmt.Sprintf("(benchmark_count > 0) = %s::boolean", v))
+		case search.FieldRedistributable:
+			preds = append(preds, fmt.Sprintf("redistributable = %s::boolean", v))
+		}
+	}
+	return joinPredicates(preds)
+}
+
+// searchFilter returns the SQL predicate on search_documents of a search with
+// the field filters of its query and opts. PrepareSearchStatements prepares
+// the queries for searchFilter(nil, SearchOptions{}).
+//
+// Unless the query filters on redistributable, only redistributable packages
+// match, so that deepSearch can use the partial index
+// idx_search_documents_tsv_search_tokens_redistributable.
+func searchFilter(filters []search.FieldFilter, opts SearchOptions) string {
+	if opts.MinImportedBy > 0 {
+		filters = append(filters[:len(filters):len(filters)], minImportedByFilter(opts.MinImportedBy))
+	}
+	redistributable := defaultRedistributableFilterSQL
+	for _, f := range filters {
+		if f.Field == search.FieldRedistributable {
+			redistributable = ""
+		}
+	}
+	return joinPredicates([]string{redistributable, searchFilterSQL(filters), commitTimeFilterSQL(opts.UpdatedAfter, opts.UpdatedBefore)})
+}
+
+// defaultRedistributableFilterSQL is the predicate of searches that do not
+// filter on redistributable. It must imply the predicate of the partial index
+// idx_search_documents_tsv_search_tokens_redistributable.
+const defaultRedistributableFilterSQL = "redistributable = TRUE"
+
+// commitTimeFilterSQL returns a SQL predicate on search_documents that holds
+// for documents committed between after and before, inclusive. A zero time
+// leaves that end of the range open. If both are zero, commitTimeFilterSQL
+// returns the empty string.
+func commitTimeFilterSQL(after, before time.Time) string {
+	var preds []string
+	if !after.IsZero() {
+		preds = append(preds, fmt.Sprintf("commit_time >= %s::timestamptz", pq.QuoteLiteral(after.Format(time.RFC3339Nano))))
+	}
+	if !before.IsZero() {
+		preds = append(preds, fmt.Sprintf("commit_time <= %s::timestamptz", pq.QuoteLiteral(before.Format(time.RFC3339Nano))))
+	}
+	return joinPredicates(preds)
+}
+
+// joinPredicates returns the conjunction of the non-empty SQL predicates in
+// preds, or the empty string if there are none.
+func joinPredicates(preds []string) string {
+	var nonEmpty []string
+	for _, p := range preds {
+		if p != "" {
+			nonEmpty = append(nonEmpty, p)
+		}
+	}
+	return strings.Join(nonEmpty, " AND ")
+}
+
+// minImportedByFilter returns the filter that restricts results to packages
+// imported by at least n packages.
+func minImportedByFilter(n int) search.FieldFilter {
+	return search.FieldFilter{Field: search.FieldImported, Op: search.OpGreaterOrEqual, Value: strconv.Itoa(n)}
+}
+
 // addPackageDataToSearchResults adds package information to SearchResults that is not stored
 // in the search_documents table.
 func (db *DB) addPackageDataToSearchResults(ctx context.Context, results []*internal.SearchResult) (err error) {
@@ -453,20 +1312,38 @@ func (db *DB) addPackageDataToSearchResults(ctx context.Context, results []*inte
 	}
 	query := fmt.Sprintf(`
 		SELECT
-			path,
-			name,
-			synopsis,
-			license_types
+			p.path,
+			p.name,
+			p.synopsis,
+			p.license_types,
+			COALESCE(sd.exported_symbol_count, 0),
+			COALESCE(sd.has_tests, false),
+			COALESCE(sd.doc_coverage_pct, 0),
+			COALESCE(sd.requires_cgo, false),
+			COALESCE(sd.has_fuzz_tests, false),
+			COALESCE(sd.benchmark_count, 0)
 		FROM
-			packages
+			packages p
+		LEFT JOIN
+			search_documents sd
+		ON
+			sd.package_path = p.path
+			AND sd.module_path = p.module_path
+			AND sd.version = p.version
 		WHERE
-			(path, version, module_path) IN (%s)`, strings.Join(keys, ","))
+			(p.path, p.version, p.module_path) IN (%s)`, strings.Join(keys, ","))
 	collect := func(rows *sql.Rows) error {
 		var (
 			path, name, synopsis string
 			licenseTypes         []string
+			exportedSymbols      int
+			hasTests             bool
+			docCoverage          int
+			requiresCgo          bool
+			hasFuzzTests         bool
+			benchmarkCount       int
 		)
-		if err := rows.Scan(&path, &name, &synopsis, pq.Array(&licenseTypes)); err != nil {
+		if err := rows.Scan(&path, &name, &synopsis, pq.Array(&licenseTypes), &exportedSymbols, &hasTests, &docCoverage, &requiresCgo, &hasFuzzTests, &benchmarkCount); err != nil {
 			return fmt.Errorf("rows.Scan(): %v", err)
 		}
 		r, ok := resultMap[path]
@@ -475,6 +1352,12 @@ func (db *DB) addPackageDataToSearchResults(ctx context.Context, results []*inte
 		}
 		r.Name = name
 		r.Synopsis = synopsis
+		r.ExportedSymbols = exportedSymbols
+		r.HasTests = hasTests
+		r.DocCoverage = docCoverage
+		r.RequiresCgo = requiresCgo
+		r.HasFuzzTests = hasFuzzTests
+		r.BenchmarkCount = benchmarkCount
 		for _, l := range licenseTypes {
 			if l != "" {
 				r.Licenses = append(r.Licenses, l)
@@ -482,7 +1365,7 @@ func (db *DB) addPackageDataToSearchResults(ctx context.Context, results []*inte
 		}
 		return nil
 	}
-	return db.db.RunQuery(ctx, query, collect)
+	return db.readDB().RunQuery(ctx, query, collect)
 }
 
 var upsertSearchStatement = fmt.Sprintf(`
@@ -497,9 +1380,24 @@ var upsertSearchStatement = fmt.Sprintf(`
 		version_updated_at,
 		commit_time,
 		has_go_mod,
+		latest_version_is_retracted,
 		tsv_search_tokens,
+		synopsis_vector,
+		readme_vector,
 		hll_register,
-		hll_leading_zeros
+		hll_leading_zeros,
+		health_score,
+		exported_symbol_count,
+		has_tests,
+		doc_coverage_pct,
+		embedded_patterns,
+		build_contexts,
+		requires_cgo,
+		is_deprecated,
+		deprecation_message,
+		successor_packages,
+		has_fuzz_tests,
+		benchmark_count
 	)
 	SELECT
 		p.path,
@@ -512,14 +1410,35 @@ var upsertSearchStatement = fmt.Sprintf(`
 		CURRENT_TIMESTAMP,
 		m.commit_time,
 		m.has_go_mod,
+		m.retracted,
 		(
 			SETWEIGHT(TO_TSVECTOR('path_tokens', $2), 'A') ||
 			SETWEIGHT(TO_TSVECTOR($3), 'B') ||
 			SETWEIGHT(TO_TSVECTOR($4), 'C') ||
 			SETWEIGHT(TO_TSVECTOR($5), 'D')
 		),
+		SETWEIGHT(TO_TSVECTOR($3), 'A'),
+		SETWEIGHT(TO_TSVECTOR($4), 'C') || SETWEIGHT(TO_TSVECTOR($5), 'C'),
 		hll_hash(p.path) & (%[1]d - 1),
-		hll_zeros(hll_hash(p.path))
+		hll_zeros(hll_hash(p.path)),
+		COALESCE($6::real, 0),
+		(
+			SELECT COUNT(*)
+			FROM package_symbols ps
+			WHERE ps.package_path = p.path
+			AND ps.module_path = p.module_path
+			AND ps.version = p.version
+		),
+		COALESCE($7::boolean, false),
+		COALESCE($8::integer, 0),
+		COALESCE($9::text[], '{}'),
+		$10::jsonb,
+		COALESCE($11::boolean, false),
+		COALESCE($12::boolean, false),
+		$13::text,
+		COALESCE($14::text[], '{}'),
+		COALESCE($15::boolean, false),
+		COALESCE($16::integer, 0)
 	FROM
 		packages p
 	INNER JOIN
@@ -549,14 +1468,46 @@ var upsertSearchStatement = fmt.Sprintf(`
 		redistributable=excluded.redistributable,
 		commit_time=excluded.commit_time,
 		has_go_mod=excluded.has_go_mod,
+		latest_version_is_retracted=excluded.latest_version_is_retracted,
 		tsv_search_tokens=excluded.tsv_search_tokens,
+		synopsis_vector=excluded.synopsis_vector,
+		readme_vector=excluded.readme_vector,
+		-- $6 is the health score without the points for imported_by_count,
+		-- which is not changed by upserts
+		health_score=(
+			CASE WHEN $6::real IS NULL
+			THEN search_documents.health_score
+			ELSE $6::real + %[2]s
+			END),
+		exported_symbol_count=excluded.exported_symbol_count,
+		has_tests=COALESCE($7::boolean, search_documents.has_tests),
+		doc_coverage_pct=COALESCE($8::integer, search_documents.doc_coverage_pct),
+		embedded_patterns=COALESCE($9::text[], search_documents.embedded_patterns),
+		build_contexts=COALESCE($10::jsonb, search_documents.build_contexts),
+		requires_cgo=COALESCE($11::boolean, search_documents.requires_cgo),
+		is_deprecated=COALESCE($12::boolean, search_documents.is_deprecated),
+		deprecation_message=(
+			CASE WHEN $12::boolean IS NULL
+			THEN search_documents.deprecation_message
+			ELSE $13::text
+			END),
+		successor_packages=COALESCE($14::text[], search_documents.successor_packages),
+		has_fuzz_tests=COALESCE($15::boolean, search_documents.has_fuzz_tests),
+		benchmark_count=COALESCE($16::integer, search_documents.benchmark_count),
 		-- the hll fields are functions of path, so they don't change
 		version_updated_at=(
 			CASE WHEN excluded.version = search_documents.version
 			THEN search_documents.version_updated_at
 			ELSE CURRENT_TIMESTAMP
 			END)
-	;`, hllRegisterCount)
+	;`, hllStoredRegisterCount, importedByPointsSQL("search_documents.imported_by_count"))
+
+// importedByPointsSQL returns the SQL expression for scoring.ImportedByPoints
+// of the imported-by count expression count.
+func importedByPointsSQL(count string) string {
+	return fmt.Sprintf("LEAST(%[2]d, %[2]d * log(1 + GREATEST(%[1]s, 0)) / log(1 + %[3]d))",
+		count, scoring.MaxImportedByPoints, scoring.FullImportedByCount)
+}
 
 // UpsertSearchDocuments adds search information for mod ot the search_documents table.
 func UpsertSearchDocuments(ctx context.Context, db *database.DB, mod *internal.Module) (err error) {
@@ -567,12 +1518,39 @@ func UpsertSearchDocuments(ctx context.Context, db *database.DB, mod *internal.M
 		if isInternalPackage(pkg.Path) {
 			continue
 		}
+		// Nil slices would keep the stored values.
+		embedded := pkg.EmbeddedPatterns
+		if embedded == nil {
+			embedded = []string{}
+		}
+		bcs := pkg.BuildContexts
+		if bcs == nil {
+			bcs = []internal.BuildContext{}
+		}
+		successors := pkg.SuccessorPackages
+		if successors == nil {
+			successors = []string{}
+		}
 		err := UpsertSearchDocument(ctx, db, upsertSearchDocumentArgs{
-			PackagePath:    pkg.Path,
-			ModulePath:     mod.ModulePath,
-			Synopsis:       pkg.Synopsis,
-			ReadmeFilePath: mod.LegacyReadmeFilePath,
-			ReadmeContents: mod.LegacyReadmeContents,
+			PackagePath:      pkg.Path,
+			ModulePath:       mod.ModulePath,
+			Synopsis:         pkg.Synopsis,
+			ReadmeFilePath:   mod.LegacyReadmeFilePath,
+			ReadmeContents:   mod.LegacyReadmeContents,
+			HealthScore:      sql.NullFloat64{Float64: scoring.ComputeHealthScore(mod, 0), Valid: true},
+			HasTests:         sql.NullBool{Bool: scoring.PackageHasTests(mod, pkg.Path), Valid: true},
+			DocCoverage:      sql.NullInt32{Int32: int32(internal.DocCoverage(pkg.Symbols)), Valid: true},
+			EmbeddedPatterns: embedded,
+			BuildContexts:    bcs,
+			RequiresCgo:      sql.NullBool{Bool: pkg.RequiresCgo, Valid: true},
+			IsDeprecated:     sql.NullBool{Bool: pkg.IsDeprecated, Valid: true},
+			DeprecationMessage: sql.NullString{
+				String: pkg.DeprecationMessage,
+				Valid:  pkg.IsDeprecated,
+			},
+			SuccessorPackages: successors,
+			HasFuzzTests:      sql.NullBool{Bool: pkg.HasFuzzTests, Valid: true},
+			BenchmarkCount:    sql.NullInt32{Int32: int32(pkg.BenchmarkCount), Valid: true},
 		})
 		if err != nil {
 			return err
@@ -587,6 +1565,40 @@ type upsertSearchDocumentArgs struct {
 	Synopsis       string
 	ReadmeFilePath string
 	ReadmeContents string
+	// HealthScore is the health score of the package for an imported-by
+	// count of zero. If it is not valid, the stored health score is kept.
+	HealthScore sql.NullFloat64
+	// HasTests reports whether the directory of the package has a Go test
+	// file. If it is not valid, the stored value is kept.
+	HasTests sql.NullBool
+	// DocCoverage is the percentage of the exported symbols of the package
+	// that are documented. If it is not valid, the stored value is kept.
+	DocCoverage sql.NullInt32
+	// EmbeddedPatterns are the patterns of the //go:embed directives of the
+	// package. If it is nil, the stored patterns are kept.
+	EmbeddedPatterns []string
+	// BuildContexts are the build contexts that the package builds for. If
+	// it is nil, the stored build contexts are kept.
+	BuildContexts []internal.BuildContext
+	// RequiresCgo reports whether a file of the package imports "C". If it
+	// is not valid, the stored value is kept.
+	RequiresCgo sql.NullBool
+	// IsDeprecated reports whether the package doc comment has a
+	// "Deprecated: " paragraph. If it is not valid, the stored deprecation is
+	// kept, including the message.
+	IsDeprecated sql.NullBool
+	// DeprecationMessage is the rest of the "Deprecated: " paragraph. It is
+	// stored as NULL if it is not valid.
+	DeprecationMessage sql.NullString
+	// SuccessorPackages are the import paths mentioned in the deprecation
+	// message. If it is nil, the stored paths are kept.
+	SuccessorPackages []string
+	// HasFuzzTests reports whether a test file of the package declares a
+	// fuzz target. If it is not valid, the stored value is kept.
+	HasFuzzTests sql.NullBool
+	// BenchmarkCount is the number of benchmarks of the package. If it is
+	// not valid, the stored value is kept.
+	BenchmarkCount sql.NullInt32
 }
 
 // UpsertSearchDocument inserts a row for each package in the module, if that
@@ -604,7 +1616,17 @@ func UpsertSearchDocument(ctx context.Context, db *database.DB, args upsertSearc
 	}
 	pathTokens := strings.Join(GeneratePathTokens(args.PackagePath), " ")
 	sectionB, sectionC, sectionD := SearchDocumentSections(args.Synopsis, args.ReadmeFilePath, args.ReadmeContents)
-	_, err = db.Exec(ctx, upsertSearchStatement, args.PackagePath, pathTokens, sectionB, sectionC, sectionD)
+	var bcsJSON interface{} // NULL unless there are build contexts
+	if args.BuildContexts != nil {
+		b, err := json.Marshal(args.BuildContexts)
+		if err != nil {
+			return err
+		}
+		bcsJSON = string(b)
+	}
+	_, err = db.Exec(ctx, upsertSearchStatement, args.PackagePath, pathTokens, sectionB, sectionC, sectionD, args.HealthScore, args.HasTests, args.DocCoverage,
+		pq.Array(args.EmbeddedPatterns), bcsJSON, args.RequiresCgo, args.IsDeprecated, args.DeprecationMessage,
+		pq.Array(args.SuccessorPackages), args.HasFuzzTests, args.BenchmarkCount)
 	return err
 }
 
@@ -635,16 +1657,45 @@ func (db *DB) GetPackagesForSearchDocumentUpsert(ctx context.Context, before tim
 	return argsList, nil
 }
 
-// UpdateSearchDocumentsImportedByCount updates imported_by_count and
-// imported_by_count_updated_at.
+// defaultImportedByHalfLife is the half-life of the decay applied by
+// ComputeDecayedImportedByCount, if DB.ImportedByHalfLife is not set.
+const defaultImportedByHalfLife = 2 * 365 * 24 * time.Hour
+
+// ComputeDecayedImportedByCount returns count decayed by the time since
+// lastCommitTime, the commit time of the latest version of a package, so that
+// the imports of packages that are no longer maintained count for less. The
+// count is halved every db.ImportedByHalfLife. Since a commit time in the
+// future is treated as the current time, the result is never greater than
+// count.
+func (db *DB) ComputeDecayedImportedByCount(count int, lastCommitTime time.Time) float64 {
+	halfLife := db.ImportedByHalfLife
+	if halfLife == 0 {
+		halfLife = defaultImportedByHalfLife
+	}
+	age := time.Since(lastCommitTime)
+	if age < 0 {
+		age = 0
+	}
+	return float64(count) * math.Pow(0.5, float64(age)/float64(halfLife))
+}
+
+// UpdateSearchDocumentsImportedByCount updates imported_by_count,
+// decayed_imported_by_count and imported_by_count_updated_at.
 //
 // It does so by completely recalculating the imported-by counts
-// from the imports_unique table.
+// from the imports_unique table. Since that accounts for every change
+// recorded in imports_unique_changelog before it started, it also deletes
+// those changelog entries, so the table does not grow when only full updates
+// are run.
 //
 // UpdateSearchDocumentsImportedByCount returns the number of rows updated.
 func (db *DB) UpdateSearchDocumentsImportedByCount(ctx context.Context) (nUpdated int64, err error) {
 	defer derrors.Wrap(&err, "UpdateSearchDocumentsImportedByCount(ctx)")
 
+	var maxID sql.NullInt64
+	if err := db.db.QueryRow(ctx, `SELECT MAX(id) FROM imports_unique_changelog`).Scan(&maxID); err != nil {
+		return 0, err
+	}
 	searchPackages, err := db.getSearchPackages(ctx)
 	if err != nil {
 		return 0, err
@@ -653,30 +1704,39 @@ func (db *DB) UpdateSearchDocumentsImportedByCount(ctx context.Context) (nUpdate
 	if err != nil {
 		return 0, err
 	}
+	decayed := db.decayImportedByCounts(counts, searchPackages)
 	err = db.db.Transact(ctx, sql.LevelDefault, func(tx *database.DB) error {
-		if err := insertImportedByCounts(ctx, tx, counts); err != nil {
+		if err := insertImportedByCounts(ctx, tx, counts, decayed); err != nil {
 			return err
 		}
 		if err := compareImportedByCounts(ctx, tx); err != nil {
 			return err
 		}
 		nUpdated, err = updateImportedByCounts(ctx, tx)
+		if err != nil || !maxID.Valid {
+			return err
+		}
+		_, err = tx.Exec(ctx, `DELETE FROM imports_unique_changelog WHERE id <= $1`, maxID.Int64)
 		return err
 	})
 	return nUpdated, err
 }
 
-// getSearchPackages returns the set of package paths that are in the search_documents table.
-func (db *DB) getSearchPackages(ctx context.Context) (set map[string]bool, err error) {
+// getSearchPackages returns the package paths that are in the search_documents
+// table, mapped to their commit times.
+func (db *DB) getSearchPackages(ctx context.Context) (set map[string]time.Time, err error) {
 	defer derrors.Wrap(&err, "DB.getSearchPackages(ctx)")
 
-	set = map[string]bool{}
-	err = db.db.RunQuery(ctx, `SELECT package_path FROM search_documents`, func(rows *sql.Rows) error {
-		var p string
-		if err := rows.Scan(&p); err != nil {
+	set = map[string]time.Time{}
+	err = db.db.RunQuery(ctx, `SELECT package_path, commit_time FROM search_documents`, func(rows *sql.Rows) error {
+		var (
+			p string
+			t time.Time
+		)
+		if err := rows.Scan(&p, &t); err != nil {
 			return err
 		}
-		set[p] = true
+		set[p] = t
 		return nil
 	})
 	if err != nil {
@@ -685,7 +1745,7 @@ func (db *DB) getSearchPackages(ctx context.Context) (set map[string]bool, err e
 	return set, nil
 }
 
-func (db *DB) computeImportedByCounts(ctx context.Context, searchDocsPackages map[string]bool) (counts map[string]int, err error) {
+func (db *DB) computeImportedByCounts(ctx context.Context, searchDocsPackages map[string]time.Time) (counts map[string]int, err error) {
 	defer derrors.Wrap(&err, "db.computeImportedByCounts(ctx)")
 
 	counts = map[string]int{}
@@ -709,13 +1769,10 @@ func (db *DB) computeImportedByCounts(ctx context.Context, searchDocsPackages ma
 			return nil, err
 		}
 		// Don't count an importer if it's not in search_documents.
-		if !searchDocsPackages[from] {
+		if _, ok := searchDocsPackages[from]; !ok {
 			continue
 		}
-		// Don't count an importer if it's in the same module as what it's importing.
-		// Approximate that check by seeing if from_module_path is a prefix of to_path.
-		// (In some cases, e.g. when to_path is in a nested module, that is not correct.)
-		if (fromMod == stdlib.ModulePath && stdlib.Contains(to)) || strings.HasPrefix(to+"/", fromMod+"/") {
+		if sameModuleImport(fromMod, to) {
 			continue
 		}
 		counts[to]++
@@ -726,23 +1783,152 @@ func (db *DB) computeImportedByCounts(ctx context.Context, searchDocsPackages ma
 	return counts, nil
 }
 
-func insertImportedByCounts(ctx context.Context, db *database.DB, counts map[string]int) (err error) {
-	defer derrors.Wrap(&err, "insertImportedByCounts(ctx, db, counts)")
+// decayImportedByCounts returns the decayed imported-by counts of the
+// packages in counts, given their commit times.
+func (db *DB) decayImportedByCounts(counts map[string]int, commitTimes map[string]time.Time) map[string]float64 {
+	decayed := make(map[string]float64, len(counts))
+	for p, c := range counts {
+		decayed[p] = db.ComputeDecayedImportedByCount(c, commitTimes[p])
+	}
+	return decayed
+}
+
+// sameModuleImport reports whether an importer in module fromMod is in the
+// same module as the package to that it imports. Such importers are not
+// counted.
+//
+// The check is approximated by seeing if fromMod is a prefix of to. (In some
+// cases, e.g. when to is in a nested module, that is not correct.)
+func sameModuleImport(fromMod, to string) bool {
+	return (fromMod == stdlib.ModulePath && stdlib.Contains(to)) || strings.HasPrefix(to+"/", fromMod+"/")
+}
+
+// UpdateSearchDocumentsImportedByCountIncremental is like
+// UpdateSearchDocumentsImportedByCount, but only recalculates the imported-by
+// counts of packages whose importers may have changed since the last call.
+// Those are recorded in the imports_unique_changelog table by triggers on
+// imports_unique and search_documents.
+//
+// Unlike UpdateSearchDocumentsImportedByCount, it sets the count of a package
+// whose last importer was removed to zero.
+//
+// UpdateSearchDocumentsImportedByCountIncremental returns the number of rows
+// updated.
+func (db *DB) UpdateSearchDocumentsImportedByCountIncremental(ctx context.Context) (nUpdated int64, err error) {
+	defer derrors.Wrap(&err, "UpdateSearchDocumentsImportedByCountIncremental(ctx)")
+
+	err = db.db.Transact(ctx, sql.LevelDefault, func(tx *database.DB) error {
+		var maxID sql.NullInt64
+		if err := tx.QueryRow(ctx, `SELECT MAX(id) FROM imports_unique_changelog`).Scan(&maxID); err != nil {
+			return err
+		}
+		if !maxID.Valid {
+			// Nothing has changed.
+			return nil
+		}
+		counts, commitTimes, err := computeChangedImportedByCounts(ctx, tx, maxID.Int64)
+		if err != nil {
+			return err
+		}
+		if err := insertImportedByCounts(ctx, tx, counts, db.decayImportedByCounts(counts, commitTimes)); err != nil {
+			return err
+		}
+		if err := compareImportedByCounts(ctx, tx); err != nil {
+			return err
+		}
+		nUpdated, err = updateImportedByCounts(ctx, tx)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `DELETE FROM imports_unique_changelog WHERE id <= $1`, maxID.Int64)
+		return err
+	})
+	return nUpdated, err
+}
+
+// computeChangedImportedByCounts computes the imported-by counts of the
+// packages imported by changelog entries with IDs up to maxID. Every such
+// package has an entry in the result, even if its count is zero. It also
+// returns the commit times of those packages that are in search_documents.
+func computeChangedImportedByCounts(ctx context.Context, db *database.DB, maxID int64) (counts map[string]int, commitTimes map[string]time.Time, err error) {
+	defer derrors.Wrap(&err, "computeChangedImportedByCounts(ctx, db, %d)", maxID)
+
+	counts = map[string]int{}
+	commitTimes = map[string]time.Time{}
+	err = db.RunQuery(ctx, `
+		SELECT DISTINCT c.to_path, s.commit_time
+		FROM imports_unique_changelog c
+		LEFT JOIN search_documents s ON s.package_path = c.to_path
+		WHERE c.id <= $1
+	`, func(rows *sql.Rows) error {
+		var (
+			to string
+			t  sql.NullTime
+		)
+		if err := rows.Scan(&to, &t); err != nil {
+			return err
+		}
+		counts[to] = 0
+		if t.Valid {
+			commitTimes[to] = t.Time
+		}
+		return nil
+	}, maxID)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Get all (from_path, to_path) pairs for the changed packages, deduped,
+	// where from_path is in search_documents. Also get the from_path's module
+	// path.
+	err = db.RunQuery(ctx, `
+		SELECT
+			i.from_module_path, i.to_path
+		FROM
+			imports_unique i
+		INNER JOIN
+			search_documents s
+		ON
+			i.from_path = s.package_path
+		WHERE
+			i.to_path IN (SELECT to_path FROM imports_unique_changelog WHERE id <= $1)
+		GROUP BY
+			i.from_path, i.from_module_path, i.to_path;
+	`, func(rows *sql.Rows) error {
+		var fromMod, to string
+		if err := rows.Scan(&fromMod, &to); err != nil {
+			return err
+		}
+		if !sameModuleImport(fromMod, to) {
+			counts[to]++
+		}
+		return nil
+	}, maxID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return counts, commitTimes, nil
+}
+
+// insertImportedByCounts inserts counts and the corresponding decayed counts
+// into a temporary computed_imported_by_counts table.
+func insertImportedByCounts(ctx context.Context, db *database.DB, counts map[string]int, decayed map[string]float64) (err error) {
+	defer derrors.Wrap(&err, "insertImportedByCounts(ctx, db, counts, decayed)")
 
 	const createTableQuery = `
 		CREATE TEMPORARY TABLE computed_imported_by_counts (
-			package_path      TEXT NOT NULL,
-			imported_by_count INTEGER DEFAULT 0 NOT NULL
+			package_path              TEXT NOT NULL,
+			imported_by_count         INTEGER DEFAULT 0 NOT NULL,
+			decayed_imported_by_count DOUBLE PRECISION DEFAULT 0 NOT NULL
 		) ON COMMIT DROP;
     `
 	if _, err := db.Exec(ctx, createTableQuery); err != nil {
 		return fmt.Errorf("CREATE TABLE: %v", err)
 	}
-	values := make([]interface{}, 0, 2*len(counts))
+	values := make([]interface{}, 0, 3*len(counts))
 	for p, c := range counts {
-		values = append(values, p, c)
+		values = append(values, p, c, decayed[p])
 	}
-	columns := []string{"package_path", "imported_by_count"}
+	columns := []string{"package_path", "imported_by_count", "decayed_imported_by_count"}
 	return db.BulkInsert(ctx, "computed_imported_by_counts", columns, values, "")
 }
 
@@ -794,8 +1980,9 @@ func compareImportedByCounts(ctx context.Context, db *database.DB) (err error) {
 	return nil
 }
 
-// updateImportedByCounts updates the imported_by_count column in search_documents
-// for every package in computed_imported_by_counts.
+// updateImportedByCounts updates the imported_by_count and
+// decayed_imported_by_count columns in search_documents for every package in
+// computed_imported_by_counts, and the points for the count in health_score.
 //
 // A row is updated even if the value doesn't change, so that the imported_by_count_updated_at
 // column is set.
@@ -803,13 +1990,16 @@ func compareImportedByCounts(ctx context.Context, db *database.DB) (err error) {
 // Note that if a package is never imported, its imported_by_count column will
 // be the default (0) and its imported_by_count_updated_at column will never be set.
 func updateImportedByCounts(ctx context.Context, db *database.DB) (int64, error) {
-	const updateStmt = `
+	updateStmt := fmt.Sprintf(`
 		UPDATE search_documents s
 		SET
 			imported_by_count = c.imported_by_count,
+			decayed_imported_by_count = c.decayed_imported_by_count,
+			health_score = LEAST(%d, GREATEST(0, s.health_score - %s + %s)),
 			imported_by_count_updated_at = CURRENT_TIMESTAMP
 		FROM computed_imported_by_counts c
-		WHERE s.package_path = c.package_path;`
+		WHERE s.package_path = c.package_path;`,
+		scoring.MaxHealthScore, importedByPointsSQL("s.imported_by_count"), importedByPointsSQL("c.imported_by_count"))
 
 	res, err := db.Exec(ctx, updateStmt)
 	if err != nil {
@@ -849,8 +2039,10 @@ var (
 // GeneratePathTokens returns the subPaths and path token parts that will be
 // indexed for search, which includes (1) the packagePath (2) all sub-paths of
 // the packagePath (3) all parts for a path element that is delimited by a dash
-// and (4) all parts of a path element that is delimited by a dot, except for
-// the last element.
+// (4) all parts of a path element that is delimited by a dot, except for
+// the last element, and (5) each run of CJK characters in a path element, and
+// each of the characters of the run, since CJK text is written without spaces
+// between words.
 func GeneratePathTokens(packagePath string) []string {
 	packagePath = strings.Trim(packagePath, "/")
 
@@ -886,6 +2078,9 @@ func GeneratePathTokens(packagePath string) []string {
 				}
 			}
 		}
+		for _, t := range cjkTokens(part) {
+			subPathSet[t] = true
+		}
 	}
 
 	var subPaths []string
@@ -898,6 +2093,109 @@ func GeneratePathTokens(packagePath string) []string {
 	return subPaths
 }
 
+// cjkScripts are the scripts of the characters that cjkTokens splits.
+var cjkScripts = []*unicode.RangeTable{unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul}
+
+// cjkTokens returns the runs of Chinese, Japanese and Korean characters in s,
+// and each character of the runs that are longer than one character.
+func cjkTokens(s string) []string {
+	var (
+		tokens []string
+		run    []rune
+	)
+	endRun := func() {
+		if len(run) > 1 {
+			for _, r := range run {
+				tokens = append(tokens, string(r))
+			}
+		}
+		if len(run) > 0 {
+			tokens = append(tokens, string(run))
+		}
+		run = run[:0]
+	}
+	for _, r := range s {
+		if unicode.IsLetter(r) && unicode.In(r, cjkScripts...) {
+			run = append(run, r)
+		} else {
+			endRun()
+		}
+	}
+	endRun()
+	return tokens
+}
+
+// minFuzzyTokenLen is the length of the shortest path token for which
+// FuzzyTokens generates variants. Shorter tokens have too many neighbors
+// for the variants to be useful.
+const minFuzzyTokenLen = 4
+
+// fuzzyAlphabet holds the characters that FuzzyTokens inserts and
+// substitutes when generating variants.
+const fuzzyAlphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+// FuzzyTokens returns the path tokens of packagePath, as generated by
+// GeneratePathTokens, together with all strings within Levenshtein distance
+// maxEdits of each token that is at least minFuzzyTokenLen bytes long.
+// Tokens containing a "/" are combinations of other tokens, and are not
+// varied. Inserted and substituted characters are taken from fuzzyAlphabet.
+//
+// The number of variants grows exponentially with maxEdits, so it should be
+// small.
+func FuzzyTokens(packagePath string, maxEdits int) []string {
+	// edits maps each token found so far to the largest number of edits
+	// that have been applied to it.
+	edits := make(map[string]int)
+	var vary func(tok string, n int)
+	vary = func(tok string, n int) {
+		if m, ok := edits[tok]; ok && m >= n {
+			return
+		}
+		edits[tok] = n
+		if n == 0 {
+			return
+		}
+		for _, v := range singleEdits(tok) {
+			vary(v, n-1)
+		}
+	}
+	for _, tok := range GeneratePathTokens(packagePath) {
+		if len(tok) < minFuzzyTokenLen || strings.Contains(tok, "/") {
+			vary(tok, 0)
+			continue
+		}
+		vary(tok, maxEdits)
+	}
+	var tokens []string
+	for tok := range edits {
+		if tok != "" {
+			tokens = append(tokens, tok)
+		}
+	}
+	sort.Strings(tokens)
+	return tokens
+}
+
+// singleEdits returns the strings at Levenshtein distance one from s: those
+// with one byte deleted, substituted or inserted.
+func singleEdits(s string) []string {
+	var out []string
+	for i := 0; i < len(s); i++ {
+		out = append(out, s[:i]+s[i+1:])
+		for _, c := range fuzzyAlphabet {
+			if byte(c) != s[i] {
+				out = append(out, s[:i]+string(c)+s[i+1:])
+			}
+		}
+	}
+	for i := 0; i <= len(s); i++ {
+		for _, c := range fuzzyAlphabet {
+			out = append(out, s[:i]+string(c)+s[i:])
+		}
+	}
+	return out
+}
+
 // isInternalPackage reports whether the path represents an internal directory.
 func isInternalPackage(path string) bool {
 	for _, p := range strings.Split(path, "/") {
@@ -914,9 +2212,19 @@ func isInternalPackage(path string) bool {
 func (db *DB) DeleteOlderVersionFromSearchDocuments(ctx context.Context, modulePath, version string) (err error) {
 	defer derrors.Wrap(&err, "DeleteOlderVersionFromSearchDocuments(ctx, %q, %q)", modulePath, version)
 
-	return db.db.Transact(ctx, sql.LevelDefault, func(tx *database.DB) error {
+	_, err = db.deleteVersionsFromSearchDocuments(ctx, modulePath, func(v string) bool {
+		return semver.Compare(v, version) < 0
+	})
+	return err
+}
+
+// deleteVersionsFromSearchDocuments deletes from search_documents every
+// package with the given module path whose version satisfies shouldDelete,
+// and returns the number of rows deleted.
+func (db *DB) deleteVersionsFromSearchDocuments(ctx context.Context, modulePath string, shouldDelete func(version string) bool) (n int64, err error) {
+	err = db.db.Transact(ctx, sql.LevelDefault, func(tx *database.DB) error {
 		// Collect all package paths in search_documents with the given module path
-		// and an older version. (package_path is the primary key of search_documents.)
+		// and a version to delete. (package_path is the primary key of search_documents.)
 		var ppaths []string
 		query := `
 			SELECT package_path, version
@@ -928,7 +2236,7 @@ func (db *DB) DeleteOlderVersionFromSearchDocuments(ctx context.Context, moduleP
 			if err := rows.Scan(&ppath, &v); err != nil {
 				return err
 			}
-			if semver.Compare(v, version) < 0 {
+			if shouldDelete(v) {
 				ppaths = append(ppaths, ppath)
 			}
 			return nil
@@ -941,16 +2249,67 @@ func (db *DB) DeleteOlderVersionFromSearchDocuments(ctx context.Context, moduleP
 		}
 
 		// Delete all of those paths.
-		q := fmt.Sprintf(`DELETE FROM search_documents WHERE package_path IN ('%s')`, strings.Join(ppaths, `', '`))
-		res, err := tx.Exec(ctx, q)
+		res, err := tx.Exec(ctx, `DELETE FROM search_documents WHERE package_path = ANY($1)`, pq.Array(ppaths))
 		if err != nil {
 			return err
 		}
-		n, err := res.RowsAffected()
+		n, err = res.RowsAffected()
 		if err != nil {
 			return fmt.Errorf("RowsAffected: %v", err)
 		}
 		log.Infof(ctx, "deleted %d rows from search_documents", n)
 		return nil
 	})
+	return n, err
+}
+
+// DeleteOlderVersionsFromSearchDocuments finds every module path that has
+// packages at more than one version in search_documents, and deletes the
+// packages that are not at the latest of those versions, including those at
+// later prereleases. That can happen when a package is removed from a module,
+// or when the module acquires an alternative path.
+//
+// DeleteOlderVersionsFromSearchDocuments returns the number of rows deleted.
+func (db *DB) DeleteOlderVersionsFromSearchDocuments(ctx context.Context) (nDeleted int64, err error) {
+	defer derrors.Wrap(&err, "DeleteOlderVersionsFromSearchDocuments(ctx)")
+
+	// The highest version is chosen like isLatestVersion does, so that a
+	// release is preferred to a later prerelease.
+	query := `
+		SELECT DISTINCT ON (m.module_path) m.module_path, m.version
+		FROM modules m
+		INNER JOIN (
+			SELECT DISTINCT module_path, version FROM search_documents
+		) s
+		ON m.module_path = s.module_path AND m.version = s.version
+		WHERE m.module_path IN (
+			SELECT module_path
+			FROM search_documents
+			GROUP BY module_path
+			HAVING COUNT(DISTINCT version) > 1
+		)
+		ORDER BY m.module_path, m.version_type = 'release' DESC, m.sort_version DESC`
+	latest := map[string]string{}
+	err = db.db.RunQuery(ctx, query, func(rows *sql.Rows) error {
+		var modulePath, version string
+		if err := rows.Scan(&modulePath, &version); err != nil {
+			return err
+		}
+		latest[modulePath] = version
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	for modulePath, version := range latest {
+		version := version
+		n, err := db.deleteVersionsFromSearchDocuments(ctx, modulePath, func(v string) bool {
+			return v != version
+		})
+		if err != nil {
+			return nDeleted, err
+		}
+		nDeleted += n
+	}
+	return nDeleted, nil
 }
@@ -7,13 +7,17 @@ package postgres
 import (
 	"context"
 	"crypto/md5"
+	"database/sql"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"math"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -22,7 +26,11 @@ import (
 	"github.com/lib/pq"
 	"go.opencensus.io/stats/view"
 	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/database"
 	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/licenses"
+	"golang.org/x/pkgsite/internal/scoring"
+	"golang.org/x/pkgsite/internal/search"
 	"golang.org/x/pkgsite/internal/testing/sample"
 )
 
@@ -139,6 +147,67 @@ func TestPathTokens(t *testing.T) {
 			path: "/",
 			want: nil,
 		},
+		{
+			// Runs of CJK characters are split into characters.
+			path: "example.com/中文/工具",
+			want: []string{
+				"example",
+				"example.com",
+				"example.com/中文",
+				"example.com/中文/工具",
+				"中",
+				"中文",
+				"中文/工具",
+				"具",
+				"工",
+				"工具",
+				"文",
+			},
+		},
+		{
+			path: "example.com/ひらがな-カタカナ",
+			want: []string{
+				"example",
+				"example.com",
+				"example.com/ひらがな-カタカナ",
+				"が",
+				"な",
+				"ひ",
+				"ひらがな",
+				"ひらがな-カタカナ",
+				"ら",
+				"カ",
+				"カタカナ",
+				"タ",
+				"ナ",
+			},
+		},
+		{
+			path: "example.com/한국어",
+			want: []string{
+				"example",
+				"example.com",
+				"example.com/한국어",
+				"국",
+				"어",
+				"한",
+				"한국어",
+			},
+		},
+		{
+			// Only the CJK part of a mixed element is split.
+			path: "github.com/foo/go日本",
+			want: []string{
+				"foo",
+				"foo/go日本",
+				"github.com/foo",
+				"github.com/foo/go日本",
+				"go日本",
+				"日",
+				"日本",
+				"本",
+			},
+		},
 	} {
 		t.Run(tc.path, func(t *testing.T) {
 			got := GeneratePathTokens(tc.path)
@@ -149,6 +218,119 @@ func TestPathTokens(t *testing.T) {
 	}
 }
 
+// pathTokensBenchmarks are the paths of BenchmarkGeneratePathTokens, which
+// have the given number of segments.
+//
+// Performance budget: on a server-class x86-64 machine, GeneratePathTokens
+// should take less than 5µs per call for 5 segments, and less than 25µs and
+// 100 allocations for 10 segments. The number of tokens grows quadratically
+// with the number of segments, so the long path, with 40 segments, guards
+// against worse than quadratic time: it should take less than 250µs.
+var pathTokensBenchmarks = []struct {
+	name, path string
+}{
+	{"short", "github.com/foo/bar/baz/qux"},
+	{"medium", "golang.org/x/tools/go/analysis/passes/buildtag/testdata/src/a"},
+	// The pathological cases of TestPathTokens.
+	{"slashes", "/example.com/foo-bar///package///"},
+	{"dashes", "cloud.google.com/go/cmd/go-cloud-debug-agent/internal/valuecollector"},
+	{"dots", "code.cloud.gitlab.google.k8s.io"},
+	{"long", "example.com/" + strings.Repeat("a-b.c/", 39)},
+}
+
+func BenchmarkGeneratePathTokens(b *testing.B) {
+	for _, bm := range pathTokensBenchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				GeneratePathTokens(bm.path)
+			}
+		})
+	}
+}
+
+// BenchmarkGeneratePathTokensAlloc reports the heap allocations of
+// GeneratePathTokens for a 10-segment path, even without -benchmem.
+func BenchmarkGeneratePathTokensAlloc(b *testing.B) {
+	const path = "golang.org/x/tools/go/analysis/passes/buildtag/testdata/src/a"
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		GeneratePathTokens(path)
+	}
+}
+
+func TestFuzzyTokens(t *testing.T) {
+	got := FuzzyTokens("github.com/google/uuid", 1)
+	has := make(map[string]bool)
+	for _, tok := range got {
+		has[tok] = true
+	}
+	for _, tok := range []string{
+		"google", "uuid", "github.com/google", // the path tokens
+		"gogle", "googl", "goozle", "googlle", // one edit from "google"
+		"uid", "uuids", // one edit from "uuid"
+	} {
+		if !has[tok] {
+			t.Errorf("FuzzyTokens: missing %q", tok)
+		}
+	}
+	for _, tok := range []string{
+		"gogel",    // three edits from "google"
+		"uid/uuid", // tokens with a "/" are not varied
+		"github.com/gogle",
+	} {
+		if has[tok] {
+			t.Errorf("FuzzyTokens: unexpected %q", tok)
+		}
+	}
+
+	// Tokens shorter than minFuzzyTokenLen are not varied.
+	if diff := cmp.Diff(GeneratePathTokens("ab/xyz"), FuzzyTokens("ab/xyz", 2)); diff != "" {
+		t.Errorf("FuzzyTokens(%q) mismatch (-want +got):\n%s", "ab/xyz", diff)
+	}
+	if diff := cmp.Diff(GeneratePathTokens("github.com/google/uuid"), FuzzyTokens("github.com/google/uuid", 0)); diff != "" {
+		t.Errorf("FuzzyTokens with no edits mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestFuzzySearch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	for _, path := range []string{
+		"github.com/google/uuid",
+		"github.com/gofrs/uuid",
+		"github.com/google/go-cmp",
+		"github.com/satori/go.uuid",
+	} {
+		if err := testDB.InsertModule(ctx, sample.Module(path, sample.VersionString, "")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testDB.FuzzySearchEnabled = true
+	defer func() { testDB.FuzzySearchEnabled = false }()
+
+	for _, q := range []string{"gogle/uuid", "googl/uuid", "goolge/uuid", "google/uid"} {
+		t.Run(q, func(t *testing.T) {
+			results, err := testDB.Search(ctx, q, SearchOptions{Limit: 3})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range results {
+				if r.PackagePath == "github.com/google/uuid" {
+					return
+				}
+			}
+			var got []string
+			for _, r := range results {
+				got = append(got, r.PackagePath)
+			}
+			t.Errorf("Search(%q) = %v, want github.com/google/uuid in the top 3", q, got)
+		})
+	}
+}
+
 // importGraph constructs a simple import graph where all importers import
 // one popular package.  For performance purposes, all importers are added to
 // a single importing module.
@@ -209,13 +391,15 @@ func resultGuard(resultOrder []string) func(string) func() {
 }
 
 func TestSearch(t *testing.T) {
+	db := NewTestDB(t)
 	tests := []struct {
-		label       string
-		modules     []*internal.Module
-		resultOrder []string
-		wantSource  string
-		wantResults []string
-		wantTotal   uint64
+		label         string
+		modules       []*internal.Module
+		minImportedBy int
+		resultOrder   []string
+		wantSource    string
+		wantResults   []string
+		wantTotal     uint64
 	}{
 		{
 			label:       "single package",
@@ -258,13 +442,30 @@ func TestSearch(t *testing.T) {
 			wantResults: []string{"foo.com/popularB", "foo.com/popularA"},
 			wantTotal:   72,
 		},
+		{
+			label:         "minimum imported by count",
+			modules:       append(importGraph("foo.com/popular", "bar.com/foo", 10), importGraph("foo.com/unpopular", "", 0)...),
+			minImportedBy: 1,
+			resultOrder:   []string{"deep", "estimate", "popular"},
+			wantSource:    "deep",
+			wantResults:   []string{"foo.com/popular"},
+			wantTotal:     1,
+		},
 		// Adding a test for *very* popular results requires ~300 importers
 		// minimum, which is pretty slow to set up at the moment (~5 seconds), and
 		// doesn't add much additional value.
 	}
 
-	view.Register(SearchResponseCount)
-	defer view.Unregister(SearchResponseCount)
+	latencyViews := []*view.View{SearchLatencyPopular, SearchLatencyDeep, SearchLatencyEstimate}
+	if err := view.Register(append(latencyViews, SearchResponseCount)...); err != nil {
+		t.Fatal(err)
+	}
+	defer view.Unregister(append(latencyViews, SearchResponseCount)...)
+	for _, v := range latencyViews {
+		if view.Find(v.Name) == nil {
+			t.Fatalf("view %q is not registered", v.Name)
+		}
+	}
 	responses := make(map[string]int64)
 	// responseDelta captures the change in the SearchResponseCount metric.
 	responseDelta := func() map[string]int64 {
@@ -287,19 +488,23 @@ func TestSearch(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.label, func(t *testing.T) {
-			defer ResetTestDB(testDB, t)
+			defer ResetTestDB(db, t)
 			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 			defer cancel()
 			for _, v := range test.modules {
-				if err := testDB.InsertModule(ctx, v); err != nil {
+				if err := db.InsertModule(ctx, v); err != nil {
 					t.Fatal(err)
 				}
 			}
-			if _, err := testDB.UpdateSearchDocumentsImportedByCount(ctx); err != nil {
+			if _, err := db.UpdateSearchDocumentsImportedByCount(ctx); err != nil {
 				t.Fatal(err)
 			}
 			guardTestResult := resultGuard(test.resultOrder)
-			resp, err := testDB.hedgedSearch(ctx, "foo", 2, 0, searchers, guardTestResult)
+			sp := searchParams{q: "foo", limit: 2}
+			if test.minImportedBy > 0 {
+				sp.filter = searchFilterSQL([]search.FieldFilter{minImportedByFilter(test.minImportedBy)})
+			}
+			resp, err := db.hedgedSearch(ctx, sp, searchers, guardTestResult)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -324,6 +529,229 @@ func TestSearch(t *testing.T) {
 			}
 		})
 	}
+
+	// Every search has at least one successful query, whose latency is
+	// recorded.
+	var latencyCount int64
+	for _, v := range latencyViews {
+		rows, err := view.RetrieveData(v.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, row := range rows {
+			latencyCount += row.Data.(*view.DistributionData).Count
+		}
+	}
+	if latencyCount == 0 {
+		t.Error("no search latency was recorded")
+	}
+}
+
+func TestSharedSearch(t *testing.T) {
+	defer ResetTestDB(testDB, t)
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	for _, m := range importGraph("foo.com/A", "", 0) {
+		if err := testDB.InsertModule(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := view.Register(SearchCoalescedCount); err != nil {
+		t.Fatal(err)
+	}
+	defer view.Unregister(SearchCoalescedCount)
+
+	// The searcher counts its calls, and blocks until all the searches have
+	// started.
+	var calls int32
+	release := make(chan struct{})
+	ss := map[string]searcher{
+		"deep": func(db *DB, ctx context.Context, sp searchParams) searchResponse {
+			atomic.AddInt32(&calls, 1)
+			<-release
+			return db.deepSearch(ctx, sp)
+		},
+	}
+
+	const n = 5
+	var (
+		wg      sync.WaitGroup
+		results = make([][]*internal.SearchResult, n)
+		errs    = make([]error, n)
+	)
+	sp := searchParams{q: "foo", limit: 10}
+	for i := 0; i < n; i++ {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := testDB.sharedSearch(ctx, sp, ss)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			results[i] = resp.results
+		}()
+	}
+	// Give the searches time to wait for the first one.
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("got %d searcher calls, want 1", got)
+	}
+	for i := 0; i < n; i++ {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		if len(results[i]) == 0 {
+			t.Fatalf("search %d returned no results", i)
+		}
+		if diff := cmp.Diff(results[0], results[i]); diff != "" {
+			t.Errorf("search %d mismatch (-first +got):\n%s", i, diff)
+		}
+		for j := 0; j < i; j++ {
+			if results[i][0] == results[j][0] {
+				t.Errorf("searches %d and %d share result %p", i, j, results[i][0])
+			}
+		}
+	}
+
+	rows, err := view.RetrieveData(SearchCoalescedCount.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var coalesced int64
+	for _, row := range rows {
+		coalesced += row.Data.(*view.CountData).Value
+	}
+	if coalesced != n-1 {
+		t.Errorf("got %d coalesced searches, want %d", coalesced, n-1)
+	}
+}
+
+func TestSearchNormalizesQuery(t *testing.T) {
+	defer ResetTestDB(testDB, t)
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	for _, m := range importGraph("foo.com/A", "", 0) {
+		if err := testDB.InsertModule(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := testDB.Search(ctx, "foo", SearchOptions{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(want) == 0 {
+		t.Fatal("got no results")
+	}
+	for _, q := range []string{"  FOO ", "Foo%20"} {
+		got, err := testDB.Search(ctx, q, SearchOptions{Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Search(%q) mismatch (-want +got):\n%s", q, diff)
+		}
+	}
+	// The query is only decoded once, to "%46oo".
+	got, err := testDB.Search(ctx, "%2546oo", SearchOptions{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("Search(%q): got %d results, want none", "%2546oo", len(got))
+	}
+}
+
+func TestSearchPathFilterKeepsCase(t *testing.T) {
+	defer ResetTestDB(testDB, t)
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	const modulePath = "github.com/BurntSushi/toml"
+	if err := testDB.InsertModule(ctx, sample.Module(modulePath, "v1.0.0", "")); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		q    string
+		want []string
+	}{
+		{"TOML path:github.com/BurntSushi/toml", []string{modulePath}},
+		{"path:github.com/BurntSushi", []string{modulePath}},
+		{"toml path:github.com/burntsushi/toml", nil},
+	} {
+		rs, err := testDB.Search(ctx, test.q, SearchOptions{Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, r := range rs {
+			got = append(got, r.PackagePath)
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("Search(%q) mismatch (-want +got):\n%s", test.q, diff)
+		}
+	}
+}
+
+func TestSharedSearchFirstCallerCanceled(t *testing.T) {
+	defer ResetTestDB(testDB, t)
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	for _, m := range importGraph("foo.com/A", "", 0) {
+		if err := testDB.InsertModule(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	ss := map[string]searcher{
+		"deep": func(db *DB, ctx context.Context, sp searchParams) searchResponse {
+			close(started)
+			<-release
+			return db.deepSearch(ctx, sp)
+		},
+	}
+	sp := searchParams{q: "foo", limit: 10}
+
+	// The first caller starts the search, and goes away while it runs.
+	firstCtx, firstCancel := context.WithCancel(ctx)
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := testDB.sharedSearch(firstCtx, sp, ss)
+		firstErr <- err
+	}()
+	<-started
+	secondResp := make(chan *searchResponse, 1)
+	secondErr := make(chan error, 1)
+	go func() {
+		resp, err := testDB.sharedSearch(ctx, sp, ss)
+		secondResp <- resp
+		secondErr <- err
+	}()
+	// Give the second search time to wait for the first one.
+	time.Sleep(100 * time.Millisecond)
+	firstCancel()
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("first search: got error %v, want context.Canceled", err)
+	}
+	close(release)
+
+	resp := <-secondResp
+	if err := <-secondErr; err != nil {
+		t.Fatalf("second search: %v", err)
+	}
+	if len(resp.results) == 0 {
+		t.Error("second search returned no results")
+	}
 }
 
 func TestSearchErrors(t *testing.T) {
@@ -334,7 +762,7 @@ func TestSearchErrors(t *testing.T) {
 		for name, search := range searchers {
 			if name == searcherName {
 				name := name
-				newSearchers[name] = func(*DB, context.Context, string, int, int) searchResponse {
+				newSearchers[name] = func(*DB, context.Context, searchParams) searchResponse {
 					return searchResponse{
 						source: name,
 						err:    errors.New("bad"),
@@ -394,7 +822,7 @@ func TestSearchErrors(t *testing.T) {
 				t.Fatal(err)
 			}
 			guardTestResult := resultGuard(test.resultOrder)
-			resp, err := testDB.hedgedSearch(ctx, "foo", 2, 0, test.searchers, guardTestResult)
+			resp, err := testDB.hedgedSearch(ctx, searchParams{q: "foo", limit: 2}, test.searchers, guardTestResult)
 			if (err != nil) != test.wantErr {
 				t.Fatalf("hedgedSearch(): got error %v, want error: %t", err, test.wantErr)
 			}
@@ -408,70 +836,231 @@ func TestSearchErrors(t *testing.T) {
 	}
 }
 
-func TestInsertSearchDocumentAndSearch(t *testing.T) {
-	var (
-		modGoCDK = "gocloud.dev"
-		pkgGoCDK = &internal.LegacyPackage{
-			Name:              "cloud",
-			Path:              "gocloud.dev/cloud",
-			Synopsis:          "Package cloud contains a library and tools for open cloud development in Go. The Go Cloud Development Kit (Go CDK)",
-			IsRedistributable: true, // required because some test cases depend on the README contents
-		}
+func TestParallelSearch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
 
-		modKube = "k8s.io"
-		pkgKube = &internal.LegacyPackage{
-			Name:              "client-go",
-			Path:              "k8s.io/client-go",
-			Synopsis:          "Package client-go implements a Go client for Kubernetes.",
-			IsRedistributable: true, // required because some test cases depend on the README contents
+	for _, m := range importGraph("foo.com/popular", "bar.com/foo", 3) {
+		if err := testDB.InsertModule(ctx, m); err != nil {
+			t.Fatal(err)
 		}
+	}
+	if _, err := testDB.UpdateSearchDocumentsImportedByCount(ctx); err != nil {
+		t.Fatal(err)
+	}
 
-		kubeResult = func(score float64, numResults uint64) *internal.SearchResult {
-			return &internal.SearchResult{
-				Name:        pkgKube.Name,
-				PackagePath: pkgKube.Path,
-				Synopsis:    pkgKube.Synopsis,
-				Licenses:    []string{"MIT"},
-				CommitTime:  sample.CommitTime,
-				Version:     sample.VersionString,
-				ModulePath:  modKube,
-				Score:       score,
-				NumResults:  numResults,
-			}
+	queries := []string{"popular", "bar", "foo"}
+	got, err := testDB.ParallelSearch(ctx, queries, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(queries) {
+		t.Fatalf("got %d result lists, want %d", len(got), len(queries))
+	}
+	paths := func(rs []*internal.SearchResult) []string {
+		var ps []string
+		for _, r := range rs {
+			ps = append(ps, r.PackagePath)
 		}
-
-		goCdkResult = func(score float64, numResults uint64) *internal.SearchResult {
-			return &internal.SearchResult{
-				Name:        pkgGoCDK.Name,
-				PackagePath: pkgGoCDK.Path,
-				Synopsis:    pkgGoCDK.Synopsis,
-				Licenses:    []string{"MIT"},
-				CommitTime:  sample.CommitTime,
-				Version:     sample.VersionString,
-				ModulePath:  modGoCDK,
-				Score:       score,
-				NumResults:  numResults,
-			}
+		return ps
+	}
+	for i, want := range [][]string{
+		{"foo.com/popular"},
+		{"bar.com/foo/importer0", "bar.com/foo/importer1", "bar.com/foo/importer2"},
+		// The popular package scores highest.
+		{"foo.com/popular", "bar.com/foo/importer0", "bar.com/foo/importer1", "bar.com/foo/importer2"},
+	} {
+		g := paths(got[i])
+		if i == 1 {
+			// The importers are equally popular and relevant.
+			sort.Strings(g)
 		}
-	)
+		if diff := cmp.Diff(want, g); diff != "" {
+			t.Errorf("%q: mismatch (-want +got):\n%s", queries[i], diff)
+		}
+	}
 
-	const (
-		packageScore  = 0.6079270839691162
-		goAndCDKScore = 0.999817967414856
-		cloudScore    = 0.8654518127441406
-	)
+	// An error in one search fails the whole.
+	if _, err := testDB.ParallelSearch(ctx, []string{"foo", "imported:lots"}, 10); !errors.Is(err, derrors.InvalidArgument) {
+		t.Errorf("got error %v, want InvalidArgument", err)
+	}
+}
 
-	for _, tc := range []struct {
-		name          string
-		packages      map[string]*internal.LegacyPackage
-		limit, offset int
-		searchQuery   string
-		want          []*internal.SearchResult
-	}{
-		{
-			name:        "two documents, single term search",
-			searchQuery: "package",
-			packages: map[string]*internal.LegacyPackage{
+func TestSearchSynonyms(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+	defer func() { testDB.Synonyms = nil }()
+
+	synonyms, err := search.LoadSynonyms("../search/testdata/synonyms.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := sample.Module("example.com/cluster", sample.VersionString, "client")
+	m.LegacyPackages[0].Synopsis = "Package client talks to Kubernetes clusters."
+	if err := testDB.InsertModule(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+	searchK8s := func() []string {
+		t.Helper()
+		rs, err := testDB.Search(ctx, "k8s", SearchOptions{Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var paths []string
+		for _, r := range rs {
+			paths = append(paths, r.PackagePath)
+		}
+		return paths
+	}
+	if got := searchK8s(); len(got) != 0 {
+		t.Errorf("without synonyms: got %v, want no results", got)
+	}
+	testDB.Synonyms = synonyms
+	if diff := cmp.Diff([]string{"example.com/cluster/client"}, searchK8s()); diff != "" {
+		t.Errorf("with synonyms: mismatch (-want +got):\n%s", diff)
+	}
+	// The facets count the packages matched by the expanded query too.
+	rs, err := testDB.Search(ctx, "k8s", SearchOptions{Limit: 10, IncludeFacets: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 1 {
+		t.Fatalf("with facets: got %d results, want 1", len(rs))
+	}
+	if diff := cmp.Diff(map[string]uint64{"MIT": 1}, rs[0].FacetCounts); diff != "" {
+		t.Errorf("with facets: FacetCounts mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSearchCJKCharacter(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	for _, m := range []*internal.Module{
+		sample.Module("example.com/cjk", sample.VersionString, "中文"),
+		sample.Module("example.com/other", sample.VersionString, "英语"),
+	} {
+		if err := testDB.InsertModule(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for method, searcher := range searchers {
+		t.Run(method, func(t *testing.T) {
+			res := searcher(testDB, ctx, searchParams{q: "文", limit: 10})
+			if res.err != nil {
+				t.Fatal(res.err)
+			}
+			var got []string
+			for _, r := range res.results {
+				got = append(got, r.PackagePath)
+			}
+			if diff := cmp.Diff([]string{"example.com/cjk/中文"}, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// TestSearchOneResultPerPackage checks that a package with several versions
+// appears once in search results, at its latest version: search_documents
+// has a single row per package path, which is updated to the latest version
+// as versions are inserted.
+func TestSearchOneResultPerPackage(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	for _, v := range []string{"v1.0.0", "v1.2.0", "v1.1.0"} {
+		if err := testDB.InsertModule(ctx, sample.Module("json.example.com", v, "json")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resp, err := testDB.hedgedSearch(ctx, searchParams{q: "json", limit: 10}, searchers, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range resp.results {
+		got = append(got, r.PackagePath+"@"+r.Version)
+	}
+	want := []string{"json.example.com/json@v1.2.0"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestInsertSearchDocumentAndSearch(t *testing.T) {
+	t.Parallel()
+	db := NewTestDB(t)
+	var (
+		modGoCDK = "gocloud.dev"
+		pkgGoCDK = &internal.LegacyPackage{
+			Name:              "cloud",
+			Path:              "gocloud.dev/cloud",
+			Synopsis:          "Package cloud contains a library and tools for open cloud development in Go. The Go Cloud Development Kit (Go CDK)",
+			IsRedistributable: true, // required because some test cases depend on the README contents
+		}
+
+		modKube = "k8s.io"
+		pkgKube = &internal.LegacyPackage{
+			Name:              "client-go",
+			Path:              "k8s.io/client-go",
+			Synopsis:          "Package client-go implements a Go client for Kubernetes.",
+			IsRedistributable: true, // required because some test cases depend on the README contents
+		}
+
+		kubeResult = func(score float64, numResults uint64) *internal.SearchResult {
+			return &internal.SearchResult{
+				Name:        pkgKube.Name,
+				PackagePath: pkgKube.Path,
+				Synopsis:    pkgKube.Synopsis,
+				Licenses:    []string{"MIT"},
+				CommitTime:  sample.CommitTime,
+				Version:     sample.VersionString,
+				ModulePath:  modKube,
+				Score:       score,
+				NumResults:  numResults,
+			}
+		}
+
+		goCdkResult = func(score float64, numResults uint64) *internal.SearchResult {
+			return &internal.SearchResult{
+				Name:        pkgGoCDK.Name,
+				PackagePath: pkgGoCDK.Path,
+				Synopsis:    pkgGoCDK.Synopsis,
+				Licenses:    []string{"MIT"},
+				CommitTime:  sample.CommitTime,
+				Version:     sample.VersionString,
+				ModulePath:  modGoCDK,
+				Score:       score,
+				NumResults:  numResults,
+			}
+		}
+	)
+
+	// The sample modules have a go.mod file and a README, and are
+	// redistributable, but have no tests and no importers.
+	healthFactor := 1 - healthScorePenalty*(1-scoring.ComputeHealthScore(sample.Module(modKube, sample.VersionString), 0)/scoring.MaxHealthScore)
+	var (
+		packageScore  = 0.6079270839691162 * healthFactor
+		goAndCDKScore = 0.999817967414856 * healthFactor
+		cloudScore    = 0.8654518127441406 * healthFactor
+	)
+
+	for _, tc := range []struct {
+		name          string
+		packages      map[string]*internal.LegacyPackage
+		limit, offset int
+		searchQuery   string
+		want          []*internal.SearchResult
+	}{
+		{
+			name:        "two documents, single term search",
+			searchQuery: "package",
+			packages: map[string]*internal.LegacyPackage{
 				modGoCDK: pkgGoCDK,
 				modKube:  pkgKube,
 			},
@@ -530,7 +1119,7 @@ func TestInsertSearchDocumentAndSearch(t *testing.T) {
 	} {
 		for method, searcher := range searchers {
 			t.Run(tc.name+":"+method, func(t *testing.T) {
-				defer ResetTestDB(testDB, t)
+				defer ResetTestDB(db, t)
 
 				ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
 				defer cancel()
@@ -539,7 +1128,7 @@ func TestInsertSearchDocumentAndSearch(t *testing.T) {
 					pkg.Licenses = sample.LicenseMetadata
 					m := sample.Module(modulePath, sample.VersionString)
 					sample.AddPackage(m, pkg)
-					if err := testDB.InsertModule(ctx, m); err != nil {
+					if err := db.InsertModule(ctx, m); err != nil {
 						t.Fatal(err)
 					}
 				}
@@ -548,107 +1137,898 @@ func TestInsertSearchDocumentAndSearch(t *testing.T) {
 					tc.limit = 10
 				}
 
-				got := searcher(testDB, ctx, tc.searchQuery, tc.limit, tc.offset)
+				got := searcher(db, ctx, searchParams{q: tc.searchQuery, limit: tc.limit, offset: tc.offset})
 				if got.err != nil {
 					t.Fatal(got.err)
 				}
 				// Normally done by hedgedSearch, but we're bypassing that.
-				if err := testDB.addPackageDataToSearchResults(ctx, got.results); err != nil {
+				if err := db.addPackageDataToSearchResults(ctx, got.results); err != nil {
 					t.Fatal(err)
 				}
 				if len(got.results) != len(tc.want) {
-					t.Errorf("testDB.Search(%v, %d, %d) mismatch: len(got) = %d, want = %d\n", tc.searchQuery, tc.limit, tc.offset, len(got.results), len(tc.want))
+					t.Errorf("db.Search(%v, %d, %d) mismatch: len(got) = %d, want = %d\n", tc.searchQuery, tc.limit, tc.offset, len(got.results), len(tc.want))
+				}
+
+				// The searchers differ in these two fields.
+				opt := cmpopts.IgnoreFields(internal.SearchResult{}, "Approximate", "NumResults")
+				if diff := cmp.Diff(tc.want, got.results, opt, cmpopts.EquateApprox(0, 1e-6)); diff != "" {
+					t.Errorf("db.Search(%v, %d, %d) mismatch (-want +got):\n%s", tc.searchQuery, tc.limit, tc.offset, diff)
+				}
+			})
+		}
+	}
+
+	// A match in the synopsis ranks above the same match in the README.
+	for method, searcher := range searchers {
+		t.Run("synopsis above readme:"+method, func(t *testing.T) {
+			defer ResetTestDB(db, t)
+
+			ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+			defer cancel()
+
+			for _, m := range []struct {
+				path, synopsis, readme string
+			}{
+				{"readme.com/widget", "Package widget is a widget.", "This module frobnicates."},
+				{"synopsis.com/widget", "Package widget frobnicates.", "This module is a widget."},
+			} {
+				mod := sample.Module(m.path, sample.VersionString, "")
+				mod.LegacyReadmeContents = m.readme
+				mod.LegacyPackages[0].Synopsis = m.synopsis
+				if err := db.InsertModule(ctx, mod); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got := searcher(db, ctx, searchParams{q: "frobnicates", limit: 10})
+			if got.err != nil {
+				t.Fatal(got.err)
+			}
+			var paths []string
+			for _, r := range got.results {
+				paths = append(paths, r.PackagePath)
+			}
+			if diff := cmp.Diff([]string{"synopsis.com/widget", "readme.com/widget"}, paths); diff != "" {
+				t.Fatalf("result mismatch (-want +got):\n%s", diff)
+			}
+			if got.results[0].Score <= got.results[1].Score {
+				t.Errorf("synopsis match score %f <= README match score %f", got.results[0].Score, got.results[1].Score)
+			}
+		})
+	}
+}
+
+func TestSearchHealthScore(t *testing.T) {
+	// Verify that of two modules that differ only in whether they have tests,
+	// the one with tests has a higher health score and ranks first.
+	defer ResetTestDB(testDB, t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	const (
+		tested   = "tested.com/foo"
+		untested = "untested.com/foo"
+	)
+	for _, path := range []string{tested, untested} {
+		m := sample.Module(path, sample.VersionString, "p")
+		m.Files = []*internal.FileInfo{{Name: "go.mod"}, {Name: "p/p.go"}}
+		if path == tested {
+			m.Files = append(m.Files, &internal.FileInfo{Name: "p/p_test.go"})
+		}
+		if err := testDB.InsertModule(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	healthScore := func(pkgPath string) float64 {
+		t.Helper()
+		var score float64
+		if err := testDB.db.QueryRow(ctx, `SELECT health_score FROM search_documents WHERE package_path = $1`,
+			pkgPath).Scan(&score); err != nil {
+			t.Fatal(err)
+		}
+		return score
+	}
+	testedScore, untestedScore := healthScore(tested+"/p"), healthScore(untested+"/p")
+	if got, want := testedScore-untestedScore, float64(scoring.TestsPoints); got != want {
+		t.Errorf("health score difference = %f - %f = %f, want %f", testedScore, untestedScore, got, want)
+	}
+
+	for method, searcher := range searchers {
+		t.Run(method, func(t *testing.T) {
+			res := searcher(testDB, ctx, searchParams{q: "foo", limit: 10})
+			if res.err != nil {
+				t.Fatal(res.err)
+			}
+			if len(res.results) != 2 {
+				t.Fatalf("got %d search results, want 2", len(res.results))
+			}
+			if got := res.results[0].ModulePath; got != tested {
+				t.Errorf("first result is %q, want %q", got, tested)
+			}
+			factor := func(h float64) float64 { return 1 - healthScorePenalty*(1-h/scoring.MaxHealthScore) }
+			got := res.results[1].Score / res.results[0].Score
+			want := factor(untestedScore) / factor(testedScore)
+			if math.Abs(got-want) > 1e-6 {
+				t.Errorf("score ratio = %f, want %f", got, want)
+			}
+		})
+	}
+}
+
+func TestSearchHasTests(t *testing.T) {
+	defer ResetTestDB(testDB, t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	const modulePath = "tests.com/foo"
+	m := sample.Module(modulePath, sample.VersionString, "tested", "untested")
+	m.Files = []*internal.FileInfo{
+		{Name: "go.mod"},
+		{Name: "tested/a.go"},
+		{Name: "tested/a_test.go"},
+		{Name: "untested/b.go"},
+		// A test file in a subdirectory belongs to another package.
+		{Name: "untested/sub/c_test.go"},
+	}
+	if err := testDB.InsertModule(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]bool{
+		modulePath + "/tested":   true,
+		modulePath + "/untested": false,
+	}
+	for pkgPath, w := range want {
+		var got bool
+		if err := testDB.db.QueryRow(ctx, `SELECT has_tests FROM search_documents WHERE package_path = $1`,
+			pkgPath).Scan(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got != w {
+			t.Errorf("has_tests for %q = %t, want %t", pkgPath, got, w)
+		}
+		pkg, err := testDB.LegacyGetPackage(ctx, pkgPath, modulePath, sample.VersionString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pkg.Facts.HasTests == nil || *pkg.Facts.HasTests != w {
+			t.Errorf("LegacyGetPackage(%q).Facts.HasTests = %v, want %t", pkgPath, pkg.Facts.HasTests, w)
+		}
+	}
+
+	for method, searcher := range searchers {
+		t.Run(method, func(t *testing.T) {
+			res := searcher(testDB, ctx, searchParams{q: "foo", limit: 10})
+			if res.err != nil {
+				t.Fatal(res.err)
+			}
+			if len(res.results) != len(want) {
+				t.Fatalf("got %d search results, want %d", len(res.results), len(want))
+			}
+			for _, r := range res.results {
+				if r.HasTests != want[r.PackagePath] {
+					t.Errorf("HasTests for %q = %t, want %t", r.PackagePath, r.HasTests, want[r.PackagePath])
+				}
+			}
+		})
+	}
+}
+
+func TestSearchRequiresCgo(t *testing.T) {
+	defer ResetTestDB(testDB, t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	cgo := sample.Module("cgo.com/foo", sample.VersionString, "sqlite")
+	cgo.LegacyPackages[0].RequiresCgo = true
+	pure := sample.Module("pure.com/foo", sample.VersionString, "sqlite")
+	for _, m := range []*internal.Module{cgo, pure} {
+		if err := testDB.InsertModule(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := map[string]bool{
+		"cgo.com/foo/sqlite":  true,
+		"pure.com/foo/sqlite": false,
+	}
+	for pkgPath, w := range want {
+		pkg, err := testDB.LegacyGetPackage(ctx, pkgPath, internal.UnknownModulePath, sample.VersionString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := pkg.Facts.RequiresCgo; got == nil || *got != w {
+			t.Errorf("LegacyGetPackage(%q).Facts.RequiresCgo = %v, want %t", pkgPath, got, w)
+		}
+	}
+
+	for method, searcher := range searchers {
+		t.Run(method, func(t *testing.T) {
+			res := searcher(testDB, ctx, searchParams{q: "foo", limit: 10})
+			if res.err != nil {
+				t.Fatal(res.err)
+			}
+			if len(res.results) != len(want) {
+				t.Fatalf("got %d search results, want %d", len(res.results), len(want))
+			}
+			for _, r := range res.results {
+				if r.RequiresCgo != want[r.PackagePath] {
+					t.Errorf("RequiresCgo for %q = %t, want %t", r.PackagePath, r.RequiresCgo, want[r.PackagePath])
+				}
+			}
+		})
+	}
+}
+
+func TestSearchHasFuzzTests(t *testing.T) {
+	defer ResetTestDB(testDB, t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	fuzzed := sample.Module("fuzzed.com/foo", sample.VersionString, "parse")
+	fuzzed.LegacyPackages[0].HasFuzzTests = true
+	unfuzzed := sample.Module("unfuzzed.com/foo", sample.VersionString, "parse")
+	for _, m := range []*internal.Module{fuzzed, unfuzzed} {
+		if err := testDB.InsertModule(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := map[string]bool{
+		"fuzzed.com/foo/parse":   true,
+		"unfuzzed.com/foo/parse": false,
+	}
+	for pkgPath, w := range want {
+		got, err := testDB.GetHasFuzzTests(ctx, pkgPath, sample.VersionString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != w {
+			t.Errorf("GetHasFuzzTests(%q) = %t, want %t", pkgPath, got, w)
+		}
+	}
+
+	for method, searcher := range searchers {
+		t.Run(method, func(t *testing.T) {
+			res := searcher(testDB, ctx, searchParams{q: "foo", limit: 10})
+			if res.err != nil {
+				t.Fatal(res.err)
+			}
+			if len(res.results) != len(want) {
+				t.Fatalf("got %d search results, want %d", len(res.results), len(want))
+			}
+			for _, r := range res.results {
+				if r.HasFuzzTests != want[r.PackagePath] {
+					t.Errorf("HasFuzzTests for %q = %t, want %t", r.PackagePath, r.HasFuzzTests, want[r.PackagePath])
+				}
+			}
+
+			sq, err := search.ParseSearchQuery("foo fuzz:true")
+			if err != nil {
+				t.Fatal(err)
+			}
+			res = searcher(testDB, ctx, searchParams{q: sq.Text(), limit: 10, filter: searchFilterSQL(sq.Filters)})
+			if res.err != nil {
+				t.Fatal(res.err)
+			}
+			if len(res.results) != 1 || res.results[0].PackagePath != "fuzzed.com/foo/parse" {
+				t.Errorf("got %d results for fuzz:true, want only fuzzed.com/foo/parse", len(res.results))
+			}
+		})
+	}
+}
+
+func TestSearchBenchmarkCount(t *testing.T) {
+	defer ResetTestDB(testDB, t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	benched := sample.Module("benched.com/foo", sample.VersionString, "parse")
+	benched.LegacyPackages[0].BenchmarkCount = 2
+	unbenched := sample.Module("unbenched.com/foo", sample.VersionString, "parse")
+	for _, m := range []*internal.Module{benched, unbenched} {
+		if err := testDB.InsertModule(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := map[string]int{
+		"benched.com/foo/parse":   2,
+		"unbenched.com/foo/parse": 0,
+	}
+	for pkgPath, w := range want {
+		got, err := testDB.GetBenchmarkCount(ctx, pkgPath, sample.VersionString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != w {
+			t.Errorf("GetBenchmarkCount(%q) = %d, want %d", pkgPath, got, w)
+		}
+	}
+
+	for method, searcher := range searchers {
+		t.Run(method, func(t *testing.T) {
+			res := searcher(testDB, ctx, searchParams{q: "foo", limit: 10})
+			if res.err != nil {
+				t.Fatal(res.err)
+			}
+			if len(res.results) != len(want) {
+				t.Fatalf("got %d search results, want %d", len(res.results), len(want))
+			}
+			for _, r := range res.results {
+				if r.BenchmarkCount != want[r.PackagePath] {
+					t.Errorf("BenchmarkCount for %q = %d, want %d", r.PackagePath, r.BenchmarkCount, want[r.PackagePath])
+				}
+			}
+
+			sq, err := search.ParseSearchQuery("foo benchmarks:true")
+			if err != nil {
+				t.Fatal(err)
+			}
+			res = searcher(testDB, ctx, searchParams{q: sq.Text(), limit: 10, filter: searchFilterSQL(sq.Filters)})
+			if res.err != nil {
+				t.Fatal(res.err)
+			}
+			if len(res.results) != 1 || res.results[0].PackagePath != "benched.com/foo/parse" {
+				t.Errorf("got %d results for benchmarks:true, want only benched.com/foo/parse", len(res.results))
+			}
+		})
+	}
+}
+
+func TestSearchPenalties(t *testing.T) {
+	// Verify that the penalties for non-redistributable modules and modules without
+	// go.mod files are applied correctly.
+	defer ResetTestDB(testDB, t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	// All these modules will have the same text ranking for the search term "foo",
+	// but different scores due to penalties.
+	modules := map[string]struct {
+		redist     bool
+		hasGoMod   bool
+		retracted  bool
+		multiplier float64 // applied to base score
+	}{
+		"both.com/foo":      {true, true, false, 1},
+		"nogomod.com/foo":   {true, false, false, noGoModPenalty},
+		"nonredist.com/foo": {false, true, false, nonRedistributablePenalty},
+		"neither.com/foo":   {false, false, false, noGoModPenalty * nonRedistributablePenalty},
+		"retracted.com/foo": {true, true, true, retractedPenalty},
+	}
+
+	for path, m := range modules {
+		v := sample.Module(path, sample.VersionString, "p")
+		v.LegacyPackages[0].IsRedistributable = m.redist
+		v.IsRedistributable = m.redist
+		v.HasGoMod = m.hasGoMod
+		v.IsRetracted = m.retracted
+		if err := testDB.InsertModule(ctx, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for method, searcher := range searchers {
+		t.Run(method, func(t *testing.T) {
+			res := searcher(testDB, ctx, searchParams{q: "foo", limit: 10})
+			if res.err != nil {
+				t.Fatal(res.err)
+			}
+			if got, want := len(res.results), len(modules); got != want {
+				t.Fatalf("got %d search results, want %d", got, want)
+			}
+			for _, r := range res.results {
+				got := r.Score
+				want := res.results[0].Score * modules[r.ModulePath].multiplier
+				if math.Abs(got-want) > 1e6 {
+					t.Errorf("%s: got %f, want %f", r.ModulePath, got, want)
 				}
+			}
+			rank := make(map[string]int)
+			for i, r := range res.results {
+				rank[r.ModulePath] = i
+			}
+			if rank["retracted.com/foo"] < rank["both.com/foo"] {
+				t.Errorf("retracted module ranked %d, above equivalent module ranked %d", rank["retracted.com/foo"], rank["both.com/foo"])
+			}
+		})
+	}
+}
+
+func TestSearchDeprecated(t *testing.T) {
+	defer ResetTestDB(testDB, t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	deprecated := sample.Module("deprecated.com/foo", sample.VersionString, "p")
+	deprecated.LegacyPackages[0].IsDeprecated = true
+	deprecated.LegacyPackages[0].DeprecationMessage = "use current.com/foo/p instead."
+	current := sample.Module("current.com/foo", sample.VersionString, "p")
+	for _, m := range []*internal.Module{deprecated, current} {
+		if err := testDB.InsertModule(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, test := range []struct {
+		pkgPath        string
+		wantDeprecated bool
+		wantMessage    string
+	}{
+		{"deprecated.com/foo/p", true, "use current.com/foo/p instead."},
+		{"current.com/foo/p", false, ""},
+	} {
+		pkg, err := testDB.LegacyGetPackage(ctx, test.pkgPath, internal.UnknownModulePath, sample.VersionString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := pkg.Facts
+		if got.IsDeprecated == nil || *got.IsDeprecated != test.wantDeprecated || got.DeprecationMessage != test.wantMessage {
+			t.Errorf("LegacyGetPackage(%q).Facts: IsDeprecated = %v, DeprecationMessage = %q, want %t, %q",
+				test.pkgPath, got.IsDeprecated, got.DeprecationMessage, test.wantDeprecated, test.wantMessage)
+		}
+	}
+
+	for method, searcher := range searchers {
+		t.Run(method, func(t *testing.T) {
+			res := searcher(testDB, ctx, searchParams{q: "foo", limit: 10})
+			if res.err != nil {
+				t.Fatal(res.err)
+			}
+			if len(res.results) != 2 {
+				t.Fatalf("got %d search results, want 2", len(res.results))
+			}
+			first, second := res.results[0], res.results[1]
+			if first.PackagePath != "current.com/foo/p" {
+				t.Fatalf("got %q ranked first, want current.com/foo/p", first.PackagePath)
+			}
+			if got, want := second.Score, first.Score*deprecatedPenalty; math.Abs(got-want) > 1e-6 {
+				t.Errorf("score of deprecated package = %f, want %f", got, want)
+			}
+		})
+	}
+}
+
+func TestExcludedFromSearch(t *testing.T) {
+	// Verify that excluded paths are omitted from search results.
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	// Insert a module with two packages.
+	const domain = "exclude.com"
+	sm := sample.Module(domain, "v1.2.3", "pkg", "exclude")
+	if err := testDB.InsertModule(ctx, sm); err != nil {
+		t.Fatal(err)
+	}
+	// Exclude a prefix that matches one of the packages.
+	if err := testDB.InsertExcludedPrefix(ctx, domain+"/ex", "no user", "no reason"); err != nil {
+		t.Fatal(err)
+	}
+	// Search for both packages.
+	gotResults, err := testDB.Search(ctx, domain, SearchOptions{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, g := range gotResults {
+		got = append(got, g.Name)
+	}
+	want := []string{"pkg"}
+	if !cmp.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestSearchCursor(t *testing.T) {
+	// Verify that paging through results with cursors returns the same results
+	// as paging with offsets.
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	for _, m := range importGraph("foo.com/popular", "bar.com/foo", 5) {
+		if err := testDB.InsertModule(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := testDB.UpdateSearchDocumentsImportedByCount(ctx); err != nil {
+		t.Fatal(err)
+	}
+	all, err := testDB.Search(ctx, "foo", SearchOptions{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []string
+	for _, r := range all {
+		want = append(want, r.PackagePath)
+	}
+
+	for method, searcher := range searchers {
+		t.Run(method, func(t *testing.T) {
+			var (
+				got   []string
+				after *searchCursor
+			)
+			for {
+				res := searcher(testDB, ctx, searchParams{q: "foo", limit: 2, after: after})
+				if res.err != nil {
+					t.Fatal(res.err)
+				}
+				if len(res.results) == 0 {
+					break
+				}
+				for _, r := range res.results {
+					got = append(got, r.PackagePath)
+				}
+				last := res.results[len(res.results)-1]
+				after = &searchCursor{Score: last.Score, CommitTime: last.CommitTime, PackagePath: last.PackagePath}
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSearchFilters(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	for _, m := range importGraph("foo.com/popular", "bar.com/foo", 3) {
+		if err := testDB.InsertModule(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := testDB.UpdateSearchDocumentsImportedByCount(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		q    string
+		want []string
+	}{
+		{"foo imported:>=1", []string{"foo.com/popular"}},
+		{"foo path:bar.com/foo imported:0", []string{"bar.com/foo/importer0", "bar.com/foo/importer1", "bar.com/foo/importer2"}},
+		{"foo license:mit path:foo.com", []string{"foo.com/popular"}},
+		{"foo license:BSD-3-Clause", nil},
+		{"foo cgo:true", nil},
+		{"foo cgo:false path:foo.com", []string{"foo.com/popular"}},
+		{"foo redistributable:true path:foo.com", []string{"foo.com/popular"}},
+		{"foo redistributable:false", nil},
+		// Queries of only filters match every package satisfying them.
+		{"path:bar.com/foo imported:0", []string{"bar.com/foo/importer0", "bar.com/foo/importer1", "bar.com/foo/importer2"}},
+		{"license:MIT imported:>=1", []string{"foo.com/popular"}},
+	} {
+		for method, searcher := range searchers {
+			t.Run(test.q+":"+method, func(t *testing.T) {
+				sq, err := search.ParseSearchQuery(test.q)
+				if err != nil {
+					t.Fatal(err)
+				}
+				res := searcher(testDB, ctx, searchParams{q: sq.Text(), limit: 10, filter: searchFilterSQL(sq.Filters)})
+				if res.err != nil {
+					t.Fatal(res.err)
+				}
+				var got []string
+				for _, r := range res.results {
+					got = append(got, r.PackagePath)
+				}
+				sort.Strings(got)
+				if diff := cmp.Diff(test.want, got); diff != "" {
+					t.Errorf("mismatch (-want +got):\n%s", diff)
+				}
+			})
+		}
+	}
+
+	// Words with unsupported fields are search terms.
+	if _, err := testDB.Search(ctx, "foo author:me https://bar.com/foo", SearchOptions{Limit: 10}); err != nil {
+		t.Errorf("got error %v, want nil", err)
+	}
+	if _, err := testDB.Search(ctx, " \t ", SearchOptions{Limit: 10}); !errors.Is(err, derrors.InvalidArgument) {
+		t.Errorf("got error %v, want InvalidArgument", err)
+	}
+}
 
-				// The searchers differ in these two fields.
-				opt := cmpopts.IgnoreFields(internal.SearchResult{}, "Approximate", "NumResults")
-				if diff := cmp.Diff(tc.want, got.results, opt); diff != "" {
-					t.Errorf("testDB.Search(%v, %d, %d) mismatch (-want +got):\n%s", tc.searchQuery, tc.limit, tc.offset, diff)
+func TestDeepSearchUsesPartialIndex(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	// Searches without a redistributable filter only match redistributable
+	// packages, as do those with redistributable:true.
+	for _, q := range []string{"foo", "foo redistributable:true"} {
+		t.Run(q, func(t *testing.T) {
+			sq, err := search.ParseSearchQuery(q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sp := searchParams{filter: searchFilter(sq.Filters, SearchOptions{})}
+			query := "EXPLAIN " + deepSearchQuery(scoreExpr, textMatch, sp.filterSQL())
+			var plan []string
+			err = testDB.db.Transact(ctx, sql.LevelDefault, func(tx *database.DB) error {
+				// The test table is too small for the planner to prefer an
+				// index over a sequential scan otherwise.
+				if _, err := tx.Exec(ctx, "SET LOCAL enable_seqscan = off"); err != nil {
+					return err
 				}
+				return tx.RunQuery(ctx, query, func(rows *sql.Rows) error {
+					var line string
+					if err := rows.Scan(&line); err != nil {
+						return err
+					}
+					plan = append(plan, line)
+					return nil
+				}, sq.Text(), 10, 0, nil, nil, nil)
 			})
-		}
+			if err != nil {
+				t.Fatal(err)
+			}
+			const index = "idx_search_documents_tsv_search_tokens_redistributable"
+			if !strings.Contains(strings.Join(plan, "\n"), index) {
+				t.Errorf("plan does not use %s:\n%s", index, strings.Join(plan, "\n"))
+			}
+		})
 	}
 }
 
-func TestSearchPenalties(t *testing.T) {
-	// Verify that the penalties for non-redistributable modules and modules without
-	// go.mod files are applied correctly.
-	defer ResetTestDB(testDB, t)
-
+func TestSearchRedistributableByDefault(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
 	defer cancel()
+	defer ResetTestDB(testDB, t)
 
-	// All these modules will have the same text ranking for the search term "foo",
-	// but different scores due to penalties.
-	modules := map[string]struct {
-		redist     bool
-		hasGoMod   bool
-		multiplier float64 // applied to base score
+	for _, m := range []struct {
+		path   string
+		redist bool
 	}{
-		"both.com/foo":      {true, true, 1},
-		"nogomod.com/foo":   {true, false, noGoModPenalty},
-		"nonredist.com/foo": {false, true, nonRedistributablePenalty},
-		"neither.com/foo":   {false, false, noGoModPenalty * nonRedistributablePenalty},
-	}
-
-	for path, m := range modules {
-		v := sample.Module(path, sample.VersionString, "p")
+		{"redist.com/foo", true},
+		{"nonredist.com/foo", false},
+	} {
+		v := sample.Module(m.path, sample.VersionString, "p")
 		v.LegacyPackages[0].IsRedistributable = m.redist
 		v.IsRedistributable = m.redist
-		v.HasGoMod = m.hasGoMod
 		if err := testDB.InsertModule(ctx, v); err != nil {
 			t.Fatal(err)
 		}
 	}
+	for _, test := range []struct {
+		q    string
+		want []string
+	}{
+		{"foo", []string{"redist.com/foo/p"}},
+		{"foo redistributable:true", []string{"redist.com/foo/p"}},
+		{"foo redistributable:false", []string{"nonredist.com/foo/p"}},
+	} {
+		t.Run(test.q, func(t *testing.T) {
+			rs, err := testDB.Search(ctx, test.q, SearchOptions{Limit: 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range rs {
+				got = append(got, r.PackagePath)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
 
-	for method, searcher := range searchers {
-		t.Run(method, func(t *testing.T) {
-			res := searcher(testDB, ctx, "foo", 10, 0)
-			if res.err != nil {
-				t.Fatal(res.err)
+func TestSearchFacets(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	for path, types := range map[string][]string{
+		"mit.com/foo":    {"MIT"},
+		"mit2.com/foo":   {"MIT"},
+		"apache.com/foo": {"Apache-2.0"},
+		"dual.com/foo":   {"Apache-2.0", "MIT"},
+		"bsd.com/bar":    {"BSD-3-Clause"}, // does not match the query
+	} {
+		m := sample.Module(path, sample.VersionString, "")
+		var lics []*licenses.Metadata
+		for _, typ := range types {
+			lics = append(lics, &licenses.Metadata{Types: []string{typ}, FilePath: "LICENSE-" + typ})
+		}
+		m.LegacyPackages[0].Licenses = lics
+		m.LegacyPackages[0].Synopsis = "foo"
+		if err := testDB.InsertModule(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, test := range []struct {
+		q    string
+		opts SearchOptions
+		want map[string]uint64
+	}{
+		{"foo", SearchOptions{Limit: 10}, nil},
+		{"foo", SearchOptions{Limit: 10, IncludeFacets: true}, map[string]uint64{"MIT": 3, "Apache-2.0": 2}},
+		// Facets count all results, not just those on the page.
+		{"foo", SearchOptions{Limit: 1, IncludeFacets: true}, map[string]uint64{"MIT": 3, "Apache-2.0": 2}},
+		{"foo license:apache-2.0", SearchOptions{Limit: 10, IncludeFacets: true}, map[string]uint64{"MIT": 1, "Apache-2.0": 2}},
+	} {
+		t.Run(fmt.Sprintf("%s %+v", test.q, test.opts), func(t *testing.T) {
+			results, err := testDB.Search(ctx, test.q, test.opts)
+			if err != nil {
+				t.Fatal(err)
 			}
-			if got, want := len(res.results), len(modules); got != want {
-				t.Fatalf("got %d search results, want %d", got, want)
+			if len(results) == 0 {
+				t.Fatal("got no results")
 			}
-			for _, r := range res.results {
-				got := r.Score
-				want := res.results[0].Score * modules[r.ModulePath].multiplier
-				if math.Abs(got-want) > 1e6 {
-					t.Errorf("%s: got %f, want %f", r.ModulePath, got, want)
+			for _, r := range results {
+				if diff := cmp.Diff(test.want, r.FacetCounts); diff != "" {
+					t.Errorf("%s: FacetCounts mismatch (-want +got):\n%s", r.PackagePath, diff)
 				}
 			}
 		})
 	}
 }
 
-func TestExcludedFromSearch(t *testing.T) {
-	// Verify that excluded paths are omitted from search results.
+func TestSearchUpdatedRange(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
 	defer cancel()
 	defer ResetTestDB(testDB, t)
 
-	// Insert a module with two packages.
-	const domain = "exclude.com"
-	sm := sample.Module(domain, "v1.2.3", "pkg", "exclude")
-	if err := testDB.InsertModule(ctx, sm); err != nil {
-		t.Fatal(err)
+	date := func(year int) time.Time { return time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC) }
+	for path, year := range map[string]int{
+		"old.com/foo":    2015,
+		"middle.com/foo": 2018,
+		"new.com/foo":    2020,
+	} {
+		m := sample.Module(path, sample.VersionString, "")
+		m.CommitTime = date(year)
+		if err := testDB.InsertModule(ctx, m); err != nil {
+			t.Fatal(err)
+		}
 	}
-	// Exclude a prefix that matches one of the packages.
-	if err := testDB.InsertExcludedPrefix(ctx, domain+"/ex", "no user", "no reason"); err != nil {
-		t.Fatal(err)
+	for _, test := range []struct {
+		name          string
+		after, before time.Time
+		want          []string
+	}{
+		{"no range", time.Time{}, time.Time{}, []string{"middle.com/foo", "new.com/foo", "old.com/foo"}},
+		{"after", date(2017), time.Time{}, []string{"middle.com/foo", "new.com/foo"}},
+		{"before", time.Time{}, date(2018), []string{"middle.com/foo", "old.com/foo"}},
+		{"between", date(2016), date(2019), []string{"middle.com/foo"}},
+		{"inclusive", date(2018), date(2018), []string{"middle.com/foo"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			results, err := testDB.Search(ctx, "foo", SearchOptions{Limit: 10, UpdatedAfter: test.after, UpdatedBefore: test.before})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range results {
+				got = append(got, r.PackagePath)
+			}
+			sort.Strings(got)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
 	}
-	// Search for both packages.
-	gotResults, err := testDB.Search(ctx, domain, 10, 0)
+
+	_, err := testDB.Search(ctx, "foo", SearchOptions{Limit: 10, UpdatedAfter: date(2020), UpdatedBefore: date(2019)})
+	if !errors.Is(err, derrors.InvalidArgument) {
+		t.Errorf("got error %v, want InvalidArgument", err)
+	}
+}
+
+func TestCommitTimeFilterSQL(t *testing.T) {
+	after := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	before := after.Add(time.Hour)
+	for _, test := range []struct {
+		after, before time.Time
+		want          string
+	}{
+		{time.Time{}, time.Time{}, ""},
+		{after, time.Time{}, "commit_time >= '2020-01-02T03:04:05Z'::timestamptz"},
+		{time.Time{}, before, "commit_time <= '2020-01-02T04:04:05Z'::timestamptz"},
+		{after, before, "commit_time >= '2020-01-02T03:04:05Z'::timestamptz AND commit_time <= '2020-01-02T04:04:05Z'::timestamptz"},
+	} {
+		if got := commitTimeFilterSQL(test.after, test.before); got != test.want {
+			t.Errorf("commitTimeFilterSQL(%v, %v) = %q, want %q", test.after, test.before, got, test.want)
+		}
+	}
+}
+
+func TestSearchFilterSQL(t *testing.T) {
+	for _, test := range []struct {
+		q    string
+		want string
+	}{
+		{"foo", ""},
+		{"foo imported:>10", "imported_by_count > '10'::integer"},
+		{"foo cgo:true", "requires_cgo = 'true'::boolean"},
+		{"foo fuzz:true", "has_fuzz_tests = 'true'::boolean"},
+		{"foo benchmarks:false", "(benchmark_count > 0) = 'false'::boolean"},
+		{
+			"license:MIT path:a.com/b's",
+			"EXISTS (SELECT 1 FROM UNNEST(license_types) t WHERE LOWER(t) = LOWER('MIT')) AND " +
+				"(package_path = 'a.com/b''s' OR starts_with(package_path, 'a.com/b''s' || '/'))",
+		},
+	} {
+		sq, err := search.ParseSearchQuery(test.q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := searchFilterSQL(sq.Filters); got != test.want {
+			t.Errorf("searchFilterSQL(%q) = %q, want %q", test.q, got, test.want)
+		}
+	}
+}
+
+func TestSearchCursorEncoding(t *testing.T) {
+	c := &searchCursor{Score: 0.6079270839691162, CommitTime: sample.CommitTime, PackagePath: "github.com/foo/bar"}
+	got, err := decodeSearchCursor(c.encode())
 	if err != nil {
 		t.Fatal(err)
 	}
-	var got []string
-	for _, g := range gotResults {
-		got = append(got, g.Name)
+	if diff := cmp.Diff(c, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
 	}
-	want := []string{"pkg"}
-	if !cmp.Equal(got, want) {
-		t.Errorf("got %v, want %v", got, want)
+
+	for _, bad := range []string{"not base64!", "bm90IGpzb24", "e30"} {
+		if _, err := decodeSearchCursor(bad); !errors.Is(err, derrors.InvalidArgument) {
+			t.Errorf("decodeSearchCursor(%q): got error %v, want InvalidArgument", bad, err)
+		}
+	}
+}
+
+func TestTopResults(t *testing.T) {
+	r := func(path string, score float64, importedBy int) *internal.SearchResult {
+		return &internal.SearchResult{PackagePath: path, Score: score, NumImportedBy: uint64(importedBy), CommitTime: sample.CommitTime}
+	}
+	// Results in descending order of imported-by count.
+	all := []*internal.SearchResult{
+		r("a", 0.5, 1000),
+		r("b", 2.0, 100),
+		r("c", 0.05, 50),
+		r("d", 1.0, 1),
+		r("e", 0.9, 0),
+	}
+	for _, test := range []struct {
+		name      string
+		sp        searchParams
+		want      []string
+		wantAdded int
+	}{
+		{"first page", searchParams{limit: 2}, []string{"b", "d"}, 5},
+		{"offset", searchParams{limit: 2, offset: 1}, []string{"d", "e"}, 5},
+		{"early exit", searchParams{limit: 1}, []string{"b"}, 4},
+		{"cursor", searchParams{limit: 2, after: &searchCursor{Score: 1.0, CommitTime: sample.CommitTime, PackagePath: "d"}}, []string{"e", "a"}, 5},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			top := newTopResults(test.sp)
+			added := 0
+			for _, res := range all {
+				added++
+				if !top.add(res) {
+					break
+				}
+			}
+			var got []string
+			for _, res := range top.results() {
+				got = append(got, res.PackagePath)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+			if added != test.wantAdded {
+				t.Errorf("added %d results, want %d", added, test.wantAdded)
+			}
+		})
 	}
 }
 
@@ -661,6 +2041,7 @@ type searchDocument struct {
 	synopsis                 string
 	licenseTypes             []string
 	importedByCount          int
+	decayedImportedByCount   float64
 	redistributable          bool
 	hasGoMod                 bool
 	versionUpdatedAt         time.Time
@@ -680,6 +2061,7 @@ func getSearchDocument(ctx context.Context, db *DB, path string) (*searchDocumen
 			synopsis,
 			license_types,
 			imported_by_count,
+			decayed_imported_by_count,
 			redistributable,
 			has_go_mod,
 			version_updated_at,
@@ -694,7 +2076,7 @@ func getSearchDocument(ctx context.Context, db *DB, path string) (*searchDocumen
 	)
 	if err := row.Scan(&sd.packagePath, &sd.modulePath, &sd.version, &sd.commitTime,
 		&sd.name, &sd.synopsis, pq.Array(&sd.licenseTypes), &sd.importedByCount,
-		&sd.redistributable, &sd.hasGoMod, &sd.versionUpdatedAt, &t); err != nil {
+		&sd.decayedImportedByCount, &sd.redistributable, &sd.hasGoMod, &sd.versionUpdatedAt, &t); err != nil {
 		return nil, fmt.Errorf("row.Scan(): %v", err)
 	}
 	if t.Valid {
@@ -781,7 +2163,48 @@ func TestUpsertSearchDocumentVersionHasGoMod(t *testing.T) {
 	}
 }
 
+func TestComputeDecayedImportedByCount(t *testing.T) {
+	const year = 365 * 24 * time.Hour
+	now := time.Now()
+	for _, test := range []struct {
+		name       string
+		halfLife   time.Duration
+		count      int
+		commitTime time.Time
+		want       float64
+	}{
+		{"today", 0, 100, now, 100},
+		{"2 years ago", 0, 100, now.Add(-2 * year), 50},
+		{"10 years ago", 0, 100, now.Add(-10 * year), 100.0 / 32},
+		{"in the future", 0, 100, now.Add(year), 100},
+		{"no importers", 0, 0, now.Add(-2 * year), 0},
+		{"custom half-life", year, 100, now.Add(-2 * year), 25},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			db := &DB{ImportedByHalfLife: test.halfLife}
+			got := db.ComputeDecayedImportedByCount(test.count, test.commitTime)
+			if math.Abs(got-test.want) > 1e-6 {
+				t.Errorf("ComputeDecayedImportedByCount(%d, %v) = %f, want %f", test.count, test.commitTime, got, test.want)
+			}
+		})
+	}
+}
+
 func TestUpdateSearchDocumentsImportedByCount(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		update func(context.Context) (int64, error)
+	}{
+		{"full", testDB.UpdateSearchDocumentsImportedByCount},
+		{"incremental", testDB.UpdateSearchDocumentsImportedByCountIncremental},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			testUpdateSearchDocumentsImportedByCount(t, test.update)
+		})
+	}
+}
+
+func testUpdateSearchDocumentsImportedByCount(t *testing.T, update func(context.Context) (int64, error)) {
 	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
 	defer cancel()
 
@@ -801,7 +2224,7 @@ func TestUpdateSearchDocumentsImportedByCount(t *testing.T) {
 	}
 	updateImportedByCount := func() {
 		t.Helper()
-		if _, err := testDB.UpdateSearchDocumentsImportedByCount(ctx); err != nil {
+		if _, err := update(ctx); err != nil {
 			t.Fatal(err)
 		}
 	}
@@ -817,6 +2240,11 @@ func TestUpdateSearchDocumentsImportedByCount(t *testing.T) {
 		if count != sd.importedByCount {
 			t.Fatalf("importedByCount for package %q = %d; want = %d", path, sd.importedByCount, count)
 		}
+		// The packages were committed just now, so their counts have barely
+		// decayed.
+		if math.Abs(sd.decayedImportedByCount-float64(count)) > 0.01 {
+			t.Fatalf("decayedImportedByCount for package %q = %f; want about %d", path, sd.decayedImportedByCount, count)
+		}
 		return sd
 	}
 
@@ -906,6 +2334,76 @@ func TestUpdateSearchDocumentsImportedByCount(t *testing.T) {
 	})
 }
 
+func TestUpdateSearchDocumentsImportedByCountIncremental(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	for _, m := range importGraph("foo.com/popular", "bar.com/foo", 2) {
+		if err := testDB.InsertModule(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	n, err := testDB.UpdateSearchDocumentsImportedByCountIncremental(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("got %d rows updated, want 1", n)
+	}
+	if sd, err := getSearchDocument(ctx, testDB, "foo.com/popular"); err != nil {
+		t.Fatal(err)
+	} else if sd.importedByCount != 2 {
+		t.Errorf("importedByCount = %d, want 2", sd.importedByCount)
+	}
+
+	// The change log has been processed, so nothing more is updated.
+	n, err = testDB.UpdateSearchDocumentsImportedByCountIncremental(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Errorf("got %d rows updated with no changes, want 0", n)
+	}
+
+	// When the importers stop importing the package, its count drops to zero.
+	m := sample.Module("bar.com/foo", "v1.3.0", "importer0", "importer1")
+	for _, p := range m.LegacyPackages {
+		p.Imports = nil
+	}
+	if err := testDB.InsertModule(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := testDB.UpdateSearchDocumentsImportedByCountIncremental(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if sd, err := getSearchDocument(ctx, testDB, "foo.com/popular"); err != nil {
+		t.Fatal(err)
+	} else if sd.importedByCount != 0 {
+		t.Errorf("importedByCount = %d, want 0", sd.importedByCount)
+	}
+
+	// Both kinds of update consume the change log.
+	checkChangelogEmpty := func() {
+		t.Helper()
+		var n int
+		if err := testDB.db.QueryRow(ctx, `SELECT COUNT(*) FROM imports_unique_changelog`).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		if n != 0 {
+			t.Errorf("imports_unique_changelog has %d rows, want 0", n)
+		}
+	}
+	checkChangelogEmpty()
+	if err := testDB.InsertModule(ctx, sample.Module("bar.com/foo", "v1.4.0", "importer0")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := testDB.UpdateSearchDocumentsImportedByCount(ctx); err != nil {
+		t.Fatal(err)
+	}
+	checkChangelogEmpty()
+}
+
 func TestGetPackagesForSearchDocumentUpsert(t *testing.T) {
 	defer ResetTestDB(testDB, t)
 
@@ -1002,6 +2500,150 @@ func TestHllZeros(t *testing.T) {
 	}
 }
 
+// TestHLLAccuracy checks the result counts estimated by hllQuery for
+// searches with known numbers of results.
+//
+// It runs at the default precision: with the 2^DefaultHLLPrecision
+// registers, the standard error of the estimates is about
+// 1.04/sqrt(2^DefaultHLLPrecision), or 0.8%, so the estimates are only
+// required to be within three standard errors of the true counts. Since the
+// hash of a package path is deterministic, so are the estimates: the errors
+// are logged to characterize the error curve.
+func TestHLLAccuracy(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+	defer func(p int) { testDB.HLLPrecision = p }(testDB.HLLPrecision)
+
+	testDB.HLLPrecision = 0
+	if got := testDB.hllPrecision(); got != DefaultHLLPrecision {
+		t.Fatalf("hllPrecision() = %d, want DefaultHLLPrecision (%d)", got, DefaultHLLPrecision)
+	}
+	maxRelErr := 3 * hllStandardError(DefaultHLLPrecision)
+	for _, n := range []int{10, 100, 1000, 10000} {
+		t.Run(strconv.Itoa(n), func(t *testing.T) {
+			if n > 1000 && testing.Short() {
+				t.Skip("skipping large insert in short mode")
+			}
+			// Each package of the module matches the query.
+			modulePath := fmt.Sprintf("hll%d.example.com", n)
+			var suffixes []string
+			for i := 0; i < n; i++ {
+				suffixes = append(suffixes, fmt.Sprintf("p%d", i))
+			}
+			m := sample.Module(modulePath, sample.VersionString, suffixes...)
+			for _, p := range m.LegacyPackages {
+				p.Imports = nil
+			}
+			if err := testDB.InsertModule(ctx, m); err != nil {
+				t.Fatal(err)
+			}
+			sp := searchParams{q: fmt.Sprintf("hll%d", n), limit: 1}
+
+			// Check that the query matches exactly the packages of the module.
+			deep := testDB.deepSearch(ctx, sp)
+			if deep.err != nil {
+				t.Fatal(deep.err)
+			}
+			if len(deep.results) == 0 || deep.results[0].NumResults != uint64(n) {
+				t.Fatalf("deepSearch(%q) did not count %d results", sp.q, n)
+			}
+
+			est := testDB.estimateResultsCount(ctx, sp)
+			if est.err != nil {
+				t.Fatal(est.err)
+			}
+			relErr := (float64(est.estimate) - float64(n)) / float64(n)
+			t.Logf("%d results: estimate %d, error %+.1f%%", n, est.estimate, 100*relErr)
+			if math.Abs(relErr) > maxRelErr {
+				t.Errorf("%d results: estimate %d is off by %.1f%%, more than %.1f%%", n, est.estimate, 100*relErr, 100*maxRelErr)
+			}
+		})
+	}
+}
+
+// TestHLLPrecision checks that estimates are more accurate at a higher
+// precision. At precision 4, the 16 registers take little time to look up
+// but have a standard error of 26%; at precision 18, the 262,144 registers
+// have a standard error of 0.2%, but the estimate looks up each of them. The
+// default precision, 14, keeps the error under 1% with a sixteenth of the
+// registers of precision 18.
+func TestHLLPrecision(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+	defer func(p int) { testDB.HLLPrecision = p }(testDB.HLLPrecision)
+
+	const n = 1000
+	var suffixes []string
+	for i := 0; i < n; i++ {
+		suffixes = append(suffixes, fmt.Sprintf("p%d", i))
+	}
+	m := sample.Module("hllprecision.example.com", sample.VersionString, suffixes...)
+	for _, p := range m.LegacyPackages {
+		p.Imports = nil
+	}
+	if err := testDB.InsertModule(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+	sp := searchParams{q: "hllprecision", limit: 1}
+	relErr := func(precision int) float64 {
+		t.Helper()
+		testDB.HLLPrecision = precision
+		est := testDB.estimateResultsCount(ctx, sp)
+		if est.err != nil {
+			t.Fatal(est.err)
+		}
+		e := (float64(est.estimate) - n) / n
+		t.Logf("precision %d: estimate %d, error %+.1f%%", precision, est.estimate, 100*e)
+		return math.Abs(e)
+	}
+	if low, high := relErr(MinHLLPrecision), relErr(MaxHLLPrecision); high >= low {
+		t.Errorf("error at precision %d (%.1f%%) is not less than at precision %d (%.1f%%)",
+			MaxHLLPrecision, 100*high, MinHLLPrecision, 100*low)
+	}
+	if got, max := relErr(0), 3*hllStandardError(DefaultHLLPrecision); got > max {
+		t.Errorf("error at the default precision is %.1f%%, more than %.1f%%", 100*got, 100*max)
+	}
+
+	testDB.HLLPrecision = MaxHLLPrecision + 1
+	if est := testDB.estimateResultsCount(ctx, sp); !errors.Is(est.err, derrors.InvalidArgument) {
+		t.Errorf("precision %d: got error %v, want InvalidArgument", testDB.HLLPrecision, est.err)
+	}
+}
+
+func TestValidateHLLPrecision(t *testing.T) {
+	for _, test := range []struct {
+		p    int
+		want bool
+	}{
+		{3, false},
+		{4, true},
+		{14, true},
+		{18, true},
+		{19, false},
+	} {
+		if got := ValidateHLLPrecision(test.p) == nil; got != test.want {
+			t.Errorf("ValidateHLLPrecision(%d) == nil is %t, want %t", test.p, got, test.want)
+		}
+	}
+}
+
+func TestHLLStandardError(t *testing.T) {
+	for _, test := range []struct {
+		precision int
+		want      float64
+	}{
+		{4, 0.26},
+		{7, 0.0919},
+		{14, 0.0081},
+	} {
+		if got := hllStandardError(test.precision); math.Abs(got-test.want) > 1e-4 {
+			t.Errorf("hllStandardError(%d) = %f, want %f", test.precision, got, test.want)
+		}
+	}
+}
+
 func TestDeleteOlderVersionFromSearch(t *testing.T) {
 	ctx := context.Background()
 	defer ResetTestDB(testDB, t)
@@ -1060,3 +2702,28 @@ func TestDeleteOlderVersionFromSearch(t *testing.T) {
 	insert(mod)
 	check(mod)
 }
+
+func TestDeleteOlderVersionsFromSearchDocuments(t *testing.T) {
+	ctx := context.Background()
+	defer ResetTestDB(testDB, t)
+
+	// A later prerelease does not replace a release in search. The
+	// prerelease is inserted first, so that both are in search_documents.
+	for _, m := range []*internal.Module{
+		sample.Module("deleteme.com", "v1.1.0-pre", "prerelease"),
+		sample.Module("deleteme.com", "v1.0.0", "release"),
+	} {
+		if err := testDB.InsertModule(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := testDB.DeleteOlderVersionsFromSearchDocuments(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, version, found := GetFromSearchDocuments(ctx, t, testDB, "deleteme.com/release"); !found || version != "v1.0.0" {
+		t.Errorf("release: got (%q, %t), want (v1.0.0, true)", version, found)
+	}
+	if _, _, found := GetFromSearchDocuments(ctx, t, testDB, "deleteme.com/prerelease"); found {
+		t.Error("prerelease: found in search_documents, want deleted")
+	}
+}
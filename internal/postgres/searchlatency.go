@@ -0,0 +1,105 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// minSearchTimeout and maxSearchTimeout bound the timeout of
+	// hedgedSearch. Before any search latency has been recorded, the timeout
+	// is maxSearchTimeout.
+	minSearchTimeout = 1 * time.Second
+	maxSearchTimeout = 10 * time.Second
+
+	// searchTimeoutMultiplier is the multiple of the 95th percentile of
+	// search latency that a search may take before it times out. A search
+	// that runs past the 95th percentile is slow, but is usually not stuck,
+	// so it is given some room to finish.
+	searchTimeoutMultiplier = 2.5
+
+	// searchLatencyWeight is the weight of each new sample in the moving
+	// averages of a latencyTracker.
+	searchLatencyWeight = 0.1
+
+	// z95 is the 95th percentile of the standard normal distribution.
+	z95 = 1.645
+)
+
+// A latencyTracker estimates the 95th percentile of a latency from
+// exponentially weighted moving averages of its mean and variance, assuming
+// it is roughly normally distributed. A nil *latencyTracker records nothing.
+type latencyTracker struct {
+	mu       sync.Mutex
+	n        int     // number of samples recorded
+	mean     float64 // in seconds
+	variance float64 // in seconds squared
+}
+
+// record adds the latency d to the moving averages.
+func (t *latencyTracker) record(d time.Duration) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	x := d.Seconds()
+	if t.n == 0 {
+		t.mean = x
+		t.variance = 0
+	} else {
+		// See "Incremental calculation of weighted mean and variance" by
+		// Tony Finch.
+		diff := x - t.mean
+		incr := searchLatencyWeight * diff
+		t.mean += incr
+		t.variance = (1 - searchLatencyWeight) * (t.variance + diff*incr)
+	}
+	t.n++
+}
+
+// p95 returns the estimated 95th percentile of the recorded latencies, or 0
+// if none were recorded.
+func (t *latencyTracker) p95() time.Duration {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.n == 0 {
+		return 0
+	}
+	p := t.mean + z95*math.Sqrt(t.variance)
+	return time.Duration(p * float64(time.Second))
+}
+
+// timeout returns searchTimeoutMultiplier times the estimated 95th
+// percentile of the recorded latencies, clamped to [minSearchTimeout,
+// maxSearchTimeout], or maxSearchTimeout if none were recorded.
+func (t *latencyTracker) timeout() time.Duration {
+	p := t.p95()
+	if p == 0 {
+		return maxSearchTimeout
+	}
+	d := time.Duration(float64(p) * searchTimeoutMultiplier)
+	switch {
+	case d > maxSearchTimeout:
+		return maxSearchTimeout
+	case d < minSearchTimeout:
+		return minSearchTimeout
+	default:
+		return d
+	}
+}
+
+// SearchP95Latency returns the estimated 95th percentile of the latency of
+// recent searches, or 0 if there were none. The timeout of each search is
+// derived from it.
+func (db *DB) SearchP95Latency() time.Duration {
+	return db.searchLatency.p95()
+}
@@ -0,0 +1,70 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveTimeout(t *testing.T) {
+	var tracker latencyTracker
+	if got := tracker.timeout(); got != maxSearchTimeout {
+		t.Errorf("timeout with no samples = %v, want %v", got, maxSearchTimeout)
+	}
+
+	// Search latencies are uniformly distributed between 0.5s and 2.5s, so
+	// their P95 is 2.4s, and the timeout is 2.5 times that.
+	const (
+		low         = 500 * time.Millisecond
+		high        = 2500 * time.Millisecond
+		wantP95     = 2400 * time.Millisecond
+		wantTimeout = 6 * time.Second
+		maxError    = wantTimeout / 5
+	)
+	r := rand.New(rand.NewSource(1))
+	for i := 1; i <= 100; i++ {
+		tracker.record(low + time.Duration(r.Int63n(int64(high-low))))
+		if i < 50 {
+			continue
+		}
+		p95 := tracker.p95()
+		if diff := p95 - wantP95; diff > maxError/2 || diff < -maxError/2 {
+			t.Errorf("after %d searches: p95 = %v, want within %v of %v", i, p95, maxError/2, wantP95)
+		}
+		got := tracker.timeout()
+		if diff := got - wantTimeout; diff > maxError || diff < -maxError {
+			t.Errorf("after %d searches: timeout = %v, want within %v of %v", i, got, maxError, wantTimeout)
+		}
+	}
+
+	// The timeout is clamped.
+	for _, test := range []struct {
+		latencies []time.Duration
+		want      time.Duration
+	}{
+		{[]time.Duration{10 * time.Millisecond, 20 * time.Millisecond}, minSearchTimeout},
+		{[]time.Duration{300 * time.Millisecond}, minSearchTimeout},
+		{[]time.Duration{2 * time.Second}, 5 * time.Second},
+		{[]time.Duration{5 * time.Second}, maxSearchTimeout},
+		{[]time.Duration{time.Minute, 2 * time.Minute}, maxSearchTimeout},
+	} {
+		var tracker latencyTracker
+		for _, l := range test.latencies {
+			tracker.record(l)
+		}
+		if got := tracker.timeout(); got != test.want {
+			t.Errorf("timeout after %v = %v, want %v", test.latencies, got, test.want)
+		}
+	}
+
+	// A nil tracker is usable.
+	var nilTracker *latencyTracker
+	nilTracker.record(time.Second)
+	if got := nilTracker.timeout(); got != maxSearchTimeout {
+		t.Errorf("nil tracker: timeout = %v, want %v", got, maxSearchTimeout)
+	}
+}
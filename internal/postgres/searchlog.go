@@ -0,0 +1,145 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/log"
+	"golang.org/x/pkgsite/internal/xcontext"
+)
+
+// keySearchQueryLogDropped counts searches that were not recorded because
+// the queue of searches waiting to be recorded was full.
+var keySearchQueryLogDropped = stats.Int64(
+	"go-discovery/search/query-log-dropped",
+	"Count of searches not recorded by the search query logger.",
+	stats.UnitDimensionless,
+)
+
+// SearchQueryLogDroppedCount counts searches that were not recorded because
+// too many searches were waiting to be recorded.
+var SearchQueryLogDroppedCount = &view.View{
+	Name:        "go-discovery/search/query-log-dropped-count",
+	Measure:     keySearchQueryLogDropped,
+	Aggregation: view.Count(),
+	Description: "Count of searches dropped from the search query log.",
+}
+
+// A SearchLogEntry describes a search, without the text of its query.
+type SearchLogEntry struct {
+	// QueryHash is the hex-encoded HMAC-SHA256 of the normalized query. See
+	// HashSearchQuery.
+	QueryHash string
+	// NumResults is the total number of results, which may be estimated.
+	NumResults int
+	// Source is the search method whose results were returned, like
+	// "popular" or "deep".
+	Source     string
+	DurationMs int64
+	// ZeroResults reports whether the search had no results.
+	ZeroResults bool
+}
+
+// A SearchQueryLogger records the searches made by DB.Search, for analytics.
+type SearchQueryLogger interface {
+	Log(ctx context.Context, entry SearchLogEntry) error
+}
+
+// HashSearchQuery returns the hex-encoded HMAC-SHA256 of q with key. q should
+// be the String of a query parsed by search.ParseSearchQuery. Unlike a plain
+// hash, the HMAC of a common query cannot be computed by someone who does not
+// know key, so the log does not reveal which queries were made.
+func HashSearchQuery(key []byte, q string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(q))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// A PostgresSearchQueryLogger is a SearchQueryLogger that records searches in
+// the search_query_log table.
+type PostgresSearchQueryLogger struct {
+	db *DB
+}
+
+// NewPostgresSearchQueryLogger returns a PostgresSearchQueryLogger that writes
+// to db.
+func NewPostgresSearchQueryLogger(db *DB) *PostgresSearchQueryLogger {
+	return &PostgresSearchQueryLogger{db: db}
+}
+
+// Log inserts entry into search_query_log.
+func (l *PostgresSearchQueryLogger) Log(ctx context.Context, entry SearchLogEntry) (err error) {
+	defer derrors.Wrap(&err, "PostgresSearchQueryLogger.Log(ctx, %q)", entry.QueryHash)
+
+	_, err = l.db.db.Exec(ctx, `
+		INSERT INTO search_query_log (query_hash, num_results, source, duration_ms, zero_results)
+		VALUES ($1, $2, $3, $4, $5)`,
+		entry.QueryHash, entry.NumResults, entry.Source, entry.DurationMs, entry.ZeroResults)
+	return err
+}
+
+const (
+	// searchQueryLogQueueSize is the number of searches that can wait to be
+	// recorded by the SearchQueryLogger of a DB.
+	searchQueryLogQueueSize = 1000
+
+	// searchQueryLogTimeout is the time allowed for recording a search with
+	// the SearchQueryLogger of a DB.
+	searchQueryLogTimeout = 5 * time.Second
+)
+
+// A searchLogQueue holds the searches waiting to be recorded by a
+// SearchQueryLogger. They are recorded one at a time by a single goroutine,
+// started by the first call to add, so that a slow logger delays the log
+// instead of accumulating goroutines.
+type searchLogQueue struct {
+	once    sync.Once
+	entries chan queuedSearchLogEntry
+}
+
+// A queuedSearchLogEntry is an entry waiting in a searchLogQueue, with the
+// logger that records it and the context of its search, detached from the
+// deadline and cancellation of the search.
+type queuedSearchLogEntry struct {
+	ctx    context.Context
+	logger SearchQueryLogger
+	entry  SearchLogEntry
+}
+
+func newSearchLogQueue(size int) *searchLogQueue {
+	return &searchLogQueue{entries: make(chan queuedSearchLogEntry, size)}
+}
+
+// add queues entry to be recorded by logger, with the values of ctx. It
+// reports false, and drops entry, if the queue is full.
+func (q *searchLogQueue) add(ctx context.Context, logger SearchQueryLogger, entry SearchLogEntry) bool {
+	q.once.Do(func() { go q.write() })
+	select {
+	case q.entries <- queuedSearchLogEntry{ctx: xcontext.Detach(ctx), logger: logger, entry: entry}:
+		return true
+	default:
+		return false
+	}
+}
+
+// write records the queued entries in order. It never returns.
+func (q *searchLogQueue) write() {
+	for e := range q.entries {
+		ctx, cancel := context.WithTimeout(e.ctx, searchQueryLogTimeout)
+		if err := e.logger.Log(ctx, e.entry); err != nil {
+			log.Errorf(ctx, "logging search query: %v", err)
+		}
+		cancel()
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"golang.org/x/pkgsite/internal/testing/sample"
+)
+
+// chanSearchQueryLogger is a SearchQueryLogger that sends the entries it logs
+// on a channel.
+type chanSearchQueryLogger chan SearchLogEntry
+
+func (l chanSearchQueryLogger) Log(ctx context.Context, entry SearchLogEntry) error {
+	l <- entry
+	return nil
+}
+
+// funcSearchQueryLogger is a SearchQueryLogger that calls a function.
+type funcSearchQueryLogger func(ctx context.Context, entry SearchLogEntry) error
+
+func (f funcSearchQueryLogger) Log(ctx context.Context, entry SearchLogEntry) error {
+	return f(ctx, entry)
+}
+
+func TestSearchLogQueueDropsWhenFull(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	var (
+		started = make(chan struct{}, 1)
+		release = make(chan struct{})
+		logged  = make(chan SearchLogEntry, 3)
+	)
+	logger := funcSearchQueryLogger(func(ctx context.Context, entry SearchLogEntry) error {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		logged <- entry
+		return nil
+	})
+	q := newSearchLogQueue(1)
+	entry := func(h string) SearchLogEntry { return SearchLogEntry{QueryHash: h} }
+
+	// The writer takes the first entry and blocks, so the second one fills
+	// the queue and the third one is dropped.
+	if !q.add(ctx, logger, entry("a")) {
+		t.Fatal("first entry was dropped")
+	}
+	select {
+	case <-started:
+	case <-ctx.Done():
+		t.Fatal("first entry was not logged")
+	}
+	if !q.add(ctx, logger, entry("b")) {
+		t.Fatal("second entry was dropped")
+	}
+	if q.add(ctx, logger, entry("c")) {
+		t.Fatal("third entry was queued, want it dropped")
+	}
+	close(release)
+
+	var got []SearchLogEntry
+	for len(got) < 2 {
+		select {
+		case e := <-logged:
+			got = append(got, e)
+		case <-ctx.Done():
+			t.Fatalf("got %d entries, want 2", len(got))
+		}
+	}
+	if diff := cmp.Diff([]SearchLogEntry{entry("a"), entry("b")}, got); diff != "" {
+		t.Errorf("logged entries mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSearchQueryLogging(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+	defer func() {
+		testDB.SearchQueryLogger = nil
+		testDB.SearchQueryLogKey = nil
+	}()
+
+	if err := testDB.InsertModule(ctx, sample.Module("example.com/json", sample.VersionString, "parser")); err != nil {
+		t.Fatal(err)
+	}
+	entries := make(chanSearchQueryLogger, 2)
+	testDB.SearchQueryLogger = entries
+	testDB.SearchQueryLogKey = []byte("key")
+	var got []SearchLogEntry
+	for _, q := range []string{"  JSON   Parser ", "nothing"} {
+		if _, err := testDB.Search(ctx, q, SearchOptions{Limit: 10}); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case e := <-entries:
+			got = append(got, e)
+		case <-ctx.Done():
+			t.Fatalf("search %q was not logged", q)
+		}
+	}
+
+	want := []SearchLogEntry{
+		{QueryHash: HashSearchQuery([]byte("key"), "json parser"), NumResults: 1},
+		{QueryHash: HashSearchQuery([]byte("key"), "nothing"), ZeroResults: true},
+	}
+	// The source and duration depend on which search finished first.
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(SearchLogEntry{}, "Source", "DurationMs")); diff != "" {
+		t.Errorf("logged entries mismatch (-want +got):\n%s", diff)
+	}
+	for _, e := range got {
+		if _, ok := searchers[e.Source]; !ok {
+			t.Errorf("got source %q, want one of the searchers", e.Source)
+		}
+	}
+}
+
+func TestPostgresSearchQueryLogger(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	want := []SearchLogEntry{
+		{QueryHash: HashSearchQuery([]byte("key"), "json parser"), NumResults: 1, Source: "deep", DurationMs: 12},
+		{QueryHash: HashSearchQuery([]byte("key"), "nothing"), Source: "popular", DurationMs: 3, ZeroResults: true},
+	}
+	l := NewPostgresSearchQueryLogger(testDB)
+	for _, e := range want {
+		if err := l.Log(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []SearchLogEntry
+	rows, err := testDB.db.Query(ctx, `
+		SELECT query_hash, num_results, source, duration_ms, zero_results
+		FROM search_query_log
+		ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var e SearchLogEntry
+		if err := rows.Scan(&e.QueryHash, &e.NumResults, &e.Source, &e.DurationMs, &e.ZeroResults); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, e)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("search_query_log mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestHashSearchQuery(t *testing.T) {
+	mac := hmac.New(sha256.New, []byte("key"))
+	mac.Write([]byte("json parser"))
+	want := hex.EncodeToString(mac.Sum(nil))
+	if got := HashSearchQuery([]byte("key"), "json parser"); got != want {
+		t.Errorf("HashSearchQuery = %q, want %q", got, want)
+	}
+	// Without the key, the hash cannot be recomputed.
+	if got := HashSearchQuery([]byte("other key"), "json parser"); got == want {
+		t.Errorf("HashSearchQuery with another key = %q, want a different hash", got)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+// RankingParams returns the names of the search ranking parameters that can be
+// set in DB.ExperimentConfig, in sorted order.
+func RankingParams() []string {
+	var names []string
+	for name := range rankingDefaults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ExperimentBuckets returns the experiment buckets named in cfg, a
+// DB.ExperimentConfig, in sorted order: the prefixes of its keys of the form
+// "bucket.name".
+func ExperimentBuckets(cfg map[string]float64) []string {
+	set := map[string]bool{}
+	for key := range cfg {
+		if i := strings.LastIndexByte(key, '.'); i >= 0 {
+			set[key[:i]] = true
+		}
+	}
+	var buckets []string
+	for b := range set {
+		buckets = append(buckets, b)
+	}
+	sort.Strings(buckets)
+	return buckets
+}
+
+// ValidateExperimentConfig returns an error wrapping derrors.InvalidArgument
+// if a key of cfg does not name a ranking parameter, optionally prefixed by a
+// bucket, or if a value is not between 0 and 1.
+func ValidateExperimentConfig(cfg map[string]float64) error {
+	for key, v := range cfg {
+		name := key
+		if i := strings.LastIndexByte(key, '.'); i >= 0 {
+			name = key[i+1:]
+		}
+		if _, ok := rankingDefaults[name]; !ok {
+			return fmt.Errorf("unknown ranking parameter %q in %q (supported parameters are %s): %w",
+				name, key, strings.Join(RankingParams(), ", "), derrors.InvalidArgument)
+		}
+		if v < 0 || v > 1 {
+			return fmt.Errorf("value of %q is %g, want a value between 0 and 1: %w", key, v, derrors.InvalidArgument)
+		}
+	}
+	return nil
+}
+
+// LoadExperimentConfig reads a DB.ExperimentConfig from the YAML file at path,
+// which maps keys to values, like
+//
+//	noGoModPenalty: 0.8
+//	lowgomod.noGoModPenalty: 0.5
+//
+// and validates it with ValidateExperimentConfig.
+func LoadExperimentConfig(path string) (_ map[string]float64, err error) {
+	defer derrors.Wrap(&err, "LoadExperimentConfig(%q)", path)
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg map[string]float64
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	if err := ValidateExperimentConfig(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// rankingParam returns the value of the ranking parameter name for searches
+// in bucket: the value of "bucket.name" in db.ExperimentConfig, or else of
+// "name", or else its default.
+func (db *DB) rankingParam(bucket, name string) float64 {
+	if bucket != "" {
+		if v, ok := db.ExperimentConfig[bucket+"."+name]; ok {
+			return v
+		}
+	}
+	if v, ok := db.ExperimentConfig[name]; ok {
+		return v
+	}
+	return rankingDefaults[name]
+}
+
+// scoreExpr returns the expression that computes the score of the search sp,
+// given the expression for the relevance of a document. See newScoreExpr.
+func (db *DB) scoreExpr(sp searchParams, relevance string) string {
+	return newScoreExpr(relevance, func(name string) float64 {
+		return db.rankingParam(sp.bucket, name)
+	})
+}
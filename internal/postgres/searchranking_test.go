@@ -0,0 +1,119 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/experiment"
+	"golang.org/x/pkgsite/internal/testing/sample"
+)
+
+func TestSearchRankingExperiment(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+	defer func() { testDB.ExperimentConfig = nil }()
+
+	// The modules have the same text ranking for "foo", and differ only by
+	// their go.mod files.
+	for path, hasGoMod := range map[string]bool{"gomod.com/foo": true, "nogomod.com/foo": false} {
+		m := sample.Module(path, sample.VersionString, "p")
+		m.HasGoMod = hasGoMod
+		if err := testDB.InsertModule(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	testDB.ExperimentConfig = map[string]float64{
+		"a.noGoModPenalty": 0.5,
+		"b.noGoModPenalty": 0.25,
+	}
+	for _, test := range []struct {
+		bucket string
+		want   float64
+	}{
+		{"", noGoModPenalty},
+		{"a", 0.5},
+		{"b", 0.25},
+	} {
+		results, err := testDB.Search(experiment.NewBucketContext(ctx, test.bucket), "foo", SearchOptions{Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		scores := map[string]float64{}
+		for _, r := range results {
+			scores[r.ModulePath] = r.Score
+		}
+		if len(scores) != 2 {
+			t.Fatalf("bucket %q: got scores %v, want two", test.bucket, scores)
+		}
+		if got := scores["nogomod.com/foo"] / scores["gomod.com/foo"]; math.Abs(got-test.want) > 1e-6 {
+			t.Errorf("bucket %q: got score ratio %f, want %f", test.bucket, got, test.want)
+		}
+	}
+}
+
+func TestRankingParam(t *testing.T) {
+	db := &DB{ExperimentConfig: map[string]float64{
+		"noGoModPenalty":   0.7,
+		"a.noGoModPenalty": 0.5,
+	}}
+	for _, test := range []struct {
+		bucket, name string
+		want         float64
+	}{
+		{"", "noGoModPenalty", 0.7},
+		{"a", "noGoModPenalty", 0.5},
+		{"b", "noGoModPenalty", 0.7},
+		{"a", "retractedPenalty", retractedPenalty},
+	} {
+		if got := db.rankingParam(test.bucket, test.name); got != test.want {
+			t.Errorf("rankingParam(%q, %q) = %g, want %g", test.bucket, test.name, got, test.want)
+		}
+	}
+	if got, want := (&DB{}).scoreExpr(searchParams{}, searchRank), scoreExpr; got != want {
+		t.Errorf("without a config, got score expression\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestExperimentBuckets(t *testing.T) {
+	got := ExperimentBuckets(map[string]float64{
+		"noGoModPenalty":               0.8,
+		"b.deprecatedPenalty":          0,
+		"a.noGoModPenalty":             0.5,
+		"a.retractedPenalty":           0.5,
+		"my.bucket.healthScorePenalty": 1,
+	})
+	want := []string{"a", "b", "my.bucket"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestValidateExperimentConfig(t *testing.T) {
+	if err := ValidateExperimentConfig(map[string]float64{
+		"noGoModPenalty":                   0.8,
+		"bucket.deprecatedPenalty":         0,
+		"my.bucket.healthScorePenalty":     1,
+		"bucket.nonRedistributablePenalty": 0.25,
+	}); err != nil {
+		t.Errorf("got %v, want nil", err)
+	}
+	for _, cfg := range []map[string]float64{
+		{"popularityBoost": 0.5},
+		{"bucket.noGoMod": 0.5},
+		{"noGoModPenalty": 1.5},
+		{"bucket.retractedPenalty": -0.1},
+	} {
+		if err := ValidateExperimentConfig(cfg); !errors.Is(err, derrors.InvalidArgument) {
+			t.Errorf("ValidateExperimentConfig(%v): got error %v, want InvalidArgument", cfg, err)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+// PrepareSearchStatements prepares the queries of the deep searches without
+// filters, so that Postgres plans them once instead of on every search, and
+// sets db.SearchUsesPreparedStatements. Searches with filters are not
+// prepared, because their queries vary with the filters, nor are searches in
+// experiment buckets with their own ranking parameters.
+//
+// It must be called before db is used concurrently. If it fails, searches
+// still work, with unprepared queries.
+func (db *DB) PrepareSearchStatements(ctx context.Context) (err error) {
+	defer derrors.Wrap(&err, "PrepareSearchStatements(ctx)")
+
+	db.closeSearchStatements()
+	// The parameters of a search without filters, so that the prepared
+	// queries are the ones that deepSearch builds for it.
+	sp := searchParams{filter: searchFilter(nil, SearchOptions{})}
+	stmts := map[string]*sql.Stmt{}
+	for _, query := range []string{
+		deepSearchQuery(db.scoreExpr(sp, searchRank), textMatch, sp.filterSQL()),
+		deepSearchQuery(db.scoreExpr(sp, fuzzySearchRank), fuzzyMatch, sp.filterSQL()),
+	} {
+		stmt, err := db.readDB().Prepare(ctx, query)
+		if err != nil {
+			for _, s := range stmts {
+				s.Close()
+			}
+			return err
+		}
+		stmts[query] = stmt
+	}
+	db.searchStmts = stmts
+	db.SearchUsesPreparedStatements = true
+	return nil
+}
+
+// runSearchQuery runs query on the read replica of db, like RunQuery. If
+// db.SearchUsesPreparedStatements is set and query was prepared by
+// PrepareSearchStatements, it executes the prepared statement instead.
+func (db *DB) runSearchQuery(ctx context.Context, query string, f func(*sql.Rows) error, params ...interface{}) error {
+	if stmt := db.searchStmts[query]; stmt != nil && db.SearchUsesPreparedStatements {
+		return db.readDB().RunStmt(ctx, stmt, query, f, params...)
+	}
+	return db.readDB().RunQuery(ctx, query, f, params...)
+}
+
+// closeSearchStatements closes the statements prepared by
+// PrepareSearchStatements.
+func (db *DB) closeSearchStatements() {
+	for _, stmt := range db.searchStmts {
+		stmt.Close()
+	}
+	db.searchStmts = nil
+	db.SearchUsesPreparedStatements = false
+}
@@ -0,0 +1,112 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal/testing/sample"
+)
+
+// insertSearchDocuments inserts n modules with one package each, all of
+// which match the query "synopsis".
+func insertSearchDocuments(ctx context.Context, tb testing.TB, db *DB, n int) {
+	tb.Helper()
+	for i := 0; i < n; i++ {
+		m := sample.Module(fmt.Sprintf("example.com/mod%d", i), sample.VersionString, "pkg")
+		if err := db.InsertModule(ctx, m); err != nil {
+			tb.Fatal(err)
+		}
+	}
+}
+
+func TestPrepareSearchStatements(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+	defer testDB.closeSearchStatements()
+	defer func(fuzzy bool) { testDB.FuzzySearchEnabled = fuzzy }(testDB.FuzzySearchEnabled)
+
+	insertSearchDocuments(ctx, t, testDB, 5)
+	search := func() []string {
+		t.Helper()
+		rs, err := testDB.Search(ctx, "synopsis", SearchOptions{Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var paths []string
+		for _, r := range rs {
+			paths = append(paths, r.PackagePath)
+		}
+		return paths
+	}
+
+	for _, fuzzy := range []bool{false, true} {
+		t.Run(fmt.Sprintf("fuzzy=%t", fuzzy), func(t *testing.T) {
+			testDB.FuzzySearchEnabled = fuzzy
+			testDB.closeSearchStatements()
+			want := search()
+			if len(want) != 5 {
+				t.Fatalf("got %d results, want 5", len(want))
+			}
+			if err := testDB.PrepareSearchStatements(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if !testDB.SearchUsesPreparedStatements {
+				t.Fatal("SearchUsesPreparedStatements is false after PrepareSearchStatements")
+			}
+			if diff := cmp.Diff(want, search()); diff != "" {
+				t.Errorf("prepared search mismatch (-unprepared +prepared):\n%s", diff)
+			}
+
+			// A search without filters runs a prepared statement: once the
+			// statements are closed, deepSearch fails.
+			for _, stmt := range testDB.searchStmts {
+				stmt.Close()
+			}
+			sp := searchParams{q: "synopsis", limit: 10, filter: searchFilter(nil, SearchOptions{})}
+			if resp := testDB.deepSearch(ctx, sp); resp.err == nil {
+				t.Error("deepSearch succeeded with closed prepared statements, want it to use them")
+			}
+		})
+	}
+}
+
+// BenchmarkDeepSearchPrepared and BenchmarkDeepSearchUnprepared compare the
+// latency of deepSearch with and without prepared statements. Run them with
+// -benchtime=1000x to compare 1000 searches each.
+func BenchmarkDeepSearchPrepared(b *testing.B) {
+	benchmarkDeepSearch(b, true)
+}
+
+func BenchmarkDeepSearchUnprepared(b *testing.B) {
+	benchmarkDeepSearch(b, false)
+}
+
+func benchmarkDeepSearch(b *testing.B, prepared bool) {
+	if testDB == nil {
+		b.Skip("no test database")
+	}
+	ctx := context.Background()
+	defer ResetTestDB(testDB, b)
+	defer testDB.closeSearchStatements()
+
+	insertSearchDocuments(ctx, b, testDB, 100)
+	if prepared {
+		if err := testDB.PrepareSearchStatements(ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+	sp := searchParams{q: "synopsis", limit: 10}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if resp := testDB.deepSearch(ctx, sp); resp.err != nil {
+			b.Fatal(resp.err)
+		}
+	}
+}
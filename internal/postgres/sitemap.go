@@ -0,0 +1,58 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+// GetPackagePathsForSitemap returns up to limit paths of redistributable
+// packages, in path order, starting after the first offset paths. Only the
+// latest version of each package is considered.
+func (db *DB) GetPackagePathsForSitemap(ctx context.Context, offset, limit int) (_ []string, err error) {
+	defer derrors.Wrap(&err, "GetPackagePathsForSitemap(ctx, %d, %d)", offset, limit)
+
+	if offset < 0 || limit <= 0 {
+		return nil, fmt.Errorf("offset must be non-negative and limit positive: %w", derrors.InvalidArgument)
+	}
+	query := `
+		SELECT package_path
+		FROM search_documents
+		WHERE redistributable
+		ORDER BY package_path
+		OFFSET $1
+		LIMIT $2`
+
+	var paths []string
+	collect := func(rows *sql.Rows) error {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return fmt.Errorf("rows.Scan(): %v", err)
+		}
+		paths = append(paths, path)
+		return nil
+	}
+	if err := db.db.RunQuery(ctx, query, collect, offset, limit); err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// GetSitemapPackageCount returns the number of package paths that
+// GetPackagePathsForSitemap can return.
+func (db *DB) GetSitemapPackageCount(ctx context.Context) (_ int, err error) {
+	defer derrors.Wrap(&err, "GetSitemapPackageCount(ctx)")
+
+	var count int
+	query := `SELECT COUNT(*) FROM search_documents WHERE redistributable`
+	if err := db.db.QueryRow(ctx, query).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
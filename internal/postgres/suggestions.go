@@ -0,0 +1,151 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opencensus.io/plugin/ochttp"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+var (
+	// keySuggestionLatency holds observed latency in search suggestion queries.
+	keySuggestionLatency = stats.Float64(
+		"go-discovery/search/suggestion-latency",
+		"Latency of a search suggestion query.",
+		stats.UnitMilliseconds,
+	)
+	// SearchSuggestionLatencyDistribution aggregates search suggestion latency.
+	SearchSuggestionLatencyDistribution = &view.View{
+		Name:        "go-discovery/search/suggestion-latency",
+		Measure:     keySuggestionLatency,
+		Aggregation: ochttp.DefaultLatencyDistribution,
+		Description: "Search suggestion latency.",
+	}
+)
+
+// GetSearchSuggestions returns up to limit packages whose path, a path
+// element, or name starts with prefix. The match is case-insensitive.
+//
+// Suggestions are ranked by the trigram similarity of the package path or
+// name to prefix, weighted by popularity in the same way as search results.
+// The trigram indexes on search_documents make the prefix match fast: the
+// gin_trgm_ops operator class of pg_trgm supports ILIKE patterns, including
+// those with a leading wildcard, since PostgreSQL 9.1 (pg_trgm 1.0).
+func (db *DB) GetSearchSuggestions(ctx context.Context, prefix string, limit int) (_ []*internal.SearchSuggestion, err error) {
+	defer derrors.Wrap(&err, "GetSearchSuggestions(ctx, %q, %d)", prefix, limit)
+
+	if prefix == "" {
+		return nil, nil
+	}
+	start := time.Now()
+	defer func() {
+		if err == nil {
+			latency := float64(time.Since(start)) / float64(time.Millisecond)
+			stats.Record(ctx, keySuggestionLatency.M(latency))
+		}
+	}()
+
+	query := `
+		SELECT
+			package_path,
+			synopsis,
+			GREATEST(similarity(package_path, $1), similarity(name, $1)) *
+				ln(exp(1)+imported_by_count) AS score
+		FROM search_documents
+		WHERE
+			package_path ILIKE $2 || '%'
+			OR package_path ILIKE '%/' || $2 || '%'
+			OR name ILIKE $2 || '%'
+		ORDER BY score DESC, package_path
+		LIMIT $3`
+	var suggestions []*internal.SearchSuggestion
+	collect := func(rows *sql.Rows) error {
+		var (
+			s        internal.SearchSuggestion
+			synopsis sql.NullString
+		)
+		if err := rows.Scan(&s.PackagePath, &synopsis, &s.Score); err != nil {
+			return fmt.Errorf("rows.Scan(): %v", err)
+		}
+		s.Synopsis = synopsis.String
+		suggestions = append(suggestions, &s)
+		return nil
+	}
+	if err := db.readDB().RunQuery(ctx, query, collect, prefix, escapeLikePattern(prefix), limit); err != nil {
+		return nil, err
+	}
+	return suggestions, nil
+}
+
+// SuggestAlternativeQuery returns a query similar to q that is more likely
+// to have results, or the empty string if it has no suggestion. It is meant
+// to be called when a search for q returns nothing.
+//
+// Each word of q that is not a stop word and does not match any search
+// document is replaced by the package name that is most similar to it by
+// trigram similarity, preferring more popular packages. Field filters and
+// quoted phrases are left as they are.
+func (db *DB) SuggestAlternativeQuery(ctx context.Context, q string) (_ string, err error) {
+	defer derrors.Wrap(&err, "SuggestAlternativeQuery(ctx, %q)", q)
+
+	// ts_lexize returns an empty array for a stop word, such as "the", which
+	// never matches documents and so needs no alternative.
+	query := `
+		SELECT
+			COALESCE(ts_lexize('english_stem', lower($1)) = '{}', false),
+			EXISTS (
+				SELECT 1 FROM search_documents
+				WHERE tsv_search_tokens @@ plainto_tsquery($1)
+			),
+			(
+				SELECT name FROM search_documents
+				WHERE name % $1
+				ORDER BY similarity(name, $1) DESC, imported_by_count DESC, name
+				LIMIT 1
+			)`
+	var (
+		words   = strings.Fields(q)
+		changed bool
+	)
+	for i, w := range words {
+		if strings.ContainsAny(w, `":`) {
+			continue
+		}
+		var (
+			stopWord, found bool
+			alt             sql.NullString
+		)
+		if err := db.readDB().QueryRow(ctx, query, w).Scan(&stopWord, &found, &alt); err != nil {
+			return "", err
+		}
+		if stopWord || found || !alt.Valid || strings.EqualFold(alt.String, w) {
+			continue
+		}
+		words[i] = alt.String
+		changed = true
+	}
+	if !changed {
+		return "", nil
+	}
+	return strings.Join(words, " "), nil
+}
+
+// likeEscaper escapes the characters that are special in a LIKE pattern.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLikePattern returns s with its LIKE wildcards escaped, so that it
+// matches only itself when used in a LIKE pattern.
+func escapeLikePattern(s string) string {
+	return likeEscaper.Replace(s)
+}
@@ -0,0 +1,163 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal/database"
+	"golang.org/x/pkgsite/internal/testing/sample"
+)
+
+func TestGetSearchSuggestions(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	for _, m := range importGraph("foo.com/popular", "bar.com/foo", 2) {
+		if err := testDB.InsertModule(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := testDB.UpdateSearchDocumentsImportedByCount(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		prefix string
+		limit  int
+		want   []string
+	}{
+		{"foo.com", 5, []string{"foo.com/popular"}},
+		{"FOO.COM/pop", 5, []string{"foo.com/popular"}},
+		{"importer", 5, []string{"bar.com/foo/importer0", "bar.com/foo/importer1"}},
+		{"bar.com", 1, []string{"bar.com/foo/importer0"}},
+		{"%", 5, nil},
+		{"", 5, nil},
+	} {
+		t.Run(test.prefix, func(t *testing.T) {
+			suggestions, err := testDB.GetSearchSuggestions(ctx, test.prefix, test.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, s := range suggestions {
+				got = append(got, s.PackagePath)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("GetSearchSuggestions(%q, %d) mismatch (-want +got):\n%s", test.prefix, test.limit, diff)
+			}
+		})
+	}
+}
+
+func TestSuggestAlternativeQuery(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	for _, path := range []string{
+		"github.com/kubernetes/kubernetes",
+		"github.com/sirupsen/logrus",
+		"github.com/spf13/cobra",
+	} {
+		if err := testDB.InsertModule(ctx, sample.Module(path, sample.VersionString, "")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, test := range []struct {
+		q, want string
+	}{
+		{"kubrenetes", "kubernetes"},
+		{"logrsu", "logrus"},
+		{"the kubrenetes", "the kubernetes"},
+		{"cobra logrsu", "cobra logrus"},
+		{"license:MIT kubrenetes", "license:MIT kubernetes"},
+		{"cobra", ""},
+		{"zzzzzzzz", ""},
+	} {
+		t.Run(test.q, func(t *testing.T) {
+			got, err := testDB.SuggestAlternativeQuery(ctx, test.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("SuggestAlternativeQuery(%q) = %q, want %q", test.q, got, test.want)
+			}
+		})
+	}
+}
+
+func TestEscapeLikePattern(t *testing.T) {
+	for _, test := range []struct {
+		in, want string
+	}{
+		{"golang.org/x/net", "golang.org/x/net"},
+		{"100%", `100\%`},
+		{"foo_bar", `foo\_bar`},
+		{`a\b`, `a\\b`},
+	} {
+		if got := escapeLikePattern(test.in); got != test.want {
+			t.Errorf("escapeLikePattern(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
+// BenchmarkGetSearchSuggestions compares the latency of GetSearchSuggestions
+// over 10,000 packages using the trigram indexes of search_documents with
+// that of a sequential scan.
+func BenchmarkGetSearchSuggestions(b *testing.B) {
+	if testDB == nil {
+		b.Skip("no test database")
+	}
+	ctx := context.Background()
+	defer ResetTestDB(testDB, b)
+
+	var suffixes []string
+	for i := 0; i < 100; i++ {
+		suffixes = append(suffixes, fmt.Sprintf("pkg%d", i))
+	}
+	for i := 0; i < 100; i++ {
+		m := sample.Module(fmt.Sprintf("example.com/mod%d", i), sample.VersionString, suffixes...)
+		if err := testDB.InsertModule(ctx, m); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if _, err := testDB.db.Exec(ctx, "ANALYZE search_documents"); err != nil {
+		b.Fatal(err)
+	}
+
+	for _, bm := range []struct {
+		name     string
+		settings []string
+	}{
+		{"index", nil},
+		{"seqscan", []string{"SET LOCAL enable_bitmapscan = off", "SET LOCAL enable_indexscan = off"}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			err := testDB.db.Transact(ctx, sql.LevelDefault, func(tx *database.DB) error {
+				for _, s := range bm.settings {
+					if _, err := tx.Exec(ctx, s); err != nil {
+						return err
+					}
+				}
+				db := New(tx)
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if _, err := db.GetSearchSuggestions(ctx, "example.com/mod42/pkg", 10); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"go.opencensus.io/trace"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/database"
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+// SearchSymbols returns the packages that declare an exported symbol whose
+// name is name or starts with name, ordered by the symbol name and package
+// popularity. Exact matches are always returned before prefix matches.
+// The name of a method is qualified by its receiver type, so "Buffer.W"
+// matches the Write method of Buffer.
+//
+// If kind is non-empty, only symbols of that kind are returned.
+func (db *DB) SearchSymbols(ctx context.Context, name string, kind internal.SymbolKind, limit, offset int) (_ []*internal.SearchResult, err error) {
+	defer derrors.Wrap(&err, "SearchSymbols(ctx, %q, %q, %d, %d)", name, kind, limit, offset)
+
+	if name == "" {
+		return nil, fmt.Errorf("empty name: %w", derrors.InvalidArgument)
+	}
+	if limit < 0 || offset < 0 {
+		return nil, fmt.Errorf("limit and offset must be non-negative: %w", derrors.InvalidArgument)
+	}
+	if kind != "" {
+		if _, ok := internal.ParseSymbolKind(string(kind)); !ok {
+			return nil, fmt.Errorf("unknown symbol kind %q: %w", kind, derrors.InvalidArgument)
+		}
+	}
+	query := `
+		SELECT
+			sd.package_path,
+			sd.module_path,
+			sd.version,
+			sd.name,
+			sd.synopsis,
+			sd.license_types,
+			sd.commit_time,
+			sd.imported_by_count,
+			s.symbol_name,
+			s.symbol_kind,
+			COUNT(*) OVER() AS total
+		FROM symbol_search_documents s
+		INNER JOIN search_documents sd
+		USING (package_path)
+		WHERE (s.symbol_name = $1 OR s.symbol_name LIKE $2)
+		AND ($3 = '' OR s.symbol_kind = $3)
+		ORDER BY
+			s.symbol_name = $1 DESC,
+			sd.imported_by_count DESC,
+			s.symbol_name,
+			sd.package_path
+		LIMIT $4
+		OFFSET $5`
+	var results []*internal.SearchResult
+	collect := func(rows *sql.Rows) error {
+		var r internal.SearchResult
+		if err := rows.Scan(&r.PackagePath, &r.ModulePath, &r.Version, &r.Name, &r.Synopsis,
+			pq.Array(&r.Licenses), &r.CommitTime, &r.NumImportedBy, &r.SymbolName, &r.SymbolKind,
+			&r.NumResults); err != nil {
+			return fmt.Errorf("rows.Scan(): %v", err)
+		}
+		results = append(results, &r)
+		return nil
+	}
+	if err := db.db.RunQuery(ctx, query, collect, name, escapeLikePattern(name)+"%", string(kind), limit, offset); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// upsertSymbolSearchDocuments replaces the rows of symbol_search_documents
+// for each package of mod with the package's exported symbols. Packages
+// whose search document belongs to a different module or version are left
+// alone.
+func upsertSymbolSearchDocuments(ctx context.Context, db *database.DB, mod *internal.Module) (err error) {
+	defer derrors.Wrap(&err, "upsertSymbolSearchDocuments(ctx, %q, %q)", mod.ModulePath, mod.Version)
+	ctx, span := trace.StartSpan(ctx, "upsertSymbolSearchDocuments")
+	defer span.End()
+
+	const (
+		ownedCond = `
+			EXISTS (
+				SELECT 1 FROM search_documents
+				WHERE package_path = $1 AND module_path = $2 AND version = $3
+			)`
+		deleteStmt = `DELETE FROM symbol_search_documents WHERE package_path = $1 AND` + ownedCond
+		insertStmt = `
+			INSERT INTO symbol_search_documents (package_path, symbol_name, symbol_kind, synopsis_vector)
+			SELECT $1, s.name, s.kind, to_tsvector('english', s.synopsis)
+			FROM UNNEST($4::text[], $5::text[], $6::text[]) AS s(name, kind, synopsis)
+			WHERE` + ownedCond
+	)
+	for _, pkg := range mod.LegacyPackages {
+		if isInternalPackage(pkg.Path) {
+			continue
+		}
+		if _, err := db.Exec(ctx, deleteStmt, pkg.Path, mod.ModulePath, mod.Version); err != nil {
+			return err
+		}
+		if len(pkg.Symbols) == 0 {
+			continue
+		}
+		var names, kinds, synopses []string
+		for _, s := range pkg.Symbols {
+			names = append(names, s.Name)
+			kinds = append(kinds, string(s.Kind))
+			synopses = append(synopses, makeValidUnicode(s.Synopsis))
+		}
+		if _, err := db.Exec(ctx, insertStmt, pkg.Path, mod.ModulePath, mod.Version,
+			pq.Array(names), pq.Array(kinds), pq.Array(synopses)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
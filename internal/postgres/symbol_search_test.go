@@ -0,0 +1,124 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/testing/sample"
+)
+
+func TestSearchSymbols(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	symbols := map[string][]*internal.Symbol{
+		"a.com/io": {
+			{Name: "Reader", Kind: internal.SymbolKindType, Synopsis: "Reader reads."},
+			{Name: "ReadAll", Kind: internal.SymbolKindFunction, Synopsis: "ReadAll reads everything."},
+		},
+		"b.com/bufio": {
+			{Name: "Reader", Kind: internal.SymbolKindType, Synopsis: "Reader is buffered."},
+			{Name: "Reader.ReadRune", Kind: internal.SymbolKindMethod, Synopsis: "ReadRune reads a rune."},
+			{Name: "NewReader", Kind: internal.SymbolKindFunction, Synopsis: "NewReader returns a Reader."},
+		},
+	}
+	for path, syms := range symbols {
+		m := sample.Module(path, sample.VersionString, "")
+		m.LegacyPackages[0].Symbols = syms
+		if err := testDB.InsertModule(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Make b.com/bufio the more popular package.
+	if _, err := testDB.db.Exec(ctx, `UPDATE search_documents SET imported_by_count = 10 WHERE package_path = 'b.com/bufio'`); err != nil {
+		t.Fatal(err)
+	}
+
+	type result struct{ PackagePath, SymbolName string }
+	for _, test := range []struct {
+		name string
+		kind internal.SymbolKind
+		want []result
+	}{
+		{
+			name: "Reader",
+			want: []result{
+				{"b.com/bufio", "Reader"},
+				{"a.com/io", "Reader"},
+				{"b.com/bufio", "Reader.ReadRune"},
+			},
+		},
+		{
+			name: "Read",
+			want: []result{
+				{"b.com/bufio", "Reader"},
+				{"b.com/bufio", "Reader.ReadRune"},
+				{"a.com/io", "ReadAll"},
+				{"a.com/io", "Reader"},
+			},
+		},
+		{
+			name: "Read",
+			kind: internal.SymbolKindFunction,
+			want: []result{{"a.com/io", "ReadAll"}},
+		},
+		{
+			name: "NewReader",
+			want: []result{{"b.com/bufio", "NewReader"}},
+		},
+		{
+			name: "Writer",
+			want: nil,
+		},
+		{
+			name: "Read%",
+			want: nil,
+		},
+	} {
+		t.Run(test.name+"/"+string(test.kind), func(t *testing.T) {
+			res, err := testDB.SearchSymbols(ctx, test.name, test.kind, 10, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []result
+			for _, r := range res {
+				got = append(got, result{r.PackagePath, r.SymbolName})
+				if r.NumResults != uint64(len(test.want)) {
+					t.Errorf("%s: NumResults = %d, want %d", r.SymbolName, r.NumResults, len(test.want))
+				}
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("SearchSymbols(%q, %q) mismatch (-want +got):\n%s", test.name, test.kind, diff)
+			}
+		})
+	}
+
+	// Reinserting a package replaces its symbols.
+	m := sample.Module("a.com/io", "v1.1.0", "")
+	m.LegacyPackages[0].Symbols = []*internal.Symbol{{Name: "Writer", Kind: internal.SymbolKindType}}
+	if err := testDB.InsertModule(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]int{"ReadAll": 0, "Writer": 1} {
+		res, err := testDB.SearchSymbols(ctx, name, "", 10, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res) != want {
+			t.Errorf("after reinsert, SearchSymbols(%q): got %d results, want %d", name, len(res), want)
+		}
+	}
+
+	if _, err := testDB.SearchSymbols(ctx, "Reader", "Struct", 10, 0); !errors.Is(err, derrors.InvalidArgument) {
+		t.Errorf("unknown kind: got error %v, want InvalidArgument", err)
+	}
+}
@@ -0,0 +1,296 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lib/pq"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/database"
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+// GetPackageSymbols returns the exported symbols of the package with path
+// pkgPath at the given version, ordered by name. If the package exists at that
+// version in more than one module, the symbols of the package in the module
+// with the longest path are returned, as in LegacyGetPackage.
+//
+// The Synopsis field of the returned symbols is not populated.
+//
+// If the package does not exist at the version, GetPackageSymbols returns an
+// error that wraps derrors.NotFound.
+func (db *DB) GetPackageSymbols(ctx context.Context, pkgPath, version string) (_ []*internal.Symbol, err error) {
+	defer derrors.Wrap(&err, "GetPackageSymbols(ctx, %q, %q)", pkgPath, version)
+
+	if pkgPath == "" || version == "" {
+		return nil, fmt.Errorf("pkgPath and version must both be non-empty: %w", derrors.InvalidArgument)
+	}
+	var modulePath string
+	err = db.db.QueryRow(ctx, `
+		SELECT module_path
+		FROM packages
+		WHERE path = $1 AND version = $2
+		ORDER BY module_path DESC
+		LIMIT 1`, pkgPath, version).Scan(&modulePath)
+	switch err {
+	case sql.ErrNoRows:
+		return nil, fmt.Errorf("package %s@%s: %w", pkgPath, version, derrors.NotFound)
+	case nil:
+	default:
+		return nil, err
+	}
+
+	query := `
+		SELECT symbol_name, symbol_kind, signature
+		FROM package_symbols
+		WHERE package_path = $1 AND module_path = $2 AND version = $3
+		ORDER BY symbol_name`
+	var syms []*internal.Symbol
+	collect := func(rows *sql.Rows) error {
+		var s internal.Symbol
+		if err := rows.Scan(&s.Name, &s.Kind, &s.Signature); err != nil {
+			return fmt.Errorf("rows.Scan(): %v", err)
+		}
+		syms = append(syms, &s)
+		return nil
+	}
+	if err := db.db.RunQuery(ctx, query, collect, pkgPath, modulePath, version); err != nil {
+		return nil, err
+	}
+	return syms, nil
+}
+
+// GetExportedSymbolCount returns the number of exported symbols, including
+// methods, of the package with path pkgPath at the given version. If the
+// package exists at that version in more than one module, the count for the
+// module with the longest path is returned, as in GetPackageSymbols.
+//
+// If the package does not exist at the version, GetExportedSymbolCount
+// returns an error that wraps derrors.NotFound.
+func (db *DB) GetExportedSymbolCount(ctx context.Context, pkgPath, version string) (_ int, err error) {
+	defer derrors.Wrap(&err, "GetExportedSymbolCount(ctx, %q, %q)", pkgPath, version)
+
+	if pkgPath == "" || version == "" {
+		return 0, fmt.Errorf("pkgPath and version must both be non-empty: %w", derrors.InvalidArgument)
+	}
+	var count int
+	err = db.db.QueryRow(ctx, `
+		SELECT
+			(SELECT COUNT(*)
+			FROM package_symbols ps
+			WHERE ps.package_path = p.path
+			AND ps.module_path = p.module_path
+			AND ps.version = p.version)
+		FROM packages p
+		WHERE p.path = $1 AND p.version = $2
+		ORDER BY p.module_path DESC
+		LIMIT 1`, pkgPath, version).Scan(&count)
+	switch err {
+	case sql.ErrNoRows:
+		return 0, fmt.Errorf("package %s@%s: %w", pkgPath, version, derrors.NotFound)
+	case nil:
+		return count, nil
+	default:
+		return 0, err
+	}
+}
+
+// GetEmbeddedPatterns returns the patterns of the //go:embed directives of the
+// package with the given path at the given version, as stored in
+// search_documents. It returns an error wrapping derrors.NotFound if there is
+// no search document for the package at that version.
+func (db *DB) GetEmbeddedPatterns(ctx context.Context, pkgPath, version string) (_ []string, err error) {
+	defer derrors.Wrap(&err, "GetEmbeddedPatterns(ctx, %q, %q)", pkgPath, version)
+
+	var patterns []string
+	err = db.db.QueryRow(ctx, `
+		SELECT embedded_patterns
+		FROM search_documents
+		WHERE package_path = $1 AND version = $2`,
+		pkgPath, version).Scan(pq.Array(&patterns))
+	switch err {
+	case sql.ErrNoRows:
+		return nil, fmt.Errorf("search document for %s@%s: %w", pkgPath, version, derrors.NotFound)
+	case nil:
+		return patterns, nil
+	default:
+		return nil, err
+	}
+}
+
+// GetBuildContexts returns the elements of internal.BuildContexts that the
+// package with the given path at the given version builds for, as stored in
+// search_documents. It returns nil if they are unknown, and an error wrapping
+// derrors.NotFound if there is no search document for the package at that
+// version.
+func (db *DB) GetBuildContexts(ctx context.Context, pkgPath, version string) (_ []internal.BuildContext, err error) {
+	defer derrors.Wrap(&err, "GetBuildContexts(ctx, %q, %q)", pkgPath, version)
+
+	var data []byte
+	err = db.db.QueryRow(ctx, `
+		SELECT build_contexts
+		FROM search_documents
+		WHERE package_path = $1 AND version = $2`,
+		pkgPath, version).Scan(&data)
+	switch err {
+	case sql.ErrNoRows:
+		return nil, fmt.Errorf("search document for %s@%s: %w", pkgPath, version, derrors.NotFound)
+	case nil:
+	default:
+		return nil, err
+	}
+	if data == nil {
+		return nil, nil
+	}
+	bcs := []internal.BuildContext{}
+	if err := json.Unmarshal(data, &bcs); err != nil {
+		return nil, err
+	}
+	return bcs, nil
+}
+
+// GetHasFuzzTests reports whether a test file of the package with the given
+// path at the given version declares a fuzz target, as stored in
+// search_documents. It returns an error wrapping derrors.NotFound if there is
+// no search document for the package at that version.
+func (db *DB) GetHasFuzzTests(ctx context.Context, pkgPath, version string) (_ bool, err error) {
+	defer derrors.Wrap(&err, "GetHasFuzzTests(ctx, %q, %q)", pkgPath, version)
+
+	var hasFuzzTests bool
+	err = db.db.QueryRow(ctx, `
+		SELECT has_fuzz_tests
+		FROM search_documents
+		WHERE package_path = $1 AND version = $2`,
+		pkgPath, version).Scan(&hasFuzzTests)
+	switch err {
+	case sql.ErrNoRows:
+		return false, fmt.Errorf("search document for %s@%s: %w", pkgPath, version, derrors.NotFound)
+	case nil:
+		return hasFuzzTests, nil
+	default:
+		return false, err
+	}
+}
+
+// GetBenchmarkCount returns the number of benchmarks declared in the test
+// files of the package with the given path at the given version, as stored in
+// search_documents. It returns an error wrapping derrors.NotFound if there is
+// no search document for the package at that version.
+func (db *DB) GetBenchmarkCount(ctx context.Context, pkgPath, version string) (_ int, err error) {
+	defer derrors.Wrap(&err, "GetBenchmarkCount(ctx, %q, %q)", pkgPath, version)
+
+	var count int
+	err = db.db.QueryRow(ctx, `
+		SELECT benchmark_count
+		FROM search_documents
+		WHERE package_path = $1 AND version = $2`,
+		pkgPath, version).Scan(&count)
+	switch err {
+	case sql.ErrNoRows:
+		return 0, fmt.Errorf("search document for %s@%s: %w", pkgPath, version, derrors.NotFound)
+	case nil:
+		return count, nil
+	default:
+		return 0, err
+	}
+}
+
+// GetSuccessorPackages returns the import paths mentioned in the deprecation
+// message of the package with the given path at the given version, as stored
+// in search_documents, that are paths of packages in the database. They are
+// in the order they appear in the message.
+//
+// The paths are resolved when GetSuccessorPackages is called, so a successor
+// that is inserted after the deprecated package is still found.
+func (db *DB) GetSuccessorPackages(ctx context.Context, pkgPath, version string) (_ []string, err error) {
+	defer derrors.Wrap(&err, "GetSuccessorPackages(ctx, %q, %q)", pkgPath, version)
+
+	query := `
+		SELECT s.path
+		FROM search_documents sd
+		CROSS JOIN UNNEST(sd.successor_packages) WITH ORDINALITY AS s(path, n)
+		WHERE sd.package_path = $1 AND sd.version = $2
+		AND EXISTS (SELECT 1 FROM packages p WHERE p.path = s.path)
+		ORDER BY s.n`
+	var paths []string
+	collect := func(rows *sql.Rows) error {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return err
+		}
+		paths = append(paths, p)
+		return nil
+	}
+	if err := db.db.RunQuery(ctx, query, collect, pkgPath, version); err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// upsertSymbolDefinitions replaces the rows of symbol_definitions for the
+// module of m, which must be the latest version of the module, with the
+// locations of the exported symbols of its packages.
+func upsertSymbolDefinitions(ctx context.Context, db *database.DB, m *internal.Module) (err error) {
+	defer derrors.Wrap(&err, "upsertSymbolDefinitions(ctx, %q, %q)", m.ModulePath, m.Version)
+
+	var (
+		values      []interface{}
+		pkgs, names []string
+	)
+	for _, p := range m.LegacyPackages {
+		for _, s := range p.Symbols {
+			if s.FilePath == "" {
+				continue
+			}
+			values = append(values, p.Path, m.ModulePath, m.Version, s.Name, s.FilePath, s.Line)
+			pkgs = append(pkgs, p.Path)
+			names = append(names, s.Name)
+		}
+	}
+	if err := deleteRowsNotIn(ctx, db, "symbol_definitions", "module_path = $1", []interface{}{m.ModulePath},
+		[]string{"package_path", "symbol_name"}, pkgs, names); err != nil {
+		return err
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	cols := []string{"package_path", "module_path", "version", "symbol_name", "file_path", "line"}
+	return db.BulkUpsert(ctx, "symbol_definitions", cols, values, []string{"package_path", "module_path", "symbol_name"})
+}
+
+// GetSymbolDefinition returns the location of the declaration of the
+// exported symbol symbolName in the latest version of the package with path
+// pkgPath. The name of a method is qualified by its receiver type, as in
+// "Buffer.Write". If the package exists in more than one module, the location
+// in the module with the longest path is returned.
+//
+// If there is no such symbol, GetSymbolDefinition returns an error that wraps
+// derrors.NotFound.
+func (db *DB) GetSymbolDefinition(ctx context.Context, pkgPath, symbolName string) (_ *internal.SymbolLocation, err error) {
+	defer derrors.Wrap(&err, "GetSymbolDefinition(ctx, %q, %q)", pkgPath, symbolName)
+
+	if pkgPath == "" || symbolName == "" {
+		return nil, fmt.Errorf("pkgPath and symbolName must both be non-empty: %w", derrors.InvalidArgument)
+	}
+	loc := &internal.SymbolLocation{PackagePath: pkgPath}
+	err = db.db.QueryRow(ctx, `
+		SELECT module_path, version, file_path, line
+		FROM symbol_definitions
+		WHERE package_path = $1 AND symbol_name = $2
+		ORDER BY module_path DESC
+		LIMIT 1`, pkgPath, symbolName).Scan(&loc.ModulePath, &loc.Version, &loc.FilePath, &loc.Line)
+	switch err {
+	case sql.ErrNoRows:
+		return nil, fmt.Errorf("symbol %s.%s: %w", pkgPath, symbolName, derrors.NotFound)
+	case nil:
+		return loc, nil
+	default:
+		return nil, err
+	}
+}
@@ -0,0 +1,304 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"path"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/testing/sample"
+)
+
+func TestGetPackageSymbols(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	insert := func(version string, syms []*internal.Symbol) {
+		t.Helper()
+		m := sample.Module(sample.ModulePath, version, sample.Suffix)
+		for _, p := range m.LegacyPackages {
+			p.Symbols = syms
+		}
+		if err := testDB.InsertModule(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	v1 := []*internal.Symbol{
+		{Name: "New", Kind: internal.SymbolKindFunction, Signature: "func New() *T"},
+		{Name: "T", Kind: internal.SymbolKindType, Signature: "type T struct{}"},
+	}
+	v2 := []*internal.Symbol{
+		{Name: "T", Kind: internal.SymbolKindType, Signature: "type T struct{}"},
+		{Name: "Make", Kind: internal.SymbolKindFunction, Signature: "func Make() *T"},
+	}
+	insert("v1.0.0", v1)
+	insert("v1.1.0", v2)
+	// Reinserting a module version replaces its symbols.
+	insert("v1.1.0", v2[1:])
+
+	pkgPath := sample.ModulePath + "/" + sample.Suffix
+	for _, test := range []struct {
+		version string
+		want    []*internal.Symbol
+	}{
+		{"v1.0.0", v1},
+		{"v1.1.0", v2[1:]},
+	} {
+		got, err := testDB.GetPackageSymbols(ctx, pkgPath, test.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("GetPackageSymbols(%q, %q) mismatch (-want +got):\n%s", pkgPath, test.version, diff)
+		}
+	}
+
+	if _, err := testDB.GetPackageSymbols(ctx, pkgPath, "v1.2.0"); !errors.Is(err, derrors.NotFound) {
+		t.Errorf("got error %v, want NotFound", err)
+	}
+}
+
+func TestGetExportedSymbolCount(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	m := sample.Module(sample.ModulePath, sample.VersionString, sample.Suffix)
+	for _, p := range m.LegacyPackages {
+		p.Symbols = []*internal.Symbol{
+			{Name: "New", Kind: internal.SymbolKindFunction, Signature: "func New() *T"},
+			{Name: "T", Kind: internal.SymbolKindType, Signature: "type T struct{}"},
+			{Name: "T.M", Kind: internal.SymbolKindMethod, Signature: "func (T) M()"},
+		}
+	}
+	if err := testDB.InsertModule(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+
+	const want = 3
+	pkgPath := sample.ModulePath + "/" + sample.Suffix
+	got, err := testDB.GetExportedSymbolCount(ctx, pkgPath, sample.VersionString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("GetExportedSymbolCount(%q, %q) = %d, want %d", pkgPath, sample.VersionString, got, want)
+	}
+
+	var stored int
+	if err := testDB.db.QueryRow(ctx,
+		`SELECT exported_symbol_count FROM search_documents WHERE package_path = $1`,
+		pkgPath).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored != want {
+		t.Errorf("search_documents.exported_symbol_count = %d, want %d", stored, want)
+	}
+
+	if _, err := testDB.GetExportedSymbolCount(ctx, pkgPath, "v9.0.0"); !errors.Is(err, derrors.NotFound) {
+		t.Errorf("got error %v, want NotFound", err)
+	}
+}
+
+func TestDocCoverage(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	documented := func(name string) *internal.Symbol {
+		return &internal.Symbol{Name: name, Kind: internal.SymbolKindFunction, Synopsis: name + " does things."}
+	}
+	undocumented := func(name string) *internal.Symbol {
+		return &internal.Symbol{Name: name, Kind: internal.SymbolKindFunction}
+	}
+	syms := map[string][]*internal.Symbol{
+		"none": {undocumented("A"), undocumented("B")},
+		"half": {documented("A"), undocumented("B")},
+		"all":  {documented("A"), documented("B")},
+	}
+	want := map[string]int{"none": 0, "half": 50, "all": 100}
+
+	m := sample.Module(sample.ModulePath, sample.VersionString, "none", "half", "all")
+	for _, p := range m.LegacyPackages {
+		p.Symbols = syms[path.Base(p.Path)]
+	}
+	if err := testDB.InsertModule(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+
+	for suffix, w := range want {
+		pkgPath := sample.ModulePath + "/" + suffix
+		var stored int
+		if err := testDB.db.QueryRow(ctx,
+			`SELECT doc_coverage_pct FROM search_documents WHERE package_path = $1`,
+			pkgPath).Scan(&stored); err != nil {
+			t.Fatal(err)
+		}
+		if stored != w {
+			t.Errorf("search_documents.doc_coverage_pct for %q = %d, want %d", pkgPath, stored, w)
+		}
+		pkg, err := testDB.LegacyGetPackage(ctx, pkgPath, sample.ModulePath, sample.VersionString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := pkg.Facts.DocCoverage; got == nil || *got != w {
+			t.Errorf("LegacyGetPackage(%q).Facts.DocCoverage = %v, want %d", pkgPath, got, w)
+		}
+	}
+}
+
+func TestGetEmbeddedPatterns(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	// The patterns of a package with a "//go:embed static/*" directive.
+	m := sample.Module(sample.ModulePath, sample.VersionString, "web", "plain")
+	m.LegacyPackages[0].EmbeddedPatterns = []string{"static/*"}
+	if err := testDB.InsertModule(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		pkgPath string
+		want    []string
+	}{
+		{m.LegacyPackages[0].Path, []string{"static/*"}},
+		{m.LegacyPackages[1].Path, []string{}},
+	} {
+		got, err := testDB.GetEmbeddedPatterns(ctx, test.pkgPath, sample.VersionString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("GetEmbeddedPatterns(%q) mismatch (-want +got):\n%s", test.pkgPath, diff)
+		}
+	}
+
+	if _, err := testDB.GetEmbeddedPatterns(ctx, m.LegacyPackages[0].Path, "v9.0.0"); !errors.Is(err, derrors.NotFound) {
+		t.Errorf("got error %v, want NotFound", err)
+	}
+}
+
+func TestGetBuildContexts(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	linux := []internal.BuildContext{{GOOS: "linux", GOARCH: "amd64"}, {GOOS: "linux", GOARCH: "arm64"}}
+	m := sample.Module(sample.ModulePath, sample.VersionString, "linux", "nowhere")
+	m.LegacyPackages[0].BuildContexts = linux
+	if err := testDB.InsertModule(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		pkgPath string
+		want    []internal.BuildContext
+	}{
+		{m.LegacyPackages[0].Path, linux},
+		{m.LegacyPackages[1].Path, []internal.BuildContext{}},
+	} {
+		got, err := testDB.GetBuildContexts(ctx, test.pkgPath, sample.VersionString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("GetBuildContexts(%q) mismatch (-want +got):\n%s", test.pkgPath, diff)
+		}
+	}
+
+	if _, err := testDB.GetBuildContexts(ctx, m.LegacyPackages[0].Path, "v9.0.0"); !errors.Is(err, derrors.NotFound) {
+		t.Errorf("got error %v, want NotFound", err)
+	}
+}
+
+func TestGetSuccessorPackages(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	old := sample.Module("old.com/foo", sample.VersionString, "p")
+	old.LegacyPackages[0].IsDeprecated = true
+	old.LegacyPackages[0].DeprecationMessage = "Use missing.com/x or new.com/foo/p instead."
+	old.LegacyPackages[0].SuccessorPackages = []string{"missing.com/x", "new.com/foo/p"}
+	if err := testDB.InsertModule(ctx, old); err != nil {
+		t.Fatal(err)
+	}
+	check := func(want []string) {
+		t.Helper()
+		got, err := testDB.GetSuccessorPackages(ctx, "old.com/foo/p", sample.VersionString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("GetSuccessorPackages mismatch (-want +got):\n%s", diff)
+		}
+	}
+	// Neither successor is known yet.
+	check(nil)
+	// A successor inserted after the deprecated package is found.
+	if err := testDB.InsertModule(ctx, sample.Module("new.com/foo", sample.VersionString, "p")); err != nil {
+		t.Fatal(err)
+	}
+	check([]string{"new.com/foo/p"})
+}
+
+func TestGetSymbolDefinition(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	insert := func(version string, syms []*internal.Symbol) {
+		t.Helper()
+		m := sample.Module(sample.ModulePath, version, sample.Suffix)
+		for _, p := range m.LegacyPackages {
+			p.Symbols = syms
+		}
+		if err := testDB.InsertModule(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	insert("v1.1.0", []*internal.Symbol{
+		{Name: "New", Kind: internal.SymbolKindFunction, FilePath: "foo/new.go", Line: 12},
+		{Name: "T.M", Kind: internal.SymbolKindMethod, FilePath: "foo/t.go", Line: 30},
+	})
+	// Older versions don't replace the definitions of the latest version.
+	insert("v1.0.0", []*internal.Symbol{
+		{Name: "New", Kind: internal.SymbolKindFunction, FilePath: "foo/old.go", Line: 3},
+		{Name: "Old", Kind: internal.SymbolKindFunction, FilePath: "foo/old.go", Line: 7},
+	})
+
+	pkgPath := sample.ModulePath + "/" + sample.Suffix
+	for _, test := range []struct {
+		symbol string
+		want   *internal.SymbolLocation
+	}{
+		{"New", &internal.SymbolLocation{PackagePath: pkgPath, ModulePath: sample.ModulePath, Version: "v1.1.0", FilePath: "foo/new.go", Line: 12}},
+		{"T.M", &internal.SymbolLocation{PackagePath: pkgPath, ModulePath: sample.ModulePath, Version: "v1.1.0", FilePath: "foo/t.go", Line: 30}},
+		{"Old", nil},
+		{"Missing", nil},
+	} {
+		got, err := testDB.GetSymbolDefinition(ctx, pkgPath, test.symbol)
+		if test.want == nil {
+			if !errors.Is(err, derrors.NotFound) {
+				t.Errorf("GetSymbolDefinition(%q, %q): got error %v, want NotFound", pkgPath, test.symbol, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("GetSymbolDefinition(%q, %q) mismatch (-want +got):\n%s", pkgPath, test.symbol, diff)
+		}
+	}
+}
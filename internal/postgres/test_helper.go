@@ -8,10 +8,12 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -19,6 +21,7 @@ import (
 	"golang.org/x/pkgsite/internal/database"
 	"golang.org/x/pkgsite/internal/derrors"
 	"golang.org/x/pkgsite/internal/testing/dbtest"
+	"golang.org/x/pkgsite/internal/testing/pgcontainer"
 	"golang.org/x/pkgsite/internal/testing/sample"
 	"golang.org/x/pkgsite/internal/testing/testhelper"
 
@@ -50,11 +53,10 @@ func migrationsSource() string {
 	return "file://" + filepath.ToSlash(migrationsDir)
 }
 
-// tryToMigrate attempts to migrate the database named dbName to the latest
+// tryToMigrate attempts to migrate the database at dbURI to the latest
 // migration. If this operation fails in the migration step, it returns
 // isMigrationError=true to signal that the database should be recreated.
-func tryToMigrate(dbName string) (isMigrationError bool, outerErr error) {
-	dbURI := dbtest.DBConnURI(dbName)
+func tryToMigrate(dbURI string) (isMigrationError bool, outerErr error) {
 	source := migrationsSource()
 	m, err := migrate.New(source, dbURI)
 	if err != nil {
@@ -79,20 +81,20 @@ func SetupTestDB(dbName string) (_ *DB, err error) {
 	if err := dbtest.CreateDBIfNotExists(dbName); err != nil {
 		return nil, fmt.Errorf("CreateDBIfNotExists(%q): %w", dbName, err)
 	}
-	if isMigrationError, err := tryToMigrate(dbName); err != nil {
+	if isMigrationError, err := tryToMigrate(dbtest.DBConnURI(dbName)); err != nil {
 		if isMigrationError {
 			// failed during migration stage, recreate and try again
 			log.Printf("Migration failed for %s: %v, recreating database.", dbName, err)
 			if err := recreateDB(dbName); err != nil {
 				return nil, fmt.Errorf("recreateDB(%q): %v", dbName, err)
 			}
-			_, err = tryToMigrate(dbName)
+			_, err = tryToMigrate(dbtest.DBConnURI(dbName))
 		}
 		if err != nil {
 			return nil, fmt.Errorf("unfixable error migrating database: %v.\nConsider running ./devtools/drop_test_dbs.sh", err)
 		}
 	}
-	db, err := database.Open("postgres", dbtest.DBConnURI(dbName), "test")
+	db, err := database.Open("postgres", dbtest.DBConnURI(dbName), "test", database.DBConfig{})
 	if err != nil {
 		return nil, err
 	}
@@ -101,7 +103,7 @@ func SetupTestDB(dbName string) (_ *DB, err error) {
 
 // ResetTestDB truncates all data from the given test DB.  It should be called
 // after every test that mutates the database.
-func ResetTestDB(db *DB, t *testing.T) {
+func ResetTestDB(db *DB, t testing.TB) {
 	ctx := context.Background()
 	t.Helper()
 	if err := db.db.Transact(ctx, sql.LevelDefault, func(tx *database.DB) error {
@@ -109,7 +111,14 @@ func ResetTestDB(db *DB, t *testing.T) {
 			TRUNCATE modules CASCADE;
 			TRUNCATE version_map;
 			TRUNCATE imports_unique;
-			TRUNCATE experiments;`); err != nil {
+			TRUNCATE imports_unique_changelog;
+			TRUNCATE imported_by_count_snapshots;
+			TRUNCATE trending_stats;
+			TRUNCATE vuln_reports;
+			TRUNCATE experiments;
+			TRUNCATE module_index_queue;
+			TRUNCATE module_events;
+			TRUNCATE search_query_log;`); err != nil {
 			return err
 		}
 		if _, err := tx.Exec(ctx, `TRUNCATE module_version_states CASCADE;`); err != nil {
@@ -128,12 +137,26 @@ func ResetTestDB(db *DB, t *testing.T) {
 // RunDBTests is a wrapper that runs the given testing suite in a test database
 // named dbName.  The given *DB reference will be set to the instantiated test
 // database.
+//
+// If no database is available, the tests are skipped, but benchmarks requested
+// with -bench still run, with a nil *DB. Benchmarks that need a database must
+// check for it.
 func RunDBTests(dbName string, m *testing.M, testDB **DB) {
 	database.QueryLoggingDisabled = true
 	db, err := SetupTestDB(dbName)
+	if errors.Is(err, derrors.NotFound) {
+		log.Printf("Could not connect to DB, starting a Postgres container: %v", err)
+		db, err = createContainerDB(context.Background(), dbName)
+	}
 	if err != nil {
 		if errors.Is(err, derrors.NotFound) && os.Getenv("GO_DISCOVERY_TESTDB") != "true" {
-			log.Printf("SKIPPING: could not connect to DB (see doc/postgres.md to set up): %v", err)
+			log.Printf("SKIPPING: could not connect to DB or Docker (see doc/postgres.md to set up): %v", err)
+			// TestMain runs before the test flags are parsed.
+			flag.Parse()
+			if f := flag.Lookup("test.bench"); f != nil && f.Value.String() != "" {
+				flag.Set("test.run", "^$")
+				os.Exit(m.Run())
+			}
 			return
 		}
 		log.Fatal(err)
@@ -143,9 +166,91 @@ func RunDBTests(dbName string, m *testing.M, testDB **DB) {
 	if err := db.Close(); err != nil {
 		log.Fatal(err)
 	}
+	if err := pgcontainer.TerminateShared(context.Background()); err != nil {
+		log.Fatal(err)
+	}
 	os.Exit(code)
 }
 
+// testDBCount numbers the databases created by NewTestDB.
+var testDBCount int64
+
+// NewTestDB returns a new database, migrated to the latest schema, which is
+// dropped when t and its subtests complete. Unlike the database of
+// RunDBTests, it is not shared with other tests, so tests that use it can
+// run in parallel.
+//
+// The database is created on the Postgres server configured for tests, like
+// the database of RunDBTests, or, if that server is not available, in the
+// Postgres container shared by the tests of the process. If neither is
+// available, t is skipped, unless the GO_DISCOVERY_TESTDB environment
+// variable is "true".
+func NewTestDB(t *testing.T) *DB {
+	t.Helper()
+	ctx := context.Background()
+	dbName := fmt.Sprintf("discovery_test_%d_%d", os.Getpid(), atomic.AddInt64(&testDBCount, 1))
+	db, err := SetupTestDB(dbName)
+	drop := func() error { return dropDB(dbName) }
+	if errors.Is(err, derrors.NotFound) {
+		db, err = createContainerDB(ctx, dbName)
+		drop = func() error {
+			c, err := pgcontainer.Shared(ctx)
+			if err != nil {
+				return err
+			}
+			return c.DropDB(dbName)
+		}
+	}
+	if err != nil {
+		if errors.Is(err, derrors.NotFound) && os.Getenv("GO_DISCOVERY_TESTDB") != "true" {
+			t.Skipf("SKIPPING: could not connect to DB or Docker (see doc/postgres.md to set up): %v", err)
+		}
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Error(err)
+		}
+		if err := drop(); err != nil {
+			t.Error(err)
+		}
+	})
+	return db
+}
+
+// dropDB drops the database named dbName on the Postgres server configured
+// for tests.
+func dropDB(dbName string) error {
+	return dbtest.ConnectAndExecute(dbtest.DBConnURI(""), func(pg *sql.DB) error {
+		if _, err := pg.Exec(fmt.Sprintf("DROP DATABASE IF EXISTS %q;", dbName)); err != nil {
+			return fmt.Errorf("error dropping %q: %v", dbName, err)
+		}
+		return nil
+	})
+}
+
+// createContainerDB creates the database dbName in the shared Postgres
+// container, and migrates it to the latest schema.
+func createContainerDB(ctx context.Context, dbName string) (_ *DB, err error) {
+	defer derrors.Wrap(&err, "createContainerDB(%q)", dbName)
+
+	c, err := pgcontainer.Shared(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.CreateDB(dbName); err != nil {
+		return nil, err
+	}
+	if _, err := tryToMigrate(c.URI(dbName)); err != nil {
+		return nil, err
+	}
+	db, err := database.Open("postgres", c.URI(dbName), "test", database.DBConfig{})
+	if err != nil {
+		return nil, err
+	}
+	return New(db), nil
+}
+
 // InsertSampleDirectory tree inserts a set of packages for testing
 // GetDirectory and frontend.FetchDirectoryDetails.
 func InsertSampleDirectoryTree(ctx context.Context, t *testing.T, testDB *DB) {
@@ -0,0 +1,141 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/database"
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+// TrendingPeriods are the periods, in days, for which trending stats are
+// computed.
+var TrendingPeriods = []int{7, 30}
+
+// IsTrendingPeriod reports whether period is one of TrendingPeriods.
+func IsTrendingPeriod(period int) bool {
+	for _, p := range TrendingPeriods {
+		if p == period {
+			return true
+		}
+	}
+	return false
+}
+
+// ComputeTrendingStats replaces the trending stats for period with the growth
+// of the imported_by_count of each package in search_documents over the
+// last period days.
+//
+// Past counts are taken from imported_by_count_snapshots, to which
+// ComputeTrendingStats first adds today's counts. The growth is computed from
+// the most recent snapshot that is at least period days old; if there is
+// none, there are no trending stats for period. Snapshots older than the
+// longest trending period are deleted.
+func (db *DB) ComputeTrendingStats(ctx context.Context, period int) (err error) {
+	defer derrors.Wrap(&err, "ComputeTrendingStats(ctx, %d)", period)
+
+	if !IsTrendingPeriod(period) {
+		return fmt.Errorf("period must be one of %v: %w", TrendingPeriods, derrors.InvalidArgument)
+	}
+	maxPeriod := 0
+	for _, p := range TrendingPeriods {
+		if p > maxPeriod {
+			maxPeriod = p
+		}
+	}
+	return db.db.Transact(ctx, sql.LevelDefault, func(tx *database.DB) error {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO imported_by_count_snapshots (package_path, recorded_on, imported_by_count)
+			SELECT package_path, CURRENT_DATE, imported_by_count
+			FROM search_documents
+			WHERE imported_by_count > 0
+			ON CONFLICT (package_path, recorded_on)
+			DO UPDATE SET imported_by_count = excluded.imported_by_count`); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM trending_stats WHERE period_days = $1`, period); err != nil {
+			return err
+		}
+		// A package missing from the old snapshot had no importers then.
+		if _, err := tx.Exec(ctx, `
+			WITH base AS (
+				SELECT MAX(recorded_on) AS recorded_on
+				FROM imported_by_count_snapshots
+				WHERE recorded_on <= CURRENT_DATE - $1::integer
+			)
+			INSERT INTO trending_stats (package_path, period_days, delta_imported_by_count)
+			SELECT
+				sd.package_path,
+				$1,
+				sd.imported_by_count - COALESCE(s.imported_by_count, 0) AS delta
+			FROM search_documents sd
+			CROSS JOIN base
+			LEFT JOIN imported_by_count_snapshots s
+			ON s.package_path = sd.package_path AND s.recorded_on = base.recorded_on
+			WHERE
+				base.recorded_on IS NOT NULL
+				AND sd.imported_by_count > COALESCE(s.imported_by_count, 0)`, period); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `
+			DELETE FROM imported_by_count_snapshots
+			WHERE recorded_on < CURRENT_DATE - $1::integer`, maxPeriod)
+		return err
+	})
+}
+
+// GetTrendingPackages returns up to limit packages whose imported_by_count
+// grew the most over the last periodDays days, as last computed by
+// ComputeTrendingStats. The Score of each result is the growth of its
+// imported_by_count.
+func (db *DB) GetTrendingPackages(ctx context.Context, periodDays, limit int) (_ []*internal.SearchResult, err error) {
+	defer derrors.Wrap(&err, "GetTrendingPackages(ctx, %d, %d)", periodDays, limit)
+
+	if !IsTrendingPeriod(periodDays) {
+		return nil, fmt.Errorf("period must be one of %v: %w", TrendingPeriods, derrors.InvalidArgument)
+	}
+	if limit <= 0 {
+		return nil, fmt.Errorf("limit must be positive: %w", derrors.InvalidArgument)
+	}
+	query := `
+		SELECT
+			sd.package_path,
+			sd.module_path,
+			sd.version,
+			sd.name,
+			sd.synopsis,
+			sd.license_types,
+			sd.commit_time,
+			sd.imported_by_count,
+			t.delta_imported_by_count
+		FROM trending_stats t
+		INNER JOIN search_documents sd
+		USING (package_path)
+		WHERE t.period_days = $1
+		ORDER BY
+			t.delta_imported_by_count DESC,
+			sd.imported_by_count DESC,
+			sd.package_path
+		LIMIT $2`
+	var results []*internal.SearchResult
+	collect := func(rows *sql.Rows) error {
+		var r internal.SearchResult
+		if err := rows.Scan(&r.PackagePath, &r.ModulePath, &r.Version, &r.Name, &r.Synopsis,
+			pq.Array(&r.Licenses), &r.CommitTime, &r.NumImportedBy, &r.Score); err != nil {
+			return fmt.Errorf("rows.Scan(): %v", err)
+		}
+		results = append(results, &r)
+		return nil
+	}
+	if err := db.db.RunQuery(ctx, query, collect, periodDays, limit); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/testing/sample"
+)
+
+func TestComputeTrendingStats(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	// Packages are keyed by module path, with their imported-by counts 10 days
+	// ago and now.
+	counts := map[string][2]int{
+		"fast.com/m":   {10, 110},
+		"slow.com/m":   {100, 105},
+		"stable.com/m": {500, 500},
+		"new.com/m":    {0, 20},
+	}
+	for modulePath, c := range counts {
+		if err := testDB.InsertModule(ctx, sample.Module(modulePath, "v1.0.0", "")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := testDB.db.Exec(ctx,
+			`UPDATE search_documents SET imported_by_count = $1 WHERE package_path = $2`, c[1], modulePath); err != nil {
+			t.Fatal(err)
+		}
+		if c[0] > 0 {
+			if _, err := testDB.db.Exec(ctx, `
+				INSERT INTO imported_by_count_snapshots (package_path, recorded_on, imported_by_count)
+				VALUES ($1, CURRENT_DATE - 10, $2)`, modulePath, c[0]); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	for _, period := range []int{7, 30} {
+		if err := testDB.ComputeTrendingStats(ctx, period); err != nil {
+			t.Fatal(err)
+		}
+	}
+	type result struct {
+		Path  string
+		Delta float64
+	}
+	get := func(period int) []result {
+		t.Helper()
+		results, err := testDB.GetTrendingPackages(ctx, period, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []result
+		for _, r := range results {
+			got = append(got, result{r.PackagePath, r.Score})
+		}
+		return got
+	}
+
+	// Growing packages rank above stable ones, which are omitted.
+	want := []result{{"fast.com/m", 100}, {"new.com/m", 20}, {"slow.com/m", 5}}
+	if diff := cmp.Diff(want, get(7)); diff != "" {
+		t.Errorf("GetTrendingPackages(ctx, 7, 10) mismatch (-want +got):\n%s", diff)
+	}
+	// There is no snapshot 30 days old.
+	if got := get(30); got != nil {
+		t.Errorf("GetTrendingPackages(ctx, 30, 10) = %v, want none", got)
+	}
+
+	var n int
+	if err := testDB.db.QueryRow(ctx, `SELECT COUNT(*) FROM imported_by_count_snapshots WHERE recorded_on = CURRENT_DATE`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != len(counts) {
+		t.Errorf("got %d snapshots for today, want %d", n, len(counts))
+	}
+
+	if err := testDB.ComputeTrendingStats(ctx, 3); !errors.Is(err, derrors.InvalidArgument) {
+		t.Errorf("ComputeTrendingStats(ctx, 3): got error %v, want InvalidArgument", err)
+	}
+}
@@ -21,7 +21,8 @@ import (
 )
 
 // InsertIndexVersions inserts new versions into the module_version_states
-// table.
+// table. In the same transaction, it sends a notification for each version on
+// the channel that ListenForModuleEvents listens to.
 func (db *DB) InsertIndexVersions(ctx context.Context, versions []*internal.IndexVersion) (err error) {
 	defer derrors.Wrap(&err, "InsertIndexVersions(ctx, %v)", versions)
 
@@ -37,7 +38,10 @@ func (db *DB) InsertIndexVersions(ctx context.Context, versions []*internal.Inde
 			index_timestamp=excluded.index_timestamp,
 			next_processed_after=CURRENT_TIMESTAMP`
 	return db.db.Transact(ctx, sql.LevelDefault, func(tx *database.DB) error {
-		return tx.BulkInsert(ctx, "module_version_states", cols, vals, conflictAction)
+		if err := tx.BulkInsert(ctx, "module_version_states", cols, vals, conflictAction); err != nil {
+			return err
+		}
+		return notifyModuleEvents(ctx, tx, versions)
 	})
 }
 
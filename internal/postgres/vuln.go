@@ -0,0 +1,76 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/database"
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+// UpsertVulnReports inserts reports into the vuln_reports table. The
+// existing rows for the vulnerabilities in reports are replaced, so that
+// ranges and modules that are no longer affected by a vulnerability are
+// removed.
+func (db *DB) UpsertVulnReports(ctx context.Context, reports []*internal.VulnReport) (err error) {
+	defer derrors.Wrap(&err, "UpsertVulnReports(ctx, [%d reports])", len(reports))
+
+	if len(reports) == 0 {
+		return nil
+	}
+	var (
+		ids    []string
+		seen   = map[string]bool{}
+		values []interface{}
+	)
+	for _, r := range reports {
+		if !seen[r.ID] {
+			seen[r.ID] = true
+			ids = append(ids, r.ID)
+		}
+		values = append(values, r.ModulePath, r.AffectedVersionRange, r.ID, makeValidUnicode(r.Description), r.PublishedAt)
+	}
+	return db.db.Transact(ctx, sql.LevelDefault, func(tx *database.DB) error {
+		if _, err := tx.Exec(ctx, `DELETE FROM vuln_reports WHERE vuln_id = ANY($1)`, pq.Array(ids)); err != nil {
+			return err
+		}
+		cols := []string{"module_path", "affected_version_range", "vuln_id", "description", "published_at"}
+		return tx.BulkUpsert(ctx, "vuln_reports", cols, values, []string{"module_path", "vuln_id", "affected_version_range"})
+	})
+}
+
+// GetVulnerabilities returns the reports of the vulnerabilities that affect
+// the given version of the module, ordered by ID.
+func (db *DB) GetVulnerabilities(ctx context.Context, modulePath, version string) (_ []*internal.VulnReport, err error) {
+	defer derrors.Wrap(&err, "GetVulnerabilities(ctx, %q, %q)", modulePath, version)
+
+	query := `
+		SELECT vuln_id, affected_version_range, description, published_at
+		FROM vuln_reports
+		WHERE module_path = $1
+		ORDER BY vuln_id, affected_version_range`
+	var reports []*internal.VulnReport
+	collect := func(rows *sql.Rows) error {
+		r := &internal.VulnReport{ModulePath: modulePath}
+		if err := rows.Scan(&r.ID, &r.AffectedVersionRange, &r.Description, &r.PublishedAt); err != nil {
+			return fmt.Errorf("rows.Scan(): %v", err)
+		}
+		// A vulnerability may have several affected ranges; report it
+		// once if any of them contains the version.
+		if r.Affects(version) && (len(reports) == 0 || reports[len(reports)-1].ID != r.ID) {
+			reports = append(reports, r)
+		}
+		return nil
+	}
+	if err := db.db.RunQuery(ctx, query, collect, modulePath); err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal"
+)
+
+func TestGetVulnerabilities(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	published := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	report := func(id, modulePath, affected string) *internal.VulnReport {
+		return &internal.VulnReport{
+			ID:                   id,
+			ModulePath:           modulePath,
+			AffectedVersionRange: affected,
+			Description:          id + " is bad.",
+			PublishedAt:          published,
+		}
+	}
+	if err := testDB.UpsertVulnReports(ctx, []*internal.VulnReport{
+		report("GO-2020-0001", "a.com/m", ">=v1.0.0, <v1.2.0"),
+		report("GO-2020-0001", "a.com/m", ">=v1.5.0, <v1.5.2"),
+		report("GO-2020-0002", "a.com/m", "<v1.1.0"),
+		report("GO-2020-0003", "a.com/m", ">=v2.0.0"),
+		report("GO-2020-0004", "b.com/m", ""),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// Re-upserting a vulnerability replaces all its rows.
+	if err := testDB.UpsertVulnReports(ctx, []*internal.VulnReport{
+		report("GO-2020-0003", "a.com/m", ">=v1.9.0"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		modulePath, version string
+		want                []*internal.VulnReport
+	}{
+		{"a.com/m", "v0.9.0", []*internal.VulnReport{report("GO-2020-0002", "a.com/m", "<v1.1.0")}},
+		{"a.com/m", "v1.0.0", []*internal.VulnReport{
+			report("GO-2020-0001", "a.com/m", ">=v1.0.0, <v1.2.0"),
+			report("GO-2020-0002", "a.com/m", "<v1.1.0"),
+		}},
+		{"a.com/m", "v1.2.0", nil},
+		{"a.com/m", "v1.5.1", []*internal.VulnReport{report("GO-2020-0001", "a.com/m", ">=v1.5.0, <v1.5.2")}},
+		{"a.com/m", "v1.9.0", []*internal.VulnReport{report("GO-2020-0003", "a.com/m", ">=v1.9.0")}},
+		{"b.com/m", "v0.0.0-20200101000000-abcdef123456", []*internal.VulnReport{report("GO-2020-0004", "b.com/m", "")}},
+		{"c.com/m", "v1.0.0", nil},
+	} {
+		got, err := testDB.GetVulnerabilities(ctx, test.modulePath, test.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("GetVulnerabilities(%q, %q) mismatch (-want +got):\n%s", test.modulePath, test.version, diff)
+		}
+	}
+}
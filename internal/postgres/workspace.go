@@ -0,0 +1,65 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/database"
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+// upsertWorkspace stores the workspace of m, which must be the latest version
+// of its module, in the workspaces table. If m has no workspace, the
+// workspace of an earlier version of the module is deleted.
+func upsertWorkspace(ctx context.Context, db *database.DB, m *internal.Module) (err error) {
+	defer derrors.Wrap(&err, "upsertWorkspace(ctx, db, %q, %q)", m.ModulePath, m.Version)
+
+	if m.Workspace == nil {
+		_, err := db.Exec(ctx, `DELETE FROM workspaces WHERE repo_path = $1`, m.ModulePath)
+		return err
+	}
+	w := m.Workspace
+	_, err = db.Exec(ctx, `
+		INSERT INTO workspaces (repo_path, version, go_version, toolchain, use_dirs, module_paths)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (repo_path)
+		DO UPDATE SET
+			version=excluded.version,
+			go_version=excluded.go_version,
+			toolchain=excluded.toolchain,
+			use_dirs=excluded.use_dirs,
+			module_paths=excluded.module_paths`,
+		m.ModulePath, m.Version, w.GoVersion, w.Toolchain, pq.Array(w.UseDirs), pq.Array(w.ModulePaths))
+	return err
+}
+
+// GetWorkspace returns the workspace declared by the go.work file of the
+// latest version of the module at the root of the repository repoPath. It
+// returns a derrors.NotFound error if there is none.
+func (db *DB) GetWorkspace(ctx context.Context, repoPath string) (_ *internal.Workspace, err error) {
+	defer derrors.Wrap(&err, "GetWorkspace(ctx, %q)", repoPath)
+
+	w := &internal.Workspace{RepoPath: repoPath}
+	var goVersion, toolchain sql.NullString
+	err = db.db.QueryRow(ctx, `
+		SELECT go_version, toolchain, use_dirs, module_paths
+		FROM workspaces
+		WHERE repo_path = $1`, repoPath).
+		Scan(&goVersion, &toolchain, pq.Array(&w.UseDirs), pq.Array(&w.ModulePaths))
+	switch err {
+	case sql.ErrNoRows:
+		return nil, derrors.NotFound
+	case nil:
+		w.GoVersion = goVersion.String
+		w.Toolchain = toolchain.String
+		return w, nil
+	default:
+		return nil, err
+	}
+}
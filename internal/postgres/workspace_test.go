@@ -0,0 +1,66 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/testing/sample"
+)
+
+func TestGetWorkspace(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer ResetTestDB(testDB, t)
+
+	const repoPath = "github.com/work/space"
+	workspace := &internal.Workspace{
+		RepoPath:    repoPath,
+		GoVersion:   "1.21.0",
+		Toolchain:   "go1.21.3",
+		UseDirs:     []string{".", "tools"},
+		ModulePaths: []string{repoPath, repoPath + "/tools"},
+	}
+	insert := func(version string, w *internal.Workspace) {
+		t.Helper()
+		m := sample.Module(repoPath, version, "p")
+		m.Workspace = w
+		if err := testDB.InsertModule(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check := func(want *internal.Workspace) {
+		t.Helper()
+		got, err := testDB.GetWorkspace(ctx, repoPath)
+		if want == nil {
+			if !errors.Is(err, derrors.NotFound) {
+				t.Errorf("got error %v, want NotFound", err)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("GetWorkspace mismatch (-want +got):\n%s", diff)
+		}
+	}
+
+	insert("v1.0.0", workspace)
+	check(workspace)
+
+	// The workspace of an older version does not replace that of the latest.
+	insert("v0.9.0", &internal.Workspace{RepoPath: repoPath, UseDirs: []string{"."}, ModulePaths: []string{repoPath}})
+	check(workspace)
+
+	// A latest version without a go.work file removes the workspace.
+	insert("v1.1.0", nil)
+	check(nil)
+}
@@ -0,0 +1,194 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package prometheus serves the data of OpenCensus views in the Prometheus
+// text exposition format.
+package prometheus
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/log"
+)
+
+// contentType is the content type of the Prometheus text format.
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Handler returns a handler that serves the current data of views, which must
+// be registered, in the Prometheus text format.
+func Handler(views []*view.View) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		bw := bufio.NewWriter(w)
+		for _, v := range views {
+			rows, err := view.RetrieveData(v.Name)
+			if err != nil {
+				log.Errorf(r.Context(), "prometheus.Handler: %v", err)
+				continue
+			}
+			if err := ExportView(bw, v, rows); err != nil {
+				log.Errorf(r.Context(), "prometheus.Handler: %v", err)
+			}
+		}
+		if err := bw.Flush(); err != nil {
+			log.Errorf(r.Context(), "prometheus.Handler: %v", err)
+		}
+	})
+}
+
+// ExportView writes rows, the data of v, to w as a Prometheus metric family.
+// The metric is named after v, and its labels after the tag keys of v, with
+// the characters that Prometheus does not allow, like '/', replaced by '_'.
+//
+// Count aggregations are exported as counters, last-value aggregations as
+// gauges, distributions as histograms, and sums, which may decrease, as
+// untyped metrics.
+func ExportView(w io.Writer, v *view.View, rows []*view.Row) (err error) {
+	defer derrors.Wrap(&err, "ExportView(w, %q, rows)", v.Name)
+
+	name := metricName(v.Name)
+	var typ string
+	switch v.Aggregation.Type {
+	case view.AggTypeCount:
+		typ = "counter"
+	case view.AggTypeLastValue:
+		typ = "gauge"
+	case view.AggTypeDistribution:
+		typ = "histogram"
+	case view.AggTypeSum:
+		typ = "untyped"
+	default:
+		return fmt.Errorf("unsupported aggregation type %v", v.Aggregation.Type)
+	}
+	fmt.Fprintf(w, "# HELP %s %s\n", name, escapeHelp(v.Description))
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, typ)
+
+	// Sort the rows, so that the output is deterministic.
+	type sample struct {
+		labels []string // name="value" pairs
+		data   view.AggregationData
+	}
+	var samples []sample
+	for _, row := range rows {
+		samples = append(samples, sample{labelPairs(v.TagKeys, row.Tags), row.Data})
+	}
+	sort.Slice(samples, func(i, j int) bool {
+		return strings.Join(samples[i].labels, ",") < strings.Join(samples[j].labels, ",")
+	})
+
+	for _, s := range samples {
+		switch d := s.data.(type) {
+		case *view.CountData:
+			writeSample(w, name, s.labels, float64(d.Value))
+		case *view.SumData:
+			writeSample(w, name, s.labels, d.Value)
+		case *view.LastValueData:
+			writeSample(w, name, s.labels, d.Value)
+		case *view.DistributionData:
+			// Prometheus buckets are cumulative.
+			var cum int64
+			for i, bound := range v.Aggregation.Buckets {
+				if i < len(d.CountPerBucket) {
+					cum += d.CountPerBucket[i]
+				}
+				writeSample(w, name+"_bucket", withLabel(s.labels, "le", formatFloat(bound)), float64(cum))
+			}
+			writeSample(w, name+"_bucket", withLabel(s.labels, "le", "+Inf"), float64(d.Count))
+			writeSample(w, name+"_sum", s.labels, d.Mean*float64(d.Count))
+			writeSample(w, name+"_count", s.labels, float64(d.Count))
+		default:
+			return fmt.Errorf("unexpected aggregation data %T", d)
+		}
+	}
+	return nil
+}
+
+// labelPairs returns the label pairs for the values of keys in tags. A key
+// without a value has an empty label value.
+func labelPairs(keys []tag.Key, tags []tag.Tag) []string {
+	values := map[tag.Key]string{}
+	for _, t := range tags {
+		values[t.Key] = t.Value
+	}
+	var pairs []string
+	for _, k := range keys {
+		pairs = append(pairs, labelPair(sanitize(k.Name(), false), values[k]))
+	}
+	return pairs
+}
+
+func labelPair(name, value string) string {
+	return name + `="` + escapeLabelValue(value) + `"`
+}
+
+// withLabel returns a copy of labels with another label pair.
+func withLabel(labels []string, name, value string) []string {
+	return append(append([]string(nil), labels...), labelPair(name, value))
+}
+
+func writeSample(w io.Writer, name string, labels []string, value float64) {
+	if len(labels) > 0 {
+		fmt.Fprintf(w, "%s{%s} %s\n", name, strings.Join(labels, ","), formatFloat(value))
+	} else {
+		fmt.Fprintf(w, "%s %s\n", name, formatFloat(value))
+	}
+}
+
+// metricName returns the Prometheus metric name for the view with the given
+// name.
+func metricName(name string) string {
+	return sanitize(name, true)
+}
+
+// sanitize replaces the characters of s that cannot appear in a Prometheus
+// metric name, or label name if metric is false, with underscores. Colons are
+// allowed only in metric names.
+func sanitize(s string, metric bool) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_',
+			r >= '0' && r <= '9' && i > 0,
+			r == ':' && metric:
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			// A name cannot begin with a digit.
+			b.WriteByte('_')
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	valueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string       { return helpEscaper.Replace(s) }
+func escapeLabelValue(s string) string { return valueEscaper.Replace(s) }
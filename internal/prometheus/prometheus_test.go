@@ -0,0 +1,137 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package prometheus
+
+import (
+	"context"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+var (
+	keyRoute  = tag.MustNewKey("http/route")
+	keyStatus = tag.MustNewKey("status")
+
+	testRequests = stats.Int64("test/requests", "requests", stats.UnitDimensionless)
+	testLatency  = stats.Float64("test/latency", "latency", stats.UnitMilliseconds)
+
+	testCount = &view.View{
+		Name:        "go-discovery/test/request_count",
+		Measure:     testRequests,
+		Aggregation: view.Count(),
+		Description: "Count of requests,\nby route",
+		TagKeys:     []tag.Key{keyRoute, keyStatus},
+	}
+	testLastValue = &view.View{
+		Name:        "go-discovery/test/last_request",
+		Measure:     testRequests,
+		Aggregation: view.LastValue(),
+		Description: "Last request",
+	}
+	testSum = &view.View{
+		Name:        "go-discovery/test/latency_sum",
+		Measure:     testLatency,
+		Aggregation: view.Sum(),
+		Description: "Sum of latencies",
+	}
+	testDistribution = &view.View{
+		Name:        "go-discovery/test/latency",
+		Measure:     testLatency,
+		Aggregation: view.Distribution(10, 100),
+		Description: "Latency distribution",
+		TagKeys:     []tag.Key{keyRoute},
+	}
+)
+
+// sampleLine matches a sample line of the Prometheus text format.
+var sampleLine = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*` + // metric name
+	`(\{[a-zA-Z_][a-zA-Z0-9_]*="(\\.|[^"\\])*"(,[a-zA-Z_][a-zA-Z0-9_]*="(\\.|[^"\\])*")*\})?` + // labels
+	` ([-+]?[0-9.eE+-]+|\+Inf|-Inf|NaN)$`) // value
+
+// commentLine matches a HELP or TYPE line of the Prometheus text format.
+var commentLine = regexp.MustCompile(`^# (HELP [a-zA-Z_:][a-zA-Z0-9_:]* .*|TYPE [a-zA-Z_:][a-zA-Z0-9_:]* (counter|gauge|histogram|summary|untyped))$`)
+
+func TestHandler(t *testing.T) {
+	views := []*view.View{testCount, testLastValue, testSum, testDistribution}
+	if err := view.Register(views...); err != nil {
+		t.Fatal(err)
+	}
+	defer view.Unregister(views...)
+
+	record := func(route, status string, ms ...stats.Measurement) {
+		ctx, err := tag.New(context.Background(), tag.Upsert(keyRoute, route), tag.Upsert(keyStatus, status))
+		if err != nil {
+			t.Fatal(err)
+		}
+		stats.Record(ctx, ms...)
+	}
+	record("search", "200", testRequests.M(1), testLatency.M(5))
+	record("search", "200", testRequests.M(1), testLatency.M(50))
+	record(`a"b`, "500", testRequests.M(3), testLatency.M(500))
+
+	w := httptest.NewRecorder()
+	Handler(views).ServeHTTP(w, httptest.NewRequest("GET", "/debug/metrics", nil))
+	if got := w.Header().Get("Content-Type"); got != contentType {
+		t.Errorf("got Content-Type %q, want %q", got, contentType)
+	}
+
+	want := `# HELP go_discovery_test_request_count Count of requests,\nby route
+# TYPE go_discovery_test_request_count counter
+go_discovery_test_request_count{http_route="a\"b",status="500"} 1
+go_discovery_test_request_count{http_route="search",status="200"} 2
+# HELP go_discovery_test_last_request Last request
+# TYPE go_discovery_test_last_request gauge
+go_discovery_test_last_request 3
+# HELP go_discovery_test_latency_sum Sum of latencies
+# TYPE go_discovery_test_latency_sum untyped
+go_discovery_test_latency_sum 555
+# HELP go_discovery_test_latency Latency distribution
+# TYPE go_discovery_test_latency histogram
+go_discovery_test_latency_bucket{http_route="a\"b",le="10"} 0
+go_discovery_test_latency_bucket{http_route="a\"b",le="100"} 0
+go_discovery_test_latency_bucket{http_route="a\"b",le="+Inf"} 1
+go_discovery_test_latency_sum{http_route="a\"b"} 500
+go_discovery_test_latency_count{http_route="a\"b"} 1
+go_discovery_test_latency_bucket{http_route="search",le="10"} 1
+go_discovery_test_latency_bucket{http_route="search",le="100"} 2
+go_discovery_test_latency_bucket{http_route="search",le="+Inf"} 2
+go_discovery_test_latency_sum{http_route="search"} 55
+go_discovery_test_latency_count{http_route="search"} 2
+`
+	got := w.Body.String()
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(got, "\n"), "\n") {
+		if !sampleLine.MatchString(line) && !commentLine.MatchString(line) {
+			t.Errorf("line does not match the text format: %q", line)
+		}
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	for _, test := range []struct {
+		in     string
+		metric bool
+		want   string
+	}{
+		{"go-discovery/quota/result_count", true, "go_discovery_quota_result_count"},
+		{"a:b", true, "a:b"},
+		{"a:b", false, "a_b"},
+		{"9lives", false, "_9lives"},
+		{"http/route", false, "http_route"},
+	} {
+		if got := sanitize(test.in, test.metric); got != test.want {
+			t.Errorf("sanitize(%q, %t) = %q, want %q", test.in, test.metric, got, test.want)
+		}
+	}
+}
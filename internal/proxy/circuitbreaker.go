@@ -0,0 +1,165 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proxy
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+// A State is the state of a CircuitBreaker.
+type State int
+
+const (
+	// Closed is the state of a CircuitBreaker that lets calls through.
+	Closed State = iota
+	// Open is the state of a CircuitBreaker that fails calls immediately,
+	// because too many calls failed in a row.
+	Open
+	// HalfOpen is the state of a CircuitBreaker whose OpenDuration has
+	// passed. It lets a single trial call through, and closes if it
+	// succeeds or opens again if it fails.
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+const (
+	// DefaultFailureThreshold is the FailureThreshold of the CircuitBreaker
+	// of a Client returned by New.
+	DefaultFailureThreshold = 5
+	// DefaultOpenDuration is the OpenDuration of the CircuitBreaker of a
+	// Client returned by New.
+	DefaultOpenDuration = 30 * time.Second
+)
+
+// A CircuitBreaker stops calls to a failing service for a while, so that
+// callers fail fast instead of piling up on it. Its fields must be set before
+// it is used. A nil *CircuitBreaker lets every call through.
+type CircuitBreaker struct {
+	// FailureThreshold is the number of consecutive failed calls that open
+	// the breaker.
+	FailureThreshold int
+	// OpenDuration is how long the breaker stays open before it lets a
+	// trial call through.
+	OpenDuration time.Duration
+
+	mu       sync.Mutex
+	state    State
+	failures int       // consecutive failures while closed
+	openedAt time.Time // when the breaker last opened
+	trying   bool      // a trial call is in flight while half-open
+}
+
+// Do calls f, unless the breaker is open, or is half-open with a trial call in
+// flight; then it returns derrors.ProxyUnavailable without calling f. An
+// error returned by f counts as a failure, and nil as a success, unless ctx
+// is done when f returns: a call that was canceled or timed out by its caller
+// says nothing about the service, and is not counted.
+func (cb *CircuitBreaker) Do(ctx context.Context, f func() error) error {
+	if cb == nil {
+		return f()
+	}
+	if err := cb.allow(); err != nil {
+		return err
+	}
+	err := f()
+	if ctx.Err() != nil {
+		cb.release()
+		return err
+	}
+	cb.record(err == nil)
+	return err
+}
+
+// State returns the current state of the breaker.
+func (cb *CircuitBreaker) State() State {
+	if cb == nil {
+		return Closed
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.update()
+	return cb.state
+}
+
+// allow reports, with a nil error, whether a call may go through.
+func (cb *CircuitBreaker) allow() error {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.update()
+	switch {
+	case cb.state == Open:
+		return derrors.ProxyUnavailable
+	case cb.state == HalfOpen && cb.trying:
+		return derrors.ProxyUnavailable
+	case cb.state == HalfOpen:
+		cb.trying = true
+	}
+	return nil
+}
+
+// release ends a call whose outcome is not counted, letting another trial
+// call through if the breaker is half-open.
+func (cb *CircuitBreaker) release() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.state == HalfOpen {
+		cb.trying = false
+	}
+}
+
+// record updates the state of the breaker with the outcome of a call.
+func (cb *CircuitBreaker) record(success bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.state == HalfOpen {
+		cb.trying = false
+	}
+	switch {
+	case cb.state == Open:
+		// The call started before the breaker opened; its outcome is stale.
+	case success:
+		cb.state = Closed
+		cb.failures = 0
+	case cb.state == HalfOpen:
+		cb.open()
+	case cb.state == Closed:
+		cb.failures++
+		if cb.failures >= cb.FailureThreshold {
+			cb.open()
+		}
+	}
+}
+
+// open opens the breaker. cb.mu must be held.
+func (cb *CircuitBreaker) open() {
+	cb.state = Open
+	cb.openedAt = time.Now()
+	cb.failures = 0
+}
+
+// update moves an open breaker to half-open once OpenDuration has passed.
+// cb.mu must be held.
+func (cb *CircuitBreaker) update() {
+	if cb.state == Open && time.Since(cb.openedAt) >= cb.OpenDuration {
+		cb.state = HalfOpen
+		cb.trying = false
+	}
+}
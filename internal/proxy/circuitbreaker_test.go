@@ -0,0 +1,148 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	const openDuration = 50 * time.Millisecond
+	cb := &CircuitBreaker{FailureThreshold: 3, OpenDuration: openDuration}
+	errFailed := errors.New("failed")
+	calls := 0
+	fail := func() error { calls++; return errFailed }
+	succeed := func() error { calls++; return nil }
+
+	check := func(f func() error, wantErr error, wantCalls int, wantState State) {
+		t.Helper()
+		calls = 0
+		if err := cb.Do(context.Background(), f); !errors.Is(err, wantErr) {
+			t.Errorf("Do: got %v, want %v", err, wantErr)
+		}
+		if calls != wantCalls {
+			t.Errorf("got %d calls, want %d", calls, wantCalls)
+		}
+		if got := cb.State(); got != wantState {
+			t.Errorf("got state %s, want %s", got, wantState)
+		}
+	}
+
+	// A success resets the count of failures.
+	check(fail, errFailed, 1, Closed)
+	check(succeed, nil, 1, Closed)
+	// Failures up to the threshold open the breaker...
+	check(fail, errFailed, 1, Closed)
+	check(fail, errFailed, 1, Closed)
+	check(fail, errFailed, 1, Open)
+	// ...and then calls are short-circuited.
+	check(succeed, derrors.ProxyUnavailable, 0, Open)
+
+	// After OpenDuration, a failed trial call opens the breaker again.
+	time.Sleep(openDuration)
+	if got := cb.State(); got != HalfOpen {
+		t.Errorf("got state %s, want %s", got, HalfOpen)
+	}
+	check(fail, errFailed, 1, Open)
+	check(succeed, derrors.ProxyUnavailable, 0, Open)
+
+	// A successful trial call closes it.
+	time.Sleep(openDuration)
+	check(succeed, nil, 1, Closed)
+	check(fail, errFailed, 1, Closed)
+}
+
+func TestCircuitBreakerCanceled(t *testing.T) {
+	const openDuration = 50 * time.Millisecond
+	cb := &CircuitBreaker{FailureThreshold: 1, OpenDuration: openDuration}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	errFailed := errors.New("failed")
+
+	// Calls that fail after their context is done do not open the breaker.
+	for i := 0; i < 3; i++ {
+		if err := cb.Do(ctx, func() error { return ctx.Err() }); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Do: got %v, want %v", err, context.Canceled)
+		}
+	}
+	if got := cb.State(); got != Closed {
+		t.Fatalf("got state %s, want %s", got, Closed)
+	}
+
+	// A canceled trial call does not keep other trial calls out.
+	if err := cb.Do(context.Background(), func() error { return errFailed }); !errors.Is(err, errFailed) {
+		t.Fatalf("Do: got %v, want %v", err, errFailed)
+	}
+	time.Sleep(openDuration)
+	if err := cb.Do(ctx, func() error { return ctx.Err() }); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Do: got %v, want %v", err, context.Canceled)
+	}
+	if got := cb.State(); got != HalfOpen {
+		t.Fatalf("got state %s, want %s", got, HalfOpen)
+	}
+	if err := cb.Do(context.Background(), func() error { return nil }); err != nil {
+		t.Fatalf("Do: got %v, want nil", err)
+	}
+	if got := cb.State(); got != Closed {
+		t.Errorf("got state %s, want %s", got, Closed)
+	}
+}
+
+func TestClientCircuitBreaker(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	var (
+		requests int64
+		down     int32 = 1
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		if atomic.LoadInt32(&down) == 1 {
+			http.Error(w, "unavailable", http.StatusBadGateway)
+			return
+		}
+		http.Error(w, "not found", http.StatusNotFound)
+	})
+	client, teardownProxy := TestProxyServer(t, mux)
+	defer teardownProxy()
+	const openDuration = 50 * time.Millisecond
+	client.CircuitBreaker().OpenDuration = openDuration
+
+	for i := 0; i < DefaultFailureThreshold; i++ {
+		if _, err := client.ListVersions(ctx, "example.com/mod"); err == nil || errors.Is(err, derrors.ProxyUnavailable) {
+			t.Fatalf("request %d: got %v, want a proxy error", i, err)
+		}
+	}
+	if got := client.CircuitBreaker().State(); got != Open {
+		t.Fatalf("got state %s, want %s", got, Open)
+	}
+	// The proxy is no longer called.
+	if _, err := client.ListVersions(ctx, "example.com/mod"); !errors.Is(err, derrors.ProxyUnavailable) {
+		t.Errorf("got %v, want %v", err, derrors.ProxyUnavailable)
+	}
+	if got := atomic.LoadInt64(&requests); got != DefaultFailureThreshold {
+		t.Errorf("got %d requests, want %d", got, DefaultFailureThreshold)
+	}
+
+	// Once the proxy is back and OpenDuration has passed, requests go
+	// through again. A 404 is not a failure of the proxy.
+	atomic.StoreInt32(&down, 0)
+	time.Sleep(openDuration)
+	if _, err := client.ListVersions(ctx, "example.com/mod"); !errors.Is(err, derrors.NotFound) {
+		t.Errorf("got %v, want %v", err, derrors.NotFound)
+	}
+	if got := client.CircuitBreaker().State(); got != Closed {
+		t.Errorf("got state %s, want %s", got, Closed)
+	}
+}
@@ -34,6 +34,9 @@ type Client struct {
 
 	// client used for HTTP requests. It is mutable for testing purposes.
 	httpClient *http.Client
+
+	// breaker guards the HTTP requests to the proxy.
+	breaker *CircuitBreaker
 }
 
 // A VersionInfo contains metadata about a given version of a module.
@@ -54,7 +57,21 @@ func New(rawurl string) (_ *Client, err error) {
 		return nil, fmt.Errorf("scheme must be https (got %s)", url.Scheme)
 	}
 	cleanURL := strings.TrimRight(rawurl, "/")
-	return &Client{url: cleanURL, httpClient: &http.Client{Transport: &ochttp.Transport{}}}, nil
+	return &Client{
+		url:        cleanURL,
+		httpClient: &http.Client{Transport: &ochttp.Transport{}},
+		breaker: &CircuitBreaker{
+			FailureThreshold: DefaultFailureThreshold,
+			OpenDuration:     DefaultOpenDuration,
+		},
+	}, nil
+}
+
+// CircuitBreaker returns the circuit breaker that guards the requests of c to
+// the proxy. Requests are made only while it is not open: a request that
+// fails to get a response, or gets a 5xx response, counts as a failure.
+func (c *Client) CircuitBreaker() *CircuitBreaker {
+	return c.breaker
 }
 
 // GetInfo makes a request to $GOPROXY/<module>/@v/<requestedVersion>.info and
@@ -169,9 +186,24 @@ func (c *Client) ListVersions(ctx context.Context, modulePath string) ([]string,
 // executeRequest executes an HTTP GET request for u, then calls the bodyFunc
 // on the response body, if no error occurred.
 func (c *Client) executeRequest(ctx context.Context, u string, bodyFunc func(body io.Reader) error) error {
-	r, err := ctxhttp.Get(ctx, c.httpClient, u)
+	var r *http.Response
+	err := c.breaker.Do(ctx, func() error {
+		var err error
+		r, err = ctxhttp.Get(ctx, c.httpClient, u)
+		if err != nil {
+			return fmt.Errorf("ctxhttp.Get(ctx, client, %q): %v", u, err)
+		}
+		if r.StatusCode >= 500 {
+			r.Body.Close()
+			return fmt.Errorf("ctxhttp.Get(ctx, client, %q): unexpected status %d %s", u, r.StatusCode, r.Status)
+		}
+		return nil
+	})
+	if errors.Is(err, derrors.ProxyUnavailable) {
+		return fmt.Errorf("ctxhttp.Get(ctx, client, %q): %w", u, err)
+	}
 	if err != nil {
-		return fmt.Errorf("ctxhttp.Get(ctx, client, %q): %v", u, err)
+		return err
 	}
 	defer r.Body.Close()
 	switch {
@@ -83,30 +83,50 @@ func (ds *DataSource) LegacyGetDirectory(ctx context.Context, dirPath, modulePat
 	if err != nil {
 		return nil, err
 	}
+	var pkgs []*internal.LegacyPackage
+	for _, p := range v.LegacyPackages {
+		if p.Path == dirPath || strings.HasPrefix(p.Path, dirPath+"/") || dirPath == stdlib.ModulePath {
+			pkgs = append(pkgs, p)
+		}
+	}
+	if len(pkgs) == 0 {
+		return nil, fmt.Errorf("%q has no packages: %w", dirPath, derrors.NotFound)
+	}
 	return &internal.LegacyDirectory{
 		LegacyModuleInfo: internal.LegacyModuleInfo{ModuleInfo: v.ModuleInfo},
 		Path:             dirPath,
-		Packages:         v.LegacyPackages,
+		Packages:         pkgs,
 	}, nil
 }
 
 // GetDirectoryNew returns information about a directory at a path.
 func (ds *DataSource) GetDirectoryNew(ctx context.Context, dirPath, modulePath, version string) (_ *internal.VersionedDirectory, err error) {
+	defer derrors.Wrap(&err, "GetDirectoryNew(%q, %q, %q)", dirPath, modulePath, version)
 	m, err := ds.getModule(ctx, modulePath, version)
 	if err != nil {
 		return nil, err
 	}
+	d := findDirectory(m, dirPath)
+	if d == nil {
+		return nil, fmt.Errorf("%q missing from module %s: %w", dirPath, m.ModulePath, derrors.NotFound)
+	}
 	return &internal.VersionedDirectory{
-		ModuleInfo: m.ModuleInfo,
-		DirectoryNew: internal.DirectoryNew{
-			DirectoryMeta: internal.DirectoryMeta{
-				Path:   dirPath,
-				V1Path: internal.V1Path(modulePath, strings.TrimPrefix(dirPath, modulePath+"/")),
-			},
-		},
+		ModuleInfo:   m.ModuleInfo,
+		DirectoryNew: *d,
 	}, nil
 }
 
+// findDirectory returns the directory of m with path dirPath, or nil if there
+// is none.
+func findDirectory(m *internal.Module, dirPath string) *internal.DirectoryNew {
+	for _, d := range m.Directories {
+		if d.Path == dirPath {
+			return d
+		}
+	}
+	return nil
+}
+
 // GetImports returns package imports as extracted from the module zip.
 func (ds *DataSource) GetImports(ctx context.Context, pkgPath, modulePath, version string) (_ []string, err error) {
 	defer derrors.Wrap(&err, "GetImports(%q, %q, %q)", pkgPath, modulePath, version)
@@ -153,6 +173,29 @@ func (ds *DataSource) LegacyGetPackage(ctx context.Context, pkgPath, modulePath,
 	return packageFromVersion(pkgPath, m)
 }
 
+// LegacyGetPackages returns a LegacyVersionedPackage for each of pkgPaths in
+// the module version specified by modulePath and version, or nil for paths
+// that are not packages of the module. The module is fetched at most once.
+func (ds *DataSource) LegacyGetPackages(ctx context.Context, pkgPaths []string, modulePath, version string) (_ []*internal.LegacyVersionedPackage, err error) {
+	defer derrors.Wrap(&err, "LegacyGetPackages([%d paths], %q, %q)", len(pkgPaths), modulePath, version)
+	m, err := ds.getModule(ctx, modulePath, version)
+	if err != nil {
+		return nil, err
+	}
+	pkgs := make([]*internal.LegacyVersionedPackage, len(pkgPaths))
+	for i, p := range pkgPaths {
+		vp, err := packageFromVersion(p, m)
+		if err != nil {
+			if errors.Is(err, derrors.NotFound) {
+				continue
+			}
+			return nil, err
+		}
+		pkgs[i] = vp
+	}
+	return pkgs, nil
+}
+
 // LegacyGetPackageLicenses returns the Licenses that apply to pkgPath within the
 // module version specified by modulePath and version.
 func (ds *DataSource) LegacyGetPackageLicenses(ctx context.Context, pkgPath, modulePath, version string) (_ []*licenses.License, err error) {
@@ -189,6 +232,12 @@ func (ds *DataSource) LegacyGetPackagesInModule(ctx context.Context, modulePath,
 	return v.LegacyPackages, nil
 }
 
+// GetSearchSuggestions returns no suggestions, since the proxy cannot be
+// searched.
+func (ds *DataSource) GetSearchSuggestions(ctx context.Context, prefix string, limit int) ([]*internal.SearchSuggestion, error) {
+	return nil, nil
+}
+
 // GetPseudoVersionsForModule returns versions from the the proxy /list
 // endpoint, if they are pseudoversions. Otherwise, it returns an empty slice.
 func (ds *DataSource) GetPseudoVersionsForModule(ctx context.Context, modulePath string) (_ []*internal.ModuleInfo, err error) {
@@ -253,11 +302,16 @@ func (ds *DataSource) getModule(ctx context.Context, modulePath, version string)
 		v = stdlib.VersionForTag(v)
 	}
 	res := fetch.FetchModule(ctx, modulePath, v, ds.proxyClient, ds.sourceClient)
-	m := res.Module
-	ds.versionCache[key] = &versionEntry{module: m, err: err}
 	if res.Error != nil {
+		// Other errors, like those of canceled requests, may not recur, so
+		// only the absence of the module version is cached.
+		if errors.Is(res.Error, derrors.NotFound) {
+			ds.versionCache[key] = &versionEntry{err: res.Error}
+		}
 		return nil, res.Error
 	}
+	m := res.Module
+	ds.versionCache[key] = &versionEntry{module: m}
 
 	// Since we hold the lock and missed the cache, we can assume that we have
 	// never seen this module version. Therefore the following insert-and-sort
@@ -426,6 +480,131 @@ func (*DataSource) GetExperiments(ctx context.Context) ([]*internal.Experiment,
 	return nil, nil
 }
 
+// GetModuleDependencies is unimplemented.
+func (*DataSource) GetModuleDependencies(ctx context.Context, modulePath, version string) ([]*internal.ModuleDependency, error) {
+	return nil, nil
+}
+
+// GetPackageSymbols returns the exported symbols of the package, as extracted
+// from the module zip.
+func (ds *DataSource) GetPackageSymbols(ctx context.Context, pkgPath, version string) (_ []*internal.Symbol, err error) {
+	defer derrors.Wrap(&err, "GetPackageSymbols(%q, %q)", pkgPath, version)
+	vp, err := ds.LegacyGetPackage(ctx, pkgPath, internal.UnknownModulePath, version)
+	if err != nil {
+		return nil, err
+	}
+	syms := append([]*internal.Symbol(nil), vp.Symbols...)
+	sort.Slice(syms, func(i, j int) bool { return syms[i].Name < syms[j].Name })
+	return syms, nil
+}
+
+// GetExamples returns the examples of the package, as extracted from the
+// module zip.
+func (ds *DataSource) GetExamples(ctx context.Context, pkgPath, version string) (_ []*internal.Example, err error) {
+	defer derrors.Wrap(&err, "GetExamples(%q, %q)", pkgPath, version)
+	vp, err := ds.LegacyGetPackage(ctx, pkgPath, internal.UnknownModulePath, version)
+	if err != nil {
+		return nil, err
+	}
+	exs := append([]*internal.Example(nil), vp.Examples...)
+	sort.Slice(exs, func(i, j int) bool { return exs[i].Name < exs[j].Name })
+	return exs, nil
+}
+
+// GetVulnerabilities is unimplemented.
+func (*DataSource) GetVulnerabilities(ctx context.Context, modulePath, version string) ([]*internal.VulnReport, error) {
+	return nil, nil
+}
+
+// GetRetractedVersions is unimplemented.
+func (*DataSource) GetRetractedVersions(ctx context.Context, modulePath string) ([]string, error) {
+	return nil, nil
+}
+
+// GetWorkspace is unimplemented.
+func (*DataSource) GetWorkspace(ctx context.Context, repoPath string) (*internal.Workspace, error) {
+	return nil, nil
+}
+
+// GetModuleLinks returns the links of the latest version of the module, as
+// detected when it was fetched.
+func (ds *DataSource) GetModuleLinks(ctx context.Context, modulePath string) (_ *internal.ModuleLinks, err error) {
+	defer derrors.Wrap(&err, "GetModuleLinks(%q)", modulePath)
+	m, err := ds.getModule(ctx, modulePath, internal.LatestVersion)
+	if err != nil {
+		return nil, err
+	}
+	if m.Links == nil {
+		return nil, derrors.NotFound
+	}
+	return m.Links, nil
+}
+
+// GetModuleFiles is unimplemented.
+func (*DataSource) GetModuleFiles(ctx context.Context, modulePath, version, subpath string) ([]*internal.FileInfo, error) {
+	return nil, nil
+}
+
+// GetExportedSymbolCount is unimplemented.
+func (*DataSource) GetExportedSymbolCount(ctx context.Context, pkgPath, version string) (int, error) {
+	return 0, nil
+}
+
+// GetBuildContexts is unimplemented.
+func (*DataSource) GetBuildContexts(ctx context.Context, pkgPath, version string) ([]internal.BuildContext, error) {
+	return nil, nil
+}
+
+// GetEmbeddedPatterns is unimplemented.
+func (*DataSource) GetEmbeddedPatterns(ctx context.Context, pkgPath, version string) ([]string, error) {
+	return nil, derrors.NotFound
+}
+
+// GetHasFuzzTests is unimplemented.
+func (*DataSource) GetHasFuzzTests(ctx context.Context, pkgPath, version string) (bool, error) {
+	return false, nil
+}
+
+// GetBenchmarkCount is unimplemented.
+func (*DataSource) GetBenchmarkCount(ctx context.Context, pkgPath, version string) (int, error) {
+	return 0, nil
+}
+
+// GetContributorCount is unimplemented.
+func (*DataSource) GetContributorCount(ctx context.Context, modulePath, version string) (int, error) {
+	return 0, nil
+}
+
+// GetSuccessorPackages is unimplemented.
+func (*DataSource) GetSuccessorPackages(ctx context.Context, pkgPath, version string) ([]string, error) {
+	return nil, nil
+}
+
+// GetSymbolDefinition is unimplemented.
+func (*DataSource) GetSymbolDefinition(ctx context.Context, pkgPath, symbolName string) (*internal.SymbolLocation, error) {
+	return nil, nil
+}
+
+// GetInterfaceImplementations is unimplemented.
+func (*DataSource) GetInterfaceImplementations(ctx context.Context, ifacePkg, ifaceName string) ([]*internal.TypeRef, error) {
+	return nil, nil
+}
+
+// GetRecentlyIndexedModules is unimplemented.
+func (*DataSource) GetRecentlyIndexedModules(ctx context.Context, limit int, since time.Time) ([]*internal.IndexedModule, error) {
+	return nil, nil
+}
+
+// GetPackagePathsForSitemap is unimplemented.
+func (*DataSource) GetPackagePathsForSitemap(ctx context.Context, offset, limit int) ([]string, error) {
+	return nil, nil
+}
+
+// GetSitemapPackageCount is unimplemented.
+func (*DataSource) GetSitemapPackageCount(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
 // GetPathInfo returns information about the given path.
 func (ds *DataSource) GetPathInfo(ctx context.Context, path, inModulePath, inVersion string) (outModulePath, outVersion string, isPackage bool, err error) {
 	defer derrors.Wrap(&err, "GetPathInfo(%q, %q, %q)", path, inModulePath, inVersion)
@@ -442,12 +621,9 @@ func (ds *DataSource) GetPathInfo(ctx context.Context, path, inModulePath, inVer
 	if err != nil {
 		return "", "", false, err
 	}
-	isPackage = false
-	for _, p := range m.LegacyPackages {
-		if p.Path == path {
-			isPackage = true
-			break
-		}
+	d := findDirectory(m, path)
+	if d == nil {
+		return "", "", false, fmt.Errorf("%q missing from module %s: %w", path, m.ModulePath, derrors.NotFound)
 	}
-	return m.ModulePath, m.Version, isPackage, nil
+	return m.ModulePath, m.Version, d.Package != nil, nil
 }
@@ -14,6 +14,7 @@ import (
 	"golang.org/x/pkgsite/internal"
 	"golang.org/x/pkgsite/internal/licenses"
 	"golang.org/x/pkgsite/internal/proxy"
+	"golang.org/x/pkgsite/internal/testing/datasourcetest"
 	"golang.org/x/pkgsite/internal/testing/sample"
 	"golang.org/x/pkgsite/internal/testing/testhelper"
 	"golang.org/x/pkgsite/internal/version"
@@ -73,7 +74,7 @@ var (
 		LegacyPackage:    wantPackage,
 	}
 	cmpOpts = append([]cmp.Option{
-		cmpopts.IgnoreFields(internal.LegacyPackage{}, "DocumentationHTML"),
+		cmpopts.IgnoreFields(internal.LegacyPackage{}, "DocumentationHTML", "Symbols", "BuildContexts", "Examples", "BenchmarkCount"),
 		cmpopts.IgnoreFields(licenses.License{}, "Contents"),
 	}, sample.LicenseCmpOpts...)
 )
@@ -292,3 +293,22 @@ func TestDataSource_GetPathInfo(t *testing.T) {
 		}
 	}
 }
+
+func TestDataSource_LegacyGetPackages(t *testing.T) {
+	ctx, ds, teardown := setup(t)
+	defer teardown()
+	got, err := ds.LegacyGetPackages(ctx, []string{"foo.com/bar/missing", "foo.com/bar/baz"}, "foo.com/bar", "v1.2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*internal.LegacyVersionedPackage{nil, wantVersionedPackage}
+	if diff := cmp.Diff(want, got, cmpOpts...); diff != "" {
+		t.Errorf("LegacyGetPackages diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestContract(t *testing.T) {
+	client, teardownProxy := proxy.SetupTestProxy(t, datasourcetest.TestModules())
+	defer teardownProxy()
+	datasourcetest.DataSourceContractTest(t, New(client))
+}
@@ -0,0 +1,100 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package sbom describes the dependencies of modules as software bills of
+// materials in the CycloneDX 1.4 JSON format. See
+// https://cyclonedx.org/docs/1.4/json/.
+package sbom
+
+import (
+	"net/url"
+	"strings"
+)
+
+// ContentType is the media type of a CycloneDXDocument.
+const ContentType = "application/vnd.cyclonedx+json; version=1.4"
+
+// A CycloneDXDocument is a CycloneDX bill of materials.
+type CycloneDXDocument struct {
+	BOMFormat   string    `json:"bomFormat"`
+	SpecVersion string    `json:"specVersion"`
+	Version     int       `json:"version"`
+	Metadata    *Metadata `json:"metadata,omitempty"`
+	// Components are the dependencies of the component of the metadata.
+	Components []Component `json:"components"`
+}
+
+// Metadata describes the subject of a CycloneDXDocument.
+type Metadata struct {
+	Component *Component `json:"component,omitempty"`
+}
+
+// A Component is a module version.
+type Component struct {
+	Type     string          `json:"type"`
+	BOMRef   string          `json:"bom-ref,omitempty"`
+	Name     string          `json:"name"`
+	Version  string          `json:"version"`
+	PURL     string          `json:"purl"`
+	Licenses []LicenseChoice `json:"licenses,omitempty"`
+}
+
+// A LicenseChoice is an element of the licenses of a Component.
+type LicenseChoice struct {
+	License License `json:"license"`
+}
+
+// A License is a license, identified by its SPDX ID.
+type License struct {
+	ID string `json:"id"`
+}
+
+// New returns a CycloneDXDocument for the module version described by root,
+// which depends on the modules described by components.
+func New(root Component, components []Component) *CycloneDXDocument {
+	if components == nil {
+		components = []Component{}
+	}
+	return &CycloneDXDocument{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.4",
+		Version:     1,
+		Metadata:    &Metadata{Component: &root},
+		Components:  components,
+	}
+}
+
+// NewComponent returns the Component for the module version, whose licenses
+// have the given types, as detected by the licenses package.
+func NewComponent(modulePath, version string, licenseTypes []string) Component {
+	purl := PURL(modulePath, version)
+	c := Component{
+		Type:    "library",
+		BOMRef:  purl,
+		Name:    modulePath,
+		Version: version,
+		PURL:    purl,
+	}
+	for _, t := range licenseTypes {
+		c.Licenses = append(c.Licenses, LicenseChoice{License{ID: t}})
+	}
+	return c
+}
+
+// PURL returns the package URL of the module version, of the form
+// pkg:golang/<module-path>@<version>. See
+// https://github.com/package-url/purl-spec.
+func PURL(modulePath, version string) string {
+	segs := strings.Split(modulePath, "/")
+	for i, s := range segs {
+		segs[i] = escape(s)
+	}
+	return "pkg:golang/" + strings.Join(segs, "/") + "@" + escape(version)
+}
+
+// escape percent-encodes s for use in a package URL, in which '+' is not
+// allowed.
+func escape(s string) string {
+	return strings.ReplaceAll(url.PathEscape(s), "+", "%2B")
+}
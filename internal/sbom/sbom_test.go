@@ -0,0 +1,99 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sbom
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPURL(t *testing.T) {
+	for _, test := range []struct {
+		modulePath, version, want string
+	}{
+		{"github.com/google/go-cmp", "v0.5.6", "pkg:golang/github.com/google/go-cmp@v0.5.6"},
+		{"example.com/m", "v2.0.0+incompatible", "pkg:golang/example.com/m@v2.0.0%2Bincompatible"},
+		{"example.com/a b", "v1.0.0", "pkg:golang/example.com/a%20b@v1.0.0"},
+	} {
+		if got := PURL(test.modulePath, test.version); got != test.want {
+			t.Errorf("PURL(%q, %q) = %q, want %q", test.modulePath, test.version, got, test.want)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	doc := New(
+		NewComponent("example.com/m", "v1.2.0", []string{"MIT"}),
+		[]Component{
+			NewComponent("github.com/google/go-cmp", "v0.5.6", []string{"BSD-3-Clause"}),
+			NewComponent("example.com/unlicensed", "v0.1.0", nil),
+		})
+	got, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  "bomFormat": "CycloneDX",
+  "specVersion": "1.4",
+  "version": 1,
+  "metadata": {
+    "component": {
+      "type": "library",
+      "bom-ref": "pkg:golang/example.com/m@v1.2.0",
+      "name": "example.com/m",
+      "version": "v1.2.0",
+      "purl": "pkg:golang/example.com/m@v1.2.0",
+      "licenses": [
+        {
+          "license": {
+            "id": "MIT"
+          }
+        }
+      ]
+    }
+  },
+  "components": [
+    {
+      "type": "library",
+      "bom-ref": "pkg:golang/github.com/google/go-cmp@v0.5.6",
+      "name": "github.com/google/go-cmp",
+      "version": "v0.5.6",
+      "purl": "pkg:golang/github.com/google/go-cmp@v0.5.6",
+      "licenses": [
+        {
+          "license": {
+            "id": "BSD-3-Clause"
+          }
+        }
+      ]
+    },
+    {
+      "type": "library",
+      "bom-ref": "pkg:golang/example.com/unlicensed@v0.1.0",
+      "name": "example.com/unlicensed",
+      "version": "v0.1.0",
+      "purl": "pkg:golang/example.com/unlicensed@v0.1.0"
+    }
+  ]
+}`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	// A module without dependencies has an empty list of components.
+	got, err = json.Marshal(New(NewComponent("example.com/m", "v1.2.0", nil), nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(got, &m); err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := m["components"].([]interface{}); !ok || len(c) != 0 {
+		t.Errorf("got components %v, want empty list", m["components"])
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package scoring computes the health score of modules, a single number
+// between 0 and 100 that summarizes their quality.
+package scoring
+
+import (
+	"math"
+	"path"
+	"strings"
+
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/stdlib"
+)
+
+// The points that each factor contributes to the health score. They add up
+// to MaxHealthScore.
+const (
+	GoModPoints           = 20
+	RedistributablePoints = 20
+	ReadmePoints          = 15
+	TestsPoints           = 15
+	CIPoints              = 10
+	MaxImportedByPoints   = 20
+
+	MaxHealthScore = GoModPoints + RedistributablePoints + ReadmePoints + TestsPoints + CIPoints + MaxImportedByPoints
+)
+
+// FullImportedByCount is the imported-by count at which a package gets all of
+// MaxImportedByPoints.
+const FullImportedByCount = 1000
+
+// ComputeHealthScore returns the health score of a package of m that is
+// imported by importedByCount packages. The score combines whether m has a
+// go.mod file, is redistributable, has a README, has tests and has a CI
+// configuration, each worth a fixed number of points, with the points
+// returned by ImportedByPoints.
+//
+// Tests and CI configuration are detected from m.Files, so a module without
+// files gets no points for them.
+func ComputeHealthScore(m *internal.Module, importedByCount int) float64 {
+	var score float64
+	if m.HasGoMod {
+		score += GoModPoints
+	}
+	if m.IsRedistributable {
+		score += RedistributablePoints
+	}
+	if m.LegacyReadmeFilePath != "" {
+		score += ReadmePoints
+	}
+	if HasTests(m) {
+		score += TestsPoints
+	}
+	if HasCIConfig(m) {
+		score += CIPoints
+	}
+	return score + ImportedByPoints(importedByCount)
+}
+
+// ImportedByPoints returns the points contributed to the health score by an
+// imported-by count. They grow with the logarithm of the count, up to
+// MaxImportedByPoints for FullImportedByCount or more importers.
+func ImportedByPoints(count int) float64 {
+	if count <= 0 {
+		return 0
+	}
+	return math.Min(MaxImportedByPoints, MaxImportedByPoints*math.Log10(1+float64(count))/math.Log10(1+FullImportedByCount))
+}
+
+// HasTests reports whether m has a Go test file.
+func HasTests(m *internal.Module) bool {
+	for _, f := range m.Files {
+		if !f.IsDir && strings.HasSuffix(f.Name, "_test.go") {
+			return true
+		}
+	}
+	return false
+}
+
+// PackageHasTests reports whether the directory of the package of m with
+// path pkgPath has a Go test file. Test files in subdirectories belong to
+// other packages, so they are not considered.
+func PackageHasTests(m *internal.Module, pkgPath string) bool {
+	var dir string
+	switch {
+	case pkgPath == m.ModulePath:
+		dir = "."
+	case m.ModulePath == stdlib.ModulePath:
+		dir = pkgPath
+	default:
+		dir = strings.TrimPrefix(pkgPath, m.ModulePath+"/")
+	}
+	for _, f := range m.Files {
+		if !f.IsDir && strings.HasSuffix(f.Name, "_test.go") && path.Dir(f.Name) == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// HasCIConfig reports whether m has a configuration file of a common
+// continuous integration service: a GitHub Actions workflow or a
+// .travis.yml file at the module root.
+func HasCIConfig(m *internal.Module) bool {
+	for _, f := range m.Files {
+		if f.IsDir {
+			continue
+		}
+		if f.Name == ".travis.yml" || path.Dir(f.Name) == ".github/workflows" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scoring
+
+import (
+	"math"
+	"testing"
+
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/stdlib"
+)
+
+func TestComputeHealthScore(t *testing.T) {
+	files := func(names ...string) []*internal.FileInfo {
+		var fs []*internal.FileInfo
+		for _, n := range names {
+			fs = append(fs, &internal.FileInfo{Name: n})
+		}
+		return fs
+	}
+	full := func() *internal.Module {
+		m := &internal.Module{Files: files("go.mod", "p/p.go", "p/p_test.go", ".github/workflows/test.yml")}
+		m.HasGoMod = true
+		m.IsRedistributable = true
+		m.LegacyReadmeFilePath = "README.md"
+		return m
+	}
+	for _, test := range []struct {
+		name            string
+		modify          func(*internal.Module)
+		importedByCount int
+		want            float64
+	}{
+		{"all factors", func(*internal.Module) {}, FullImportedByCount, MaxHealthScore},
+		{"no importers", func(*internal.Module) {}, 0, MaxHealthScore - MaxImportedByPoints},
+		{"no go.mod", func(m *internal.Module) { m.HasGoMod = false }, FullImportedByCount, MaxHealthScore - GoModPoints},
+		{"not redistributable", func(m *internal.Module) { m.IsRedistributable = false }, FullImportedByCount, MaxHealthScore - RedistributablePoints},
+		{"no README", func(m *internal.Module) { m.LegacyReadmeFilePath = "" }, FullImportedByCount, MaxHealthScore - ReadmePoints},
+		{"no tests", func(m *internal.Module) { m.Files = files("go.mod", "p/p.go", ".github/workflows/test.yml") }, FullImportedByCount, MaxHealthScore - TestsPoints},
+		{"no CI", func(m *internal.Module) { m.Files = files("go.mod", "p/p.go", "p/p_test.go") }, FullImportedByCount, MaxHealthScore - CIPoints},
+		{"nothing", func(m *internal.Module) { *m = internal.Module{} }, 0, 0},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			m := full()
+			test.modify(m)
+			if got := ComputeHealthScore(m, test.importedByCount); math.Abs(got-test.want) > 1e-9 {
+				t.Errorf("ComputeHealthScore(m, %d) = %f, want %f", test.importedByCount, got, test.want)
+			}
+		})
+	}
+}
+
+func TestImportedByPoints(t *testing.T) {
+	for _, test := range []struct {
+		count int
+		want  float64
+	}{
+		{-1, 0},
+		{0, 0},
+		{FullImportedByCount, MaxImportedByPoints},
+		{10 * FullImportedByCount, MaxImportedByPoints},
+		// log10(1+10)/log10(1+1000) is about a third.
+		{10, 6.9416},
+		{100, 13.3602},
+	} {
+		if got := ImportedByPoints(test.count); math.Abs(got-test.want) > 1e-4 {
+			t.Errorf("ImportedByPoints(%d) = %f, want %f", test.count, got, test.want)
+		}
+	}
+}
+
+func TestHasTests(t *testing.T) {
+	for _, test := range []struct {
+		files []*internal.FileInfo
+		want  bool
+	}{
+		{nil, false},
+		{[]*internal.FileInfo{{Name: "p.go"}}, false},
+		{[]*internal.FileInfo{{Name: "p.go"}, {Name: "a/b/p_test.go"}}, true},
+		{[]*internal.FileInfo{{Name: "x_test.go", IsDir: true}}, false},
+	} {
+		if got := HasTests(&internal.Module{Files: test.files}); got != test.want {
+			t.Errorf("HasTests(%v) = %t, want %t", test.files, got, test.want)
+		}
+	}
+}
+
+func TestPackageHasTests(t *testing.T) {
+	m := &internal.Module{
+		LegacyModuleInfo: internal.LegacyModuleInfo{ModuleInfo: internal.ModuleInfo{ModulePath: "example.com/m"}},
+		Files: []*internal.FileInfo{
+			{Name: "m.go"},
+			{Name: "m_test.go"},
+			{Name: "a/a.go"},
+			{Name: "a/b/b.go"},
+			{Name: "a/b/b_test.go"},
+		},
+	}
+	for _, test := range []struct {
+		pkgPath string
+		want    bool
+	}{
+		{"example.com/m", true},
+		{"example.com/m/a", false},
+		{"example.com/m/a/b", true},
+	} {
+		if got := PackageHasTests(m, test.pkgPath); got != test.want {
+			t.Errorf("PackageHasTests(m, %q) = %t, want %t", test.pkgPath, got, test.want)
+		}
+	}
+
+	std := &internal.Module{
+		LegacyModuleInfo: internal.LegacyModuleInfo{ModuleInfo: internal.ModuleInfo{ModulePath: stdlib.ModulePath}},
+		Files:            []*internal.FileInfo{{Name: "strings/strings_test.go"}},
+	}
+	if !PackageHasTests(std, "strings") {
+		t.Error("PackageHasTests(std, \"strings\") = false, want true")
+	}
+}
+
+func TestHasCIConfig(t *testing.T) {
+	for _, name := range []string{".travis.yml", ".github/workflows/go.yml"} {
+		if !HasCIConfig(&internal.Module{Files: []*internal.FileInfo{{Name: name}}}) {
+			t.Errorf("HasCIConfig with file %q = false, want true", name)
+		}
+	}
+	for _, name := range []string{"p/.travis.yml", ".github/dependabot.yml", "workflows/go.yml"} {
+		if HasCIConfig(&internal.Module{Files: []*internal.FileInfo{{Name: name}}}) {
+			t.Errorf("HasCIConfig with file %q = true, want false", name)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package search parses the search queries entered by users into free-text
+// terms and field filters.
+package search
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+// Fields that can be used in a field filter.
+const (
+	// FieldBenchmarks matches packages by whether they have benchmarks,
+	// like "benchmarks:true".
+	FieldBenchmarks = "benchmarks"
+	// FieldCgo matches packages by whether they require cgo, like
+	// "cgo:false".
+	FieldCgo = "cgo"
+	// FieldFuzz matches packages by whether they have fuzz tests, like
+	// "fuzz:true".
+	FieldFuzz = "fuzz"
+	// FieldLicense matches packages that have a license of the given type,
+	// like "license:MIT".
+	FieldLicense = "license"
+	// FieldImported matches packages by the number of packages that import
+	// them, like "imported:>100".
+	FieldImported = "imported"
+	// FieldPath matches packages whose import path is, or is under, the given
+	// path, like "path:github.com/foo".
+	FieldPath = "path"
+	// FieldRedistributable matches packages by whether their licenses allow
+	// pkgsite to display their documentation, like "redistributable:true".
+	FieldRedistributable = "redistributable"
+)
+
+// Operators that can appear in a field filter.
+const (
+	OpEqual          = "="
+	OpGreater        = ">"
+	OpGreaterOrEqual = ">="
+	OpLess           = "<"
+	OpLessOrEqual    = "<="
+)
+
+// fieldOps maps each supported field to the operators it supports.
+var fieldOps = map[string][]string{
+	FieldBenchmarks:      {OpEqual},
+	FieldCgo:             {OpEqual},
+	FieldFuzz:            {OpEqual},
+	FieldLicense:         {OpEqual},
+	FieldImported:        {OpEqual, OpGreater, OpGreaterOrEqual, OpLess, OpLessOrEqual},
+	FieldPath:            {OpEqual},
+	FieldRedistributable: {OpEqual},
+}
+
+// fieldAliases maps alternative names of fields to the fields they stand
+// for.
+var fieldAliases = map[string]string{
+	"has_benchmarks": FieldBenchmarks,
+	"has_fuzz_tests": FieldFuzz,
+}
+
+// Fields returns the names of the fields supported in field filters, in
+// sorted order.
+func Fields() []string {
+	return []string{FieldBenchmarks, FieldCgo, FieldFuzz, FieldImported, FieldLicense, FieldPath, FieldRedistributable}
+}
+
+// SearchQuery is a parsed search query.
+type SearchQuery struct {
+	// Filters are the field predicates in the query, in the order they
+	// appeared.
+	Filters []FieldFilter
+	// Terms are the free-text parts of the query, lower-cased. Quoted
+	// phrases are kept intact, including their quotes.
+	Terms []string
+}
+
+// Text returns the free-text part of the query, suitable for passing to
+// websearch_to_tsquery.
+func (q SearchQuery) Text() string {
+	return strings.Join(q.Terms, " ")
+}
+
+// String returns the query with its terms followed by its filters, so that
+// queries that differ only in the case of their terms or in the order of
+// their terms and filters have the same string.
+func (q SearchQuery) String() string {
+	words := append([]string(nil), q.Terms...)
+	for _, f := range q.Filters {
+		words = append(words, f.String())
+	}
+	return strings.Join(words, " ")
+}
+
+// FieldFilter is a predicate of the form field:value, or field:<op>value for
+// comparison operators, like "imported:>100".
+type FieldFilter struct {
+	Field string
+	Op    string
+	Value string
+}
+
+// String returns the filter in the form it is written in a query.
+func (f FieldFilter) String() string {
+	if f.Op == OpEqual {
+		return f.Field + ":" + f.Value
+	}
+	return f.Field + ":" + f.Op + f.Value
+}
+
+// ParseSearchQuery splits q into field filters and free-text terms.
+//
+// A field filter is a word of the form field:value, where field is one of
+// the supported fields or an alias of one, like "has_fuzz_tests" for "fuzz",
+// in any case. Aliases are replaced by the field they stand for. The value
+// is kept as written, since some, like import paths, are case-sensitive. If
+// it is malformed, the error wraps derrors.InvalidArgument. All other words,
+// including URLs like "https://github.com/foo" and identifiers like
+// "http:handler", and phrases in double quotes, are terms. Terms are
+// lower-cased.
+func ParseSearchQuery(q string) (SearchQuery, error) {
+	var sq SearchQuery
+	for _, word := range splitWords(q) {
+		field, value, ok := splitField(word)
+		if !ok {
+			sq.Terms = append(sq.Terms, strings.ToLower(word))
+			continue
+		}
+		f, err := parseFilter(field, value, fieldOps[field])
+		if err != nil {
+			return SearchQuery{}, err
+		}
+		sq.Filters = append(sq.Filters, f)
+	}
+	return sq, nil
+}
+
+// MaxQueryLength is the maximum length of a normalized search query, in
+// characters.
+const MaxQueryLength = 500
+
+// NormalizeSearchQuery returns q percent-decoded, as it may come from a URL
+// that was encoded twice, with its white space trimmed and collapsed to single
+// spaces. It does not change case: ParseSearchQuery lower-cases the terms of
+// the query, but not the values of its filters. A q that is not a valid
+// percent-encoding, like "100%", is not decoded. The returned error wraps derrors.InvalidArgument if
+// the result contains a null byte or is longer than MaxQueryLength.
+func NormalizeSearchQuery(q string) (string, error) {
+	if dq, err := url.PathUnescape(q); err == nil {
+		q = dq
+	}
+	if strings.ContainsRune(q, 0) {
+		return "", fmt.Errorf("search query contains a null byte: %w", derrors.InvalidArgument)
+	}
+	q = strings.Join(strings.Fields(q), " ")
+	if n := utf8.RuneCountInString(q); n > MaxQueryLength {
+		return "", fmt.Errorf("search query is %d characters long, more than %d: %w", n, MaxQueryLength, derrors.InvalidArgument)
+	}
+	return q, nil
+}
+
+// splitWords splits q on white space, keeping double-quoted phrases
+// together.
+func splitWords(q string) []string {
+	var (
+		words   []string
+		b       strings.Builder
+		inQuote bool
+	)
+	for _, r := range q {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			b.WriteRune(r)
+		case unicode.IsSpace(r) && !inQuote:
+			if b.Len() > 0 {
+				words = append(words, b.String())
+				b.Reset()
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() > 0 {
+		words = append(words, b.String())
+	}
+	return words
+}
+
+// splitField splits a word of the form field:value, lower-casing field and
+// replacing an alias of a field by the field. It reports false if word does
+// not have that form, or if field is not a supported field or alias.
+func splitField(word string) (field, value string, ok bool) {
+	i := strings.IndexByte(word, ':')
+	if i <= 0 {
+		return "", "", false
+	}
+	field = strings.ToLower(word[:i])
+	if f, ok := fieldAliases[field]; ok {
+		field = f
+	}
+	if _, ok := fieldOps[field]; !ok {
+		return "", "", false
+	}
+	return field, word[i+1:], true
+}
+
+// parseFilter parses the value of a filter on field, which supports the
+// given operators.
+func parseFilter(field, value string, ops []string) (_ FieldFilter, err error) {
+	defer derrors.Wrap(&err, "parseFilter(%q, %q)", field, value)
+
+	f := FieldFilter{Field: field, Op: OpEqual, Value: value}
+	// Check the two-character operators first, so that ">=" is not parsed
+	// as ">".
+	for _, op := range []string{OpGreaterOrEqual, OpLessOrEqual, OpGreater, OpLess, OpEqual} {
+		if strings.HasPrefix(value, op) {
+			f.Op = op
+			f.Value = value[len(op):]
+			break
+		}
+	}
+	if !containsString(ops, f.Op) {
+		return FieldFilter{}, fmt.Errorf("operator %q not supported for field %q: %w", f.Op, field, derrors.InvalidArgument)
+	}
+	if f.Value == "" {
+		return FieldFilter{}, fmt.Errorf("missing value for field %q: %w", field, derrors.InvalidArgument)
+	}
+	switch field {
+	case FieldImported:
+		if _, err := strconv.Atoi(f.Value); err != nil {
+			return FieldFilter{}, fmt.Errorf("value for field %q must be an integer: %w", field, derrors.InvalidArgument)
+		}
+	case FieldBenchmarks, FieldCgo, FieldFuzz, FieldRedistributable:
+		if _, err := strconv.ParseBool(f.Value); err != nil {
+			return FieldFilter{}, fmt.Errorf("value for field %q must be true or false: %w", field, derrors.InvalidArgument)
+		}
+	}
+	return f, nil
+}
+
+func containsString(ss []string, s string) bool {
+	for _, t := range ss {
+		if t == s {
+			return true
+		}
+	}
+	return false
+}
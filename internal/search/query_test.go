@@ -0,0 +1,272 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package search
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	for _, test := range []struct {
+		q    string
+		want SearchQuery
+	}{
+		{"", SearchQuery{}},
+		{"json", SearchQuery{Terms: []string{"json"}}},
+		{
+			"  yaml   OR json ",
+			SearchQuery{Terms: []string{"yaml", "or", "json"}},
+		},
+		{
+			`"go cloud" kit`,
+			SearchQuery{Terms: []string{`"go cloud"`, "kit"}},
+		},
+		{
+			"license:MIT imported:>100 path:github.com/foo json",
+			SearchQuery{
+				Filters: []FieldFilter{
+					{Field: "license", Op: OpEqual, Value: "MIT"},
+					{Field: "imported", Op: OpGreater, Value: "100"},
+					{Field: "path", Op: OpEqual, Value: "github.com/foo"},
+				},
+				Terms: []string{"json"},
+			},
+		},
+		{
+			"imported:>=5 imported:<=10 imported:<7 imported:=3 imported:4",
+			SearchQuery{
+				Filters: []FieldFilter{
+					{Field: "imported", Op: OpGreaterOrEqual, Value: "5"},
+					{Field: "imported", Op: OpLessOrEqual, Value: "10"},
+					{Field: "imported", Op: OpLess, Value: "7"},
+					{Field: "imported", Op: OpEqual, Value: "3"},
+					{Field: "imported", Op: OpEqual, Value: "4"},
+				},
+			},
+		},
+		{
+			"cgo:false sqlite",
+			SearchQuery{
+				Filters: []FieldFilter{{Field: "cgo", Op: OpEqual, Value: "false"}},
+				Terms:   []string{"sqlite"},
+			},
+		},
+		{
+			"redistributable:true yaml",
+			SearchQuery{
+				Filters: []FieldFilter{{Field: "redistributable", Op: OpEqual, Value: "true"}},
+				Terms:   []string{"yaml"},
+			},
+		},
+		{
+			"fuzz:true benchmarks:true parser",
+			SearchQuery{
+				Filters: []FieldFilter{
+					{Field: "fuzz", Op: OpEqual, Value: "true"},
+					{Field: "benchmarks", Op: OpEqual, Value: "true"},
+				},
+				Terms: []string{"parser"},
+			},
+		},
+		{
+			"has_fuzz_tests:true parser",
+			SearchQuery{
+				Filters: []FieldFilter{{Field: "fuzz", Op: OpEqual, Value: "true"}},
+				Terms:   []string{"parser"},
+			},
+		},
+		{
+			"has_benchmarks:false parser",
+			SearchQuery{
+				Filters: []FieldFilter{{Field: "benchmarks", Op: OpEqual, Value: "false"}},
+				Terms:   []string{"parser"},
+			},
+		},
+		{
+			// Words that don't look like filters are terms.
+			"Foo:bar :x std/fmt",
+			SearchQuery{Terms: []string{"foo:bar", ":x", "std/fmt"}},
+		},
+		{
+			// So are words whose field is not supported.
+			"json author:rsc http:handler https://github.com/foo/bar",
+			SearchQuery{Terms: []string{"json", "author:rsc", "http:handler", "https://github.com/foo/bar"}},
+		},
+		{
+			"license:MIT path:github.com/foo",
+			SearchQuery{
+				Filters: []FieldFilter{
+					{Field: "license", Op: OpEqual, Value: "MIT"},
+					{Field: "path", Op: OpEqual, Value: "github.com/foo"},
+				},
+			},
+		},
+		{
+			`"license:MIT" yaml`,
+			SearchQuery{Terms: []string{`"license:mit"`, "yaml"}},
+		},
+		{
+			// Terms and field names are lower-cased, but not values.
+			`TOML "Go Parser" Path:github.com/BurntSushi/toml license:MIT`,
+			SearchQuery{
+				Filters: []FieldFilter{
+					{Field: "path", Op: OpEqual, Value: "github.com/BurntSushi/toml"},
+					{Field: "license", Op: OpEqual, Value: "MIT"},
+				},
+				Terms: []string{"toml", `"go parser"`},
+			},
+		},
+	} {
+		got, err := ParseSearchQuery(test.q)
+		if err != nil {
+			t.Fatalf("ParseSearchQuery(%q): %v", test.q, err)
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("ParseSearchQuery(%q) mismatch (-want +got):\n%s", test.q, diff)
+		}
+	}
+}
+
+func TestParseSearchQueryErrors(t *testing.T) {
+	for _, q := range []string{
+		"imported:lots",
+		"imported:>",
+		"license:",
+		"license:>MIT",
+		"path:<=github.com",
+		"cgo:maybe",
+		"fuzz:yes",
+		"fuzz:",
+		"has_fuzz_tests:yes",
+		"benchmarks:>0",
+		"has_benchmarks:",
+		"cgo:>true",
+	} {
+		if _, err := ParseSearchQuery(q); !errors.Is(err, derrors.InvalidArgument) {
+			t.Errorf("ParseSearchQuery(%q): got error %v, want InvalidArgument", q, err)
+		}
+	}
+}
+
+func TestFieldFilterString(t *testing.T) {
+	for _, f := range []FieldFilter{
+		{Field: "license", Op: OpEqual, Value: "MIT"},
+		{Field: "imported", Op: OpGreaterOrEqual, Value: "3"},
+	} {
+		got, err := ParseSearchQuery(f.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([]FieldFilter{f}, got.Filters); diff != "" {
+			t.Errorf("round trip of %q mismatch (-want +got):\n%s", f, diff)
+		}
+	}
+}
+
+func TestSearchQueryString(t *testing.T) {
+	for _, test := range []struct {
+		q, want string
+	}{
+		{"JSON Parser", "json parser"},
+		{"license:MIT yaml", "yaml license:MIT"},
+		{"yaml LICENSE:MIT has_fuzz_tests:true", "yaml license:MIT fuzz:true"},
+		{"imported:>100", "imported:>100"},
+	} {
+		sq, err := ParseSearchQuery(test.q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := sq.String(); got != test.want {
+			t.Errorf("ParseSearchQuery(%q).String() = %q, want %q", test.q, got, test.want)
+		}
+	}
+}
+
+func TestNormalizeSearchQuery(t *testing.T) {
+	for _, test := range []struct {
+		q, want string
+	}{
+		{"%2Fjson%20parser", "/json parser"},
+		{"json%2Bparser", "json+parser"},
+		{"c++", "c++"},
+		{"100% coverage", "100% coverage"},
+		{"  JSON \t  Parser\n", "JSON Parser"},
+		{`"yaml   parser"  license:MIT`, `"yaml parser" license:MIT`},
+		{"path:github.com/BurntSushi/toml", "path:github.com/BurntSushi/toml"},
+		{strings.Repeat("a", MaxQueryLength), strings.Repeat("a", MaxQueryLength)},
+		{"", ""},
+	} {
+		got, err := NormalizeSearchQuery(test.q)
+		if err != nil {
+			t.Errorf("NormalizeSearchQuery(%q): %v", test.q, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("NormalizeSearchQuery(%q) = %q, want %q", test.q, got, test.want)
+		}
+	}
+
+	for _, q := range []string{
+		"json\x00parser",
+		"json%00parser",
+		strings.Repeat("a", MaxQueryLength+1),
+		strings.Repeat("%61", MaxQueryLength+1),
+	} {
+		if _, err := NormalizeSearchQuery(q); !errors.Is(err, derrors.InvalidArgument) {
+			t.Errorf("NormalizeSearchQuery(%.20q...): got error %v, want InvalidArgument", q, err)
+		}
+	}
+}
+
+func TestLoadSynonyms(t *testing.T) {
+	m, err := LoadSynonyms("testdata/synonyms.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) < 20 {
+		t.Errorf("got %d synonyms, want at least 20", len(m))
+	}
+	if diff := cmp.Diff([]string{"kubernetes"}, m["k8s"]); diff != "" {
+		t.Errorf("k8s mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := LoadSynonyms("testdata/missing.yaml"); err == nil {
+		t.Error("got nil error for a missing file")
+	}
+}
+
+func TestSynonymMapExpand(t *testing.T) {
+	m := SynonymMap{
+		"k8s": {"kubernetes"},
+		"pg":  {"postgres", "postgresql"},
+		"gcp": {"google cloud"},
+		"a":   {"b", "c"},
+	}
+	for _, test := range []struct {
+		q, want string
+	}{
+		{"k8s", "k8s or kubernetes"},
+		{"k8s client", "k8s client or kubernetes client"},
+		{"pg k8s", "pg k8s or pg kubernetes or postgres k8s or postgres kubernetes or postgresql k8s or postgresql kubernetes"},
+		{"gcp", "gcp or google cloud"},
+		{"yaml parser", "yaml parser"},
+		// Quoted phrases, negated terms and queries with "or" are kept.
+		{`"k8s client" -gcp`, `"k8s client" -gcp`},
+		{"k8s or nomad", "k8s or nomad"},
+		// Expansion stops at maxSynonymExpansions alternatives.
+		{"pg a k8s", "pg a k8s or pg a kubernetes or postgres a k8s or postgres a kubernetes or postgresql a k8s or postgresql a kubernetes"},
+	} {
+		if got := m.Expand(test.q); got != test.want {
+			t.Errorf("Expand(%q) = %q, want %q", test.q, got, test.want)
+		}
+	}
+	if got := SynonymMap(nil).Expand("k8s"); got != "k8s" {
+		t.Errorf("nil map: Expand(%q) = %q, want %q", "k8s", got, "k8s")
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package search
+
+import (
+	"io/ioutil"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+// A SynonymMap maps lower-case search terms to the terms that a search for
+// them should also match, like "k8s" to "kubernetes". A synonym may have
+// several words, which must all match.
+type SynonymMap map[string][]string
+
+// maxSynonymExpansions is the maximum number of alternative queries that
+// SynonymMap.Expand combines.
+const maxSynonymExpansions = 8
+
+// LoadSynonyms reads a SynonymMap from the YAML file at path, which maps each
+// term to a list of synonyms. See testdata/synonyms.yaml for an example.
+func LoadSynonyms(path string) (_ SynonymMap, err error) {
+	defer derrors.Wrap(&err, "LoadSynonyms(%q)", path)
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string][]string
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	m := SynonymMap{}
+	for term, syns := range raw {
+		term = strings.ToLower(term)
+		for _, s := range syns {
+			m[term] = append(m[term], strings.ToLower(s))
+		}
+	}
+	return m, nil
+}
+
+// Expand returns the free-text query q, in the syntax of
+// websearch_to_tsquery, rewritten to also match the synonyms of its terms.
+// Since "or" has a lower precedence than the implicit "and" between terms,
+// the result is the alternatives for each combination of the terms and their
+// synonyms, like "k8s client or kubernetes client". To keep the query small,
+// terms are expanded from left to right until there would be more than
+// maxSynonymExpansions alternatives.
+//
+// Quoted phrases and negated terms are not expanded, and neither are queries
+// that already contain "or".
+func (m SynonymMap) Expand(q string) string {
+	if len(m) == 0 {
+		return q
+	}
+	words := splitWords(q)
+	alts := make([][]string, len(words))
+	n := 1 // number of alternative queries
+	for i, w := range words {
+		if strings.EqualFold(w, "or") {
+			return q
+		}
+		alts[i] = []string{w}
+		if strings.HasPrefix(w, `"`) || strings.HasPrefix(w, "-") {
+			continue
+		}
+		syns := m[strings.ToLower(w)]
+		if len(syns) == 0 || n*(1+len(syns)) > maxSynonymExpansions {
+			continue
+		}
+		alts[i] = append(alts[i], syns...)
+		n *= len(alts[i])
+	}
+	if n == 1 {
+		return q
+	}
+	queries := []string{""}
+	for _, a := range alts {
+		var next []string
+		for _, prefix := range queries {
+			for _, w := range a {
+				next = append(next, strings.TrimSpace(prefix+" "+w))
+			}
+		}
+		queries = next
+	}
+	return strings.Join(queries, " or ")
+}
@@ -57,6 +57,15 @@ func (i *Info) RepoURL() string {
 	return i.repoURL
 }
 
+// ModuleDir returns the directory of the module relative to the root of its
+// repository. It is empty if the module is at the root.
+func (i *Info) ModuleDir() string {
+	if i == nil {
+		return ""
+	}
+	return i.moduleDir
+}
+
 // ModuleURL returns a URL for the home page of the module.
 func (i *Info) ModuleURL() string {
 	return i.DirectoryURL("")
@@ -0,0 +1,158 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package sumdb verifies module hashes against a Go checksum database, such
+// as sum.golang.org.
+package sumdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"go.opencensus.io/plugin/ochttp"
+	"golang.org/x/mod/sumdb"
+	"golang.org/x/net/context/ctxhttp"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/log"
+)
+
+const (
+	// DefaultURL is the URL of the Go checksum database.
+	DefaultURL = "https://sum.golang.org"
+
+	// DefaultKey is the verifier key of the Go checksum database.
+	DefaultKey = "sum.golang.org+033de0ae+Ac4zctda0e5eza+HJyk9SxEdh+s3Ty0djYT4B8ZYxiH"
+)
+
+// A Client verifies module hashes against a checksum database.
+type Client struct {
+	url        string
+	key        string
+	httpClient *http.Client
+
+	mu     sync.Mutex
+	latest []byte // latest signed tree head seen from the database
+}
+
+// New returns a Client for the checksum database at url, whose signed tree
+// heads are verified with the given key.
+func New(url, key string) *Client {
+	return &Client{
+		url:        strings.TrimRight(url, "/"),
+		key:        key,
+		httpClient: &http.Client{Transport: &ochttp.Transport{}},
+	}
+}
+
+// VerifyModuleChecksum checks that hash, the "h1:" hash of the zip of the
+// given module version, is the one recorded in the checksum database. The
+// record is only trusted if its inclusion in the database's transparency log
+// is proven by the log's tiles, and the log is consistent with the tree heads
+// seen by earlier calls.
+//
+// It returns an error wrapping derrors.HashMismatch if the hashes differ or
+// the proofs fail, and derrors.NotFound if the database has no record of the
+// module version.
+func (c *Client) VerifyModuleChecksum(ctx context.Context, modulePath, version, hash string) (err error) {
+	defer derrors.Wrap(&err, "VerifyModuleChecksum(ctx, %q, %q, %q)", modulePath, version, hash)
+
+	// sumdb.Client has no way to pass a context to its operations, so use a
+	// new one for each lookup. Only the latest tree head is kept between
+	// lookups, to check that the log is append-only.
+	ops := &clientOps{ctx: ctx, c: c}
+	lines, err := sumdb.NewClient(ops).Lookup(modulePath, version)
+	if ops.securityErr != "" {
+		return fmt.Errorf("%s: %w", ops.securityErr, derrors.HashMismatch)
+	}
+	if err != nil {
+		if ops.notFound {
+			return fmt.Errorf("%v: %w", err, derrors.NotFound)
+		}
+		return err
+	}
+	want := fmt.Sprintf("%s %s %s", modulePath, version, hash)
+	for _, line := range lines {
+		if line == want {
+			return nil
+		}
+	}
+	return fmt.Errorf("checksum database has %q: %w", lines, derrors.HashMismatch)
+}
+
+// clientOps implements sumdb.ClientOps for a single lookup by a Client.
+// Nothing is cached, so every tile is read from the database.
+type clientOps struct {
+	ctx context.Context
+	c   *Client
+
+	mu          sync.Mutex
+	notFound    bool
+	securityErr string
+}
+
+func (o *clientOps) ReadRemote(path string) ([]byte, error) {
+	url := o.c.url + path
+	resp, err := ctxhttp.Get(o.ctx, o.c.httpClient, url)
+	if err != nil {
+		return nil, fmt.Errorf("ctxhttp.Get(ctx, client, %q): %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+			o.mu.Lock()
+			o.notFound = true
+			o.mu.Unlock()
+		}
+		return nil, fmt.Errorf("GET %q: %s", url, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+func (o *clientOps) ReadConfig(file string) ([]byte, error) {
+	if file == "key" {
+		return []byte(o.c.key), nil
+	}
+	if strings.HasSuffix(file, "/latest") {
+		o.c.mu.Lock()
+		defer o.c.mu.Unlock()
+		return o.c.latest, nil
+	}
+	return nil, fmt.Errorf("unknown config file %q", file)
+}
+
+func (o *clientOps) WriteConfig(file string, old, new []byte) error {
+	if !strings.HasSuffix(file, "/latest") {
+		return fmt.Errorf("unknown config file %q", file)
+	}
+	o.c.mu.Lock()
+	defer o.c.mu.Unlock()
+	if !bytes.Equal(o.c.latest, old) {
+		return sumdb.ErrWriteConflict
+	}
+	o.c.latest = new
+	return nil
+}
+
+func (o *clientOps) ReadCache(file string) ([]byte, error) {
+	return nil, errors.New("not cached")
+}
+
+func (o *clientOps) WriteCache(file string, data []byte) {}
+
+func (o *clientOps) Log(msg string) {
+	log.Debugf(o.ctx, "sumdb: %s", msg)
+}
+
+func (o *clientOps) SecurityError(msg string) {
+	log.Errorf(o.ctx, "sumdb: %s", msg)
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.securityErr = msg
+}
@@ -0,0 +1,54 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sumdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"golang.org/x/pkgsite/internal/derrors"
+)
+
+func TestVerifyModuleChecksum(t *testing.T) {
+	client, teardown := SetupTestSumDB(t, map[string]string{
+		"a.com/m@v1.0.0": "h1:aaaa=",
+		"a.com/m@v1.1.0": "h1:bbbb=",
+		"b.com/m@v1.0.0": "h1:cccc=",
+	})
+	defer teardown()
+
+	ctx := context.Background()
+	for _, test := range []struct {
+		modulePath, version, hash string
+		want                      error
+	}{
+		{"a.com/m", "v1.0.0", "h1:aaaa=", nil},
+		{"a.com/m", "v1.1.0", "h1:bbbb=", nil},
+		{"b.com/m", "v1.0.0", "h1:cccc=", nil},
+		{"a.com/m", "v1.0.0", "h1:bbbb=", derrors.HashMismatch},
+		{"a.com/m", "v1.0.0", "", derrors.HashMismatch},
+		{"a.com/m", "v1.2.0", "h1:aaaa=", derrors.NotFound},
+	} {
+		err := client.VerifyModuleChecksum(ctx, test.modulePath, test.version, test.hash)
+		if !errors.Is(err, test.want) {
+			t.Errorf("VerifyModuleChecksum(%q, %q, %q) = %v, want %v", test.modulePath, test.version, test.hash, err, test.want)
+		}
+	}
+}
+
+func TestVerifyModuleChecksumBadKey(t *testing.T) {
+	client, teardown := SetupTestSumDB(t, map[string]string{"a.com/m@v1.0.0": "h1:aaaa="})
+	defer teardown()
+	other, teardownOther := SetupTestSumDB(t, nil)
+	defer teardownOther()
+
+	// A database whose tree heads are not signed by the expected key is
+	// not trusted.
+	client.key = other.key
+	if err := client.VerifyModuleChecksum(context.Background(), "a.com/m", "v1.0.0", "h1:aaaa="); err == nil {
+		t.Error("got nil error, want error")
+	}
+}
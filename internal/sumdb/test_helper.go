@@ -0,0 +1,39 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sumdb
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"golang.org/x/mod/sumdb"
+	"golang.org/x/mod/sumdb/note"
+)
+
+// SetupTestSumDB creates a fake checksum database for testing, which records
+// the given hashes. The keys of hashes are of the form "path@version", and
+// the values are "h1:" hashes.
+//
+// It returns a Client for the database and a function for shutting it down
+// after the test is completed.
+func SetupTestSumDB(t *testing.T, hashes map[string]string) (*Client, func()) {
+	t.Helper()
+	skey, vkey, err := note.GenerateKey(rand.Reader, "sumdb.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gosum := func(path, vers string) ([]byte, error) {
+		hash, ok := hashes[path+"@"+vers]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		return []byte(fmt.Sprintf("%s %s %s\n%s %s/go.mod h1:gomod=\n", path, vers, hash, path, vers)), nil
+	}
+	srv := httptest.NewServer(sumdb.NewServer(sumdb.NewTestServer(skey, gosum)))
+	return New(srv.URL, vkey), srv.Close
+}
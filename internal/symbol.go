@@ -0,0 +1,103 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package internal
+
+import "strings"
+
+// SymbolKind is the kind of a Go declaration.
+type SymbolKind string
+
+// The kinds of symbols that can be declared in a package.
+const (
+	SymbolKindConstant SymbolKind = "Constant"
+	SymbolKindVariable SymbolKind = "Variable"
+	SymbolKindFunction SymbolKind = "Function"
+	SymbolKindType     SymbolKind = "Type"
+	SymbolKindMethod   SymbolKind = "Method"
+)
+
+// SymbolKinds returns all symbol kinds.
+func SymbolKinds() []SymbolKind {
+	return []SymbolKind{
+		SymbolKindConstant,
+		SymbolKindVariable,
+		SymbolKindFunction,
+		SymbolKindType,
+		SymbolKindMethod,
+	}
+}
+
+// ParseSymbolKind returns the SymbolKind whose name is s, ignoring case. It
+// reports false if there is no such kind.
+func ParseSymbolKind(s string) (SymbolKind, bool) {
+	for _, k := range SymbolKinds() {
+		if strings.EqualFold(string(k), s) {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+// A Symbol is an exported identifier declared at the top level of a package,
+// or a method of such a type.
+type Symbol struct {
+	// Name is the name of the symbol. The name of a method is qualified by
+	// its receiver type, as in "Buffer.Write".
+	Name     string
+	Kind     SymbolKind
+	Synopsis string
+	// Signature is the declaration of the symbol, without its documentation
+	// or function body.
+	Signature string
+	// FilePath is the path of the file that declares the symbol, relative to
+	// the module root, and Line is the line of the declared name in the file.
+	FilePath string
+	Line     int
+}
+
+// DocCoverage returns the percentage, rounded down, of syms that have a
+// documentation comment, as indicated by a non-empty Synopsis. It returns 0
+// if syms is empty.
+func DocCoverage(syms []*Symbol) int {
+	var documented int
+	for _, s := range syms {
+		if s.Synopsis != "" {
+			documented++
+		}
+	}
+	n := len(syms)
+	if n == 0 {
+		n = 1
+	}
+	return documented * 100 / n
+}
+
+// A SymbolLocation is the location of the declaration of a symbol in the
+// latest version of its module.
+type SymbolLocation struct {
+	PackagePath string
+	ModulePath  string
+	Version     string
+	// FilePath is the path of the file relative to the module root.
+	FilePath string
+	Line     int
+}
+
+// A TypeRef identifies an exported named type declared at the top level of a
+// package.
+type TypeRef struct {
+	PackagePath string
+	TypeName    string
+	ModulePath  string
+}
+
+// An Implementation records that the exported type Type implements the
+// exported interface InterfaceName of the package InterfacePackagePath,
+// either directly or through a pointer to it.
+type Implementation struct {
+	InterfacePackagePath string
+	InterfaceName        string
+	Type                 TypeRef
+}
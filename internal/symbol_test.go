@@ -0,0 +1,26 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package internal
+
+import "testing"
+
+func TestDocCoverage(t *testing.T) {
+	documented := &Symbol{Name: "A", Synopsis: "A does things."}
+	undocumented := &Symbol{Name: "B"}
+	for _, test := range []struct {
+		syms []*Symbol
+		want int
+	}{
+		{nil, 0},
+		{[]*Symbol{undocumented}, 0},
+		{[]*Symbol{documented, undocumented}, 50},
+		{[]*Symbol{documented, documented, undocumented}, 66},
+		{[]*Symbol{documented}, 100},
+	} {
+		if got := DocCoverage(test.syms); got != test.want {
+			t.Errorf("DocCoverage(%d symbols) = %d, want %d", len(test.syms), got, test.want)
+		}
+	}
+}
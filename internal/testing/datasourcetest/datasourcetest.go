@@ -0,0 +1,574 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package datasourcetest checks that implementations of internal.DataSource
+// behave alike. It should only be imported by test files.
+//
+// An implementation under test must serve the module versions of
+// TestModules, and nothing else. Implementations that read modules from a
+// proxy can be given a test proxy serving TestModules; others can store the
+// modules returned by FetchModules.
+package datasourcetest
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/fetch"
+	"golang.org/x/pkgsite/internal/licenses"
+	"golang.org/x/pkgsite/internal/proxy"
+	"golang.org/x/pkgsite/internal/source"
+	"golang.org/x/pkgsite/internal/testing/testhelper"
+)
+
+const (
+	// ModulePath is the path of the module of TestModules. It is hosted on
+	// GitHub, so that its source information is known without network
+	// access.
+	ModulePath = "github.com/contract/module"
+
+	greetPath   = ModulePath + "/greet"
+	wordsPath   = ModulePath + "/internal/words"
+	missingPath = ModulePath + "/missing"
+
+	missingModulePath = "github.com/contract/missing"
+)
+
+// TestModules returns the module versions that an implementation under test
+// must serve: versions v1.0.0 and v1.1.0 of ModulePath, which differ in the
+// synopsis of their greet package.
+func TestModules() []*proxy.TestModule {
+	module := func(version, synopsis string) *proxy.TestModule {
+		return &proxy.TestModule{
+			ModulePath: ModulePath,
+			Version:    version,
+			Files: map[string]string{
+				"LICENSE":   testhelper.MITLicense,
+				"README.md": "# Contract\n\nThis module tests implementations of DataSource.\n",
+				"greet/greet.go": `// ` + synopsis + `
+package greet
+
+import (
+	"fmt"
+
+	"` + wordsPath + `"
+)
+
+// Hello prints a greeting.
+func Hello() {
+	fmt.Println(words.Hello)
+}
+`,
+				"internal/words/words.go": `// Package words holds words.
+package words
+
+// Hello is a greeting.
+const Hello = "hello"
+`,
+			},
+		}
+	}
+	return []*proxy.TestModule{
+		module("v1.0.0", "Package greet greets."),
+		module("v1.1.0", "Package greet greets politely."),
+	}
+}
+
+// FetchModules fetches the module versions of TestModules from proxyClient,
+// which must serve them.
+func FetchModules(ctx context.Context, t *testing.T, proxyClient *proxy.Client) []*internal.Module {
+	t.Helper()
+	var mods []*internal.Module
+	for _, tm := range TestModules() {
+		res := fetch.FetchModule(ctx, tm.ModulePath, tm.Version, proxyClient, source.NewClient(time.Minute))
+		if res.Error != nil {
+			t.Fatal(res.Error)
+		}
+		mods = append(mods, res.Module)
+	}
+	return mods
+}
+
+// DataSourceContractTest checks that ds, which must serve exactly the module
+// versions of TestModules, behaves as every DataSource must:
+//
+//   - Methods that look up a single module, package or directory return the
+//     same data for the same inputs, and an error wrapping derrors.NotFound
+//     for paths and versions that do not exist. Empty paths are reported as
+//     not found or as invalid arguments.
+//   - Methods that return lists report unknown inputs either with an empty
+//     list or with an error wrapping derrors.NotFound.
+//   - Methods for data that some implementations cannot compute, like
+//     counts or vulnerability reports, return either the right value or the
+//     zero value, and no errors other than derrors.NotFound.
+//   - Errors, like those of canceled requests, are returned rather than
+//     reported as data or as derrors.NotFound.
+//
+// The contract is checked on a fresh ds: errors are checked before anything
+// is looked up, because implementations may cache lookups.
+func DataSourceContractTest(t *testing.T, ds internal.DataSource) {
+	t.Run("errors", func(t *testing.T) { testErrors(t, ds) })
+	t.Run("lookups", func(t *testing.T) { testLookups(t, ds) })
+	t.Run("lists", func(t *testing.T) { testLists(t, ds) })
+	t.Run("not found", func(t *testing.T) { testNotFound(t, ds) })
+	t.Run("empty inputs", func(t *testing.T) { testEmptyInputs(t, ds) })
+	t.Run("optional", func(t *testing.T) { testOptional(t, ds) })
+}
+
+func testErrors(t *testing.T, ds internal.DataSource) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	check := func(name string, err error) {
+		t.Helper()
+		if err == nil || errors.Is(err, derrors.NotFound) {
+			t.Errorf("%s with a canceled context: got error %v, want a non-NotFound error", name, err)
+		}
+	}
+	_, _, _, err := ds.GetPathInfo(ctx, greetPath, internal.UnknownModulePath, internal.LatestVersion)
+	check("GetPathInfo", err)
+	_, err = ds.LegacyGetPackage(ctx, greetPath, internal.UnknownModulePath, internal.LatestVersion)
+	check("LegacyGetPackage", err)
+	_, err = ds.LegacyGetModuleInfo(ctx, ModulePath, "v1.0.0")
+	check("LegacyGetModuleInfo", err)
+	_, err = ds.GetTaggedVersionsForModule(ctx, ModulePath)
+	check("GetTaggedVersionsForModule", err)
+}
+
+func testLookups(t *testing.T, ds internal.DataSource) {
+	ctx := context.Background()
+
+	t.Run("GetPathInfo", func(t *testing.T) {
+		for _, test := range []struct {
+			path, modulePath, version string
+			wantVersion               string
+			wantIsPackage             bool
+		}{
+			{greetPath, internal.UnknownModulePath, internal.LatestVersion, "v1.1.0", true},
+			{greetPath, ModulePath, "v1.0.0", "v1.0.0", true},
+			{ModulePath + "/internal", internal.UnknownModulePath, internal.LatestVersion, "v1.1.0", false},
+			{ModulePath, ModulePath, internal.LatestVersion, "v1.1.0", false},
+		} {
+			modulePath, version, isPackage, err := ds.GetPathInfo(ctx, test.path, test.modulePath, test.version)
+			if err != nil {
+				t.Errorf("GetPathInfo(%q, %q, %q): %v", test.path, test.modulePath, test.version, err)
+				continue
+			}
+			if modulePath != ModulePath || version != test.wantVersion || isPackage != test.wantIsPackage {
+				t.Errorf("GetPathInfo(%q, %q, %q) = %q, %q, %t, want %q, %q, %t",
+					test.path, test.modulePath, test.version, modulePath, version, isPackage,
+					ModulePath, test.wantVersion, test.wantIsPackage)
+			}
+		}
+	})
+
+	t.Run("LegacyGetModuleInfo", func(t *testing.T) {
+		mi, err := ds.LegacyGetModuleInfo(ctx, ModulePath, "v1.0.0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mi.ModulePath != ModulePath || mi.Version != "v1.0.0" || !mi.IsRedistributable {
+			t.Errorf("got %s@%s, redistributable %t; want %s@v1.0.0, redistributable", mi.ModulePath, mi.Version, mi.IsRedistributable, ModulePath)
+		}
+		if want := time.Date(2019, time.January, 30, 0, 0, 0, 0, time.UTC); !mi.CommitTime.Equal(want) {
+			t.Errorf("got commit time %v, want %v", mi.CommitTime, want)
+		}
+	})
+
+	t.Run("LegacyGetPackage", func(t *testing.T) {
+		for _, test := range []struct {
+			modulePath, version       string
+			wantVersion, wantSynopsis string
+		}{
+			{internal.UnknownModulePath, internal.LatestVersion, "v1.1.0", "Package greet greets politely."},
+			{ModulePath, internal.LatestVersion, "v1.1.0", "Package greet greets politely."},
+			{ModulePath, "v1.0.0", "v1.0.0", "Package greet greets."},
+		} {
+			pkg, err := ds.LegacyGetPackage(ctx, greetPath, test.modulePath, test.version)
+			if err != nil {
+				t.Errorf("LegacyGetPackage(%q, %q, %q): %v", greetPath, test.modulePath, test.version, err)
+				continue
+			}
+			got := packageSummary{pkg.Path, pkg.Name, pkg.Synopsis, pkg.ModulePath, pkg.Version, pkg.LegacyPackage.IsRedistributable}
+			want := packageSummary{greetPath, "greet", test.wantSynopsis, ModulePath, test.wantVersion, true}
+			if got != want {
+				t.Errorf("LegacyGetPackage(%q, %q, %q) = %+v, want %+v", greetPath, test.modulePath, test.version, got, want)
+			}
+			if diff := cmp.Diff([]string{"LICENSE"}, licenseMetadataPaths(pkg.Licenses)); diff != "" {
+				t.Errorf("license files mismatch (-want +got):\n%s", diff)
+			}
+		}
+	})
+
+	t.Run("LegacyGetPackages", func(t *testing.T) {
+		pkgs, err := ds.LegacyGetPackages(ctx, []string{wordsPath, missingPath, greetPath}, ModulePath, "v1.1.0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, p := range pkgs {
+			if p == nil {
+				got = append(got, "")
+				continue
+			}
+			got = append(got, p.Path+"@"+p.Version)
+		}
+		want := []string{wordsPath + "@v1.1.0", "", greetPath + "@v1.1.0"}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("GetDirectoryNew", func(t *testing.T) {
+		for _, test := range []struct {
+			path, wantPackageName string
+		}{
+			{greetPath, "greet"},
+			{ModulePath, ""},
+		} {
+			dir, err := ds.GetDirectoryNew(ctx, test.path, ModulePath, "v1.1.0")
+			if err != nil {
+				t.Errorf("GetDirectoryNew(%q): %v", test.path, err)
+				continue
+			}
+			if dir.Path != test.path || dir.V1Path != test.path || dir.ModulePath != ModulePath || dir.Version != "v1.1.0" {
+				t.Errorf("GetDirectoryNew(%q) = %s (v1 path %s) in %s@%s, want %[1]s in %s@v1.1.0",
+					test.path, dir.Path, dir.V1Path, dir.ModulePath, dir.Version, ModulePath)
+			}
+			var name string
+			if dir.Package != nil {
+				name = dir.Package.Name
+			}
+			if name != test.wantPackageName {
+				t.Errorf("GetDirectoryNew(%q): got package %q, want %q", test.path, name, test.wantPackageName)
+			}
+		}
+	})
+
+	t.Run("LegacyGetDirectory", func(t *testing.T) {
+		for _, test := range []struct {
+			path, modulePath string
+			want             []string
+		}{
+			{ModulePath, ModulePath, []string{greetPath, wordsPath}},
+			{ModulePath + "/internal", internal.UnknownModulePath, []string{wordsPath}},
+		} {
+			dir, err := ds.LegacyGetDirectory(ctx, test.path, test.modulePath, internal.LatestVersion, internal.AllFields)
+			if err != nil {
+				t.Errorf("LegacyGetDirectory(%q, %q): %v", test.path, test.modulePath, err)
+				continue
+			}
+			if dir.Version != "v1.1.0" {
+				t.Errorf("LegacyGetDirectory(%q, %q): got version %q, want v1.1.0", test.path, test.modulePath, dir.Version)
+			}
+			if diff := cmp.Diff(test.want, packagePaths(dir.Packages)); diff != "" {
+				t.Errorf("LegacyGetDirectory(%q, %q) mismatch (-want +got):\n%s", test.path, test.modulePath, diff)
+			}
+		}
+	})
+}
+
+func testLists(t *testing.T, ds internal.DataSource) {
+	ctx := context.Background()
+
+	t.Run("GetImports", func(t *testing.T) {
+		got, err := ds.GetImports(ctx, greetPath, ModulePath, "v1.1.0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(got)
+		if diff := cmp.Diff([]string{"fmt", wordsPath}, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("LegacyGetPackagesInModule", func(t *testing.T) {
+		pkgs, err := ds.LegacyGetPackagesInModule(ctx, ModulePath, "v1.0.0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([]string{greetPath, wordsPath}, packagePaths(pkgs)); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("licenses", func(t *testing.T) {
+		mlics, err := ds.LegacyGetModuleLicenses(ctx, ModulePath, "v1.0.0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		plics, err := ds.LegacyGetPackageLicenses(ctx, greetPath, ModulePath, "v1.0.0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"LICENSE: MIT"}
+		if diff := cmp.Diff(want, licenseSummaries(mlics)); diff != "" {
+			t.Errorf("LegacyGetModuleLicenses mismatch (-want +got):\n%s", diff)
+		}
+		if diff := cmp.Diff(want, licenseSummaries(plics)); diff != "" {
+			t.Errorf("LegacyGetPackageLicenses mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("versions", func(t *testing.T) {
+		for _, test := range []struct {
+			name string
+			get  func(context.Context, string) ([]*internal.ModuleInfo, error)
+			path string
+			want []string
+		}{
+			{"GetTaggedVersionsForModule", ds.GetTaggedVersionsForModule, ModulePath, []string{"v1.1.0", "v1.0.0"}},
+			{"GetTaggedVersionsForPackageSeries", ds.GetTaggedVersionsForPackageSeries, greetPath, []string{"v1.1.0", "v1.0.0"}},
+			{"GetPseudoVersionsForModule", ds.GetPseudoVersionsForModule, ModulePath, nil},
+			{"GetPseudoVersionsForPackageSeries", ds.GetPseudoVersionsForPackageSeries, greetPath, nil},
+		} {
+			mis, err := test.get(ctx, test.path)
+			if err != nil {
+				t.Errorf("%s(%q): %v", test.name, test.path, err)
+				continue
+			}
+			var got []string
+			for _, mi := range mis {
+				if mi.ModulePath != ModulePath {
+					t.Errorf("%s(%q): got module %q, want %q", test.name, test.path, mi.ModulePath, ModulePath)
+				}
+				got = append(got, mi.Version)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("%s(%q) mismatch (-want +got):\n%s", test.name, test.path, diff)
+			}
+		}
+	})
+
+	t.Run("GetPackageSymbols", func(t *testing.T) {
+		syms, err := ds.GetPackageSymbols(ctx, greetPath, "v1.1.0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, s := range syms {
+			got = append(got, s.Name)
+		}
+		if diff := cmp.Diff([]string{"Hello"}, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func testNotFound(t *testing.T, ds internal.DataSource) {
+	ctx := context.Background()
+	check := func(name string, err error) {
+		t.Helper()
+		if !errors.Is(err, derrors.NotFound) {
+			t.Errorf("%s: got error %v, want NotFound", name, err)
+		}
+	}
+	_, _, _, err := ds.GetPathInfo(ctx, missingPath, internal.UnknownModulePath, internal.LatestVersion)
+	check("GetPathInfo of a missing path", err)
+	_, _, _, err = ds.GetPathInfo(ctx, greetPath, ModulePath, "v9.9.9")
+	check("GetPathInfo at a missing version", err)
+	_, err = ds.LegacyGetModuleInfo(ctx, ModulePath, "v9.9.9")
+	check("LegacyGetModuleInfo at a missing version", err)
+	_, err = ds.LegacyGetModuleInfo(ctx, missingModulePath, "v1.0.0")
+	check("LegacyGetModuleInfo of a missing module", err)
+	_, err = ds.LegacyGetPackage(ctx, missingPath, internal.UnknownModulePath, internal.LatestVersion)
+	check("LegacyGetPackage of a missing path", err)
+	_, err = ds.LegacyGetPackage(ctx, ModulePath+"/internal", ModulePath, "v1.1.0")
+	check("LegacyGetPackage of a directory", err)
+	_, err = ds.GetDirectoryNew(ctx, missingPath, ModulePath, "v1.1.0")
+	check("GetDirectoryNew of a missing path", err)
+	_, err = ds.LegacyGetDirectory(ctx, missingPath, internal.UnknownModulePath, internal.LatestVersion, internal.AllFields)
+	check("LegacyGetDirectory of a missing path", err)
+
+	// Unknown inputs of lists are reported as empty lists or as not found.
+	checkEmpty := func(name string, n int, err error) {
+		t.Helper()
+		if err != nil && !errors.Is(err, derrors.NotFound) {
+			t.Errorf("%s: got error %v, want nil or NotFound", name, err)
+		}
+		if err == nil && n != 0 {
+			t.Errorf("%s: got %d results, want none", name, n)
+		}
+	}
+	imports, err := ds.GetImports(ctx, missingPath, ModulePath, "v1.1.0")
+	checkEmpty("GetImports", len(imports), err)
+	lics, err := ds.LegacyGetPackageLicenses(ctx, missingPath, ModulePath, "v1.1.0")
+	checkEmpty("LegacyGetPackageLicenses", len(lics), err)
+	lics, err = ds.LegacyGetModuleLicenses(ctx, missingModulePath, "v1.0.0")
+	checkEmpty("LegacyGetModuleLicenses", len(lics), err)
+	pkgs, err := ds.LegacyGetPackagesInModule(ctx, missingModulePath, "v1.0.0")
+	checkEmpty("LegacyGetPackagesInModule", len(pkgs), err)
+	mis, err := ds.GetTaggedVersionsForModule(ctx, missingModulePath)
+	checkEmpty("GetTaggedVersionsForModule", len(mis), err)
+	mis, err = ds.GetTaggedVersionsForPackageSeries(ctx, missingModulePath+"/pkg")
+	checkEmpty("GetTaggedVersionsForPackageSeries", len(mis), err)
+	syms, err := ds.GetPackageSymbols(ctx, missingPath, "v1.1.0")
+	checkEmpty("GetPackageSymbols", len(syms), err)
+	exs, err := ds.GetExamples(ctx, missingPath, "v1.1.0")
+	checkEmpty("GetExamples", len(exs), err)
+	deps, err := ds.GetModuleDependencies(ctx, missingModulePath, "v1.0.0")
+	checkEmpty("GetModuleDependencies", len(deps), err)
+	bcs, err := ds.GetBuildContexts(ctx, missingPath, "v1.1.0")
+	checkEmpty("GetBuildContexts", len(bcs), err)
+	patterns, err := ds.GetEmbeddedPatterns(ctx, missingPath, "v1.1.0")
+	checkEmpty("GetEmbeddedPatterns", len(patterns), err)
+	successors, err := ds.GetSuccessorPackages(ctx, missingPath, "v1.1.0")
+	checkEmpty("GetSuccessorPackages", len(successors), err)
+	impls, err := ds.GetInterfaceImplementations(ctx, missingPath, "Greeter")
+	checkEmpty("GetInterfaceImplementations", len(impls), err)
+	vulns, err := ds.GetVulnerabilities(ctx, missingModulePath, "v1.0.0")
+	checkEmpty("GetVulnerabilities", len(vulns), err)
+	retracted, err := ds.GetRetractedVersions(ctx, missingModulePath)
+	checkEmpty("GetRetractedVersions", len(retracted), err)
+	files, err := ds.GetModuleFiles(ctx, missingModulePath, "v1.0.0", "")
+	checkEmpty("GetModuleFiles", len(files), err)
+	suggestions, err := ds.GetSearchSuggestions(ctx, "nosuchprefix", 10)
+	checkEmpty("GetSearchSuggestions", len(suggestions), err)
+}
+
+func testEmptyInputs(t *testing.T, ds internal.DataSource) {
+	ctx := context.Background()
+	check := func(name string, err error) {
+		t.Helper()
+		if !errors.Is(err, derrors.NotFound) && !errors.Is(err, derrors.InvalidArgument) {
+			t.Errorf("%s: got error %v, want NotFound or InvalidArgument", name, err)
+		}
+	}
+	_, _, _, err := ds.GetPathInfo(ctx, "", internal.UnknownModulePath, internal.LatestVersion)
+	check("GetPathInfo", err)
+	_, err = ds.LegacyGetPackage(ctx, "", internal.UnknownModulePath, internal.LatestVersion)
+	check("LegacyGetPackage", err)
+	_, err = ds.GetImports(ctx, "", ModulePath, "v1.1.0")
+	check("GetImports", err)
+
+	pkgs, err := ds.LegacyGetPackages(ctx, nil, ModulePath, "v1.1.0")
+	if err != nil || len(pkgs) != 0 {
+		t.Errorf("LegacyGetPackages with no paths = %d packages, %v; want none, nil", len(pkgs), err)
+	}
+}
+
+// testOptional checks the methods that some implementations cannot support:
+// they must return the right value or the zero value, and no errors other
+// than derrors.NotFound.
+func testOptional(t *testing.T, ds internal.DataSource) {
+	ctx := context.Background()
+	check := func(name string, err error) bool {
+		t.Helper()
+		if err != nil && !errors.Is(err, derrors.NotFound) {
+			t.Errorf("%s: got error %v, want nil or NotFound", name, err)
+			return false
+		}
+		return err == nil
+	}
+	checkZero := func(name string, got interface{}, err error) {
+		t.Helper()
+		if check(name, err) && !cmp.Equal(got, zero(got)) {
+			t.Errorf("%s = %v, want the zero value", name, got)
+		}
+	}
+
+	// greet does not use cgo, fuzzing, benchmarks or deprecation, so the
+	// right values are the zero values.
+	fuzz, err := ds.GetHasFuzzTests(ctx, greetPath, "v1.1.0")
+	checkZero("GetHasFuzzTests", fuzz, err)
+	benchmarks, err := ds.GetBenchmarkCount(ctx, greetPath, "v1.1.0")
+	checkZero("GetBenchmarkCount", benchmarks, err)
+	retracted, err := ds.GetRetractedVersions(ctx, ModulePath)
+	checkZero("GetRetractedVersions", len(retracted), err)
+	vulns, err := ds.GetVulnerabilities(ctx, ModulePath, "v1.1.0")
+	checkZero("GetVulnerabilities", len(vulns), err)
+	if ws, err := ds.GetWorkspace(ctx, ModulePath); check("GetWorkspace", err) && ws != nil {
+		t.Errorf("GetWorkspace = %+v, want nil", ws)
+	}
+
+	// Values that only some implementations compute are not checked.
+	_, err = ds.GetExportedSymbolCount(ctx, greetPath, "v1.1.0")
+	check("GetExportedSymbolCount", err)
+	_, err = ds.GetContributorCount(ctx, ModulePath, "v1.1.0")
+	check("GetContributorCount", err)
+	_, err = ds.GetSymbolDefinition(ctx, greetPath, "Hello")
+	check("GetSymbolDefinition", err)
+	_, err = ds.GetBuildContexts(ctx, greetPath, "v1.1.0")
+	check("GetBuildContexts", err)
+	_, err = ds.GetModuleDependencies(ctx, ModulePath, "v1.1.0")
+	check("GetModuleDependencies", err)
+	_, err = ds.GetSitemapPackageCount(ctx)
+	check("GetSitemapPackageCount", err)
+
+	if links, err := ds.GetModuleLinks(ctx, ModulePath); check("GetModuleLinks", err) {
+		if want := "https://" + ModulePath + "/issues"; links.IssuesURL != want {
+			t.Errorf("GetModuleLinks: got issues URL %q, want %q", links.IssuesURL, want)
+		}
+	}
+	if paths, err := ds.GetPackagePathsForSitemap(ctx, 0, 10); check("GetPackagePathsForSitemap", err) {
+		for _, p := range paths {
+			if p != greetPath && p != wordsPath {
+				t.Errorf("GetPackagePathsForSitemap: got unknown path %q", p)
+			}
+		}
+	}
+	if mods, err := ds.GetRecentlyIndexedModules(ctx, 10, time.Time{}); check("GetRecentlyIndexedModules", err) {
+		for _, m := range mods {
+			if m.ModulePath != ModulePath {
+				t.Errorf("GetRecentlyIndexedModules: got unknown module %q", m.ModulePath)
+			}
+		}
+	}
+}
+
+// A packageSummary holds the fields of a package that all implementations
+// must agree on.
+type packageSummary struct {
+	Path, Name, Synopsis string
+	ModulePath, Version  string
+	IsRedistributable    bool
+}
+
+func packagePaths(pkgs []*internal.LegacyPackage) []string {
+	var paths []string
+	for _, p := range pkgs {
+		paths = append(paths, p.Path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func licenseMetadataPaths(lics []*licenses.Metadata) []string {
+	var paths []string
+	for _, l := range lics {
+		paths = append(paths, l.FilePath)
+	}
+	return paths
+}
+
+// licenseSummaries returns the path and types of each license, as
+// "path: type1, type2".
+func licenseSummaries(lics []*licenses.License) []string {
+	var sums []string
+	for _, l := range lics {
+		sums = append(sums, l.FilePath+": "+strings.Join(l.Types, ", "))
+	}
+	sort.Strings(sums)
+	return sums
+}
+
+// zero returns the zero value of the type of v.
+func zero(v interface{}) interface{} {
+	switch v.(type) {
+	case bool:
+		return false
+	case int:
+		return 0
+	case string:
+		return ""
+	default:
+		panic("zero: unsupported type")
+	}
+}
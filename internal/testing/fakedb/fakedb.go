@@ -0,0 +1,790 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package fakedb provides an internal.DataSource that serves module versions
+// from memory, so that tests of its clients, like the frontend, do not need
+// a database. It should only be imported by test files.
+package fakedb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/mod/semver"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/licenses"
+	"golang.org/x/pkgsite/internal/stdlib"
+	"golang.org/x/pkgsite/internal/testing/sample"
+	"golang.org/x/pkgsite/internal/version"
+)
+
+var _ internal.DataSource = (*FakeDataSource)(nil)
+
+// FakeDataSource is an internal.DataSource that serves the module versions
+// added to it with AddModule and AddPackage. The data that is not part of an
+// internal.Module, like vulnerabilities and module dependencies, is never
+// found.
+//
+// The values it returns are shared with it and with the modules it was given,
+// and must not be modified.
+type FakeDataSource struct {
+	mu      sync.RWMutex
+	modules map[string]map[string]*moduleEntry // by module path, then version
+}
+
+// A moduleEntry is a module version added to a FakeDataSource.
+type moduleEntry struct {
+	module    *internal.Module
+	indexedAt time.Time
+}
+
+// New returns an empty FakeDataSource.
+func New() *FakeDataSource {
+	return &FakeDataSource{modules: map[string]map[string]*moduleEntry{}}
+}
+
+// AddModule adds the module version m to ds, replacing the version of the same
+// module with the same version, if any.
+func (ds *FakeDataSource) AddModule(m *internal.Module) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.addModule(m)
+}
+
+func (ds *FakeDataSource) addModule(m *internal.Module) {
+	versions := ds.modules[m.ModulePath]
+	if versions == nil {
+		versions = map[string]*moduleEntry{}
+		ds.modules[m.ModulePath] = versions
+	}
+	versions[m.Version] = &moduleEntry{module: m, indexedAt: time.Now()}
+}
+
+// AddPackage adds the package p to its module version in ds, which is created,
+// with a root directory, if it was not added before. The directories between
+// the module root and the package are added too, like sample.AddPackage does.
+func (ds *FakeDataSource) AddPackage(p *internal.LegacyVersionedPackage) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	var m *internal.Module
+	if e := ds.modules[p.ModulePath][p.Version]; e != nil {
+		m = e.module
+	} else {
+		m = &internal.Module{
+			LegacyModuleInfo: p.LegacyModuleInfo,
+			Directories:      []*internal.DirectoryNew{sample.DirectoryNewForModuleRoot(&p.LegacyModuleInfo, p.Licenses)},
+		}
+		ds.addModule(m)
+	}
+	pkg := p.LegacyPackage
+	for i, q := range m.LegacyPackages {
+		if q.Path == pkg.Path {
+			m.LegacyPackages = append(m.LegacyPackages[:i], m.LegacyPackages[i+1:]...)
+			break
+		}
+	}
+	m.LegacyPackages = append(m.LegacyPackages, &pkg)
+	for i, d := range m.Directories {
+		if d.Path == pkg.Path {
+			m.Directories = append(m.Directories[:i], m.Directories[i+1:]...)
+			break
+		}
+	}
+	m.Directories = append(m.Directories, sample.DirectoryNewForPackage(&pkg))
+	minLen := len(m.ModulePath)
+	if m.ModulePath == stdlib.ModulePath {
+		minLen = 1
+	}
+	for dir := path.Dir(pkg.Path); len(dir) > minLen; dir = path.Dir(dir) {
+		if findDirectory(m, dir) == nil {
+			m.Directories = append(m.Directories, sample.DirectoryNewEmpty(dir))
+		}
+	}
+}
+
+// getModule returns the module version specified by modulePath and version,
+// which may be internal.LatestVersion.
+func (ds *FakeDataSource) getModule(ctx context.Context, modulePath, version string) (_ *internal.Module, err error) {
+	defer derrors.Wrap(&err, "getModule(%q, %q)", modulePath, version)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	if version == internal.LatestVersion {
+		ms := ds.sortedVersions(modulePath)
+		if len(ms) == 0 {
+			return nil, derrors.NotFound
+		}
+		return ms[0], nil
+	}
+	e := ds.modules[modulePath][version]
+	if e == nil {
+		return nil, derrors.NotFound
+	}
+	return e.module, nil
+}
+
+// sortedVersions returns the versions of the module at modulePath, best
+// first: releases before other versions, then by descending version. The
+// first one is the latest version. ds.mu must be held.
+func (ds *FakeDataSource) sortedVersions(modulePath string) []*internal.Module {
+	var ms []*internal.Module
+	for _, e := range ds.modules[modulePath] {
+		ms = append(ms, e.module)
+	}
+	sort.Slice(ms, func(i, j int) bool { return better(ms[i], ms[j]) })
+	return ms
+}
+
+// better reports whether m1 is preferred to m2 when a path is found in both,
+// in the same way as the GetPathInfo method of postgres.DB: releases are
+// preferred, then higher versions, then module paths that sort later.
+func better(m1, m2 *internal.Module) bool {
+	r1, r2 := m1.VersionType == version.TypeRelease, m2.VersionType == version.TypeRelease
+	if r1 != r2 {
+		return r1
+	}
+	if c := semver.Compare(m1.Version, m2.Version); c != 0 {
+		return c > 0
+	}
+	return m1.ModulePath > m2.ModulePath
+}
+
+// findPath returns the module version that contains the directory path, and
+// that directory. If modulePath is internal.UnknownModulePath, any module may
+// contain it, and if version is internal.LatestVersion, any version. The best
+// match is chosen as described by better.
+func (ds *FakeDataSource) findPath(ctx context.Context, dirPath, modulePath, version string) (_ *internal.Module, _ *internal.DirectoryNew, err error) {
+	defer derrors.Wrap(&err, "findPath(%q, %q, %q)", dirPath, modulePath, version)
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	if dirPath == "" {
+		return nil, nil, fmt.Errorf("empty path: %w", derrors.InvalidArgument)
+	}
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	var (
+		best    *internal.Module
+		bestDir *internal.DirectoryNew
+	)
+	for mp, versions := range ds.modules {
+		if modulePath != internal.UnknownModulePath && mp != modulePath {
+			continue
+		}
+		for v, e := range versions {
+			if version != internal.LatestVersion && v != version {
+				continue
+			}
+			d := findDirectory(e.module, dirPath)
+			if d != nil && (best == nil || better(e.module, best)) {
+				best, bestDir = e.module, d
+			}
+		}
+	}
+	if best == nil {
+		return nil, nil, derrors.NotFound
+	}
+	return best, bestDir, nil
+}
+
+// findDirectory returns the directory of m with path dirPath, or nil if there
+// is none.
+func findDirectory(m *internal.Module, dirPath string) *internal.DirectoryNew {
+	for _, d := range m.Directories {
+		if d.Path == dirPath {
+			return d
+		}
+	}
+	return nil
+}
+
+// getPackage returns the package pkgPath of the module version specified by
+// modulePath and version, either of which may be unknown, as in findPath.
+func (ds *FakeDataSource) getPackage(ctx context.Context, pkgPath, modulePath, version string) (_ *internal.LegacyVersionedPackage, err error) {
+	m, d, err := ds.findPath(ctx, pkgPath, modulePath, version)
+	if err != nil {
+		return nil, err
+	}
+	if d.Package == nil {
+		return nil, fmt.Errorf("%q is not a package: %w", pkgPath, derrors.NotFound)
+	}
+	return packageFromModule(pkgPath, m)
+}
+
+// packageFromModule returns the package pkgPath of m.
+func packageFromModule(pkgPath string, m *internal.Module) (*internal.LegacyVersionedPackage, error) {
+	for _, p := range m.LegacyPackages {
+		if p.Path == pkgPath {
+			return &internal.LegacyVersionedPackage{
+				LegacyPackage:    *p,
+				LegacyModuleInfo: m.LegacyModuleInfo,
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("package %s missing from module %s: %w", pkgPath, m.ModulePath, derrors.NotFound)
+}
+
+// latestModules returns the latest version of each module of ds, ordered by
+// module path.
+func (ds *FakeDataSource) latestModules() []*internal.Module {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	var ms []*internal.Module
+	for mp := range ds.modules {
+		if vs := ds.sortedVersions(mp); len(vs) > 0 {
+			ms = append(ms, vs[0])
+		}
+	}
+	sort.Slice(ms, func(i, j int) bool { return ms[i].ModulePath < ms[j].ModulePath })
+	return ms
+}
+
+// GetDirectoryNew returns the directory dirPath of the module version.
+func (ds *FakeDataSource) GetDirectoryNew(ctx context.Context, dirPath, modulePath, version string) (_ *internal.VersionedDirectory, err error) {
+	defer derrors.Wrap(&err, "GetDirectoryNew(%q, %q, %q)", dirPath, modulePath, version)
+	m, d, err := ds.findPath(ctx, dirPath, modulePath, version)
+	if err != nil {
+		return nil, err
+	}
+	return &internal.VersionedDirectory{
+		ModuleInfo:   m.ModuleInfo,
+		DirectoryNew: *d,
+	}, nil
+}
+
+// GetImports returns the imports of the package.
+func (ds *FakeDataSource) GetImports(ctx context.Context, pkgPath, modulePath, version string) (_ []string, err error) {
+	defer derrors.Wrap(&err, "GetImports(%q, %q, %q)", pkgPath, modulePath, version)
+	vp, err := ds.getPackage(ctx, pkgPath, modulePath, version)
+	if err != nil {
+		return nil, err
+	}
+	return vp.Imports, nil
+}
+
+// GetModuleDependencies returns no dependencies, since they are not part of
+// the modules added to ds.
+func (*FakeDataSource) GetModuleDependencies(ctx context.Context, modulePath, version string) ([]*internal.ModuleDependency, error) {
+	return nil, nil
+}
+
+// GetPackageSymbols returns the exported symbols of the package, ordered by
+// name.
+func (ds *FakeDataSource) GetPackageSymbols(ctx context.Context, pkgPath, version string) (_ []*internal.Symbol, err error) {
+	defer derrors.Wrap(&err, "GetPackageSymbols(%q, %q)", pkgPath, version)
+	vp, err := ds.getPackage(ctx, pkgPath, internal.UnknownModulePath, version)
+	if err != nil {
+		return nil, err
+	}
+	syms := append([]*internal.Symbol(nil), vp.Symbols...)
+	sort.Slice(syms, func(i, j int) bool { return syms[i].Name < syms[j].Name })
+	return syms, nil
+}
+
+// GetExamples returns the examples of the package, ordered by name.
+func (ds *FakeDataSource) GetExamples(ctx context.Context, pkgPath, version string) (_ []*internal.Example, err error) {
+	defer derrors.Wrap(&err, "GetExamples(%q, %q)", pkgPath, version)
+	vp, err := ds.getPackage(ctx, pkgPath, internal.UnknownModulePath, version)
+	if err != nil {
+		return nil, err
+	}
+	exs := append([]*internal.Example(nil), vp.Examples...)
+	sort.Slice(exs, func(i, j int) bool { return exs[i].Name < exs[j].Name })
+	return exs, nil
+}
+
+// GetExportedSymbolCount returns the number of exported symbols of the
+// package.
+func (ds *FakeDataSource) GetExportedSymbolCount(ctx context.Context, pkgPath, version string) (_ int, err error) {
+	defer derrors.Wrap(&err, "GetExportedSymbolCount(%q, %q)", pkgPath, version)
+	vp, err := ds.getPackage(ctx, pkgPath, internal.UnknownModulePath, version)
+	if err != nil {
+		return 0, err
+	}
+	return len(vp.Symbols), nil
+}
+
+// GetBuildContexts returns the build contexts of the package.
+func (ds *FakeDataSource) GetBuildContexts(ctx context.Context, pkgPath, version string) (_ []internal.BuildContext, err error) {
+	defer derrors.Wrap(&err, "GetBuildContexts(%q, %q)", pkgPath, version)
+	vp, err := ds.getPackage(ctx, pkgPath, internal.UnknownModulePath, version)
+	if err != nil {
+		return nil, err
+	}
+	return vp.BuildContexts, nil
+}
+
+// GetEmbeddedPatterns returns the //go:embed patterns of the package.
+func (ds *FakeDataSource) GetEmbeddedPatterns(ctx context.Context, pkgPath, version string) (_ []string, err error) {
+	defer derrors.Wrap(&err, "GetEmbeddedPatterns(%q, %q)", pkgPath, version)
+	vp, err := ds.getPackage(ctx, pkgPath, internal.UnknownModulePath, version)
+	if err != nil {
+		return nil, err
+	}
+	return vp.EmbeddedPatterns, nil
+}
+
+// GetHasFuzzTests reports whether the package has a fuzz target.
+func (ds *FakeDataSource) GetHasFuzzTests(ctx context.Context, pkgPath, version string) (_ bool, err error) {
+	defer derrors.Wrap(&err, "GetHasFuzzTests(%q, %q)", pkgPath, version)
+	vp, err := ds.getPackage(ctx, pkgPath, internal.UnknownModulePath, version)
+	if err != nil {
+		return false, err
+	}
+	return vp.HasFuzzTests, nil
+}
+
+// GetBenchmarkCount returns the number of benchmarks of the package.
+func (ds *FakeDataSource) GetBenchmarkCount(ctx context.Context, pkgPath, version string) (_ int, err error) {
+	defer derrors.Wrap(&err, "GetBenchmarkCount(%q, %q)", pkgPath, version)
+	vp, err := ds.getPackage(ctx, pkgPath, internal.UnknownModulePath, version)
+	if err != nil {
+		return 0, err
+	}
+	return vp.BenchmarkCount, nil
+}
+
+// GetContributorCount is unimplemented.
+func (*FakeDataSource) GetContributorCount(ctx context.Context, modulePath, version string) (int, error) {
+	return 0, nil
+}
+
+// GetSuccessorPackages returns the packages mentioned in the deprecation
+// message of the package.
+func (ds *FakeDataSource) GetSuccessorPackages(ctx context.Context, pkgPath, version string) (_ []string, err error) {
+	defer derrors.Wrap(&err, "GetSuccessorPackages(%q, %q)", pkgPath, version)
+	vp, err := ds.getPackage(ctx, pkgPath, internal.UnknownModulePath, version)
+	if err != nil {
+		return nil, err
+	}
+	return vp.SuccessorPackages, nil
+}
+
+// GetPackagePathsForSitemap returns up to limit paths of the redistributable
+// packages of the latest versions of the modules, in sorted order, after the
+// first offset paths.
+func (ds *FakeDataSource) GetPackagePathsForSitemap(ctx context.Context, offset, limit int) ([]string, error) {
+	paths := ds.sitemapPaths()
+	if offset >= len(paths) {
+		return nil, nil
+	}
+	paths = paths[offset:]
+	if len(paths) > limit {
+		paths = paths[:limit]
+	}
+	return paths, nil
+}
+
+// GetSitemapPackageCount returns the number of paths that
+// GetPackagePathsForSitemap can return.
+func (ds *FakeDataSource) GetSitemapPackageCount(ctx context.Context) (int, error) {
+	return len(ds.sitemapPaths()), nil
+}
+
+func (ds *FakeDataSource) sitemapPaths() []string {
+	var paths []string
+	for _, m := range ds.latestModules() {
+		for _, p := range m.LegacyPackages {
+			if p.IsRedistributable {
+				paths = append(paths, p.Path)
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// GetPathInfo returns the module version that contains path, and whether path
+// is a package.
+func (ds *FakeDataSource) GetPathInfo(ctx context.Context, path, inModulePath, inVersion string) (outModulePath, outVersion string, isPackage bool, err error) {
+	defer derrors.Wrap(&err, "GetPathInfo(%q, %q, %q)", path, inModulePath, inVersion)
+	m, d, err := ds.findPath(ctx, path, inModulePath, inVersion)
+	if err != nil {
+		return "", "", false, err
+	}
+	return m.ModulePath, m.Version, d.Package != nil, nil
+}
+
+// GetSymbolDefinition returns the location of the symbol in the latest
+// version of the package.
+func (ds *FakeDataSource) GetSymbolDefinition(ctx context.Context, pkgPath, symbolName string) (_ *internal.SymbolLocation, err error) {
+	defer derrors.Wrap(&err, "GetSymbolDefinition(%q, %q)", pkgPath, symbolName)
+	vp, err := ds.getPackage(ctx, pkgPath, internal.UnknownModulePath, internal.LatestVersion)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range vp.Symbols {
+		if s.Name == symbolName {
+			return &internal.SymbolLocation{
+				PackagePath: vp.Path,
+				ModulePath:  vp.ModulePath,
+				Version:     vp.Version,
+				FilePath:    s.FilePath,
+				Line:        s.Line,
+			}, nil
+		}
+	}
+	return nil, derrors.NotFound
+}
+
+// GetInterfaceImplementations returns the types of the latest versions of the
+// modules that implement the interface, ordered by package path and name.
+func (ds *FakeDataSource) GetInterfaceImplementations(ctx context.Context, ifacePkg, ifaceName string) ([]*internal.TypeRef, error) {
+	var refs []*internal.TypeRef
+	for _, m := range ds.latestModules() {
+		for _, impl := range m.Implementations {
+			if impl.InterfacePackagePath == ifacePkg && impl.InterfaceName == ifaceName {
+				ref := impl.Type
+				refs = append(refs, &ref)
+			}
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].PackagePath != refs[j].PackagePath {
+			return refs[i].PackagePath < refs[j].PackagePath
+		}
+		return refs[i].TypeName < refs[j].TypeName
+	})
+	return refs, nil
+}
+
+// GetSearchSuggestions returns up to limit packages of the latest versions of
+// the modules whose path, a path element or name starts with prefix. Packages
+// whose path starts with prefix come first, then they are ordered by path.
+func (ds *FakeDataSource) GetSearchSuggestions(ctx context.Context, prefix string, limit int) ([]*internal.SearchSuggestion, error) {
+	var suggestions []*internal.SearchSuggestion
+	for _, m := range ds.latestModules() {
+		for _, p := range m.LegacyPackages {
+			var score float64
+			switch {
+			case strings.HasPrefix(p.Path, prefix):
+				score = 2
+			case strings.HasPrefix(p.Name, prefix) || strings.Contains(p.Path, "/"+prefix):
+				score = 1
+			default:
+				continue
+			}
+			suggestions = append(suggestions, &internal.SearchSuggestion{
+				PackagePath: p.Path,
+				Synopsis:    p.Synopsis,
+				Score:       score,
+			})
+		}
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].PackagePath < suggestions[j].PackagePath
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions, nil
+}
+
+// GetVulnerabilities returns no vulnerabilities, since they are not part of
+// the modules added to ds.
+func (*FakeDataSource) GetVulnerabilities(ctx context.Context, modulePath, version string) ([]*internal.VulnReport, error) {
+	return nil, nil
+}
+
+// GetRetractedVersions returns the versions of the module that are retracted
+// by the go.mod file of the version itself or of a newer one, in ascending
+// order.
+func (ds *FakeDataSource) GetRetractedVersions(ctx context.Context, modulePath string) ([]string, error) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	ms := ds.sortedVersions(modulePath)
+	var retracted []string
+	for _, m := range ms {
+		for _, n := range ms {
+			if semver.Compare(n.Version, m.Version) < 0 {
+				continue
+			}
+			if retracts(n.RetractedVersions, m.Version) {
+				retracted = append(retracted, m.Version)
+				break
+			}
+		}
+	}
+	sort.Slice(retracted, func(i, j int) bool { return semver.Compare(retracted[i], retracted[j]) < 0 })
+	return retracted, nil
+}
+
+// retracts reports whether one of the retractions covers v.
+func retracts(retractions []string, v string) bool {
+	for _, r := range retractions {
+		if version.RetractionCovers(r, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetWorkspace returns the workspace of the latest version of the module at
+// repoPath, or nil if it has none.
+func (ds *FakeDataSource) GetWorkspace(ctx context.Context, repoPath string) (_ *internal.Workspace, err error) {
+	defer derrors.Wrap(&err, "GetWorkspace(%q)", repoPath)
+	m, err := ds.getModule(ctx, repoPath, internal.LatestVersion)
+	if err != nil {
+		return nil, err
+	}
+	return m.Workspace, nil
+}
+
+// GetModuleLinks returns the links of the latest version of the module.
+func (ds *FakeDataSource) GetModuleLinks(ctx context.Context, modulePath string) (_ *internal.ModuleLinks, err error) {
+	defer derrors.Wrap(&err, "GetModuleLinks(%q)", modulePath)
+	m, err := ds.getModule(ctx, modulePath, internal.LatestVersion)
+	if err != nil {
+		return nil, err
+	}
+	if m.Links == nil {
+		return nil, derrors.NotFound
+	}
+	return m.Links, nil
+}
+
+// GetModuleFiles is unimplemented.
+func (*FakeDataSource) GetModuleFiles(ctx context.Context, modulePath, version, subpath string) ([]*internal.FileInfo, error) {
+	return nil, nil
+}
+
+// GetRecentlyIndexedModules returns up to limit module versions that were
+// added to ds after since, most recent first.
+func (ds *FakeDataSource) GetRecentlyIndexedModules(ctx context.Context, limit int, since time.Time) ([]*internal.IndexedModule, error) {
+	ds.mu.RLock()
+	var mods []*internal.IndexedModule
+	for _, versions := range ds.modules {
+		for _, e := range versions {
+			if e.indexedAt.After(since) {
+				mods = append(mods, &internal.IndexedModule{ModuleInfo: e.module.ModuleInfo, IndexedAt: e.indexedAt})
+			}
+		}
+	}
+	ds.mu.RUnlock()
+	sort.Slice(mods, func(i, j int) bool { return mods[i].IndexedAt.After(mods[j].IndexedAt) })
+	if len(mods) > limit {
+		mods = mods[:limit]
+	}
+	return mods, nil
+}
+
+// GetPseudoVersionsForModule returns the pseudo-versions of the module, in
+// descending order.
+func (ds *FakeDataSource) GetPseudoVersionsForModule(ctx context.Context, modulePath string) (_ []*internal.ModuleInfo, err error) {
+	defer derrors.Wrap(&err, "GetPseudoVersionsForModule(%q)", modulePath)
+	return ds.listModuleVersions(ctx, []string{modulePath}, true)
+}
+
+// GetPseudoVersionsForPackageSeries returns the pseudo-versions of the
+// modules of the series that contain the package, in descending order.
+func (ds *FakeDataSource) GetPseudoVersionsForPackageSeries(ctx context.Context, pkgPath string) (_ []*internal.ModuleInfo, err error) {
+	defer derrors.Wrap(&err, "GetPseudoVersionsForPackageSeries(%q)", pkgPath)
+	return ds.listPackageVersions(ctx, pkgPath, true)
+}
+
+// GetTaggedVersionsForModule returns the versions of the module that are not
+// pseudo-versions, in descending order.
+func (ds *FakeDataSource) GetTaggedVersionsForModule(ctx context.Context, modulePath string) (_ []*internal.ModuleInfo, err error) {
+	defer derrors.Wrap(&err, "GetTaggedVersionsForModule(%q)", modulePath)
+	return ds.listModuleVersions(ctx, []string{modulePath}, false)
+}
+
+// GetTaggedVersionsForPackageSeries returns the versions of the modules of
+// the series that contain the package that are not pseudo-versions, in
+// descending order.
+func (ds *FakeDataSource) GetTaggedVersionsForPackageSeries(ctx context.Context, pkgPath string) (_ []*internal.ModuleInfo, err error) {
+	defer derrors.Wrap(&err, "GetTaggedVersionsForPackageSeries(%q)", pkgPath)
+	return ds.listPackageVersions(ctx, pkgPath, false)
+}
+
+// listPackageVersions lists the versions of the modules whose series is that
+// of the latest module containing pkgPath, and that contain a package with
+// the same v1 path. If pseudo is true, it lists pseudo-versions; otherwise,
+// other versions.
+func (ds *FakeDataSource) listPackageVersions(ctx context.Context, pkgPath string, pseudo bool) ([]*internal.ModuleInfo, error) {
+	m, d, err := ds.findPath(ctx, pkgPath, internal.UnknownModulePath, internal.LatestVersion)
+	if errors.Is(err, derrors.NotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	series := m.SeriesPath()
+	ds.mu.RLock()
+	var modulePaths []string
+	for mp, versions := range ds.modules {
+		if internal.SeriesPathForModule(mp) != series {
+			continue
+		}
+		for _, e := range versions {
+			if pd := findPackageByV1Path(e.module, d.V1Path); pd != nil {
+				modulePaths = append(modulePaths, mp)
+				break
+			}
+		}
+	}
+	ds.mu.RUnlock()
+	return ds.listModuleVersions(ctx, modulePaths, pseudo)
+}
+
+// findPackageByV1Path returns the package directory of m with the given v1
+// path, or nil if there is none.
+func findPackageByV1Path(m *internal.Module, v1Path string) *internal.DirectoryNew {
+	for _, d := range m.Directories {
+		if d.Package != nil && d.V1Path == v1Path {
+			return d
+		}
+	}
+	return nil
+}
+
+// listModuleVersions lists the versions of the modules at modulePaths. If
+// pseudo is true, it lists pseudo-versions; otherwise, other versions.
+func (ds *FakeDataSource) listModuleVersions(ctx context.Context, modulePaths []string, pseudo bool) ([]*internal.ModuleInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	var mis []*internal.ModuleInfo
+	for _, mp := range modulePaths {
+		for v, e := range ds.modules[mp] {
+			if version.IsPseudo(v) == pseudo {
+				mi := e.module.ModuleInfo
+				mis = append(mis, &mi)
+			}
+		}
+	}
+	sort.Slice(mis, func(i, j int) bool {
+		if c := semver.Compare(mis[i].Version, mis[j].Version); c != 0 {
+			return c > 0
+		}
+		return mis[i].ModulePath > mis[j].ModulePath
+	})
+	return mis, nil
+}
+
+// LegacyGetDirectory returns the packages of the module version that are in
+// dirPath or its subdirectories.
+func (ds *FakeDataSource) LegacyGetDirectory(ctx context.Context, dirPath, modulePath, version string, _ internal.FieldSet) (_ *internal.LegacyDirectory, err error) {
+	defer derrors.Wrap(&err, "LegacyGetDirectory(%q, %q, %q)", dirPath, modulePath, version)
+	m, _, err := ds.findPath(ctx, dirPath, modulePath, version)
+	if err != nil {
+		return nil, err
+	}
+	var pkgs []*internal.LegacyPackage
+	for _, p := range m.LegacyPackages {
+		if p.Path == dirPath || strings.HasPrefix(p.Path, dirPath+"/") || dirPath == stdlib.ModulePath {
+			pkgs = append(pkgs, p)
+		}
+	}
+	if len(pkgs) == 0 {
+		return nil, fmt.Errorf("%q has no packages: %w", dirPath, derrors.NotFound)
+	}
+	return &internal.LegacyDirectory{
+		LegacyModuleInfo: m.LegacyModuleInfo,
+		Path:             dirPath,
+		Packages:         pkgs,
+	}, nil
+}
+
+// LegacyGetModuleLicenses returns the licenses at the root of the module
+// version.
+func (ds *FakeDataSource) LegacyGetModuleLicenses(ctx context.Context, modulePath, version string) (_ []*licenses.License, err error) {
+	defer derrors.Wrap(&err, "LegacyGetModuleLicenses(%q, %q)", modulePath, version)
+	m, err := ds.getModule(ctx, modulePath, version)
+	if err != nil {
+		return nil, err
+	}
+	var lics []*licenses.License
+	for _, lic := range m.Licenses {
+		if !strings.Contains(lic.FilePath, "/") {
+			lics = append(lics, lic)
+		}
+	}
+	return lics, nil
+}
+
+// LegacyGetPackage returns the package pkgPath of the module version, either
+// of which may be unknown.
+func (ds *FakeDataSource) LegacyGetPackage(ctx context.Context, pkgPath, modulePath, version string) (_ *internal.LegacyVersionedPackage, err error) {
+	defer derrors.Wrap(&err, "LegacyGetPackage(%q, %q, %q)", pkgPath, modulePath, version)
+	return ds.getPackage(ctx, pkgPath, modulePath, version)
+}
+
+// LegacyGetPackages returns the packages with the given paths in the module
+// version, with nil for the paths that are not packages of the module.
+func (ds *FakeDataSource) LegacyGetPackages(ctx context.Context, pkgPaths []string, modulePath, version string) (_ []*internal.LegacyVersionedPackage, err error) {
+	defer derrors.Wrap(&err, "LegacyGetPackages([%d paths], %q, %q)", len(pkgPaths), modulePath, version)
+	if len(pkgPaths) == 0 {
+		return nil, nil
+	}
+	m, err := ds.getModule(ctx, modulePath, version)
+	if err != nil {
+		return nil, err
+	}
+	pkgs := make([]*internal.LegacyVersionedPackage, len(pkgPaths))
+	for i, p := range pkgPaths {
+		if vp, err := packageFromModule(p, m); err == nil {
+			pkgs[i] = vp
+		}
+	}
+	return pkgs, nil
+}
+
+// LegacyGetPackageLicenses returns the licenses that apply to the package.
+func (ds *FakeDataSource) LegacyGetPackageLicenses(ctx context.Context, pkgPath, modulePath, version string) (_ []*licenses.License, err error) {
+	defer derrors.Wrap(&err, "LegacyGetPackageLicenses(%q, %q, %q)", pkgPath, modulePath, version)
+	m, err := ds.getModule(ctx, modulePath, version)
+	if err != nil {
+		return nil, err
+	}
+	vp, err := packageFromModule(pkgPath, m)
+	if err != nil {
+		return nil, err
+	}
+	var lics []*licenses.License
+	for _, lmd := range vp.Licenses {
+		for _, lic := range m.Licenses {
+			if lic.FilePath == lmd.FilePath {
+				lics = append(lics, lic)
+				break
+			}
+		}
+	}
+	return lics, nil
+}
+
+// LegacyGetPackagesInModule returns the packages of the module version.
+func (ds *FakeDataSource) LegacyGetPackagesInModule(ctx context.Context, modulePath, version string) (_ []*internal.LegacyPackage, err error) {
+	defer derrors.Wrap(&err, "LegacyGetPackagesInModule(%q, %q)", modulePath, version)
+	m, err := ds.getModule(ctx, modulePath, version)
+	if err != nil {
+		return nil, err
+	}
+	return m.LegacyPackages, nil
+}
+
+// LegacyGetModuleInfo returns the information of the module version.
+func (ds *FakeDataSource) LegacyGetModuleInfo(ctx context.Context, modulePath, version string) (_ *internal.LegacyModuleInfo, err error) {
+	defer derrors.Wrap(&err, "LegacyGetModuleInfo(%q, %q)", modulePath, version)
+	m, err := ds.getModule(ctx, modulePath, version)
+	if err != nil {
+		return nil, err
+	}
+	mi := m.LegacyModuleInfo
+	return &mi, nil
+}
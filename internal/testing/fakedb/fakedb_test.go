@@ -0,0 +1,71 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fakedb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/proxy"
+	"golang.org/x/pkgsite/internal/testing/datasourcetest"
+	"golang.org/x/pkgsite/internal/testing/sample"
+)
+
+func TestContract(t *testing.T) {
+	ctx := context.Background()
+	proxyClient, teardownProxy := proxy.SetupTestProxy(t, datasourcetest.TestModules())
+	defer teardownProxy()
+	ds := New()
+	for _, m := range datasourcetest.FetchModules(ctx, t, proxyClient) {
+		ds.AddModule(m)
+	}
+	datasourcetest.DataSourceContractTest(t, ds)
+}
+
+func TestAddPackage(t *testing.T) {
+	ctx := context.Background()
+	ds := New()
+	mi := sample.LegacyModuleInfo("example.com/mod", "v1.0.0")
+	for _, suffix := range []string{"a/b/c", "d"} {
+		ds.AddPackage(&internal.LegacyVersionedPackage{
+			LegacyPackage:    *sample.LegacyPackage(mi.ModulePath, suffix),
+			LegacyModuleInfo: *mi,
+		})
+	}
+
+	for _, test := range []struct {
+		path          string
+		wantIsPackage bool
+	}{
+		{"example.com/mod", false},
+		{"example.com/mod/a", false},
+		{"example.com/mod/a/b", false},
+		{"example.com/mod/a/b/c", true},
+		{"example.com/mod/d", true},
+	} {
+		modulePath, version, isPackage, err := ds.GetPathInfo(ctx, test.path, internal.UnknownModulePath, internal.LatestVersion)
+		if err != nil {
+			t.Errorf("GetPathInfo(%q): %v", test.path, err)
+			continue
+		}
+		if modulePath != mi.ModulePath || version != mi.Version || isPackage != test.wantIsPackage {
+			t.Errorf("GetPathInfo(%q) = %q, %q, %t; want %q, %q, %t",
+				test.path, modulePath, version, isPackage, mi.ModulePath, mi.Version, test.wantIsPackage)
+		}
+	}
+	pkgs, err := ds.LegacyGetPackagesInModule(ctx, mi.ModulePath, mi.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range pkgs {
+		got = append(got, p.Path)
+	}
+	if diff := cmp.Diff([]string{"example.com/mod/a/b/c", "example.com/mod/d"}, got); diff != "" {
+		t.Errorf("LegacyGetPackagesInModule mismatch (-want +got):\n%s", diff)
+	}
+}
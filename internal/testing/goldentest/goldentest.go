@@ -0,0 +1,104 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package goldentest compares the output of tests with golden files holding
+// their expected output. It should only be imported by test files.
+//
+// Golden files are stored in the testdata directory of the package under
+// test, with the extension ".golden". Run the tests with the -update flag to
+// create or update them, and review the changes before committing them.
+package goldentest
+
+import (
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var update = flag.Bool("update", false, "update golden files instead of comparing with them")
+
+// Path returns the path of the golden file with the given name.
+func Path(name string) string {
+	return filepath.Join("testdata", name+".golden")
+}
+
+// Check compares got, after normalizing it, with the golden file with the
+// given name, and fails t if they differ. If the -update flag is set, it
+// writes the normalized got to the golden file instead.
+func Check(t *testing.T, name string, got []byte) {
+	t.Helper()
+	got = Normalize(got)
+	path := Path(name)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		t.Fatalf("golden file %s does not exist; run the test with -update to create it", path)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(string(want), string(got)); diff != "" {
+		t.Errorf("output does not match %s (-want +got):\n%s\nIf the change is intended, run the test with -update.", path, diff)
+	}
+}
+
+// normalizations replace the parts of test output that vary between runs, or
+// whenever the test data changes, with placeholders.
+var normalizations = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	// CSP nonces are random.
+	{regexp.MustCompile(`nonce="[^"]*"`), `nonce="NONCE"`},
+	// RFC 3339 timestamps, like commit times in JSON.
+	{regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})`), "TIME"},
+	// Dates as displayed on pages, like "Jan 2, 2006".
+	{regexp.MustCompile(`\b(Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec) \d{1,2}, \d{4}\b`), "DATE"},
+	// Semantic versions, including pseudo-versions.
+	{regexp.MustCompile(`\bv\d+\.\d+\.\d+(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?\b`), "VERSION"},
+}
+
+// Normalize returns a copy of b in which the parts that vary between runs or
+// with the test data, like CSP nonces, commit times and versions, are replaced
+// with placeholders, so that golden files only change with the structure of
+// the output.
+func Normalize(b []byte) []byte {
+	b = append([]byte(nil), b...)
+	for _, n := range normalizations {
+		b = n.re.ReplaceAll(b, []byte(n.repl))
+	}
+	return b
+}
+
+// MakeGoldenHandler returns a handler that serves requests with h, and checks
+// the body of each response with the golden file with the given name, as
+// Check does.
+func MakeGoldenHandler(t *testing.T, name string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		for k, v := range rec.Header() {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(rec.Code)
+		w.Write(rec.Body.Bytes())
+		Check(t, name, rec.Body.Bytes())
+	})
+}
@@ -0,0 +1,49 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package goldentest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	for _, test := range []struct {
+		in, want string
+	}{
+		{`<script nonce="abc+/=">`, `<script nonce="NONCE">`},
+		{`"CommitTime":"2019-01-30T00:00:00Z"`, `"CommitTime":"TIME"`},
+		{`"CommitTime":"2020-05-04T12:30:01.123-07:00"`, `"CommitTime":"TIME"`},
+		{"Published: Jan 30, 2019", "Published: DATE"},
+		{"Version: v1.2.3", "Version: VERSION"},
+		{"/github.com/a/b@v0.0.0-20200101120000-abcdef123456/c", "/github.com/a/b@VERSION/c"},
+		{"v1.2.3-pre.1+incompatible", "VERSION"},
+		{"go1.14 and v1.2", "go1.14 and v1.2"},
+	} {
+		if got := string(Normalize([]byte(test.in))); got != test.want {
+			t.Errorf("Normalize(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestMakeGoldenHandler(t *testing.T) {
+	h := MakeGoldenHandler(t, "hello", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, "<p>Hello, %s@v1.2.3, published Jan 30, 2019.</p>\n", r.URL.Path[1:])
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/world", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("got status %d, want %d", w.Code, http.StatusOK)
+	}
+	if got, want := w.Header().Get("Content-Type"), "text/html; charset=utf-8"; got != want {
+		t.Errorf("Content-Type = %q, want %q", got, want)
+	}
+	if got, want := w.Body.String(), "<p>Hello, world@v1.2.3, published Jan 30, 2019.</p>\n"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
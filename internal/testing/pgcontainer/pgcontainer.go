@@ -0,0 +1,162 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package pgcontainer runs Postgres in a throwaway Docker container, so that
+// database tests can run without a database set up beforehand. It should
+// only be imported by tests and test helpers.
+//
+// Only a local Docker daemon is needed: the Postgres image is pulled if it is
+// missing, and no other images are used, so once the image has been pulled
+// tests run offline. The tests of a process share one container, which
+// TerminateShared removes.
+package pgcontainer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/testing/dbtest"
+)
+
+const (
+	// Image is the Docker image that is run. Its version is the one used
+	// in production and in cloudbuild.yaml.
+	Image = "postgres:11.4"
+
+	user     = "postgres"
+	password = "postgres"
+	port     = "5432/tcp"
+
+	startupTimeout = 2 * time.Minute
+)
+
+// A Container is a running Postgres container.
+type Container struct {
+	c          testcontainers.Container
+	host, port string
+}
+
+// Start starts a Postgres container and waits until it accepts connections.
+// If there is no Docker daemon to run it, the error wraps derrors.NotFound.
+func Start(ctx context.Context) (_ *Container, err error) {
+	defer derrors.Wrap(&err, "pgcontainer.Start")
+
+	provider, err := testcontainers.NewDockerProvider()
+	if err == nil {
+		err = provider.Health(ctx)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: connecting to Docker: %v", derrors.NotFound, err)
+	}
+	c, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        Image,
+			ExposedPorts: []string{port},
+			Env: map[string]string{
+				"POSTGRES_USER":     user,
+				"POSTGRES_PASSWORD": password,
+			},
+			// Postgres logs this line once when the database has been
+			// initialized, and again when the server has restarted after
+			// initialization.
+			WaitingFor: wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(startupTimeout),
+			// The reaper removes containers left behind by crashed tests, but
+			// it is another image to pull. Containers are removed by
+			// Terminate instead.
+			SkipReaper: true,
+			AutoRemove: true,
+		},
+		Started: true,
+	})
+	if err != nil {
+		if c != nil {
+			c.Terminate(ctx)
+		}
+		return nil, err
+	}
+	host, err := c.Host(ctx)
+	if err != nil {
+		c.Terminate(ctx)
+		return nil, err
+	}
+	mapped, err := c.MappedPort(ctx, port)
+	if err != nil {
+		c.Terminate(ctx)
+		return nil, err
+	}
+	return &Container{c: c, host: host, port: mapped.Port()}, nil
+}
+
+// URI returns the connection URI of the database dbName in the container, in
+// the form expected by migrate. If dbName is empty, the URI is that of the
+// default database.
+func (c *Container) URI(dbName string) string {
+	return fmt.Sprintf("postgres://%s:%s/%s?sslmode=disable&user=%s&password=%s",
+		c.host, c.port, dbName, url.QueryEscape(user), url.QueryEscape(password))
+}
+
+// CreateDB creates the database dbName in the container.
+func (c *Container) CreateDB(dbName string) error {
+	return dbtest.ConnectAndExecute(c.URI(""), func(pg *sql.DB) error {
+		_, err := pg.Exec(fmt.Sprintf("CREATE DATABASE %q;", dbName))
+		return err
+	})
+}
+
+// DropDB drops the database dbName in the container.
+func (c *Container) DropDB(dbName string) error {
+	return dbtest.ConnectAndExecute(c.URI(""), func(pg *sql.DB) error {
+		_, err := pg.Exec(fmt.Sprintf("DROP DATABASE IF EXISTS %q;", dbName))
+		return err
+	})
+}
+
+// Terminate stops and removes the container.
+func (c *Container) Terminate(ctx context.Context) error {
+	return c.c.Terminate(ctx)
+}
+
+var shared struct {
+	once sync.Once
+	mu   sync.Mutex
+	c    *Container
+	err  error
+}
+
+// Shared returns the container shared by the tests of the process, starting
+// it on the first call.
+func Shared(ctx context.Context) (*Container, error) {
+	shared.once.Do(func() {
+		c, err := Start(ctx)
+		shared.mu.Lock()
+		shared.c, shared.err = c, err
+		shared.mu.Unlock()
+	})
+	shared.mu.Lock()
+	defer shared.mu.Unlock()
+	return shared.c, shared.err
+}
+
+// TerminateShared terminates the shared container, if it was started. It
+// should be called by TestMain after the tests have run.
+func TerminateShared(ctx context.Context) error {
+	shared.mu.Lock()
+	defer shared.mu.Unlock()
+	if shared.c == nil {
+		return nil
+	}
+	err := shared.c.Terminate(ctx)
+	shared.c, shared.err = nil, errors.New("the shared container was terminated")
+	return err
+}
@@ -0,0 +1,117 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package tracing provides distributed tracing with OpenTelemetry.
+//
+// Spans are propagated between services in W3C Trace Context headers.
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/pkgsite/internal/config"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/middleware"
+)
+
+// tracerName is the name of the tracer of the spans started by this package.
+const tracerName = "golang.org/x/pkgsite"
+
+// propagator reads and writes W3C Trace Context headers.
+var propagator = propagation.TraceContext{}
+
+// Init returns a TracerProvider that samples traces at cfg.SampleRate and
+// exports them to the OTLP/HTTP collector at cfg.Endpoint. It also installs
+// the provider as the global one, which StartSpan uses. The caller should
+// call Shutdown on the provider before exiting, to export buffered spans.
+func Init(ctx context.Context, cfg config.TracingConfig) (_ *sdktrace.TracerProvider, err error) {
+	defer derrors.Wrap(&err, "tracing.Init(ctx, %+v)", cfg)
+
+	exp, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpoint(cfg.Endpoint))
+	if err != nil {
+		return nil, err
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(cfg.ServiceName))),
+		// Follow the decision of the caller, if there is one, so that
+		// traces are not broken up.
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRate))),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagator)
+	return tp, nil
+}
+
+// StartSpan starts a span with the given name, as a child of the span in ctx
+// if there is one, using the global TracerProvider. It returns a context
+// holding the new span.
+func StartSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name)
+}
+
+// SetRoute names the server span in ctx after route, the pattern that the
+// request was routed by, and records route as an attribute of it.
+func SetRoute(ctx context.Context, route string) {
+	span := trace.SpanFromContext(ctx)
+	span.SetName(route)
+	span.SetAttributes(semconv.HTTPRouteKey.String(route))
+}
+
+// Middleware returns a middleware that starts a span with tp for each request,
+// as a child of the span in the request's Trace Context headers, if any. The
+// span records the method and status code of the request; handlers can record
+// its route with SetRoute.
+func Middleware(tp trace.TracerProvider) middleware.Middleware {
+	tracer := tp.Tracer(tracerName)
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := tracer.Start(ctx, "HTTP "+r.Method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPMethodKey.String(r.Method),
+					semconv.HTTPTargetKey.String(r.URL.RequestURI()),
+				))
+			defer span.End()
+
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			h.ServeHTTP(sw, r.WithContext(ctx))
+			span.SetAttributes(semconv.HTTPStatusCodeKey.Int(sw.status))
+			if sw.status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(sw.status))
+			}
+		})
+	}
+}
+
+// statusWriter is an http.ResponseWriter that records the status of the
+// response.
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
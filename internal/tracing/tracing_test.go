@@ -0,0 +1,104 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestMiddleware(t *testing.T) {
+	exp := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp))
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+	otel.SetTracerProvider(tp)
+
+	handler := Middleware(tp)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetRoute(r.Context(), "/search")
+		_, span := StartSpan(r.Context(), "child")
+		span.End()
+		switch r.FormValue("status") {
+		case "404":
+			http.NotFound(w, r)
+		case "500":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+	for _, test := range []struct {
+		name, target string
+		wantStatus   int
+		wantCode     codes.Code
+	}{
+		{"ok", "/search?q=x", http.StatusOK, codes.Unset},
+		{"not found", "/search?status=404", http.StatusNotFound, codes.Unset},
+		{"server error", "/search?status=500", http.StatusInternalServerError, codes.Error},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			exp.Reset()
+			r := httptest.NewRequest("GET", test.target, nil)
+			r.Header.Set("traceparent", "00-"+traceID+"-"+spanID+"-01")
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			spans := exp.GetSpans()
+			if len(spans) != 2 {
+				t.Fatalf("got %d spans, want 2", len(spans))
+			}
+			// The child span ends first.
+			child, server := spans[0], spans[1]
+
+			if server.Name != "/search" {
+				t.Errorf("got span name %q, want %q", server.Name, "/search")
+			}
+			if server.SpanKind != trace.SpanKindServer {
+				t.Errorf("got span kind %v, want %v", server.SpanKind, trace.SpanKindServer)
+			}
+			wantAttrs := map[attribute.Key]attribute.Value{
+				"http.method":      attribute.StringValue("GET"),
+				"http.target":      attribute.StringValue(test.target),
+				"http.route":       attribute.StringValue("/search"),
+				"http.status_code": attribute.IntValue(test.wantStatus),
+			}
+			gotAttrs := map[attribute.Key]attribute.Value{}
+			for _, kv := range server.Attributes {
+				gotAttrs[kv.Key] = kv.Value
+			}
+			if diff := cmp.Diff(wantAttrs, gotAttrs, cmp.AllowUnexported(attribute.Value{})); diff != "" {
+				t.Errorf("attributes mismatch (-want +got):\n%s", diff)
+			}
+			if server.Status.Code != test.wantCode {
+				t.Errorf("got status code %v, want %v", server.Status.Code, test.wantCode)
+			}
+
+			// The server span continues the trace of the request, and the
+			// child span is its child.
+			if got := server.SpanContext.TraceID().String(); got != traceID {
+				t.Errorf("got trace ID %s, want %s", got, traceID)
+			}
+			if got := server.Parent.SpanID().String(); got != spanID {
+				t.Errorf("got parent span ID %s, want %s", got, spanID)
+			}
+			if child.Name != "child" || child.Parent.SpanID() != server.SpanContext.SpanID() {
+				t.Errorf("got span %q with parent %s, want %q with parent %s",
+					child.Name, child.Parent.SpanID(), "child", server.SpanContext.SpanID())
+			}
+		})
+	}
+}
@@ -155,3 +155,30 @@ func appendNumericPrefix(dst []byte, n int) []byte {
 	}
 	return dst
 }
+
+// RetractionCovers reports whether retraction covers the version v. A
+// retraction is written as in the retract directive of a go.mod file: it is
+// either a single version, like "v1.0.1", or a closed interval of versions,
+// like "[v1.0.0, v1.0.5]".
+func RetractionCovers(retraction, v string) bool {
+	if strings.HasPrefix(retraction, "[") && strings.HasSuffix(retraction, "]") {
+		bounds := strings.Split(retraction[1:len(retraction)-1], ",")
+		if len(bounds) != 2 {
+			return false
+		}
+		low, high := strings.TrimSpace(bounds[0]), strings.TrimSpace(bounds[1])
+		if !semver.IsValid(low) || !semver.IsValid(high) {
+			return false
+		}
+		return semver.Compare(low, v) <= 0 && semver.Compare(v, high) <= 0
+	}
+	return semver.IsValid(retraction) && semver.Compare(retraction, v) == 0
+}
+
+var toolchainRE = regexp.MustCompile(`^go1(\.(0|[1-9][0-9]*)){1,2}((rc|beta)[1-9][0-9]*)?(-[A-Za-z0-9._+-]+)?$`)
+
+// IsToolchain reports whether v is a valid Go toolchain name, as in the
+// toolchain directive of a go.mod file, like "go1.21.0" or "go1.22rc1".
+func IsToolchain(v string) bool {
+	return toolchainRE.MatchString(v)
+}
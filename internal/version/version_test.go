@@ -151,3 +151,48 @@ func TestParseVersionType(t *testing.T) {
 		})
 	}
 }
+
+func TestRetractionCovers(t *testing.T) {
+	for _, test := range []struct {
+		retraction, version string
+		want                bool
+	}{
+		{"v1.0.1", "v1.0.1", true},
+		{"v1.0.1", "v1.0.2", false},
+		{"[v1.1.0, v1.1.3]", "v1.0.9", false},
+		{"[v1.1.0, v1.1.3]", "v1.1.0", true},
+		{"[v1.1.0, v1.1.3]", "v1.1.2-pre", true},
+		{"[v1.1.0, v1.1.3]", "v1.1.3", true},
+		{"[v1.1.0, v1.1.3]", "v1.1.4", false},
+		{"[v1.1.0,v1.1.3]", "v1.1.1", true},
+		{"[v1.4.0]", "v1.4.0", false},
+		{"bad", "v1.0.0", false},
+	} {
+		if got := RetractionCovers(test.retraction, test.version); got != test.want {
+			t.Errorf("RetractionCovers(%q, %q) = %t, want %t", test.retraction, test.version, got, test.want)
+		}
+	}
+}
+
+func TestIsToolchain(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want bool
+	}{
+		{"go1.21", true},
+		{"go1.21.0", true},
+		{"go1.21.3", true},
+		{"go1.22rc1", true},
+		{"go1.21.0-custom", true},
+		{"go1", false},
+		{"1.21.0", false},
+		{"go1.21.0.1", false},
+		{"go1.021", false},
+		{"default", false},
+		{"", false},
+	} {
+		if got := IsToolchain(test.in); got != test.want {
+			t.Errorf("IsToolchain(%q) = %t, want %t", test.in, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package internal
+
+import (
+	"strings"
+	"time"
+
+	"golang.org/x/mod/semver"
+)
+
+// A VulnReport is a known vulnerability that affects a range of versions of a
+// module.
+type VulnReport struct {
+	// ID is the identifier of the vulnerability, as in "GO-2020-0001".
+	ID         string
+	ModulePath string
+	// AffectedVersionRange is the range of affected versions: a list of
+	// constraints of the form ">=v1.2.0" or "<v1.2.3", separated by ", ". The
+	// empty string means that all versions are affected.
+	AffectedVersionRange string
+	Description          string
+	PublishedAt          time.Time
+}
+
+// Affects reports whether version is in r.AffectedVersionRange. A
+// constraint that cannot be parsed is treated as satisfied, so that a
+// malformed range errs on the side of reporting the vulnerability.
+func (r *VulnReport) Affects(version string) bool {
+	if r.AffectedVersionRange == "" {
+		return true
+	}
+	for _, c := range strings.Split(r.AffectedVersionRange, ", ") {
+		switch {
+		case strings.HasPrefix(c, ">="):
+			if semver.Compare(version, strings.TrimPrefix(c, ">=")) < 0 {
+				return false
+			}
+		case strings.HasPrefix(c, "<"):
+			if semver.Compare(version, strings.TrimPrefix(c, "<")) >= 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,28 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package internal
+
+import "testing"
+
+func TestVulnReportAffects(t *testing.T) {
+	for _, test := range []struct {
+		affected, version string
+		want              bool
+	}{
+		{"", "v1.0.0", true},
+		{">=v1.0.0, <v1.2.3", "v0.9.0", false},
+		{">=v1.0.0, <v1.2.3", "v1.0.0", true},
+		{">=v1.0.0, <v1.2.3", "v1.2.2", true},
+		{">=v1.0.0, <v1.2.3", "v1.2.3", false},
+		{"<v1.2.3", "v0.0.0-20200101000000-abcdef123456", true},
+		{">=v1.2.3", "v1.2.3-pre", false},
+		{">=v1.2.3", "v2.0.0+incompatible", true},
+	} {
+		r := &VulnReport{AffectedVersionRange: test.affected}
+		if got := r.Affects(test.version); got != test.want {
+			t.Errorf("VulnReport{AffectedVersionRange: %q}.Affects(%q) = %t, want %t", test.affected, test.version, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package worker
+
+import (
+	"context"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"golang.org/x/pkgsite/internal/log"
+	"golang.org/x/pkgsite/internal/postgres"
+)
+
+var (
+	keyCleanupStatus = tag.MustNewKey("search-cleanup.status")
+	cleanupRuns      = stats.Int64(
+		"go-discovery/worker/search-cleanup/runs",
+		"A run of the search document cleanup.",
+		stats.UnitDimensionless,
+	)
+	cleanupDeleted = stats.Int64(
+		"go-discovery/worker/search-cleanup/deleted",
+		"Number of search documents deleted by a cleanup run.",
+		stats.UnitDimensionless,
+	)
+
+	// SearchDocumentCleanupRunCount is a counter of search document cleanup
+	// runs, by whether they succeeded.
+	SearchDocumentCleanupRunCount = &view.View{
+		Name:        "go-discovery/worker/search-cleanup/run_count",
+		Measure:     cleanupRuns,
+		Aggregation: view.Count(),
+		Description: "search document cleanup runs, by status",
+		TagKeys:     []tag.Key{keyCleanupStatus},
+	}
+	// SearchDocumentCleanupDeleted is a gauge of the number of search
+	// documents deleted by the last cleanup run.
+	SearchDocumentCleanupDeleted = &view.View{
+		Name:        "go-discovery/worker/search-cleanup/deleted",
+		Measure:     cleanupDeleted,
+		Aggregation: view.LastValue(),
+		Description: "search documents deleted by the last cleanup run",
+	}
+)
+
+// A SearchDocumentCleanupWorker periodically removes the packages in
+// search_documents that are not at the highest version of their module present
+// in the table.
+type SearchDocumentCleanupWorker struct {
+	db *postgres.DB
+}
+
+// NewSearchDocumentCleanupWorker returns a SearchDocumentCleanupWorker that
+// cleans up search_documents in db.
+func NewSearchDocumentCleanupWorker(db *postgres.DB) *SearchDocumentCleanupWorker {
+	return &SearchDocumentCleanupWorker{db: db}
+}
+
+// Run cleans up search_documents immediately and then once every interval,
+// until ctx is done. A failed cleanup is logged, and retried at the next
+// interval. Run returns ctx.Err().
+func (w *SearchDocumentCleanupWorker) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		w.cleanup(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// cleanup runs a single cleanup and records its metrics.
+func (w *SearchDocumentCleanupWorker) cleanup(ctx context.Context) {
+	n, err := w.db.DeleteOlderVersionsFromSearchDocuments(ctx)
+	status := "ok"
+	if err != nil {
+		log.Errorf(ctx, "SearchDocumentCleanupWorker: %v", err)
+		status = "error"
+	} else {
+		log.Infof(ctx, "SearchDocumentCleanupWorker: deleted %d search documents", n)
+	}
+	stats.RecordWithTags(ctx, []tag.Mutator{
+		tag.Upsert(keyCleanupStatus, status),
+	}, cleanupRuns.M(1))
+	stats.Record(ctx, cleanupDeleted.M(n))
+}
@@ -0,0 +1,72 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal/postgres"
+	"golang.org/x/pkgsite/internal/testing/sample"
+)
+
+func TestSearchDocumentCleanupWorker(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer postgres.ResetTestDB(testDB, t)
+
+	// Package "removed" is only in the older versions of its module, so its
+	// search document stays behind when the newer versions are inserted.
+	for _, m := range []struct {
+		modulePath, version string
+		suffixes            []string
+	}{
+		{"a.com/m", "v1.0.0", []string{"kept", "removed"}},
+		{"a.com/m", "v1.1.0", []string{"kept"}},
+		{"b.com/m", "v1.9.0", []string{"kept", "removed"}},
+		{"b.com/m", "v1.10.0", []string{"kept"}},
+		{"c.com/m", "v1.0.0", []string{"kept"}},
+		// A release is preferred to a later prerelease, whose packages are
+		// deleted.
+		{"d.com/m", "v1.2.0", []string{"kept"}},
+		{"d.com/m", "v1.3.0-pre", []string{"kept", "pre"}},
+	} {
+		if err := testDB.InsertModule(ctx, sample.Module(m.modulePath, m.version, m.suffixes...)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	runCtx, cancelRun := context.WithTimeout(ctx, 500*time.Millisecond)
+	defer cancelRun()
+	if err := NewSearchDocumentCleanupWorker(testDB).Run(runCtx, 50*time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Run: got %v, want context.DeadlineExceeded", err)
+	}
+
+	got := map[string]string{}
+	err := testDB.Underlying().RunQuery(ctx, `SELECT package_path, version FROM search_documents`, func(rows *sql.Rows) error {
+		var path, version string
+		if err := rows.Scan(&path, &version); err != nil {
+			return err
+		}
+		got[path] = version
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"a.com/m/kept": "v1.1.0",
+		"b.com/m/kept": "v1.10.0",
+		"c.com/m/kept": "v1.0.0",
+		"d.com/m/kept": "v1.2.0",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("search_documents mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,294 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opencensus.io/plugin/ochttp"
+	"golang.org/x/net/context/ctxhttp"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/log"
+)
+
+// contributorsClient is the client used to call the APIs of code hosting
+// services.
+var contributorsClient = &http.Client{Transport: &ochttp.Transport{}}
+
+const (
+	// contributorCountTTL is the time after which the contributor count of a
+	// module is fetched again.
+	contributorCountTTL = 7 * 24 * time.Hour
+
+	// maxContributorRetries is the number of times a rate-limited or failed
+	// request to a hosting API is retried. The first retry is after
+	// contributorRetryDelay, and each following one waits twice as long as
+	// the previous one, or longer if the API asks for it, up to
+	// maxContributorRetryDelay.
+	maxContributorRetries    = 5
+	contributorRetryDelay    = time.Second
+	maxContributorRetryDelay = time.Minute
+)
+
+// UpdateContributorCounts fetches and stores the contributor counts of up to
+// limit module versions whose count was never fetched, or was fetched more
+// than contributorCountTTL ago. It returns the number of module versions
+// updated.
+//
+// The versions of a module usually share a repository, whose count is fetched
+// only once. Modules whose count cannot be determined, because their
+// repository is private or not hosted on a supported service, are recorded as
+// such so that they are not retried until their count is stale. When the
+// count of a repository cannot be fetched for another reason, the error is
+// logged, and the attempt is recorded for its modules without changing their
+// counts, so that they do not keep the other modules from being updated.
+func (s *Server) UpdateContributorCounts(ctx context.Context, limit int) (n int, err error) {
+	defer derrors.Wrap(&err, "UpdateContributorCounts(ctx, %d)", limit)
+
+	mods, err := s.db.GetModulesForContributorCount(ctx, time.Now().Add(-contributorCountTTL), limit)
+	if err != nil {
+		return 0, err
+	}
+	counts := map[string]int{}
+	failed := map[string]bool{}
+	for _, mi := range mods {
+		repoURL := mi.SourceInfo.RepoURL()
+		count, ok := counts[repoURL]
+		if !ok && !failed[repoURL] {
+			count, err = s.FetchContributorCount(ctx, repoURL, mi.Version)
+			switch {
+			case err == nil:
+				counts[repoURL] = count
+				ok = true
+			case errors.Is(err, derrors.NotFound):
+				log.Infof(ctx, "no contributor count for %s@%s: %v", mi.ModulePath, mi.Version, err)
+				counts[repoURL] = -1
+				count, ok = -1, true
+			case ctx.Err() != nil:
+				return n, err
+			default:
+				log.Errorf(ctx, "contributor count for %s@%s: %v", mi.ModulePath, mi.Version, err)
+				failed[repoURL] = true
+			}
+		}
+		if ok {
+			err = s.db.UpdateContributorCount(ctx, mi.ModulePath, mi.Version, count)
+		} else {
+			err = s.db.MarkContributorCountFetched(ctx, mi.ModulePath, mi.Version)
+		}
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// FetchContributorCount returns the number of contributors of the repository
+// at repoURL, which holds the given version of a module, using the API of
+// the service hosting it. GitHub and GitLab are supported, at the API URLs of
+// s.cfg, authenticated with the tokens of s.cfg if they are set. The APIs
+// count the contributors of the default branch of the repository, so the
+// count does not depend on version.
+//
+// It returns an error wrapping derrors.NotFound if the repository matches
+// one of s.cfg.PrivateRepos, is not hosted on a supported service, or does
+// not exist.
+func (s *Server) FetchContributorCount(ctx context.Context, repoURL, version string) (int, error) {
+	c := &contributorCounter{
+		client:      contributorsClient,
+		githubURL:   s.cfg.GitHubAPIURL,
+		gitlabURL:   s.cfg.GitLabAPIURL,
+		githubToken: s.cfg.GitHubToken,
+		gitlabToken: s.cfg.GitLabToken,
+		private:     s.cfg.PrivateRepos,
+		retries:     maxContributorRetries,
+		delay:       contributorRetryDelay,
+		maxDelay:    maxContributorRetryDelay,
+	}
+	return c.count(ctx, repoURL, version)
+}
+
+// A contributorCounter counts the contributors of repositories using the
+// APIs of their hosting services.
+type contributorCounter struct {
+	client                   *http.Client
+	githubURL, gitlabURL     string   // base URLs of the APIs
+	githubToken, gitlabToken string   // API tokens; optional
+	private                  []string // glob patterns of private repositories
+	retries                  int      // number of retries of a request
+	delay, maxDelay          time.Duration
+}
+
+// count returns the number of contributors of the repository at repoURL.
+func (c *contributorCounter) count(ctx context.Context, repoURL, version string) (_ int, err error) {
+	defer derrors.Wrap(&err, "count(ctx, %q, %q)", repoURL, version)
+
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return 0, fmt.Errorf("%v: %w", err, derrors.InvalidArgument)
+	}
+	repoPath := strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+	if matchGlobPrefix(c.private, u.Host+"/"+repoPath) {
+		return 0, fmt.Errorf("private repository: %w", derrors.NotFound)
+	}
+	var (
+		apiURL string
+		header http.Header
+	)
+	switch u.Host {
+	case "github.com":
+		// The contributors endpoint lists each contributor on its own
+		// page, so the number of the last page is their number.
+		apiURL = fmt.Sprintf("%s/repos/%s/contributors?per_page=1&anon=true", strings.TrimRight(c.githubURL, "/"), repoPath)
+		if c.githubToken != "" {
+			header = http.Header{"Authorization": {"token " + c.githubToken}}
+		}
+	case "gitlab.com":
+		apiURL = fmt.Sprintf("%s/projects/%s/repository/contributors?per_page=1", strings.TrimRight(c.gitlabURL, "/"), url.PathEscape(repoPath))
+		if c.gitlabToken != "" {
+			header = http.Header{"Private-Token": {c.gitlabToken}}
+		}
+	default:
+		return 0, fmt.Errorf("no API for host %q: %w", u.Host, derrors.NotFound)
+	}
+
+	resp, err := c.get(ctx, apiURL, header)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNoContent:
+		// GitHub responds with 204 No Content for an empty repository.
+		return 0, nil
+	case http.StatusNotFound:
+		return 0, fmt.Errorf("GET %q: %s: %w", apiURL, resp.Status, derrors.NotFound)
+	default:
+		return 0, fmt.Errorf("GET %q: %s", apiURL, resp.Status)
+	}
+	// GitLab reports the number of items; both report the last page.
+	if total := resp.Header.Get("X-Total"); total != "" {
+		return strconv.Atoi(total)
+	}
+	if last, ok := lastPage(resp.Header.Get("Link")); ok {
+		return last, nil
+	}
+	// There is a single page.
+	var contributors []json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&contributors); err != nil {
+		return 0, fmt.Errorf("decoding %q: %v", apiURL, err)
+	}
+	return len(contributors), nil
+}
+
+// get returns the response to a GET request for apiURL with the given
+// header, which may be nil. Requests that are rate limited or fail with a
+// server error are retried with exponential backoff, up to c.retries times.
+func (c *contributorCounter) get(ctx context.Context, apiURL string, header http.Header) (*http.Response, error) {
+	delay := c.delay
+	for retry := 0; ; retry++ {
+		req, err := http.NewRequest(http.MethodGet, apiURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		resp, err := ctxhttp.Do(ctx, c.client, req)
+		if err != nil {
+			return nil, fmt.Errorf("ctxhttp.Do(ctx, client, %q): %v", apiURL, err)
+		}
+		if !rateLimited(resp) && resp.StatusCode < 500 {
+			return resp, nil
+		}
+		resp.Body.Close()
+		if retry == c.retries {
+			return nil, fmt.Errorf("GET %q: %s after %d retries", apiURL, resp.Status, retry)
+		}
+		d := delay
+		if ra, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && time.Duration(ra)*time.Second > d {
+			d = time.Duration(ra) * time.Second
+		}
+		if d > c.maxDelay {
+			d = c.maxDelay
+		}
+		log.Infof(ctx, "GET %q: %s; retrying in %v", apiURL, resp.Status, d)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(d):
+		}
+		delay *= 2
+	}
+}
+
+// rateLimited reports whether resp is the response to a request rejected by
+// the rate limits of a hosting API. GitHub responds with 403 Forbidden when
+// the limit is exhausted.
+func rateLimited(resp *http.Response) bool {
+	return resp.StatusCode == http.StatusTooManyRequests ||
+		(resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0")
+}
+
+var lastLinkRegexp = regexp.MustCompile(`<([^>]*)>;\s*rel="last"`)
+
+// lastPage returns the page number of the link with relation "last" in the
+// Link header value link, as used for pagination by GitHub and GitLab.
+func lastPage(link string) (int, bool) {
+	m := lastLinkRegexp.FindStringSubmatch(link)
+	if m == nil {
+		return 0, false
+	}
+	u, err := url.Parse(m[1])
+	if err != nil {
+		return 0, false
+	}
+	page, err := strconv.Atoi(u.Query().Get("page"))
+	if err != nil {
+		return 0, false
+	}
+	return page, true
+}
+
+// matchGlobPrefix reports whether any of the glob patterns matches a prefix
+// of target, in the manner of the GONOSUMDB and GOPRIVATE environment
+// variables: a pattern with n slashes is matched against the first n+1
+// path elements of target.
+func matchGlobPrefix(patterns []string, target string) bool {
+	for _, p := range patterns {
+		p = strings.TrimSuffix(p, "/")
+		n := strings.Count(p, "/")
+		prefix := target
+		for i := 0; i < len(target); i++ {
+			if target[i] == '/' {
+				if n == 0 {
+					prefix = target[:i]
+					break
+				}
+				n--
+			}
+		}
+		if n > 0 {
+			// target has fewer elements than p.
+			continue
+		}
+		if ok, _ := path.Match(p, prefix); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,221 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/pkgsite/internal/config"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/postgres"
+	"golang.org/x/pkgsite/internal/testing/sample"
+)
+
+// newContributorsAPI returns a server that mimics the contributors endpoints
+// of the GitHub and GitLab APIs, at /github and /gitlab. The first request
+// for the GitHub repository "owner/limited" is rate limited, requests for
+// "owner/authed" need the token "secret", and requests for "owner/broken"
+// fail.
+func newContributorsAPI(t *testing.T) *httptest.Server {
+	limited := true
+	mux := http.NewServeMux()
+	lastPage := func(w http.ResponseWriter, r *http.Request, n int) {
+		w.Header().Set("Link", fmt.Sprintf(`<http://%s%s?per_page=1&page=2>; rel="next", <http://%[1]s%[2]s?per_page=1&page=%d>; rel="last"`, r.Host, r.URL.Path, n))
+		w.Write([]byte(`[{"login": "a"}]`))
+	}
+	mux.HandleFunc("/github/repos/owner/repo/contributors", func(w http.ResponseWriter, r *http.Request) {
+		lastPage(w, r, 42)
+	})
+	mux.HandleFunc("/github/repos/owner/solo/contributors", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"login": "a"}]`))
+	})
+	mux.HandleFunc("/github/repos/owner/limited/contributors", func(w http.ResponseWriter, r *http.Request) {
+		if limited {
+			limited = false
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			http.Error(w, "rate limited", http.StatusForbidden)
+			return
+		}
+		lastPage(w, r, 3)
+	})
+	mux.HandleFunc("/github/repos/owner/empty/contributors", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/github/repos/owner/authed/contributors", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token secret" {
+			http.Error(w, "requires authentication", http.StatusUnauthorized)
+			return
+		}
+		lastPage(w, r, 5)
+	})
+	mux.HandleFunc("/github/repos/owner/broken/contributors", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+	})
+	mux.HandleFunc("/gitlab/projects/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/gitlab/projects/group%2Fproject/repository/contributors" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("X-Total", "7")
+		w.Write([]byte(`[{"name": "a"}]`))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestContributorCount(t *testing.T) {
+	srv := newContributorsAPI(t)
+	c := &contributorCounter{
+		client:      srv.Client(),
+		githubURL:   srv.URL + "/github",
+		gitlabURL:   srv.URL + "/gitlab/",
+		githubToken: "secret",
+		private:     []string{"github.com/corp", "*.internal.example.com"},
+		retries:     2,
+		delay:       time.Millisecond,
+		maxDelay:    10 * time.Millisecond,
+	}
+	ctx := context.Background()
+	for _, test := range []struct {
+		repoURL string
+		want    int
+		wantErr error
+	}{
+		{"https://github.com/owner/repo", 42, nil},
+		{"https://github.com/owner/solo.git", 1, nil},
+		{"https://github.com/owner/limited", 3, nil},
+		{"https://gitlab.com/group/project", 7, nil},
+		{"https://github.com/owner/empty", 0, nil},
+		{"https://github.com/owner/authed", 5, nil},
+		{"https://github.com/owner/missing", 0, derrors.NotFound},
+		{"https://github.com/corp/secret", 0, derrors.NotFound},
+		{"https://git.internal.example.com/team/repo", 0, derrors.NotFound},
+		{"https://go.googlesource.com/tools", 0, derrors.NotFound},
+	} {
+		t.Run(test.repoURL, func(t *testing.T) {
+			got, err := c.count(ctx, test.repoURL, sample.VersionString)
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Fatalf("got error %v, want %v", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("got %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestContributorCountRetriesExhausted(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "slow down", http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	c := &contributorCounter{
+		client:    srv.Client(),
+		githubURL: srv.URL,
+		retries:   2,
+		delay:     time.Millisecond,
+		maxDelay:  10 * time.Millisecond,
+	}
+	if _, err := c.count(context.Background(), "https://github.com/owner/repo", sample.VersionString); err == nil || errors.Is(err, derrors.NotFound) {
+		t.Fatalf("got error %v, want a rate limit error", err)
+	}
+	if want := 3; requests != want {
+		t.Errorf("got %d requests, want %d", requests, want)
+	}
+}
+
+func TestMatchGlobPrefix(t *testing.T) {
+	patterns := []string{"github.com/corp/", "*.corp.example.com", "gitlab.com/a/b"}
+	for _, test := range []struct {
+		target string
+		want   bool
+	}{
+		{"github.com/corp", true},
+		{"github.com/corp/repo", true},
+		{"github.com/corporate/repo", false},
+		{"github.com", false},
+		{"git.corp.example.com/x/y", true},
+		{"corp.example.com/x", false},
+		{"gitlab.com/a/b/c", true},
+		{"gitlab.com/a", false},
+	} {
+		if got := matchGlobPrefix(patterns, test.target); got != test.want {
+			t.Errorf("matchGlobPrefix(%q) = %t, want %t", test.target, got, test.want)
+		}
+	}
+}
+
+func TestUpdateContributorCounts(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer postgres.ResetTestDB(testDB, t)
+
+	// The sample modules are hosted on GitHub, at their module paths.
+	for _, modulePath := range []string{"github.com/owner/repo", "github.com/owner/solo", "github.com/owner/broken", "github.com/corp/secret"} {
+		if err := testDB.InsertModule(ctx, sample.Module(modulePath, sample.VersionString, "")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	srv := newContributorsAPI(t)
+	s := &Server{
+		cfg: &config.Config{
+			GitHubAPIURL: srv.URL + "/github",
+			PrivateRepos: []string{"github.com/corp"},
+		},
+		db: testDB,
+	}
+	n, err := s.UpdateContributorCounts(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The module whose count could not be fetched is updated too, so that
+	// it is not fetched again before the others.
+	if n != 4 {
+		t.Errorf("updated %d modules, want 4", n)
+	}
+	for modulePath, want := range map[string]int{
+		"github.com/owner/repo": 42,
+		"github.com/owner/solo": 1,
+	} {
+		got, err := testDB.GetContributorCount(ctx, modulePath, sample.VersionString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("GetContributorCount(%q) = %d, want %d", modulePath, got, want)
+		}
+	}
+	for _, modulePath := range []string{"github.com/owner/broken", "github.com/corp/secret"} {
+		if _, err := testDB.GetContributorCount(ctx, modulePath, sample.VersionString); !errors.Is(err, derrors.NotFound) {
+			t.Errorf("GetContributorCount(%q): got error %v, want NotFound", modulePath, err)
+		}
+	}
+
+	// The counts were just fetched, so they are not fetched again.
+	n, err = s.UpdateContributorCounts(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Errorf("updated %d modules on the second run, want 0", n)
+	}
+}
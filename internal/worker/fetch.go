@@ -6,6 +6,7 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"sort"
@@ -15,6 +16,7 @@ import (
 	"go.opencensus.io/trace"
 	"golang.org/x/mod/semver"
 	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/database"
 	"golang.org/x/pkgsite/internal/derrors"
 	"golang.org/x/pkgsite/internal/experiment"
 	"golang.org/x/pkgsite/internal/fetch"
@@ -30,6 +32,14 @@ const (
 	hasIncompletePackagesDesc = "incomplete packages"
 )
 
+// insertModuleMaxAttempts and insertModuleRetryBase configure the retries of
+// InsertModule after transient database errors (see
+// database.RetryWithBackoff).
+var (
+	insertModuleMaxAttempts = 3
+	insertModuleRetryBase   = time.Second
+)
+
 // ProxyRemoved is a set of module@version that have been removed from the proxy,
 // even though they are still in the index.
 var ProxyRemoved = map[string]bool{}
@@ -57,6 +67,12 @@ func FetchAndUpdateState(ctx context.Context, modulePath, requestedVersion strin
 
 	ft := fetchAndInsertModule(ctx, modulePath, requestedVersion, proxyClient, sourceClient, db)
 	span.AddAttributes(trace.Int64Attribute("numPackages", int64(len(ft.PackageVersionStates))))
+	if errors.Is(ft.Error, derrors.ModuleLocked) {
+		// Another worker is inserting the module, and will record the
+		// result. Leave the state of the module alone.
+		log.Infof(ctx, "%s@%s is locked by another worker; skipping", modulePath, requestedVersion)
+		return ft.Status, ft.Error
+	}
 	dbErr := updateVersionMapAndDeleteModulesWithErrors(ctx, db, ft)
 	if dbErr != nil {
 		log.Error(ctx, dbErr)
@@ -147,7 +163,9 @@ func fetchAndInsertModule(ctx context.Context, modulePath, requestedVersion stri
 	log.Infof(ctx, "fetch.FetchVersion succeeded for %s@%s", ft.ModulePath, ft.RequestedVersion)
 
 	start = time.Now()
-	err = db.InsertModule(ctx, ft.Module)
+	err = database.RetryWithBackoff(ctx, insertModuleMaxAttempts, insertModuleRetryBase, func() error {
+		return db.InsertModule(ctx, ft.Module)
+	})
 	ft.timings["db.InsertModule"] = time.Since(start)
 	if err != nil {
 		log.Error(ctx, err)
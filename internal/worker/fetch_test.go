@@ -142,13 +142,23 @@ func TestFetchAndUpdateState_NotFound(t *testing.T) {
 		t.Errorf("testDB.GetPackageVersionStatesForModule(ctx, %q, %q) mismatch (-want +got):\n%s", modulePath, version, diff)
 	}
 
-	// The module should no longer be in the database:
-	// - It shouldn't be in the modules table. That also covers licenses, packages and paths tables
-	//   via foreign key constraints with ON DELETE CASCADE.
-	// - It shouldn't be in other tables like search_documents and the various imports tables.
+	// The module should no longer be served:
+	// - Its row in the modules table should be marked deleted. The rows of
+	//   other tables that refer to it are kept.
+	// - It shouldn't be in imports_unique, which only holds modules with
+	//   versions that are not deleted.
 	if _, err := testDB.LegacyGetModuleInfo(ctx, modulePath, version); !errors.Is(err, derrors.NotFound) {
 		t.Fatalf("LegacyGetModuleInfo: got %v, want NotFound", err)
 	}
+	var deleted bool
+	if err := testDB.Underlying().QueryRow(ctx,
+		`SELECT deleted_at IS NOT NULL FROM modules WHERE module_path = $1 AND version = $2`,
+		modulePath, version).Scan(&deleted); err != nil {
+		t.Fatal(err)
+	}
+	if !deleted {
+		t.Error("modules row is not marked deleted")
+	}
 
 	checkNotInTable := func(table, column string) {
 		q := fmt.Sprintf("SELECT 1 FROM %s WHERE %s = $1 LIMIT 1", table, column)
@@ -159,9 +169,7 @@ func TestFetchAndUpdateState_NotFound(t *testing.T) {
 		}
 	}
 
-	checkNotInTable("search_documents", "module_path")
 	checkNotInTable("imports_unique", "from_module_path")
-	checkNotInTable("imports", "from_module_path")
 }
 
 func TestFetchAndUpdateState_Excluded(t *testing.T) {
@@ -660,7 +668,7 @@ func TestReFetch(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(internal.LegacyPackage{}, "DocumentationHTML"), cmp.AllowUnexported(source.Info{})); diff != "" {
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(internal.LegacyPackage{}, "DocumentationHTML", "Facts"), cmp.AllowUnexported(source.Info{})); diff != "" {
 		t.Errorf("testDB.LegacyGetPackage(ctx, %q, %q) mismatch (-want +got):\n%s", pkgBar, version, diff)
 	}
 
@@ -1069,7 +1077,7 @@ func TestFetchAndInsertModule(t *testing.T) {
 			sort.Slice(gotPkg.Licenses, func(i, j int) bool {
 				return gotPkg.Licenses[i].FilePath < gotPkg.Licenses[j].FilePath
 			})
-			if diff := cmp.Diff(test.want, gotPkg, cmpopts.IgnoreFields(internal.LegacyPackage{}, "DocumentationHTML"), cmp.AllowUnexported(source.Info{})); diff != "" {
+			if diff := cmp.Diff(test.want, gotPkg, cmpopts.IgnoreFields(internal.LegacyPackage{}, "DocumentationHTML", "Facts"), cmp.AllowUnexported(source.Info{})); diff != "" {
 				t.Errorf("testDB.LegacyGetPackage(ctx, %q, %q) mismatch (-want +got):\n%s", test.pkg, test.version, diff)
 			}
 			if got, want := gotPkg.DocumentationHTML, test.want.DocumentationHTML; len(want) == 0 && len(got) != 0 {
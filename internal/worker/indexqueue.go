@@ -0,0 +1,108 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package worker
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/log"
+	"golang.org/x/pkgsite/internal/postgres"
+	"golang.org/x/pkgsite/internal/proxy"
+	"golang.org/x/pkgsite/internal/source"
+	"golang.org/x/pkgsite/internal/workerpool"
+	"golang.org/x/pkgsite/internal/xcontext"
+)
+
+// An IndexQueueWorker fetches the module versions in module_index_queue, in
+// order of priority, several at a time.
+type IndexQueueWorker struct {
+	db              *postgres.DB
+	proxyClient     *proxy.Client
+	sourceClient    *source.Client
+	appVersionLabel string
+	pool            *workerpool.WorkerPool
+}
+
+// NewIndexQueueWorker returns an IndexQueueWorker that fetches up to
+// concurrency of the module versions enqueued in db at once, and records them
+// in db.
+func NewIndexQueueWorker(db *postgres.DB, proxyClient *proxy.Client, sourceClient *source.Client, appVersionLabel string, concurrency int) *IndexQueueWorker {
+	w := &IndexQueueWorker{
+		db:              db,
+		proxyClient:     proxyClient,
+		sourceClient:    sourceClient,
+		appVersionLabel: appVersionLabel,
+	}
+	w.pool = workerpool.New(concurrency, w.fetch, w.requeue)
+	return w
+}
+
+// Run dequeues and fetches module versions until ctx is done. When the queue
+// is empty, or dequeueing fails, it waits for pollInterval before trying
+// again. When ctx is done, the fetches in progress are finished before Run
+// returns ctx.Err().
+func (w *IndexQueueWorker) Run(ctx context.Context, pollInterval time.Duration) error {
+	items := make(chan interface{})
+	go w.dequeue(ctx, items, pollInterval)
+	return w.pool.Run(ctx, items)
+}
+
+// Stats returns the counts of the module versions fetched by w.
+func (w *IndexQueueWorker) Stats() workerpool.PoolStats {
+	return w.pool.Stats()
+}
+
+// dequeue sends the module versions dequeued from module_index_queue on
+// items, as *postgres.QueueItems, until ctx is done.
+func (w *IndexQueueWorker) dequeue(ctx context.Context, items chan<- interface{}, pollInterval time.Duration) {
+	for {
+		item, err := w.db.DequeueModule(ctx)
+		if err != nil {
+			if !errors.Is(err, derrors.NotFound) && ctx.Err() == nil {
+				log.Errorf(ctx, "IndexQueueWorker: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(pollInterval):
+			}
+			continue
+		}
+		select {
+		case items <- item:
+		case <-ctx.Done():
+			w.requeue(xcontext.Detach(ctx), item)
+			return
+		}
+	}
+}
+
+// requeue puts the module version of item, a *postgres.QueueItem that was
+// dequeued but not fetched, back in module_index_queue, so that it is not
+// lost.
+func (w *IndexQueueWorker) requeue(ctx context.Context, item interface{}) {
+	qi := item.(*postgres.QueueItem)
+	if err := w.db.EnqueueModule(ctx, qi.ModulePath, qi.Version, qi.Priority); err != nil {
+		log.Errorf(ctx, "IndexQueueWorker: %v", err)
+	}
+}
+
+// fetch fetches the module version of item, a *postgres.QueueItem. Fetch
+// errors are recorded in module_version_states by FetchAndUpdateState, and
+// returned to be counted.
+func (w *IndexQueueWorker) fetch(ctx context.Context, i interface{}) error {
+	item := i.(*postgres.QueueItem)
+	code, err := FetchAndUpdateState(ctx, item.ModulePath, item.Version, w.proxyClient, w.sourceClient, w.db, w.appVersionLabel)
+	if err != nil {
+		log.Infof(ctx, "IndexQueueWorker: fetched %s@%s (priority %d): status=%d, err=%v",
+			item.ModulePath, item.Version, item.Priority, code, err)
+		return err
+	}
+	log.Infof(ctx, "IndexQueueWorker: fetched %s@%s (priority %d)", item.ModulePath, item.Version, item.Priority)
+	return nil
+}
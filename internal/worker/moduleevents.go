@@ -0,0 +1,59 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package worker
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/pkgsite/internal/log"
+	"golang.org/x/pkgsite/internal/postgres"
+)
+
+var (
+	// moduleEventsQuietInterval is how long RunModuleEvents waits for an
+	// event before it falls back to polling module_version_states.
+	moduleEventsQuietInterval = 30 * time.Second
+	// moduleEventsRequeueLimit is the number of module versions that
+	// RunModuleEvents requeues each time it polls.
+	moduleEventsRequeueLimit = 10
+)
+
+// RunModuleEvents schedules the module versions of events, as returned by
+// postgres.ListenForModuleEvents, to be fetched as soon as they arrive. Since
+// events can be lost, whenever no event has arrived for
+// moduleEventsQuietInterval it falls back to polling: it requeues the next
+// module versions to fetch, like the /requeue handler, and does so again
+// every moduleEventsQuietInterval until an event arrives. If events is
+// closed, RunModuleEvents keeps polling. It returns ctx.Err() when ctx is
+// done.
+func (s *Server) RunModuleEvents(ctx context.Context, events <-chan postgres.ModuleEvent) error {
+	timer := time.NewTimer(moduleEventsQuietInterval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case e, ok := <-events:
+			if !ok {
+				log.Infof(ctx, "RunModuleEvents: events channel closed; polling only")
+				// A nil channel blocks forever, leaving only the timer.
+				events = nil
+				continue
+			}
+			if err := s.queue.ScheduleFetch(ctx, e.ModulePath, e.Version, "", s.taskIDChangeInterval); err != nil {
+				log.Errorf(ctx, "RunModuleEvents: %v", err)
+			}
+			if !timer.Stop() {
+				<-timer.C
+			}
+		case <-timer.C:
+			if _, err := s.requeue(ctx, moduleEventsRequeueLimit, ""); err != nil {
+				log.Errorf(ctx, "RunModuleEvents: %v", err)
+			}
+		}
+		timer.Reset(moduleEventsQuietInterval)
+	}
+}
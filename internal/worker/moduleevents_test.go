@@ -0,0 +1,92 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/postgres"
+)
+
+// recordingQueue is a queue.Queue that records the module versions it is
+// asked to fetch.
+type recordingQueue struct {
+	mu        sync.Mutex
+	scheduled []string
+}
+
+func (q *recordingQueue) ScheduleFetch(ctx context.Context, modulePath, version, suffix string, taskIDChangeInterval time.Duration) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.scheduled = append(q.scheduled, modulePath+"@"+version)
+	return nil
+}
+
+func (q *recordingQueue) get() []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]string(nil), q.scheduled...)
+}
+
+func TestRunModuleEvents(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	defer postgres.ResetTestDB(testDB, t)
+
+	defer func(d time.Duration) { moduleEventsQuietInterval = d }(moduleEventsQuietInterval)
+	moduleEventsQuietInterval = 200 * time.Millisecond
+
+	// A module version that only polling finds.
+	if err := testDB.InsertIndexVersions(ctx, []*internal.IndexVersion{
+		{Path: "example.com/polled", Version: "v1.0.0", Timestamp: time.Now()},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	q := &recordingQueue{}
+	s := &Server{db: testDB, queue: q}
+	events := make(chan postgres.ModuleEvent)
+	runCtx, stop := context.WithCancel(ctx)
+	done := make(chan error)
+	go func() { done <- s.RunModuleEvents(runCtx, events) }()
+
+	// Events are scheduled as they arrive, without polling.
+	events <- postgres.ModuleEvent{ModulePath: "example.com/pushed", Version: "v1.0.0"}
+	events <- postgres.ModuleEvent{ModulePath: "example.com/pushed", Version: "v1.1.0"}
+	// waitFor waits until the first module versions scheduled are want. Polling
+	// may schedule the same module version again later.
+	waitFor := func(want []string) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			got := q.get()
+			if len(got) > len(want) {
+				got = got[:len(want)]
+			}
+			if cmp.Equal(want, got) {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("scheduled mismatch (-want +got):\n%s", cmp.Diff(want, got))
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitFor([]string{"example.com/pushed@v1.0.0", "example.com/pushed@v1.1.0"})
+
+	// Once the channel has been quiet, the module versions to fetch are polled.
+	waitFor([]string{"example.com/pushed@v1.0.0", "example.com/pushed@v1.1.0", "example.com/polled@v1.0.0"})
+
+	stop()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("RunModuleEvents: got %v, want context.Canceled", err)
+	}
+}
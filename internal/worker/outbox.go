@@ -0,0 +1,112 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.opencensus.io/plugin/ochttp"
+	"golang.org/x/net/context/ctxhttp"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/log"
+	"golang.org/x/pkgsite/internal/postgres"
+)
+
+const (
+	// publishedModuleEventRetention is how long published module events are
+	// kept in the module_events outbox.
+	publishedModuleEventRetention = 24 * time.Hour
+	// moduleEventRetention is how long module events are kept in the
+	// module_events outbox if they could not be published.
+	moduleEventRetention = 30 * 24 * time.Hour
+)
+
+// An HTTPEventSink is a postgres.EventSink that delivers each module event
+// in a POST request to a URL, with the JSON encoding of the event as body.
+type HTTPEventSink struct {
+	client *http.Client
+	url    string
+}
+
+// NewHTTPEventSink returns an HTTPEventSink that delivers events to url.
+func NewHTTPEventSink(url string) *HTTPEventSink {
+	return &HTTPEventSink{client: &http.Client{Transport: &ochttp.Transport{}}, url: url}
+}
+
+// Publish implements postgres.EventSink. The event is delivered if the
+// response has a 2xx status.
+func (s *HTTPEventSink) Publish(ctx context.Context, event postgres.ModuleEvent) (err error) {
+	defer derrors.Wrap(&err, "HTTPEventSink.Publish(ctx, %+v)", event)
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	resp, err := ctxhttp.Post(ctx, s.client, s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("POST %q: %s", s.url, resp.Status)
+	}
+	return nil
+}
+
+// A ModuleEventPublisher periodically publishes the pending events of the
+// module_events outbox, and prunes the outbox.
+type ModuleEventPublisher struct {
+	db   *postgres.DB
+	sink postgres.EventSink
+}
+
+// NewModuleEventPublisher returns a ModuleEventPublisher that publishes the
+// events of db to sink. If sink is nil, the events are not published, and
+// are only pruned.
+func NewModuleEventPublisher(db *postgres.DB, sink postgres.EventSink) *ModuleEventPublisher {
+	return &ModuleEventPublisher{db: db, sink: sink}
+}
+
+// Run publishes and prunes the events immediately and then once every
+// interval, until ctx is done. Errors are logged, and the events that could
+// not be published are retried at the next interval. Run returns ctx.Err().
+func (p *ModuleEventPublisher) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		p.publish(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// publish publishes the pending events and prunes the outbox once.
+func (p *ModuleEventPublisher) publish(ctx context.Context) {
+	if p.sink != nil {
+		n, err := postgres.PublishPendingEvents(ctx, p.db, p.sink)
+		if err != nil {
+			log.Errorf(ctx, "ModuleEventPublisher: published %d events: %v", n, err)
+		} else if n > 0 {
+			log.Infof(ctx, "ModuleEventPublisher: published %d events", n)
+		}
+	}
+	now := time.Now()
+	n, err := postgres.PruneModuleEvents(ctx, p.db, now.Add(-publishedModuleEventRetention), now.Add(-moduleEventRetention))
+	if err != nil {
+		log.Errorf(ctx, "ModuleEventPublisher: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Infof(ctx, "ModuleEventPublisher: pruned %d events", n)
+	}
+}
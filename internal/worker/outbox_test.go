@@ -0,0 +1,52 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal/postgres"
+)
+
+func TestHTTPEventSink(t *testing.T) {
+	var got []postgres.ModuleEvent
+	fail := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if fail {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		var e postgres.ModuleEvent
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		got = append(got, e)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	sink := NewHTTPEventSink(srv.URL)
+	event := postgres.ModuleEvent{Type: postgres.ModuleEventIndexed, ModulePath: "example.com/m", Version: "v1.0.0"}
+	if err := sink.Publish(ctx, event); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]postgres.ModuleEvent{event}, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	fail = true
+	if err := sink.Publish(ctx, event); err == nil {
+		t.Error("got no error from a failing server, want one")
+	}
+}
@@ -47,6 +47,7 @@ type Server struct {
 	queue                queue.Queue
 	reportingClient      *errorreporting.Client
 	taskIDChangeInterval time.Duration
+	incremental          bool
 
 	indexTemplate *template.Template
 }
@@ -63,6 +64,10 @@ type ServerConfig struct {
 	ReportingClient      *errorreporting.Client
 	TaskIDChangeInterval time.Duration
 	StaticPath           string
+	// Incremental reports whether imported-by counts are updated
+	// incrementally, using
+	// postgres.DB.UpdateSearchDocumentsImportedByCountIncremental.
+	Incremental bool
 }
 
 // NewServer creates a new Server with the given dependencies.
@@ -86,6 +91,7 @@ func NewServer(cfg *config.Config, scfg ServerConfig) (_ *Server, err error) {
 		reportingClient:      scfg.ReportingClient,
 		indexTemplate:        indexTemplate,
 		taskIDChangeInterval: scfg.TaskIDChangeInterval,
+		incremental:          scfg.Incremental,
 	}, nil
 }
 
@@ -112,6 +118,24 @@ func (s *Server) Install(handle func(string, http.Handler)) {
 	// This endpoint is invoked by a Cloud Scheduler job.
 	handle("/update-imported-by-count", rmw(s.errorHandler(s.handleUpdateImportedByCount)))
 
+	// cloud-scheduler: compute-trending-stats records the imported_by_count of
+	// every package in search_documents, and recomputes the growth of those
+	// counts shown on the frontend's trending page. The "period" query
+	// parameter restricts the computation to one period, in days.
+	// This endpoint should be invoked daily by a Cloud Scheduler job.
+	handle("/compute-trending-stats", rmw(s.errorHandler(s.handleComputeTrendingStats)))
+
+	// cloud-scheduler: fetch-vulnerabilities downloads the Go vulnerability
+	// database and updates the vuln_reports table, which the frontend uses to
+	// warn about vulnerable packages and modules.
+	handle("/fetch-vulnerabilities", rmw(s.errorHandler(s.handleFetchVulnerabilities)))
+
+	// cloud-scheduler: update-contributor-counts fetches the number of
+	// contributors of the repositories of modules from the APIs of their
+	// hosting services, for at most "limit" module versions whose count is
+	// missing or stale.
+	handle("/update-contributor-counts", rmw(s.errorHandler(s.handleUpdateContributorCounts)))
+
 	// cloud-scheduler: download search document data and update the redis sorted
 	// set(s) used in auto-completion.
 	handle("/update-redis-indexes", rmw(s.errorHandler(s.handleUpdateRedisIndexes)))
@@ -159,9 +183,14 @@ func (s *Server) Install(handle func(string, http.Handler)) {
 	handle("/", http.HandlerFunc(s.handleStatusPage))
 }
 
-// handleUpdateImportedByCount updates imported_by_count for all packages.
+// handleUpdateImportedByCount updates imported_by_count for all packages, or
+// only for those whose importers have changed if s.incremental is set.
 func (s *Server) handleUpdateImportedByCount(w http.ResponseWriter, r *http.Request) error {
-	n, err := s.db.UpdateSearchDocumentsImportedByCount(r.Context())
+	update := s.db.UpdateSearchDocumentsImportedByCount
+	if s.incremental {
+		update = s.db.UpdateSearchDocumentsImportedByCountIncremental
+	}
+	n, err := update(r.Context())
 	if err != nil {
 		return err
 	}
@@ -169,6 +198,55 @@ func (s *Server) handleUpdateImportedByCount(w http.ResponseWriter, r *http.Requ
 	return nil
 }
 
+// handleComputeTrendingStats computes the trending stats for the period in
+// the "period" query parameter, or for all of postgres.TrendingPeriods if it
+// is empty.
+func (s *Server) handleComputeTrendingStats(w http.ResponseWriter, r *http.Request) error {
+	periods := postgres.TrendingPeriods
+	if p := r.FormValue("period"); p != "" {
+		period, err := strconv.Atoi(p)
+		if err != nil {
+			return &serverError{http.StatusBadRequest, fmt.Errorf("invalid period %q: %v", p, err)}
+		}
+		periods = []int{period}
+	}
+	for _, period := range periods {
+		if err := s.db.ComputeTrendingStats(r.Context(), period); err != nil {
+			if errors.Is(err, derrors.InvalidArgument) {
+				return &serverError{http.StatusBadRequest, err}
+			}
+			return err
+		}
+	}
+	fmt.Fprintf(w, "computed trending stats for periods %v", periods)
+	return nil
+}
+
+// handleFetchVulnerabilities updates the vuln_reports table from the
+// vulnerability database at s.cfg.VulnDBURL.
+func (s *Server) handleFetchVulnerabilities(w http.ResponseWriter, r *http.Request) error {
+	if err := s.FetchVulnerabilities(r.Context(), s.cfg.VulnDBURL); err != nil {
+		return err
+	}
+	fmt.Fprint(w, "fetched vulnerabilities")
+	return nil
+}
+
+// handleUpdateContributorCounts updates the contributor counts of the
+// modules whose counts are missing or stale.
+func (s *Server) handleUpdateContributorCounts(w http.ResponseWriter, r *http.Request) error {
+	limit := parseIntParam(r, "limit", 100)
+	n, err := s.UpdateContributorCounts(r.Context(), limit)
+	if err != nil {
+		if errors.Is(err, derrors.InvalidArgument) {
+			return &serverError{http.StatusBadRequest, err}
+		}
+		return err
+	}
+	fmt.Fprintf(w, "updated contributor counts of %d modules", n)
+	return nil
+}
+
 // handleRepopulateSearchDocuments repopulates every row in the search_documents table
 // that was last updated before the given time.
 func (s *Server) handleRepopulateSearchDocuments(w http.ResponseWriter, r *http.Request) error {
@@ -310,22 +388,32 @@ func (s *Server) handleRequeue(w http.ResponseWriter, r *http.Request) (err erro
 	suffixParam := r.FormValue("suffix") // append to task name to avoid deduplication
 	span := trace.FromContext(r.Context())
 	span.Annotate([]trace.Attribute{trace.Int64Attribute("limit", int64(limit))}, "processed limit")
-	versions, err := s.db.GetNextModulesToFetch(ctx, limit)
+	n, err := s.requeue(ctx, limit, suffixParam)
 	if err != nil {
 		return err
 	}
 
-	span.Annotate([]trace.Attribute{trace.Int64Attribute("versions to fetch", int64(len(versions)))}, "processed limit")
+	span.Annotate([]trace.Attribute{trace.Int64Attribute("versions to fetch", int64(n))}, "processed limit")
 	w.Header().Set("Content-Type", "text/plain")
+	return nil
+}
+
+// requeue enqueues the next batch of at most limit module versions to
+// process, and returns how many it enqueued. suffix is appended to the task
+// names to avoid deduplication.
+func (s *Server) requeue(ctx context.Context, limit int, suffix string) (int, error) {
+	versions, err := s.db.GetNextModulesToFetch(ctx, limit)
+	if err != nil {
+		return 0, err
+	}
 	log.Infof(ctx, "Scheduling modules to be fetched: requeuing %d modules", len(versions))
 	for _, v := range versions {
-		if err := s.queue.ScheduleFetch(ctx, v.ModulePath, v.Version, suffixParam, s.taskIDChangeInterval); err != nil {
-			return err
+		if err := s.queue.ScheduleFetch(ctx, v.ModulePath, v.Version, suffix, s.taskIDChangeInterval); err != nil {
+			return 0, err
 		}
 	}
 	log.Infof(ctx, "Successfully scheduled modules to be fetched: %d modules requeued", len(versions))
-
-	return nil
+	return len(versions), nil
 }
 
 // handleStatusPage serves the worker status page.
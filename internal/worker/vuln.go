@@ -0,0 +1,182 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opencensus.io/plugin/ochttp"
+	"golang.org/x/mod/semver"
+	"golang.org/x/net/context/ctxhttp"
+	"golang.org/x/pkgsite/internal"
+	"golang.org/x/pkgsite/internal/derrors"
+	"golang.org/x/pkgsite/internal/log"
+	"golang.org/x/pkgsite/internal/stdlib"
+)
+
+// vulndbClient is the client used to download the vulnerability database.
+var vulndbClient = &http.Client{Transport: &ochttp.Transport{}}
+
+// FetchVulnerabilities downloads the reports in the Go vulnerability database
+// at vulndbURL and upserts them into the vuln_reports table.
+func (s *Server) FetchVulnerabilities(ctx context.Context, vulndbURL string) (err error) {
+	defer derrors.Wrap(&err, "FetchVulnerabilities(ctx, %q)", vulndbURL)
+
+	reports, err := fetchVulnReports(ctx, vulndbClient, vulndbURL)
+	if err != nil {
+		return err
+	}
+	log.Infof(ctx, "FetchVulnerabilities: upserting %d reports", len(reports))
+	return s.db.UpsertVulnReports(ctx, reports)
+}
+
+// fetchVulnReports returns the reports of all the vulnerabilities in the
+// database at vulndbURL. The database is laid out as on
+// https://vuln.go.dev: index/modules.json lists the IDs of the
+// vulnerabilities of each module, and ID/<id>.json is the OSV entry for a
+// vulnerability.
+func fetchVulnReports(ctx context.Context, client *http.Client, vulndbURL string) (_ []*internal.VulnReport, err error) {
+	defer derrors.Wrap(&err, "fetchVulnReports(ctx, client, %q)", vulndbURL)
+
+	vulndbURL = strings.TrimRight(vulndbURL, "/")
+	var modules []struct {
+		Path  string `json:"path"`
+		Vulns []struct {
+			ID string `json:"id"`
+		} `json:"vulns"`
+	}
+	if err := getJSON(ctx, client, vulndbURL+"/index/modules.json", &modules); err != nil {
+		return nil, err
+	}
+	var (
+		reports []*internal.VulnReport
+		seen    = map[string]bool{}
+	)
+	for _, m := range modules {
+		for _, v := range m.Vulns {
+			if seen[v.ID] {
+				continue
+			}
+			seen[v.ID] = true
+			var entry osvEntry
+			if err := getJSON(ctx, client, fmt.Sprintf("%s/ID/%s.json", vulndbURL, v.ID), &entry); err != nil {
+				return nil, err
+			}
+			reports = append(reports, entry.reports()...)
+		}
+	}
+	return reports, nil
+}
+
+// getJSON decodes the JSON document at url into v.
+func getJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	resp, err := ctxhttp.Get(ctx, client, url)
+	if err != nil {
+		return fmt.Errorf("ctxhttp.Get(ctx, client, %q): %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %q: %s", url, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decoding %q: %v", url, err)
+	}
+	return nil
+}
+
+// osvEntry is the part of a vulnerability in the Open Source Vulnerability
+// format (https://ossf.github.io/osv-schema) that is stored in vuln_reports.
+type osvEntry struct {
+	ID        string    `json:"id"`
+	Published time.Time `json:"published"`
+	Summary   string    `json:"summary"`
+	Details   string    `json:"details"`
+	Affected  []struct {
+		Package struct {
+			Name string `json:"name"`
+		} `json:"package"`
+		Ranges []struct {
+			Type   string `json:"type"`
+			Events []struct {
+				Introduced string `json:"introduced"`
+				Fixed      string `json:"fixed"`
+			} `json:"events"`
+		} `json:"ranges"`
+	} `json:"affected"`
+}
+
+// reports returns a report for each range of versions of each module affected
+// by e.
+func (e *osvEntry) reports() []*internal.VulnReport {
+	desc := e.Summary
+	if desc == "" {
+		desc = e.Details
+	}
+	var reports []*internal.VulnReport
+	add := func(modulePath string, constraints []string) {
+		reports = append(reports, &internal.VulnReport{
+			ID:                   e.ID,
+			ModulePath:           modulePath,
+			AffectedVersionRange: strings.Join(constraints, ", "),
+			Description:          desc,
+			PublishedAt:          e.Published,
+		})
+	}
+	for _, a := range e.Affected {
+		modulePath := a.Package.Name
+		if modulePath == "stdlib" {
+			modulePath = stdlib.ModulePath
+		}
+		for _, r := range a.Ranges {
+			if r.Type != "SEMVER" {
+				continue
+			}
+			// The events alternate between the introduction of the
+			// vulnerability and its fix. Each introduction starts a range,
+			// which is ended by the following fix, if any.
+			var constraints []string
+			open := false
+			for _, ev := range r.Events {
+				switch {
+				case ev.Introduced != "":
+					constraints = nil
+					if v := osvVersion(ev.Introduced); v != "" {
+						constraints = []string{">=" + v}
+					}
+					open = true
+				case ev.Fixed != "" && open:
+					if v := osvVersion(ev.Fixed); v != "" {
+						constraints = append(constraints, "<"+v)
+					}
+					add(modulePath, constraints)
+					open = false
+				}
+			}
+			if open {
+				add(modulePath, constraints)
+			}
+		}
+	}
+	return reports
+}
+
+// osvVersion converts a SEMVER version of an OSV entry, which has no "v"
+// prefix, to a Go module version. It returns the empty string for the
+// version "0", which precedes all others, and for invalid versions.
+func osvVersion(v string) string {
+	if v == "0" {
+		return ""
+	}
+	v = "v" + v
+	if !semver.IsValid(v) {
+		return ""
+	}
+	return v
+}
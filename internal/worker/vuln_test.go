@@ -0,0 +1,96 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package worker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/pkgsite/internal"
+)
+
+func TestFetchVulnReports(t *testing.T) {
+	files := map[string]string{
+		"/index/modules.json": `[
+			{"path": "a.com/m", "vulns": [{"id": "GO-2020-0001"}, {"id": "GO-2020-0002"}]},
+			{"path": "b.com/m", "vulns": [{"id": "GO-2020-0001"}]},
+			{"path": "stdlib", "vulns": [{"id": "GO-2020-0003"}]}
+		]`,
+		"/ID/GO-2020-0001.json": `{
+			"id": "GO-2020-0001",
+			"published": "2020-06-01T00:00:00Z",
+			"summary": "Injection in a.com/m and b.com/m.",
+			"details": "More details.",
+			"affected": [
+				{
+					"package": {"name": "a.com/m", "ecosystem": "Go"},
+					"ranges": [{"type": "SEMVER", "events": [
+						{"introduced": "0"}, {"fixed": "1.2.0"},
+						{"introduced": "1.5.0"}, {"fixed": "1.5.2"}
+					]}]
+				},
+				{
+					"package": {"name": "b.com/m", "ecosystem": "Go"},
+					"ranges": [{"type": "SEMVER", "events": [{"introduced": "2.0.0"}]}]
+				}
+			]
+		}`,
+		"/ID/GO-2020-0002.json": `{
+			"id": "GO-2020-0002",
+			"published": "2020-07-01T00:00:00Z",
+			"details": "Only details.",
+			"affected": [{
+				"package": {"name": "a.com/m", "ecosystem": "Go"},
+				"ranges": [
+					{"type": "GIT", "events": [{"introduced": "0"}, {"fixed": "abcdef"}]},
+					{"type": "SEMVER", "events": [{"introduced": "0"}]}
+				]
+			}]
+		}`,
+		"/ID/GO-2020-0003.json": `{
+			"id": "GO-2020-0003",
+			"published": "2020-08-01T00:00:00Z",
+			"summary": "Panic in net/http.",
+			"affected": [{
+				"package": {"name": "stdlib", "ecosystem": "Go"},
+				"ranges": [{"type": "SEMVER", "events": [{"introduced": "1.14.0"}, {"fixed": "1.14.6"}]}]
+			}]
+		}`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(f))
+	}))
+	defer srv.Close()
+
+	got, err := fetchVulnReports(context.Background(), srv.Client(), srv.URL+"/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	date := func(month time.Month) time.Time { return time.Date(2020, month, 1, 0, 0, 0, 0, time.UTC) }
+	want := []*internal.VulnReport{
+		{ID: "GO-2020-0001", ModulePath: "a.com/m", AffectedVersionRange: "<v1.2.0", Description: "Injection in a.com/m and b.com/m.", PublishedAt: date(6)},
+		{ID: "GO-2020-0001", ModulePath: "a.com/m", AffectedVersionRange: ">=v1.5.0, <v1.5.2", Description: "Injection in a.com/m and b.com/m.", PublishedAt: date(6)},
+		{ID: "GO-2020-0001", ModulePath: "b.com/m", AffectedVersionRange: ">=v2.0.0", Description: "Injection in a.com/m and b.com/m.", PublishedAt: date(6)},
+		{ID: "GO-2020-0002", ModulePath: "a.com/m", AffectedVersionRange: "", Description: "Only details.", PublishedAt: date(7)},
+		{ID: "GO-2020-0003", ModulePath: "std", AffectedVersionRange: ">=v1.14.0, <v1.14.6", Description: "Panic in net/http.", PublishedAt: date(8)},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("fetchVulnReports mismatch (-want +got):\n%s", diff)
+	}
+
+	delete(files, "/ID/GO-2020-0002.json")
+	if _, err := fetchVulnReports(context.Background(), srv.Client(), srv.URL); err == nil {
+		t.Error("got nil error for missing entry, want error")
+	}
+}
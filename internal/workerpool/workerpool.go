@@ -0,0 +1,137 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package workerpool processes the items of a queue, such as module versions
+// to fetch, concurrently, with a bounded number of workers.
+package workerpool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/pkgsite/internal/xcontext"
+	"golang.org/x/sync/errgroup"
+)
+
+// A WorkerPool calls a work function on the items it receives, with at most
+// a fixed number of calls running at once. Items can be of any type, which
+// the work function knows.
+type WorkerPool struct {
+	concurrency int
+	work        func(ctx context.Context, item interface{}) error
+	requeue     func(ctx context.Context, item interface{})
+
+	// Counters reported by Stats. They are updated atomically.
+	active, waiting, completed, errored int64
+
+	mu  sync.Mutex
+	src <-chan interface{} // the channel Run is receiving from, if any
+}
+
+// PoolStats are the counts of the items of a WorkerPool.
+type PoolStats struct {
+	Active    int64 // being worked on
+	Queued    int64 // received or buffered in the source, but not started
+	Completed int64 // worked on successfully
+	Errored   int64 // worked on, but work returned an error
+}
+
+// New returns a WorkerPool that calls work on at most concurrency items at
+// once. If concurrency is less than 1, it is 1.
+//
+// If requeue is not nil, it is called on an item that Run received but could
+// not start work on because its context was done, so that the item can be
+// put back in its queue. It is passed a context that is not canceled along
+// with the context of Run.
+func New(concurrency int, work func(ctx context.Context, item interface{}) error, requeue func(ctx context.Context, item interface{})) *WorkerPool {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &WorkerPool{concurrency: concurrency, work: work, requeue: requeue}
+}
+
+// Run calls the work function of p on each item received from src, until src
+// is closed or ctx is done, and waits for the calls to return. When ctx is
+// done, Run stops receiving from src, but lets the calls in flight finish:
+// they are passed a context that is not canceled along with ctx. An item
+// that was received but not started is handed to the requeue function of p.
+// Items that were still buffered in src are left there.
+//
+// Errors returned by the work function are counted, and do not stop Run. Run
+// returns ctx.Err() if ctx is done, and otherwise the first error returned by
+// the work function, if any.
+func (p *WorkerPool) Run(ctx context.Context, src <-chan interface{}) error {
+	p.mu.Lock()
+	p.src = src
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		p.src = nil
+		p.mu.Unlock()
+	}()
+
+	var g errgroup.Group
+	workCtx := xcontext.Detach(ctx)
+	sem := make(chan struct{}, p.concurrency)
+loop:
+	for {
+		var item interface{}
+		select {
+		case <-ctx.Done():
+			break loop
+		case i, ok := <-src:
+			if !ok {
+				break loop
+			}
+			item = i
+		}
+		// Wait for a worker to be free.
+		atomic.AddInt64(&p.waiting, 1)
+		select {
+		case <-ctx.Done():
+			atomic.AddInt64(&p.waiting, -1)
+			if p.requeue != nil {
+				p.requeue(workCtx, item)
+			}
+			break loop
+		case sem <- struct{}{}:
+		}
+		atomic.AddInt64(&p.waiting, -1)
+		atomic.AddInt64(&p.active, 1)
+		g.Go(func() error {
+			defer func() { <-sem }()
+			err := p.work(workCtx, item)
+			atomic.AddInt64(&p.active, -1)
+			if err != nil {
+				atomic.AddInt64(&p.errored, 1)
+			} else {
+				atomic.AddInt64(&p.completed, 1)
+			}
+			return err
+		})
+	}
+	err := g.Wait()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// Stats returns the current counts of the items of p. Completed and Errored
+// accumulate over all calls to Run.
+func (p *WorkerPool) Stats() PoolStats {
+	queued := atomic.LoadInt64(&p.waiting)
+	p.mu.Lock()
+	if p.src != nil {
+		queued += int64(len(p.src))
+	}
+	p.mu.Unlock()
+	return PoolStats{
+		Active:    atomic.LoadInt64(&p.active),
+		Queued:    queued,
+		Completed: atomic.LoadInt64(&p.completed),
+		Errored:   atomic.LoadInt64(&p.errored),
+	}
+}
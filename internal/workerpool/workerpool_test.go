@@ -0,0 +1,143 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWorkerPool(t *testing.T) {
+	const (
+		numItems    = 100
+		concurrency = 5
+	)
+	var running, maxRunning int64
+	p := New(concurrency, func(ctx context.Context, item interface{}) error {
+		n := atomic.AddInt64(&running, 1)
+		defer atomic.AddInt64(&running, -1)
+		for {
+			m := atomic.LoadInt64(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt64(&maxRunning, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}, nil)
+
+	if err := p.Run(context.Background(), items(numItems)); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(PoolStats{Completed: numItems}, p.Stats()); diff != "" {
+		t.Errorf("Stats mismatch (-want +got):\n%s", diff)
+	}
+	if maxRunning != concurrency {
+		t.Errorf("got at most %d items worked on at once, want %d", maxRunning, concurrency)
+	}
+}
+
+func TestWorkerPoolErrors(t *testing.T) {
+	errFailed := errors.New("failed")
+	p := New(3, func(ctx context.Context, item interface{}) error {
+		if item.(int)%10 == 0 {
+			return errFailed
+		}
+		return nil
+	}, nil)
+	// Errors do not stop the pool, and the first one is returned.
+	if err := p.Run(context.Background(), items(100)); !errors.Is(err, errFailed) {
+		t.Errorf("Run: got %v, want %v", err, errFailed)
+	}
+	if diff := cmp.Diff(PoolStats{Completed: 90, Errored: 10}, p.Stats()); diff != "" {
+		t.Errorf("Stats mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// items returns a closed channel of the ints from 0 to n-1.
+func items(n int) <-chan interface{} {
+	src := make(chan interface{}, n)
+	for i := 0; i < n; i++ {
+		src <- i
+	}
+	close(src)
+	return src
+}
+
+func TestWorkerPoolDrainOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	p := New(2, func(workCtx context.Context, item interface{}) error {
+		started <- struct{}{}
+		// Cancellation of the context of Run must not reach the work.
+		select {
+		case <-workCtx.Done():
+			return workCtx.Err()
+		case <-time.After(50 * time.Millisecond):
+			return nil
+		}
+	}, nil)
+
+	src := make(chan interface{})
+	done := make(chan error)
+	go func() { done <- p.Run(ctx, src) }()
+	for i := 0; i < 2; i++ {
+		src <- i
+		<-started
+	}
+	if got := p.Stats().Active; got != 2 {
+		t.Errorf("got %d active, want 2", got)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Run: got %v, want context.Canceled", err)
+	}
+	// Run returned only after the work in flight had finished.
+	if diff := cmp.Diff(PoolStats{Completed: 2}, p.Stats()); diff != "" {
+		t.Errorf("Stats mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestWorkerPoolRequeueOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	release := make(chan struct{})
+	requeued := make(chan interface{}, 1)
+	p := New(1, func(ctx context.Context, item interface{}) error {
+		<-release
+		return nil
+	}, func(ctx context.Context, item interface{}) {
+		if ctx.Err() != nil {
+			t.Errorf("requeue: got canceled context")
+		}
+		requeued <- item
+	})
+
+	src := make(chan interface{})
+	done := make(chan error)
+	go func() { done <- p.Run(ctx, src) }()
+	// The first item occupies the only worker, so the second one waits.
+	src <- 0
+	src <- 1
+	for p.Stats().Queued != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	// The waiting item is handed back rather than dropped.
+	if got := <-requeued; got != 1 {
+		t.Errorf("requeued %v, want 1", got)
+	}
+	close(release)
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Run: got %v, want context.Canceled", err)
+	}
+	if diff := cmp.Diff(PoolStats{Completed: 1}, p.Stats()); diff != "" {
+		t.Errorf("Stats mismatch (-want +got):\n%s", diff)
+	}
+}
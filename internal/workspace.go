@@ -0,0 +1,121 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package internal
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// A Workspace is a set of modules developed together in one repository, as
+// declared by a go.work file at the root of the repository.
+type Workspace struct {
+	// RepoPath is the path of the module at the root of the repository,
+	// whose zip contains the go.work file.
+	RepoPath string
+	// GoVersion and Toolchain are the arguments of the go and toolchain
+	// directives of the go.work file, if any.
+	GoVersion string
+	Toolchain string
+	// UseDirs are the directories of the use directives, relative to the
+	// root of the repository and cleaned, so that the root itself is ".".
+	UseDirs []string
+	// ModulePaths are the paths of the modules in the workspace.
+	ModulePaths []string
+}
+
+// ParseWorkspaceFile parses the contents of a go.work file. It returns a
+// Workspace with UseDirs, GoVersion and Toolchain populated. The replace
+// directives of the file, and directives it does not know about, are
+// ignored.
+func ParseWorkspaceFile(content []byte) (_ *Workspace, err error) {
+	w := &Workspace{}
+	var (
+		block  string // verb of the enclosing block, if any
+		lineno int
+	)
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("go.work:%d: %v", lineno, err)
+		}
+	}()
+	for i, line := range strings.Split(string(content), "\n") {
+		lineno = i + 1
+		if j := strings.Index(line, "//"); j >= 0 {
+			line = line[:j]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if block != "" {
+			if len(fields) == 1 && fields[0] == ")" {
+				block = ""
+				continue
+			}
+			if err := w.addDirective(block, fields); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if len(fields) == 2 && fields[1] == "(" {
+			block = fields[0]
+			continue
+		}
+		if err := w.addDirective(fields[0], fields[1:]); err != nil {
+			return nil, err
+		}
+	}
+	if block != "" {
+		return nil, fmt.Errorf("unterminated %s block", block)
+	}
+	return w, nil
+}
+
+// addDirective adds the directive with the given verb and arguments to w.
+func (w *Workspace) addDirective(verb string, args []string) error {
+	switch verb {
+	case "go", "toolchain":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: %s version", verb)
+		}
+		if verb == "go" {
+			w.GoVersion = args[0]
+		} else {
+			w.Toolchain = args[0]
+		}
+	case "use":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: use directory")
+		}
+		dir := args[0]
+		if strings.HasPrefix(dir, `"`) || strings.HasPrefix(dir, "`") {
+			d, err := strconv.Unquote(dir)
+			if err != nil {
+				return fmt.Errorf("invalid quoted string %s", dir)
+			}
+			dir = d
+		}
+		w.UseDirs = append(w.UseDirs, path.Clean(dir))
+	}
+	return nil
+}
+
+// SetRepoPath sets the RepoPath of w, and sets its ModulePaths to the paths
+// of the modules in its use directories, assuming that a module in
+// directory d of the repository has path repoPath/d. Directories outside the
+// repository are skipped.
+func (w *Workspace) SetRepoPath(repoPath string) {
+	w.RepoPath = repoPath
+	w.ModulePaths = nil
+	for _, dir := range w.UseDirs {
+		if dir == ".." || strings.HasPrefix(dir, "../") || path.IsAbs(dir) {
+			continue
+		}
+		w.ModulePaths = append(w.ModulePaths, path.Join(repoPath, dir))
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package internal
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseWorkspaceFile(t *testing.T) {
+	for _, test := range []struct {
+		name, content string
+		want          *Workspace
+	}{
+		{
+			name:    "empty",
+			content: "",
+			want:    &Workspace{},
+		},
+		{
+			name: "use directives",
+			content: `
+go 1.18
+
+use .
+use ./tools // the tools module
+use (
+	./api/
+	"./cmd/server"
+)
+`,
+			want: &Workspace{
+				GoVersion: "1.18",
+				UseDirs:   []string{".", "tools", "api", "cmd/server"},
+			},
+		},
+		{
+			name: "replace directives",
+			content: `
+go 1.18
+
+use ./a
+
+replace example.com/old => example.com/new v1.2.3
+replace (
+	example.com/local => ./local
+	example.com/pinned v1.0.0 => example.com/pinned v1.0.1
+)
+
+use ./b
+`,
+			want: &Workspace{
+				GoVersion: "1.18",
+				UseDirs:   []string{"a", "b"},
+			},
+		},
+		{
+			name: "toolchain directive",
+			content: `
+go 1.21.0
+
+toolchain go1.21.3
+
+use (
+	.
+	../sibling
+)
+`,
+			want: &Workspace{
+				GoVersion: "1.21.0",
+				Toolchain: "go1.21.3",
+				UseDirs:   []string{".", "../sibling"},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseWorkspaceFile([]byte(test.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseWorkspaceFileErrors(t *testing.T) {
+	for _, content := range []string{
+		"use ./a ./b",
+		"use (\n\t./a\n",
+		"go",
+		"toolchain go1.21.0 go1.21.1",
+		"use \"./a",
+	} {
+		if _, err := ParseWorkspaceFile([]byte(content)); err == nil {
+			t.Errorf("ParseWorkspaceFile(%q): got nil error, want error", content)
+		}
+	}
+}
+
+func TestWorkspaceSetRepoPath(t *testing.T) {
+	w := &Workspace{UseDirs: []string{".", "tools", "../sibling", "/abs", "cmd/server"}}
+	w.SetRepoPath("github.com/a/b")
+	want := []string{"github.com/a/b", "github.com/a/b/tools", "github.com/a/b/cmd/server"}
+	if diff := cmp.Diff(want, w.ModulePaths); diff != "" {
+		t.Errorf("ModulePaths mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,11 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+DROP FUNCTION popular_search(rawquery text, lim integer, off integer,
+	redist_factor double precision, go_mod_factor double precision,
+	after_score double precision, after_commit_time timestamp with time zone, after_path text);
+
+END;
@@ -0,0 +1,17 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX idx_search_documents_package_path_trgm ON search_documents USING gin (package_path gin_trgm_ops);
+COMMENT ON INDEX idx_search_documents_package_path_trgm IS
+'INDEX idx_search_documents_package_path_trgm is used to find search suggestions by matching a prefix of the package path or of one of its elements.';
+
+CREATE INDEX idx_search_documents_name_trgm ON search_documents USING gin (name gin_trgm_ops);
+COMMENT ON INDEX idx_search_documents_name_trgm IS
+'INDEX idx_search_documents_name_trgm is used to find search suggestions by matching a prefix of the package name.';
+
+END;
@@ -0,0 +1,10 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+ALTER TABLE search_documents DROP COLUMN latest_version_is_retracted;
+ALTER TABLE modules DROP COLUMN retracted;
+
+END;
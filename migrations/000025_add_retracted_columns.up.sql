@@ -0,0 +1,10 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+ALTER TABLE modules ADD COLUMN retracted BOOLEAN;
+ALTER TABLE search_documents ADD COLUMN latest_version_is_retracted BOOLEAN;
+
+END;
@@ -0,0 +1,13 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+DROP TRIGGER log_imports ON search_documents;
+DROP FUNCTION trigger_log_search_documents_imports;
+DROP TRIGGER log_change ON imports_unique;
+DROP FUNCTION trigger_log_imports_unique_change;
+DROP TABLE imports_unique_changelog;
+
+END;
@@ -0,0 +1,65 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+CREATE TABLE imports_unique_changelog (
+    id bigserial PRIMARY KEY,
+    from_path text NOT NULL,
+    from_module_path text NOT NULL,
+    to_path text NOT NULL,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+COMMENT ON TABLE imports_unique_changelog IS
+'TABLE imports_unique_changelog records the rows of imports_unique that may affect imported-by counts since they were last updated. It is read and emptied by the incremental update of imported-by counts.';
+
+CREATE FUNCTION trigger_log_imports_unique_change() RETURNS TRIGGER
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO imports_unique_changelog (from_path, from_module_path, to_path)
+        VALUES (OLD.from_path, OLD.from_module_path, OLD.to_path);
+    ELSE
+        INSERT INTO imports_unique_changelog (from_path, from_module_path, to_path)
+        VALUES (NEW.from_path, NEW.from_module_path, NEW.to_path);
+    END IF;
+    RETURN NULL;
+END;
+$$;
+COMMENT ON FUNCTION trigger_log_imports_unique_change IS
+'FUNCTION trigger_log_imports_unique_change records an inserted or deleted row of imports_unique in imports_unique_changelog.';
+
+CREATE TRIGGER log_change AFTER INSERT OR DELETE ON imports_unique
+    FOR EACH ROW EXECUTE PROCEDURE trigger_log_imports_unique_change();
+COMMENT ON TRIGGER log_change ON imports_unique IS
+'TRIGGER log_change records every inserted or deleted row in imports_unique_changelog.';
+
+CREATE FUNCTION trigger_log_search_documents_imports() RETURNS TRIGGER
+    LANGUAGE plpgsql
+    AS $$
+DECLARE
+    pkg_path text;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        pkg_path := OLD.package_path;
+    ELSE
+        pkg_path := NEW.package_path;
+    END IF;
+    INSERT INTO imports_unique_changelog (from_path, from_module_path, to_path)
+    SELECT from_path, from_module_path, to_path
+    FROM imports_unique
+    WHERE from_path = pkg_path;
+    RETURN NULL;
+END;
+$$;
+COMMENT ON FUNCTION trigger_log_search_documents_imports IS
+'FUNCTION trigger_log_search_documents_imports records the imports_unique rows of an inserted or deleted search document in imports_unique_changelog, since only importers in search_documents are counted.';
+
+CREATE TRIGGER log_imports AFTER INSERT OR DELETE ON search_documents
+    FOR EACH ROW EXECUTE PROCEDURE trigger_log_search_documents_imports();
+COMMENT ON TRIGGER log_imports ON search_documents IS
+'TRIGGER log_imports records the imports of every inserted or deleted search document in imports_unique_changelog.';
+
+END;
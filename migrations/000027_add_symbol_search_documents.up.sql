@@ -0,0 +1,22 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+CREATE TABLE symbol_search_documents (
+    package_path text NOT NULL,
+    symbol_name text NOT NULL,
+    symbol_kind text NOT NULL,
+    synopsis_vector tsvector,
+    PRIMARY KEY (package_path, symbol_name),
+    FOREIGN KEY (package_path) REFERENCES search_documents(package_path) ON DELETE CASCADE
+);
+COMMENT ON TABLE symbol_search_documents IS
+'TABLE symbol_search_documents contains a record for each exported symbol of the packages in search_documents. It is used to find the packages that declare a symbol.';
+COMMENT ON COLUMN symbol_search_documents.symbol_name IS
+'COLUMN symbol_name is the name of the symbol. The name of a method is qualified by its receiver type, as in "Buffer.Write".';
+
+CREATE INDEX idx_symbol_search_documents_symbol_name ON symbol_search_documents (symbol_name text_pattern_ops);
+
+END;
@@ -0,0 +1,27 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+ALTER TABLE search_documents
+    ADD COLUMN synopsis_vector tsvector,
+    ADD COLUMN readme_vector tsvector;
+
+-- The synopsis and README are the B, and the C and D sections of
+-- tsv_search_tokens, respectively. (See UpsertSearchDocument.) Extract them
+-- for existing rows, so that they do not have to be reprocessed.
+UPDATE search_documents SET
+    synopsis_vector = SETWEIGHT(TS_FILTER(tsv_search_tokens, '{b}'), 'A'),
+    readme_vector = SETWEIGHT(TS_FILTER(tsv_search_tokens, '{c,d}'), 'C');
+
+ALTER TABLE search_documents
+    ALTER COLUMN synopsis_vector SET NOT NULL,
+    ALTER COLUMN readme_vector SET NOT NULL;
+
+COMMENT ON COLUMN search_documents.synopsis_vector IS
+'COLUMN synopsis_vector is the tsvector of the package synopsis, with weight A. It is used to rank search results.';
+COMMENT ON COLUMN search_documents.readme_vector IS
+'COLUMN readme_vector is the tsvector of the module README, with weight C. It is used to rank search results.';
+
+END;
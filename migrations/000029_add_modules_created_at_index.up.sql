@@ -0,0 +1,11 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+CREATE INDEX idx_modules_created_at ON modules (created_at DESC);
+COMMENT ON INDEX idx_modules_created_at IS
+'INDEX idx_modules_created_at is used to get the most recently indexed module versions.';
+
+END;
@@ -0,0 +1,10 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+DROP TABLE trending_stats;
+DROP TABLE imported_by_count_snapshots;
+
+END;
@@ -0,0 +1,34 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+CREATE TABLE imported_by_count_snapshots (
+    package_path text NOT NULL,
+    recorded_on date NOT NULL,
+    imported_by_count integer NOT NULL,
+    PRIMARY KEY (package_path, recorded_on)
+);
+COMMENT ON TABLE imported_by_count_snapshots IS
+'TABLE imported_by_count_snapshots records the imported_by_count of each package in search_documents once a day, so that its growth can be computed. Packages with no importers are omitted.';
+
+CREATE INDEX idx_imported_by_count_snapshots_recorded_on ON imported_by_count_snapshots (recorded_on);
+COMMENT ON INDEX idx_imported_by_count_snapshots_recorded_on IS
+'INDEX idx_imported_by_count_snapshots_recorded_on is used to find and prune the snapshots of a day.';
+
+CREATE TABLE trending_stats (
+    package_path text NOT NULL,
+    period_days integer NOT NULL,
+    delta_imported_by_count integer NOT NULL,
+    recorded_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    PRIMARY KEY (package_path, period_days)
+);
+COMMENT ON TABLE trending_stats IS
+'TABLE trending_stats contains, for each period, the packages whose imported_by_count grew over the last period_days days, and by how much.';
+
+CREATE INDEX idx_trending_stats_period_days_delta ON trending_stats (period_days, delta_imported_by_count DESC);
+COMMENT ON INDEX idx_trending_stats_period_days_delta IS
+'INDEX idx_trending_stats_period_days_delta is used to get the packages with the largest growth in a period.';
+
+END;
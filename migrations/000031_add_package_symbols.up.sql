@@ -0,0 +1,23 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+CREATE TABLE package_symbols (
+    package_path text NOT NULL,
+    module_path text NOT NULL,
+    version text NOT NULL,
+    symbol_name text NOT NULL,
+    symbol_kind text NOT NULL,
+    signature text NOT NULL,
+    PRIMARY KEY (package_path, module_path, version, symbol_name),
+    FOREIGN KEY (package_path, module_path, version)
+        REFERENCES packages(path, module_path, version) ON DELETE CASCADE
+);
+COMMENT ON TABLE package_symbols IS
+'TABLE package_symbols contains the exported symbols of each package in the packages table. It is used to compare the API of two versions of a package.';
+COMMENT ON COLUMN package_symbols.signature IS
+'COLUMN signature is the declaration of the symbol, without its documentation or function body.';
+
+END;
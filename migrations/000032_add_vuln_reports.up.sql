@@ -0,0 +1,20 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+CREATE TABLE vuln_reports (
+    module_path text NOT NULL,
+    affected_version_range text NOT NULL,
+    vuln_id text NOT NULL,
+    description text NOT NULL,
+    published_at timestamp with time zone NOT NULL,
+    PRIMARY KEY (module_path, vuln_id, affected_version_range)
+);
+COMMENT ON TABLE vuln_reports IS
+'TABLE vuln_reports contains the vulnerabilities reported in the Go vulnerability database, with a row for each range of affected versions of each module.';
+COMMENT ON COLUMN vuln_reports.affected_version_range IS
+'COLUMN affected_version_range is a list of constraints such as ">=v1.2.0" or "<v1.2.3", separated by ", ". The empty string means that all versions are affected.';
+
+END;
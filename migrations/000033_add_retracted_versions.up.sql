@@ -0,0 +1,12 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+ALTER TABLE modules ADD COLUMN retracted_versions TEXT[];
+
+COMMENT ON COLUMN modules.retracted_versions IS
+'COLUMN retracted_versions holds the retract directives of the module''s go.mod file. Each is a version or an interval of versions of the form "[low, high]".';
+
+END;
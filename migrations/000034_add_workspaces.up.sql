@@ -0,0 +1,19 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+CREATE TABLE workspaces (
+    repo_path text NOT NULL PRIMARY KEY,
+    version text NOT NULL,
+    go_version text,
+    toolchain text,
+    use_dirs text[] NOT NULL,
+    module_paths text[] NOT NULL,
+    FOREIGN KEY (repo_path, version) REFERENCES modules(module_path, version) ON DELETE CASCADE
+);
+COMMENT ON TABLE workspaces IS
+'TABLE workspaces contains the workspaces declared by go.work files at the root of the latest version of a module. The module is the one at the root of the repository, so its path is used as the path of the repository.';
+
+END;
@@ -0,0 +1,12 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+ALTER TABLE modules ADD COLUMN toolchain_version TEXT;
+
+COMMENT ON COLUMN modules.toolchain_version IS
+'COLUMN toolchain_version is the argument of the toolchain directive of the module''s go.mod file, like "go1.21.3". It is NULL or empty if there is none.';
+
+END;
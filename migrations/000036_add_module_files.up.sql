@@ -0,0 +1,22 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+CREATE TABLE module_files (
+    module_id INTEGER NOT NULL REFERENCES modules(id) ON DELETE CASCADE,
+    path TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    content_type TEXT NOT NULL,
+    contents BYTEA,
+    PRIMARY KEY (module_id, path)
+);
+COMMENT ON TABLE module_files IS
+'TABLE module_files contains the files of the zip of each module version.';
+COMMENT ON COLUMN module_files.path IS
+'COLUMN path is the slash-separated path of the file relative to the module root.';
+COMMENT ON COLUMN module_files.contents IS
+'COLUMN contents holds the contents of small text files of redistributable modules, and is NULL for other files.';
+
+END;
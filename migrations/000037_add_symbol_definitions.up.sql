@@ -0,0 +1,23 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+CREATE TABLE symbol_definitions (
+    package_path text NOT NULL,
+    module_path text NOT NULL,
+    version text NOT NULL,
+    symbol_name text NOT NULL,
+    file_path text NOT NULL,
+    line integer NOT NULL,
+    PRIMARY KEY (package_path, module_path, symbol_name),
+    FOREIGN KEY (package_path, module_path, version)
+        REFERENCES packages(path, module_path, version) ON DELETE CASCADE
+);
+COMMENT ON TABLE symbol_definitions IS
+'TABLE symbol_definitions contains the location of the declaration of each exported symbol of the packages of the latest version of each module.';
+COMMENT ON COLUMN symbol_definitions.file_path IS
+'COLUMN file_path is the path of the file that declares the symbol, relative to the module root.';
+
+END;
@@ -0,0 +1,22 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+CREATE TABLE implements (
+    interface_package_path text NOT NULL,
+    interface_name text NOT NULL,
+    package_path text NOT NULL,
+    module_path text NOT NULL,
+    version text NOT NULL,
+    type_name text NOT NULL,
+    PRIMARY KEY (interface_package_path, interface_name, package_path, module_path, type_name),
+    FOREIGN KEY (package_path, module_path, version)
+        REFERENCES packages(path, module_path, version) ON DELETE CASCADE
+);
+CREATE INDEX idx_implements_module_path ON implements (module_path);
+COMMENT ON TABLE implements IS
+'TABLE implements contains the exported types of the packages of the latest version of each module that implement exported interfaces, as computed by type-checking the module at fetch time.';
+
+END;
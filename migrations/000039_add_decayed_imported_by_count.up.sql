@@ -0,0 +1,18 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+ALTER TABLE search_documents
+    ADD COLUMN decayed_imported_by_count double precision DEFAULT 0 NOT NULL;
+COMMENT ON COLUMN search_documents.decayed_imported_by_count IS
+'COLUMN decayed_imported_by_count is imported_by_count decayed by the age of commit_time, with a half-life of two years by default. It is used instead of imported_by_count to compute search scores, and is never greater than imported_by_count.';
+
+-- Backfill with the default half-life, until imported-by counts are next
+-- recomputed.
+UPDATE search_documents
+SET decayed_imported_by_count = imported_by_count *
+    power(0.5, GREATEST(EXTRACT(EPOCH FROM CURRENT_TIMESTAMP - commit_time), 0) / (2 * 365 * 24 * 3600));
+
+END;
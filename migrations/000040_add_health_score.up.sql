@@ -0,0 +1,22 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+ALTER TABLE search_documents ADD COLUMN health_score real DEFAULT 0 NOT NULL;
+COMMENT ON COLUMN search_documents.health_score IS
+'COLUMN health_score is a number between 0 and 100 that summarizes the quality of the package and its module, as computed by scoring.ComputeHealthScore. It is used as a multiplier in search scores.';
+
+-- Backfill with the factors known without refetching modules. Tests and CI
+-- configuration are detected when the module is next fetched.
+UPDATE search_documents s
+SET health_score =
+    CASE WHEN COALESCE(s.has_go_mod, true) THEN 20 ELSE 0 END +
+    CASE WHEN s.redistributable THEN 20 ELSE 0 END +
+    CASE WHEN COALESCE(m.readme_file_path, '') <> '' THEN 15 ELSE 0 END +
+    LEAST(20, 20 * log(1 + s.imported_by_count) / log(1 + 1000))
+FROM modules m
+WHERE m.module_path = s.module_path AND m.version = s.version;
+
+END;
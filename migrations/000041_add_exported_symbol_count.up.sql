@@ -0,0 +1,20 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+ALTER TABLE search_documents ADD COLUMN exported_symbol_count integer DEFAULT 0 NOT NULL;
+COMMENT ON COLUMN search_documents.exported_symbol_count IS
+'COLUMN exported_symbol_count is the number of exported symbols of the package, including methods, as stored in package_symbols.';
+
+UPDATE search_documents s
+SET exported_symbol_count = (
+    SELECT COUNT(*)
+    FROM package_symbols ps
+    WHERE ps.package_path = s.package_path
+    AND ps.module_path = s.module_path
+    AND ps.version = s.version
+);
+
+END;
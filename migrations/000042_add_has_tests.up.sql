@@ -0,0 +1,30 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+ALTER TABLE search_documents ADD COLUMN has_tests boolean DEFAULT false NOT NULL;
+COMMENT ON COLUMN search_documents.has_tests IS
+'COLUMN has_tests records whether the directory of the package contains a Go test file.';
+
+UPDATE search_documents s
+SET has_tests = EXISTS (
+    SELECT 1
+    FROM module_files f
+    INNER JOIN modules m ON m.id = f.module_id
+    CROSS JOIN (
+        SELECT CASE
+            WHEN s.package_path = s.module_path THEN ''
+            WHEN s.module_path = 'std' THEN s.package_path || '/'
+            ELSE substr(s.package_path, length(s.module_path) + 2) || '/'
+        END AS prefix
+    ) d
+    WHERE m.module_path = s.module_path
+    AND m.version = s.version
+    AND left(f.path, length(d.prefix)) = d.prefix
+    AND strpos(substr(f.path, length(d.prefix) + 1), '/') = 0
+    AND f.path LIKE '%\_test.go'
+);
+
+END;
@@ -0,0 +1,13 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+-- The synopses of symbols are not stored, so existing rows cannot be
+-- backfilled. They are updated when their module is next inserted.
+ALTER TABLE search_documents ADD COLUMN doc_coverage_pct integer DEFAULT 0 NOT NULL;
+COMMENT ON COLUMN search_documents.doc_coverage_pct IS
+'COLUMN doc_coverage_pct is the percentage of the exported symbols of the package that have a documentation comment.';
+
+END;
@@ -0,0 +1,12 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+-- Existing rows are updated when their module is next inserted.
+ALTER TABLE licenses ADD COLUMN spdx_types text[];
+COMMENT ON COLUMN licenses.spdx_types IS
+'COLUMN spdx_types contains the SPDX identifiers of the license types of the file that have one, normalized from its types, which are the raw types reported by licensecheck.';
+
+END;
@@ -0,0 +1,13 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+-- The sources of packages are not stored, so existing rows cannot be
+-- backfilled. They are updated when their module is next inserted.
+ALTER TABLE search_documents ADD COLUMN embedded_patterns text[] DEFAULT '{}' NOT NULL;
+COMMENT ON COLUMN search_documents.embedded_patterns IS
+'COLUMN embedded_patterns contains the patterns of the //go:embed directives of the package.';
+
+END;
@@ -0,0 +1,13 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+-- Existing rows are updated when their module is next inserted. Until then,
+-- the build contexts of their packages are unknown.
+ALTER TABLE search_documents ADD COLUMN build_contexts jsonb;
+COMMENT ON COLUMN search_documents.build_contexts IS
+'COLUMN build_contexts contains the major GOOS/GOARCH combinations that the package builds for, as a JSON array of objects with GOOS and GOARCH fields.';
+
+END;
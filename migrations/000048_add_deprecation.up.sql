@@ -0,0 +1,16 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+-- The sources of packages are not stored, so existing rows cannot be
+-- backfilled. They are updated when their module is next inserted.
+ALTER TABLE search_documents ADD COLUMN is_deprecated boolean DEFAULT false NOT NULL;
+ALTER TABLE search_documents ADD COLUMN deprecation_message text;
+COMMENT ON COLUMN search_documents.is_deprecated IS
+'COLUMN is_deprecated reports whether a paragraph of the package doc comment begins with "Deprecated: ".';
+COMMENT ON COLUMN search_documents.deprecation_message IS
+'COLUMN deprecation_message is the rest of the "Deprecated: " paragraph of the package doc comment. It is NULL if the package is not deprecated.';
+
+END;
@@ -0,0 +1,11 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+ALTER TABLE search_documents ADD COLUMN successor_packages text[] DEFAULT '{}' NOT NULL;
+COMMENT ON COLUMN search_documents.successor_packages IS
+'COLUMN successor_packages contains the import paths mentioned in deprecation_message. They are not necessarily known packages.';
+
+END;
@@ -0,0 +1,25 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+CREATE TABLE examples (
+    package_path text NOT NULL,
+    module_path text NOT NULL,
+    version text NOT NULL,
+    name text NOT NULL,
+    code text NOT NULL,
+    output text NOT NULL,
+    PRIMARY KEY (package_path, module_path, version, name),
+    FOREIGN KEY (package_path, module_path, version)
+        REFERENCES packages(path, module_path, version) ON DELETE CASCADE
+);
+COMMENT ON TABLE examples IS
+'TABLE examples contains the example functions of each package in the packages table, declared in its _test.go files.';
+COMMENT ON COLUMN examples.code IS
+'COLUMN code is the body of the example function, without its enclosing braces and its output comment.';
+COMMENT ON COLUMN examples.output IS
+'COLUMN output is the expected output of the example, or the empty string if it has no output comment.';
+
+END;
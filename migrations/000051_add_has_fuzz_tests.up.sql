@@ -0,0 +1,13 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+-- The sources of packages are not stored, so existing rows cannot be
+-- backfilled. They are updated when their module is next inserted.
+ALTER TABLE search_documents ADD COLUMN has_fuzz_tests boolean DEFAULT false NOT NULL;
+COMMENT ON COLUMN search_documents.has_fuzz_tests IS
+'COLUMN has_fuzz_tests reports whether a test file of the package declares a fuzz target, like func FuzzXxx(*testing.F).';
+
+END;
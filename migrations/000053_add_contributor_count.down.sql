@@ -0,0 +1,10 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+ALTER TABLE modules DROP COLUMN contributor_count_fetched_at;
+ALTER TABLE modules DROP COLUMN contributor_count;
+
+END;
@@ -0,0 +1,15 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+ALTER TABLE modules ADD COLUMN contributor_count integer;
+COMMENT ON COLUMN modules.contributor_count IS
+'COLUMN contributor_count is the number of contributors of the repository of the module, as reported by the API of its hosting service. It is NULL if that is unknown.';
+
+ALTER TABLE modules ADD COLUMN contributor_count_fetched_at timestamp with time zone;
+COMMENT ON COLUMN modules.contributor_count_fetched_at IS
+'COLUMN contributor_count_fetched_at is the time at which contributor_count was last fetched, or NULL if it never was.';
+
+END;
@@ -0,0 +1,17 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+CREATE TABLE module_links (
+    module_path text NOT NULL PRIMARY KEY,
+    version text NOT NULL,
+    issues_url text NOT NULL,
+    ci_url text NOT NULL,
+    FOREIGN KEY (module_path, version) REFERENCES modules(module_path, version) ON DELETE CASCADE
+);
+COMMENT ON TABLE module_links IS
+'TABLE module_links contains the links to the issue tracker and the continuous integration status of the repository of the latest version of a module, derived from its repository URL and CI configuration files.';
+
+END;
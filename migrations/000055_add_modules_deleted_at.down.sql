@@ -0,0 +1,10 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+DELETE FROM modules WHERE deleted_at IS NOT NULL;
+ALTER TABLE modules DROP COLUMN deleted_at;
+
+END;
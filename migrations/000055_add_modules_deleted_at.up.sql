@@ -0,0 +1,11 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+ALTER TABLE modules ADD COLUMN deleted_at timestamp with time zone;
+COMMENT ON COLUMN modules.deleted_at IS
+'COLUMN deleted_at is the time at which the module version was deleted, for example because it could no longer be processed, or NULL if it was not. Deleted module versions keep their rows, so that their history can be inspected, but are not served.';
+
+END;
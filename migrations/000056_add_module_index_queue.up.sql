@@ -0,0 +1,19 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+CREATE TABLE module_index_queue (
+    module_path text NOT NULL,
+    version text NOT NULL,
+    priority integer NOT NULL DEFAULT 0,
+    enqueued_at timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (module_path, version)
+);
+COMMENT ON TABLE module_index_queue IS
+'TABLE module_index_queue contains the module versions waiting to be fetched by the worker, such as those that users requested before they were indexed. Module versions with a higher priority are fetched first, and those with the same priority in the order in which they were enqueued.';
+
+CREATE INDEX idx_module_index_queue_priority_enqueued_at ON module_index_queue (priority DESC, enqueued_at);
+
+END;
@@ -0,0 +1,19 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+CREATE TABLE module_events (
+    id bigserial PRIMARY KEY,
+    event_type text NOT NULL,
+    payload jsonb NOT NULL,
+    published boolean NOT NULL DEFAULT false,
+    created_at timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+COMMENT ON TABLE module_events IS
+'TABLE module_events is an outbox of the events about module versions, such as their insertion, for downstream consumers. Events are written in the same transaction as the change they describe, and marked published once they have been delivered, so that none are lost.';
+
+CREATE INDEX idx_module_events_unpublished ON module_events (id) WHERE NOT published;
+
+END;
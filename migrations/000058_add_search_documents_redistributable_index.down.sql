@@ -0,0 +1,5 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+DROP INDEX CONCURRENTLY idx_search_documents_tsv_search_tokens_redistributable;
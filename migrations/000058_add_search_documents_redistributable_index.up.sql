@@ -0,0 +1,9 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+-- CREATE INDEX CONCURRENTLY cannot run in a transaction, so this migration
+-- has a single statement and no BEGIN/END.
+CREATE INDEX CONCURRENTLY idx_search_documents_tsv_search_tokens_redistributable
+    ON search_documents USING gin (tsv_search_tokens)
+    WHERE redistributable = TRUE;
@@ -0,0 +1,32 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+-- Store the hyperloglog registers at 7 bits again.
+--
+-- The rows are updated in batches of 10000, each committed on its own, so
+-- that the rows of search_documents are not all locked at once. COMMIT is
+-- only allowed in a DO block that is not in a transaction, so this migration
+-- has a single statement and no BEGIN/END.
+DO $$
+DECLARE
+    last_path text := '';
+    batch_last_path text;
+BEGIN
+    LOOP
+        SELECT max(package_path) INTO batch_last_path
+        FROM (
+            SELECT package_path
+            FROM search_documents
+            WHERE package_path > last_path
+            ORDER BY package_path
+            LIMIT 10000
+        ) batch;
+        EXIT WHEN batch_last_path IS NULL;
+        UPDATE search_documents
+        SET hll_register = hll_hash(package_path) & (128 - 1)
+        WHERE package_path > last_path AND package_path <= batch_last_path;
+        COMMIT;
+        last_path := batch_last_path;
+    END LOOP;
+END $$;
@@ -0,0 +1,22 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+CREATE TABLE search_query_log (
+    id bigserial PRIMARY KEY,
+    query_hash text NOT NULL,
+    num_results integer NOT NULL,
+    source text NOT NULL,
+    duration_ms bigint NOT NULL,
+    zero_results boolean NOT NULL,
+    created_at timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+COMMENT ON TABLE search_query_log IS
+'TABLE search_query_log records the searches made on the site, to find popular queries and queries without results. Queries are identified by the SHA-256 hash of their normalized text, so that the text entered by users is not stored.';
+
+CREATE INDEX idx_search_query_log_query_hash ON search_query_log (query_hash);
+CREATE INDEX idx_search_query_log_created_at ON search_query_log (created_at);
+
+END;
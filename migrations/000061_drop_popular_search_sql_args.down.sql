@@ -0,0 +1,63 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+CREATE OR REPLACE FUNCTION popular_search(rawquery text, lim integer, off integer,
+	score_expr text, filter text,
+	after_score double precision, after_commit_time timestamp with time zone, after_path text)
+	RETURNS SETOF search_result
+    LANGUAGE plpgsql
+    AS $$
+	DECLARE cur refcursor;
+	top search_result[];
+	res search_result;
+	last_idx INT;
+BEGIN
+	last_idx := lim+off;
+	top := array_fill(NULL::search_result, array[last_idx]);
+	OPEN cur FOR EXECUTE
+		'SELECT package_path, module_path, version, commit_time, imported_by_count, ('
+			|| score_expr ||
+			') * CASE WHEN tsv_search_tokens @@ websearch_to_tsquery($1) THEN 1 ELSE 0 END AS score
+		FROM search_documents
+		WHERE ' || filter || '
+		ORDER BY imported_by_count DESC'
+		USING rawquery;
+	FETCH cur INTO res;
+	WHILE found LOOP
+		-- Skip results at or before the cursor position.
+		IF after_path IS NULL OR
+			(res.score < after_score) OR
+			(res.score = after_score AND res.commit_time < after_commit_time) OR
+			(res.score = after_score AND res.commit_time = after_commit_time AND
+			 res.package_path > after_path) THEN
+			IF top[last_idx] IS NULL OR res.score >= top[last_idx].score THEN
+				FOR i IN 1..last_idx LOOP
+					IF top[i] IS NULL OR
+						(res.score > top[i].score) OR
+						(res.score = top[i].score AND res.commit_time > top[i].commit_time) OR
+						(res.score = top[i].score AND res.commit_time = top[i].commit_time AND
+						 res.package_path < top[i].package_path) THEN
+						top := (top[1:i-1] || res) || top[i:last_idx-1];
+						EXIT;
+					END IF;
+				END LOOP;
+			END IF;
+		END IF;
+		IF top[last_idx].score > ln(exp(1)+res.imported_by_count) THEN
+			EXIT;
+		END IF;
+		FETCH cur INTO res;
+	END LOOP;
+	CLOSE cur;
+	RETURN QUERY SELECT * FROM UNNEST(top[off+1:last_idx])
+		WHERE package_path IS NOT NULL AND score > 0.1;
+END; $$;
+COMMENT ON FUNCTION popular_search(rawquery text, lim integer, off integer,
+	score_expr text, filter text,
+	after_score double precision, after_commit_time timestamp with time zone, after_path text) IS
+'FUNCTION popular_search is used to generate results for search. It is implemented as a stored function, so that we can use a cursor to scan search documents procedurally, and stop scanning early, whenever our search results are provably correct. This overload takes the score expression and a filter predicate as SQL text, and supports resuming the search after a given (score, commit_time, package_path) position.';
+
+END;
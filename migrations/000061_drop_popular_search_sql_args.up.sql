@@ -0,0 +1,14 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+-- Popular search is now run as a cursor from Go, so the overload of
+-- popular_search that EXECUTEs SQL text passed in as arguments is no longer
+-- needed.
+DROP FUNCTION popular_search(rawquery text, lim integer, off integer,
+	score_expr text, filter text,
+	after_score double precision, after_commit_time timestamp with time zone, after_path text);
+
+END;
@@ -0,0 +1,13 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+-- The column is left NULL for existing rows, which are shown as unknown until
+-- their module is next inserted.
+ALTER TABLE packages ADD COLUMN has_tests boolean;
+COMMENT ON COLUMN packages.has_tests IS
+'COLUMN has_tests records whether the directory of the package contains a Go test file. It is NULL if that is not known, because the package was inserted before it was recorded.';
+
+END;
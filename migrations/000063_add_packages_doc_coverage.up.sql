@@ -0,0 +1,14 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+-- The synopses of symbols are not stored, so existing rows cannot be
+-- backfilled. They are left NULL, which is shown as unknown, until their
+-- module is next inserted.
+ALTER TABLE packages ADD COLUMN doc_coverage_pct integer;
+COMMENT ON COLUMN packages.doc_coverage_pct IS
+'COLUMN doc_coverage_pct is the percentage of the exported symbols of the package that have a documentation comment. It is NULL if the package has no exported symbols, or if it was inserted before the percentage was recorded.';
+
+END;
@@ -0,0 +1,14 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+-- The column is left NULL for existing rows, which are shown as unknown until
+-- their module is next inserted. search_documents.requires_cgo is not copied,
+-- because it defaults to false for rows inserted before it was recorded.
+ALTER TABLE packages ADD COLUMN requires_cgo boolean;
+COMMENT ON COLUMN packages.requires_cgo IS
+'COLUMN requires_cgo reports whether a file of the package imports "C". It is NULL if that is not known, because the package was inserted before it was recorded.';
+
+END;
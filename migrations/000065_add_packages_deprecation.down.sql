@@ -0,0 +1,10 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+ALTER TABLE packages DROP COLUMN is_deprecated;
+ALTER TABLE packages DROP COLUMN deprecation_message;
+
+END;
@@ -0,0 +1,18 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+-- The columns are left NULL for existing rows, which are shown as unknown
+-- until their module is next inserted. search_documents.is_deprecated is not
+-- copied, because it defaults to false for rows inserted before it was
+-- recorded.
+ALTER TABLE packages ADD COLUMN is_deprecated boolean;
+ALTER TABLE packages ADD COLUMN deprecation_message text;
+COMMENT ON COLUMN packages.is_deprecated IS
+'COLUMN is_deprecated reports whether a paragraph of the package doc comment begins with "Deprecated: ". It is NULL if that is not known, because the package was inserted before it was recorded.';
+COMMENT ON COLUMN packages.deprecation_message IS
+'COLUMN deprecation_message is the rest of the "Deprecated: " paragraph of the package doc comment. It is NULL unless is_deprecated is true.';
+
+END;
@@ -0,0 +1,13 @@
+-- Copyright 2020 The Go Authors. All rights reserved.
+-- Use of this source code is governed by a BSD-style
+-- license that can be found in the LICENSE file.
+
+BEGIN;
+
+-- The column is left NULL for existing rows, which show no conflicts until
+-- their module is next inserted.
+ALTER TABLE packages ADD COLUMN license_conflicts jsonb;
+COMMENT ON COLUMN packages.license_conflicts IS
+'COLUMN license_conflicts holds the pairs of incompatible licenses of the package and the packages it imports, directly or indirectly, as a JSON array of objects with the fields License, Package, OtherLicense and OtherPackage. It is computed when the package is inserted, from the imported packages known then. It is NULL if it was not computed, because the package was inserted before it was recorded.';
+
+END;